@@ -16,6 +16,7 @@ import (
 	"net"
 	"net/http"
 	"sort"
+	"strconv"
 	"time"
 
 	"github.com/gorilla/mux"
@@ -30,6 +31,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/autodiscovery"
 	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
 	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/dogstatsd/replay"
 	"github.com/DataDog/datadog-agent/pkg/flare"
 	"github.com/DataDog/datadog-agent/pkg/logs"
 	"github.com/DataDog/datadog-agent/pkg/logs/diagnostic"
@@ -58,6 +60,8 @@ func SetupHandlers(r *mux.Router) *mux.Router {
 	r.HandleFunc("/status", getStatus).Methods("GET")
 	r.HandleFunc("/stream-logs", streamLogs).Methods("POST")
 	r.HandleFunc("/dogstatsd-stats", getDogstatsdStats).Methods("GET")
+	r.HandleFunc("/dogstatsd-capture", captureDogstatsdTraffic).Methods("POST")
+	r.HandleFunc("/dogstatsd-replay", replayDogstatsdTraffic).Methods("POST")
 	r.HandleFunc("/status/formatted", getFormattedStatus).Methods("GET")
 	r.HandleFunc("/status/health", getHealth).Methods("GET")
 	r.HandleFunc("/{component}/status", componentStatusGetterHandler).Methods("GET")
@@ -233,6 +237,12 @@ func streamLogs(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if err := filters.Compile(); err != nil {
+		http.Error(w, log.Errorf("Invalid filters: %s", err).Error(), 400)
+		return
+	}
+	logMessageReceiver.SetStages(filters.Stages)
+
 	conn := GetConnection(r)
 
 	// Override the default server timeouts so the connection never times out
@@ -302,6 +312,80 @@ func getDogstatsdStats(w http.ResponseWriter, r *http.Request) {
 	w.Write(jsonStats)
 }
 
+// writeDogstatsdError writes a json error, errorType being set for errors
+// that are due to the configuration of the Agent
+func writeDogstatsdError(w http.ResponseWriter, code int, err string, errorType string) {
+	w.Header().Set("Content-Type", "application/json")
+	errMap := map[string]string{"error": err}
+	if errorType != "" {
+		errMap["error_type"] = errorType
+	}
+	body, _ := json.Marshal(errMap)
+	w.WriteHeader(code)
+	w.Write(body)
+}
+
+func captureDogstatsdTraffic(w http.ResponseWriter, r *http.Request) {
+	log.Info("Got a request for a Dogstatsd traffic capture.")
+
+	if !config.Datadog.GetBool("use_dogstatsd") || common.DSD == nil {
+		writeDogstatsdError(w, 400, "Dogstatsd not enabled in the Agent configuration", "no server")
+		return
+	}
+
+	r.ParseForm() //nolint:errcheck
+	duration, err := time.ParseDuration(r.Form.Get("duration"))
+	if err != nil {
+		writeDogstatsdError(w, 400, fmt.Sprintf("invalid capture duration: %s", err), "")
+		return
+	}
+
+	path, err := common.DSD.TCapture.Start("", duration)
+	if err != nil {
+		log.Errorf("Unable to start the Dogstatsd traffic capture: %s", err)
+		writeDogstatsdError(w, 500, err.Error(), "")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	body, _ := json.Marshal(map[string]string{"path": path})
+	w.Write(body)
+}
+
+func replayDogstatsdTraffic(w http.ResponseWriter, r *http.Request) {
+	log.Info("Got a request to replay a Dogstatsd traffic capture.")
+
+	if !config.Datadog.GetBool("use_dogstatsd") || common.DSD == nil {
+		writeDogstatsdError(w, 400, "Dogstatsd not enabled in the Agent configuration", "no server")
+		return
+	}
+
+	r.ParseForm() //nolint:errcheck
+	speed, err := strconv.ParseFloat(r.Form.Get("speed"), 64)
+	if err != nil {
+		writeDogstatsdError(w, 400, fmt.Sprintf("invalid replay speed: %s", err), "")
+		return
+	}
+
+	// only the capture files written by the Agent can be replayed
+	path, err := replay.GetCaptureFilePath(r.Form.Get("file"))
+	if err != nil {
+		writeDogstatsdError(w, 400, err.Error(), "")
+		return
+	}
+
+	// the replay may take as long as the capture, it runs in the background
+	if err := common.DSD.StartReplay(path, speed); err != nil {
+		log.Errorf("Unable to replay the Dogstatsd traffic capture: %s", err)
+		writeDogstatsdError(w, 500, err.Error(), "")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	body, _ := json.Marshal(map[string]string{"path": path})
+	w.Write(body)
+}
+
 func getFormattedStatus(w http.ResponseWriter, r *http.Request) {
 	log.Info("Got a request for the formatted status. Making formatted status.")
 	s, err := status.GetAndFormatStatus()
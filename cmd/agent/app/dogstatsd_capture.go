@@ -0,0 +1,145 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/cmd/agent/common"
+	"github.com/DataDog/datadog-agent/pkg/api/util"
+	"github.com/DataDog/datadog-agent/pkg/config"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var (
+	dsdCaptureDuration time.Duration
+	dsdReplayFilePath  string
+	dsdReplaySpeed     float64
+)
+
+func init() {
+	AgentCmd.AddCommand(dogstatsdCaptureCmd)
+	dogstatsdCaptureCmd.Flags().DurationVarP(&dsdCaptureDuration, "duration", "d", time.Minute, "duration of the traffic capture")
+
+	AgentCmd.AddCommand(dogstatsdReplayCmd)
+	dogstatsdReplayCmd.Flags().StringVarP(&dsdReplayFilePath, "file", "f", "", "capture file to replay")
+	dogstatsdReplayCmd.Flags().Float64VarP(&dsdReplaySpeed, "speed", "s", 1, "replay speed factor, 0 replays the traffic as fast as possible")
+	dogstatsdReplayCmd.MarkFlagRequired("file") //nolint:errcheck
+}
+
+var dogstatsdCaptureCmd = &cobra.Command{
+	Use:   "dogstatsd-capture",
+	Short: "Record the traffic received by dogstatsd to a capture file",
+	Long: `Record the raw datagrams received by the dogstatsd UDP and UDS listeners, along with
+the credentials of the UDS senders, to a capture file written by the running Agent.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := setupDogstatsdCommand(); err != nil {
+			return err
+		}
+
+		r, err := postDogstatsdCommand("dogstatsd-capture", url.Values{"duration": {dsdCaptureDuration.String()}})
+		if err != nil {
+			return err
+		}
+
+		var resp map[string]string
+		if err := json.Unmarshal(r, &resp); err != nil {
+			return fmt.Errorf("unexpected response from the agent: %s", r)
+		}
+		fmt.Printf("Capturing the dogstatsd traffic for %s to %s\n", dsdCaptureDuration, resp["path"])
+		return nil
+	},
+}
+
+var dogstatsdReplayCmd = &cobra.Command{
+	Use:   "dogstatsd-replay",
+	Short: "Replay a dogstatsd capture file in the running Agent",
+	Long: `Inject the datagrams of a capture file, recorded with dogstatsd-capture, in the
+packets pipeline of the dogstatsd server of the running Agent, with their original
+origin. The datagrams are replayed with their original timing divided by the speed
+factor. The file must be in the capture folder of the Agent, and is replayed in
+the background.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := setupDogstatsdCommand(); err != nil {
+			return err
+		}
+
+		// the file is read by the Agent, which may not run in the same folder
+		path, err := filepath.Abs(dsdReplayFilePath)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Replaying %s in the agent.\n", path)
+		r, err := postDogstatsdCommand("dogstatsd-replay", url.Values{
+			"file":  {path},
+			"speed": {strconv.FormatFloat(dsdReplaySpeed, 'f', -1, 64)},
+		})
+		if err != nil {
+			return err
+		}
+
+		var resp map[string]string
+		if err := json.Unmarshal(r, &resp); err != nil {
+			return fmt.Errorf("unexpected response from the agent: %s", r)
+		}
+		fmt.Printf("Replay of %s started, its outcome is logged by the agent.\n", resp["path"])
+		return nil
+	},
+}
+
+func setupDogstatsdCommand() error {
+	if flagNoColor {
+		color.NoColor = true
+	}
+
+	err := common.SetupConfigWithoutSecrets(confFilePath, "")
+	if err != nil {
+		return fmt.Errorf("unable to set up global agent configuration: %v", err)
+	}
+
+	err = config.SetupLogger(loggerName, config.GetEnvDefault("DD_LOG_LEVEL", "off"), "", "", false, true, false)
+	if err != nil {
+		fmt.Printf("Cannot setup logger, exiting: %v\n", err)
+		return err
+	}
+	return nil
+}
+
+func postDogstatsdCommand(endpoint string, values url.Values) ([]byte, error) {
+	c := util.GetClient(false) // FIX: get certificates right then make this true
+	ipcAddress, err := config.GetIPCAddress()
+	if err != nil {
+		return nil, err
+	}
+	urlstr := fmt.Sprintf("https://%v:%v/agent/%s", ipcAddress, config.Datadog.GetInt("cmd_port"), endpoint)
+
+	// Set session token
+	if err := util.SetAuthToken(); err != nil {
+		return nil, err
+	}
+
+	r, err := util.DoPost(c, urlstr, "application/x-www-form-urlencoded", strings.NewReader(values.Encode()))
+	if err != nil {
+		var errMap = make(map[string]string)
+		json.Unmarshal(r, &errMap) //nolint:errcheck
+		// If the error has been marshalled into a json object, check it and return it properly
+		if e, found := errMap["error"]; found {
+			return nil, fmt.Errorf(e)
+		}
+		fmt.Printf("Could not reach agent: %v \nMake sure the agent is running before running %s and contact support if you continue having issues. \n", err, endpoint)
+		return nil, err
+	}
+	return r, nil
+}
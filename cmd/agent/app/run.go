@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"runtime"
 	"syscall"
+	"time"
 
 	_ "expvar" // Blank import used because this isn't directly used in this file
 	"net/http"
@@ -36,6 +37,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/metadata"
 	"github.com/DataDog/datadog-agent/pkg/metadata/host"
 	"github.com/DataDog/datadog-agent/pkg/pidfile"
+	"github.com/DataDog/datadog-agent/pkg/secrets"
 	"github.com/DataDog/datadog-agent/pkg/serializer"
 	"github.com/DataDog/datadog-agent/pkg/snmp/traps"
 	"github.com/DataDog/datadog-agent/pkg/status/health"
@@ -56,6 +58,7 @@ import (
 	_ "github.com/DataDog/datadog-agent/pkg/collector/corechecks/embed"
 	_ "github.com/DataDog/datadog-agent/pkg/collector/corechecks/net"
 	_ "github.com/DataDog/datadog-agent/pkg/collector/corechecks/nvidia/jetson"
+	_ "github.com/DataDog/datadog-agent/pkg/collector/corechecks/snmp"
 	_ "github.com/DataDog/datadog-agent/pkg/collector/corechecks/system"
 	_ "github.com/DataDog/datadog-agent/pkg/collector/corechecks/systemd"
 
@@ -312,7 +315,8 @@ func StartAgent() error {
 	options := forwarder.NewOptions(keysPerDomain)
 	options.EnabledFeatures = forwarder.SetFeature(options.EnabledFeatures, forwarder.CoreFeatures)
 
-	common.Forwarder = forwarder.NewDefaultForwarder(options)
+	f := forwarder.NewDefaultForwarder(options)
+	common.Forwarder = f
 	log.Debugf("Starting forwarder")
 	common.Forwarder.Start() //nolint:errcheck
 	log.Debugf("Forwarder started")
@@ -374,6 +378,22 @@ func StartAgent() error {
 	// start the autoconfig, this will immediately run any configured check
 	common.StartAutoConfig()
 
+	// refresh the secrets periodically and apply the new values to the API keys and the checks
+	if refreshInterval := config.Datadog.GetInt("secret_refresh_interval"); refreshInterval > 0 {
+		secrets.RegisterRefreshCallback(func(changes []secrets.SecretChange) {
+			for _, change := range changes {
+				if change.Handle != config.APIKeySecretHandle() {
+					continue
+				}
+				newKey := config.SanitizeAPIKey(change.NewValue)
+				config.Datadog.Set("api_key", newKey)
+				f.UpdateAPIKey(config.SanitizeAPIKey(change.OldValue), newKey)
+			}
+		})
+		secrets.RegisterRefreshCallback(common.AC.ProcessSecretChanges)
+		secrets.StartRefresh(time.Duration(refreshInterval) * time.Second)
+	}
+
 	// check for common misconfigurations and report them to log
 	misconfig.ToLog()
 
@@ -409,6 +429,8 @@ func StopAgent() {
 	// gracefully shut down any component
 	common.MainCtxCancel()
 
+	secrets.StopRefresh()
+
 	if common.DSD != nil {
 		common.DSD.Stop()
 	}
@@ -29,6 +29,11 @@ func init() {
 	troubleshootLogsCmd.Flags().StringVar(&filters.Name, "name", "", "Filter by name")
 	troubleshootLogsCmd.Flags().StringVar(&filters.Type, "type", "", "Filter by type")
 	troubleshootLogsCmd.Flags().StringVar(&filters.Source, "source", "", "Filter by source")
+	troubleshootLogsCmd.Flags().StringVar(&filters.Service, "service", "", "Filter by service")
+	troubleshootLogsCmd.Flags().StringVar(&filters.Status, "status", "", "Filter by status")
+	troubleshootLogsCmd.Flags().StringSliceVar(&filters.Tags, "tag", nil, "Filter by tag, either a key or a key:value pair, can be repeated to require several tags")
+	troubleshootLogsCmd.Flags().StringVar(&filters.Content, "content", "", "Filter by a regular expression matching the content of the logs")
+	troubleshootLogsCmd.Flags().StringSliceVar(&filters.Stages, "stage", nil, "Stages of the pipeline to stream the logs at: tailer (before the processing rules), processed (default) or encoded")
 }
 
 var troubleshootLogsCmd = &cobra.Command{
@@ -52,6 +57,10 @@ var troubleshootLogsCmd = &cobra.Command{
 			return err
 		}
 
+		if err := filters.Compile(); err != nil {
+			return err
+		}
+
 		return connectAndStream()
 	},
 }
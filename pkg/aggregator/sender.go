@@ -32,6 +32,7 @@ type Sender interface {
 	Counter(metric string, value float64, hostname string, tags []string)
 	Histogram(metric string, value float64, hostname string, tags []string)
 	Historate(metric string, value float64, hostname string, tags []string)
+	Distribution(metric string, value float64, hostname string, tags []string)
 	ServiceCheck(checkName string, status metrics.ServiceCheckStatus, hostname string, tags []string, message string)
 	HistogramBucket(metric string, value int64, lowerBound, upperBound float64, monotonic bool, hostname string, tags []string)
 	Event(e metrics.Event)
@@ -337,6 +338,11 @@ func (s *checkSender) Historate(metric string, value float64, hostname string, t
 	s.sendMetricSample(metric, value, hostname, tags, metrics.HistorateType, false)
 }
 
+// Distribution should be used to send a distribution value to the aggregator, the values are aggregated in a sketch.
+func (s *checkSender) Distribution(metric string, value float64, hostname string, tags []string) {
+	s.sendMetricSample(metric, value, hostname, tags, metrics.DistributionType, false)
+}
+
 // SendRawServiceCheck sends the raw service check
 // Useful for testing - submitting precomputed service check.
 func (s *checkSender) SendRawServiceCheck(sc *metrics.ServiceCheck) {
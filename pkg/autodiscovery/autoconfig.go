@@ -300,18 +300,80 @@ func (ac *AutoConfig) processNewConfig(config integration.Config) []integration.
 	}
 
 	// decrypt and store non-template config in AC as well
-	config, err := decryptConfig(config)
+	resolvedConfig, err := decryptConfig(config)
 	if err != nil {
 		log.Errorf("Dropping conf for '%s': %s", config.Name, err.Error())
 		return configs
 	}
-	configs = append(configs, config)
+	configs = append(configs, resolvedConfig)
 
-	ac.store.setLoadedConfig(config)
+	ac.setLoadedConfig(resolvedConfig, config)
 
 	return configs
 }
 
+// setLoadedConfig stores a resolved config, along with the config before secrets
+// decryption if it references secrets so that it can be decrypted again when
+// their value change.
+func (ac *AutoConfig) setLoadedConfig(config integration.Config, rawConfig integration.Config) {
+	ac.store.setLoadedConfig(config)
+	if config.Digest() != rawConfig.Digest() {
+		ac.store.setRawConfig(config, rawConfig)
+	}
+}
+
+// ProcessSecretChanges decrypts again the configs referencing secrets whose
+// value changed and reschedules them.
+func (ac *AutoConfig) ProcessSecretChanges(changes []secrets.SecretChange) {
+	origins := make(map[string]bool)
+	for _, change := range changes {
+		for _, origin := range change.Origins {
+			origins[origin] = true
+		}
+	}
+
+	oldConfigs, newConfigs := ac.replaceSecretConfigs(origins)
+	if len(newConfigs) == 0 {
+		return
+	}
+	ac.unschedule(oldConfigs)
+	ac.schedule(newConfigs)
+}
+
+// replaceSecretConfigs decrypts again the configs with the given names and replaces
+// the ones whose secrets changed in the store. It returns the replaced configs and
+// the configs replacing them.
+func (ac *AutoConfig) replaceSecretConfigs(origins map[string]bool) (oldConfigs, newConfigs []integration.Config) {
+	ac.m.Lock()
+	defer ac.m.Unlock()
+
+	for digest, rawConfig := range ac.store.getRawConfigs() {
+		if !origins[rawConfig.Name] {
+			continue
+		}
+		oldConfig, found := ac.store.getLoadedConfig(digest)
+		if !found {
+			continue
+		}
+		newConfig, err := decryptConfig(rawConfig)
+		if err != nil {
+			log.Errorf("Could not decrypt secrets of config '%s', keeping the previous values: %s", rawConfig.Name, err)
+			continue
+		}
+		if newConfig.Digest() == digest {
+			continue
+		}
+
+		log.Infof("Secrets of config '%s' changed, rescheduling it", rawConfig.Name)
+		ac.store.removeLoadedConfig(oldConfig)
+		ac.setLoadedConfig(newConfig, rawConfig)
+		ac.store.replaceConfig(oldConfig, newConfig)
+		oldConfigs = append(oldConfigs, oldConfig)
+		newConfigs = append(newConfigs, newConfig)
+	}
+	return oldConfigs, newConfigs
+}
+
 // AddListeners tries to initialise the listeners listed in the given configs. A first
 // try is done synchronously. If a listener fails with a ErrWillRetry, the initialization
 // will be re-triggered later until success or ErrPermaFail.
@@ -424,6 +486,13 @@ func (ac *AutoConfig) RemoveScheduler(name string) {
 func decryptConfig(conf integration.Config) (integration.Config, error) {
 	var err error
 
+	// decrypt the instances in a copy to keep the original config untouched
+	if conf.Instances != nil {
+		instances := make([]integration.Data, len(conf.Instances))
+		copy(instances, conf.Instances)
+		conf.Instances = instances
+	}
+
 	// init_config
 	conf.InitConfig, err = secretsDecrypt(conf.InitConfig, conf.Name)
 	if err != nil {
@@ -541,7 +610,7 @@ func (ac *AutoConfig) resolveTemplateForService(tpl integration.Config, svc list
 		newErr := fmt.Errorf("error decrypting secrets in config %s for service %s: %v", config.Name, svc.GetEntity(), err)
 		return config, log.Warn(newErr)
 	}
-	ac.store.setLoadedConfig(resolvedConfig)
+	ac.setLoadedConfig(resolvedConfig, config)
 	ac.store.addConfigForService(svc.GetEntity(), resolvedConfig)
 	ac.store.addConfigForTemplate(tpl.Digest(), resolvedConfig)
 	ac.store.setTagsHashForService(
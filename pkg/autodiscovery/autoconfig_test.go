@@ -22,6 +22,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/autodiscovery/providers/names"
 	"github.com/DataDog/datadog-agent/pkg/autodiscovery/scheduler"
 	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/secrets"
 	"github.com/DataDog/datadog-agent/pkg/util/retry"
 )
 
@@ -482,3 +483,61 @@ func TestSecretDecrypt(t *testing.T) {
 
 	assert.True(t, mockDecrypt.haveAllScenariosBeenCalled())
 }
+
+type recordingScheduler struct {
+	scheduled   []integration.Config
+	unscheduled []integration.Config
+}
+
+func (s *recordingScheduler) Schedule(configs []integration.Config) {
+	s.scheduled = append(s.scheduled, configs...)
+}
+
+func (s *recordingScheduler) Unschedule(configs []integration.Config) {
+	s.unscheduled = append(s.unscheduled, configs...)
+}
+
+func (s *recordingScheduler) Stop() {}
+
+func TestProcessSecretChanges(t *testing.T) {
+	password := "password1"
+	originalSecretsDecrypt := secretsDecrypt
+	secretsDecrypt = func(data []byte, origin string) ([]byte, error) {
+		return bytes.Replace(data, []byte("ENC[pass]"), []byte(password), -1), nil
+	}
+	defer func() { secretsDecrypt = originalSecretsDecrypt }()
+
+	ac := NewAutoConfig(scheduler.NewMetaScheduler())
+	sch := &recordingScheduler{}
+	ac.AddScheduler("test", sch, false)
+
+	withSecret := integration.Config{
+		Name:      "postgres",
+		Instances: []integration.Data{integration.Data("password: ENC[pass]")},
+	}
+	withoutSecret := integration.Config{
+		Name:      "cpu",
+		Instances: []integration.Data{integration.Data("{}")},
+	}
+	ac.schedule(ac.processNewConfig(withSecret))
+	ac.schedule(ac.processNewConfig(withoutSecret))
+	require.Len(t, sch.scheduled, 2)
+	// the original config is left untouched
+	assert.Equal(t, integration.Data("password: ENC[pass]"), withSecret.Instances[0])
+
+	// the secret did not change
+	ac.ProcessSecretChanges([]secrets.SecretChange{{Handle: "pass", Origins: []string{"postgres"}}})
+	assert.Len(t, sch.scheduled, 2)
+	assert.Len(t, sch.unscheduled, 0)
+
+	password = "password2"
+	ac.ProcessSecretChanges([]secrets.SecretChange{{Handle: "pass", Origins: []string{"postgres"}, OldValue: "password1", NewValue: "password2"}})
+	require.Len(t, sch.unscheduled, 1)
+	assert.Equal(t, integration.Data("password: password1"), sch.unscheduled[0].Instances[0])
+	require.Len(t, sch.scheduled, 3)
+	assert.Equal(t, integration.Data("password: password2"), sch.scheduled[2].Instances[0])
+
+	loaded := ac.GetLoadedConfigs()
+	assert.Len(t, loaded, 2)
+	assert.Contains(t, loaded, sch.scheduled[2].Digest())
+}
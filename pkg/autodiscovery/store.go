@@ -18,6 +18,7 @@ type store struct {
 	serviceToTagsHash map[string]string
 	templateToConfigs map[string][]integration.Config
 	loadedConfigs     map[string]integration.Config
+	rawConfigs        map[string]integration.Config
 	nameToJMXMetrics  map[string]integration.Data
 	adIDToServices    map[string]map[string]bool
 	entityToService   map[string]listeners.Service
@@ -32,6 +33,7 @@ func newStore() *store {
 		serviceToTagsHash: make(map[string]string),
 		templateToConfigs: make(map[string][]integration.Config),
 		loadedConfigs:     make(map[string]integration.Config),
+		rawConfigs:        make(map[string]integration.Config),
 		nameToJMXMetrics:  make(map[string]integration.Data),
 		adIDToServices:    make(map[string]map[string]bool),
 		entityToService:   make(map[string]listeners.Service),
@@ -121,6 +123,52 @@ func (s *store) removeLoadedConfig(config integration.Config) {
 	s.m.Lock()
 	defer s.m.Unlock()
 	delete(s.loadedConfigs, config.Digest())
+	delete(s.rawConfigs, config.Digest())
+}
+
+// setRawConfig stores the config before secrets decryption of a resolved
+// config, by the digest of the resolved config
+func (s *store) setRawConfig(config integration.Config, rawConfig integration.Config) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.rawConfigs[config.Digest()] = rawConfig
+}
+
+// getRawConfigs returns a copy of the configs before secrets decryption, by
+// the digest of the resolved configs
+func (s *store) getRawConfigs() map[string]integration.Config {
+	s.m.RLock()
+	defer s.m.RUnlock()
+	rawConfigs := make(map[string]integration.Config, len(s.rawConfigs))
+	for digest, config := range s.rawConfigs {
+		rawConfigs[digest] = config
+	}
+	return rawConfigs
+}
+
+// getLoadedConfig returns a loaded config by its digest
+func (s *store) getLoadedConfig(digest string) (integration.Config, bool) {
+	s.m.RLock()
+	defer s.m.RUnlock()
+	config, found := s.loadedConfigs[digest]
+	return config, found
+}
+
+// replaceConfig replaces a resolved config by a new one in the configs of
+// the services and templates
+func (s *store) replaceConfig(oldConfig integration.Config, newConfig integration.Config) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	digest := oldConfig.Digest()
+	for _, configsMap := range []map[string][]integration.Config{s.serviceToConfigs, s.templateToConfigs} {
+		for _, configs := range configsMap {
+			for i, config := range configs {
+				if config.Digest() == digest {
+					configs[i] = newConfig
+				}
+			}
+		}
+	}
 }
 
 // getLoadedConfigs returns all loaded and resolved configs
@@ -0,0 +1,200 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package snmp
+
+import (
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/soniah/gosnmp"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testCommunity = "public"
+	testUser      = "datadog"
+	testEngineID  = "\x80\x00\x1f\x88\x80\x01\x02\x03\x04"
+)
+
+// simulatedAgent answers the GET, GETNEXT and GETBULK requests of the check with
+// a fixed set of values, like snmpsim does
+type simulatedAgent struct {
+	conn *net.UDPConn
+	pdus []gosnmp.SnmpPDU
+
+	mu       sync.Mutex
+	requests []gosnmp.PDUType
+}
+
+func newSimulatedAgent(t *testing.T, pdus []gosnmp.SnmpPDU) *simulatedAgent {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP("127.0.0.1")})
+	require.NoError(t, err)
+
+	sorted := make([]gosnmp.SnmpPDU, len(pdus))
+	copy(sorted, pdus)
+	sort.Slice(sorted, func(i, j int) bool {
+		return compareOIDs(sorted[i].Name, sorted[j].Name) < 0
+	})
+
+	agent := &simulatedAgent{conn: conn, pdus: sorted}
+	go agent.serve()
+	return agent
+}
+
+func (a *simulatedAgent) port() uint16 {
+	return uint16(a.conn.LocalAddr().(*net.UDPAddr).Port)
+}
+
+func (a *simulatedAgent) close() {
+	a.conn.Close()
+}
+
+func (a *simulatedAgent) requestTypes() []gosnmp.PDUType {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]gosnmp.PDUType{}, a.requests...)
+}
+
+func (a *simulatedAgent) serve() {
+	buf := make([]byte, 65535)
+	decoder := &gosnmp.GoSNMP{}
+	for {
+		n, addr, err := a.conn.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+		request, err := decoder.SnmpDecodePacket(buf[:n])
+		if err != nil {
+			continue
+		}
+		response := a.handle(request)
+		if response == nil {
+			continue
+		}
+		out, err := response.MarshalMsg()
+		if err != nil {
+			continue
+		}
+		a.conn.WriteToUDP(out, addr) //nolint:errcheck
+	}
+}
+
+func (a *simulatedAgent) handle(request *gosnmp.SnmpPacket) *gosnmp.SnmpPacket {
+	response := &gosnmp.SnmpPacket{
+		Version:         request.Version,
+		Community:       request.Community,
+		PDUType:         gosnmp.GetResponse,
+		MsgID:           request.MsgID,
+		RequestID:       request.RequestID,
+		MsgFlags:        request.MsgFlags &^ gosnmp.Reportable,
+		SecurityModel:   request.SecurityModel,
+		ContextEngineID: testEngineID,
+		ContextName:     request.ContextName,
+	}
+
+	if request.Version == gosnmp.Version3 {
+		usm := request.SecurityParameters.(*gosnmp.UsmSecurityParameters)
+		if usm.UserName != testUser && usm.UserName != "" {
+			return nil
+		}
+		response.SecurityParameters = &gosnmp.UsmSecurityParameters{
+			UserName:                 usm.UserName,
+			AuthoritativeEngineID:    testEngineID,
+			AuthoritativeEngineBoots: 1,
+			AuthoritativeEngineTime:  1,
+		}
+		if usm.AuthoritativeEngineID == "" {
+			// engine discovery
+			response.PDUType = gosnmp.Report
+			response.Variables = []gosnmp.SnmpPDU{{Name: ".1.3.6.1.6.3.15.1.1.4.0", Type: gosnmp.Counter32, Value: uint32(1)}}
+			return response
+		}
+	} else if request.Community != testCommunity {
+		return nil
+	}
+
+	a.mu.Lock()
+	a.requests = append(a.requests, request.PDUType)
+	a.mu.Unlock()
+
+	switch request.PDUType {
+	case gosnmp.GetRequest:
+		for i, variable := range request.Variables {
+			pdu, ok := a.get(variable.Name)
+			if !ok && request.Version == gosnmp.Version1 {
+				response.Error = gosnmp.NoSuchName
+				response.ErrorIndex = uint8(i + 1)
+				response.Variables = request.Variables
+				return response
+			}
+			response.Variables = append(response.Variables, pdu)
+		}
+	case gosnmp.GetNextRequest:
+		for i, variable := range request.Variables {
+			pdu, ok := a.next(variable.Name)
+			if !ok && request.Version == gosnmp.Version1 {
+				response.Error = gosnmp.NoSuchName
+				response.ErrorIndex = uint8(i + 1)
+				response.Variables = request.Variables
+				return response
+			}
+			response.Variables = append(response.Variables, pdu)
+		}
+	case gosnmp.GetBulkRequest:
+		lastOids := make([]string, len(request.Variables))
+		for i, variable := range request.Variables {
+			lastOids[i] = variable.Name
+		}
+		for r := 0; r < int(request.MaxRepetitions); r++ {
+			for i := range lastOids {
+				pdu, _ := a.next(lastOids[i])
+				lastOids[i] = pdu.Name
+				response.Variables = append(response.Variables, pdu)
+			}
+		}
+	default:
+		return nil
+	}
+	return response
+}
+
+func (a *simulatedAgent) get(oid string) (gosnmp.SnmpPDU, bool) {
+	for _, pdu := range a.pdus {
+		if compareOIDs(pdu.Name, oid) == 0 {
+			return pdu, true
+		}
+	}
+	return gosnmp.SnmpPDU{Name: oid, Type: gosnmp.NoSuchObject}, false
+}
+
+func (a *simulatedAgent) next(oid string) (gosnmp.SnmpPDU, bool) {
+	for _, pdu := range a.pdus {
+		if compareOIDs(pdu.Name, oid) > 0 {
+			return pdu, true
+		}
+	}
+	return gosnmp.SnmpPDU{Name: oid, Type: gosnmp.EndOfMibView}, false
+}
+
+func compareOIDs(a string, b string) int {
+	aParts := strings.Split(normalizeOID(a), ".")
+	bParts := strings.Split(normalizeOID(b), ".")
+	for i := 0; i < len(aParts) && i < len(bParts); i++ {
+		aInt, _ := strconv.Atoi(aParts[i])
+		bInt, _ := strconv.Atoi(bParts[i])
+		if aInt != bInt {
+			if aInt < bInt {
+				return -1
+			}
+			return 1
+		}
+	}
+	return len(aParts) - len(bParts)
+}
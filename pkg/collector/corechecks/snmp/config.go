@@ -0,0 +1,203 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package snmp
+
+import (
+	"fmt"
+
+	"github.com/soniah/gosnmp"
+	"gopkg.in/yaml.v2"
+
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/snmp"
+)
+
+const (
+	defaultPort               = 161
+	defaultTimeout            = 5
+	defaultRetries            = 3
+	defaultOidBatchSize       = 10
+	defaultBulkMaxRepetitions = 10
+)
+
+type snmpInitConfig struct {
+	Profiles           profileConfigMap `yaml:"profiles"`
+	OidBatchSize       int              `yaml:"oid_batch_size"`
+	BulkMaxRepetitions uint8            `yaml:"bulk_max_repetitions"`
+}
+
+// snmpInstanceConfig follows the options of the Python snmp integration, so that
+// the same instances (and autodiscovery templates) can be run by both checks.
+type snmpInstanceConfig struct {
+	IPAddress       string            `yaml:"ip_address"`
+	Port            uint16            `yaml:"port"`
+	SnmpVersion     string            `yaml:"snmp_version"`
+	Timeout         int               `yaml:"timeout"`
+	Retries         int               `yaml:"retries"`
+	CommunityString string            `yaml:"community_string"`
+	User            string            `yaml:"user"`
+	AuthProtocol    string            `yaml:"authProtocol"`
+	AuthKey         string            `yaml:"authKey"`
+	PrivProtocol    string            `yaml:"privProtocol"`
+	PrivKey         string            `yaml:"privKey"`
+	ContextEngineID string            `yaml:"context_engine_id"`
+	ContextName     string            `yaml:"context_name"`
+	Profile         string            `yaml:"profile"`
+	Metrics         []metricsConfig   `yaml:"metrics"`
+	MetricTags      []metricTagConfig `yaml:"metric_tags"`
+	Tags            []string          `yaml:"tags"`
+	OidBatchSize    int               `yaml:"oid_batch_size"`
+
+	// CollectInterfaceMetrics is a pointer to default to true when unset
+	CollectInterfaceMetrics *bool `yaml:"collect_interface_metrics"`
+}
+
+// checkConfig is the configuration of a check instance, once merged with its init_config
+type checkConfig struct {
+	ipAddress               string
+	snmpConfig              snmp.Config
+	instanceMetrics         []metricsConfig
+	instanceMetricTags      []metricTagConfig
+	instanceTags            []string
+	oidBatchSize            int
+	bulkMaxRepetitions      uint8
+	collectInterfaceMetrics bool
+	profiles                profileDefinitionMap
+	profile                 string
+
+	// resolved from the instance configuration and the profile
+	metrics    []metricsConfig
+	metricTags []metricTagConfig
+	tags       []string
+	oidConfig  oidConfig
+}
+
+func buildConfig(rawInstance integration.Data, rawInitConfig integration.Data) (checkConfig, error) {
+	instance := snmpInstanceConfig{}
+	initConfig := snmpInitConfig{}
+
+	if err := yaml.Unmarshal(rawInitConfig, &initConfig); err != nil {
+		return checkConfig{}, err
+	}
+	if err := yaml.Unmarshal(rawInstance, &instance); err != nil {
+		return checkConfig{}, err
+	}
+
+	if instance.IPAddress == "" {
+		return checkConfig{}, fmt.Errorf("ip_address config must be provided")
+	}
+
+	c := checkConfig{
+		ipAddress: instance.IPAddress,
+		snmpConfig: snmp.Config{
+			Port:            instance.Port,
+			Version:         instance.SnmpVersion,
+			Timeout:         instance.Timeout,
+			Retries:         instance.Retries,
+			Community:       instance.CommunityString,
+			User:            instance.User,
+			AuthKey:         instance.AuthKey,
+			AuthProtocol:    instance.AuthProtocol,
+			PrivKey:         instance.PrivKey,
+			PrivProtocol:    instance.PrivProtocol,
+			ContextEngineID: instance.ContextEngineID,
+			ContextName:     instance.ContextName,
+		},
+		instanceMetrics:         instance.Metrics,
+		instanceMetricTags:      instance.MetricTags,
+		instanceTags:            instance.Tags,
+		oidBatchSize:            defaultOidBatchSize,
+		bulkMaxRepetitions:      defaultBulkMaxRepetitions,
+		collectInterfaceMetrics: true,
+		profile:                 instance.Profile,
+	}
+
+	if c.snmpConfig.Port == 0 {
+		c.snmpConfig.Port = defaultPort
+	}
+	if c.snmpConfig.Timeout == 0 {
+		c.snmpConfig.Timeout = defaultTimeout
+	}
+	if c.snmpConfig.Retries == 0 {
+		c.snmpConfig.Retries = defaultRetries
+	}
+	if instance.OidBatchSize != 0 {
+		c.oidBatchSize = instance.OidBatchSize
+	} else if initConfig.OidBatchSize != 0 {
+		c.oidBatchSize = initConfig.OidBatchSize
+	}
+	if initConfig.BulkMaxRepetitions != 0 {
+		c.bulkMaxRepetitions = initConfig.BulkMaxRepetitions
+	}
+	if instance.CollectInterfaceMetrics != nil {
+		c.collectInterfaceMetrics = *instance.CollectInterfaceMetrics
+	}
+
+	// Validate the connection parameters early
+	if _, err := c.buildSNMPParams(); err != nil {
+		return checkConfig{}, err
+	}
+
+	if err := validateMetrics(c.instanceMetrics); err != nil {
+		return checkConfig{}, err
+	}
+
+	profiles, err := loadProfiles(initConfig.Profiles)
+	if err != nil {
+		return checkConfig{}, err
+	}
+	c.profiles = profiles
+
+	if c.profile != "" {
+		if _, ok := c.profiles[c.profile]; !ok {
+			return checkConfig{}, fmt.Errorf("unknown profile '%s'", c.profile)
+		}
+	}
+	c.refreshWithProfile(c.profile)
+
+	return c, nil
+}
+
+// buildSNMPParams returns the GoSNMP params used to query the device
+func (c *checkConfig) buildSNMPParams() (*gosnmp.GoSNMP, error) {
+	params, err := c.snmpConfig.BuildSNMPParams()
+	if err != nil {
+		return nil, err
+	}
+	params.Target = c.ipAddress
+	return params, nil
+}
+
+// needsProfileDetection returns whether the profile of the device must be
+// detected from its sysObjectID
+func (c *checkConfig) needsProfileDetection() bool {
+	return c.profile == "" && len(c.instanceMetrics) == 0 && len(c.profiles) > 0
+}
+
+// refreshWithProfile resolves the metrics, metric tags, tags and OIDs to fetch
+// from the instance configuration and the given profile, if any
+func (c *checkConfig) refreshWithProfile(profile string) {
+	c.profile = profile
+	c.metrics = append([]metricsConfig{}, c.instanceMetrics...)
+	c.metricTags = append([]metricTagConfig{}, c.instanceMetricTags...)
+	c.tags = append([]string{}, c.instanceTags...)
+	c.tags = append(c.tags, "snmp_device:"+c.ipAddress)
+
+	if definition, ok := c.profiles[profile]; ok {
+		c.metrics = append(c.metrics, definition.Metrics...)
+		c.metricTags = append(c.metricTags, definition.MetricTags...)
+		c.tags = append(c.tags, "snmp_profile:"+profile)
+		if definition.Device.Vendor != "" {
+			c.tags = append(c.tags, "device_vendor:"+definition.Device.Vendor)
+		}
+	}
+
+	if c.collectInterfaceMetrics {
+		c.metrics = appendMissingMetrics(c.metrics, interfaceMetrics)
+	}
+
+	c.oidConfig = buildOidConfig(c.metrics, c.metricTags)
+}
@@ -0,0 +1,188 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package snmp
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/soniah/gosnmp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildConfigDefaults(t *testing.T) {
+	setConfdPath(filepath.Join("testdata", "conf.d"))
+
+	config, err := buildConfig([]byte(`
+ip_address: 1.2.3.4
+community_string: public
+tags:
+  - env:prod
+`), []byte(``))
+	require.NoError(t, err)
+
+	assert.Equal(t, defaultOidBatchSize, config.oidBatchSize)
+	assert.Equal(t, uint8(defaultBulkMaxRepetitions), config.bulkMaxRepetitions)
+	assert.True(t, config.collectInterfaceMetrics)
+	assert.Len(t, config.profiles, 3)
+	assert.True(t, config.needsProfileDetection())
+	assert.Equal(t, []string{"env:prod", "snmp_device:1.2.3.4"}, config.tags)
+	// only the interface metrics until the profile is detected
+	assert.Equal(t, interfaceMetrics, config.metrics)
+	assert.Empty(t, config.oidConfig.scalarOids)
+	assert.Contains(t, config.oidConfig.columnOids, "1.3.6.1.2.1.31.1.1.1.1")
+
+	params, err := config.buildSNMPParams()
+	require.NoError(t, err)
+	assert.Equal(t, "1.2.3.4", params.Target)
+	assert.Equal(t, uint16(defaultPort), params.Port)
+	assert.Equal(t, gosnmp.Version2c, params.Version)
+	assert.Equal(t, "public", params.Community)
+	assert.Equal(t, defaultTimeout*time.Second, params.Timeout)
+	assert.Equal(t, defaultRetries, params.Retries)
+}
+
+func TestBuildConfigWithProfile(t *testing.T) {
+	setConfdPath(filepath.Join("testdata", "conf.d"))
+
+	config, err := buildConfig([]byte(`
+ip_address: 1.2.3.4
+port: 1161
+snmp_version: 1
+community_string: public
+timeout: 1
+retries: 2
+oid_batch_size: 5
+profile: cisco
+collect_interface_metrics: false
+metrics:
+  - OID: 1.3.6.1.2.1.6.5.0
+    name: tcpActiveOpens
+`), []byte(`
+bulk_max_repetitions: 20
+oid_batch_size: 50
+`))
+	require.NoError(t, err)
+
+	assert.Equal(t, 5, config.oidBatchSize)
+	assert.Equal(t, uint8(20), config.bulkMaxRepetitions)
+	assert.False(t, config.needsProfileDetection())
+	assert.Equal(t, []string{"snmp_device:1.2.3.4", "snmp_profile:cisco", "device_vendor:cisco"}, config.tags)
+	// instance metrics come first, and there is no interface metric
+	require.Len(t, config.metrics, 4)
+	assert.Equal(t, "tcpActiveOpens", config.metrics[0].Name)
+	assert.Equal(t, []string{
+		"1.3.6.1.2.1.1.3.0",
+		"1.3.6.1.2.1.1.5.0",
+		"1.3.6.1.2.1.6.5.0",
+		"1.3.6.1.4.1.9.9.221.1.1.1.1.18.1.1",
+	}, config.oidConfig.scalarOids)
+	assert.Equal(t, []string{
+		"1.3.6.1.4.1.9.9.109.1.1.1.1.2",
+		"1.3.6.1.4.1.9.9.109.1.1.1.1.7",
+	}, config.oidConfig.columnOids)
+
+	params, err := config.buildSNMPParams()
+	require.NoError(t, err)
+	assert.Equal(t, uint16(1161), params.Port)
+	assert.Equal(t, gosnmp.Version1, params.Version)
+	assert.Equal(t, time.Second, params.Timeout)
+	assert.Equal(t, 2, params.Retries)
+}
+
+func TestBuildConfigV3(t *testing.T) {
+	setConfdPath(filepath.Join("testdata", "conf.d"))
+
+	config, err := buildConfig([]byte(`
+ip_address: 1.2.3.4
+user: datadog
+authProtocol: sha
+authKey: authpass
+privProtocol: aes
+privKey: privpass
+context_name: ctx
+`), []byte(``))
+	require.NoError(t, err)
+
+	params, err := config.buildSNMPParams()
+	require.NoError(t, err)
+	assert.Equal(t, gosnmp.Version3, params.Version)
+	assert.Equal(t, gosnmp.AuthPriv, params.MsgFlags)
+	assert.Equal(t, "ctx", params.ContextName)
+	usm := params.SecurityParameters.(*gosnmp.UsmSecurityParameters)
+	assert.Equal(t, "datadog", usm.UserName)
+	assert.Equal(t, gosnmp.SHA, usm.AuthenticationProtocol)
+	assert.Equal(t, gosnmp.AES, usm.PrivacyProtocol)
+}
+
+func TestBuildConfigErrors(t *testing.T) {
+	setConfdPath(filepath.Join("testdata", "conf.d"))
+
+	tests := []struct {
+		name     string
+		instance string
+		err      string
+	}{
+		{
+			"missing ip address",
+			`community_string: public`,
+			"ip_address config must be provided",
+		},
+		{
+			"missing credentials",
+			`ip_address: 1.2.3.4`,
+			"No authentication mechanism specified",
+		},
+		{
+			"unsupported version",
+			"ip_address: 1.2.3.4\ncommunity_string: public\nsnmp_version: 4",
+			"SNMP version not supported: 4",
+		},
+		{
+			"unknown profile",
+			"ip_address: 1.2.3.4\ncommunity_string: public\nprofile: foo",
+			"unknown profile 'foo'",
+		},
+		{
+			"unsupported forced type",
+			"ip_address: 1.2.3.4\ncommunity_string: public\nmetrics:\n- OID: 1.2.3\n  name: foo\n  forced_type: histogram",
+			"unsupported forced_type 'histogram'",
+		},
+		{
+			"table tag without index nor column",
+			"ip_address: 1.2.3.4\ncommunity_string: public\nmetrics:\n- table: {OID: 1.2.3, name: foo}\n  symbols: [{OID: 1.2.3.1.1, name: bar}]\n  metric_tags: [{tag: baz}]",
+			"table metric tag 'baz' must have an index or a column OID",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := buildConfig([]byte(test.instance), []byte(``))
+			assert.EqualError(t, err, test.err)
+		})
+	}
+}
+
+func TestAppendMissingMetrics(t *testing.T) {
+	metrics := []metricsConfig{
+		{OID: "1.3.6.1.2.1.1.3.0", Name: "sysUpTimeInstance"},
+		{
+			Table:   symbolConfig{OID: "1.3.6.1.2.1.31.1.1", Name: "ifXTable"},
+			Symbols: []symbolConfig{{OID: "1.3.6.1.2.1.31.1.1.1.6", Name: "ifHCInOctets"}},
+		},
+	}
+	metrics = appendMissingMetrics(metrics, interfaceMetrics)
+
+	require.Len(t, metrics, 2+len(interfaceMetrics))
+	// ifHCInOctets is not collected twice
+	assert.Equal(t, []symbolConfig{
+		{OID: "1.3.6.1.2.1.31.1.1.1.7", Name: "ifHCInUcastPkts"},
+		{OID: "1.3.6.1.2.1.31.1.1.1.10", Name: "ifHCOutOctets"},
+		{OID: "1.3.6.1.2.1.31.1.1.1.11", Name: "ifHCOutUcastPkts"},
+	}, metrics[4].Symbols)
+	assert.Len(t, interfaceMetrics[2].Symbols, 4)
+}
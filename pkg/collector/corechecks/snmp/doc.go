@@ -0,0 +1,14 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+/*
+Package snmp provides a core check polling network devices over SNMP
+
+The check is selected over the Python snmp integration by setting `loader: core`
+in its instances or init_config. It collects the metrics defined in the instance
+configuration and in the device profile, either set explicitly or detected from
+the sysObjectID of the device.
+*/
+package snmp
@@ -0,0 +1,164 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package snmp
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/soniah/gosnmp"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const sysObjectIDOid = "1.3.6.1.2.1.1.2.0"
+
+// fetchValues fetches the scalar OIDs with GET requests and walks the columns
+// with GETBULK requests (GETNEXT with SNMP v1)
+func fetchValues(session *gosnmp.GoSNMP, config oidConfig, batchSize int, bulkMaxRepetitions uint8) (*valueStore, error) {
+	store := newValueStore()
+	for _, batch := range createBatches(config.scalarOids, batchSize) {
+		if err := fetchScalarOids(session, batch, store); err != nil {
+			return nil, err
+		}
+	}
+	for _, batch := range createBatches(config.columnOids, batchSize) {
+		if err := fetchColumnOids(session, batch, bulkMaxRepetitions, store); err != nil {
+			return nil, err
+		}
+	}
+	return store, nil
+}
+
+func fetchSysObjectID(session *gosnmp.GoSNMP) (string, error) {
+	store := newValueStore()
+	if err := fetchScalarOids(session, []string{sysObjectIDOid}, store); err != nil {
+		return "", err
+	}
+	value, ok := store.getScalarValue(sysObjectIDOid)
+	if !ok {
+		return "", fmt.Errorf("sysObjectID not found")
+	}
+	return value.toString(), nil
+}
+
+func fetchScalarOids(session *gosnmp.GoSNMP, oids []string, store *valueStore) error {
+	result, err := session.Get(oids)
+	if err != nil {
+		return fmt.Errorf("error getting oids %v: %s", oids, err)
+	}
+	if result.Error == gosnmp.NoSuchName && session.Version == gosnmp.Version1 && len(oids) > 1 {
+		// SNMP v1 agents fail the whole request when one of the OIDs does not
+		// exist, so the OIDs are fetched one by one
+		for _, oid := range oids {
+			if err := fetchScalarOids(session, []string{oid}, store); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if result.Error != gosnmp.NoError {
+		log.Debugf("Error getting oids %v: %s", oids, result.Error)
+		return nil
+	}
+	for _, variable := range result.Variables {
+		if value, ok := getValueFromPDU(variable); ok {
+			store.scalarValues[normalizeOID(variable.Name)] = value
+		}
+	}
+	return nil
+}
+
+func fetchColumnOids(session *gosnmp.GoSNMP, columns []string, bulkMaxRepetitions uint8, store *valueStore) error {
+	// last OID fetched for each column still being walked
+	lastOids := make(map[string]string, len(columns))
+	for _, column := range columns {
+		lastOids[column] = column
+	}
+
+	for len(lastOids) > 0 {
+		requestColumns := make([]string, 0, len(lastOids))
+		requestOids := make([]string, 0, len(lastOids))
+		for _, column := range columns {
+			if lastOid, ok := lastOids[column]; ok {
+				requestColumns = append(requestColumns, column)
+				requestOids = append(requestOids, lastOid)
+			}
+		}
+
+		var result *gosnmp.SnmpPacket
+		var err error
+		if session.Version == gosnmp.Version1 {
+			result, err = session.GetNext(requestOids)
+		} else {
+			result, err = session.GetBulk(requestOids, 0, bulkMaxRepetitions)
+		}
+		if err != nil {
+			return fmt.Errorf("error walking columns %v: %s", requestColumns, err)
+		}
+		if result.Error == gosnmp.NoSuchName && session.Version == gosnmp.Version1 {
+			// SNMP v1 agents report the end of the MIB view as an error on the
+			// corresponding OID
+			errorIndex := int(result.ErrorIndex) - 1
+			if errorIndex < 0 || errorIndex >= len(requestColumns) {
+				return fmt.Errorf("error walking columns %v: %s", requestColumns, result.Error)
+			}
+			delete(lastOids, requestColumns[errorIndex])
+			continue
+		}
+		if result.Error != gosnmp.NoError {
+			return fmt.Errorf("error walking columns %v: %s", requestColumns, result.Error)
+		}
+
+		// variables are returned by repetition, each one with a variable per column
+		advanced := make(map[string]bool, len(requestColumns))
+		done := make(map[string]bool, len(requestColumns))
+		for i, variable := range result.Variables {
+			column := requestColumns[i%len(requestColumns)]
+			if done[column] {
+				continue
+			}
+			oid := normalizeOID(variable.Name)
+			if variable.Type == gosnmp.EndOfMibView || variable.Type == gosnmp.NoSuchObject ||
+				variable.Type == gosnmp.NoSuchInstance || !strings.HasPrefix(oid, column+".") {
+				done[column] = true
+				continue
+			}
+			index := oid[len(column)+1:]
+			if _, seen := store.columnValues[column][index]; seen || oid == lastOids[column] {
+				// the agent is not returning increasing OIDs
+				done[column] = true
+				continue
+			}
+			if value, ok := getValueFromPDU(variable); ok {
+				store.setColumnValue(column, index, value)
+			}
+			lastOids[column] = oid
+			advanced[column] = true
+		}
+		for _, column := range requestColumns {
+			if done[column] || !advanced[column] {
+				delete(lastOids, column)
+			}
+		}
+	}
+	return nil
+}
+
+func createBatches(oids []string, batchSize int) [][]string {
+	if batchSize <= 0 {
+		batchSize = len(oids)
+	}
+	var batches [][]string
+	for start := 0; start < len(oids); start += batchSize {
+		end := start + batchSize
+		if end > len(oids) {
+			end = len(oids)
+		}
+		batches = append(batches, oids[start:end])
+	}
+	return batches
+}
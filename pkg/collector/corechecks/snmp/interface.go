@@ -0,0 +1,61 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package snmp
+
+// interfaceMetricTags tags the interface metrics with the name and alias of the interface,
+// ifXTable and ifTable sharing the same ifIndex
+var interfaceMetricTags = []metricTagConfig{
+	{Tag: "interface", Column: symbolConfig{OID: "1.3.6.1.2.1.31.1.1.1.1", Name: "ifName"}},
+	{Tag: "interface_alias", Column: symbolConfig{OID: "1.3.6.1.2.1.31.1.1.1.18", Name: "ifAlias"}},
+}
+
+// interfaceMetrics are collected on every device unless collect_interface_metrics
+// is disabled, on top of the metrics of the instance and of the profile
+var interfaceMetrics = []metricsConfig{
+	{
+		MIB:   "IF-MIB",
+		Table: symbolConfig{OID: "1.3.6.1.2.1.2.2", Name: "ifTable"},
+		Symbols: []symbolConfig{
+			{OID: "1.3.6.1.2.1.2.2.1.13", Name: "ifInDiscards"},
+			{OID: "1.3.6.1.2.1.2.2.1.14", Name: "ifInErrors"},
+			{OID: "1.3.6.1.2.1.2.2.1.19", Name: "ifOutDiscards"},
+			{OID: "1.3.6.1.2.1.2.2.1.20", Name: "ifOutErrors"},
+		},
+		ForcedType: "monotonic_count_and_rate",
+		MetricTags: interfaceMetricTags,
+	},
+	{
+		MIB:   "IF-MIB",
+		Table: symbolConfig{OID: "1.3.6.1.2.1.2.2", Name: "ifTable"},
+		Symbols: []symbolConfig{
+			{OID: "1.3.6.1.2.1.2.2.1.7", Name: "ifAdminStatus"},
+			{OID: "1.3.6.1.2.1.2.2.1.8", Name: "ifOperStatus"},
+		},
+		ForcedType: "gauge",
+		MetricTags: interfaceMetricTags,
+	},
+	{
+		MIB:   "IF-MIB",
+		Table: symbolConfig{OID: "1.3.6.1.2.1.31.1.1", Name: "ifXTable"},
+		Symbols: []symbolConfig{
+			{OID: "1.3.6.1.2.1.31.1.1.1.6", Name: "ifHCInOctets"},
+			{OID: "1.3.6.1.2.1.31.1.1.1.7", Name: "ifHCInUcastPkts"},
+			{OID: "1.3.6.1.2.1.31.1.1.1.10", Name: "ifHCOutOctets"},
+			{OID: "1.3.6.1.2.1.31.1.1.1.11", Name: "ifHCOutUcastPkts"},
+		},
+		ForcedType: "monotonic_count_and_rate",
+		MetricTags: interfaceMetricTags,
+	},
+	{
+		MIB:   "IF-MIB",
+		Table: symbolConfig{OID: "1.3.6.1.2.1.31.1.1", Name: "ifXTable"},
+		Symbols: []symbolConfig{
+			{OID: "1.3.6.1.2.1.31.1.1.1.15", Name: "ifHighSpeed"},
+		},
+		ForcedType: "gauge",
+		MetricTags: interfaceMetricTags,
+	},
+}
@@ -0,0 +1,180 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package snmp
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type symbolConfig struct {
+	OID  string `yaml:"OID"`
+	Name string `yaml:"name"`
+}
+
+// metricTagConfig is either a tag of a table metric, whose value is a column or an
+// index component of the row, or a global tag whose value is a scalar OID
+type metricTagConfig struct {
+	Tag string `yaml:"tag"`
+
+	// table metric tags
+	Index  uint         `yaml:"index"`
+	Column symbolConfig `yaml:"column"`
+
+	// global metric tags
+	OID    string `yaml:"OID"`
+	Symbol string `yaml:"symbol"`
+}
+
+// metricsConfig is either a scalar metric (symbol, or OID and name) or a
+// table metric (table and symbols)
+type metricsConfig struct {
+	MIB string `yaml:"MIB"`
+
+	// scalar metric
+	Symbol symbolConfig `yaml:"symbol"`
+	OID    string       `yaml:"OID"`
+	Name   string       `yaml:"name"`
+
+	// table metric
+	Table      symbolConfig      `yaml:"table"`
+	Symbols    []symbolConfig    `yaml:"symbols"`
+	MetricTags []metricTagConfig `yaml:"metric_tags"`
+
+	ForcedType string `yaml:"forced_type"`
+}
+
+func (m *metricsConfig) isTable() bool {
+	return len(m.Symbols) > 0
+}
+
+// scalarSymbol returns the symbol of a scalar metric, using either of the
+// supported syntaxes
+func (m *metricsConfig) scalarSymbol() symbolConfig {
+	if m.Symbol.OID != "" {
+		return m.Symbol
+	}
+	return symbolConfig{OID: m.OID, Name: m.Name}
+}
+
+var supportedForcedTypes = map[string]bool{
+	"":                         true,
+	"gauge":                    true,
+	"counter":                  true,
+	"percent":                  true,
+	"monotonic_count":          true,
+	"monotonic_count_and_rate": true,
+}
+
+func validateMetrics(metrics []metricsConfig) error {
+	for i := range metrics {
+		metric := &metrics[i]
+		if !supportedForcedTypes[metric.ForcedType] {
+			return fmt.Errorf("unsupported forced_type '%s'", metric.ForcedType)
+		}
+		if metric.isTable() {
+			for _, symbol := range metric.Symbols {
+				if symbol.OID == "" || symbol.Name == "" {
+					return fmt.Errorf("table symbols must have an OID and a name: %+v", symbol)
+				}
+			}
+			for _, tag := range metric.MetricTags {
+				if tag.Tag == "" {
+					return fmt.Errorf("table metric tags must have a tag name: %+v", tag)
+				}
+				if tag.Index == 0 && tag.Column.OID == "" {
+					return fmt.Errorf("table metric tag '%s' must have an index or a column OID", tag.Tag)
+				}
+			}
+			continue
+		}
+		symbol := metric.scalarSymbol()
+		if symbol.OID == "" || symbol.Name == "" {
+			return fmt.Errorf("metrics must have either a symbol, an OID and a name, or symbols: %+v", *metric)
+		}
+	}
+	return nil
+}
+
+// oidConfig lists the OIDs to fetch, scalars with a GET and columns by walking
+// the tables
+type oidConfig struct {
+	scalarOids []string
+	columnOids []string
+}
+
+func buildOidConfig(metrics []metricsConfig, metricTags []metricTagConfig) oidConfig {
+	scalars := map[string]bool{}
+	columns := map[string]bool{}
+	for _, metric := range metrics {
+		if metric.isTable() {
+			for _, symbol := range metric.Symbols {
+				columns[normalizeOID(symbol.OID)] = true
+			}
+			for _, tag := range metric.MetricTags {
+				if tag.Column.OID != "" {
+					columns[normalizeOID(tag.Column.OID)] = true
+				}
+			}
+			continue
+		}
+		scalars[normalizeOID(metric.scalarSymbol().OID)] = true
+	}
+	for _, tag := range metricTags {
+		if tag.OID != "" {
+			scalars[normalizeOID(tag.OID)] = true
+		}
+	}
+	return oidConfig{
+		scalarOids: sortedKeys(scalars),
+		columnOids: sortedKeys(columns),
+	}
+}
+
+// appendMissingMetrics appends the symbols of the extra metrics that are not
+// already collected by the metrics
+func appendMissingMetrics(metrics []metricsConfig, extraMetrics []metricsConfig) []metricsConfig {
+	collected := map[string]bool{}
+	for _, metric := range metrics {
+		if !metric.isTable() {
+			collected[normalizeOID(metric.scalarSymbol().OID)] = true
+			continue
+		}
+		for _, symbol := range metric.Symbols {
+			collected[normalizeOID(symbol.OID)] = true
+		}
+	}
+
+	for _, extra := range extraMetrics {
+		var symbols []symbolConfig
+		for _, symbol := range extra.Symbols {
+			if !collected[normalizeOID(symbol.OID)] {
+				symbols = append(symbols, symbol)
+			}
+		}
+		if len(symbols) == 0 {
+			continue
+		}
+		extra.Symbols = symbols
+		metrics = append(metrics, extra)
+	}
+	return metrics
+}
+
+// normalizeOID removes the leading dot of the OIDs returned by gosnmp
+func normalizeOID(oid string) string {
+	return strings.TrimPrefix(oid, ".")
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,211 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package snmp
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+type profileConfig struct {
+	DefinitionFile string            `yaml:"definition_file"`
+	Definition     profileDefinition `yaml:"definition"`
+}
+
+type profileConfigMap map[string]profileConfig
+
+type deviceMeta struct {
+	Vendor string `yaml:"vendor"`
+}
+
+type profileDefinition struct {
+	Metrics      []metricsConfig   `yaml:"metrics"`
+	MetricTags   []metricTagConfig `yaml:"metric_tags"`
+	Extends      []string          `yaml:"extends"`
+	Device       deviceMeta        `yaml:"device"`
+	SysObjectIds stringList        `yaml:"sysobjectid"`
+}
+
+type profileDefinitionMap map[string]profileDefinition
+
+// stringList unmarshals either a single string or a list of strings
+type stringList []string
+
+func (l *stringList) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var single string
+	if err := unmarshal(&single); err == nil {
+		*l = stringList{single}
+		return nil
+	}
+	var list []string
+	if err := unmarshal(&list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+func getProfilesRoot() string {
+	return filepath.Join(config.Datadog.GetString("confd_path"), "snmp.d", "profiles")
+}
+
+// resolveProfileDefinitionPath returns the absolute path of a definition file,
+// relative paths being relative to the profiles folder
+func resolveProfileDefinitionPath(definitionFile string) string {
+	if filepath.IsAbs(definitionFile) {
+		return definitionFile
+	}
+	return filepath.Join(getProfilesRoot(), definitionFile)
+}
+
+// loadProfiles returns the profiles configured in init_config, or the ones
+// found in the profiles folder if none is configured
+func loadProfiles(profilesConfig profileConfigMap) (profileDefinitionMap, error) {
+	if len(profilesConfig) == 0 {
+		var err error
+		profilesConfig, err = getDefaultProfilesConfig()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	profiles := make(profileDefinitionMap, len(profilesConfig))
+	for name, profConfig := range profilesConfig {
+		definition := profConfig.Definition
+		if profConfig.DefinitionFile != "" {
+			var err error
+			definition, err = readProfileDefinition(profConfig.DefinitionFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read profile '%s': %s", name, err)
+			}
+		}
+		if err := resolveExtends(&definition, []string{}); err != nil {
+			return nil, fmt.Errorf("failed to resolve profile '%s': %s", name, err)
+		}
+		if err := validateMetrics(definition.Metrics); err != nil {
+			return nil, fmt.Errorf("invalid profile '%s': %s", name, err)
+		}
+		profiles[name] = definition
+	}
+	return profiles, nil
+}
+
+// getDefaultProfilesConfig lists the profiles in the profiles folder, files
+// starting with an underscore being only usable as base profiles
+func getDefaultProfilesConfig() (profileConfigMap, error) {
+	profiles := make(profileConfigMap)
+	files, err := ioutil.ReadDir(getProfilesRoot())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return profiles, nil
+		}
+		return nil, err
+	}
+	for _, f := range files {
+		fileName := f.Name()
+		if f.IsDir() || strings.HasPrefix(fileName, "_") {
+			continue
+		}
+		ext := filepath.Ext(fileName)
+		if ext != ".yaml" && ext != ".yml" {
+			continue
+		}
+		profiles[strings.TrimSuffix(fileName, ext)] = profileConfig{DefinitionFile: fileName}
+	}
+	return profiles, nil
+}
+
+func readProfileDefinition(definitionFile string) (profileDefinition, error) {
+	definition := profileDefinition{}
+	buf, err := ioutil.ReadFile(resolveProfileDefinitionPath(definitionFile))
+	if err != nil {
+		return definition, err
+	}
+	if err := yaml.Unmarshal(buf, &definition); err != nil {
+		return definition, fmt.Errorf("parse error in file %s: %s", definitionFile, err)
+	}
+	return definition, nil
+}
+
+// resolveExtends merges the metrics and metric tags of the extended base
+// profiles into the definition
+func resolveExtends(definition *profileDefinition, extendsHistory []string) error {
+	for _, baseFile := range definition.Extends {
+		for _, seen := range extendsHistory {
+			if seen == baseFile {
+				return fmt.Errorf("cyclic profile extend detected, '%s' has already been extended", baseFile)
+			}
+		}
+		baseDefinition, err := readProfileDefinition(baseFile)
+		if err != nil {
+			return err
+		}
+		if err := resolveExtends(&baseDefinition, append(extendsHistory, baseFile)); err != nil {
+			return err
+		}
+		definition.Metrics = append(definition.Metrics, baseDefinition.Metrics...)
+		definition.MetricTags = append(definition.MetricTags, baseDefinition.MetricTags...)
+	}
+	definition.Extends = nil
+	return nil
+}
+
+// getProfileForSysObjectID returns the profile matching the sysObjectID, the
+// most specific pattern winning when several profiles match
+func getProfileForSysObjectID(profiles profileDefinitionMap, sysObjectID string) (string, error) {
+	var matchedProfile, matchedPattern string
+	for name, definition := range profiles {
+		for _, pattern := range definition.SysObjectIds {
+			found, err := path.Match(pattern, sysObjectID)
+			if err != nil {
+				log.Debugf("Invalid sysObjectID pattern '%s' in profile '%s': %s", pattern, name, err)
+				continue
+			}
+			if !found {
+				continue
+			}
+			if matchedProfile == "" || isMoreSpecificPattern(pattern, matchedPattern) ||
+				// keep the result stable when the same pattern is in several profiles
+				(pattern == matchedPattern && name < matchedProfile) {
+				matchedProfile, matchedPattern = name, pattern
+			}
+		}
+	}
+	if matchedProfile == "" {
+		return "", fmt.Errorf("no profile found for sysObjectID %s", sysObjectID)
+	}
+	return matchedProfile, nil
+}
+
+// isMoreSpecificPattern returns whether the pattern matches fewer OIDs than
+// the other one: the more OID components the better, exact patterns winning
+// over wildcards
+func isMoreSpecificPattern(pattern string, other string) bool {
+	patternParts, otherParts := strings.Split(pattern, "."), strings.Split(other, ".")
+	if len(patternParts) != len(otherParts) {
+		return len(patternParts) > len(otherParts)
+	}
+	for i := range patternParts {
+		if patternParts[i] == otherParts[i] {
+			continue
+		}
+		patternWildcard := strings.ContainsAny(patternParts[i], "*?[")
+		otherWildcard := strings.ContainsAny(otherParts[i], "*?[")
+		if patternWildcard != otherWildcard {
+			return otherWildcard
+		}
+	}
+	return false
+}
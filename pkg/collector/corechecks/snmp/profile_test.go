@@ -0,0 +1,123 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package snmp
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+)
+
+func setConfdPath(confdPath string) {
+	mockConfig := config.Mock()
+	mockConfig.Set("confd_path", confdPath)
+}
+
+func TestLoadDefaultProfiles(t *testing.T) {
+	setConfdPath(filepath.Join("testdata", "conf.d"))
+
+	profiles, err := loadProfiles(nil)
+	require.NoError(t, err)
+
+	// base profiles are not loaded
+	require.Len(t, profiles, 3)
+	require.Contains(t, profiles, "cisco")
+	require.Contains(t, profiles, "cisco-asa")
+	require.Contains(t, profiles, "generic-device")
+
+	cisco := profiles["cisco"]
+	assert.Equal(t, "cisco", cisco.Device.Vendor)
+	assert.Equal(t, stringList{"1.3.6.1.4.1.9.1.*"}, cisco.SysObjectIds)
+	assert.Empty(t, cisco.Extends)
+	// metrics of the extended profiles are appended, recursively
+	require.Len(t, cisco.Metrics, 3)
+	assert.Equal(t, "cempMemPoolHCUsed", cisco.Metrics[0].Symbol.Name)
+	assert.Equal(t, "cpmCPUTotalTable", cisco.Metrics[1].Table.Name)
+	assert.Equal(t, "sysUpTimeInstance", cisco.Metrics[2].Symbol.Name)
+	require.Len(t, cisco.MetricTags, 1)
+	assert.Equal(t, "snmp_host", cisco.MetricTags[0].Tag)
+
+	assert.Equal(t, stringList{"1.3.6.1.4.1.*", "1.3.6.1.4.1.9.*"}, profiles["generic-device"].SysObjectIds)
+}
+
+func TestLoadDefaultProfilesMissingFolder(t *testing.T) {
+	setConfdPath(filepath.Join("testdata", "does-not-exist"))
+
+	profiles, err := loadProfiles(nil)
+	require.NoError(t, err)
+	assert.Empty(t, profiles)
+}
+
+func TestLoadConfiguredProfiles(t *testing.T) {
+	setConfdPath(filepath.Join("testdata", "conf.d"))
+
+	profiles, err := loadProfiles(profileConfigMap{
+		"from-file": {DefinitionFile: "cisco-asa.yaml"},
+		"inline": {Definition: profileDefinition{
+			Extends:      []string{"_base.yaml"},
+			SysObjectIds: stringList{"1.3.6.1.4.1.8072.*"},
+		}},
+	})
+	require.NoError(t, err)
+
+	// the configured profiles replace the default ones
+	require.Len(t, profiles, 2)
+	assert.Equal(t, stringList{"1.3.6.1.4.1.9.1.1795"}, profiles["from-file"].SysObjectIds)
+	require.Len(t, profiles["inline"].Metrics, 1)
+	assert.Equal(t, "sysUpTimeInstance", profiles["inline"].Metrics[0].Symbol.Name)
+}
+
+func TestLoadProfilesErrors(t *testing.T) {
+	setConfdPath(filepath.Join("testdata", "conf.d"))
+
+	_, err := loadProfiles(profileConfigMap{"missing": {DefinitionFile: "missing.yaml"}})
+	assert.Error(t, err)
+
+	_, err = loadProfiles(profileConfigMap{"cycle": {Definition: profileDefinition{Extends: []string{"_cycle_a.yaml"}}}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cyclic profile extend detected, '_cycle_a.yaml' has already been extended")
+
+	_, err = loadProfiles(profileConfigMap{"invalid": {Definition: profileDefinition{
+		Metrics: []metricsConfig{{Symbol: symbolConfig{OID: "1.2.3"}}},
+	}}})
+	assert.Error(t, err)
+}
+
+func TestGetProfileForSysObjectID(t *testing.T) {
+	setConfdPath(filepath.Join("testdata", "conf.d"))
+	profiles, err := loadProfiles(nil)
+	require.NoError(t, err)
+
+	tests := []struct {
+		sysObjectID string
+		profile     string
+	}{
+		{"1.3.6.1.4.1.9.1.5", "cisco"},
+		{"1.3.6.1.4.1.9.1.1795", "cisco-asa"},
+		{"1.3.6.1.4.1.9.2.1", "generic-device"},
+		{"1.3.6.1.4.1.8072.3.2.10", "generic-device"},
+	}
+	for _, test := range tests {
+		profile, err := getProfileForSysObjectID(profiles, test.sysObjectID)
+		assert.NoError(t, err, test.sysObjectID)
+		assert.Equal(t, test.profile, profile, test.sysObjectID)
+	}
+
+	_, err = getProfileForSysObjectID(profiles, "1.3.6.1.2.1.1")
+	assert.EqualError(t, err, "no profile found for sysObjectID 1.3.6.1.2.1.1")
+}
+
+func TestIsMoreSpecificPattern(t *testing.T) {
+	assert.True(t, isMoreSpecificPattern("1.3.6.1.4.1.9.1.*", "1.3.6.1.4.1.9.*"))
+	assert.True(t, isMoreSpecificPattern("1.3.6.1.4.1.9.1.5", "1.3.6.1.4.1.9.1.*"))
+	assert.False(t, isMoreSpecificPattern("1.3.6.1.4.1.9.1.*", "1.3.6.1.4.1.9.1.5"))
+	assert.False(t, isMoreSpecificPattern("1.3.6.1.4.1.9.*", "1.3.6.1.4.1.9.1.*"))
+	assert.False(t, isMoreSpecificPattern("1.3.6.1.4.1.9.1.5", "1.3.6.1.4.1.9.1.5"))
+}
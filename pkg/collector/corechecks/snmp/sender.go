@@ -0,0 +1,101 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package snmp
+
+import (
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const metricPrefix = "snmp."
+
+func sendMetrics(sender aggregator.Sender, metrics []metricsConfig, values *valueStore, tags []string) {
+	for _, metric := range metrics {
+		if !metric.isTable() {
+			symbol := metric.scalarSymbol()
+			value, ok := values.getScalarValue(symbol.OID)
+			if !ok {
+				log.Debugf("No value for scalar OID %s (%s)", symbol.OID, symbol.Name)
+				continue
+			}
+			sendMetric(sender, symbol.Name, value, tags, metric.ForcedType)
+			continue
+		}
+		for _, symbol := range metric.Symbols {
+			for index, value := range values.getColumnValues(symbol.OID) {
+				rowTags := append(copyTags(tags), getRowTags(metric.MetricTags, index, values)...)
+				sendMetric(sender, symbol.Name, value, rowTags, metric.ForcedType)
+			}
+		}
+	}
+}
+
+func sendMetric(sender aggregator.Sender, name string, value snmpValue, tags []string, forcedType string) {
+	metricName := metricPrefix + name
+	floatValue, err := value.toFloat64()
+	if err != nil {
+		log.Debugf("Unable to submit metric %s: %s", metricName, err)
+		return
+	}
+
+	switch forcedType {
+	case "gauge":
+		sender.Gauge(metricName, floatValue, "", tags)
+	case "counter":
+		sender.Rate(metricName, floatValue, "", tags)
+	case "percent":
+		sender.Rate(metricName, floatValue*100, "", tags)
+	case "monotonic_count":
+		sender.MonotonicCount(metricName, floatValue, "", tags)
+	case "monotonic_count_and_rate":
+		sender.MonotonicCount(metricName, floatValue, "", tags)
+		sender.Rate(metricName+".rate", floatValue, "", tags)
+	default:
+		if value.isCounter {
+			sender.Rate(metricName, floatValue, "", tags)
+		} else {
+			sender.Gauge(metricName, floatValue, "", tags)
+		}
+	}
+}
+
+// getRowTags returns the tags of a table row, from the index of the row or
+// from the values of other columns on the same row
+func getRowTags(metricTags []metricTagConfig, index string, values *valueStore) []string {
+	var tags []string
+	for _, metricTag := range metricTags {
+		if metricTag.Index > 0 {
+			indexes := strings.Split(index, ".")
+			if int(metricTag.Index) > len(indexes) {
+				log.Debugf("Index %d of tag '%s' is out of range for row %s", metricTag.Index, metricTag.Tag, index)
+				continue
+			}
+			tags = append(tags, metricTag.Tag+":"+indexes[metricTag.Index-1])
+			continue
+		}
+		if value, ok := values.getColumnValues(metricTag.Column.OID)[index]; ok {
+			tags = append(tags, metricTag.Tag+":"+value.toString())
+		}
+	}
+	return tags
+}
+
+// getGlobalMetricTags returns the tags applied to every metric of the device,
+// taken from scalar OIDs
+func getGlobalMetricTags(metricTags []metricTagConfig, values *valueStore) []string {
+	var tags []string
+	for _, metricTag := range metricTags {
+		if metricTag.OID == "" {
+			continue
+		}
+		if value, ok := values.getScalarValue(metricTag.OID); ok {
+			tags = append(tags, metricTag.Tag+":"+value.toString())
+		}
+	}
+	return tags
+}
@@ -0,0 +1,113 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package snmp
+
+import (
+	"fmt"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	core "github.com/DataDog/datadog-agent/pkg/collector/corechecks"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	snmpCheckName    = "snmp"
+	serviceCheckName = "snmp.can_check"
+)
+
+// Check polls a device over SNMP
+type Check struct {
+	core.CheckBase
+	config checkConfig
+}
+
+// Configure configures the snmp check
+func (c *Check) Configure(rawInstance integration.Data, rawInitConfig integration.Data, source string) error {
+	config, err := buildConfig(rawInstance, rawInitConfig)
+	if err != nil {
+		log.Errorf("Error parsing configuration file: %s", err)
+		return err
+	}
+
+	c.BuildID(rawInstance, rawInitConfig)
+	c.config = config
+
+	return c.CommonConfigure(rawInstance, source)
+}
+
+// Run runs the check
+func (c *Check) Run() error {
+	sender, err := aggregator.GetSender(c.ID())
+	if err != nil {
+		return err
+	}
+
+	tags, checkErr := c.processMetrics(sender)
+	if checkErr != nil {
+		sender.ServiceCheck(serviceCheckName, metrics.ServiceCheckCritical, "", tags, checkErr.Error())
+	} else {
+		sender.ServiceCheck(serviceCheckName, metrics.ServiceCheckOK, "", tags, "")
+	}
+	sender.Gauge("snmp.devices_monitored", 1, "", tags)
+
+	sender.Commit()
+	return checkErr
+}
+
+// processMetrics fetches and sends the metrics of the device, returning the tags
+// of the device
+func (c *Check) processMetrics(sender aggregator.Sender) ([]string, error) {
+	tags := copyTags(c.config.tags)
+
+	session, err := c.config.buildSNMPParams()
+	if err != nil {
+		return tags, err
+	}
+	if err := session.Connect(); err != nil {
+		return tags, fmt.Errorf("snmp connection error: %s", err)
+	}
+	defer session.Conn.Close()
+
+	if c.config.needsProfileDetection() {
+		sysObjectID, err := fetchSysObjectID(session)
+		if err != nil {
+			return tags, fmt.Errorf("failed to fetch sysObjectID: %s", err)
+		}
+		profile, err := getProfileForSysObjectID(c.config.profiles, sysObjectID)
+		if err != nil {
+			return tags, err
+		}
+		log.Debugf("Using profile '%s' for device %s", profile, c.config.ipAddress)
+		c.config.refreshWithProfile(profile)
+		tags = copyTags(c.config.tags)
+	}
+
+	values, err := fetchValues(session, c.config.oidConfig, c.config.oidBatchSize, c.config.bulkMaxRepetitions)
+	if err != nil {
+		return tags, err
+	}
+
+	tags = append(tags, getGlobalMetricTags(c.config.metricTags, values)...)
+	sendMetrics(sender, c.config.metrics, values, tags)
+	return tags, nil
+}
+
+func copyTags(tags []string) []string {
+	return append([]string{}, tags...)
+}
+
+func snmpFactory() check.Check {
+	return &Check{
+		CheckBase: core.NewCheckBase(snmpCheckName),
+	}
+}
+
+func init() {
+	core.RegisterCheck(snmpCheckName, snmpFactory)
+}
@@ -0,0 +1,177 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package snmp
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/soniah/gosnmp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+// ciscoDevicePDUs are the values of a device matching the cisco test profile
+var ciscoDevicePDUs = []gosnmp.SnmpPDU{
+	{Name: ".1.3.6.1.2.1.1.2.0", Type: gosnmp.ObjectIdentifier, Value: ".1.3.6.1.4.1.9.1.5"},
+	{Name: ".1.3.6.1.2.1.1.3.0", Type: gosnmp.TimeTicks, Value: uint32(12345)},
+	{Name: ".1.3.6.1.2.1.1.5.0", Type: gosnmp.OctetString, Value: []byte("router-1")},
+	// ifTable
+	{Name: ".1.3.6.1.2.1.2.2.1.7.1", Type: gosnmp.Integer, Value: 1},
+	{Name: ".1.3.6.1.2.1.2.2.1.7.2", Type: gosnmp.Integer, Value: 1},
+	{Name: ".1.3.6.1.2.1.2.2.1.8.1", Type: gosnmp.Integer, Value: 1},
+	{Name: ".1.3.6.1.2.1.2.2.1.8.2", Type: gosnmp.Integer, Value: 2},
+	{Name: ".1.3.6.1.2.1.2.2.1.14.1", Type: gosnmp.Counter32, Value: uint32(3)},
+	{Name: ".1.3.6.1.2.1.2.2.1.14.2", Type: gosnmp.Counter32, Value: uint32(4)},
+	// ifXTable
+	{Name: ".1.3.6.1.2.1.31.1.1.1.1.1", Type: gosnmp.OctetString, Value: []byte("eth0")},
+	{Name: ".1.3.6.1.2.1.31.1.1.1.1.2", Type: gosnmp.OctetString, Value: []byte("eth1")},
+	{Name: ".1.3.6.1.2.1.31.1.1.1.6.1", Type: gosnmp.Counter64, Value: uint64(5000000000)},
+	{Name: ".1.3.6.1.2.1.31.1.1.1.6.2", Type: gosnmp.Counter64, Value: uint64(6000)},
+	{Name: ".1.3.6.1.2.1.31.1.1.1.15.1", Type: gosnmp.Gauge32, Value: uint32(1000)},
+	{Name: ".1.3.6.1.2.1.31.1.1.1.15.2", Type: gosnmp.Gauge32, Value: uint32(100)},
+	{Name: ".1.3.6.1.2.1.31.1.1.1.18.1", Type: gosnmp.OctetString, Value: []byte("uplink")},
+	// cpmCPUTotalTable
+	{Name: ".1.3.6.1.4.1.9.9.109.1.1.1.1.2.1", Type: gosnmp.Integer, Value: 1001},
+	{Name: ".1.3.6.1.4.1.9.9.109.1.1.1.1.2.2", Type: gosnmp.Integer, Value: 1002},
+	{Name: ".1.3.6.1.4.1.9.9.109.1.1.1.1.7.1", Type: gosnmp.Gauge32, Value: uint32(10)},
+	{Name: ".1.3.6.1.4.1.9.9.109.1.1.1.1.7.2", Type: gosnmp.Gauge32, Value: uint32(20)},
+	{Name: ".1.3.6.1.4.1.9.9.221.1.1.1.1.18.1.1", Type: gosnmp.Counter64, Value: uint64(1024)},
+}
+
+func runCheck(t *testing.T, instance string) (*mocksender.MockSender, error) {
+	check := snmpFactory().(*Check)
+	err := check.Configure([]byte(instance), []byte(``), "test")
+	require.NoError(t, err)
+
+	sender := mocksender.NewMockSender(check.ID())
+	sender.SetupAcceptAll()
+	return sender, check.Run()
+}
+
+func assertCiscoDeviceMetrics(t *testing.T, sender *mocksender.MockSender) {
+	deviceTags := []string{"snmp_device:127.0.0.1", "snmp_profile:cisco", "device_vendor:cisco", "snmp_host:router-1"}
+
+	sender.AssertMetric(t, "Gauge", "snmp.sysUpTimeInstance", 12345, "", deviceTags)
+	sender.AssertMetric(t, "Gauge", "snmp.cempMemPoolHCUsed", 1024, "", deviceTags)
+	sender.AssertMetric(t, "Gauge", "snmp.cpmCPUTotal1minRev", 10, "", append(deviceTags, "cpu:1", "cpu_physical_index:1001"))
+	sender.AssertMetric(t, "Gauge", "snmp.cpmCPUTotal1minRev", 20, "", append(deviceTags, "cpu:2", "cpu_physical_index:1002"))
+
+	eth0Tags := append(deviceTags, "interface:eth0", "interface_alias:uplink")
+	sender.AssertMetric(t, "MonotonicCount", "snmp.ifInErrors", 3, "", eth0Tags)
+	sender.AssertMetric(t, "Rate", "snmp.ifInErrors.rate", 3, "", eth0Tags)
+	sender.AssertMetric(t, "MonotonicCount", "snmp.ifHCInOctets", 5000000000, "", eth0Tags)
+	sender.AssertMetric(t, "Gauge", "snmp.ifHighSpeed", 1000, "", eth0Tags)
+	sender.AssertMetric(t, "Gauge", "snmp.ifOperStatus", 1, "", eth0Tags)
+	sender.AssertMetric(t, "Gauge", "snmp.ifOperStatus", 2, "", append(deviceTags, "interface:eth1"))
+	sender.AssertMetricNotTaggedWith(t, "Gauge", "snmp.ifOperStatus", []string{"interface:eth1", "interface_alias:"})
+
+	sender.AssertMetric(t, "Gauge", "snmp.devices_monitored", 1, "", deviceTags)
+	sender.AssertServiceCheck(t, "snmp.can_check", metrics.ServiceCheckOK, "", deviceTags, "")
+}
+
+func TestCheckV2(t *testing.T) {
+	setConfdPath(filepath.Join("testdata", "conf.d"))
+	agent := newSimulatedAgent(t, ciscoDevicePDUs)
+	defer agent.close()
+
+	sender, err := runCheck(t, fmt.Sprintf(`
+ip_address: 127.0.0.1
+port: %d
+community_string: public
+timeout: 1
+`, agent.port()))
+	require.NoError(t, err)
+
+	assertCiscoDeviceMetrics(t, sender)
+	assert.Contains(t, agent.requestTypes(), gosnmp.GetBulkRequest)
+	assert.NotContains(t, agent.requestTypes(), gosnmp.GetNextRequest)
+}
+
+func TestCheckV1(t *testing.T) {
+	setConfdPath(filepath.Join("testdata", "conf.d"))
+	agent := newSimulatedAgent(t, ciscoDevicePDUs)
+	defer agent.close()
+
+	sender, err := runCheck(t, fmt.Sprintf(`
+ip_address: 127.0.0.1
+port: %d
+snmp_version: 1
+community_string: public
+timeout: 1
+profile: cisco
+metrics:
+  - OID: 1.3.6.1.2.1.6.5.0
+    name: tcpActiveOpens
+`, agent.port()))
+	require.NoError(t, err)
+
+	// the missing OID does not prevent the collection of the other scalars
+	assertCiscoDeviceMetrics(t, sender)
+	sender.AssertNotCalled(t, "Gauge", "snmp.tcpActiveOpens", mocksender.AnythingBut(0.0), "", mocksender.MatchTagsContains(nil))
+	assert.Contains(t, agent.requestTypes(), gosnmp.GetNextRequest)
+	assert.NotContains(t, agent.requestTypes(), gosnmp.GetBulkRequest)
+}
+
+func TestCheckV3(t *testing.T) {
+	setConfdPath(filepath.Join("testdata", "conf.d"))
+	agent := newSimulatedAgent(t, ciscoDevicePDUs)
+	defer agent.close()
+
+	sender, err := runCheck(t, fmt.Sprintf(`
+ip_address: 127.0.0.1
+port: %d
+snmp_version: 3
+user: %s
+timeout: 1
+`, agent.port(), testUser))
+	require.NoError(t, err)
+
+	assertCiscoDeviceMetrics(t, sender)
+}
+
+func TestCheckUnreachableDevice(t *testing.T) {
+	setConfdPath(filepath.Join("testdata", "conf.d"))
+	agent := newSimulatedAgent(t, ciscoDevicePDUs)
+	port := agent.port()
+	agent.close()
+
+	sender, err := runCheck(t, fmt.Sprintf(`
+ip_address: 127.0.0.1
+port: %d
+community_string: public
+timeout: 1
+retries: 1
+profile: cisco
+`, port))
+	require.Error(t, err)
+
+	deviceTags := []string{"snmp_device:127.0.0.1", "snmp_profile:cisco"}
+	sender.AssertCalled(t, "ServiceCheck", "snmp.can_check", metrics.ServiceCheckCritical, "", mocksender.MatchTagsContains(deviceTags), err.Error())
+	sender.AssertMetric(t, "Gauge", "snmp.devices_monitored", 1, "", deviceTags)
+	sender.AssertNotCalled(t, "Gauge", "snmp.sysUpTimeInstance", mocksender.AnythingBut(0.0), "", mocksender.MatchTagsContains(nil))
+}
+
+func TestCheckNoMatchingProfile(t *testing.T) {
+	setConfdPath(filepath.Join("testdata", "conf.d"))
+	pdus := append([]gosnmp.SnmpPDU{}, ciscoDevicePDUs...)
+	pdus[0] = gosnmp.SnmpPDU{Name: ".1.3.6.1.2.1.1.2.0", Type: gosnmp.ObjectIdentifier, Value: ".1.3.6.1.2.1.1"}
+	agent := newSimulatedAgent(t, pdus)
+	defer agent.close()
+
+	sender, err := runCheck(t, fmt.Sprintf(`
+ip_address: 127.0.0.1
+port: %d
+community_string: public
+timeout: 1
+`, agent.port()))
+	assert.EqualError(t, err, "no profile found for sysObjectID 1.3.6.1.2.1.1")
+	sender.AssertServiceCheck(t, "snmp.can_check", metrics.ServiceCheckCritical, "", []string{"snmp_device:127.0.0.1"}, err.Error())
+}
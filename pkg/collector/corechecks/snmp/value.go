@@ -0,0 +1,116 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package snmp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/soniah/gosnmp"
+)
+
+// snmpValue is the value of an OID, either a float64 or a string
+type snmpValue struct {
+	value     interface{}
+	isCounter bool
+}
+
+// valueStore holds the values fetched from the device, column values being
+// indexed by the index of their row
+type valueStore struct {
+	scalarValues map[string]snmpValue
+	columnValues map[string]map[string]snmpValue
+}
+
+func newValueStore() *valueStore {
+	return &valueStore{
+		scalarValues: make(map[string]snmpValue),
+		columnValues: make(map[string]map[string]snmpValue),
+	}
+}
+
+func (s *valueStore) getScalarValue(oid string) (snmpValue, bool) {
+	value, ok := s.scalarValues[normalizeOID(oid)]
+	return value, ok
+}
+
+func (s *valueStore) getColumnValues(oid string) map[string]snmpValue {
+	return s.columnValues[normalizeOID(oid)]
+}
+
+func (s *valueStore) setColumnValue(column string, index string, value snmpValue) {
+	if _, ok := s.columnValues[column]; !ok {
+		s.columnValues[column] = make(map[string]snmpValue)
+	}
+	s.columnValues[column][index] = value
+}
+
+// toFloat64 returns the value as a float, strings being parsed
+func (v snmpValue) toFloat64() (float64, error) {
+	switch value := v.value.(type) {
+	case float64:
+		return value, nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return 0, fmt.Errorf("value '%s' is not a number", value)
+		}
+		return f, nil
+	}
+	return 0, fmt.Errorf("unsupported value type %T", v.value)
+}
+
+// toString returns the value as a string, to be used in a tag
+func (v snmpValue) toString() string {
+	switch value := v.value.(type) {
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case string:
+		return value
+	}
+	return ""
+}
+
+// getValueFromPDU converts a variable returned by the device, returning false
+// when it does not hold a value
+func getValueFromPDU(pdu gosnmp.SnmpPDU) (snmpValue, bool) {
+	switch pdu.Type {
+	case gosnmp.Integer, gosnmp.Gauge32, gosnmp.TimeTicks, gosnmp.Uinteger32:
+		return snmpValue{value: integerToFloat64(pdu.Value)}, true
+	case gosnmp.Counter32, gosnmp.Counter64:
+		return snmpValue{value: integerToFloat64(pdu.Value), isCounter: true}, true
+	case gosnmp.OpaqueFloat:
+		if f, ok := pdu.Value.(float32); ok {
+			return snmpValue{value: float64(f)}, true
+		}
+	case gosnmp.OpaqueDouble:
+		if f, ok := pdu.Value.(float64); ok {
+			return snmpValue{value: f}, true
+		}
+	case gosnmp.OctetString:
+		if b, ok := pdu.Value.([]byte); ok {
+			return snmpValue{value: string(b)}, true
+		}
+	case gosnmp.IPAddress:
+		if s, ok := pdu.Value.(string); ok {
+			return snmpValue{value: s}, true
+		}
+	case gosnmp.ObjectIdentifier:
+		if s, ok := pdu.Value.(string); ok {
+			return snmpValue{value: normalizeOID(s)}, true
+		}
+	}
+	return snmpValue{}, false
+}
+
+func integerToFloat64(value interface{}) float64 {
+	// Counter64 values may not fit in an int64
+	if u, ok := value.(uint64); ok {
+		return float64(u)
+	}
+	return float64(gosnmp.ToBigInt(value).Int64())
+}
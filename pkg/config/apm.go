@@ -26,10 +26,17 @@ func setupAPM(config Config) {
 	config.SetKnown("apm_config.obfuscation.sql_exec_plan_normalize.obfuscate_sql_values")
 	config.SetKnown("apm_config.obfuscation.http.remove_query_string")
 	config.SetKnown("apm_config.obfuscation.http.remove_paths_with_digits")
+	config.SetKnown("apm_config.obfuscation.sql.collect_metadata")
 	config.SetKnown("apm_config.obfuscation.remove_stack_traces")
 	config.SetKnown("apm_config.obfuscation.redis.enabled")
 	config.SetKnown("apm_config.obfuscation.memcached.enabled")
 	config.SetKnown("apm_config.extra_sample_rate")
+	config.SetKnown("apm_config.tail_sampling.enabled")
+	config.SetKnown("apm_config.tail_sampling.decision_wait_seconds")
+	config.SetKnown("apm_config.tail_sampling.max_memory_ratio")
+	config.SetKnown("apm_config.tail_sampling.errors")
+	config.SetKnown("apm_config.tail_sampling.latency_percentile")
+	config.SetKnown("apm_config.tail_sampling.tags")
 	config.SetKnown("apm_config.dd_agent_bin")
 	config.SetKnown("apm_config.trace_writer.connection_limit")
 	config.SetKnown("apm_config.trace_writer.queue_size")
@@ -72,9 +79,13 @@ func setupAPM(config Config) {
 	config.BindEnv("apm_config.additional_endpoints", "DD_APM_ADDITIONAL_ENDPOINTS")                     //nolint:errcheck
 	config.BindEnv("apm_config.replace_tags", "DD_APM_REPLACE_TAGS")                                     //nolint:errcheck
 	config.BindEnv("apm_config.analyzed_spans", "DD_APM_ANALYZED_SPANS")                                 //nolint:errcheck
+	config.BindEnv("apm_config.sampling_rules", "DD_APM_SAMPLING_RULES")                                 //nolint:errcheck
+	config.BindEnv("apm_config.obfuscation.rules", "DD_APM_OBFUSCATION_RULES")                           //nolint:errcheck
 	config.BindEnv("apm_config.ignore_resources", "DD_APM_IGNORE_RESOURCES", "DD_IGNORE_RESOURCE")       //nolint:errcheck
 	config.BindEnv("apm_config.receiver_socket", "DD_APM_RECEIVER_SOCKET")                               //nolint:errcheck
 	config.BindEnv("apm_config.windows_pipe_name", "DD_APM_WINDOWS_PIPE_NAME")                           //nolint:errcheck
+	config.BindEnv("apm_config.otlp.http_port", "DD_APM_OTLP_HTTP_PORT")                                 //nolint:errcheck
+	config.BindEnv("apm_config.otlp.grpc_port", "DD_APM_OTLP_GRPC_PORT")                                 //nolint:errcheck
 
 	config.SetEnvKeyTransformer("apm_config.ignore_resources", func(in string) interface{} {
 		r, err := splitCSVString(in, ',')
@@ -93,6 +104,22 @@ func setupAPM(config Config) {
 		return out
 	})
 
+	config.SetEnvKeyTransformer("apm_config.sampling_rules", func(in string) interface{} {
+		var out []map[string]interface{}
+		if err := json.Unmarshal([]byte(in), &out); err != nil {
+			log.Warnf(`"apm_config.sampling_rules" can not be parsed: %v`, err)
+		}
+		return out
+	})
+
+	config.SetEnvKeyTransformer("apm_config.obfuscation.rules", func(in string) interface{} {
+		var out []map[string]interface{}
+		if err := json.Unmarshal([]byte(in), &out); err != nil {
+			log.Warnf(`"apm_config.obfuscation.rules" can not be parsed: %v`, err)
+		}
+		return out
+	})
+
 	config.SetEnvKeyTransformer("apm_config.analyzed_spans", func(in string) interface{} {
 		out, err := parseAnalyzedSpans(in)
 		if err != nil {
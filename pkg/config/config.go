@@ -65,6 +65,9 @@ var (
 	Datadog       Config
 	proxies       *Proxy
 	overrideFuncs = make([]func(Config), 0)
+
+	// apiKeySecretHandle is the secret handle the main API key is resolved from, if any
+	apiKeySecretHandle string
 )
 
 // Variables to initialize at build time
@@ -239,6 +242,8 @@ func InitConfig(config Config) {
 	config.BindEnvAndSetDefault("secret_backend_output_max_size", secrets.SecretBackendOutputMaxSize)
 	config.BindEnvAndSetDefault("secret_backend_timeout", 5)
 	config.BindEnvAndSetDefault("secret_backend_command_allow_group_exec_perm", false)
+	config.BindEnvAndSetDefault("secret_backends", map[string]interface{}{})
+	config.BindEnvAndSetDefault("secret_refresh_interval", 0)
 
 	// Use to output logs in JSON format
 	config.BindEnvAndSetDefault("log_format_json", false)
@@ -373,6 +378,10 @@ func InitConfig(config Config) {
 	config.BindEnvAndSetDefault("dogstatsd_entity_id_precedence", false)
 	// Sends Dogstatsd parse errors to the Debug level instead of the Error level
 	config.BindEnvAndSetDefault("dogstatsd_disable_verbose_logs", false)
+	// Traffic capture: folder of the capture files (empty means `run_path`/dsd_capture) and
+	// number of datagrams buffered before being written, datagrams being dropped past it
+	config.BindEnvAndSetDefault("dogstatsd_capture_path", "")
+	config.BindEnvAndSetDefault("dogstatsd_capture_depth", 2048)
 
 	_ = config.BindEnv("dogstatsd_mapper_profiles")
 	config.SetEnvKeyTransformer("dogstatsd_mapper_profiles", func(in string) interface{} {
@@ -583,6 +592,12 @@ func InitConfig(config Config) {
 	config.BindEnvAndSetDefault("logs_config.open_files_limit", 100)
 	// add global processing rules that are applied on all logs
 	config.BindEnv("logs_config.processing_rules") //nolint:errcheck
+	// detect multi-line logs automatically for the sources that don't set
+	// auto_multi_line_detection, by sampling their first lines:
+	config.BindEnvAndSetDefault("logs_config.auto_multi_line_detection", false)
+	config.BindEnvAndSetDefault("logs_config.auto_multi_line_default_sample_size", 500)
+	config.BindEnvAndSetDefault("logs_config.auto_multi_line_default_match_threshold", 0.48)
+	config.BindEnv("logs_config.auto_multi_line_extra_patterns") //nolint:errcheck
 	// enforce the agent to use files to collect container logs on kubernetes environment
 	config.BindEnvAndSetDefault("logs_config.k8s_container_use_file", false)
 	// enforce the agent to use files to collect container logs on standalone docker environment
@@ -603,6 +618,7 @@ func InitConfig(config Config) {
 	config.BindEnvAndSetDefault("logs_config.use_tcp", false)
 	config.BindEnvAndSetDefault("logs_config.use_compression", true)
 	config.BindEnvAndSetDefault("logs_config.compression_level", 6) // Default level for the gzip/deflate algorithm
+	config.BindEnvAndSetDefault("logs_config.compression_kind", "gzip")
 	config.BindEnvAndSetDefault("logs_config.batch_wait", DefaultBatchWait)
 	config.BindEnvAndSetDefault("logs_config.connection_reset_interval", 0) // in seconds, 0 means disabled
 	config.BindEnvAndSetDefault("logs_config.dd_port", 10516)
@@ -610,7 +626,14 @@ func InitConfig(config Config) {
 	config.BindEnvAndSetDefault("logs_config.dd_url_443", "agent-443-intake.logs.datadoghq.com")
 	config.BindEnvAndSetDefault("logs_config.stop_grace_period", 30)
 	config.BindEnvAndSetDefault("logs_config.close_timeout", 60)
+	// number of bytes at the beginning of the files used to identify them, 0 to disable
+	config.BindEnvAndSetDefault("logs_config.file_fingerprint_size", 0)
+	// look for the compressed rotation of the files rotated with unread data
+	config.BindEnvAndSetDefault("logs_config.catch_up_compressed_rotated_files", false)
 	config.BindEnvAndSetDefault("logs_config.auditor_ttl", DefaultAuditorTTL) // in hours
+	// disk buffer of the payloads which can't be sent over HTTP (empty path means `logs_config.run_path`/logs_to_retry)
+	config.BindEnvAndSetDefault("logs_config.storage_path", "")
+	config.BindEnvAndSetDefault("logs_config.storage_max_size_in_bytes", 0) // 0 means disabled
 	config.BindEnv("logs_config.additional_endpoints")                        //nolint:errcheck
 
 	// The cardinality of tags to send for checks and dogstatsd respectively.
@@ -990,9 +1013,10 @@ func ResolveSecrets(config Config, origin string) error {
 		config.GetInt("secret_backend_timeout"),
 		config.GetInt("secret_backend_output_max_size"),
 		config.GetBool("secret_backend_command_allow_group_exec_perm"),
+		config.GetStringMap("secret_backends"),
 	)
 
-	if config.GetString("secret_backend_command") != "" {
+	if config.GetString("secret_backend_command") != "" || len(config.GetStringMap("secret_backends")) != 0 {
 		// Viper doesn't expose the final location of the file it
 		// loads. Since we are searching for 'datadog.yaml' in multiple
 		// locations we let viper determine the one to use before
@@ -1002,6 +1026,10 @@ func ResolveSecrets(config Config, origin string) error {
 			return fmt.Errorf("unable to marshal configuration to YAML to decrypt secrets: %v", err)
 		}
 
+		if handle := secretHandle(config.GetString("api_key")); handle != "" {
+			apiKeySecretHandle = handle
+		}
+
 		finalYamlConf, err := secrets.Decrypt(yamlConf, origin)
 		if err != nil {
 			return fmt.Errorf("unable to decrypt secret from datadog.yaml: %v", err)
@@ -1014,6 +1042,21 @@ func ResolveSecrets(config Config, origin string) error {
 	return nil
 }
 
+// APIKeySecretHandle returns the secret handle the `api_key` setting was resolved from,
+// or an empty string if the API key is not a secret.
+func APIKeySecretHandle() string {
+	return apiKeySecretHandle
+}
+
+// secretHandle returns the handle of a "ENC[handle]" value, or an empty string
+func secretHandle(value string) string {
+	value = strings.Trim(value, " \t")
+	if strings.HasPrefix(value, "ENC[") && strings.HasSuffix(value, "]") {
+		return value[4 : len(value)-1]
+	}
+	return ""
+}
+
 // SanitizeAPIKeyConfig strips newlines and other control characters from a given key.
 func SanitizeAPIKeyConfig(config Config, key string) {
 	config.Set(key, SanitizeAPIKey(config.GetString(key)))
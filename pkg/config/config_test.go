@@ -921,3 +921,10 @@ func TestDogstatsdMappingProfilesEnv(t *testing.T) {
 	mappings, _ := GetDogstatsdMappingProfiles()
 	assert.Equal(t, mappings, expected)
 }
+
+func TestSecretHandle(t *testing.T) {
+	assert.Equal(t, "api_key_handle", secretHandle("ENC[api_key_handle]"))
+	assert.Equal(t, "vault:secret/data/dd#api_key", secretHandle(" ENC[vault:secret/data/dd#api_key]	"))
+	assert.Equal(t, "", secretHandle("0123456789abcdef"))
+	assert.Equal(t, "", secretHandle(""))
+}
@@ -10,12 +10,17 @@ import (
 type batcher struct {
 	samples      []metrics.MetricSample
 	samplesCount int
+	// samples sent with a timestamp by the client are batched separately as
+	// they are not aggregated with the samples received during the same interval
+	samplesWithTs      []metrics.MetricSample
+	samplesWithTsCount int
 
 	events        []*metrics.Event
 	serviceChecks []*metrics.ServiceCheck
 
 	// output channels
 	choutSamples       chan<- []metrics.MetricSample
+	choutSamplesWithTs chan<- []metrics.MetricSample
 	choutEvents        chan<- []*metrics.Event
 	choutServiceChecks chan<- []*metrics.ServiceCheck
 
@@ -26,14 +31,20 @@ func newBatcher(agg *aggregator.BufferedAggregator) *batcher {
 	s, e, sc := agg.GetBufferedChannels()
 	return &batcher{
 		samples:            agg.MetricSamplePool.GetBatch(),
+		samplesWithTs:      agg.MetricSamplePool.GetBatch(),
 		metricSamplePool:   agg.MetricSamplePool,
 		choutSamples:       s,
+		choutSamplesWithTs: agg.GetBufferedMetricsWithTsChannel(),
 		choutEvents:        e,
 		choutServiceChecks: sc,
 	}
 }
 
 func (b *batcher) appendSample(sample metrics.MetricSample) {
+	if sample.Timestamp > 0 {
+		b.appendSampleWithTs(sample)
+		return
+	}
 	if b.samplesCount == len(b.samples) {
 		b.flushSamples()
 	}
@@ -41,6 +52,14 @@ func (b *batcher) appendSample(sample metrics.MetricSample) {
 	b.samplesCount++
 }
 
+func (b *batcher) appendSampleWithTs(sample metrics.MetricSample) {
+	if b.samplesWithTsCount == len(b.samplesWithTs) {
+		b.flushSamplesWithTs()
+	}
+	b.samplesWithTs[b.samplesWithTsCount] = sample
+	b.samplesWithTsCount++
+}
+
 func (b *batcher) appendEvent(event *metrics.Event) {
 	b.events = append(b.events, event)
 }
@@ -57,9 +76,18 @@ func (b *batcher) flushSamples() {
 	}
 }
 
+func (b *batcher) flushSamplesWithTs() {
+	if b.samplesWithTsCount > 0 {
+		b.choutSamplesWithTs <- b.samplesWithTs[:b.samplesWithTsCount]
+		b.samplesWithTsCount = 0
+		b.samplesWithTs = b.metricSamplePool.GetBatch()
+	}
+}
+
 // flush pushes all batched metrics to the aggregator.
 func (b *batcher) flush() {
 	b.flushSamples()
+	b.flushSamplesWithTs()
 	if len(b.events) > 0 {
 		b.choutEvents <- b.events
 		b.events = []*metrics.Event{}
@@ -2,8 +2,10 @@ package dogstatsd
 
 import (
 	"strings"
+	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/containers"
 	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/kubelet"
 )
 
@@ -85,6 +87,20 @@ func enrichMetricSample(metricSamples []metrics.MetricSample, ddSample dogstatsd
 	metricName := ddSample.name
 	tags, hostnameFromTags, originID, k8sOriginID := extractTagsMetadata(ddSample.tags, defaultHostname, origin, entityIDPrecedenceEnabled)
 
+	// The container ID sent by the client is only used when the origin
+	// could not be detected from the socket nor from the entity ID tag.
+	if originID == "" && k8sOriginID == "" && ddSample.containerID != "" {
+		originID = containers.BuildTaggerEntityName(ddSample.containerID)
+	}
+
+	// Samples sent with a timestamp are forwarded with it, in nanoseconds
+	// as expected by the aggregator, 0 meaning the sample is timestamped
+	// at reception.
+	var timestamp float64
+	if ddSample.timestamp > 0 {
+		timestamp = float64(ddSample.timestamp) * float64(time.Second)
+	}
+
 	if !isBlacklisted(metricName, namespace, namespaceBlacklist) {
 		metricName = namespace + metricName
 	}
@@ -109,6 +125,7 @@ func enrichMetricSample(metricSamples []metrics.MetricSample, ddSample dogstatsd
 					Value:       ddSample.values[idx],
 					SampleRate:  ddSample.sampleRate,
 					RawValue:    ddSample.setValue,
+					Timestamp:   timestamp,
 					OriginID:    originID,
 					K8sOriginID: k8sOriginID,
 				})
@@ -125,6 +142,7 @@ func enrichMetricSample(metricSamples []metrics.MetricSample, ddSample dogstatsd
 		Value:       ddSample.value,
 		SampleRate:  ddSample.sampleRate,
 		RawValue:    ddSample.setValue,
+		Timestamp:   timestamp,
 		OriginID:    originID,
 		K8sOriginID: k8sOriginID,
 	})
@@ -3,6 +3,7 @@ package dogstatsd
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/metrics"
 	"github.com/stretchr/testify/assert"
@@ -835,6 +836,45 @@ func TestConvertEntityOriginDetectionTags(t *testing.T) {
 	assert.InEpsilon(t, 1.0, parsed.SampleRate, epsilon)
 }
 
+func TestConvertContainerIDOriginDetection(t *testing.T) {
+	parser := newParser(newFloat64ListPool())
+	parsed, err := parser.parseMetricSample([]byte("daemon:666|g|#sometag1:somevalue1|c:f3a8b2b9e4c1"))
+	require.NoError(t, err)
+
+	// the container ID field is used when there is no socket origin
+	samples := enrichMetricSample([]metrics.MetricSample{}, parsed, "", nil, "default-hostname", "", false, false)
+	require.Len(t, samples, 1)
+	assert.Equal(t, []string{"sometag1:somevalue1"}, samples[0].Tags)
+	assert.Equal(t, "container_id://f3a8b2b9e4c1", samples[0].OriginID)
+	assert.Equal(t, "", samples[0].K8sOriginID)
+
+	// the socket origin takes precedence over the container ID field
+	samples = enrichMetricSample([]metrics.MetricSample{}, parsed, "", nil, "default-hostname", "container_id://abcdef", false, false)
+	require.Len(t, samples, 1)
+	assert.Equal(t, "container_id://abcdef", samples[0].OriginID)
+
+	// as well as the entity ID tag
+	parsed, err = parser.parseMetricSample([]byte("daemon:666|g|#dd.internal.entity_id:foo|c:f3a8b2b9e4c1"))
+	require.NoError(t, err)
+	samples = enrichMetricSample([]metrics.MetricSample{}, parsed, "", nil, "default-hostname", "", false, false)
+	require.Len(t, samples, 1)
+	assert.Equal(t, "", samples[0].OriginID)
+	assert.Equal(t, "kubernetes_pod_uid://foo", samples[0].K8sOriginID)
+}
+
+func TestConvertTimestamp(t *testing.T) {
+	parsed, err := parseAndEnrichSingleMetricMessage([]byte("daemon:666|c|#sometag1:somevalue1|T1617285000"), "", nil, "default-hostname")
+	assert.NoError(t, err)
+
+	assert.Equal(t, "daemon", parsed.Name)
+	assert.Equal(t, metrics.CounterType, parsed.Mtype)
+	assert.Equal(t, float64(1617285000*time.Second), parsed.Timestamp)
+
+	parsed, err = parseAndEnrichSingleMetricMessage([]byte("daemon:666|c|#sometag1:somevalue1"), "", nil, "default-hostname")
+	assert.NoError(t, err)
+	assert.Equal(t, 0.0, parsed.Timestamp)
+}
+
 func TestConvertEntityOriginDetectionTagsError(t *testing.T) {
 	parsed, err := parseAndEnrichSingleMetricMessage([]byte("daemon:666|g|#sometag1:somevalue1,host:my-hostname,dd.internal.entity_id:foo,sometag2:somevalue2"), "", nil, "default-hostname")
 	assert.NoError(t, err)
@@ -17,6 +17,13 @@ type Packet struct {
 	Origin   string // Origin container if identified
 }
 
+// CopyContents copies the data in the packet buffer and sets the packet
+// contents to it, data larger than the buffer being truncated like
+// datagrams read by the listeners.
+func (p *Packet) CopyContents(data []byte) {
+	p.Contents = p.buffer[:copy(p.buffer, data)]
+}
+
 // Packets is a slice of packet pointers
 type Packets []*Packet
 
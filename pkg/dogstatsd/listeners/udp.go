@@ -11,6 +11,7 @@ import (
 	"net"
 	"strings"
 
+	"github.com/DataDog/datadog-agent/pkg/dogstatsd/replay"
 	"github.com/DataDog/datadog-agent/pkg/telemetry"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 
@@ -44,10 +45,11 @@ type UDPListener struct {
 	packetsBuffer   *packetsBuffer
 	packetAssembler *packetAssembler
 	buffer          []byte
+	trafficCapture  *replay.TrafficCapture // nil if traffic capture is not supported
 }
 
 // NewUDPListener returns an idle UDP Statsd listener
-func NewUDPListener(packetOut chan Packets, sharedPacketPool *PacketPool) (*UDPListener, error) {
+func NewUDPListener(packetOut chan Packets, sharedPacketPool *PacketPool, capture *replay.TrafficCapture) (*UDPListener, error) {
 	var err error
 	var url string
 
@@ -86,6 +88,7 @@ func NewUDPListener(packetOut chan Packets, sharedPacketPool *PacketPool) (*UDPL
 		packetsBuffer:   packetsBuffer,
 		packetAssembler: packetAssembler,
 		buffer:          buffer,
+		trafficCapture:  capture,
 	}
 	log.Debugf("dogstatsd-udp: %s successfully initialized", conn.LocalAddr())
 	return listener, nil
@@ -113,6 +116,10 @@ func (l *UDPListener) Listen() {
 		udpBytes.Add(int64(n))
 		tlmUDPPacketsBytes.Add(float64(n))
 
+		if l.trafficCapture != nil {
+			l.trafficCapture.Enqueue("udp", l.buffer[:n], 0, NoOrigin)
+		}
+
 		// packetAssembler merges multiple packets together and sends them when its buffer is full
 		l.packetAssembler.addMessage(l.buffer[:n])
 	}
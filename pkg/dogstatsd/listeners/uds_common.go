@@ -13,6 +13,7 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/DataDog/datadog-agent/pkg/dogstatsd/replay"
 	"github.com/DataDog/datadog-agent/pkg/telemetry"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 
@@ -53,10 +54,11 @@ type UDSListener struct {
 	sharedPacketPool *PacketPool
 	oobPool          *sync.Pool // For origin detection ancilary data
 	OriginDetection  bool
+	trafficCapture   *replay.TrafficCapture // nil if traffic capture is not supported
 }
 
 // NewUDSListener returns an idle UDS Statsd listener
-func NewUDSListener(packetOut chan Packets, sharedPacketPool *PacketPool) (*UDSListener, error) {
+func NewUDSListener(packetOut chan Packets, sharedPacketPool *PacketPool, capture *replay.TrafficCapture) (*UDSListener, error) {
 	socketPath := config.Datadog.GetString("dogstatsd_socket")
 	originDetection := config.Datadog.GetBool("dogstatsd_origin_detection")
 
@@ -109,6 +111,7 @@ func NewUDSListener(packetOut chan Packets, sharedPacketPool *PacketPool) (*UDSL
 		packetsBuffer: newPacketsBuffer(uint(config.Datadog.GetInt("dogstatsd_packet_buffer_size")),
 			config.Datadog.GetDuration("dogstatsd_packet_buffer_flush_timeout"), packetOut),
 		sharedPacketPool: sharedPacketPool,
+		trafficCapture:   capture,
 	}
 
 	// Init the oob buffer pool if origin detection is enabled
@@ -129,6 +132,7 @@ func (l *UDSListener) Listen() {
 	log.Infof("dogstatsd-uds: starting to listen on %s", l.conn.LocalAddr())
 	for {
 		var n int
+		var pid int32
 		var err error
 		// retrieve an available packet from the packet pool,
 		// which will be pushed back by the server when processed.
@@ -140,7 +144,9 @@ func (l *UDSListener) Listen() {
 			var oobn int
 			n, oobn, _, _, err = l.conn.ReadMsgUnix(packet.buffer, oob)
 			// Extract container id from credentials
-			container, taggingErr := processUDSOrigin(oob[:oobn])
+			var container string
+			var taggingErr error
+			pid, container, taggingErr = processUDSOrigin(oob[:oobn])
 			if taggingErr != nil {
 				log.Warnf("dogstatsd-uds: error processing origin, data will not be tagged : %v", taggingErr)
 				udsOriginDetectionErrors.Add(1)
@@ -172,6 +178,10 @@ func (l *UDSListener) Listen() {
 		tlmUDSPacketsBytes.Add(float64(n))
 		packet.Contents = packet.buffer[:n]
 
+		if l.trafficCapture != nil {
+			l.trafficCapture.Enqueue("uds", packet.Contents, pid, packet.Origin)
+		}
+
 		// packetsBuffer handles the forwarding of the packets to the dogstatsd server intake channel
 		l.packetsBuffer.append(packet)
 	}
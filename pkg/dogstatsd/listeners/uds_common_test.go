@@ -28,7 +28,7 @@ func testFileExistsNewUDSListener(t *testing.T, socketPath string) {
 	_, err := os.Create(socketPath)
 	assert.Nil(t, err)
 	defer os.Remove(socketPath)
-	_, err = NewUDSListener(nil, packetPoolUDS)
+	_, err = NewUDSListener(nil, packetPoolUDS, nil)
 	assert.Error(t, err)
 }
 
@@ -41,7 +41,7 @@ func testSocketExistsNewUSDListener(t *testing.T, socketPath string) {
 }
 
 func testWorkingNewUDSListener(t *testing.T, socketPath string) {
-	s, err := NewUDSListener(nil, packetPoolUDS)
+	s, err := NewUDSListener(nil, packetPoolUDS, nil)
 	defer s.Stop()
 
 	assert.Nil(t, err)
@@ -79,7 +79,7 @@ func TestStartStopUDSListener(t *testing.T) {
 	mockConfig := config.Mock()
 	mockConfig.Set("dogstatsd_socket", socketPath)
 	mockConfig.Set("dogstatsd_origin_detection", false)
-	s, err := NewUDSListener(nil, packetPoolUDS)
+	s, err := NewUDSListener(nil, packetPoolUDS, nil)
 	assert.Nil(t, err)
 	assert.NotNil(t, s)
 
@@ -106,7 +106,7 @@ func TestUDSReceive(t *testing.T) {
 	var contents = []byte("daemon:666|g|#sometag1:somevalue1,sometag2:somevalue2")
 
 	packetsChannel := make(chan Packets)
-	s, err := NewUDSListener(packetsChannel, packetPoolUDS)
+	s, err := NewUDSListener(packetsChannel, packetPoolUDS, nil)
 	assert.Nil(t, err)
 	assert.NotNil(t, s)
 
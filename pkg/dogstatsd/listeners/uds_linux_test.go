@@ -33,7 +33,7 @@ func TestUDSPassCred(t *testing.T) {
 	mockConfig.Set("dogstatsd_socket", socketPath)
 	mockConfig.Set("dogstatsd_origin_detection", true)
 
-	s, err := NewUDSListener(nil, NewPacketPool(512))
+	s, err := NewUDSListener(nil, NewPacketPool(512), nil)
 	defer s.Stop()
 
 	assert.Nil(t, err)
@@ -26,6 +26,6 @@ func enableUDSPassCred(conn *net.UnixConn) error {
 }
 
 // processUDSOrigin returns a "not implemented" error on non-linux hosts
-func processUDSOrigin(oob []byte) (string, error) {
-	return NoOrigin, ErrLinuxOnly
+func processUDSOrigin(oob []byte) (int32, string, error) {
+	return 0, NoOrigin, ErrLinuxOnly
 }
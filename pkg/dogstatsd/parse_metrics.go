@@ -24,8 +24,10 @@ var (
 	setSymbol          = []byte("s")
 	timingSymbol       = []byte("ms")
 
-	tagsFieldPrefix       = []byte("#")
-	sampleRateFieldPrefix = []byte("@")
+	tagsFieldPrefix        = []byte("#")
+	sampleRateFieldPrefix  = []byte("@")
+	timestampFieldPrefix   = []byte("T")
+	containerIDFieldPrefix = []byte("c:")
 )
 
 type dogstatsdMetricSample struct {
@@ -39,6 +41,12 @@ type dogstatsdMetricSample struct {
 	metricType metricType
 	sampleRate float64
 	tags       []string
+	// containerID is the ID of the container the client runs in, as
+	// declared by the client
+	containerID string
+	// timestamp is the unix timestamp of the sample in seconds, set by
+	// clients sending pre-aggregated samples. 0 if not set.
+	timestamp int64
 }
 
 // sanity checks a given message against the metric sample format
@@ -47,7 +55,9 @@ func hasMetricSampleFormat(message []byte) bool {
 		return false
 	}
 	separatorCount := bytes.Count(message, fieldSeparator)
-	if separatorCount < 1 || separatorCount > 3 {
+	// name:value|type followed by up to four optional fields:
+	// sample rate, tags, timestamp and container ID
+	if separatorCount < 1 || separatorCount > 5 {
 		return false
 	}
 	return true
@@ -87,3 +97,14 @@ func parseMetricSampleMetricType(rawMetricType []byte) (metricType, error) {
 func parseMetricSampleSampleRate(rawSampleRate []byte) (float64, error) {
 	return parseFloat64(rawSampleRate)
 }
+
+func parseMetricSampleTimestamp(rawTimestamp []byte) (int64, error) {
+	timestamp, err := parseInt64(rawTimestamp)
+	if err != nil {
+		return 0, err
+	}
+	if timestamp <= 0 {
+		return 0, fmt.Errorf("invalid timestamp: %d", timestamp)
+	}
+	return timestamp, nil
+}
@@ -268,6 +268,52 @@ func TestParseGaugeWithUnicode(t *testing.T) {
 	assert.InEpsilon(t, 1.0, sample.sampleRate, epsilon)
 }
 
+func TestParseGaugeWithTimestamp(t *testing.T) {
+	sample, err := parseMetricSample([]byte("daemon:666|g|#sometag:someval|T1617285000"))
+
+	assert.NoError(t, err)
+
+	assert.Equal(t, "daemon", sample.name)
+	assert.InEpsilon(t, 666.0, sample.value, epsilon)
+	assert.Equal(t, gaugeType, sample.metricType)
+	assert.Equal(t, []string{"sometag:someval"}, sample.tags)
+	assert.Equal(t, int64(1617285000), sample.timestamp)
+	assert.Equal(t, "", sample.containerID)
+}
+
+func TestParseCounterWithContainerID(t *testing.T) {
+	sample, err := parseMetricSample([]byte("daemon:21|c|@0.5|#sometag:someval|c:f3a8b2b9e4c1"))
+
+	assert.NoError(t, err)
+
+	assert.Equal(t, "daemon", sample.name)
+	assert.InEpsilon(t, 21.0, sample.value, epsilon)
+	assert.Equal(t, countType, sample.metricType)
+	assert.InEpsilon(t, 0.5, sample.sampleRate, epsilon)
+	assert.Equal(t, []string{"sometag:someval"}, sample.tags)
+	assert.Equal(t, "f3a8b2b9e4c1", sample.containerID)
+	assert.Equal(t, int64(0), sample.timestamp)
+}
+
+func TestParseMetricAllExtendedFields(t *testing.T) {
+	// optional fields can be sent in any order
+	sample, err := parseMetricSample([]byte("daemon:1:2|d|c:f3a8b2b9e4c1|T1617285000|#sometag:someval|@0.1"))
+
+	assert.NoError(t, err)
+
+	assert.Equal(t, "daemon", sample.name)
+	assert.Equal(t, []float64{1, 2}, sample.values)
+	assert.Equal(t, distributionType, sample.metricType)
+	assert.InEpsilon(t, 0.1, sample.sampleRate, epsilon)
+	assert.Equal(t, []string{"sometag:someval"}, sample.tags)
+	assert.Equal(t, "f3a8b2b9e4c1", sample.containerID)
+	assert.Equal(t, int64(1617285000), sample.timestamp)
+
+	// too many fields
+	_, err = parseMetricSample([]byte("daemon:666|g|c:f3a8b2b9e4c1|T1617285000|#sometag:someval|@0.1|m:test"))
+	assert.Error(t, err)
+}
+
 func TestParseMetricError(t *testing.T) {
 	// not enough information
 	_, err := parseMetricSample([]byte("daemon:666"))
@@ -300,4 +346,14 @@ func TestParseMetricError(t *testing.T) {
 	// invalid sample rate
 	_, err = parseMetricSample([]byte("daemon:666|g|@abc"))
 	assert.Error(t, err)
+
+	// invalid timestamps
+	_, err = parseMetricSample([]byte("daemon:666|g|Tabc"))
+	assert.Error(t, err)
+
+	_, err = parseMetricSample([]byte("daemon:666|g|T-1617285000"))
+	assert.Error(t, err)
+
+	_, err = parseMetricSample([]byte("daemon:666|g|T"))
+	assert.Error(t, err)
 }
@@ -0,0 +1,117 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package dogstatsd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/dogstatsd/listeners"
+	"github.com/DataDog/datadog-agent/pkg/dogstatsd/replay"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// Replay injects the datagrams of a capture file in the packets pipeline of the
+// server, with their original origin. The delays between the datagrams are the
+// original ones divided by the speed factor, a speed of 0 replaying the datagrams
+// as fast as the server processes them. It returns the number of datagrams replayed.
+func (s *Server) Replay(path string, speed float64) (int, error) {
+	file, reader, err := openCapture(path, speed)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	return s.replay(path, reader, speed)
+}
+
+// StartReplay replays a capture file like Replay, in the background. Only one
+// replay runs at a time, its outcome is logged.
+func (s *Server) StartReplay(path string, speed float64) error {
+	if !atomic.CompareAndSwapInt32(&s.replaying, 0, 1) {
+		return errors.New("a replay is already in progress")
+	}
+
+	file, reader, err := openCapture(path, speed)
+	if err != nil {
+		atomic.StoreInt32(&s.replaying, 0)
+		return err
+	}
+
+	go func() {
+		defer atomic.StoreInt32(&s.replaying, 0)
+		defer file.Close()
+
+		if count, err := s.replay(path, reader, speed); err != nil {
+			log.Errorf("Unable to replay the dogstatsd capture %s: %s (%d datagrams replayed)", path, err, count)
+		}
+	}()
+	return nil
+}
+
+func openCapture(path string, speed float64) (*os.File, *replay.CaptureReader, error) {
+	if speed < 0 {
+		return nil, nil, fmt.Errorf("invalid replay speed %v", speed)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	reader, err := replay.NewCaptureReader(file)
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+	return file, reader, nil
+}
+
+func (s *Server) replay(path string, reader *replay.CaptureReader, speed float64) (int, error) {
+	log.Infof("Replaying the dogstatsd capture %s", path)
+	var start, firstTimestamp time.Time
+	count := 0
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return count, err
+		}
+
+		if count == 0 {
+			start, firstTimestamp = time.Now(), record.Timestamp
+		} else if speed > 0 {
+			offset := time.Duration(float64(record.Timestamp.Sub(firstTimestamp)) / speed)
+			if wait := time.Until(start.Add(offset)); wait > 0 {
+				select {
+				case <-time.After(wait):
+				case <-s.stopChan:
+					return count, fmt.Errorf("the server was stopped during the replay")
+				}
+			}
+		}
+
+		// the packet is pushed back to the pool by the workers
+		packet := s.sharedPacketPool.Get()
+		packet.CopyContents(record.Payload)
+		packet.Origin = record.Origin
+
+		select {
+		case s.packetsIn <- listeners.Packets{packet}:
+		case <-s.stopChan:
+			return count, fmt.Errorf("the server was stopped during the replay")
+		}
+		count++
+	}
+	log.Infof("Replayed %d datagrams from the dogstatsd capture %s", count, path)
+	return count, nil
+}
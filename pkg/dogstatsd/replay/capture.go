@@ -0,0 +1,207 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package replay
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/telemetry"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+var (
+	tlmCaptureRecords = telemetry.NewCounter("dogstatsd", "capture_records",
+		[]string{"state"}, "Count of datagrams recorded by the dogstatsd traffic capture")
+)
+
+// TrafficCapture records the datagrams received by the dogstatsd listeners to
+// a capture file, for the duration of the capture.
+type TrafficCapture struct {
+	// ongoing is an atomic int used as a boolean, checked by the listeners
+	// for every datagram
+	ongoing uint32
+
+	// records holds the chan *CaptureRecord of the ongoing capture, loaded
+	// by the listeners without locking
+	records atomic.Value
+
+	sync.Mutex
+	path string
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewTrafficCapture returns an idle traffic capture
+func NewTrafficCapture() *TrafficCapture {
+	return &TrafficCapture{}
+}
+
+// IsOngoing returns whether a capture is in progress
+func (tc *TrafficCapture) IsOngoing() bool {
+	return atomic.LoadUint32(&tc.ongoing) == 1
+}
+
+// Path returns the path of the current or last capture file
+func (tc *TrafficCapture) Path() string {
+	tc.Lock()
+	defer tc.Unlock()
+	return tc.path
+}
+
+// Start starts recording the traffic to a new file in the given folder, or in
+// dogstatsd_capture_path if empty, for the given duration. It returns the path
+// of the capture file.
+func (tc *TrafficCapture) Start(dir string, duration time.Duration) (string, error) {
+	tc.Lock()
+	defer tc.Unlock()
+
+	if tc.IsOngoing() {
+		return "", fmt.Errorf("a capture is already in progress, writing to %s", tc.path)
+	}
+	if duration <= 0 {
+		return "", fmt.Errorf("invalid capture duration %s", duration)
+	}
+
+	if dir == "" {
+		dir = GetCaptureDir()
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("unable to create the capture folder: %s", err)
+	}
+	path := filepath.Join(dir, fmt.Sprintf("dogstatsd-capture-%s.dsdcap", time.Now().UTC().Format("20060102T150405.000Z")))
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return "", fmt.Errorf("unable to create the capture file: %s", err)
+	}
+	writer, err := NewCaptureWriter(file)
+	if err != nil {
+		file.Close()
+		return "", fmt.Errorf("unable to write the capture file: %s", err)
+	}
+
+	records := make(chan *CaptureRecord, config.Datadog.GetInt("dogstatsd_capture_depth"))
+	tc.path = path
+	tc.records.Store(records)
+	tc.stop = make(chan struct{})
+	tc.done = make(chan struct{})
+	atomic.StoreUint32(&tc.ongoing, 1)
+
+	go tc.run(file, writer, duration, records, tc.stop, tc.done)
+
+	log.Infof("Capturing the dogstatsd traffic for %s to %s", duration, path)
+	return path, nil
+}
+
+// Stop stops the ongoing capture, if any, and waits for the capture file to be written
+func (tc *TrafficCapture) Stop() {
+	tc.Lock()
+	defer tc.Unlock()
+
+	if !tc.IsOngoing() {
+		return
+	}
+	close(tc.stop)
+	<-tc.done
+}
+
+// Enqueue records a datagram if a capture is ongoing. The payload is copied as
+// the listeners reuse their buffers. The datagram is dropped if the capture
+// can't keep up, as the listeners must never be blocked. A datagram enqueued
+// while the capture stops may not be written.
+func (tc *TrafficCapture) Enqueue(listener string, payload []byte, pid int32, origin string) {
+	if !tc.IsOngoing() {
+		return
+	}
+	records := tc.records.Load().(chan *CaptureRecord)
+
+	record := &CaptureRecord{
+		Timestamp: time.Now(),
+		Listener:  listener,
+		PID:       pid,
+		Origin:    origin,
+		Payload:   append([]byte(nil), payload...),
+	}
+	select {
+	case records <- record:
+		tlmCaptureRecords.Inc("ok")
+	default:
+		tlmCaptureRecords.Inc("dropped")
+	}
+}
+
+func (tc *TrafficCapture) run(file *os.File, writer *CaptureWriter, duration time.Duration, records chan *CaptureRecord, stop chan struct{}, done chan struct{}) {
+	defer close(done)
+
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	write := func(record *CaptureRecord) {
+		if err := writer.Write(record); err != nil {
+			log.Debugf("Unable to write a dogstatsd capture record: %s", err)
+		}
+	}
+
+loop:
+	for {
+		select {
+		case record := <-records:
+			write(record)
+		case <-timer.C:
+			break loop
+		case <-stop:
+			break loop
+		}
+	}
+
+	// stop accepting records then write the pending ones
+	atomic.StoreUint32(&tc.ongoing, 0)
+	for len(records) > 0 {
+		write(<-records)
+	}
+
+	if err := writer.Flush(); err != nil {
+		log.Errorf("Unable to write the dogstatsd capture file %s: %s", file.Name(), err)
+	}
+	if err := file.Close(); err != nil {
+		log.Errorf("Unable to close the dogstatsd capture file %s: %s", file.Name(), err)
+	}
+	log.Infof("Dogstatsd traffic capture written to %s", file.Name())
+}
+
+// GetCaptureDir returns the folder where the capture files are written by default
+func GetCaptureDir() string {
+	if dir := config.Datadog.GetString("dogstatsd_capture_path"); dir != "" {
+		return dir
+	}
+	return filepath.Join(config.Datadog.GetString("run_path"), "dsd_capture")
+}
+
+// GetCaptureFilePath returns the clean path of a capture file, which must be in the capture folder.
+// A relative path is relative to the capture folder.
+func GetCaptureFilePath(file string) (string, error) {
+	dir, err := filepath.Abs(GetCaptureDir())
+	if err != nil {
+		return "", err
+	}
+	dir = filepath.Clean(dir)
+
+	path := file
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	path = filepath.Clean(path)
+	if !strings.HasPrefix(path, dir+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is not in the capture folder %s", file, dir)
+	}
+	return path, nil
+}
@@ -0,0 +1,163 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package replay
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+)
+
+func readCaptureFile(t *testing.T, path string) []*CaptureRecord {
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	reader, err := NewCaptureReader(file)
+	require.NoError(t, err)
+	var records []*CaptureRecord
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return records
+		}
+		require.NoError(t, err)
+		records = append(records, record)
+	}
+}
+
+func TestTrafficCapture(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dsd-capture-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	tc := NewTrafficCapture()
+	assert.False(t, tc.IsOngoing())
+	// nothing is recorded before the capture is started
+	tc.Enqueue("udp", []byte("before:1|c"), 0, "")
+
+	path, err := tc.Start(dir, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, dir, filepath.Dir(path))
+	assert.Equal(t, path, tc.Path())
+	assert.True(t, tc.IsOngoing())
+
+	_, err = tc.Start(dir, time.Minute)
+	assert.Error(t, err)
+
+	payload := []byte("daemon:666|g")
+	tc.Enqueue("udp", payload, 0, "")
+	// the listeners reuse their buffers
+	copy(payload, "modified")
+	tc.Enqueue("uds", []byte("daemon:1|c"), 42, "container_id://abcdef")
+
+	tc.Stop()
+	assert.False(t, tc.IsOngoing())
+	tc.Enqueue("udp", []byte("after:1|c"), 0, "")
+
+	records := readCaptureFile(t, path)
+	require.Len(t, records, 2)
+	assert.Equal(t, "udp", records[0].Listener)
+	assert.Equal(t, []byte("daemon:666|g"), records[0].Payload)
+	assert.Equal(t, "uds", records[1].Listener)
+	assert.Equal(t, int32(42), records[1].PID)
+	assert.Equal(t, "container_id://abcdef", records[1].Origin)
+	assert.Equal(t, []byte("daemon:1|c"), records[1].Payload)
+	assert.False(t, records[1].Timestamp.Before(records[0].Timestamp))
+}
+
+func TestTrafficCaptureConcurrentEnqueue(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dsd-capture-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	tc := NewTrafficCapture()
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					tc.Enqueue("udp", []byte("daemon:1|c"), 0, "")
+				}
+			}
+		}()
+	}
+
+	// the listeners keep enqueuing while captures are started and stopped
+	for i := 0; i < 3; i++ {
+		path, err := tc.Start(dir, time.Minute)
+		require.NoError(t, err)
+		time.Sleep(10 * time.Millisecond)
+		tc.Stop()
+		assert.NotEmpty(t, readCaptureFile(t, path))
+	}
+	close(stop)
+	wg.Wait()
+}
+
+func TestTrafficCaptureDuration(t *testing.T) {
+	mockConfig := config.Mock()
+	dir, err := ioutil.TempDir("", "dsd-capture-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	mockConfig.Set("dogstatsd_capture_path", dir)
+
+	tc := NewTrafficCapture()
+	_, err = tc.Start("", 0)
+	assert.Error(t, err)
+
+	path, err := tc.Start("", 200*time.Millisecond)
+	require.NoError(t, err)
+	assert.Equal(t, dir, filepath.Dir(path))
+	tc.Enqueue("udp", []byte("daemon:666|g"), 0, "")
+
+	assert.Eventually(t, func() bool { return !tc.IsOngoing() }, 5*time.Second, 10*time.Millisecond)
+	// stopping a finished capture is a no-op
+	tc.Stop()
+	assert.Len(t, readCaptureFile(t, path), 1)
+}
+
+func TestGetCaptureFilePath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dsd-capture-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	config.Datadog.Set("dogstatsd_capture_path", dir)
+	defer config.Datadog.Set("dogstatsd_capture_path", "")
+
+	path, err := GetCaptureFilePath(filepath.Join(dir, "capture.dsdcap"))
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "capture.dsdcap"), path)
+
+	path, err = GetCaptureFilePath("capture.dsdcap")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "capture.dsdcap"), path)
+
+	for _, file := range []string{
+		"/etc/passwd",
+		dir,
+		filepath.Join(dir, "..", "capture.dsdcap"),
+		"../capture.dsdcap",
+		dir + "-other/capture.dsdcap",
+	} {
+		_, err = GetCaptureFilePath(file)
+		assert.Error(t, err, file)
+	}
+}
@@ -0,0 +1,127 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package replay
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+const (
+	// fileMagic starts every capture file
+	fileMagic = "DDSDCAP"
+	// fileVersion is bumped when the records format changes
+	fileVersion = byte(1)
+)
+
+// CaptureRecord is a datagram received by a dogstatsd listener
+type CaptureRecord struct {
+	Timestamp time.Time
+	// Listener is the listener the datagram was received on (udp or uds)
+	Listener string
+	// PID is the process ID of the sender from the UDS credentials, 0 if unknown
+	PID int32
+	// Origin is the tagger entity of the sender container, if identified
+	Origin  string
+	Payload []byte
+}
+
+// CaptureWriter writes capture records to a capture file
+type CaptureWriter struct {
+	w *bufio.Writer
+}
+
+// NewCaptureWriter writes the capture file header and returns a writer for the records
+func NewCaptureWriter(w io.Writer) (*CaptureWriter, error) {
+	bw := bufio.NewWriter(w)
+	if _, err := bw.WriteString(fileMagic); err != nil {
+		return nil, err
+	}
+	if err := bw.WriteByte(fileVersion); err != nil {
+		return nil, err
+	}
+	return &CaptureWriter{w: bw}, nil
+}
+
+// Write writes a record, records are buffered until Flush is called
+func (cw *CaptureWriter) Write(record *CaptureRecord) error {
+	if len(record.Listener) > math.MaxUint8 || len(record.Origin) > math.MaxUint16 || len(record.Payload) > math.MaxUint32 {
+		return fmt.Errorf("record too large to be written")
+	}
+
+	var header [8 + 1 + 4 + 2 + 4]byte
+	binary.BigEndian.PutUint64(header[0:8], uint64(record.Timestamp.UnixNano()))
+	header[8] = uint8(len(record.Listener))
+	binary.BigEndian.PutUint32(header[9:13], uint32(record.PID))
+	binary.BigEndian.PutUint16(header[13:15], uint16(len(record.Origin)))
+	binary.BigEndian.PutUint32(header[15:19], uint32(len(record.Payload)))
+
+	if _, err := cw.w.Write(header[:]); err != nil {
+		return err
+	}
+	if _, err := cw.w.WriteString(record.Listener); err != nil {
+		return err
+	}
+	if _, err := cw.w.WriteString(record.Origin); err != nil {
+		return err
+	}
+	_, err := cw.w.Write(record.Payload)
+	return err
+}
+
+// Flush writes the buffered records to the underlying writer
+func (cw *CaptureWriter) Flush() error {
+	return cw.w.Flush()
+}
+
+// CaptureReader reads the records of a capture file
+type CaptureReader struct {
+	r *bufio.Reader
+}
+
+// NewCaptureReader checks the capture file header and returns a reader for the records
+func NewCaptureReader(r io.Reader) (*CaptureReader, error) {
+	br := bufio.NewReader(r)
+	header := make([]byte, len(fileMagic)+1)
+	if _, err := io.ReadFull(br, header); err != nil || string(header[:len(fileMagic)]) != fileMagic {
+		return nil, fmt.Errorf("not a dogstatsd capture file")
+	}
+	if version := header[len(fileMagic)]; version != fileVersion {
+		return nil, fmt.Errorf("unsupported capture file version %d", version)
+	}
+	return &CaptureReader{r: br}, nil
+}
+
+// Read returns the next record of the capture file, or io.EOF at the end of the file
+func (cr *CaptureReader) Read() (*CaptureRecord, error) {
+	var header [8 + 1 + 4 + 2 + 4]byte
+	if _, err := io.ReadFull(cr.r, header[:]); err != nil {
+		if err == io.EOF {
+			return nil, err
+		}
+		return nil, fmt.Errorf("truncated capture record: %s", err)
+	}
+
+	listenerLen := int(header[8])
+	originLen := int(binary.BigEndian.Uint16(header[13:15]))
+	payloadLen := int(binary.BigEndian.Uint32(header[15:19]))
+	data := make([]byte, listenerLen+originLen+payloadLen)
+	if _, err := io.ReadFull(cr.r, data); err != nil {
+		return nil, fmt.Errorf("truncated capture record: %s", err)
+	}
+
+	return &CaptureRecord{
+		Timestamp: time.Unix(0, int64(binary.BigEndian.Uint64(header[0:8]))),
+		Listener:  string(data[:listenerLen]),
+		PID:       int32(binary.BigEndian.Uint32(header[9:13])),
+		Origin:    string(data[listenerLen : listenerLen+originLen]),
+		Payload:   data[listenerLen+originLen:],
+	}, nil
+}
@@ -0,0 +1,81 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package replay
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCaptureFileRoundTrip(t *testing.T) {
+	records := []*CaptureRecord{
+		{
+			Timestamp: time.Unix(1600000000, 123456789),
+			Listener:  "udp",
+			Payload:   []byte("daemon:666|g|#sometag1:somevalue1"),
+		},
+		{
+			Timestamp: time.Unix(1600000001, 0),
+			Listener:  "uds",
+			PID:       4242,
+			Origin:    "container_id://abcdef",
+			Payload:   []byte("daemon:1|c\ndaemon:2|c"),
+		},
+		{
+			Timestamp: time.Unix(1600000002, 0),
+			Listener:  "uds",
+			Payload:   []byte{},
+		},
+	}
+
+	var buf bytes.Buffer
+	writer, err := NewCaptureWriter(&buf)
+	require.NoError(t, err)
+	for _, record := range records {
+		require.NoError(t, writer.Write(record))
+	}
+	require.NoError(t, writer.Flush())
+
+	reader, err := NewCaptureReader(&buf)
+	require.NoError(t, err)
+	for _, expected := range records {
+		record, err := reader.Read()
+		require.NoError(t, err)
+		assert.True(t, expected.Timestamp.Equal(record.Timestamp))
+		assert.Equal(t, expected.Listener, record.Listener)
+		assert.Equal(t, expected.PID, record.PID)
+		assert.Equal(t, expected.Origin, record.Origin)
+		assert.Equal(t, expected.Payload, record.Payload)
+	}
+	_, err = reader.Read()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestCaptureFileInvalid(t *testing.T) {
+	_, err := NewCaptureReader(bytes.NewReader([]byte("daemon:666|g")))
+	assert.EqualError(t, err, "not a dogstatsd capture file")
+
+	_, err = NewCaptureReader(bytes.NewReader([]byte(fileMagic + "\x02")))
+	assert.EqualError(t, err, "unsupported capture file version 2")
+
+	var buf bytes.Buffer
+	writer, err := NewCaptureWriter(&buf)
+	require.NoError(t, err)
+	require.NoError(t, writer.Write(&CaptureRecord{Timestamp: time.Now(), Listener: "udp", Payload: []byte("daemon:666|g")}))
+	require.NoError(t, writer.Flush())
+
+	// the file is cut in the middle of a record
+	reader, err := NewCaptureReader(bytes.NewReader(buf.Bytes()[:buf.Len()-3]))
+	require.NoError(t, err)
+	_, err = reader.Read()
+	assert.Error(t, err)
+	assert.NotEqual(t, io.EOF, err)
+}
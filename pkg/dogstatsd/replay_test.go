@@ -0,0 +1,152 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package dogstatsd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/dogstatsd/replay"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+func receiveSampleNames(t *testing.T, metricOut chan []metrics.MetricSample, count int) []string {
+	var names []string
+	for len(names) < count {
+		select {
+		case samples := <-metricOut:
+			for _, sample := range samples {
+				names = append(names, sample.Name)
+			}
+		case <-time.After(2 * time.Second):
+			require.FailNow(t, "Timeout on receive channel")
+		}
+	}
+	return names
+}
+
+func TestCaptureAndReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dsd-capture-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	port, err := getAvailableUDPPort()
+	require.NoError(t, err)
+	config.Datadog.SetDefault("dogstatsd_port", port)
+
+	agg := mockAggregator()
+	metricOut, _, _ := agg.GetBufferedChannels()
+	s, err := NewServer(agg, nil)
+	require.NoError(t, err, "cannot start DSD")
+	defer s.Stop()
+
+	path, err := s.TCapture.Start(dir, time.Minute)
+	require.NoError(t, err)
+
+	conn, err := net.Dial("udp", fmt.Sprintf("127.0.0.1:%d", port))
+	require.NoError(t, err, "cannot connect to DSD socket")
+	defer conn.Close()
+	conn.Write([]byte("first:1|c"))
+	assert.Equal(t, []string{"first"}, receiveSampleNames(t, metricOut, 1))
+	conn.Write([]byte("second:1|g\nthird:1|g"))
+	assert.ElementsMatch(t, []string{"second", "third"}, receiveSampleNames(t, metricOut, 2))
+
+	s.TCapture.Stop()
+
+	count, err := s.Replay(path, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+	assert.ElementsMatch(t, []string{"first", "second", "third"}, receiveSampleNames(t, metricOut, 3))
+}
+
+func TestReplayTiming(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dsd-capture-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "capture.dsdcap")
+	file, err := os.Create(path)
+	require.NoError(t, err)
+	writer, err := replay.NewCaptureWriter(file)
+	require.NoError(t, err)
+	start := time.Now()
+	require.NoError(t, writer.Write(&replay.CaptureRecord{Timestamp: start, Listener: "uds", Payload: []byte("first:1|c")}))
+	require.NoError(t, writer.Write(&replay.CaptureRecord{Timestamp: start.Add(400 * time.Millisecond), Listener: "uds", Payload: []byte("second:1|c")}))
+	require.NoError(t, writer.Flush())
+	require.NoError(t, file.Close())
+
+	port, err := getAvailableUDPPort()
+	require.NoError(t, err)
+	config.Datadog.SetDefault("dogstatsd_port", port)
+
+	agg := mockAggregator()
+	metricOut, _, _ := agg.GetBufferedChannels()
+	s, err := NewServer(agg, nil)
+	require.NoError(t, err, "cannot start DSD")
+	defer s.Stop()
+
+	// the original delay is divided by the speed factor
+	replayStart := time.Now()
+	count, err := s.Replay(path, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+	assert.True(t, time.Since(replayStart) >= 200*time.Millisecond)
+	assert.ElementsMatch(t, []string{"first", "second"}, receiveSampleNames(t, metricOut, 2))
+
+	_, err = s.Replay(path, -1)
+	assert.Error(t, err)
+	_, err = s.Replay(filepath.Join(dir, "missing.dsdcap"), 1)
+	assert.Error(t, err)
+}
+
+func TestStartReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dsd-capture-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "capture.dsdcap")
+	file, err := os.Create(path)
+	require.NoError(t, err)
+	writer, err := replay.NewCaptureWriter(file)
+	require.NoError(t, err)
+	start := time.Now()
+	require.NoError(t, writer.Write(&replay.CaptureRecord{Timestamp: start, Listener: "uds", Payload: []byte("first:1|c")}))
+	require.NoError(t, writer.Write(&replay.CaptureRecord{Timestamp: start.Add(time.Second), Listener: "uds", Payload: []byte("second:1|c")}))
+	require.NoError(t, writer.Flush())
+	require.NoError(t, file.Close())
+
+	port, err := getAvailableUDPPort()
+	require.NoError(t, err)
+	config.Datadog.SetDefault("dogstatsd_port", port)
+
+	agg := mockAggregator()
+	metricOut, _, _ := agg.GetBufferedChannels()
+	s, err := NewServer(agg, nil)
+	require.NoError(t, err, "cannot start DSD")
+	defer s.Stop()
+
+	// the replay runs in the background
+	replayStart := time.Now()
+	require.NoError(t, s.StartReplay(path, 1))
+	assert.True(t, time.Since(replayStart) < time.Second)
+	assert.Error(t, s.StartReplay(path, 1), "a single replay runs at a time")
+	assert.ElementsMatch(t, []string{"first", "second"}, receiveSampleNames(t, metricOut, 2))
+
+	assert.Eventually(t, func() bool { return s.StartReplay(path, 0) == nil }, 2*time.Second, 10*time.Millisecond)
+	assert.ElementsMatch(t, []string{"first", "second"}, receiveSampleNames(t, metricOut, 2))
+
+	assert.Error(t, s.StartReplay(filepath.Join(dir, "missing.dsdcap"), 1))
+	assert.Error(t, s.StartReplay(path, -1))
+}
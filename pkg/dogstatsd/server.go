@@ -23,6 +23,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/dogstatsd/listeners"
 	"github.com/DataDog/datadog-agent/pkg/dogstatsd/mapper"
+	"github.com/DataDog/datadog-agent/pkg/dogstatsd/replay"
 	"github.com/DataDog/datadog-agent/pkg/metrics"
 	"github.com/DataDog/datadog-agent/pkg/status/health"
 	"github.com/DataDog/datadog-agent/pkg/telemetry"
@@ -83,6 +84,8 @@ type Server struct {
 	histToDistPrefix          string
 	extraTags                 []string
 	Debug                     *dsdServerDebug
+	TCapture                  *replay.TrafficCapture
+	replaying                 int32
 	mapper                    *mapper.MetricMapper
 	eolTerminationEnabled     bool
 	telemetryEnabled          bool
@@ -157,9 +160,12 @@ func NewServer(aggregator *aggregator.BufferedAggregator, extraTags []string) (*
 
 	udsListenerRunning := false
 
+	// the traffic capture is started on demand by the dogstatsd-capture command
+	capture := replay.NewTrafficCapture()
+
 	socketPath := config.Datadog.GetString("dogstatsd_socket")
 	if len(socketPath) > 0 {
-		unixListener, err := listeners.NewUDSListener(packetsChannel, sharedPacketPool)
+		unixListener, err := listeners.NewUDSListener(packetsChannel, sharedPacketPool, capture)
 		if err != nil {
 			log.Errorf(err.Error())
 		} else {
@@ -168,7 +174,7 @@ func NewServer(aggregator *aggregator.BufferedAggregator, extraTags []string) (*
 		}
 	}
 	if config.Datadog.GetInt("dogstatsd_port") > 0 {
-		udpListener, err := listeners.NewUDPListener(packetsChannel, sharedPacketPool)
+		udpListener, err := listeners.NewUDPListener(packetsChannel, sharedPacketPool, capture)
 		if err != nil {
 			log.Errorf(err.Error())
 		} else {
@@ -240,6 +246,7 @@ func NewServer(aggregator *aggregator.BufferedAggregator, extraTags []string) (*
 			},
 			keyGen: ckey.NewKeyGenerator(),
 		},
+		TCapture:           capture,
 		UdsListenerRunning: udsListenerRunning,
 	}
 
@@ -527,6 +534,7 @@ func (s *Server) Stop() {
 	for _, l := range s.listeners {
 		l.Stop()
 	}
+	s.TCapture.Stop()
 	if s.Statistics != nil {
 		s.Statistics.Stop()
 	}
@@ -414,6 +414,47 @@ func TestHistToDist(t *testing.T) {
 	}
 }
 
+func TestUDPReceiveWithTimestamp(t *testing.T) {
+	port, err := getAvailableUDPPort()
+	require.NoError(t, err)
+	defaultPort := config.Datadog.GetInt("dogstatsd_port")
+	config.Datadog.SetDefault("dogstatsd_port", port)
+	defer config.Datadog.SetDefault("dogstatsd_port", defaultPort)
+
+	agg := mockAggregator()
+	metricOut, _, _ := agg.GetBufferedChannels()
+	metricWithTsOut := agg.GetBufferedMetricsWithTsChannel()
+	s, err := NewServer(agg, nil)
+	require.NoError(t, err, "cannot start DSD")
+	defer s.Stop()
+
+	url := fmt.Sprintf("127.0.0.1:%d", config.Datadog.GetInt("dogstatsd_port"))
+	conn, err := net.Dial("udp", url)
+	require.NoError(t, err, "cannot connect to DSD socket")
+	defer conn.Close()
+
+	// timestamped samples are sent on their own channel
+	conn.Write([]byte("daemon:666|g|#sometag1:somevalue1|T1617285000\ndaemon:777|g|#sometag1:somevalue1"))
+	select {
+	case res := <-metricWithTsOut:
+		require.Equal(t, 1, len(res))
+		assert.Equal(t, "daemon", res[0].Name)
+		assert.EqualValues(t, 666.0, res[0].Value)
+		assert.Equal(t, float64(1617285000*time.Second), res[0].Timestamp)
+	case <-time.After(2 * time.Second):
+		assert.FailNow(t, "Timeout on receive channel")
+	}
+	select {
+	case res := <-metricOut:
+		require.Equal(t, 1, len(res))
+		assert.Equal(t, "daemon", res[0].Name)
+		assert.EqualValues(t, 777.0, res[0].Value)
+		assert.Equal(t, 0.0, res[0].Timestamp)
+	case <-time.After(2 * time.Second):
+		assert.FailNow(t, "Timeout on receive channel")
+	}
+}
+
 func TestScanLines(t *testing.T) {
 
 	messages := []string{"foo", "bar", "baz", "quz", "hax", ""}
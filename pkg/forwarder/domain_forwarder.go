@@ -125,6 +125,13 @@ func (f *domainForwarder) addToTransactionContainer(t Transaction) int {
 	return dropCount
 }
 
+// updateAPIKey replaces an API key used by the transactions of the retry queue
+func (f *domainForwarder) updateAPIKey(oldKey, newKey string) {
+	if f.transactionContainer != nil {
+		f.transactionContainer.updateAPIKey(oldKey, newKey)
+	}
+}
+
 func (f *domainForwarder) requeueTransaction(t Transaction) {
 	f.addToTransactionContainer(t)
 	retryQueueSize := f.transactionContainer.getTransactionCount()
@@ -260,7 +260,8 @@ type DefaultForwarder struct {
 	keysPerDomains   map[string][]string
 	healthChecker    *forwarderHealth
 	internalState    uint32
-	m                sync.Mutex // To control Start/Stop races
+	m                sync.Mutex   // To control Start/Stop races
+	keysMutex        sync.RWMutex // To protect keysPerDomains against API key updates
 
 	completionHandler HTTPCompletionHandler
 }
@@ -391,11 +392,13 @@ func (f *DefaultForwarder) Start() error {
 	}
 
 	// log endpoints configuration
+	f.keysMutex.RLock()
 	endpointLogs := make([]string, 0, len(f.keysPerDomains))
 	for domain, apiKeys := range f.keysPerDomains {
 		endpointLogs = append(endpointLogs, fmt.Sprintf("\"%s\" (%v api key(s))",
 			domain, len(apiKeys)))
 	}
+	f.keysMutex.RUnlock()
 	log.Infof("Forwarder started, sending to %v endpoint(s) with %v worker(s) each: %s",
 		len(endpointLogs), f.NumberOfWorkers, strings.Join(endpointLogs, " ; "))
 
@@ -462,11 +465,68 @@ func (f *DefaultForwarder) State() uint32 {
 
 	return f.internalState
 }
+
+// UpdateAPIKey replaces the API key `oldKey` by `newKey` for every domain, e.g. when the
+// API key was rotated in a secret backend. The transactions already created keep `oldKey`
+// except the ones stored on disk.
+func (f *DefaultForwarder) UpdateAPIKey(oldKey, newKey string) {
+	f.m.Lock()
+	defer f.m.Unlock()
+	f.keysMutex.Lock()
+	defer f.keysMutex.Unlock()
+
+	updated := false
+	for domain, apiKeys := range f.keysPerDomains {
+		keys, ok := replaceAPIKey(apiKeys, oldKey, newKey)
+		if !ok {
+			continue
+		}
+		updated = true
+		f.keysPerDomains[domain] = keys
+		if df, found := f.domainForwarders[domain]; found {
+			df.updateAPIKey(oldKey, newKey)
+		}
+	}
+	if !updated {
+		return
+	}
+
+	if f.healthChecker != nil {
+		f.healthChecker.updateAPIKey(oldKey, newKey)
+	}
+	log.Infof("API key ending with %s replaced by API key ending with %s", apiKeySuffix(oldKey), apiKeySuffix(newKey))
+}
+
+// replaceAPIKey returns a copy of apiKeys where `oldKey` is replaced by `newKey`, and
+// whether `oldKey` was found.
+func replaceAPIKey(apiKeys []string, oldKey, newKey string) ([]string, bool) {
+	found := false
+	keys := make([]string, len(apiKeys))
+	for i, k := range apiKeys {
+		if k == oldKey {
+			k = newKey
+			found = true
+		}
+		keys[i] = k
+	}
+	return keys, found
+}
+
+func apiKeySuffix(apiKey string) string {
+	if len(apiKey) > 5 {
+		return apiKey[len(apiKey)-5:]
+	}
+	return apiKey
+}
+
 func (f *DefaultForwarder) createHTTPTransactions(endpoint endpoint, payloads Payloads, apiKeyInQueryString bool, extra http.Header) []*HTTPTransaction {
 	return f.createAdvancedHTTPTransactions(endpoint, payloads, apiKeyInQueryString, extra, TransactionPriorityNormal, true)
 }
 
 func (f *DefaultForwarder) createAdvancedHTTPTransactions(endpoint endpoint, payloads Payloads, apiKeyInQueryString bool, extra http.Header, priority TransactionPriority, storableOnDisk bool) []*HTTPTransaction {
+	f.keysMutex.RLock()
+	defer f.keysMutex.RUnlock()
+
 	transactions := make([]*HTTPTransaction, 0, len(payloads)*len(f.keysPerDomains))
 	allowArbitraryTags := config.Datadog.GetBool("allow_arbitrary_tags")
 
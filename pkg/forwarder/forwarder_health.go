@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"net/http"
 	"regexp"
+	"sync"
 	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/status/health"
@@ -56,12 +57,15 @@ type forwarderHealth struct {
 	keysPerAPIEndpoint    map[string][]string
 	disableAPIKeyChecking bool
 	validationInterval    time.Duration
+	keyMapMutex           sync.Mutex
 }
 
 func (fh *forwarderHealth) init() {
 	fh.stop = make(chan bool, 1)
 	fh.stopped = make(chan struct{})
 
+	fh.keyMapMutex.Lock()
+	defer fh.keyMapMutex.Unlock()
 	fh.keysPerAPIEndpoint = make(map[string][]string)
 	fh.computeDomainsURL()
 
@@ -141,6 +145,24 @@ func (fh *forwarderHealth) computeDomainsURL() {
 	}
 }
 
+// updateAPIKey replaces the API key `oldKey` by `newKey`
+func (fh *forwarderHealth) updateAPIKey(oldKey, newKey string) {
+	fh.keyMapMutex.Lock()
+	defer fh.keyMapMutex.Unlock()
+
+	// keysPerDomains is shared with the forwarder options, build a new map
+	keysPerDomains := make(map[string][]string, len(fh.keysPerDomains))
+	for domain, apiKeys := range fh.keysPerDomains {
+		keysPerDomains[domain], _ = replaceAPIKey(apiKeys, oldKey, newKey)
+	}
+	fh.keysPerDomains = keysPerDomains
+	for domain, apiKeys := range fh.keysPerAPIEndpoint {
+		if keys, updated := replaceAPIKey(apiKeys, oldKey, newKey); updated {
+			fh.keysPerAPIEndpoint[domain] = keys
+		}
+	}
+}
+
 func (fh *forwarderHealth) setAPIKeyStatus(apiKey string, domain string, status expvar.Var) {
 	if len(apiKey) > 5 {
 		apiKey = apiKey[len(apiKey)-5:]
@@ -196,7 +218,15 @@ func (fh *forwarderHealth) hasValidAPIKey() bool {
 	validKey := false
 	apiError := false
 
+	// Copy the API keys as the validation can take some time
+	fh.keyMapMutex.Lock()
+	keysPerAPIEndpoint := make(map[string][]string, len(fh.keysPerAPIEndpoint))
 	for domain, apiKeys := range fh.keysPerAPIEndpoint {
+		keysPerAPIEndpoint[domain] = apiKeys
+	}
+	fh.keyMapMutex.Unlock()
+
+	for domain, apiKeys := range keysPerAPIEndpoint {
 		for _, apiKey := range apiKeys {
 			v, err := fh.validateAPIKey(apiKey, domain)
 			if err != nil {
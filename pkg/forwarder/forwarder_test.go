@@ -202,6 +202,27 @@ func TestCreateHTTPTransactionsWithMultipleDomains(t *testing.T) {
 	assert.Equal(t, txBar[0].Endpoint.route, "/api/foo?api_key=api-key-3")
 }
 
+func TestUpdateAPIKey(t *testing.T) {
+	forwarder := NewDefaultForwarder(NewOptions(keysWithMultipleDomains))
+	endpoint := endpoint{"/api/foo", "foo"}
+	p1 := []byte("A payload")
+	payloads := Payloads{&p1}
+
+	forwarder.UpdateAPIKey("api-key-2", "api-key-4")
+	forwarder.UpdateAPIKey("unknown-key", "api-key-5")
+
+	transactions := forwarder.createHTTPTransactions(endpoint, payloads, false, nil)
+	var apiKeys []string
+	for _, t := range transactions {
+		apiKeys = append(apiKeys, t.Headers.Get("DD-Api-Key"))
+	}
+	assert.ElementsMatch(t, []string{"api-key-1", "api-key-4", "api-key-3"}, apiKeys)
+	assert.ElementsMatch(t, []string{"api-key-1", "api-key-4"}, forwarder.healthChecker.keysPerDomains[testDomain])
+
+	// the options are left untouched
+	assert.Equal(t, []string{"api-key-1", "api-key-2"}, keysWithMultipleDomains[testDomain])
+}
+
 func TestArbitraryTagsHTTPHeader(t *testing.T) {
 	mockConfig := config.Mock()
 	mockConfig.Set("allow_arbitrary_tags", true)
@@ -16,6 +16,7 @@ import (
 type transactionStorage interface {
 	Serialize([]Transaction) error
 	Deserialize() ([]Transaction, error)
+	UpdateAPIKey(oldKey, newKey string)
 }
 
 type transactionPrioritySorter interface {
@@ -174,6 +175,16 @@ func (tc *transactionContainer) getMaxMemSizeInBytes() int {
 	return tc.maxMemSizeInBytes
 }
 
+// updateAPIKey replaces an API key used by the transactions stored on disk
+func (tc *transactionContainer) updateAPIKey(oldKey, newKey string) {
+	tc.mutex.Lock()
+	defer tc.mutex.Unlock()
+
+	if tc.optionalTransactionStorage != nil {
+		tc.optionalTransactionStorage.UpdateAPIKey(oldKey, newKey)
+	}
+}
+
 func (tc *transactionContainer) extractTransactionsForDisk(payloadSize int) [][]Transaction {
 	sizeInBytesToFlush := int(float64(tc.maxMemSizeInBytes) * tc.flushToStorageRatio)
 	var payloadsGroupToFlush [][]Transaction
@@ -97,6 +97,61 @@ func (s *transactionsFileStorage) Serialize(transactions []Transaction) error {
 	return nil
 }
 
+// UpdateAPIKey replaces the API key `oldKey` by `newKey` for the transactions stored on the file system.
+// The placeholder of an API key depends on its position in the sorted API keys, so the stored
+// transactions are serialized again with the placeholders of the new API keys.
+func (s *transactionsFileStorage) UpdateAPIKey(oldKey, newKey string) {
+	apiKeys, found := replaceAPIKey(s.serializer.apiKeys, oldKey, newKey)
+	if !found {
+		return
+	}
+	deserializer := s.serializer.withRestoredAPIKey(oldKey, newKey)
+	serializer := NewTransactionsSerializer(s.serializer.domain, apiKeys)
+	for i := len(s.filenames) - 1; i >= 0; i-- {
+		filename := s.filenames[i]
+		if err := s.reserializeFile(filename, deserializer, serializer); err != nil {
+			// Drop the file so its transactions are never sent with a stale API key.
+			log.Errorf("Cannot update the API key of the transactions stored in %s, removing it: %v", filename, err)
+			if err := s.removeFileAt(i); err != nil {
+				log.Errorf("Cannot remove %s: %v", filename, err)
+			}
+		}
+	}
+	s.serializer = serializer
+	s.telemetry.setCurrentSizeInBytes(s.getCurrentSizeInBytes())
+	s.telemetry.setFilesCount(s.getFilesCount())
+}
+
+// reserializeFile deserializes the transactions stored in the file and stores them again
+// using the given serializer.
+func (s *transactionsFileStorage) reserializeFile(filename string, deserializer, serializer *TransactionsSerializer) error {
+	bytes, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	transactions, _, err := deserializer.Deserialize(bytes)
+	if err != nil {
+		return err
+	}
+
+	// Reset the serializer in case a previous file failed to be serialized.
+	_, _ = serializer.GetBytesAndReset()
+	for _, t := range transactions {
+		if err := t.SerializeTo(serializer); err != nil {
+			return err
+		}
+	}
+	newBytes, err := serializer.GetBytesAndReset()
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filename, newBytes, 0600); err != nil {
+		return err
+	}
+	s.currentSizeInBytes += int64(len(newBytes) - len(bytes))
+	return nil
+}
+
 // Deserialize deserializes a transactions from the file system.
 func (s *transactionsFileStorage) Deserialize() ([]Transaction, error) {
 	if len(s.filenames) == 0 {
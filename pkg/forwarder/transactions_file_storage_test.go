@@ -83,6 +83,55 @@ func TestTransactionsFileStorageReloadExistingRetryFiles(t *testing.T) {
 	a.Equal([]string{"endpoint1", "endpoint2"}, getEndpointsFromTransactions(transactions))
 }
 
+func TestTransactionsFileStorageUpdateAPIKey(t *testing.T) {
+	a := assert.New(t)
+	path, clean := createTmpFolder(a)
+	defer clean()
+
+	// Replacing "c-key" by "a-key" changes the order of the API keys and so their placeholders.
+	telemetry := transactionsFileStorageTelemetry{}
+	storage, err := newTransactionsFileStorage(NewTransactionsSerializer(domainName, []string{"b-key", "c-key"}), path, 1000, telemetry)
+	a.NoError(err)
+	a.NoError(storage.Serialize(createHTTPTransactionWithKeysTests("b-key", "c-key")))
+
+	storage.UpdateAPIKey("c-key", "a-key")
+	storage.UpdateAPIKey("unknown-key", "d-key")
+	a.Equal(1, storage.getFilesCount())
+
+	// A storage created with the new API keys reads the files of the previous storage.
+	newStorage, err := newTransactionsFileStorage(NewTransactionsSerializer(domainName, []string{"b-key", "a-key"}), path, 1000, telemetry)
+	a.NoError(err)
+	a.Equal(storage.getCurrentSizeInBytes(), newStorage.getCurrentSizeInBytes())
+	transactions, err := newStorage.Deserialize()
+	a.NoError(err)
+	a.Equal([]string{"b-key", "a-key"}, getAPIKeysFromTransactions(transactions))
+
+	a.NoError(storage.Serialize(createHTTPTransactionWithKeysTests("a-key")))
+	transactions, err = storage.Deserialize()
+	a.NoError(err)
+	a.Equal([]string{"a-key"}, getAPIKeysFromTransactions(transactions))
+}
+
+func createHTTPTransactionWithKeysTests(apiKeys ...string) []Transaction {
+	var transactions []Transaction
+
+	for _, k := range apiKeys {
+		t := NewHTTPTransaction()
+		t.Domain = domainName
+		t.Headers.Set("DD-Api-Key", k)
+		transactions = append(transactions, t)
+	}
+	return transactions
+}
+
+func getAPIKeysFromTransactions(transactions []Transaction) []string {
+	var apiKeys []string
+	for _, t := range transactions {
+		apiKeys = append(apiKeys, t.(*HTTPTransaction).Headers.Get("DD-Api-Key"))
+	}
+	return apiKeys
+}
+
 func createHTTPTransactionCollectionTests(endpoints ...string) []Transaction {
 	var transactions []Transaction
 
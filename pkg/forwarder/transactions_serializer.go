@@ -33,6 +33,7 @@ type TransactionsSerializer struct {
 	collection          HttpTransactionProtoCollection
 	apiKeyToPlaceholder *strings.Replacer
 	placeholderToAPIKey *strings.Replacer
+	apiKeys             []string
 	domain              string
 }
 
@@ -46,6 +47,7 @@ func NewTransactionsSerializer(domain string, apiKeys []string) *TransactionsSer
 		},
 		apiKeyToPlaceholder: apiKeyToPlaceholder,
 		placeholderToAPIKey: placeholderToAPIKey,
+		apiKeys:             apiKeys,
 		domain:              domain,
 	}
 }
@@ -137,6 +139,21 @@ func (s *TransactionsSerializer) Deserialize(bytes []byte) ([]Transaction, int,
 	return httpTransactions, errorCount, nil
 }
 
+// withRestoredAPIKey returns a serializer which deserializes the transactions serialized by `s`,
+// restoring the API key `oldKey` as `newKey`.
+func (s *TransactionsSerializer) withRestoredAPIKey(oldKey, newKey string) *TransactionsSerializer {
+	var placeholderToAPIKey []string
+	for i, k := range sortAPIKeys(s.apiKeys) {
+		if k == oldKey {
+			k = newKey
+		}
+		placeholderToAPIKey = append(placeholderToAPIKey, fmt.Sprintf(placeHolderFormat, i), k)
+	}
+	serializer := NewTransactionsSerializer(s.domain, s.apiKeys)
+	serializer.placeholderToAPIKey = strings.NewReplacer(placeholderToAPIKey...)
+	return serializer
+}
+
 func (s *TransactionsSerializer) replaceAPIKeys(str string) string {
 	return s.apiKeyToPlaceholder.Replace(str)
 }
@@ -198,18 +215,22 @@ func toTransactionPriorityProto(priority TransactionPriority) (TransactionPriori
 }
 
 func createReplacers(apiKeys []string) (*strings.Replacer, *strings.Replacer) {
-	// Copy to not modify apiKeys order
-	keys := make([]string, len(apiKeys))
-	copy(keys, apiKeys)
-
-	// Sort to always have the same order
-	sort.Strings(keys)
 	var apiKeyPlaceholder []string
 	var placeholderToAPIKey []string
-	for i, k := range keys {
+	for i, k := range sortAPIKeys(apiKeys) {
 		placeholder := fmt.Sprintf(placeHolderFormat, i)
 		apiKeyPlaceholder = append(apiKeyPlaceholder, k, placeholder)
 		placeholderToAPIKey = append(placeholderToAPIKey, placeholder, k)
 	}
 	return strings.NewReplacer(apiKeyPlaceholder...), strings.NewReplacer(placeholderToAPIKey...)
 }
+
+func sortAPIKeys(apiKeys []string) []string {
+	// Copy to not modify apiKeys order
+	keys := make([]string, len(apiKeys))
+	copy(keys, apiKeys)
+
+	// Sort to always have the same order
+	sort.Strings(keys)
+	return keys
+}
@@ -6,6 +6,8 @@
 package logs
 
 import (
+	"path/filepath"
+	"strconv"
 	"time"
 
 	coreConfig "github.com/DataDog/datadog-agent/pkg/config"
@@ -15,6 +17,7 @@ import (
 
 	"github.com/DataDog/datadog-agent/pkg/logs/auditor"
 	"github.com/DataDog/datadog-agent/pkg/logs/client"
+	"github.com/DataDog/datadog-agent/pkg/logs/client/http"
 	"github.com/DataDog/datadog-agent/pkg/logs/config"
 	"github.com/DataDog/datadog-agent/pkg/logs/diagnostic"
 	"github.com/DataDog/datadog-agent/pkg/logs/input/channel"
@@ -22,10 +25,12 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/logs/input/file"
 	"github.com/DataDog/datadog-agent/pkg/logs/input/journald"
 	"github.com/DataDog/datadog-agent/pkg/logs/input/listener"
+	"github.com/DataDog/datadog-agent/pkg/logs/input/syslog"
 	"github.com/DataDog/datadog-agent/pkg/logs/input/traps"
 	"github.com/DataDog/datadog-agent/pkg/logs/input/windowsevent"
 	"github.com/DataDog/datadog-agent/pkg/logs/pipeline"
 	"github.com/DataDog/datadog-agent/pkg/logs/restart"
+	"github.com/DataDog/datadog-agent/pkg/logs/sender"
 	"github.com/DataDog/datadog-agent/pkg/logs/service"
 )
 
@@ -39,6 +44,7 @@ import (
 type Agent struct {
 	auditor                   auditor.Auditor
 	destinationsCtx           *client.DestinationsContext
+	diskBuffers               []*sender.DiskBuffer
 	pipelineProvider          pipeline.Provider
 	inputs                    []restart.Restartable
 	health                    *health.Handle
@@ -58,7 +64,13 @@ func NewAgent(sources *config.LogSources, services *service.Services, processing
 	diagnosticMessageReceiver := diagnostic.NewBufferedMessageReceiver()
 
 	// setup the pipeline provider that provides pairs of processor and sender
-	pipelineProvider := pipeline.NewProvider(config.NumberOfPipelines, auditor, diagnosticMessageReceiver, processingRules, endpoints, destinationsCtx)
+	var pipelineProvider pipeline.Provider
+	diskBuffers := newDiskBuffers(config.NumberOfPipelines, endpoints, destinationsCtx)
+	if diskBuffers != nil {
+		pipelineProvider = pipeline.NewProviderWithDiskBuffers(config.NumberOfPipelines, auditor, diagnosticMessageReceiver, processingRules, endpoints, destinationsCtx, diskBuffers)
+	} else {
+		pipelineProvider = pipeline.NewProvider(config.NumberOfPipelines, auditor, diagnosticMessageReceiver, processingRules, endpoints, destinationsCtx)
+	}
 
 	// setup the inputs
 	inputs := []restart.Restartable{
@@ -70,6 +82,7 @@ func NewAgent(sources *config.LogSources, services *service.Services, processing
 			time.Duration(coreConfig.Datadog.GetInt("logs_config.docker_client_read_timeout"))*time.Second,
 			sources, services, pipelineProvider, auditor),
 		listener.NewLauncher(sources, coreConfig.Datadog.GetInt("logs_config.frame_size"), pipelineProvider),
+		syslog.NewLauncher(sources, coreConfig.Datadog.GetInt("logs_config.frame_size"), pipelineProvider),
 		journald.NewLauncher(sources, pipelineProvider, auditor),
 		windowsevent.NewLauncher(sources, pipelineProvider),
 		traps.NewLauncher(sources, pipelineProvider),
@@ -78,6 +91,7 @@ func NewAgent(sources *config.LogSources, services *service.Services, processing
 	return &Agent{
 		auditor:                   auditor,
 		destinationsCtx:           destinationsCtx,
+		diskBuffers:               diskBuffers,
 		pipelineProvider:          pipelineProvider,
 		inputs:                    inputs,
 		health:                    health,
@@ -85,6 +99,34 @@ func NewAgent(sources *config.LogSources, services *service.Services, processing
 	}
 }
 
+// newDiskBuffers returns the buffers spooling on disk the payloads of each pipeline while the main
+// HTTP destination is unreachable, nil when they are disabled or could not be created. The maximum
+// size is split evenly between the buffers, which are written in a directory per pipeline.
+func newDiskBuffers(numberOfPipelines int, endpoints *config.Endpoints, destinationsCtx *client.DestinationsContext) []*sender.DiskBuffer {
+	maxSizeInBytes := coreConfig.Datadog.GetInt64("logs_config.storage_max_size_in_bytes")
+	if maxSizeInBytes <= 0 || !endpoints.UseHTTP {
+		return nil
+	}
+	storagePath := coreConfig.Datadog.GetString("logs_config.storage_path")
+	if storagePath == "" {
+		storagePath = filepath.Join(coreConfig.Datadog.GetString("logs_config.run_path"), "logs_to_retry")
+	}
+	diskBuffers := make([]*sender.DiskBuffer, 0, numberOfPipelines)
+	for i := 0; i < numberOfPipelines; i++ {
+		// the payloads are replayed with a destination of their own so that they don't
+		// delay the pipeline while it writes its new payloads to the buffer
+		destination := http.NewDestination(endpoints.Main, http.JSONContentType, destinationsCtx)
+		path := filepath.Join(storagePath, strconv.Itoa(i))
+		diskBuffer, err := sender.NewDiskBuffer(path, maxSizeInBytes/int64(numberOfPipelines), destination)
+		if err != nil {
+			log.Errorf("Could not create the disk buffer in %s, logs will only be buffered in memory: %v", path, err)
+			return nil
+		}
+		diskBuffers = append(diskBuffers, diskBuffer)
+	}
+	return diskBuffers
+}
+
 // NewServerless returns a Logs Agent instance to run in a serverless environment.
 // The Serverless Logs Agent has only one input being the channel to receive the logs to process.
 // It is using a NullAuditor because we've nothing to do after having sent the logs to the intake.
@@ -116,6 +158,9 @@ func NewServerless(sources *config.LogSources, services *service.Services, proce
 // in the right order to prevent data loss
 func (a *Agent) Start() {
 	starter := restart.NewStarter(a.destinationsCtx, a.auditor, a.pipelineProvider, a.diagnosticMessageReceiver)
+	for _, diskBuffer := range a.diskBuffers {
+		starter.Add(diskBuffer)
+	}
 	for _, input := range a.inputs {
 		starter.Add(input)
 	}
@@ -134,13 +179,17 @@ func (a *Agent) Stop() {
 	for _, input := range a.inputs {
 		inputs.Add(input)
 	}
-	stopper := restart.NewSerialStopper(
-		inputs,
-		a.pipelineProvider,
-		a.auditor,
-		a.destinationsCtx,
-		a.diagnosticMessageReceiver,
-	)
+	components := []restart.Stoppable{inputs, a.pipelineProvider}
+	if len(a.diskBuffers) > 0 {
+		// the payloads left in the buffers are replayed on the next start
+		diskBuffers := restart.NewParallelStopper()
+		for _, diskBuffer := range a.diskBuffers {
+			diskBuffers.Add(diskBuffer)
+		}
+		components = append(components, diskBuffers)
+	}
+	components = append(components, a.auditor, a.destinationsCtx, a.diagnosticMessageReceiver)
+	stopper := restart.NewSerialStopper(components...)
 
 	// This will try to stop everything in order, including the potentially blocking
 	// parts like the sender. After StopTimeout it will just stop the last part of the
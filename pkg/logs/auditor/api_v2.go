@@ -7,21 +7,34 @@ package auditor
 
 import (
 	"encoding/json"
+	"time"
 )
 
 // v2: In the third version of the auditor, we dropped Timestamp and used a generic Offset instead to reinforce the separation of concerns
 // between the auditor and log sources.
 
+type registryEntryV2 struct {
+	LastUpdated time.Time
+	Offset      string
+	TailingMode string
+}
+
+type jsonRegistryV2 struct {
+	Version  int
+	Registry map[string]registryEntryV2
+}
+
 func unmarshalRegistryV2(b []byte) (map[string]*RegistryEntry, error) {
-	var r JSONRegistry
+	var r jsonRegistryV2
 	err := json.Unmarshal(b, &r)
 	if err != nil {
 		return nil, err
 	}
 	registry := make(map[string]*RegistryEntry)
 	for identifier, entry := range r.Registry {
-		newEntry := entry
-		registry[identifier] = &newEntry
+		// the fingerprints are unknown until the files are tailed again,
+		// the offsets are matched on the identifiers in the meantime
+		registry[identifier] = &RegistryEntry{LastUpdated: entry.LastUpdated, Offset: entry.Offset, TailingMode: entry.TailingMode}
 	}
 	return registry, nil
 }
@@ -0,0 +1,44 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package auditor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditorUnmarshalRegistryV3(t *testing.T) {
+	input := `{
+	    "Registry": {
+	        "path1.log": {
+	            "Offset": "1",
+	            "LastUpdated": "2006-01-12T01:01:01.000000001Z",
+	            "TailingMode": "end",
+	            "Fingerprint": 12345678901234567890,
+	            "Completed": true
+	        },
+	        "path2.log": {
+	            "Offset": "2006-01-12T01:01:03.000000001Z",
+	            "LastUpdated": "2006-01-12T01:01:02.000000001Z"
+	        }
+	    },
+	    "Version": 3
+	}`
+	r, err := unmarshalRegistryV3([]byte(input))
+	assert.Nil(t, err)
+
+	assert.Equal(t, "1", r["path1.log"].Offset)
+	assert.Equal(t, 1, r["path1.log"].LastUpdated.Second())
+	assert.Equal(t, "end", r["path1.log"].TailingMode)
+	assert.Equal(t, uint64(12345678901234567890), r["path1.log"].Fingerprint)
+	assert.True(t, r["path1.log"].Completed)
+
+	assert.Equal(t, "2006-01-12T01:01:03.000000001Z", r["path2.log"].Offset)
+	assert.Equal(t, 2, r["path2.log"].LastUpdated.Second())
+	assert.Equal(t, uint64(0), r["path2.log"].Fingerprint)
+	assert.False(t, r["path2.log"].Completed)
+}
@@ -28,12 +28,15 @@ const defaultFlushPeriod = 1 * time.Second
 const defaultCleanupPeriod = 300 * time.Second
 
 // latest version of the API used by the auditor to retrieve the registry from disk.
-const registryAPIVersion = 2
+const registryAPIVersion = 3
 
 // Registry holds a list of offsets.
 type Registry interface {
 	GetOffset(identifier string) string
 	GetTailingMode(identifier string) string
+	GetFingerprint(identifier string) uint64
+	GetIdentifierByFingerprint(fingerprint uint64) string
+	IsCompleted(identifier string) bool
 }
 
 // A RegistryEntry represents an entry in the registry where we keep track
@@ -42,6 +45,10 @@ type RegistryEntry struct {
 	LastUpdated time.Time
 	Offset      string
 	TailingMode string
+	// Fingerprint identifies the content of the file the offset belongs to, 0 when unknown
+	Fingerprint uint64 `json:",omitempty"`
+	// Completed is set once a file which is not expected to change, like a compressed one, has been entirely read
+	Completed bool `json:",omitempty"`
 }
 
 // JSONRegistry represents the registry that will be written on disk
@@ -145,6 +152,45 @@ func (a *RegistryAuditor) GetTailingMode(identifier string) string {
 	return entry.TailingMode
 }
 
+// GetFingerprint returns the fingerprint of the file the last committed offset
+// of a given identifier belongs to, returns 0 if it does not exist or is unknown.
+func (a *RegistryAuditor) GetFingerprint(identifier string) uint64 {
+	r := a.readOnlyRegistryCopy()
+	entry, exists := r[identifier]
+	if !exists {
+		return 0
+	}
+	return entry.Fingerprint
+}
+
+// GetIdentifierByFingerprint returns the identifier of the most recently updated
+// entry having a given fingerprint, returns an empty string if it does not exist.
+func (a *RegistryAuditor) GetIdentifierByFingerprint(fingerprint uint64) string {
+	if fingerprint == 0 {
+		return ""
+	}
+	r := a.readOnlyRegistryCopy()
+	var identifier string
+	var lastUpdated time.Time
+	for id, entry := range r {
+		if entry.Fingerprint == fingerprint && (identifier == "" || entry.LastUpdated.After(lastUpdated)) {
+			identifier, lastUpdated = id, entry.LastUpdated
+		}
+	}
+	return identifier
+}
+
+// IsCompleted returns whether the file of a given identifier has been entirely read,
+// returns false if it does not exist.
+func (a *RegistryAuditor) IsCompleted(identifier string) bool {
+	r := a.readOnlyRegistryCopy()
+	entry, exists := r[identifier]
+	if !exists {
+		return false
+	}
+	return entry.Completed
+}
+
 // run keeps up to date the registry depending on different events
 func (a *RegistryAuditor) run() {
 	cleanUpTicker := time.NewTicker(defaultCleanupPeriod)
@@ -166,7 +212,7 @@ func (a *RegistryAuditor) run() {
 				return
 			}
 			// update the registry with new entry
-			a.updateRegistry(msg.Origin.Identifier, msg.Origin.Offset, msg.Origin.LogSource.Config.TailingMode)
+			a.updateRegistry(msg.Origin.Identifier, msg.Origin.Offset, msg.Origin.LogSource.Config.TailingMode, msg.Origin.Fingerprint, msg.Origin.Completed)
 		case <-cleanUpTicker.C:
 			// remove expired offsets from registry
 			a.cleanupRegistry()
@@ -217,8 +263,8 @@ func (a *RegistryAuditor) cleanupRegistry() {
 	}
 }
 
-// updateRegistry updates the registry entry matching identifier with new the offset and timestamp
-func (a *RegistryAuditor) updateRegistry(identifier string, offset string, tailingMode string) {
+// updateRegistry updates the registry entry matching identifier with new the offset, fingerprint, completion and timestamp
+func (a *RegistryAuditor) updateRegistry(identifier string, offset string, tailingMode string, fingerprint uint64, completed bool) {
 	a.registryMutex.Lock()
 	defer a.registryMutex.Unlock()
 	if identifier == "" {
@@ -231,6 +277,8 @@ func (a *RegistryAuditor) updateRegistry(identifier string, offset string, taili
 		LastUpdated: time.Now().UTC(),
 		Offset:      offset,
 		TailingMode: tailingMode,
+		Fingerprint: fingerprint,
+		Completed:   completed,
 	}
 }
 
@@ -277,6 +325,8 @@ func (a *RegistryAuditor) unmarshalRegistry(b []byte) (map[string]*RegistryEntry
 	}
 	// ensure backward compatibility
 	switch int(version) {
+	case 3:
+		return unmarshalRegistryV3(b)
 	case 2:
 		return unmarshalRegistryV2(b)
 	case 1:
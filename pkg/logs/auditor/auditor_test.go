@@ -62,14 +62,18 @@ func (suite *AuditorTestSuite) TestAuditorStartStop() {
 func (suite *AuditorTestSuite) TestAuditorUpdatesRegistry() {
 	suite.a.registry = make(map[string]*RegistryEntry)
 	suite.Equal(0, len(suite.a.registry))
-	suite.a.updateRegistry(suite.source.Config.Path, "42", "end")
+	suite.a.updateRegistry(suite.source.Config.Path, "42", "end", 0, false)
 	suite.Equal(1, len(suite.a.registry))
 	suite.Equal("42", suite.a.registry[suite.source.Config.Path].Offset)
 	suite.Equal("end", suite.a.registry[suite.source.Config.Path].TailingMode)
-	suite.a.updateRegistry(suite.source.Config.Path, "43", "beginning")
+	suite.a.updateRegistry(suite.source.Config.Path, "43", "beginning", 1234, false)
 	suite.Equal(1, len(suite.a.registry))
 	suite.Equal("43", suite.a.registry[suite.source.Config.Path].Offset)
 	suite.Equal("beginning", suite.a.registry[suite.source.Config.Path].TailingMode)
+	suite.Equal(uint64(1234), suite.a.registry[suite.source.Config.Path].Fingerprint)
+	suite.False(suite.a.IsCompleted(suite.source.Config.Path))
+	suite.a.updateRegistry(suite.source.Config.Path, "44", "beginning", 1234, true)
+	suite.True(suite.a.IsCompleted(suite.source.Config.Path))
 }
 
 func (suite *AuditorTestSuite) TestAuditorFlushesAndRecoversRegistry() {
@@ -82,7 +86,7 @@ func (suite *AuditorTestSuite) TestAuditorFlushesAndRecoversRegistry() {
 	suite.a.flushRegistry()
 	r, err := ioutil.ReadFile(suite.testPath)
 	suite.Nil(err)
-	suite.Equal("{\"Version\":2,\"Registry\":{\"testpath\":{\"LastUpdated\":\"2006-01-12T01:01:01.000000001Z\",\"Offset\":\"42\",\"TailingMode\":\"end\"}}}", string(r))
+	suite.Equal("{\"Version\":3,\"Registry\":{\"testpath\":{\"LastUpdated\":\"2006-01-12T01:01:01.000000001Z\",\"Offset\":\"42\",\"TailingMode\":\"end\"}}}", string(r))
 
 	suite.a.registry = make(map[string]*RegistryEntry)
 	suite.a.registry = suite.a.recoverRegistry()
@@ -123,6 +127,32 @@ func (suite *AuditorTestSuite) TestAuditorCleansupRegistry() {
 	suite.Equal("43", suite.a.registry[otherpath].Offset)
 }
 
+func (suite *AuditorTestSuite) TestAuditorRecoversRegistryForFingerprint() {
+	suite.a.registry = make(map[string]*RegistryEntry)
+	suite.a.registry["file:/var/log/app.log"] = &RegistryEntry{
+		LastUpdated: time.Date(2006, time.January, 12, 1, 1, 1, 1, time.UTC),
+		Offset:      "42",
+		Fingerprint: 1234,
+	}
+	suite.a.registry["file:/var/log/app.log.1"] = &RegistryEntry{
+		LastUpdated: time.Date(2006, time.January, 12, 1, 1, 2, 1, time.UTC),
+		Offset:      "43",
+		Fingerprint: 1234,
+	}
+	suite.a.registry["container_id:abc"] = &RegistryEntry{
+		Offset: "2006-01-12T01:01:03.000000001Z",
+	}
+
+	suite.Equal(uint64(1234), suite.a.GetFingerprint("file:/var/log/app.log"))
+	suite.Equal(uint64(0), suite.a.GetFingerprint("container_id:abc"))
+	suite.Equal(uint64(0), suite.a.GetFingerprint("file:/var/log/other.log"))
+
+	// the most recently updated entry is returned
+	suite.Equal("file:/var/log/app.log.1", suite.a.GetIdentifierByFingerprint(1234))
+	suite.Equal("", suite.a.GetIdentifierByFingerprint(5678))
+	suite.Equal("", suite.a.GetIdentifierByFingerprint(0))
+}
+
 func TestScannerTestSuite(t *testing.T) {
 	suite.Run(t, new(AuditorTestSuite))
 }
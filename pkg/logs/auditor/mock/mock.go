@@ -7,17 +7,27 @@ package mock
 
 // Registry does nothing
 type Registry struct {
-	offset      string
-	tailingMode string
+	offset       string
+	offsets      map[string]string
+	tailingMode  string
+	fingerprints map[string]uint64
+	completed    map[string]bool
 }
 
 // NewRegistry returns a new registry.
 func NewRegistry() *Registry {
-	return &Registry{}
+	return &Registry{
+		offsets:      make(map[string]string),
+		fingerprints: make(map[string]uint64),
+		completed:    make(map[string]bool),
+	}
 }
 
-// GetOffset returns the offset.
+// GetOffset returns the offset of an identifier if set, the offset otherwise.
 func (r *Registry) GetOffset(identifier string) string {
+	if offset, exists := r.offsets[identifier]; exists {
+		return offset
+	}
 	return r.offset
 }
 
@@ -26,6 +36,11 @@ func (r *Registry) SetOffset(offset string) {
 	r.offset = offset
 }
 
+// SetIdentifierOffset sets the offset of an identifier.
+func (r *Registry) SetIdentifierOffset(identifier string, offset string) {
+	r.offsets[identifier] = offset
+}
+
 // GetTailingMode returns the tailing mode.
 func (r *Registry) GetTailingMode(identifier string) string {
 	return r.tailingMode
@@ -35,3 +50,33 @@ func (r *Registry) GetTailingMode(identifier string) string {
 func (r *Registry) SetTailingMode(tailingMode string) {
 	r.tailingMode = tailingMode
 }
+
+// GetFingerprint returns the fingerprint of an identifier.
+func (r *Registry) GetFingerprint(identifier string) uint64 {
+	return r.fingerprints[identifier]
+}
+
+// SetFingerprint sets the fingerprint of an identifier.
+func (r *Registry) SetFingerprint(identifier string, fingerprint uint64) {
+	r.fingerprints[identifier] = fingerprint
+}
+
+// GetIdentifierByFingerprint returns an identifier having the fingerprint.
+func (r *Registry) GetIdentifierByFingerprint(fingerprint uint64) string {
+	for identifier, f := range r.fingerprints {
+		if f == fingerprint && fingerprint != 0 {
+			return identifier
+		}
+	}
+	return ""
+}
+
+// IsCompleted returns whether an identifier has been completed.
+func (r *Registry) IsCompleted(identifier string) bool {
+	return r.completed[identifier]
+}
+
+// SetCompleted marks an identifier as completed.
+func (r *Registry) SetCompleted(identifier string) {
+	r.completed[identifier] = true
+}
@@ -27,6 +27,15 @@ func (a *NullAuditor) GetOffset(identifier string) string { return "" }
 // GetTailingMode returns an empty string.
 func (a *NullAuditor) GetTailingMode(identifier string) string { return "" }
 
+// GetFingerprint returns 0.
+func (a *NullAuditor) GetFingerprint(identifier string) uint64 { return 0 }
+
+// GetIdentifierByFingerprint returns an empty string.
+func (a *NullAuditor) GetIdentifierByFingerprint(fingerprint uint64) string { return "" }
+
+// IsCompleted returns false.
+func (a *NullAuditor) IsCompleted(identifier string) bool { return false }
+
 // Start starts the NullAuditor main loop.
 func (a *NullAuditor) Start() {
 	go a.run()
@@ -8,6 +8,14 @@ package http
 import (
 	"bytes"
 	"compress/gzip"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Zstd compression levels, as defined by the reference implementation.
+const (
+	zstdMinCompressionLevel = 1
+	zstdMaxCompressionLevel = 22
 )
 
 // ContentEncoding encodes the payload
@@ -71,3 +79,35 @@ func (c *GzipContentEncoding) encode(payload []byte) ([]byte, error) {
 	}
 	return compressedPayload.Bytes(), nil
 }
+
+// ZstdContentEncoding encodes the payload using zstd algorithm
+type ZstdContentEncoding struct {
+	encoder *zstd.Encoder
+}
+
+// NewZstdContentEncoding creates a new Zstd content type
+func NewZstdContentEncoding(level int) (*ZstdContentEncoding, error) {
+	if level < zstdMinCompressionLevel {
+		level = zstdMinCompressionLevel
+	} else if level > zstdMaxCompressionLevel {
+		level = zstdMaxCompressionLevel
+	}
+
+	// the payloads are encoded at once, so the encoder does not need its own goroutines
+	encoder, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)), zstd.WithEncoderConcurrency(1))
+	if err != nil {
+		return nil, err
+	}
+	return &ZstdContentEncoding{
+		encoder,
+	}, nil
+}
+
+func (c *ZstdContentEncoding) name() string {
+	return "zstd"
+}
+
+func (c *ZstdContentEncoding) encode(payload []byte) ([]byte, error) {
+	// EncodeAll can be called concurrently
+	return c.encoder.EncodeAll(payload, make([]byte, 0, len(payload)/2)), nil
+}
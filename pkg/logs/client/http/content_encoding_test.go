@@ -10,6 +10,7 @@ import (
 	"compress/gzip"
 	"testing"
 
+	"github.com/klauspost/compress/zstd"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -42,6 +43,29 @@ func TestGzipContentEncodingName(t *testing.T) {
 	assert.Equal(t, NewGzipContentEncoding(gzip.BestCompression).name(), "gzip")
 }
 
+func TestZstdContentEncoding(t *testing.T) {
+	payload := []byte("my payload")
+
+	encoding, err := NewZstdContentEncoding(3)
+	assert.Nil(t, err)
+	encodedPayload, err := encoding.encode(payload)
+	assert.Nil(t, err)
+
+	decoder, err := zstd.NewReader(nil)
+	assert.Nil(t, err)
+	defer decoder.Close()
+	decompressedPayload, err := decoder.DecodeAll(encodedPayload, nil)
+	assert.Nil(t, err)
+
+	assert.Equal(t, payload, decompressedPayload)
+}
+
+func TestZstdContentEncodingName(t *testing.T) {
+	encoding, err := NewZstdContentEncoding(3)
+	assert.Nil(t, err)
+	assert.Equal(t, encoding.name(), "zstd")
+}
+
 func decompress(payload []byte) ([]byte, error) {
 	reader, err := gzip.NewReader(bytes.NewReader(payload))
 	if err != nil {
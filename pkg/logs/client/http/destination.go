@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"expvar"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -24,6 +25,10 @@ const (
 	JSONContentType = "application/json"
 )
 
+const (
+	warningPeriod = 1000
+)
+
 // HTTP errors.
 var (
 	errClient = errors.New("client error")
@@ -36,6 +41,7 @@ var emptyPayload []byte
 // Destination sends a payload over HTTP.
 type Destination struct {
 	url                 string
+	host                string
 	contentType         string
 	contentEncoding     ContentEncoding
 	client              *httputils.ResetClient
@@ -53,6 +59,7 @@ func NewDestination(endpoint config.Endpoint, contentType string, destinationsCo
 func newDestination(endpoint config.Endpoint, contentType string, destinationsContext *client.DestinationsContext, timeout time.Duration) *Destination {
 	return &Destination{
 		url:                 buildURL(endpoint),
+		host:                endpoint.Host,
 		contentType:         contentType,
 		contentEncoding:     buildContentEncoding(endpoint),
 		client:              httputils.NewResetClient(endpoint.ConnectionResetInterval, httpClientFactory(timeout)),
@@ -65,12 +72,19 @@ func newDestination(endpoint config.Endpoint, contentType string, destinationsCo
 func (d *Destination) Send(payload []byte) error {
 	ctx := d.destinationsContext.Context()
 
+	start := time.Now()
 	encodedPayload, err := d.contentEncoding.encode(payload)
 	if err != nil {
 		return err
 	}
+	compressionKind := d.contentEncoding.name()
+	metrics.TlmEncodingTime.Add(time.Since(start).Seconds(), compressionKind)
+	metrics.TlmBytesEncoded.Add(float64(len(payload)), compressionKind)
+	metrics.TlmEncodedBytes.Add(float64(len(encodedPayload)), compressionKind)
 	metrics.BytesSent.Add(int64(len(payload)))
+	metrics.TlmBytesSent.Add(float64(len(payload)))
 	metrics.EncodedBytesSent.Add(int64(len(encodedPayload)))
+	metrics.TlmEncodedBytesSent.Add(float64(len(encodedPayload)))
 
 	req, err := http.NewRequest("POST", d.url, bytes.NewReader(encodedPayload))
 	if err != nil {
@@ -112,14 +126,25 @@ func (d *Destination) Send(payload []byte) error {
 	}
 }
 
-// SendAsync sends a payload in background.
+// SendAsync sends a payload in background without blocking. If the channel is full,
+// the incoming payloads will be dropped.
 func (d *Destination) SendAsync(payload []byte) {
 	d.once.Do(func() {
 		payloadChan := make(chan []byte, config.ChanSize)
+		metrics.DestinationLogsDropped.Set(d.host, &expvar.Int{})
 		d.sendInBackground(payloadChan)
 		d.payloadChan = payloadChan
 	})
-	d.payloadChan <- payload
+
+	select {
+	case d.payloadChan <- payload:
+	default:
+		if metrics.DestinationLogsDropped.Get(d.host).(*expvar.Int).Value()%warningPeriod == 0 {
+			log.Warnf("Some logs sent to additional destination %v were dropped", d.host)
+		}
+		metrics.DestinationLogsDropped.Add(d.host, 1)
+		metrics.TlmLogsDropped.Inc(d.host)
+	}
 }
 
 // sendInBackground sends all payloads from payloadChan in background.
@@ -165,10 +190,21 @@ func buildURL(endpoint config.Endpoint) string {
 }
 
 func buildContentEncoding(endpoint config.Endpoint) ContentEncoding {
-	if endpoint.UseCompression {
-		return NewGzipContentEncoding(endpoint.CompressionLevel)
+	if !endpoint.UseCompression {
+		return IdentityContentType
+	}
+	switch endpoint.CompressionKind {
+	case config.ZstdCompressionKind:
+		encoding, err := NewZstdContentEncoding(endpoint.CompressionLevel)
+		if err == nil {
+			return encoding
+		}
+		log.Warnf("Could not create the zstd encoder for %v, fallback on gzip: %v", endpoint.Host, err)
+	case config.GzipCompressionKind, "":
+	default:
+		log.Warnf("Invalid compression_kind %q for %v, fallback on gzip", endpoint.CompressionKind, endpoint.Host)
 	}
-	return IdentityContentType
+	return NewGzipContentEncoding(endpoint.CompressionLevel)
 }
 
 // CheckConnectivity check if sending logs through HTTP works
@@ -81,6 +81,14 @@ func TestBuildURLShouldReturnAddressWithPortWhenDefined(t *testing.T) {
 	assert.Equal(t, "http://foo:1234/v1/input/bar", url)
 }
 
+func TestBuildContentEncoding(t *testing.T) {
+	assert.Equal(t, "identity", buildContentEncoding(config.Endpoint{CompressionKind: config.ZstdCompressionKind}).name())
+	assert.Equal(t, "gzip", buildContentEncoding(config.Endpoint{UseCompression: true}).name())
+	assert.Equal(t, "gzip", buildContentEncoding(config.Endpoint{UseCompression: true, CompressionKind: config.GzipCompressionKind}).name())
+	assert.Equal(t, "zstd", buildContentEncoding(config.Endpoint{UseCompression: true, CompressionKind: config.ZstdCompressionKind}).name())
+	assert.Equal(t, "gzip", buildContentEncoding(config.Endpoint{UseCompression: true, CompressionKind: "lz4"}).name())
+}
+
 func TestDestinationSend200(t *testing.T) {
 	server := NewHTTPServerTest(200)
 	err := server.destination.Send([]byte("yo"))
@@ -184,6 +184,7 @@ func buildTCPEndpoints() (*Endpoints, error) {
 // LogsConfigKeys stores logs configuration keys stored in YAML configuration files
 type LogsConfigKeys struct {
 	UseCompression          string
+	CompressionKind         string
 	CompressionLevel        string
 	ConnectionResetInterval string
 	LogsDDURL               string
@@ -197,6 +198,7 @@ type LogsConfigKeys struct {
 // logsConfigDefaultKeys defines the default YAML keys used to retrieve logs configuration
 var logsConfigDefaultKeys = LogsConfigKeys{
 	UseCompression:          "logs_config.use_compression",
+	CompressionKind:         "logs_config.compression_kind",
 	CompressionLevel:        "logs_config.compression_level",
 	ConnectionResetInterval: "logs_config.connection_reset_interval",
 	LogsDDURL:               "logs_config.logs_dd_url",
@@ -225,9 +227,15 @@ func BuildHTTPEndpointsWithConfig(logsConfig LogsConfigKeys, endpointPrefix stri
 		defaultUseCompression = coreConfig.Datadog.GetBool(logsConfig.UseCompression)
 	}
 
+	defaultCompressionKind := GzipCompressionKind
+	if len(logsConfig.CompressionKind) != 0 {
+		defaultCompressionKind = coreConfig.Datadog.GetString(logsConfig.CompressionKind)
+	}
+
 	main := Endpoint{
 		APIKey:                  getLogsAPIKey(coreConfig.Datadog),
 		UseCompression:          defaultUseCompression,
+		CompressionKind:         defaultCompressionKind,
 		CompressionLevel:        coreConfig.Datadog.GetInt(logsConfig.CompressionLevel),
 		ConnectionResetInterval: time.Duration(coreConfig.Datadog.GetInt(logsConfig.ConnectionResetInterval)) * time.Second,
 	}
@@ -276,6 +284,12 @@ func getAdditionalEndpointsFromKey(additionalEndpointsParameter string) []Endpoi
 	if err != nil {
 		log.Warnf("Could not parse additional_endpoints for logs: %v", err)
 	}
+	for i := range endpoints {
+		if endpoints[i].Exclusive && endpoints[i].Filter == nil {
+			log.Warnf("The additional endpoint %v is exclusive but has no filter, its logs are also sent to the main endpoint", endpoints[i].Host)
+			endpoints[i].Exclusive = false
+		}
+	}
 	return endpoints
 }
 
@@ -162,6 +162,7 @@ func (suite *ConfigTestSuite) TestMultipleHttpEndpointsEnvVar() {
 		Port:             443,
 		UseSSL:           true,
 		UseCompression:   true,
+		CompressionKind:  "gzip",
 		CompressionLevel: 6}
 	expectedAdditionalEndpoint1 := Endpoint{
 		APIKey:           "456",
@@ -248,6 +249,7 @@ func (suite *ConfigTestSuite) TestMultipleHttpEndpointsInConfig() {
 		Port:             443,
 		UseSSL:           true,
 		UseCompression:   true,
+		CompressionKind:  "gzip",
 		CompressionLevel: 6}
 	expectedAdditionalEndpoint1 := Endpoint{
 		APIKey:           "456",
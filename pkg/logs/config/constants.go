@@ -11,6 +11,12 @@ const (
 	NumberOfPipelines = 4
 )
 
+// Compression kinds of the HTTP payloads
+const (
+	GzipCompressionKind = "gzip"
+	ZstdCompressionKind = "zstd"
+)
+
 const (
 	// DateFormat is the default date format.
 	DateFormat = "2006-01-02T15:04:05.000000000Z"
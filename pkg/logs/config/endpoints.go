@@ -15,10 +15,37 @@ type Endpoint struct {
 	Host                    string
 	Port                    int
 	UseSSL                  bool
-	UseCompression          bool `mapstructure:"use_compression" json:"use_compression"`
-	CompressionLevel        int  `mapstructure:"compression_level" json:"compression_level"`
+	UseCompression          bool   `mapstructure:"use_compression" json:"use_compression"`
+	CompressionKind         string `mapstructure:"compression_kind" json:"compression_kind"`
+	CompressionLevel        int    `mapstructure:"compression_level" json:"compression_level"`
 	ProxyAddress            string
 	ConnectionResetInterval time.Duration
+	// Filter restricts the logs sent to an additional endpoint, all the logs are sent when nil.
+	Filter *EndpointFilter `mapstructure:"filter" json:"filter"`
+	// Exclusive prevents the logs matching the filter of an additional endpoint
+	// from being sent to the main endpoint.
+	Exclusive bool `mapstructure:"exclusive" json:"exclusive"`
+	// BatchWait is the maximum time in seconds to batch logs for an additional endpoint,
+	// the batch wait of the endpoints is used when not set.
+	BatchWait int `mapstructure:"batch_wait" json:"batch_wait"`
+	// BatchMaxSize is the maximum number of logs in a batch for an additional endpoint,
+	// the default maximum is used when not set.
+	BatchMaxSize int `mapstructure:"batch_max_size" json:"batch_max_size"`
+}
+
+// EndpointFilter holds the criteria a log must match to be sent to an endpoint,
+// a log matches when it matches one of the values of each non-empty criterion.
+type EndpointFilter struct {
+	Sources  []string `mapstructure:"sources" json:"sources"`
+	Services []string `mapstructure:"services" json:"services"`
+	Tags     []string `mapstructure:"tags" json:"tags"`
+	Statuses []string `mapstructure:"statuses" json:"statuses"`
+}
+
+// IsRouted returns true if the logs are sent to the endpoint independently of the main
+// endpoint, with their own filter and batching settings.
+func (e *Endpoint) IsRouted() bool {
+	return e.Filter != nil || e.Exclusive || e.BatchWait > 0 || e.BatchMaxSize > 0
 }
 
 // Endpoints holds the main endpoint and additional ones to dualship logs.
@@ -145,9 +145,26 @@ func (suite *EndpointsTestSuite) TestBuildEndpointsShouldSucceedWithValidHTTPCon
 
 	endpoint = endpoints.Main
 	suite.True(endpoint.UseCompression)
+	suite.Equal(endpoint.CompressionKind, GzipCompressionKind)
 	suite.Equal(endpoint.CompressionLevel, 6)
 }
 
+func (suite *EndpointsTestSuite) TestBuildEndpointsShouldSucceedWithValidHTTPConfigAndZstdCompression() {
+	suite.config.Set("logs_config.use_http", true)
+	suite.config.Set("logs_config.use_compression", true)
+	suite.config.Set("logs_config.compression_kind", "zstd")
+	suite.config.Set("logs_config.compression_level", 3)
+
+	endpoints, err := BuildEndpoints(HTTPConnectivityFailure)
+	suite.Nil(err)
+	suite.True(endpoints.UseHTTP)
+
+	endpoint := endpoints.Main
+	suite.True(endpoint.UseCompression)
+	suite.Equal(endpoint.CompressionKind, ZstdCompressionKind)
+	suite.Equal(endpoint.CompressionLevel, 3)
+}
+
 func (suite *EndpointsTestSuite) TestBuildEndpointsShouldSucceedWithValidHTTPConfigAndCompressionAndOverride() {
 	var endpoints *Endpoints
 	var endpoint Endpoint
@@ -371,6 +388,54 @@ func (suite *EndpointsTestSuite) TestAdditionalEndpoints() {
 	suite.True(endpoint.UseSSL)
 }
 
+func (suite *EndpointsTestSuite) TestAdditionalEndpointsWithFilter() {
+	suite.config.Set("logs_config.use_http", true)
+	suite.config.Set("logs_config.additional_endpoints", []map[string]interface{}{
+		{
+			"host":    "collector.local",
+			"api_key": "1234",
+			"filter": map[string]interface{}{
+				"sources":  []string{"audit"},
+				"statuses": []string{"error", "critical"},
+			},
+			"exclusive":      true,
+			"batch_wait":     2,
+			"batch_max_size": 50,
+		},
+		{
+			"host":      "foo",
+			"api_key":   "1234",
+			"exclusive": true,
+		},
+	})
+
+	endpoints, err := BuildEndpoints(HTTPConnectivityFailure)
+	suite.Nil(err)
+	suite.Len(endpoints.Additionals, 2)
+
+	endpoint := endpoints.Additionals[0]
+	suite.Equal("collector.local", endpoint.Host)
+	suite.Equal(&EndpointFilter{Sources: []string{"audit"}, Statuses: []string{"error", "critical"}}, endpoint.Filter)
+	suite.True(endpoint.Exclusive)
+	suite.Equal(2, endpoint.BatchWait)
+	suite.Equal(50, endpoint.BatchMaxSize)
+	suite.True(endpoint.IsRouted())
+
+	// an endpoint can't be exclusive without filter
+	endpoint = endpoints.Additionals[1]
+	suite.Nil(endpoint.Filter)
+	suite.False(endpoint.Exclusive)
+	suite.False(endpoint.IsRouted())
+
+	// the filters can be set as JSON
+	suite.config.Set("logs_config.additional_endpoints", `[{"host": "collector.local", "filter": {"services": ["auth"], "tags": ["env:prod"]}}]`)
+	endpoints, err = BuildEndpoints(HTTPConnectivityFailure)
+	suite.Nil(err)
+	suite.Len(endpoints.Additionals, 1)
+	suite.Equal(&EndpointFilter{Services: []string{"auth"}, Tags: []string{"env:prod"}}, endpoints.Additionals[0].Filter)
+	suite.False(endpoints.Additionals[0].Exclusive)
+}
+
 func TestEndpointsTestSuite(t *testing.T) {
 	suite.Run(t, new(EndpointsTestSuite))
 }
@@ -0,0 +1,58 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package config
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// grokPatterns are the patterns that can be referenced with %{NAME} or
+// %{NAME:field} in the pattern of the grok processing rules.
+var grokPatterns = map[string]string{
+	"WORD":              `\w+`,
+	"NOTSPACE":          `\S+`,
+	"SPACE":             `\s*`,
+	"DATA":              `.*?`,
+	"GREEDYDATA":        `.*`,
+	"INT":               `[+-]?\d+`,
+	"NUMBER":            `[+-]?(?:\d+(?:\.\d*)?|\.\d+)(?:[eE][+-]?\d+)?`,
+	"QUOTEDSTRING":      `"(?:[^"\\]|\\.)*"`,
+	"UUID":              `[A-Fa-f0-9]{8}-(?:[A-Fa-f0-9]{4}-){3}[A-Fa-f0-9]{12}`,
+	"IPV4":              `(?:\d{1,3}\.){3}\d{1,3}`,
+	"IPV6":              `[0-9A-Fa-f:]*:[0-9A-Fa-f:.]+`,
+	"IP":                `(?:(?:\d{1,3}\.){3}\d{1,3}|[0-9A-Fa-f:]*:[0-9A-Fa-f:.]+)`,
+	"HOSTNAME":          `[0-9A-Za-z][0-9A-Za-z\-_.]*`,
+	"PATH":              `(?:/[^\s/]*)+`,
+	"URIPATHPARAM":      `/[^\s?#]*(?:\?[^\s#]*)?`,
+	"LOGLEVEL":          `(?i:trace|debug|info|notice|warn(?:ing)?|err(?:or)?|crit(?:ical)?|fatal|severe|emerg(?:ency)?|alert)`,
+	"TIMESTAMP_ISO8601": `\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}(?::\d{2}(?:[.,]\d+)?)?(?:Z|[+-]\d{2}:?\d{2})?`,
+	"HTTPDATE":          `\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}`,
+	"SYSLOGTIMESTAMP":   `\w{3} +\d{1,2} \d{2}:\d{2}:\d{2}`,
+}
+
+// grokReferenceRegex matches the %{NAME} and %{NAME:field} references of a grok pattern.
+var grokReferenceRegex = regexp.MustCompile(`%\{(\w+)(?::(\w+))?\}`)
+
+// expandGrokPattern returns the regular expression of a grok pattern, the
+// %{NAME:field} references being replaced by named captures. Regular
+// expression named captures, (?P<field>...), can be used as well.
+func expandGrokPattern(pattern string) (string, error) {
+	var err error
+	expanded := grokReferenceRegex.ReplaceAllStringFunc(pattern, func(reference string) string {
+		groups := grokReferenceRegex.FindStringSubmatch(reference)
+		re, found := grokPatterns[groups[1]]
+		if !found {
+			err = fmt.Errorf("unknown grok pattern %s", groups[1])
+			return reference
+		}
+		if groups[2] == "" {
+			return "(?:" + re + ")"
+		}
+		return "(?P<" + groups[2] + ">" + re + ")"
+	})
+	return expanded, err
+}
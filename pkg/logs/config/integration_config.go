@@ -21,6 +21,7 @@ const (
 	JournaldType      = "journald"
 	WindowsEventType  = "windows_event"
 	SnmpTrapsType     = "snmp_traps"
+	SyslogType        = "syslog"
 	StringChannelType = "string_channel"
 
 	// UTF16BE for UTF-16 Big endian encoding
@@ -37,6 +38,8 @@ type LogsConfig struct {
 	Port int    // Network
 	Path string // File, Journald
 
+	Protocol string `mapstructure:"protocol" json:"protocol"` // Syslog
+
 	Encoding     string   `mapstructure:"encoding" json:"encoding"`             // File
 	ExcludePaths []string `mapstructure:"exclude_paths" json:"exclude_paths"`   // File
 	TailingMode  string   `mapstructure:"start_position" json:"start_position"` // File
@@ -66,6 +69,22 @@ type LogsConfig struct {
 	SourceCategory  string
 	Tags            []string
 	ProcessingRules []*ProcessingRule `mapstructure:"log_processing_rules" json:"log_processing_rules"`
+
+	// AutoMultiLine enables the detection of the multi-line pattern of the source,
+	// the logs_config.auto_multi_line_detection setting is used when not set.
+	AutoMultiLine               *bool   `mapstructure:"auto_multi_line_detection" json:"auto_multi_line_detection"`
+	AutoMultiLineSampleSize     int     `mapstructure:"auto_multi_line_sample_size" json:"auto_multi_line_sample_size"`
+	AutoMultiLineMatchThreshold float64 `mapstructure:"auto_multi_line_match_threshold" json:"auto_multi_line_match_threshold"`
+
+	// SampleOneIn keeps one log out of SampleOneIn and SamplePercentage keeps a
+	// percentage of the logs, chosen by a hash of their content.
+	SampleOneIn      int     `mapstructure:"sample_one_in" json:"sample_one_in"`
+	SamplePercentage float64 `mapstructure:"sample_percentage" json:"sample_percentage"`
+	// RateLimitLines and RateLimitBytes cap the logs sent per second, the logs over
+	// the limits being dropped, or sampled in the burst_then_sample mode.
+	RateLimitLines int    `mapstructure:"rate_limit_lines_per_second" json:"rate_limit_lines_per_second"`
+	RateLimitBytes int    `mapstructure:"rate_limit_bytes_per_second" json:"rate_limit_bytes_per_second"`
+	RateLimitMode  string `mapstructure:"rate_limit_mode" json:"rate_limit_mode"`
 }
 
 // TailingMode type
@@ -129,6 +148,19 @@ func (c *LogsConfig) Validate() error {
 		return fmt.Errorf("tcp source must have a port")
 	case c.Type == UDPType && c.Port == 0:
 		return fmt.Errorf("udp source must have a port")
+	case c.Type == SyslogType && c.Port == 0:
+		return fmt.Errorf("syslog source must have a port")
+	case c.Type == SyslogType && c.Protocol != "" && c.Protocol != TCPType && c.Protocol != UDPType:
+		return fmt.Errorf("invalid protocol '%v' for syslog source, must be tcp or udp", c.Protocol)
+	}
+	if c.AutoMultiLineSampleSize < 0 {
+		return fmt.Errorf("invalid auto_multi_line_sample_size %d, must be positive", c.AutoMultiLineSampleSize)
+	}
+	if c.AutoMultiLineMatchThreshold < 0 || c.AutoMultiLineMatchThreshold > 1 {
+		return fmt.Errorf("invalid auto_multi_line_match_threshold %v, must be between 0 and 1", c.AutoMultiLineMatchThreshold)
+	}
+	if err := c.validateThrottling(); err != nil {
+		return err
 	}
 	err := ValidateProcessingRules(c.ProcessingRules)
 	if err != nil {
@@ -137,6 +169,34 @@ func (c *LogsConfig) Validate() error {
 	return CompileProcessingRules(c.ProcessingRules)
 }
 
+func (c *LogsConfig) validateThrottling() error {
+	switch {
+	case c.SampleOneIn < 0:
+		return fmt.Errorf("invalid sample_one_in %d, must be positive", c.SampleOneIn)
+	case c.SamplePercentage < 0 || c.SamplePercentage > 100:
+		return fmt.Errorf("invalid sample_percentage %v, must be between 0 and 100", c.SamplePercentage)
+	case c.SampleOneIn > 0 && c.SamplePercentage > 0:
+		return fmt.Errorf("sample_one_in and sample_percentage can't be both set")
+	case c.RateLimitLines < 0:
+		return fmt.Errorf("invalid rate_limit_lines_per_second %d, must be positive", c.RateLimitLines)
+	case c.RateLimitBytes < 0:
+		return fmt.Errorf("invalid rate_limit_bytes_per_second %d, must be positive", c.RateLimitBytes)
+	}
+	switch c.RateLimitMode {
+	case "", RateLimitDrop:
+	case RateLimitBurstThenSample:
+		if c.RateLimitLines == 0 && c.RateLimitBytes == 0 {
+			return fmt.Errorf("rate_limit_mode %s requires a rate limit", c.RateLimitMode)
+		}
+		if c.SampleOneIn == 0 && c.SamplePercentage == 0 {
+			return fmt.Errorf("rate_limit_mode %s requires sample_one_in or sample_percentage", c.RateLimitMode)
+		}
+	default:
+		return fmt.Errorf("invalid rate_limit_mode '%v', must be %s or %s", c.RateLimitMode, RateLimitDrop, RateLimitBurstThenSample)
+	}
+	return nil
+}
+
 func (c *LogsConfig) validateTailingMode() error {
 	mode, found := TailingModeFromString(c.TailingMode)
 	if !found && c.TailingMode != "" {
@@ -21,6 +21,11 @@ func TestValidateShouldSucceedWithValidConfigs(t *testing.T) {
 		{Type: DockerType},
 		{Type: JournaldType, ProcessingRules: []*ProcessingRule{{Name: "foo", Type: ExcludeAtMatch, Pattern: ".*"}}},
 		{Type: SnmpTrapsType},
+		{Type: SyslogType, Port: 514},
+		{Type: SyslogType, Port: 514, Protocol: TCPType},
+		{Type: FileType, Path: "/var/log/foo.log", AutoMultiLineSampleSize: 100, AutoMultiLineMatchThreshold: 0.5},
+		{Type: DockerType, SampleOneIn: 10, RateLimitLines: 100, RateLimitBytes: 10000},
+		{Type: DockerType, SamplePercentage: 12.5, RateLimitLines: 100, RateLimitMode: RateLimitBurstThenSample},
 	}
 
 	for _, config := range validConfigs {
@@ -35,6 +40,8 @@ func TestValidateShouldFailWithInvalidConfigs(t *testing.T) {
 		{Type: FileType},
 		{Type: TCPType},
 		{Type: UDPType},
+		{Type: SyslogType},
+		{Type: SyslogType, Port: 514, Protocol: "http"},
 		{Type: DockerType, ProcessingRules: []*ProcessingRule{{Name: "foo"}}},
 		{Type: DockerType, ProcessingRules: []*ProcessingRule{{Name: "foo", Type: "bar"}}},
 		{Type: DockerType, ProcessingRules: []*ProcessingRule{{Name: "foo", Type: ExcludeAtMatch}}},
@@ -42,6 +49,16 @@ func TestValidateShouldFailWithInvalidConfigs(t *testing.T) {
 		{Type: DockerType, ProcessingRules: []*ProcessingRule{{Type: ExcludeAtMatch, Pattern: ".*"}}},
 		{Type: DockerType, ProcessingRules: []*ProcessingRule{{Type: ExcludeAtMatch}}},
 		{Type: DockerType, ProcessingRules: []*ProcessingRule{{Pattern: ".*"}}},
+		{Type: DockerType, AutoMultiLineSampleSize: -1},
+		{Type: DockerType, AutoMultiLineMatchThreshold: 1.5},
+		{Type: DockerType, SampleOneIn: -1},
+		{Type: DockerType, SamplePercentage: 101},
+		{Type: DockerType, SampleOneIn: 10, SamplePercentage: 10},
+		{Type: DockerType, RateLimitLines: -1},
+		{Type: DockerType, RateLimitBytes: -1},
+		{Type: DockerType, RateLimitLines: 100, RateLimitMode: "foo"},
+		{Type: DockerType, RateLimitLines: 100, RateLimitMode: RateLimitBurstThenSample},
+		{Type: DockerType, SampleOneIn: 10, RateLimitMode: RateLimitBurstThenSample},
 	}
 
 	for _, config := range invalidConfigs {
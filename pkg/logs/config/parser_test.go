@@ -10,6 +10,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestParseJSONWithValidFormatShouldSucceed(t *testing.T) {
@@ -61,6 +62,8 @@ logs:
   - type: file
     path: /var/log/app.log
     tags: a, b:c
+    auto_multi_line_detection: true
+    auto_multi_line_sample_size: 100
   - type: udp
     source: foo
     service: bar
@@ -83,6 +86,9 @@ logs:
 	assert.Equal(t, FileType, config.Type)
 	assert.Equal(t, "/var/log/app.log", config.Path)
 	assert.Equal(t, 2, len(config.Tags))
+	require.NotNil(t, config.AutoMultiLine)
+	assert.True(t, *config.AutoMultiLine)
+	assert.Equal(t, 100, config.AutoMultiLineSampleSize)
 
 	tag = config.Tags[0]
 	assert.Equal(t, "a", strings.TrimSpace(tag))
@@ -94,6 +100,7 @@ logs:
 	assert.Equal(t, UDPType, config.Type)
 	assert.Equal(t, "foo", config.Source)
 	assert.Equal(t, "bar", config.Service)
+	assert.Nil(t, config.AutoMultiLine)
 
 	config = configs[2]
 	assert.Equal(t, DockerType, config.Type)
@@ -16,15 +16,45 @@ const (
 	IncludeAtMatch = "include_at_match"
 	MaskSequences  = "mask_sequences"
 	MultiLine      = "multi_line"
+	JSONParser     = "parse_json"
+	KVParser       = "parse_kv"
+	GrokParser     = "grok"
+	GenerateMetric = "generate_metric"
 )
 
-// ProcessingRule defines an exclusion or a masking rule to
+// Types of the metrics generated from the logs
+const (
+	CountMetric        = "count"
+	DistributionMetric = "distribution"
+)
+
+// ProcessingRule defines an exclusion, a masking or a parsing rule to
 // be applied on log lines
 type ProcessingRule struct {
 	Type               string
 	Name               string
 	ReplacePlaceholder string `mapstructure:"replace_placeholder" json:"replace_placeholder"`
 	Pattern            string
+
+	// Parsing rules only: the attributes extracted from the line can be
+	// removed or masked, and promoted to the status, service or timestamp of the log.
+	ExcludeFields  []string `mapstructure:"exclude_fields" json:"exclude_fields"`
+	MaskFields     []string `mapstructure:"mask_fields" json:"mask_fields"`
+	StatusField    string   `mapstructure:"status_field" json:"status_field"`
+	ServiceField   string   `mapstructure:"service_field" json:"service_field"`
+	TimestampField string   `mapstructure:"timestamp_field" json:"timestamp_field"`
+	// KVSeparator separates the keys from the values for the parse_kv rules, defaults to "="
+	KVSeparator string `mapstructure:"kv_separator" json:"kv_separator"`
+
+	// Generate metric rules only: the lines matching the pattern, or having the
+	// metric field, are counted or their metric field is added to a distribution.
+	MetricName      string   `mapstructure:"metric_name" json:"metric_name"`
+	MetricType      string   `mapstructure:"metric_type" json:"metric_type"`
+	MetricField     string   `mapstructure:"metric_field" json:"metric_field"`
+	MetricTagFields []string `mapstructure:"metric_tag_fields" json:"metric_tag_fields"`
+	// DropLine drops the matching lines once the metric has been generated.
+	DropLine bool `mapstructure:"drop_line" json:"drop_line"`
+
 	// TODO: should be moved out
 	Regex       *regexp.Regexp
 	Placeholder []byte
@@ -42,8 +72,19 @@ func ValidateProcessingRules(rules []*ProcessingRule) error {
 		}
 
 		switch rule.Type {
-		case ExcludeAtMatch, IncludeAtMatch, MaskSequences, MultiLine:
+		case ExcludeAtMatch, IncludeAtMatch, MaskSequences, MultiLine, GrokParser:
 			break
+		case JSONParser, KVParser:
+			// the whole line is parsed, no pattern is needed
+			continue
+		case GenerateMetric:
+			if err := validateMetricRule(rule); err != nil {
+				return err
+			}
+			if rule.Pattern == "" {
+				// the lines are matched on the metric field
+				continue
+			}
 		case "":
 			return fmt.Errorf("type must be set for processing rule `%s`", rule.Name)
 		default:
@@ -53,23 +94,81 @@ func ValidateProcessingRules(rules []*ProcessingRule) error {
 		if rule.Pattern == "" {
 			return fmt.Errorf("no pattern provided for processing rule: %s", rule.Name)
 		}
-		_, err := regexp.Compile(rule.Pattern)
+		pattern := rule.Pattern
+		if rule.Type == GrokParser {
+			var err error
+			if pattern, err = expandGrokPattern(pattern); err != nil {
+				return fmt.Errorf("invalid pattern %s for processing rule: %s: %v", rule.Pattern, rule.Name, err)
+			}
+		}
+		re, err := regexp.Compile(pattern)
 		if err != nil {
 			return fmt.Errorf("invalid pattern %s for processing rule: %s", rule.Pattern, rule.Name)
 		}
+		if rule.Type == GrokParser && !hasNamedCapture(re) {
+			return fmt.Errorf("no named capture in pattern %s for processing rule: %s", rule.Pattern, rule.Name)
+		}
+	}
+	return nil
+}
+
+// validateMetricRule validates the options specific to the generate_metric rules.
+func validateMetricRule(rule *ProcessingRule) error {
+	if rule.MetricName == "" {
+		return fmt.Errorf("no metric name provided for processing rule: %s", rule.Name)
+	}
+	switch rule.MetricType {
+	case "", CountMetric:
+		if rule.Pattern == "" && rule.MetricField == "" {
+			return fmt.Errorf("no pattern or metric field provided for processing rule: %s", rule.Name)
+		}
+	case DistributionMetric:
+		if rule.MetricField == "" {
+			return fmt.Errorf("no metric field provided for the distribution of processing rule: %s", rule.Name)
+		}
+	default:
+		return fmt.Errorf("metric type %s is not supported for processing rule: %s", rule.MetricType, rule.Name)
 	}
 	return nil
 }
 
+// hasNamedCapture returns true if the regular expression has at least one named capture.
+func hasNamedCapture(re *regexp.Regexp) bool {
+	for _, name := range re.SubexpNames() {
+		if name != "" {
+			return true
+		}
+	}
+	return false
+}
+
 // CompileProcessingRules compiles all processing rule regular expressions.
 func CompileProcessingRules(rules []*ProcessingRule) error {
 	for _, rule := range rules {
+		switch rule.Type {
+		case JSONParser, KVParser:
+			continue
+		case GenerateMetric:
+			if rule.Pattern == "" {
+				continue
+			}
+		case GrokParser:
+			pattern, err := expandGrokPattern(rule.Pattern)
+			if err != nil {
+				return err
+			}
+			rule.Regex, err = regexp.Compile(pattern)
+			if err != nil {
+				return err
+			}
+			continue
+		}
 		re, err := regexp.Compile(rule.Pattern)
 		if err != nil {
 			return err
 		}
 		switch rule.Type {
-		case ExcludeAtMatch, IncludeAtMatch:
+		case ExcludeAtMatch, IncludeAtMatch, GenerateMetric:
 			rule.Regex = re
 		case MaskSequences:
 			rule.Regex = re
@@ -8,6 +8,7 @@
 package config
 
 import (
+	"regexp"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -33,3 +34,65 @@ func TestCompileShouldFailWithInvalidRules(t *testing.T) {
 		assert.Nil(t, rule.Regex)
 	}
 }
+
+func TestValidateParsingRules(t *testing.T) {
+	validRules := []*ProcessingRule{
+		{Name: "json", Type: JSONParser},
+		{Name: "kv", Type: KVParser, KVSeparator: ":"},
+		{Name: "grok", Type: GrokParser, Pattern: `%{TIMESTAMP_ISO8601:timestamp} %{LOGLEVEL:level} %{GREEDYDATA:message}`},
+		{Name: "grok", Type: GrokParser, Pattern: `^(?P<level>\w+): .*`},
+	}
+	assert.NoError(t, ValidateProcessingRules(validRules))
+	assert.NoError(t, CompileProcessingRules(validRules))
+	assert.Nil(t, validRules[0].Regex)
+	assert.Nil(t, validRules[1].Regex)
+	assert.Equal(t, []string{"", "timestamp", "level", "message"}, validRules[2].Regex.SubexpNames())
+
+	invalidRules := []*ProcessingRule{
+		{Name: "grok", Type: GrokParser},
+		{Name: "grok", Type: GrokParser, Pattern: `%{UNKNOWN:field}`},
+		{Name: "grok", Type: GrokParser, Pattern: `%{WORD} %{INT}`},
+		{Name: "grok", Type: GrokParser, Pattern: `(\w+) (\d+)`},
+		{Name: "grok", Type: GrokParser, Pattern: `(?P<field>`},
+	}
+	for _, rule := range invalidRules {
+		assert.Error(t, ValidateProcessingRules([]*ProcessingRule{rule}), rule.Pattern)
+	}
+}
+
+func TestExpandGrokPattern(t *testing.T) {
+	pattern, err := expandGrokPattern(`%{IP:client} %{WORD:method} %{URIPATHPARAM:path}%{SPACE}`)
+	assert.NoError(t, err)
+
+	re := regexp.MustCompile(pattern)
+	groups := re.FindStringSubmatch("10.0.0.1 GET /index.html?a=b ")
+	assert.Equal(t, []string{"10.0.0.1 GET /index.html?a=b ", "10.0.0.1", "GET", "/index.html?a=b"}, groups)
+	assert.Equal(t, []string{"", "client", "method", "path"}, re.SubexpNames())
+
+	_, err = expandGrokPattern(`%{FOO:bar}`)
+	assert.EqualError(t, err, "unknown grok pattern FOO")
+}
+
+func TestValidateMetricRules(t *testing.T) {
+	validRules := []*ProcessingRule{
+		{Name: "errors", Type: GenerateMetric, MetricName: "app.errors", Pattern: "ERROR"},
+		{Name: "status", Type: GenerateMetric, MetricName: "app.requests", MetricType: CountMetric, MetricField: "status", MetricTagFields: []string{"status"}},
+		{Name: "duration", Type: GenerateMetric, MetricName: "app.duration", MetricType: DistributionMetric, MetricField: "duration", Pattern: `took (?P<duration>\d+)ms`, DropLine: true},
+	}
+	assert.NoError(t, ValidateProcessingRules(validRules))
+	assert.NoError(t, CompileProcessingRules(validRules))
+	assert.NotNil(t, validRules[0].Regex)
+	assert.Nil(t, validRules[1].Regex)
+	assert.NotNil(t, validRules[2].Regex)
+
+	invalidRules := []*ProcessingRule{
+		{Name: "no_name", Type: GenerateMetric, Pattern: "ERROR"},
+		{Name: "no_match", Type: GenerateMetric, MetricName: "app.errors"},
+		{Name: "no_field", Type: GenerateMetric, MetricName: "app.duration", MetricType: DistributionMetric, Pattern: "took"},
+		{Name: "bad_type", Type: GenerateMetric, MetricName: "app.errors", MetricType: "gauge", Pattern: "ERROR"},
+		{Name: "bad_pattern", Type: GenerateMetric, MetricName: "app.errors", Pattern: "(ERROR"},
+	}
+	for _, rule := range invalidRules {
+		assert.Error(t, ValidateProcessingRules([]*ProcessingRule{rule}), rule.Name)
+	}
+}
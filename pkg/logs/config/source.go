@@ -31,6 +31,9 @@ type LogSource struct {
 	// Put expvar Int first because it's modified with sync/atomic, so it needs to
 	// be 64-bit aligned on 32-bit systems. See https://golang.org/pkg/sync/atomic/#pkg-note-BUG
 	BytesRead expvar.Int
+	// LogsDroppedBySampling and LogsDroppedByRateLimit count the logs dropped by the Throttler
+	LogsDroppedBySampling  expvar.Int
+	LogsDroppedByRateLimit expvar.Int
 
 	Name     string
 	Config   *LogsConfig
@@ -46,6 +49,8 @@ type LogSource struct {
 	// In the case that the source is overridden, keep a reference to the parent for bubbling up information about the child
 	ParentSource *LogSource
 	LatencyStats *util.StatsTracker
+	// Throttler samples and rate limits the logs of the source, nil when not configured
+	Throttler *Throttler
 }
 
 // NewLogSource creates a new log source.
@@ -60,6 +65,7 @@ func NewLogSource(name string, config *LogsConfig) *LogSource {
 		BytesRead:    expvar.Int{},
 		info:         make(map[string]string),
 		LatencyStats: util.NewStatsTracker(time.Hour*24, time.Hour),
+		Throttler:    NewThrottler(config),
 	}
 }
 
@@ -0,0 +1,171 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package config
+
+import (
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+// Rate limit modes
+const (
+	// RateLimitDrop drops the logs over the rate limits.
+	RateLimitDrop = "drop"
+	// RateLimitBurstThenSample sends all the logs within the rate limits and samples the others.
+	RateLimitBurstThenSample = "burst_then_sample"
+)
+
+// ThrottleDecision tells whether a log is kept by a throttler or why it is dropped.
+type ThrottleDecision int
+
+// Throttle decisions
+const (
+	Keep ThrottleDecision = iota
+	DroppedBySampling
+	DroppedByRateLimit
+)
+
+// samplePercentagePrecision is the number of buckets the content hashes are split into
+// to sample a percentage of the logs, allowing percentages with two decimals.
+const samplePercentagePrecision = 10000
+
+// Throttler samples and rate limits the logs of a source.
+// It is shared by the pipelines processing the logs of the source and is safe for concurrent use.
+type Throttler struct {
+	sampleOneIn      int
+	samplePercentage float64
+	burstThenSample  bool
+	lines            *tokenBucket
+	bytes            *tokenBucket
+	count            int
+	now              func() time.Time
+	mu               sync.Mutex
+}
+
+// NewThrottler returns the throttler of a source, nil if the source is neither sampled nor rate limited.
+func NewThrottler(config *LogsConfig) *Throttler {
+	if config == nil {
+		return nil
+	}
+	t := &Throttler{
+		burstThenSample: config.RateLimitMode == RateLimitBurstThenSample,
+		now:             time.Now,
+	}
+	if config.SampleOneIn > 1 {
+		t.sampleOneIn = config.SampleOneIn
+	}
+	if config.SamplePercentage > 0 && config.SamplePercentage < 100 {
+		t.samplePercentage = config.SamplePercentage
+	}
+	if config.RateLimitLines > 0 {
+		t.lines = newTokenBucket(float64(config.RateLimitLines))
+	}
+	if config.RateLimitBytes > 0 {
+		t.bytes = newTokenBucket(float64(config.RateLimitBytes))
+	}
+	if !t.isSampling() && t.lines == nil && t.bytes == nil {
+		return nil
+	}
+	return t
+}
+
+// Throttle returns whether the log must be kept or dropped. The logs are sampled,
+// the kept ones being then rate limited, or in the burst_then_sample mode, the logs
+// within the rate limits are kept and the other ones sampled.
+func (t *Throttler) Throttle(content []byte) ThrottleDecision {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.burstThenSample {
+		if t.takeTokens(len(content)) || t.sample(content) {
+			return Keep
+		}
+		return DroppedBySampling
+	}
+	if !t.sample(content) {
+		return DroppedBySampling
+	}
+	if !t.takeTokens(len(content)) {
+		return DroppedByRateLimit
+	}
+	return Keep
+}
+
+func (t *Throttler) isSampling() bool {
+	return t.sampleOneIn > 0 || t.samplePercentage > 0
+}
+
+// sample returns true if the log is kept by the sampling, the first log out of
+// sampleOneIn or the logs whose content hash falls in the sampled percentage.
+func (t *Throttler) sample(content []byte) bool {
+	if t.sampleOneIn > 0 {
+		t.count = (t.count + 1) % t.sampleOneIn
+		return t.count == 1
+	}
+	if t.samplePercentage > 0 {
+		h := fnv.New32a()
+		h.Write(content) //nolint:errcheck
+		return float64(h.Sum32()%samplePercentagePrecision) < t.samplePercentage*samplePercentagePrecision/100
+	}
+	return true
+}
+
+// takeTokens takes the tokens of a log from the buckets, it returns false
+// without taking any token if one of the buckets doesn't have enough.
+func (t *Throttler) takeTokens(size int) bool {
+	now := t.now()
+	if t.lines != nil && !t.lines.has(now, 1) {
+		return false
+	}
+	if t.bytes != nil && !t.bytes.has(now, float64(size)) {
+		return false
+	}
+	if t.lines != nil {
+		t.lines.take(1)
+	}
+	if t.bytes != nil {
+		t.bytes.take(float64(size))
+	}
+	return true
+}
+
+// tokenBucket is refilled at a constant rate up to a capacity of one second of tokens.
+type tokenBucket struct {
+	rate       float64
+	tokens     float64
+	lastRefill time.Time
+}
+
+func newTokenBucket(rate float64) *tokenBucket {
+	return &tokenBucket{
+		rate:   rate,
+		tokens: rate,
+	}
+}
+
+// has refills the bucket and returns true if it holds enough tokens, the
+// requests larger than the capacity being accepted when the bucket is full.
+func (b *tokenBucket) has(now time.Time, tokens float64) bool {
+	if !b.lastRefill.IsZero() {
+		b.tokens += now.Sub(b.lastRefill).Seconds() * b.rate
+		if b.tokens > b.rate {
+			b.tokens = b.rate
+		}
+	}
+	b.lastRefill = now
+	if tokens > b.rate {
+		tokens = b.rate
+	}
+	return b.tokens >= tokens
+}
+
+func (b *tokenBucket) take(tokens float64) {
+	b.tokens -= tokens
+	if b.tokens < 0 {
+		b.tokens = 0
+	}
+}
@@ -0,0 +1,115 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package config
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestThrottler returns a throttler whose clock is controlled by the returned function.
+func newTestThrottler(config *LogsConfig) (*Throttler, func(time.Duration)) {
+	t := NewThrottler(config)
+	now := time.Now()
+	t.now = func() time.Time { return now }
+	return t, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestNewThrottler(t *testing.T) {
+	assert.Nil(t, NewThrottler(nil))
+	assert.Nil(t, NewThrottler(&LogsConfig{}))
+	assert.Nil(t, NewThrottler(&LogsConfig{SampleOneIn: 1, SamplePercentage: 100}))
+	assert.NotNil(t, NewThrottler(&LogsConfig{SampleOneIn: 2}))
+	assert.NotNil(t, NewThrottler(&LogsConfig{SamplePercentage: 50}))
+	assert.NotNil(t, NewThrottler(&LogsConfig{RateLimitLines: 10}))
+	assert.NotNil(t, NewThrottler(&LogsConfig{RateLimitBytes: 10}))
+}
+
+func TestThrottlerSampleOneIn(t *testing.T) {
+	throttler := NewThrottler(&LogsConfig{SampleOneIn: 3})
+	var decisions []ThrottleDecision
+	for i := 0; i < 7; i++ {
+		decisions = append(decisions, throttler.Throttle([]byte("hello")))
+	}
+	assert.Equal(t, []ThrottleDecision{Keep, DroppedBySampling, DroppedBySampling, Keep, DroppedBySampling, DroppedBySampling, Keep}, decisions)
+}
+
+func TestThrottlerSamplePercentage(t *testing.T) {
+	throttler := NewThrottler(&LogsConfig{SamplePercentage: 25})
+	kept := 0
+	for i := 0; i < 10000; i++ {
+		content := []byte(fmt.Sprintf("log %d", i))
+		decision := throttler.Throttle(content)
+		// the decision only depends on the content
+		assert.Equal(t, decision, throttler.Throttle(content))
+		if decision == Keep {
+			kept++
+		}
+	}
+	assert.InDelta(t, 2500, kept, 250)
+}
+
+func TestThrottlerRateLimitLines(t *testing.T) {
+	throttler, advance := newTestThrottler(&LogsConfig{RateLimitLines: 2})
+
+	assert.Equal(t, Keep, throttler.Throttle([]byte("a")))
+	assert.Equal(t, Keep, throttler.Throttle([]byte("b")))
+	assert.Equal(t, DroppedByRateLimit, throttler.Throttle([]byte("c")))
+
+	advance(500 * time.Millisecond)
+	assert.Equal(t, Keep, throttler.Throttle([]byte("d")))
+	assert.Equal(t, DroppedByRateLimit, throttler.Throttle([]byte("e")))
+
+	// the bucket doesn't hold more than one second of tokens
+	advance(time.Minute)
+	assert.Equal(t, Keep, throttler.Throttle([]byte("f")))
+	assert.Equal(t, Keep, throttler.Throttle([]byte("g")))
+	assert.Equal(t, DroppedByRateLimit, throttler.Throttle([]byte("h")))
+}
+
+func TestThrottlerRateLimitBytes(t *testing.T) {
+	throttler, advance := newTestThrottler(&LogsConfig{RateLimitBytes: 10})
+
+	assert.Equal(t, Keep, throttler.Throttle([]byte("123456")))
+	assert.Equal(t, DroppedByRateLimit, throttler.Throttle([]byte("123456")))
+	assert.Equal(t, Keep, throttler.Throttle([]byte("1234")))
+
+	// a log larger than the limit is kept when the bucket is full
+	advance(time.Second)
+	assert.Equal(t, Keep, throttler.Throttle([]byte("123456789012")))
+	assert.Equal(t, DroppedByRateLimit, throttler.Throttle([]byte("1")))
+}
+
+func TestThrottlerSampleThenRateLimit(t *testing.T) {
+	throttler, _ := newTestThrottler(&LogsConfig{SampleOneIn: 2, RateLimitLines: 1})
+
+	assert.Equal(t, Keep, throttler.Throttle([]byte("a")))
+	// the logs dropped by the sampling don't take tokens
+	assert.Equal(t, DroppedBySampling, throttler.Throttle([]byte("b")))
+	assert.Equal(t, DroppedByRateLimit, throttler.Throttle([]byte("c")))
+}
+
+func TestThrottlerBurstThenSample(t *testing.T) {
+	throttler, advance := newTestThrottler(&LogsConfig{SampleOneIn: 2, RateLimitLines: 2, RateLimitMode: RateLimitBurstThenSample})
+
+	// the burst is kept
+	assert.Equal(t, Keep, throttler.Throttle([]byte("a")))
+	assert.Equal(t, Keep, throttler.Throttle([]byte("b")))
+	// then the logs are sampled
+	assert.Equal(t, Keep, throttler.Throttle([]byte("c")))
+	assert.Equal(t, DroppedBySampling, throttler.Throttle([]byte("d")))
+	assert.Equal(t, Keep, throttler.Throttle([]byte("e")))
+	assert.Equal(t, DroppedBySampling, throttler.Throttle([]byte("f")))
+
+	advance(time.Second)
+	assert.Equal(t, Keep, throttler.Throttle([]byte("g")))
+	assert.Equal(t, Keep, throttler.Throttle([]byte("h")))
+	assert.Equal(t, Keep, throttler.Throttle([]byte("i")))
+	assert.Equal(t, DroppedBySampling, throttler.Throttle([]byte("j")))
+}
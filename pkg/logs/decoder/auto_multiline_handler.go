@@ -0,0 +1,194 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package decoder
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// autoMultiLineInfoKey is the key of the source info reporting the state of the detection.
+const autoMultiLineInfoKey = "auto_multi_line"
+
+// defaultAutoMultiLinePatterns are the line-start patterns tried during the
+// automatic multi-line detection, the most specific ones first as a line is
+// only scored against the first pattern it matches.
+var defaultAutoMultiLinePatterns = []*regexp.Regexp{
+	// 2021-01-31T15:04:05, 2021-01-31 15:04:05,000
+	regexp.MustCompile(`^\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}`),
+	// [2021-01-31 15:04:05]
+	regexp.MustCompile(`^\[\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}`),
+	// 2021/01/31 15:04:05
+	regexp.MustCompile(`^\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2}`),
+	// Jan 31, 2021 3:04:05 PM
+	regexp.MustCompile(`^[A-Za-z]{3} \d{1,2}, \d{4} \d{1,2}:\d{2}:\d{2} (AM|PM)`),
+	// Sun Jan 31 15:04:05 2021
+	regexp.MustCompile(`^[A-Za-z]{3} [A-Za-z]{3} +\d{1,2} \d{2}:\d{2}:\d{2}`),
+	// Jan 31 15:04:05
+	regexp.MustCompile(`^[A-Za-z]{3} +\d{1,2} \d{2}:\d{2}:\d{2}`),
+	// 31/Jan/2021:15:04:05
+	regexp.MustCompile(`^\d{2}/[A-Za-z]{3}/\d{4}:\d{2}:\d{2}:\d{2}`),
+	// 15:04:05.000
+	regexp.MustCompile(`^\d{2}:\d{2}:\d{2}[.,]\d+`),
+	// INFO, [ERROR], WARNING:root:
+	regexp.MustCompile(`^\[?(TRACE|DEBUG|INFO|NOTICE|WARN|WARNING|ERROR|SEVERE|CRITICAL|FATAL)\b`),
+}
+
+// scoredPattern is a pattern tried during the automatic multi-line detection
+// along with the number of sampled lines it matched.
+type scoredPattern struct {
+	re    *regexp.Regexp
+	score int
+}
+
+// AutoMultiLineHandler samples the first lines of a source to detect the pattern
+// matching the beginning of its logs, the lines are then aggregated with this
+// pattern as done by the MultiLineHandler. Lines are not aggregated while
+// sampling or when no pattern matches enough lines.
+type AutoMultiLineHandler struct {
+	inputChan         chan *Message
+	outputChan        chan *Message
+	source            *config.LogSource
+	singleLineHandler *SingleLineHandler
+	multiLineHandler  *MultiLineHandler
+	scoredPatterns    []*scoredPattern
+	linesToSample     int
+	linesSampled      int
+	matchThreshold    float64
+	flushTimeout      time.Duration
+	lineLimit         int
+}
+
+// NewAutoMultiLineHandler returns a new AutoMultiLineHandler, the extra patterns
+// being tried before the default ones.
+func NewAutoMultiLineHandler(outputChan chan *Message, source *config.LogSource, extraPatterns []*regexp.Regexp, linesToSample int, matchThreshold float64, flushTimeout time.Duration, lineLimit int) *AutoMultiLineHandler {
+	scoredPatterns := make([]*scoredPattern, 0, len(extraPatterns)+len(defaultAutoMultiLinePatterns))
+	for _, re := range extraPatterns {
+		scoredPatterns = append(scoredPatterns, &scoredPattern{re: re})
+	}
+	for _, re := range defaultAutoMultiLinePatterns {
+		scoredPatterns = append(scoredPatterns, &scoredPattern{re: re})
+	}
+
+	h := &AutoMultiLineHandler{
+		inputChan:         make(chan *Message),
+		outputChan:        outputChan,
+		source:            source,
+		singleLineHandler: NewSingleLineHandler(outputChan, lineLimit),
+		scoredPatterns:    scoredPatterns,
+		linesToSample:     linesToSample,
+		matchThreshold:    matchThreshold,
+		flushTimeout:      flushTimeout,
+		lineLimit:         lineLimit,
+	}
+	source.UpdateInfo(autoMultiLineInfoKey, fmt.Sprintf("Auto multi-line detection: sampling the first %d lines", linesToSample))
+	return h
+}
+
+// Handle forward lines to lineChan to process them.
+func (h *AutoMultiLineHandler) Handle(input *Message) {
+	h.inputChan <- input
+}
+
+// Stop stops the handler.
+func (h *AutoMultiLineHandler) Stop() {
+	close(h.inputChan)
+}
+
+// Start starts the handler.
+func (h *AutoMultiLineHandler) Start() {
+	go h.run()
+}
+
+// run processes new lines from the channel and, once a multi-line pattern has been
+// detected, makes sure the aggregated content is sent when it stayed for too long
+// in the buffer.
+func (h *AutoMultiLineHandler) run() {
+	flushTimer := time.NewTimer(h.flushTimeout)
+	defer func() {
+		flushTimer.Stop()
+		if h.multiLineHandler != nil {
+			// make sure the content stored in the buffer gets sent
+			h.multiLineHandler.sendBuffer()
+		}
+		close(h.outputChan)
+	}()
+	for {
+		select {
+		case message, isOpen := <-h.inputChan:
+			if !isOpen {
+				// lineChan has been closed, no more lines are expected
+				return
+			}
+			if !flushTimer.Stop() {
+				select {
+				case <-flushTimer.C:
+				default:
+				}
+			}
+			h.process(message)
+			flushTimer.Reset(h.flushTimeout)
+		case <-flushTimer.C:
+			if h.multiLineHandler != nil {
+				// no line has been collected since a while,
+				// the content is supposed to be complete.
+				h.multiLineHandler.sendBuffer()
+			}
+		}
+	}
+}
+
+// process scores the line while sampling and forwards it as is, the following
+// lines being handled by the handler chosen at the end of the sampling.
+func (h *AutoMultiLineHandler) process(message *Message) {
+	if h.multiLineHandler != nil {
+		h.multiLineHandler.process(message)
+		return
+	}
+	if h.linesSampled >= h.linesToSample {
+		h.singleLineHandler.process(message)
+		return
+	}
+
+	for _, pattern := range h.scoredPatterns {
+		if pattern.re.Match(message.Content) {
+			pattern.score++
+			break
+		}
+	}
+	h.linesSampled++
+	h.singleLineHandler.process(message)
+
+	if h.linesSampled == h.linesToSample {
+		h.detectPattern()
+	}
+}
+
+// detectPattern switches to multi-line aggregation when a pattern matched
+// enough sampled lines.
+func (h *AutoMultiLineHandler) detectPattern() {
+	var best *scoredPattern
+	for _, pattern := range h.scoredPatterns {
+		if best == nil || pattern.score > best.score {
+			best = pattern
+		}
+	}
+
+	ratio := float64(best.score) / float64(h.linesSampled)
+	if best.score == 0 || ratio < h.matchThreshold {
+		log.Infof("No multi-line pattern detected for source %s, %d%% of the %d sampled lines matched the best pattern", h.source.Name, int(ratio*100), h.linesSampled)
+		h.source.UpdateInfo(autoMultiLineInfoKey, fmt.Sprintf("Auto multi-line detection: no pattern detected in the %d sampled lines", h.linesSampled))
+		return
+	}
+
+	log.Infof("Multi-line pattern %s detected for source %s, matching %d%% of the %d sampled lines", best.re, h.source.Name, int(ratio*100), h.linesSampled)
+	h.source.UpdateInfo(autoMultiLineInfoKey, fmt.Sprintf("Auto multi-line detection: pattern %s detected, matching %d%% of the %d sampled lines", best.re, int(ratio*100), h.linesSampled))
+	h.multiLineHandler = NewMultiLineHandler(h.outputChan, best.re, h.flushTimeout, h.lineLimit)
+}
@@ -0,0 +1,128 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package decoder
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+)
+
+func TestAutoMultiLineHandlerDetectsPattern(t *testing.T) {
+	outputChan := make(chan *Message, 10)
+	source := config.NewLogSource("config", &config.LogsConfig{})
+	h := NewAutoMultiLineHandler(outputChan, source, nil, 3, 0.6, 10*time.Millisecond, 1000)
+	h.Start()
+
+	assert.Equal(t, []string{"Auto multi-line detection: sampling the first 3 lines"}, source.GetInfo())
+
+	// sampled lines are sent as is
+	h.Handle(getDummyMessageWithLF("2021-01-31 15:04:05 ERROR something failed"))
+	h.Handle(getDummyMessageWithLF("\tat com.example.Main.main(Main.java:42)"))
+	h.Handle(getDummyMessageWithLF("2021-01-31 15:04:06 INFO hello"))
+	assert.Equal(t, "2021-01-31 15:04:05 ERROR something failed", string((<-outputChan).Content))
+	assert.Equal(t, "at com.example.Main.main(Main.java:42)", string((<-outputChan).Content))
+	assert.Equal(t, "2021-01-31 15:04:06 INFO hello", string((<-outputChan).Content))
+
+	// the following lines are aggregated with the detected pattern
+	h.Handle(getDummyMessageWithLF("2021-01-31 15:04:07 ERROR something else failed"))
+	h.Handle(getDummyMessageWithLF("\tat com.example.Main.main(Main.java:42)"))
+	h.Handle(getDummyMessageWithLF("\tat com.example.Main.run(Main.java:12)"))
+	h.Handle(getDummyMessageWithLF("2021-01-31 15:04:08 INFO bye"))
+
+	output := <-outputChan
+	expectedContent := "2021-01-31 15:04:07 ERROR something else failed\\n\tat com.example.Main.main(Main.java:42)\\n\tat com.example.Main.run(Main.java:12)"
+	assert.Equal(t, expectedContent, string(output.Content))
+	assert.Equal(t, len("2021-01-31 15:04:07 ERROR something else failed\n\tat com.example.Main.main(Main.java:42)\n\tat com.example.Main.run(Main.java:12)\n"), output.RawDataLen)
+
+	// the last message is sent on timeout
+	assert.Equal(t, "2021-01-31 15:04:08 INFO bye", string((<-outputChan).Content))
+
+	assert.Len(t, source.GetInfo(), 1)
+	assert.Contains(t, source.GetInfo()[0], "pattern ^\\d{4}-\\d{2}-\\d{2}[T ]\\d{2}:\\d{2}:\\d{2} detected, matching 66% of the 3 sampled lines")
+
+	h.Stop()
+}
+
+func TestAutoMultiLineHandlerNoPatternDetected(t *testing.T) {
+	outputChan := make(chan *Message, 10)
+	source := config.NewLogSource("config", &config.LogsConfig{})
+	h := NewAutoMultiLineHandler(outputChan, source, nil, 2, 0.6, 10*time.Millisecond, 100)
+	h.Start()
+
+	h.Handle(getDummyMessageWithLF("2021-01-31 15:04:05 hello"))
+	h.Handle(getDummyMessageWithLF("world"))
+	h.Handle(getDummyMessageWithLF("2021-01-31 15:04:05 hello"))
+	h.Handle(getDummyMessageWithLF("world"))
+
+	// lines keep being sent as is
+	assert.Equal(t, "2021-01-31 15:04:05 hello", string((<-outputChan).Content))
+	assert.Equal(t, "world", string((<-outputChan).Content))
+	assert.Equal(t, "2021-01-31 15:04:05 hello", string((<-outputChan).Content))
+	assert.Equal(t, "world", string((<-outputChan).Content))
+
+	assert.Equal(t, []string{"Auto multi-line detection: no pattern detected in the 2 sampled lines"}, source.GetInfo())
+
+	h.Stop()
+}
+
+func TestAutoMultiLineHandlerExtraPatterns(t *testing.T) {
+	outputChan := make(chan *Message, 10)
+	source := config.NewLogSource("config", &config.LogsConfig{})
+	// the extra pattern is tried first
+	extraPatterns := []*regexp.Regexp{regexp.MustCompile(`^\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2} \[`)}
+	h := NewAutoMultiLineHandler(outputChan, source, extraPatterns, 2, 0.5, 10*time.Millisecond, 100)
+	h.Start()
+
+	h.Handle(getDummyMessageWithLF("2021-01-31 15:04:05 [main] hello"))
+	h.Handle(getDummyMessageWithLF("2021-01-31 15:04:05 [main] world"))
+	h.Handle(getDummyMessageWithLF("2021-01-31 15:04:06 [main] bye"))
+	<-outputChan
+	<-outputChan
+	// the detected pattern is used for the lines following the sampled ones
+	assert.Equal(t, "2021-01-31 15:04:06 [main] bye", string((<-outputChan).Content))
+
+	assert.Equal(t, []string{"Auto multi-line detection: pattern ^\\d{4}-\\d{2}-\\d{2} \\d{2}:\\d{2}:\\d{2} \\[ detected, matching 100% of the 2 sampled lines"}, source.GetInfo())
+
+	h.Stop()
+}
+
+func TestDefaultAutoMultiLinePatterns(t *testing.T) {
+	lines := []string{
+		"2021-01-31T15:04:05.000Z hello",
+		"2021-01-31 15:04:05,000 hello",
+		"[2021-01-31 15:04:05] hello",
+		"2021/01/31 15:04:05 hello",
+		"Jan 31, 2021 3:04:05 PM org.example.Main main",
+		"Sun Jan 31 15:04:05 2021 hello",
+		"Jan 31 15:04:05 host app[42]: hello",
+		"31/Jan/2021:15:04:05 +0000 hello",
+		"15:04:05.000 hello",
+		"INFO hello",
+		"[ERROR] hello",
+		"WARNING:root:hello",
+	}
+	for _, line := range lines {
+		matched := false
+		for _, re := range defaultAutoMultiLinePatterns {
+			if re.MatchString(line) {
+				matched = true
+				break
+			}
+		}
+		assert.True(t, matched, line)
+	}
+
+	for _, line := range []string{"\tat com.example.Main.main(Main.java:42)", "Traceback (most recent call last):", "  File \"main.py\", line 1"} {
+		for _, re := range defaultAutoMultiLinePatterns {
+			assert.False(t, re.MatchString(line), line)
+		}
+	}
+}
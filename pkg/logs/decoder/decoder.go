@@ -7,10 +7,13 @@ package decoder
 
 import (
 	"bytes"
+	"regexp"
 	"time"
 
+	coreConfig "github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/logs/config"
 	"github.com/DataDog/datadog-agent/pkg/logs/parser"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
 
 // defaultContentLenLimit represents the max size for a line,
@@ -74,6 +77,8 @@ type Decoder struct {
 	lineParser      LineParser
 	contentLenLimit int
 	rawDataLen      int
+	// flushOnStop is set when the line being decoded must be sent when the decoder stops
+	flushOnStop bool
 }
 
 // InitializeDecoder returns a properly initialized Decoder
@@ -94,6 +99,9 @@ func NewDecoderWithEndLineMatcher(source *config.LogSource, parser parser.Parser
 			lineHandler = NewMultiLineHandler(outputChan, rule.Regex, defaultFlushTimeout, lineLimit)
 		}
 	}
+	if lineHandler == nil && isAutoMultiLineEnabled(source) {
+		lineHandler = newAutoMultiLineHandler(outputChan, source, lineLimit)
+	}
 	if lineHandler == nil {
 		lineHandler = NewSingleLineHandler(outputChan, lineLimit)
 	}
@@ -107,6 +115,40 @@ func NewDecoderWithEndLineMatcher(source *config.LogSource, parser parser.Parser
 	return New(inputChan, outputChan, lineParser, lineLimit, matcher)
 }
 
+// isAutoMultiLineEnabled returns true if the multi-line pattern of the source
+// must be detected, the source setting taking precedence over the global one.
+func isAutoMultiLineEnabled(source *config.LogSource) bool {
+	if source.Config.AutoMultiLine != nil {
+		return *source.Config.AutoMultiLine
+	}
+	return coreConfig.Datadog.GetBool("logs_config.auto_multi_line_detection")
+}
+
+// newAutoMultiLineHandler returns an AutoMultiLineHandler configured with the
+// source settings, defaulting to the global ones.
+func newAutoMultiLineHandler(outputChan chan *Message, source *config.LogSource, lineLimit int) *AutoMultiLineHandler {
+	linesToSample := source.Config.AutoMultiLineSampleSize
+	if linesToSample <= 0 {
+		linesToSample = coreConfig.Datadog.GetInt("logs_config.auto_multi_line_default_sample_size")
+	}
+	matchThreshold := source.Config.AutoMultiLineMatchThreshold
+	if matchThreshold <= 0 {
+		matchThreshold = coreConfig.Datadog.GetFloat64("logs_config.auto_multi_line_default_match_threshold")
+	}
+
+	var extraPatterns []*regexp.Regexp
+	for _, pattern := range coreConfig.Datadog.GetStringSlice("logs_config.auto_multi_line_extra_patterns") {
+		re, err := regexp.Compile("^(?:" + pattern + ")")
+		if err != nil {
+			log.Warnf("Ignoring invalid auto multi-line pattern %q: %v", pattern, err)
+			continue
+		}
+		extraPatterns = append(extraPatterns, re)
+	}
+
+	return NewAutoMultiLineHandler(outputChan, source, extraPatterns, linesToSample, matchThreshold, defaultFlushTimeout, lineLimit)
+}
+
 // New returns an initialized Decoder
 func New(InputChan chan *Input, OutputChan chan *Message, lineParser LineParser, contentLenLimit int, matcher EndLineMatcher) *Decoder {
 	var lineBuffer bytes.Buffer
@@ -131,11 +173,21 @@ func (d *Decoder) Stop() {
 	close(d.InputChan)
 }
 
+// FlushAndStop stops the Decoder after sending the line being decoded, even if it
+// did not end, it must be used when no more data can be appended to the line.
+func (d *Decoder) FlushAndStop() {
+	d.flushOnStop = true
+	close(d.InputChan)
+}
+
 // run lets the Decoder handle data coming from InputChan
 func (d *Decoder) run() {
 	for data := range d.InputChan {
 		d.decodeIncomingData(data.content)
 	}
+	if d.flushOnStop && d.lineBuffer.Len() > 0 {
+		d.flushLine()
+	}
 	// finish to stop decoder
 	d.lineParser.Stop()
 }
@@ -176,3 +228,12 @@ func (d *Decoder) sendLine() {
 	d.lineParser.Handle(NewDecodedInput(content, d.rawDataLen))
 	d.rawDataLen = 0
 }
+
+// flushLine sends the line being decoded, which has no end line sequence.
+func (d *Decoder) flushLine() {
+	content := make([]byte, d.lineBuffer.Len())
+	copy(content, d.lineBuffer.Bytes())
+	d.lineBuffer.Reset()
+	d.lineParser.Handle(NewDecodedInput(content, d.rawDataLen))
+	d.rawDataLen = 0
+}
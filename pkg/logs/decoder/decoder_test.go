@@ -9,6 +9,7 @@ import (
 	"strings"
 	"testing"
 
+	coreConfig "github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/logs/config"
 	"github.com/DataDog/datadog-agent/pkg/logs/parser"
 
@@ -227,6 +228,25 @@ func TestDecoderLifeCycle(t *testing.T) {
 	}
 }
 
+func TestDecoderFlushAndStop(t *testing.T) {
+	inputChan := make(chan *Input)
+	p := NewMockLineParser()
+	d := New(inputChan, nil, p, contentLenLimit, &NewLineMatcher{})
+	d.Start()
+
+	inputChan <- NewInput([]byte("hello\nworld"))
+	output := <-p.inputChan
+	assert.Equal(t, "hello", string(output.content))
+
+	// the line which did not end is sent before stopping
+	d.FlushAndStop()
+	output = <-p.inputChan
+	assert.Equal(t, "world", string(output.content))
+	assert.Equal(t, len("world"), output.rawDataLen)
+	_, ok := <-p.inputChan
+	assert.False(t, ok)
+}
+
 func TestDecoderInputNotDockerHeader(t *testing.T) {
 	inputChan := make(chan *Input)
 	h := NewMockLineParser()
@@ -303,3 +323,44 @@ func TestDecoderWithDecodingParser(t *testing.T) {
 
 	d.Stop()
 }
+
+func TestDecoderWithAutoMultiLine(t *testing.T) {
+	enabled := true
+	source := config.NewLogSource("config", &config.LogsConfig{AutoMultiLine: &enabled, AutoMultiLineSampleSize: 2})
+	d := InitializeDecoder(source, parser.NoopParser)
+	d.Start()
+
+	d.InputChan <- NewInput([]byte("2021-01-31 15:04:05 hello\n2021-01-31 15:04:05 world\n"))
+	assert.Equal(t, "2021-01-31 15:04:05 hello", string((<-d.OutputChan).Content))
+	assert.Equal(t, "2021-01-31 15:04:05 world", string((<-d.OutputChan).Content))
+
+	d.InputChan <- NewInput([]byte("2021-01-31 15:04:06 panic\n  stack\n2021-01-31 15:04:07 bye\n"))
+	assert.Equal(t, "2021-01-31 15:04:06 panic\\n  stack", string((<-d.OutputChan).Content))
+
+	d.Stop()
+	assert.Equal(t, "2021-01-31 15:04:07 bye", string((<-d.OutputChan).Content))
+}
+
+func TestIsAutoMultiLineEnabled(t *testing.T) {
+	mockConfig := coreConfig.Mock()
+
+	enabled, disabled := true, false
+	assert.False(t, isAutoMultiLineEnabled(config.NewLogSource("config", &config.LogsConfig{})))
+	assert.True(t, isAutoMultiLineEnabled(config.NewLogSource("config", &config.LogsConfig{AutoMultiLine: &enabled})))
+
+	mockConfig.Set("logs_config.auto_multi_line_detection", true)
+	defer mockConfig.Set("logs_config.auto_multi_line_detection", false)
+	assert.True(t, isAutoMultiLineEnabled(config.NewLogSource("config", &config.LogsConfig{})))
+	assert.False(t, isAutoMultiLineEnabled(config.NewLogSource("config", &config.LogsConfig{AutoMultiLine: &disabled})))
+}
+
+func TestAutoMultiLineExtraPatternsAreAnchored(t *testing.T) {
+	mockConfig := coreConfig.Mock()
+	mockConfig.Set("logs_config.auto_multi_line_extra_patterns", []string{"INFO|ERROR"})
+	defer mockConfig.Set("logs_config.auto_multi_line_extra_patterns", []string{})
+
+	h := newAutoMultiLineHandler(make(chan *Message), config.NewLogSource("config", &config.LogsConfig{}), 100)
+	re := h.scoredPatterns[0].re
+	assert.True(t, re.MatchString("ERROR something failed"))
+	assert.False(t, re.MatchString("something failed: ERROR"))
+}
@@ -0,0 +1,141 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package diagnostic
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Stages of the pipeline at which the messages can be streamed.
+const (
+	// StageTailer is the raw message received from the tailer, before the processing rules.
+	StageTailer = "tailer"
+	// StageProcessed is the message kept by the processing rules, with its content redacted.
+	StageProcessed = "processed"
+	// StageEncoded is the message as encoded to be sent to the intake.
+	StageEncoded = "encoded"
+)
+
+// Filters for processing log messages, a message is handled when it matches all the filters set
+type Filters struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Source  string `json:"source"`
+	Service string `json:"service"`
+	Status  string `json:"status"`
+	// Tags are either keys or key:value pairs, the message must have all of them.
+	Tags []string `json:"tags"`
+	// Content is a regular expression matched against the content of the message at its stage.
+	Content string `json:"content"`
+	// Stages are the stages at which the messages are streamed, the processed stage when empty.
+	Stages []string `json:"stages"`
+
+	contentRegex *regexp.Regexp
+}
+
+// Compile validates the filters and compiles the content regular expression.
+func (f *Filters) Compile() error {
+	for _, stage := range f.Stages {
+		switch stage {
+		case StageTailer, StageProcessed, StageEncoded:
+		default:
+			return fmt.Errorf("invalid stage %q, must be one of %s, %s or %s", stage, StageTailer, StageProcessed, StageEncoded)
+		}
+	}
+	if f.Content == "" {
+		f.contentRegex = nil
+		return nil
+	}
+	regex, err := regexp.Compile(f.Content)
+	if err != nil {
+		return fmt.Errorf("invalid content filter: %v", err)
+	}
+	f.contentRegex = regex
+	return nil
+}
+
+func shouldHandleMessage(pair *messagePair, filters *Filters) bool {
+	if filters == nil {
+		return pair.stage == StageProcessed
+	}
+
+	if !filters.hasStage(pair.stage) {
+		return false
+	}
+
+	m := &pair.msg
+
+	if filters.Name != "" && m.Origin.LogSource.Name != filters.Name {
+		return false
+	}
+
+	if filters.Type != "" && m.Origin.LogSource.Config.Type != filters.Type {
+		return false
+	}
+
+	if filters.Source != "" && filters.Source != m.Origin.Source() {
+		return false
+	}
+
+	if filters.Service != "" && filters.Service != m.Origin.Service() {
+		return false
+	}
+
+	if filters.Status != "" && filters.Status != m.GetStatus() {
+		return false
+	}
+
+	if len(filters.Tags) > 0 && !hasTags(m.Origin.Tags(), filters.Tags) {
+		return false
+	}
+
+	if filters.Content != "" {
+		if filters.contentRegex == nil && filters.Compile() != nil {
+			return false
+		}
+		if !filters.contentRegex.Match(pair.rendered) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (f *Filters) hasStage(stage string) bool {
+	return hasStage(f.Stages, stage)
+}
+
+// hasStage returns true if stage is one of stages, the processed stage being the default one.
+func hasStage(stages []string, stage string) bool {
+	if len(stages) == 0 {
+		return stage == StageProcessed
+	}
+	for _, s := range stages {
+		if s == stage {
+			return true
+		}
+	}
+	return false
+}
+
+// hasTags returns true if all the expected tags, or tag keys, are in tags.
+func hasTags(tags []string, expected []string) bool {
+	for _, e := range expected {
+		found := false
+		for _, tag := range tags {
+			if tag == e || (!strings.Contains(e, ":") && strings.HasPrefix(tag, e+":")) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
@@ -15,33 +15,37 @@ import (
 
 // MessageReceiver interface to handle messages for diagnostics
 type MessageReceiver interface {
-	HandleMessage(message.Message)
+	// HandleMessage handles a message at a stage of the pipeline, rendered being its content at this stage.
+	HandleMessage(m message.Message, rendered []byte, stage string)
+	// IsStageEnabled returns true if the messages are handled at stage, so that they are only rendered when needed.
+	IsStageEnabled(stage string) bool
+}
+
+// messagePair holds a message and its content at a stage of the pipeline.
+type messagePair struct {
+	msg      message.Message
+	rendered []byte
+	stage    string
 }
 
 // BufferedMessageReceiver handles in coming log messages and makes them available for diagnostics
 type BufferedMessageReceiver struct {
-	inputChan chan message.Message
+	inputChan chan messagePair
 	enabled   bool
+	stages    []string
 	m         sync.RWMutex
 }
 
-// Filters for processing log messages
-type Filters struct {
-	Name   string `json:"name"`
-	Type   string `json:"type"`
-	Source string `json:"source"`
-}
-
 // NewBufferedMessageReceiver creates a new MessageReceiver
 func NewBufferedMessageReceiver() *BufferedMessageReceiver {
 	return &BufferedMessageReceiver{
-		inputChan: make(chan message.Message, config.ChanSize),
+		inputChan: make(chan messagePair, config.ChanSize),
 	}
 }
 
 // Start opens new input channel
 func (b *BufferedMessageReceiver) Start() {
-	b.inputChan = make(chan message.Message, config.ChanSize)
+	b.inputChan = make(chan messagePair, config.ChanSize)
 }
 
 // Stop closes the input channel
@@ -68,11 +72,19 @@ func (b *BufferedMessageReceiver) SetEnabled(e bool) bool {
 
 	b.enabled = e
 	if !e {
+		b.stages = nil
 		b.clear()
 	}
 	return true
 }
 
+// SetStages sets the stages of the pipeline at which the messages are handled, the processed stage when empty.
+func (b *BufferedMessageReceiver) SetStages(stages []string) {
+	b.m.Lock()
+	defer b.m.Unlock()
+	b.stages = stages
+}
+
 // IsEnabled returns the enabled state of the message receiver
 func (b *BufferedMessageReceiver) IsEnabled() bool {
 	b.m.RLock()
@@ -80,12 +92,19 @@ func (b *BufferedMessageReceiver) IsEnabled() bool {
 	return b.enabled
 }
 
+// IsStageEnabled returns true if the message receiver is enabled and handles the messages at stage
+func (b *BufferedMessageReceiver) IsStageEnabled(stage string) bool {
+	b.m.RLock()
+	defer b.m.RUnlock()
+	return b.enabled && hasStage(b.stages, stage)
+}
+
 // HandleMessage buffers a message for diagnostic processing
-func (b *BufferedMessageReceiver) HandleMessage(m message.Message) {
+func (b *BufferedMessageReceiver) HandleMessage(m message.Message, rendered []byte, stage string) {
 	if !b.IsEnabled() {
 		return
 	}
-	b.inputChan <- m
+	b.inputChan <- messagePair{msg: m, rendered: rendered, stage: stage}
 }
 
 // Next pops the next buffered event off the input channel formatted as a string
@@ -93,9 +112,9 @@ func (b *BufferedMessageReceiver) Next(filters *Filters) (line string, ok bool)
 	// Read messages until one is handled or none are left
 	for {
 		select {
-		case msg := <-b.inputChan:
-			if shouldHandleMessage(&msg, filters) {
-				return formatMessage(&msg), true
+		case pair := <-b.inputChan:
+			if shouldHandleMessage(&pair, filters) {
+				return formatMessage(&pair), true
 			}
 			continue
 		default:
@@ -104,30 +123,10 @@ func (b *BufferedMessageReceiver) Next(filters *Filters) (line string, ok bool)
 	}
 }
 
-func shouldHandleMessage(m *message.Message, filters *Filters) bool {
-	if filters == nil {
-		return true
-	}
-
-	shouldHandle := true
-
-	if filters.Name != "" {
-		shouldHandle = shouldHandle && m.Origin.LogSource.Name == filters.Name
-	}
-
-	if filters.Type != "" {
-		shouldHandle = shouldHandle && m.Origin.LogSource.Config.Type == filters.Type
-	}
-
-	if filters.Source != "" {
-		shouldHandle = shouldHandle && filters.Source == m.Origin.Source()
-	}
-
-	return shouldHandle
-}
-
-func formatMessage(m *message.Message) string {
-	return fmt.Sprintf("Name: %s | Type: %s | Status: %s | Timestamp: %s | Service: %s | Source: %s | Tags: %s | Message: %s\n",
+func formatMessage(pair *messagePair) string {
+	m := &pair.msg
+	return fmt.Sprintf("Stage: %s | Name: %s | Type: %s | Status: %s | Timestamp: %s | Service: %s | Source: %s | Tags: %s | Message: %s\n",
+		pair.stage,
 		m.Origin.LogSource.Name,
 		m.Origin.LogSource.Config.Type,
 		m.GetStatus(),
@@ -135,5 +134,5 @@ func formatMessage(m *message.Message) string {
 		m.Origin.Service(),
 		m.Origin.Source(),
 		m.Origin.TagsToString(),
-		string(m.Content))
+		string(pair.rendered))
 }
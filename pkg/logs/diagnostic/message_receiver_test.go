@@ -18,7 +18,7 @@ func TestEnableDisable(t *testing.T) {
 	assert.False(t, b.SetEnabled(true))
 
 	for i := 0; i < 10; i++ {
-		b.HandleMessage(newMessage("", "", ""))
+		b.HandleMessage(newMessage("", "", ""), []byte("a"), StageProcessed)
 	}
 
 	msg, ok := b.Next(nil)
@@ -33,7 +33,7 @@ func TestEnableDisable(t *testing.T) {
 	assert.Equal(t, "", msg)
 
 	for i := 0; i < 10; i++ {
-		b.HandleMessage(newMessage("", "", ""))
+		b.HandleMessage(newMessage("", "", ""), []byte("a"), StageProcessed)
 	}
 
 	// disabled, no messages should have been buffered
@@ -48,9 +48,9 @@ func TestFilterAll(t *testing.T) {
 	b.SetEnabled(true)
 
 	for i := 0; i < 5; i++ {
-		b.HandleMessage(newMessage("test1", "a", "b"))
-		b.HandleMessage(newMessage("test1", "1", "2"))
-		b.HandleMessage(newMessage("test2", "a", "b"))
+		b.HandleMessage(newMessage("test1", "a", "b"), []byte("a"), StageProcessed)
+		b.HandleMessage(newMessage("test1", "1", "2"), []byte("a"), StageProcessed)
+		b.HandleMessage(newMessage("test2", "a", "b"), []byte("a"), StageProcessed)
 	}
 
 	filters := Filters{
@@ -76,8 +76,8 @@ func TestFilterTypeAndSource(t *testing.T) {
 	b.SetEnabled(true)
 
 	for i := 0; i < 5; i++ {
-		b.HandleMessage(newMessage("test", "a", "b"))
-		b.HandleMessage(newMessage("test", "1", "2"))
+		b.HandleMessage(newMessage("test", "a", "b"), []byte("a"), StageProcessed)
+		b.HandleMessage(newMessage("test", "1", "2"), []byte("a"), StageProcessed)
 	}
 
 	filters := Filters{
@@ -102,9 +102,9 @@ func TestFilterName(t *testing.T) {
 	b.SetEnabled(true)
 
 	for i := 0; i < 5; i++ {
-		b.HandleMessage(newMessage("test1", "a", "b"))
-		b.HandleMessage(newMessage("test2", "a", "2"))
-		b.HandleMessage(newMessage("test2", "b", "2"))
+		b.HandleMessage(newMessage("test1", "a", "b"), []byte("a"), StageProcessed)
+		b.HandleMessage(newMessage("test2", "a", "2"), []byte("a"), StageProcessed)
+		b.HandleMessage(newMessage("test2", "b", "2"), []byte("a"), StageProcessed)
 	}
 
 	filters := Filters{
@@ -128,9 +128,9 @@ func TestFilterSource(t *testing.T) {
 	b.SetEnabled(true)
 
 	for i := 0; i < 5; i++ {
-		b.HandleMessage(newMessage("test", "a", "b"))
-		b.HandleMessage(newMessage("test", "a", "2"))
-		b.HandleMessage(newMessage("test", "b", "2"))
+		b.HandleMessage(newMessage("test", "a", "b"), []byte("a"), StageProcessed)
+		b.HandleMessage(newMessage("test", "a", "2"), []byte("a"), StageProcessed)
+		b.HandleMessage(newMessage("test", "b", "2"), []byte("a"), StageProcessed)
 	}
 
 	filters := Filters{
@@ -154,9 +154,9 @@ func TestFilterType(t *testing.T) {
 	b.SetEnabled(true)
 
 	for i := 0; i < 5; i++ {
-		b.HandleMessage(newMessage("test", "a", "b"))
-		b.HandleMessage(newMessage("test", "a", "2"))
-		b.HandleMessage(newMessage("test", "b", "2"))
+		b.HandleMessage(newMessage("test", "a", "b"), []byte("a"), StageProcessed)
+		b.HandleMessage(newMessage("test", "a", "2"), []byte("a"), StageProcessed)
+		b.HandleMessage(newMessage("test", "b", "2"), []byte("a"), StageProcessed)
 	}
 
 	filters := Filters{
@@ -180,9 +180,9 @@ func TestNoFilters(t *testing.T) {
 	b.SetEnabled(true)
 
 	for i := 0; i < 5; i++ {
-		b.HandleMessage(newMessage("test", "a", "b"))
-		b.HandleMessage(newMessage("test", "a", "2"))
-		b.HandleMessage(newMessage("test", "b", "2"))
+		b.HandleMessage(newMessage("test", "a", "b"), []byte("a"), StageProcessed)
+		b.HandleMessage(newMessage("test", "a", "2"), []byte("a"), StageProcessed)
+		b.HandleMessage(newMessage("test", "b", "2"), []byte("a"), StageProcessed)
 	}
 
 	filters := Filters{
@@ -210,3 +210,112 @@ func newMessage(n string, t string, s string) message.Message {
 	origin := message.NewOrigin(source)
 	return *message.NewMessage([]byte("a"), origin, "", 0)
 }
+
+func TestFilterServiceStatusAndTags(t *testing.T) {
+	b := NewBufferedMessageReceiver()
+	b.SetEnabled(true)
+
+	source := config.NewLogSource("test", &config.LogsConfig{Service: "web", Tags: []string{"env:prod", "team:logs"}})
+	b.HandleMessage(*message.NewMessage([]byte("a"), message.NewOrigin(source), message.StatusError, 0), []byte("a"), StageProcessed)
+	b.HandleMessage(*message.NewMessage([]byte("b"), message.NewOrigin(source), message.StatusInfo, 0), []byte("b"), StageProcessed)
+	b.HandleMessage(newMessage("test", "a", "b"), []byte("c"), StageProcessed)
+
+	filters := Filters{
+		Service: "web",
+		Status:  message.StatusError,
+		Tags:    []string{"env:prod", "team"},
+	}
+
+	msg, ok := b.Next(&filters)
+	assert.True(t, ok)
+	assert.Contains(t, msg, "Message: a")
+
+	_, ok = b.Next(&filters)
+	assert.False(t, ok)
+
+	b.HandleMessage(*message.NewMessage([]byte("a"), message.NewOrigin(source), message.StatusError, 0), []byte("a"), StageProcessed)
+	filters.Tags = []string{"env:staging"}
+	_, ok = b.Next(&filters)
+	assert.False(t, ok)
+}
+
+func TestFilterContent(t *testing.T) {
+	b := NewBufferedMessageReceiver()
+	b.SetEnabled(true)
+
+	b.HandleMessage(newMessage("test", "a", "b"), []byte("user=foo password=bar"), StageTailer)
+	b.HandleMessage(newMessage("test", "a", "b"), []byte("user=foo password=[redacted]"), StageProcessed)
+	b.HandleMessage(newMessage("test", "a", "b"), []byte(`{"message":"user=foo password=[redacted]"}`), StageEncoded)
+
+	filters := Filters{
+		Content: `password=\w+`,
+		Stages:  []string{StageTailer, StageProcessed, StageEncoded},
+	}
+	assert.Nil(t, filters.Compile())
+
+	msg, ok := b.Next(&filters)
+	assert.True(t, ok)
+	assert.Equal(t, "Stage: tailer | Name: test | Type: a | Status: info | Timestamp: 0001-01-01 00:00:00 +0000 UTC | Service:  | Source: b | Tags:  | Message: user=foo password=bar\n", msg)
+
+	// the mask rule has been applied
+	_, ok = b.Next(&filters)
+	assert.False(t, ok)
+}
+
+func TestFilterStages(t *testing.T) {
+	b := NewBufferedMessageReceiver()
+	b.SetEnabled(true)
+
+	for _, stage := range []string{StageTailer, StageProcessed, StageEncoded} {
+		b.HandleMessage(newMessage("test", "a", "b"), []byte(stage), stage)
+	}
+
+	// the processed messages are streamed by default
+	msg, ok := b.Next(&Filters{})
+	assert.True(t, ok)
+	assert.Contains(t, msg, "Stage: processed")
+	_, ok = b.Next(&Filters{})
+	assert.False(t, ok)
+
+	for _, stage := range []string{StageTailer, StageProcessed, StageEncoded} {
+		b.HandleMessage(newMessage("test", "a", "b"), []byte(stage), stage)
+	}
+
+	filters := Filters{Stages: []string{StageTailer, StageEncoded}}
+	msg, ok = b.Next(&filters)
+	assert.True(t, ok)
+	assert.Contains(t, msg, "Stage: tailer")
+	msg, ok = b.Next(&filters)
+	assert.True(t, ok)
+	assert.Contains(t, msg, "Stage: encoded")
+	_, ok = b.Next(&filters)
+	assert.False(t, ok)
+}
+
+func TestIsStageEnabled(t *testing.T) {
+	b := NewBufferedMessageReceiver()
+	assert.False(t, b.IsStageEnabled(StageProcessed))
+
+	b.SetEnabled(true)
+	assert.False(t, b.IsStageEnabled(StageTailer))
+	assert.True(t, b.IsStageEnabled(StageProcessed))
+	assert.False(t, b.IsStageEnabled(StageEncoded))
+
+	b.SetStages([]string{StageTailer, StageEncoded})
+	assert.True(t, b.IsStageEnabled(StageTailer))
+	assert.False(t, b.IsStageEnabled(StageProcessed))
+	assert.True(t, b.IsStageEnabled(StageEncoded))
+
+	// the stages are reset with the receiver
+	b.SetEnabled(false)
+	b.SetEnabled(true)
+	assert.False(t, b.IsStageEnabled(StageTailer))
+	assert.True(t, b.IsStageEnabled(StageProcessed))
+}
+
+func TestFiltersCompile(t *testing.T) {
+	assert.Nil(t, (&Filters{}).Compile())
+	assert.Nil(t, (&Filters{Content: "foo.*bar", Stages: []string{StageTailer}}).Compile())
+	assert.NotNil(t, (&Filters{Content: "foo("}).Compile())
+	assert.NotNil(t, (&Filters{Stages: []string{"sender"}}).Compile())
+}
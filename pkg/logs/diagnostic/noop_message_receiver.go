@@ -11,4 +11,9 @@ import "github.com/DataDog/datadog-agent/pkg/logs/message"
 type NoopMessageReceiver struct{}
 
 // HandleMessage does nothing with the message
-func (n *NoopMessageReceiver) HandleMessage(m message.Message) {}
+func (n *NoopMessageReceiver) HandleMessage(m message.Message, rendered []byte, stage string) {}
+
+// IsStageEnabled returns false as the messages are never handled
+func (n *NoopMessageReceiver) IsStageEnabled(stage string) bool {
+	return false
+}
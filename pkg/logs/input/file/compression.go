@@ -0,0 +1,75 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package file
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression formats of the files read through a decompressing reader.
+const (
+	noCompression   = ""
+	gzipCompression = "gzip"
+	zstdCompression = "zstd"
+)
+
+// magic numbers starting the compressed files.
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// DetectCompression returns the compression format of the file at path,
+// an empty string when it is not compressed.
+func DetectCompression(path string) (string, error) {
+	f, err := openFile(path)
+	if err != nil {
+		return noCompression, err
+	}
+	defer f.Close()
+	return detectCompression(f)
+}
+
+// detectCompression returns the compression format of an open file from the
+// magic number it starts with, without moving its read offset.
+func detectCompression(f *os.File) (string, error) {
+	header := make([]byte, len(zstdMagic))
+	n, err := f.ReadAt(header, 0)
+	if err != nil && err != io.EOF {
+		return noCompression, err
+	}
+	header = header[:n]
+	switch {
+	case bytes.HasPrefix(header, gzipMagic):
+		return gzipCompression, nil
+	case bytes.HasPrefix(header, zstdMagic):
+		return zstdCompression, nil
+	default:
+		return noCompression, nil
+	}
+}
+
+// newDecompressingReader returns a reader decompressing the content of r.
+func newDecompressingReader(r io.Reader, compression string) (io.ReadCloser, error) {
+	switch compression {
+	case gzipCompression:
+		return gzip.NewReader(r)
+	case zstdCompression:
+		decoder, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		return decoder.IOReadCloser(), nil
+	default:
+		return nil, fmt.Errorf("unsupported compression %q", compression)
+	}
+}
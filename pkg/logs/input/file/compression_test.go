@@ -0,0 +1,114 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package file
+
+import (
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeCompressedFile writes a content compressed with the given format to path.
+func writeCompressedFile(t *testing.T, path string, compression string, content string) {
+	f, err := os.Create(path)
+	require.Nil(t, err)
+	defer f.Close()
+	var w io.WriteCloser
+	switch compression {
+	case gzipCompression:
+		w = gzip.NewWriter(f)
+	case zstdCompression:
+		w, err = zstd.NewWriter(f)
+		require.Nil(t, err)
+	default:
+		_, err = f.WriteString(content)
+		require.Nil(t, err)
+		return
+	}
+	_, err = w.Write([]byte(content))
+	require.Nil(t, err)
+	require.Nil(t, w.Close())
+}
+
+func TestDetectCompression(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "log-compression-test-")
+	require.Nil(t, err)
+	defer os.RemoveAll(testDir)
+
+	for _, compression := range []string{noCompression, gzipCompression, zstdCompression} {
+		path := filepath.Join(testDir, "file.log")
+		writeCompressedFile(t, path, compression, "hello world\n")
+		detected, err := DetectCompression(path)
+		assert.Nil(t, err)
+		assert.Equal(t, compression, detected)
+	}
+
+	// files too small to hold a magic number are not compressed
+	path := filepath.Join(testDir, "empty.log")
+	require.Nil(t, ioutil.WriteFile(path, []byte{0x1f}, 0644))
+	detected, err := DetectCompression(path)
+	assert.Nil(t, err)
+	assert.Equal(t, noCompression, detected)
+
+	_, err = DetectCompression(filepath.Join(testDir, "missing.log"))
+	assert.NotNil(t, err)
+}
+
+func TestNewDecompressingReader(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "log-compression-test-")
+	require.Nil(t, err)
+	defer os.RemoveAll(testDir)
+
+	for _, compression := range []string{gzipCompression, zstdCompression} {
+		path := filepath.Join(testDir, "file.log."+compression)
+		writeCompressedFile(t, path, compression, "hello\nworld\n")
+
+		f, err := os.Open(path)
+		require.Nil(t, err)
+		reader, err := newDecompressingReader(f, compression)
+		require.Nil(t, err, compression)
+		content, err := ioutil.ReadAll(reader)
+		assert.Nil(t, err, compression)
+		assert.Equal(t, "hello\nworld\n", string(content), compression)
+		assert.Nil(t, reader.Close())
+		f.Close()
+	}
+
+	_, err = newDecompressingReader(nil, "lz4")
+	assert.NotNil(t, err)
+}
+
+func TestComputeDecompressedFingerprint(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "log-compression-test-")
+	require.Nil(t, err)
+	defer os.RemoveAll(testDir)
+
+	path := filepath.Join(testDir, "file.log")
+	writeCompressedFile(t, path, noCompression, "hello world\n")
+	fingerprint, err := ComputeFingerprint(path, 10)
+	require.Nil(t, err)
+
+	// the fingerprint of a compressed file is the one of the file it was made of
+	for _, compression := range []string{gzipCompression, zstdCompression} {
+		compressedPath := filepath.Join(testDir, "file.log."+compression)
+		writeCompressedFile(t, compressedPath, compression, "hello world\n")
+		other, err := ComputeDecompressedFingerprint(compressedPath, compression, 10)
+		assert.Nil(t, err)
+		assert.Equal(t, fingerprint, other, compression)
+
+		// the decompressed content is too small to be fingerprinted
+		other, err = ComputeDecompressedFingerprint(compressedPath, compression, 100)
+		assert.Nil(t, err)
+		assert.Equal(t, uint64(0), other, compression)
+	}
+}
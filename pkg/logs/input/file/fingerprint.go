@@ -0,0 +1,81 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package file
+
+import (
+	"hash/crc64"
+	"io"
+	"math"
+	"os"
+)
+
+// fingerprintTable is the table used to compute the checksums identifying the files.
+var fingerprintTable = crc64.MakeTable(crc64.ECMA)
+
+// ComputeFingerprint returns the checksum of the first size bytes of the file at path,
+// it returns 0 when the file is smaller as its fingerprint is not known yet.
+func ComputeFingerprint(path string, size int) (uint64, error) {
+	f, err := openFile(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	return fingerprintFile(f, size)
+}
+
+// ComputeDecompressedFingerprint returns the checksum of the first size bytes of the
+// decompressed content of the file at path, it returns 0 when this content is smaller.
+func ComputeDecompressedFingerprint(path string, compression string, size int) (uint64, error) {
+	f, err := openFile(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	return fingerprintDecompressedFile(f, compression, size)
+}
+
+// fingerprintFile returns the checksum of the first size bytes of an open file,
+// without moving its read offset.
+func fingerprintFile(f *os.File, size int) (uint64, error) {
+	buf := make([]byte, size)
+	n, err := f.ReadAt(buf, 0)
+	if n < size {
+		if err == io.EOF {
+			err = nil
+		}
+		return 0, err
+	}
+	return checksum(buf), nil
+}
+
+// fingerprintDecompressedFile returns the checksum of the first size bytes of the
+// decompressed content of an open file, without moving its read offset. The
+// fingerprint of a compressed file thus matches the one of the file it was made of.
+func fingerprintDecompressedFile(f *os.File, compression string, size int) (uint64, error) {
+	reader, err := newDecompressingReader(io.NewSectionReader(f, 0, math.MaxInt64), compression)
+	if err != nil {
+		return 0, err
+	}
+	defer reader.Close()
+	buf := make([]byte, size)
+	if _, err := io.ReadFull(reader, buf); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			err = nil
+		}
+		return 0, err
+	}
+	return checksum(buf), nil
+}
+
+// checksum returns the fingerprint of a content.
+func checksum(content []byte) uint64 {
+	fingerprint := crc64.Checksum(content, fingerprintTable)
+	if fingerprint == 0 {
+		// 0 stands for an unknown fingerprint
+		fingerprint = 1
+	}
+	return fingerprint
+}
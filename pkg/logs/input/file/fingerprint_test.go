@@ -0,0 +1,73 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package file
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComputeFingerprint(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "log-fingerprint-test-")
+	require.Nil(t, err)
+	defer os.RemoveAll(testDir)
+
+	path := filepath.Join(testDir, "file.log")
+	require.Nil(t, ioutil.WriteFile(path, []byte("hello\n"), 0644))
+
+	// the file is too small to be fingerprinted
+	fingerprint, err := ComputeFingerprint(path, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(0), fingerprint)
+
+	require.Nil(t, ioutil.WriteFile(path, []byte("hello world\n"), 0644))
+	fingerprint, err = ComputeFingerprint(path, 10)
+	assert.Nil(t, err)
+	assert.NotEqual(t, uint64(0), fingerprint)
+
+	// only the first bytes are used
+	require.Nil(t, ioutil.WriteFile(path, []byte("hello world, how are you?\n"), 0644))
+	other, err := ComputeFingerprint(path, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, fingerprint, other)
+
+	require.Nil(t, ioutil.WriteFile(path, []byte("bye world, how are you?\n"), 0644))
+	other, err = ComputeFingerprint(path, 10)
+	assert.Nil(t, err)
+	assert.NotEqual(t, fingerprint, other)
+
+	_, err = ComputeFingerprint(filepath.Join(testDir, "missing.log"), 10)
+	assert.NotNil(t, err)
+}
+
+func TestFingerprintFileKeepsOffset(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "log-fingerprint-test-")
+	require.Nil(t, err)
+	defer os.RemoveAll(testDir)
+
+	path := filepath.Join(testDir, "file.log")
+	require.Nil(t, ioutil.WriteFile(path, []byte("hello world\n"), 0644))
+
+	f, err := os.Open(path)
+	require.Nil(t, err)
+	defer f.Close()
+	_, err = f.Seek(6, io.SeekStart)
+	require.Nil(t, err)
+
+	fingerprint, err := fingerprintFile(f, 5)
+	assert.Nil(t, err)
+	assert.NotEqual(t, uint64(0), fingerprint)
+
+	offset, err := f.Seek(0, io.SeekCurrent)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(6), offset)
+}
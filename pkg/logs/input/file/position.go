@@ -14,12 +14,32 @@ import (
 )
 
 // Position returns the position from where logs should be collected.
-func Position(registry auditor.Registry, identifier string, mode config.TailingMode) (int64, int, error) {
+// When the fingerprint of the file is known, the offset registered for the identifier
+// is only used if it belongs to the same file, the offset of a renamed file is found
+// with its fingerprint and a file which has been replaced is tailed from the beginning.
+func Position(registry auditor.Registry, identifier string, fingerprint uint64, mode config.TailingMode) (int64, int, error) {
 	var offset int64
 	var whence int
 	var err error
 
 	value := registry.GetOffset(identifier)
+	replaced := false
+	if fingerprint != 0 {
+		registeredFingerprint := registry.GetFingerprint(identifier)
+		switch {
+		case value != "" && registeredFingerprint == 0:
+			// the fingerprint of the registered offset is unknown, trust the identifier
+		case registeredFingerprint != fingerprint:
+			value = ""
+			if previousIdentifier := registry.GetIdentifierByFingerprint(fingerprint); previousIdentifier != "" {
+				// the file has been renamed
+				value = registry.GetOffset(previousIdentifier)
+			} else if registeredFingerprint != 0 {
+				// the file has been replaced since its offset was registered
+				replaced = true
+			}
+		}
+	}
 
 	switch {
 	case mode == config.ForceBeginning:
@@ -38,6 +58,9 @@ func Position(registry auditor.Registry, identifier string, mode config.TailingM
 				whence = io.SeekStart
 			}
 		}
+	case replaced:
+		// all the content of the new file is collected
+		offset, whence = 0, io.SeekStart
 	case mode == config.Beginning:
 		offset, whence = 0, io.SeekStart
 	case mode == config.End:
@@ -47,3 +70,19 @@ func Position(registry auditor.Registry, identifier string, mode config.TailingM
 	}
 	return offset, whence, err
 }
+
+// IsCompleted returns whether the file of an identifier has already been entirely read.
+// When the fingerprint of the file is known, a completion registered for another content
+// is ignored and the completion of a renamed file is found with its fingerprint.
+func IsCompleted(registry auditor.Registry, identifier string, fingerprint uint64) bool {
+	if fingerprint == 0 {
+		return registry.IsCompleted(identifier)
+	}
+	if registeredFingerprint := registry.GetFingerprint(identifier); registeredFingerprint == 0 || registeredFingerprint == fingerprint {
+		if registry.IsCompleted(identifier) {
+			return true
+		}
+	}
+	previousIdentifier := registry.GetIdentifierByFingerprint(fingerprint)
+	return previousIdentifier != "" && registry.IsCompleted(previousIdentifier)
+}
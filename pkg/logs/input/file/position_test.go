@@ -22,49 +22,117 @@ func TestPosition(t *testing.T) {
 	var offset int64
 	var whence int
 
-	offset, whence, err = Position(registry, "", config.End)
+	offset, whence, err = Position(registry, "", 0, config.End)
 	assert.Nil(t, err)
 	assert.Equal(t, int64(0), offset)
 	assert.Equal(t, io.SeekEnd, whence)
 
-	offset, whence, err = Position(registry, "", config.Beginning)
+	offset, whence, err = Position(registry, "", 0, config.Beginning)
 	assert.Nil(t, err)
 	assert.Equal(t, int64(0), offset)
 	assert.Equal(t, io.SeekStart, whence)
 
 	registry.SetOffset("123456789")
-	offset, whence, err = Position(registry, "", config.End)
+	offset, whence, err = Position(registry, "", 0, config.End)
 	assert.Nil(t, err)
 	assert.Equal(t, int64(123456789), offset)
 	assert.Equal(t, io.SeekStart, whence)
 
 	registry.SetOffset("987654321")
-	offset, whence, err = Position(registry, "", config.Beginning)
+	offset, whence, err = Position(registry, "", 0, config.Beginning)
 	assert.Nil(t, err)
 	assert.Equal(t, int64(987654321), offset)
 	assert.Equal(t, io.SeekStart, whence)
 
 	registry.SetOffset("foo")
-	offset, whence, err = Position(registry, "", config.End)
+	offset, whence, err = Position(registry, "", 0, config.End)
 	assert.NotNil(t, err)
 	assert.Equal(t, int64(0), offset)
 	assert.Equal(t, io.SeekEnd, whence)
 
 	registry.SetOffset("bar")
-	offset, whence, err = Position(registry, "", config.Beginning)
+	offset, whence, err = Position(registry, "", 0, config.Beginning)
 	assert.NotNil(t, err)
 	assert.Equal(t, int64(0), offset)
 	assert.Equal(t, io.SeekStart, whence)
 
 	registry.SetOffset("123456789")
-	offset, whence, err = Position(registry, "", config.ForceBeginning)
+	offset, whence, err = Position(registry, "", 0, config.ForceBeginning)
 	assert.Nil(t, err)
 	assert.Equal(t, int64(0), offset)
 	assert.Equal(t, io.SeekStart, whence)
 
 	registry.SetOffset("987654321")
-	offset, whence, err = Position(registry, "", config.ForceEnd)
+	offset, whence, err = Position(registry, "", 0, config.ForceEnd)
 	assert.Nil(t, err)
 	assert.Equal(t, int64(0), offset)
 	assert.Equal(t, io.SeekEnd, whence)
 }
+
+func TestPositionWithFingerprint(t *testing.T) {
+	registry := mock.NewRegistry()
+
+	var err error
+	var offset int64
+	var whence int
+
+	// the fingerprint of the offset is unknown, the identifier is trusted
+	registry.SetIdentifierOffset("file:/var/log/app.log", "42")
+	offset, whence, err = Position(registry, "file:/var/log/app.log", 1234, config.End)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(42), offset)
+	assert.Equal(t, io.SeekStart, whence)
+
+	// same file
+	registry.SetFingerprint("file:/var/log/app.log", 1234)
+	offset, whence, err = Position(registry, "file:/var/log/app.log", 1234, config.End)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(42), offset)
+	assert.Equal(t, io.SeekStart, whence)
+
+	// the file has been replaced, it is tailed from the beginning
+	offset, whence, err = Position(registry, "file:/var/log/app.log", 5678, config.End)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), offset)
+	assert.Equal(t, io.SeekStart, whence)
+
+	// the file has been renamed, its offset is found with its fingerprint
+	offset, whence, err = Position(registry, "file:/var/log/app.log.1", 1234, config.End)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(42), offset)
+	assert.Equal(t, io.SeekStart, whence)
+
+	// new file
+	offset, whence, err = Position(registry, "file:/var/log/other.log", 5678, config.End)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), offset)
+	assert.Equal(t, io.SeekEnd, whence)
+
+	// the tailing mode is forced
+	offset, whence, err = Position(registry, "file:/var/log/app.log.1", 1234, config.ForceEnd)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), offset)
+	assert.Equal(t, io.SeekEnd, whence)
+}
+
+func TestIsCompleted(t *testing.T) {
+	registry := mock.NewRegistry()
+
+	assert.False(t, IsCompleted(registry, "file:/var/log/app.log.1.gz", 0))
+	assert.False(t, IsCompleted(registry, "file:/var/log/app.log.1.gz", 1234))
+
+	registry.SetCompleted("file:/var/log/app.log.1.gz")
+	assert.True(t, IsCompleted(registry, "file:/var/log/app.log.1.gz", 0))
+	// the fingerprint of the completion is unknown, the identifier is trusted
+	assert.True(t, IsCompleted(registry, "file:/var/log/app.log.1.gz", 1234))
+
+	registry.SetFingerprint("file:/var/log/app.log.1.gz", 1234)
+	assert.True(t, IsCompleted(registry, "file:/var/log/app.log.1.gz", 1234))
+
+	// the file has been replaced
+	assert.False(t, IsCompleted(registry, "file:/var/log/app.log.1.gz", 5678))
+
+	// the file has been renamed, its completion is found with its fingerprint
+	assert.True(t, IsCompleted(registry, "file:/var/log/app.log.2.gz", 1234))
+	assert.False(t, IsCompleted(registry, "file:/var/log/app.log.2.gz", 5678))
+}
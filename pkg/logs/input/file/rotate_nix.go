@@ -16,7 +16,10 @@ import (
 // - renamed and recreated
 // - removed and recreated
 // - truncated
-func DidRotate(file *os.File, lastReadOffset int64) (bool, error) {
+// When its fingerprint is known, the file is also considered rotated if the beginning
+// of its content changed, which happens when it has been truncated then written
+// beyond the last read offset, or recreated with the same inode.
+func DidRotate(file *os.File, lastReadOffset int64, fingerprint uint64, fingerprintSize int) (bool, error) {
 	f, err := openFile(file.Name())
 	defer f.Close()
 	if err != nil {
@@ -36,5 +39,11 @@ func DidRotate(file *os.File, lastReadOffset int64) (bool, error) {
 	recreated := !os.SameFile(fi1, fi2)
 	truncated := fi1.Size() < lastReadOffset
 
-	return recreated || truncated, nil
+	changed := false
+	if fingerprint != 0 {
+		currentFingerprint, err := fingerprintFile(f, fingerprintSize)
+		changed = err == nil && currentFingerprint != 0 && currentFingerprint != fingerprint
+	}
+
+	return recreated || truncated || changed, nil
 }
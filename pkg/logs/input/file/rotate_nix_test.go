@@ -0,0 +1,48 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+// +build !windows
+
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDidRotateCopyTruncateWithFingerprint(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "log-rotate-test-")
+	require.Nil(t, err)
+	defer os.RemoveAll(testDir)
+
+	path := filepath.Join(testDir, "file.log")
+	require.Nil(t, ioutil.WriteFile(path, []byte("first line\n"), 0644))
+
+	f, err := os.Open(path)
+	require.Nil(t, err)
+	defer f.Close()
+	fingerprint, err := fingerprintFile(f, 10)
+	require.Nil(t, err)
+
+	didRotate, err := DidRotate(f, 11, fingerprint, 10)
+	assert.Nil(t, err)
+	assert.False(t, didRotate)
+
+	// the file is truncated then written beyond the last read offset
+	require.Nil(t, ioutil.WriteFile(path, []byte("another first line\n"), 0644))
+
+	didRotate, err = DidRotate(f, 11, 0, 10)
+	assert.Nil(t, err)
+	assert.False(t, didRotate)
+
+	didRotate, err = DidRotate(f, 11, fingerprint, 10)
+	assert.Nil(t, err)
+	assert.True(t, didRotate)
+}
@@ -13,6 +13,6 @@ import (
 
 // DidRotate is not implemented on windows, log rotations are handled by the
 // tailer for now.
-func DidRotate(file *os.File, lastReadOffset int64) (bool, error) {
+func DidRotate(file *os.File, lastReadOffset int64, fingerprint uint64, fingerprintSize int) (bool, error) {
 	return false, nil
 }
@@ -6,9 +6,12 @@
 package file
 
 import (
+	"os"
+	"path/filepath"
 	"sync/atomic"
 	"time"
 
+	coreConfig "github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 
 	"github.com/DataDog/datadog-agent/pkg/logs/auditor"
@@ -33,21 +36,30 @@ type Scanner struct {
 	tailers             map[string]*Tailer
 	registry            auditor.Registry
 	tailerSleepDuration time.Duration
-	stop                chan struct{}
+	// completedFiles holds the compressed files which have been entirely read, by scan
+	// key, so that they are not read again unless they are replaced
+	completedFiles map[string]os.FileInfo
+	// completedTailers receives the tailers which have entirely read their compressed file
+	completedTailers       chan *Tailer
+	catchUpCompressedFiles bool
+	stop                   chan struct{}
 }
 
 // NewScanner returns a new scanner.
 func NewScanner(sources *config.LogSources, tailingLimit int, pipelineProvider pipeline.Provider, registry auditor.Registry, tailerSleepDuration time.Duration) *Scanner {
 	return &Scanner{
-		pipelineProvider:    pipelineProvider,
-		tailingLimit:        tailingLimit,
-		addedSources:        sources.GetAddedForType(config.FileType),
-		removedSources:      sources.GetRemovedForType(config.FileType),
-		fileProvider:        NewProvider(tailingLimit),
-		tailers:             make(map[string]*Tailer),
-		registry:            registry,
-		tailerSleepDuration: tailerSleepDuration,
-		stop:                make(chan struct{}),
+		pipelineProvider:       pipelineProvider,
+		tailingLimit:           tailingLimit,
+		addedSources:           sources.GetAddedForType(config.FileType),
+		removedSources:         sources.GetRemovedForType(config.FileType),
+		fileProvider:           NewProvider(tailingLimit),
+		tailers:                make(map[string]*Tailer),
+		registry:               registry,
+		tailerSleepDuration:    tailerSleepDuration,
+		completedFiles:         make(map[string]os.FileInfo),
+		completedTailers:       make(chan *Tailer, tailingLimit),
+		catchUpCompressedFiles: coreConfig.Datadog.GetBool("logs_config.catch_up_compressed_rotated_files"),
+		stop:                   make(chan struct{}),
 	}
 }
 
@@ -73,6 +85,8 @@ func (s *Scanner) run() {
 			s.addSource(source)
 		case source := <-s.removedSources:
 			s.removeSource(source)
+		case tailer := <-s.completedTailers:
+			s.removeCompletedTailer(tailer)
 		case <-scanTicker.C:
 			// check if there are new files to tail, tailers to stop and tailer to restart because of file rotation
 			s.scan()
@@ -103,6 +117,7 @@ func (s *Scanner) cleanup() {
 func (s *Scanner) scan() {
 	files := s.fileProvider.FilesToTail(s.activeSources)
 	filesTailed := make(map[string]bool)
+	filesScanned := make(map[string]bool)
 	tailersLen := len(s.tailers)
 
 	for _, file := range files {
@@ -116,6 +131,7 @@ func (s *Scanner) scan() {
 		// when a tailer for a dead container is still tailing the file, and another
 		// tailer is tailing the file for the new container).
 		tailerKey := file.GetScanKey()
+		filesScanned[tailerKey] = true
 		tailer, isTailed := s.tailers[tailerKey]
 		if isTailed && atomic.LoadInt32(&tailer.shouldStop) != 0 {
 			// skip this tailer as it must be stopped
@@ -133,12 +149,19 @@ func (s *Scanner) scan() {
 				// the setup failed, let's try to tail this file in the next scan
 				continue
 			}
-			tailersLen++
+			// a tailer catching up on the rotation of the file may have been started too
+			tailersLen = len(s.tailers)
 			filesTailed[tailerKey] = true
 			continue
 		}
 
-		didRotate, err := DidRotate(tailer.osFile, tailer.GetReadOffset())
+		if tailer.compression != noCompression {
+			// a compressed file is read once and does not rotate
+			filesTailed[tailerKey] = true
+			continue
+		}
+
+		didRotate, err := DidRotate(tailer.osFile, tailer.GetReadOffset(), tailer.GetFingerprint(), tailer.fingerprintSize)
 		if err != nil {
 			continue
 		}
@@ -155,12 +178,25 @@ func (s *Scanner) scan() {
 	}
 
 	for _, tailer := range s.tailers {
-		// stop all tailers which have not been selected
+		if atomic.LoadInt32(&tailer.completed) != 0 && atomic.LoadInt32(&tailer.shouldStop) != 0 {
+			// the compressed file has been entirely read
+			s.removeCompletedTailer(tailer)
+			continue
+		}
+		// stop all tailers which have not been selected, the ones catching up
+		// on a rotated file being stopped once they are done
 		_, shouldTail := filesTailed[tailer.file.GetScanKey()]
-		if !shouldTail {
+		if !shouldTail && (!tailer.catchUp || atomic.LoadInt32(&tailer.shouldStop) != 0) {
 			s.stopTailer(tailer)
 		}
 	}
+
+	for key := range s.completedFiles {
+		// forget the files which are not there anymore
+		if !filesScanned[key] {
+			delete(s.completedFiles, key)
+		}
+	}
 }
 
 // addSource keeps track of the new source and launch new tailers for this source.
@@ -213,17 +249,44 @@ func (s *Scanner) launchTailers(source *config.LogSource) {
 func (s *Scanner) startNewTailer(file *File, m config.TailingMode) bool {
 	tailer := s.createTailer(file, s.pipelineProvider.NextPipelineChan())
 
-	var offset int64
-	var whence int
+	compression, err := DetectCompression(file.Path)
+	if err != nil {
+		log.Debugf("Could not detect the compression of file with path %v: %v", file.Path, err)
+	}
+	tailer.compression = compression
+
 	mode := s.handleTailingModeChange(tailer.Identifier(), m)
 
-	offset, whence, err := Position(s.registry, tailer.Identifier(), mode)
+	tailer.updateFingerprint()
+	if s.catchUpCompressedFiles && tailer.compression == noCompression {
+		s.catchUpRotatedFile(tailer)
+	}
+	return s.startTailer(tailer, mode)
+}
+
+// startTailer makes a tailer tail its file from the last committed offset, the beginning or the end of the file,
+// returns true if the operation succeeded, false otherwise or when the file is compressed and has already been read
+func (s *Scanner) startTailer(tailer *Tailer, mode config.TailingMode) bool {
+	file := tailer.file
+	if tailer.compression != noCompression && s.isCompleted(tailer) {
+		return false
+	}
+
+	if tailer.compression != noCompression && mode != config.ForceBeginning {
+		// the content of a compressed file does not change so there is nothing to tail from its end,
+		// it is read from the beginning unless an offset has been registered for it
+		mode = config.Beginning
+	}
+	offset, whence, err := Position(s.registry, tailer.Identifier(), tailer.GetFingerprint(), mode)
 	if err != nil {
 		log.Warnf("Could not recover offset for file with path %v: %v", file.Path, err)
 	}
 
 	log.Infof("Starting a new tailer for: %s (offset: %d, whence: %d) for tailer key %s", file.Path, offset, whence, file.GetScanKey())
 
+	if tailer.compression != noCompression {
+		tailer.completedTailers = s.completedTailers
+	}
 	err = tailer.Start(offset, whence)
 	if err != nil {
 		log.Warn(err)
@@ -234,6 +297,86 @@ func (s *Scanner) startNewTailer(file *File, m config.TailingMode) bool {
 	return true
 }
 
+// isCompleted returns whether the compressed file of a tailer has already been entirely read,
+// during this run or according to the registry.
+func (s *Scanner) isCompleted(tailer *Tailer) bool {
+	key := tailer.file.GetScanKey()
+	if info, exists := s.completedFiles[key]; exists {
+		if current, err := os.Stat(tailer.file.Path); err == nil && os.SameFile(info, current) {
+			return true
+		}
+		// the file has been replaced
+		delete(s.completedFiles, key)
+	}
+	if IsCompleted(s.registry, tailer.Identifier(), tailer.GetFingerprint()) {
+		s.markCompleted(tailer.file)
+		return true
+	}
+	return false
+}
+
+// removeCompletedTailer stops the tailer of a compressed file which has been entirely read
+// to free its open file slot, the file is not read again unless it is replaced.
+func (s *Scanner) removeCompletedTailer(tailer *Tailer) {
+	key := tailer.file.GetScanKey()
+	if s.tailers[key] != tailer {
+		// the tailer has already been removed
+		return
+	}
+	s.markCompleted(tailer.file)
+	s.stopTailer(tailer)
+}
+
+// markCompleted remembers that a compressed file has been entirely read.
+func (s *Scanner) markCompleted(file *File) {
+	if info, err := os.Stat(file.Path); err == nil {
+		s.completedFiles[file.GetScanKey()] = info
+	}
+}
+
+// catchUpRotatedFile looks for the compressed rotation of a file which has been replaced since
+// its offset was registered, like when the agent was stopped during a rotation, and starts a
+// tailer to collect the logs it still had unread. The rotation is found with the fingerprint of
+// its decompressed content. The tailer catching up is not started when it would exceed the open
+// files limit.
+func (s *Scanner) catchUpRotatedFile(tailer *Tailer) {
+	registeredFingerprint := s.registry.GetFingerprint(tailer.Identifier())
+	if registeredFingerprint == 0 || registeredFingerprint == tailer.GetFingerprint() {
+		return
+	}
+	if len(s.tailers)+2 > s.tailingLimit {
+		// the tailer catching up counts against the open files limit, with the tailer of the file
+		log.Warnf("Can't catch up on the rotation of %s, the limit of %d open files is reached", tailer.file.Path, s.tailingLimit)
+		return
+	}
+	paths, err := filepath.Glob(tailer.file.Path + "*")
+	if err != nil {
+		log.Debugf("Could not look for the rotations of file with path %v: %v", tailer.file.Path, err)
+		return
+	}
+	for _, path := range paths {
+		file := NewFile(path, tailer.file.Source, tailer.file.IsWildcardPath)
+		if _, isTailed := s.tailers[file.GetScanKey()]; isTailed || path == tailer.file.Path {
+			continue
+		}
+		compression, err := DetectCompression(path)
+		if err != nil || compression == noCompression {
+			continue
+		}
+		fingerprint, err := ComputeDecompressedFingerprint(path, compression, tailer.fingerprintSize)
+		if err != nil || fingerprint != registeredFingerprint {
+			continue
+		}
+		log.Infof("Catching up on %s, the compressed rotation of %s", path, tailer.file.Path)
+		catchUpTailer := s.createTailer(file, tailer.outputChan)
+		catchUpTailer.compression = compression
+		catchUpTailer.catchUp = true
+		catchUpTailer.updateFingerprint()
+		s.startTailer(catchUpTailer, config.Beginning)
+		return
+	}
+}
+
 // handleTailingModeChange determines the tailing behaviour when the tailing mode for a given file has its
 // configuration change. Two case may happen we can switch from "end" to "beginning" (1) and from "beginning" to
 // "end" (2). If the tailing mode is set to forceEnd or forceBeginning it will remain unchanged.
@@ -11,12 +11,15 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 
+	coreConfig "github.com/DataDog/datadog-agent/pkg/config"
 	auditor "github.com/DataDog/datadog-agent/pkg/logs/auditor/mock"
 	"github.com/DataDog/datadog-agent/pkg/logs/config"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
@@ -390,6 +393,135 @@ func TestScannerScanWithTooManyFiles(t *testing.T) {
 	assert.Equal(t, 2, len(scanner.tailers))
 }
 
+func TestScannerReadsCompressedFilesOnce(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "log-scanner-test-")
+	assert.Nil(t, err)
+	defer os.RemoveAll(testDir)
+
+	path := fmt.Sprintf("%s/app.log.1.gz", testDir)
+	writeCompressedFile(t, path, gzipCompression, "hello\nworld\n")
+
+	// create scanner
+	openFilesLimit := 2
+	sleepDuration := 20 * time.Millisecond
+	registry := auditor.NewRegistry()
+	scanner := NewScanner(config.NewLogSources(), openFilesLimit, mock.NewMockProvider(), registry, sleepDuration)
+	source := config.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: fmt.Sprintf("%s/*.gz", testDir), TailingMode: "beginning"})
+	status.Clear()
+	status.InitStatus(config.CreateSources([]*config.LogSource{source}))
+	defer status.Clear()
+
+	scanner.addSource(source)
+	assert.Equal(t, 1, len(scanner.tailers))
+	tailer := scanner.tailers[getScanKey(path, source)]
+	assert.Equal(t, gzipCompression, tailer.compression)
+	msg := <-tailer.outputChan
+	assert.Equal(t, "hello", string(msg.Content))
+	assert.False(t, msg.Origin.Completed)
+	msg = <-tailer.outputChan
+	assert.Equal(t, "world", string(msg.Content))
+	assert.True(t, msg.Origin.Completed)
+
+	// the tailer is removed once the file has been read, without waiting for a scan
+	assert.Equal(t, tailer, <-scanner.completedTailers)
+	scanner.removeCompletedTailer(tailer)
+	assert.Equal(t, 0, len(scanner.tailers))
+
+	// the file is not read again
+	scanner.scan()
+	assert.Equal(t, 0, len(scanner.tailers))
+
+	// the completion is recovered from the registry
+	registry.SetCompleted("file:" + path)
+	scanner = NewScanner(config.NewLogSources(), openFilesLimit, mock.NewMockProvider(), registry, sleepDuration)
+	scanner.addSource(source)
+	assert.Equal(t, 0, len(scanner.tailers))
+}
+
+func TestScannerReadsCompressedFilesTailedFromTheEnd(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "log-scanner-test-")
+	assert.Nil(t, err)
+	defer os.RemoveAll(testDir)
+
+	path := fmt.Sprintf("%s/app.log.1.zst", testDir)
+	writeCompressedFile(t, path, zstdCompression, "hello\nworld\n")
+
+	sleepDuration := 20 * time.Millisecond
+	scanner := NewScanner(config.NewLogSources(), 2, mock.NewMockProvider(), auditor.NewRegistry(), sleepDuration)
+	source := config.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: fmt.Sprintf("%s/*.zst", testDir), TailingMode: "end"})
+	status.Clear()
+	status.InitStatus(config.CreateSources([]*config.LogSource{source}))
+	defer status.Clear()
+
+	// the content of a compressed file does not change, it is read from the beginning
+	scanner.addSource(source)
+	assert.Equal(t, 1, len(scanner.tailers))
+	tailer := scanner.tailers[getScanKey(path, source)]
+	msg := <-tailer.outputChan
+	assert.Equal(t, "hello", string(msg.Content))
+	msg = <-tailer.outputChan
+	assert.Equal(t, "world", string(msg.Content))
+	scanner.cleanup()
+}
+
+func TestScannerCatchesUpOnCompressedRotation(t *testing.T) {
+	coreConfig.Datadog.Set("logs_config.file_fingerprint_size", 6)
+	defer coreConfig.Datadog.Set("logs_config.file_fingerprint_size", 0)
+
+	testDir, err := ioutil.TempDir("", "log-scanner-test-")
+	assert.Nil(t, err)
+	defer os.RemoveAll(testDir)
+
+	// the file has been rotated and compressed while its last lines were not collected yet
+	path := fmt.Sprintf("%s/app.log", testDir)
+	writeCompressedFile(t, path+".1.gz", gzipCompression, "hello\nagain\nworld\n")
+	writeCompressedFile(t, path, noCompression, "hello\n")
+	fingerprint, err := ComputeFingerprint(path, 6)
+	assert.Nil(t, err)
+	writeCompressedFile(t, path, noCompression, "new file\n")
+
+	registry := auditor.NewRegistry()
+	registry.SetIdentifierOffset("file:"+path, "6")
+	registry.SetFingerprint("file:"+path, fingerprint)
+
+	sleepDuration := 20 * time.Millisecond
+	scanner := NewScanner(config.NewLogSources(), 2, mock.NewMockProvider(), registry, sleepDuration)
+	scanner.catchUpCompressedFiles = true
+	source := config.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: path, TailingMode: "beginning"})
+	status.Clear()
+	status.InitStatus(config.CreateSources([]*config.LogSource{source}))
+	defer status.Clear()
+
+	scanner.addSource(source)
+	assert.Equal(t, 2, len(scanner.tailers))
+	catchUpTailer := scanner.tailers[getScanKey(path+".1.gz", source)]
+	assert.True(t, catchUpTailer.catchUp)
+
+	var contents []string
+	for i := 0; i < 3; i++ {
+		msg := <-catchUpTailer.outputChan
+		contents = append(contents, string(msg.Content))
+	}
+	assert.ElementsMatch(t, []string{"again", "world", "new file"}, contents)
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&catchUpTailer.shouldStop) != 0 }, 5*time.Second, 10*time.Millisecond)
+
+	// the tailer catching up is stopped once done
+	scanner.scan()
+	assert.Equal(t, 1, len(scanner.tailers))
+	scanner.cleanup()
+
+	// the tailer catching up counts against the open files limit
+	scanner = NewScanner(config.NewLogSources(), 1, mock.NewMockProvider(), registry, sleepDuration)
+	scanner.catchUpCompressedFiles = true
+	scanner.addSource(source)
+	assert.Equal(t, 1, len(scanner.tailers))
+	tailer := scanner.tailers[getScanKey(path, source)]
+	require.NotNil(t, tailer)
+	msg := <-tailer.outputChan
+	assert.Equal(t, "new file", string(msg.Content))
+	scanner.cleanup()
+}
+
 func getScanKey(path string, source *config.LogSource) string {
 	return NewFile(path, source, false).GetScanKey()
 }
@@ -9,6 +9,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -35,6 +36,22 @@ const DefaultSleepDuration = 1 * time.Second
 type Tailer struct {
 	readOffset    int64
 	decodedOffset int64
+	// fingerprint is the checksum of the first fingerprintSize bytes of the file, 0 until known
+	fingerprint     uint64
+	fingerprintSize int
+	// completed is set once the whole content of a compressed file has been read
+	completed int32
+
+	// compression is the compression format of the file, a compressed file is read
+	// once through the decompressor and its offsets are the ones of its decompressed content
+	compression  string
+	decompressor io.ReadCloser
+	// catchUp is set when the tailer reads the compressed rotation of a file
+	// which still had unread data
+	catchUp bool
+	// completedTailers is notified once the tailer of a compressed file has
+	// forwarded all the messages of the file
+	completedTailers chan<- *Tailer
 
 	// file contains the logs configuration for the file to parse (path, source, ...)
 	// If you are looking for the os.file use to read on the FS, see osFile.
@@ -95,19 +112,21 @@ func NewTailer(outputChan chan *message.Message, file *File, sleepDuration time.
 
 	forwardContext, stopForward := context.WithCancel(context.Background())
 	closeTimeout := coreConfig.Datadog.GetDuration("logs_config.close_timeout") * time.Second
+	fingerprintSize := coreConfig.Datadog.GetInt("logs_config.file_fingerprint_size")
 
 	return &Tailer{
-		file:           file,
-		outputChan:     outputChan,
-		decoder:        decoder.NewDecoderWithEndLineMatcher(file.Source, parser, matcher),
-		tagProvider:    tagProvider,
-		readOffset:     0,
-		sleepDuration:  sleepDuration,
-		closeTimeout:   closeTimeout,
-		stop:           make(chan struct{}, 1),
-		done:           make(chan struct{}, 1),
-		forwardContext: forwardContext,
-		stopForward:    stopForward,
+		file:            file,
+		outputChan:      outputChan,
+		decoder:         decoder.NewDecoderWithEndLineMatcher(file.Source, parser, matcher),
+		tagProvider:     tagProvider,
+		readOffset:      0,
+		sleepDuration:   sleepDuration,
+		closeTimeout:    closeTimeout,
+		fingerprintSize: fingerprintSize,
+		stop:            make(chan struct{}, 1),
+		done:            make(chan struct{}, 1),
+		forwardContext:  forwardContext,
+		stopForward:     stopForward,
 	}
 }
 
@@ -123,7 +142,12 @@ func (t *Tailer) Identifier() string {
 
 // Start let's the tailer open a file and tail from whence
 func (t *Tailer) Start(offset int64, whence int) error {
-	err := t.setup(offset, whence)
+	var err error
+	if t.compression != noCompression {
+		err = t.setupDecompression(offset)
+	} else {
+		err = t.setup(offset, whence)
+	}
 	if err != nil {
 		t.file.Source.Status.Error(err)
 		return err
@@ -143,14 +167,25 @@ func (t *Tailer) Start(offset int64, whence int) error {
 func (t *Tailer) readForever() {
 	defer t.onStop()
 	for {
-		n, err := t.read()
+		var n int
+		var err error
+		if t.decompressor != nil {
+			n, err = t.readDecompressed()
+		} else {
+			n, err = t.read()
+		}
 		if err != nil {
+			// stops the tailer, flushing the decoder when the end of a compressed file is reached
 			return
 		}
 		t.file.Source.BytesRead.Add(int64(n))
 		if t.file.Source.ParentSource != nil {
 			t.file.Source.ParentSource.BytesRead.Add(int64(n))
 		}
+		if n != 0 && t.GetFingerprint() == 0 && t.GetReadOffset() >= int64(t.fingerprintSize) {
+			// the file is now large enough to be fingerprinted
+			t.updateFingerprint()
+		}
 
 		select {
 		case <-t.stop:
@@ -168,6 +203,66 @@ func (t *Tailer) readForever() {
 	}
 }
 
+// setupDecompression sets up the tailer of a compressed file, offset being
+// the number of decompressed bytes to skip.
+func (t *Tailer) setupDecompression(offset int64) error {
+	fullpath, err := filepath.Abs(t.file.Path)
+	if err != nil {
+		return err
+	}
+	t.fullpath = fullpath
+
+	// adds metadata to enable users to filter logs by filename
+	t.tags = t.buildTailerTags()
+
+	log.Info("Opening", t.file.Path, "compressed with", t.compression, "for tailer key", t.file.GetScanKey())
+	f, err := openFile(fullpath)
+	if err != nil {
+		return err
+	}
+	decompressor, err := newDecompressingReader(f, t.compression)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	skipped, err := io.CopyN(ioutil.Discard, decompressor, offset)
+	if err != nil && err != io.EOF {
+		decompressor.Close()
+		f.Close()
+		return err
+	}
+
+	t.osFile = f
+	t.decompressor = decompressor
+	t.readOffset = skipped
+	t.decodedOffset = skipped
+
+	return nil
+}
+
+// readDecompressed reads the next bytes of the decompressed content of a
+// compressed file, it returns io.EOF once all of them have been read.
+func (t *Tailer) readDecompressed() (int, error) {
+	inBuf := make([]byte, 4096)
+	n, err := t.decompressor.Read(inBuf)
+	if err != nil && err != io.EOF {
+		// the file is corrupted or truncated, stop the tailer
+		t.file.Source.Status.Error(err)
+		return 0, log.Error("Unexpected error occurred while decompressing file: ", err)
+	}
+	if n == 0 && err == io.EOF {
+		log.Info("Finished reading", t.file.Path)
+		atomic.StoreInt32(&t.completed, 1)
+		return 0, err
+	}
+	if n == 0 {
+		return 0, nil
+	}
+	t.decoder.InputChan <- decoder.NewInput(inBuf[:n])
+	t.incrementReadOffset(n)
+	return n, nil
+}
+
 // buildTailerTags groups the file tag, directory (if wildcard path) and user tags
 func (t *Tailer) buildTailerTags() []string {
 	tags := []string{fmt.Sprintf("filename:%s", filepath.Base(t.file.Path))}
@@ -211,8 +306,16 @@ func (t *Tailer) startStopTimer() {
 // onStop finishes to stop the tailer
 func (t *Tailer) onStop() {
 	log.Info("Closing", t.file.Path, "for tailer key", t.file.GetScanKey())
+	if t.decompressor != nil {
+		t.decompressor.Close()
+	}
 	t.osFile.Close()
-	t.decoder.Stop()
+	if atomic.LoadInt32(&t.completed) != 0 {
+		// nothing can be appended to the last line of a compressed file
+		t.decoder.FlushAndStop()
+	} else {
+		t.decoder.Stop()
+	}
 }
 
 // forwardMessages lets the Tailer forward log messages to the output channel
@@ -221,7 +324,9 @@ func (t *Tailer) forwardMessages() {
 		// the decoder has successfully been flushed
 		atomic.StoreInt32(&t.shouldStop, 1)
 		close(t.done)
+		t.notifyCompleted()
 	}()
+	var last *message.Message
 	for output := range t.decoder.OutputChan {
 		offset := t.decodedOffset + int64(output.RawDataLen)
 		identifier := t.Identifier()
@@ -233,19 +338,55 @@ func (t *Tailer) forwardMessages() {
 		origin := message.NewOrigin(t.file.Source)
 		origin.Identifier = identifier
 		origin.Offset = strconv.FormatInt(offset, 10)
+		if identifier != "" {
+			origin.Fingerprint = t.GetFingerprint()
+		}
 		origin.SetTags(append(t.tags, t.tagProvider.GetTags()...))
 		// Ignore empty lines once the registry offset is updated
 		if len(output.Content) == 0 {
 			continue
 		}
-		// Make the write to the output chan cancellable to be able to stop the tailer
-		// after a file rotation when it is stuck on it.
-		// We don't return directly to keep the same shutdown sequence that in the
-		// normal case.
-		select {
-		case t.outputChan <- message.NewMessage(output.Content, origin, output.Status, output.IngestionTimestamp):
-		case <-t.forwardContext.Done():
+		msg := message.NewMessage(output.Content, origin, output.Status, output.IngestionTimestamp)
+		if t.compression != noCompression {
+			// the messages of a compressed file are forwarded one step behind, so that
+			// the last one can record in the registry that the file has been entirely read
+			last, msg = msg, last
+			if msg == nil {
+				continue
+			}
 		}
+		t.forward(msg)
+	}
+	if last != nil {
+		if atomic.LoadInt32(&t.completed) != 0 && last.Origin.Identifier != "" {
+			last.Origin.Completed = true
+		}
+		t.forward(last)
+	}
+}
+
+// notifyCompleted lets the scanner know that the compressed file has been entirely
+// forwarded, so that the tailer does not hold an open file slot until the next scan.
+func (t *Tailer) notifyCompleted() {
+	if t.completedTailers == nil || atomic.LoadInt32(&t.completed) == 0 {
+		return
+	}
+	select {
+	case t.completedTailers <- t:
+	default:
+		// the tailer will be removed by the next scan
+	}
+}
+
+// forward sends a message to the output channel.
+func (t *Tailer) forward(msg *message.Message) {
+	// Make the write to the output chan cancellable to be able to stop the tailer
+	// after a file rotation when it is stuck on it.
+	// We don't return directly to keep the same shutdown sequence that in the
+	// normal case.
+	select {
+	case t.outputChan <- msg:
+	case <-t.forwardContext.Done():
 	}
 }
 
@@ -270,6 +411,33 @@ func (t *Tailer) SetDecodedOffset(off int64) {
 	atomic.StoreInt64(&t.decodedOffset, off)
 }
 
+// GetFingerprint returns the fingerprint of the file, 0 if it is not known yet.
+func (t *Tailer) GetFingerprint() uint64 {
+	return atomic.LoadUint64(&t.fingerprint)
+}
+
+// updateFingerprint computes the fingerprint of the file when enabled, it stays
+// unknown while the file is smaller than the fingerprint size.
+func (t *Tailer) updateFingerprint() {
+	if t.fingerprintSize <= 0 {
+		return
+	}
+	var fingerprint uint64
+	var err error
+	if t.compression != noCompression {
+		fingerprint, err = ComputeDecompressedFingerprint(t.file.Path, t.compression, t.fingerprintSize)
+	} else if t.osFile != nil {
+		fingerprint, err = fingerprintFile(t.osFile, t.fingerprintSize)
+	} else {
+		fingerprint, err = ComputeFingerprint(t.file.Path, t.fingerprintSize)
+	}
+	if err != nil {
+		log.Debugf("Could not compute the fingerprint of %s: %v", t.file.Path, err)
+		return
+	}
+	atomic.StoreUint64(&t.fingerprint, fingerprint)
+}
+
 // shouldTrackOffset returns whether the tailer should track the file offset or not
 func (t *Tailer) shouldTrackOffset() bool {
 	if atomic.LoadInt32(&t.didFileRotate) != 0 {
@@ -138,6 +138,45 @@ func (suite *TailerTestSuite) TestTailFromBeginning() {
 	suite.Equal(len(lines[0])+len(lines[1])+len(lines[2]), int(suite.tailer.decodedOffset))
 }
 
+func (suite *TailerTestSuite) TestTailCompressedFile() {
+	writeCompressedFile(suite.T(), suite.testPath, gzipCompression, "hello world\nhello again\ngood bye\n")
+	suite.tailer.compression = gzipCompression
+
+	// the offset is the one of the decompressed content
+	suite.Nil(suite.tailer.Start(int64(len("hello world\n")), io.SeekStart))
+
+	msg := <-suite.outputChan
+	suite.Equal("hello again", string(msg.Content))
+	suite.Equal(len("hello world\nhello again\n"), toInt(msg.Origin.Offset))
+	suite.False(msg.Origin.Completed)
+
+	// the last message records that the file has been entirely read
+	msg = <-suite.outputChan
+	suite.Equal("good bye", string(msg.Content))
+	suite.Equal(len("hello world\nhello again\ngood bye\n"), toInt(msg.Origin.Offset))
+	suite.True(msg.Origin.Completed)
+}
+
+func (suite *TailerTestSuite) TestTailCompressedFileWithoutTrailingNewLine() {
+	writeCompressedFile(suite.T(), suite.testPath, zstdCompression, "hello world\ngood bye")
+	suite.tailer.compression = zstdCompression
+	completedTailers := make(chan *Tailer, 1)
+	suite.tailer.completedTailers = completedTailers
+
+	suite.Nil(suite.tailer.Start(0, io.SeekStart))
+
+	msg := <-suite.outputChan
+	suite.Equal("hello world", string(msg.Content))
+	suite.False(msg.Origin.Completed)
+
+	// the decoder is flushed at the end of the file
+	msg = <-suite.outputChan
+	suite.Equal("good bye", string(msg.Content))
+	suite.Equal(len("hello world\ngood bye"), toInt(msg.Origin.Offset))
+	suite.True(msg.Origin.Completed)
+	suite.Equal(suite.tailer, <-completedTailers)
+}
+
 func (suite *TailerTestSuite) TestTailFromEnd() {
 	lines := []string{"hello world\n", "hello again\n", "good bye\n"}
 
@@ -0,0 +1,128 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package syslog
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+)
+
+// maxFrameLengthDigits is the maximum number of digits of the MSG-LEN of an octet-counted frame.
+const maxFrameLengthDigits = 10
+
+// frameReader returns the syslog frames read from a connection one by one.
+type frameReader interface {
+	next() ([]byte, error)
+}
+
+// streamFrameReader splits a TCP stream into frames as described in RFC 6587:
+// a frame starting with a digit uses octet counting ("MSG-LEN SP SYSLOG-MSG"),
+// any other frame is terminated by a line feed (non-transparent framing).
+// Frames bigger than frameSize are truncated, the trailing part is dropped.
+type streamFrameReader struct {
+	reader    *bufio.Reader
+	frameSize int
+}
+
+func newStreamFrameReader(r io.Reader, frameSize int) *streamFrameReader {
+	return &streamFrameReader{
+		reader:    bufio.NewReaderSize(r, frameSize),
+		frameSize: frameSize,
+	}
+}
+
+// next returns the next frame of the stream.
+func (r *streamFrameReader) next() ([]byte, error) {
+	for {
+		first, err := r.reader.Peek(1)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case first[0] >= '1' && first[0] <= '9':
+			return r.nextOctetCounted()
+		case first[0] == '\n' || first[0] == '\r':
+			// skip empty lines
+			r.reader.ReadByte() //nolint:errcheck
+		default:
+			return r.nextNonTransparent()
+		}
+	}
+}
+
+// nextOctetCounted reads a frame prefixed with its length.
+func (r *streamFrameReader) nextOctetCounted() ([]byte, error) {
+	prefix, err := r.reader.ReadSlice(' ')
+	if err != nil {
+		return nil, err
+	}
+	if len(prefix) > maxFrameLengthDigits+1 {
+		return nil, fmt.Errorf("invalid frame length %q", prefix)
+	}
+	length, err := strconv.Atoi(string(prefix[:len(prefix)-1]))
+	if err != nil {
+		return nil, fmt.Errorf("invalid frame length %q", prefix)
+	}
+	size := length
+	if size > r.frameSize {
+		size = r.frameSize
+	}
+	frame := make([]byte, size)
+	if _, err := io.ReadFull(r.reader, frame); err != nil {
+		return nil, err
+	}
+	if _, err := r.reader.Discard(length - size); err != nil {
+		return nil, err
+	}
+	return frame, nil
+}
+
+// nextNonTransparent reads a frame terminated by a line feed.
+func (r *streamFrameReader) nextNonTransparent() ([]byte, error) {
+	line, err := r.reader.ReadSlice('\n')
+	if err == bufio.ErrBufferFull {
+		// the frame is too big, keep its head and drop the rest of the line.
+		frame := make([]byte, len(line))
+		copy(frame, line)
+		for err == bufio.ErrBufferFull {
+			_, err = r.reader.ReadSlice('\n')
+		}
+		return frame, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	frame := make([]byte, len(line))
+	copy(frame, line)
+	return frame, nil
+}
+
+// datagramFrameReader reads UDP datagrams, each datagram holding exactly one message
+// as described in RFC 5426. Datagrams bigger than frameSize are truncated.
+type datagramFrameReader struct {
+	conn      net.Conn
+	frameSize int
+}
+
+func newDatagramFrameReader(conn net.Conn, frameSize int) *datagramFrameReader {
+	return &datagramFrameReader{
+		conn:      conn,
+		frameSize: frameSize,
+	}
+}
+
+// next returns the content of the next datagram.
+func (r *datagramFrameReader) next() ([]byte, error) {
+	frame := make([]byte, r.frameSize)
+	n, err := r.conn.Read(frame)
+	if err != nil {
+		return nil, err
+	}
+	return frame[:n], nil
+}
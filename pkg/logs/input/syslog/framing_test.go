@@ -0,0 +1,57 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package syslog
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readFrames(t *testing.T, reader frameReader) []string {
+	var frames []string
+	for {
+		frame, err := reader.next()
+		if err == io.EOF {
+			return frames
+		}
+		require.NoError(t, err)
+		frames = append(frames, string(frame))
+	}
+}
+
+func TestStreamFrameReaderWithOctetCounting(t *testing.T) {
+	reader := newStreamFrameReader(strings.NewReader("5 <1>ab10 <1>foo\nbar"), 256)
+	assert.Equal(t, []string{"<1>ab", "<1>foo\nbar"}, readFrames(t, reader))
+}
+
+func TestStreamFrameReaderWithNonTransparentFraming(t *testing.T) {
+	reader := newStreamFrameReader(strings.NewReader("<1>foo\n\n<1>bar\r\n"), 256)
+	assert.Equal(t, []string{"<1>foo\n", "<1>bar\r\n"}, readFrames(t, reader))
+}
+
+func TestStreamFrameReaderWithMixedFraming(t *testing.T) {
+	reader := newStreamFrameReader(strings.NewReader("<1>foo\n6 <1>bar<1>baz\n"), 256)
+	assert.Equal(t, []string{"<1>foo\n", "<1>bar", "<1>baz\n"}, readFrames(t, reader))
+}
+
+func TestStreamFrameReaderShouldTruncateBigFrames(t *testing.T) {
+	reader := newStreamFrameReader(strings.NewReader("20 <1>0123456789abcdefg<1>0123456789abcdefg\n<1>foo\n"), 16)
+	assert.Equal(t, []string{"<1>0123456789abc", "<1>0123456789abc", "<1>foo\n"}, readFrames(t, reader))
+}
+
+func TestStreamFrameReaderShouldFailWithInvalidLength(t *testing.T) {
+	reader := newStreamFrameReader(strings.NewReader("12345678901 <1>foo"), 256)
+	_, err := reader.next()
+	assert.Error(t, err)
+
+	reader = newStreamFrameReader(strings.NewReader("1a <1>foo"), 256)
+	_, err = reader.next()
+	assert.Error(t, err)
+}
@@ -0,0 +1,65 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package syslog
+
+import (
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/pipeline"
+	"github.com/DataDog/datadog-agent/pkg/logs/restart"
+)
+
+// Launcher starts a syslog listener for every syslog source.
+type Launcher struct {
+	pipelineProvider pipeline.Provider
+	frameSize        int
+	sources          chan *config.LogSource
+	listeners        []restart.Restartable
+	stop             chan struct{}
+}
+
+// NewLauncher returns an initialized Launcher
+func NewLauncher(sources *config.LogSources, frameSize int, pipelineProvider pipeline.Provider) *Launcher {
+	return &Launcher{
+		pipelineProvider: pipelineProvider,
+		frameSize:        frameSize,
+		sources:          sources.GetAddedForType(config.SyslogType),
+		stop:             make(chan struct{}),
+	}
+}
+
+// Start starts the launcher.
+func (l *Launcher) Start() {
+	go l.run()
+}
+
+// run starts a new listener for every new source, using UDP by default.
+func (l *Launcher) run() {
+	for {
+		select {
+		case source := <-l.sources:
+			var listener restart.Restartable
+			if source.Config.Protocol == config.TCPType {
+				listener = NewTCPListener(l.pipelineProvider, source, l.frameSize)
+			} else {
+				listener = NewUDPListener(l.pipelineProvider, source, l.frameSize)
+			}
+			listener.Start()
+			l.listeners = append(l.listeners, listener)
+		case <-l.stop:
+			return
+		}
+	}
+}
+
+// Stop stops all listeners
+func (l *Launcher) Stop() {
+	l.stop <- struct{}{}
+	stopper := restart.NewParallelStopper()
+	for _, listener := range l.listeners {
+		stopper.Add(listener)
+	}
+	stopper.Stop()
+}
@@ -0,0 +1,233 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package syslog
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/pipeline"
+	"github.com/DataDog/datadog-agent/pkg/logs/restart"
+)
+
+// defaultTimeout represents the time after which a connection is closed when no data is read
+const defaultTimeout = time.Minute
+
+// A TCPListener accepts syslog TCP connections and delegates the read operations to a tailer.
+type TCPListener struct {
+	pipelineProvider pipeline.Provider
+	source           *config.LogSource
+	frameSize        int
+	idleTimeout      time.Duration
+	listener         net.Listener
+	tailers          []*Tailer
+	mu               sync.Mutex
+	stop             chan struct{}
+}
+
+// NewTCPListener returns an initialized TCPListener
+func NewTCPListener(pipelineProvider pipeline.Provider, source *config.LogSource, frameSize int) *TCPListener {
+	return &TCPListener{
+		pipelineProvider: pipelineProvider,
+		source:           source,
+		frameSize:        frameSize,
+		idleTimeout:      defaultTimeout,
+		tailers:          []*Tailer{},
+		stop:             make(chan struct{}, 1),
+	}
+}
+
+// Start starts the listener to accept new incoming connections.
+func (l *TCPListener) Start() {
+	log.Infof("Starting syslog TCP listener on port %d", l.source.Config.Port)
+	err := l.startListener()
+	if err != nil {
+		log.Errorf("Can't start syslog TCP listener on port %d: %v", l.source.Config.Port, err)
+		l.source.Status.Error(err)
+		return
+	}
+	l.source.Status.Success()
+	go l.run()
+}
+
+// Stop stops the listener from accepting new connections and all the active tailers.
+func (l *TCPListener) Stop() {
+	log.Infof("Stopping syslog TCP listener on port %d", l.source.Config.Port)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.stop <- struct{}{}
+	if l.listener != nil {
+		l.listener.Close()
+	}
+	stopper := restart.NewParallelStopper()
+	for _, tailer := range l.tailers {
+		stopper.Add(tailer)
+	}
+	stopper.Stop()
+	l.tailers = nil
+}
+
+// run accepts new TCP connections and creates a dedicated tailer for each.
+func (l *TCPListener) run() {
+	for {
+		listener := l.currentListener()
+		select {
+		case <-l.stop:
+			// stop accepting new connections.
+			listener.Close()
+			return
+		default:
+			conn, err := listener.Accept()
+			switch {
+			case err != nil && isClosedConnError(err):
+				return
+			case err != nil:
+				// an error occurred, restart the listener.
+				log.Warnf("Can't accept syslog connection on port %d, restarting the listener: %v", l.source.Config.Port, err)
+				listener.Close()
+				if err := l.startListener(); err != nil {
+					log.Errorf("Can't restart syslog TCP listener on port %d: %v", l.source.Config.Port, err)
+					l.source.Status.Error(err)
+					return
+				}
+				l.source.Status.Success()
+			default:
+				l.startTailer(conn)
+			}
+		}
+	}
+}
+
+// currentListener returns the listener accepting the connections.
+func (l *TCPListener) currentListener() net.Listener {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.listener
+}
+
+// startListener starts a new listener, returns an error if it failed.
+func (l *TCPListener) startListener() error {
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", l.source.Config.Port))
+	if err != nil {
+		return err
+	}
+	// the listener is swapped under the lock so that Stop always closes the current one,
+	// a listener started after Stop is closed by run which then reads the stop signal.
+	l.mu.Lock()
+	l.listener = listener
+	l.mu.Unlock()
+	return nil
+}
+
+// startTailer creates and starts a new tailer that reads from the connection.
+func (l *TCPListener) startTailer(conn net.Conn) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	reader := &tcpFrameReader{listener: l, conn: conn}
+	reader.reader = newStreamFrameReader(reader, l.frameSize)
+	reader.tailer = NewTailer(l.source, conn, reader, l.pipelineProvider.NextPipelineChan())
+	l.tailers = append(l.tailers, reader.tailer)
+	reader.tailer.Start()
+}
+
+// stopTailer stops the tailer and removes it from the active tailers, unless the listener
+// already did when stopping.
+func (l *TCPListener) stopTailer(tailer *Tailer) {
+	l.mu.Lock()
+	found := false
+	for i, t := range l.tailers {
+		if t == tailer {
+			l.tailers = append(l.tailers[:i], l.tailers[i+1:]...)
+			found = true
+			break
+		}
+	}
+	l.mu.Unlock()
+	if found {
+		tailer.Stop()
+	}
+}
+
+// tcpFrameReader reads the frames of a TCP connection. It closes idle connections and
+// stops their tailer once they are closed.
+type tcpFrameReader struct {
+	listener *TCPListener
+	conn     net.Conn
+	reader   frameReader
+	tailer   *Tailer
+}
+
+// next implements frameReader.
+func (r *tcpFrameReader) next() ([]byte, error) {
+	frame, err := r.reader.next()
+	if err != nil {
+		go r.listener.stopTailer(r.tailer)
+	}
+	return frame, err
+}
+
+// Read reads from the connection, failing when no data is read before the idle timeout.
+func (r *tcpFrameReader) Read(p []byte) (int, error) {
+	r.conn.SetReadDeadline(time.Now().Add(r.listener.idleTimeout)) //nolint:errcheck
+	return r.conn.Read(p)
+}
+
+// A UDPListener reads syslog datagrams, one message per datagram.
+type UDPListener struct {
+	pipelineProvider pipeline.Provider
+	source           *config.LogSource
+	frameSize        int
+	tailer           *Tailer
+}
+
+// NewUDPListener returns an initialized UDPListener
+func NewUDPListener(pipelineProvider pipeline.Provider, source *config.LogSource, frameSize int) *UDPListener {
+	return &UDPListener{
+		pipelineProvider: pipelineProvider,
+		source:           source,
+		frameSize:        frameSize,
+	}
+}
+
+// Start opens a new UDP connection and starts a tailer.
+func (l *UDPListener) Start() {
+	log.Infof("Starting syslog UDP listener on port %d", l.source.Config.Port)
+	udpAddr, err := net.ResolveUDPAddr("udp", fmt.Sprintf(":%d", l.source.Config.Port))
+	if err == nil {
+		var conn *net.UDPConn
+		conn, err = net.ListenUDP("udp", udpAddr)
+		if err == nil {
+			l.tailer = NewTailer(l.source, conn, newDatagramFrameReader(conn, l.frameSize), l.pipelineProvider.NextPipelineChan())
+			l.tailer.Start()
+		}
+	}
+	if err != nil {
+		log.Errorf("Can't start syslog UDP listener on port %d: %v", l.source.Config.Port, err)
+		l.source.Status.Error(err)
+		return
+	}
+	l.source.Status.Success()
+}
+
+// Stop stops the tailer.
+func (l *UDPListener) Stop() {
+	log.Infof("Stopping syslog UDP listener on port %d", l.source.Config.Port)
+	if l.tailer != nil {
+		l.tailer.Stop()
+	}
+}
+
+// isClosedConnError returns true if the error is related to a closed connection,
+// for more details, see: https://golang.org/src/internal/poll/fd.go#L18.
+func isClosedConnError(err error) bool {
+	return strings.Contains(err.Error(), "use of closed network connection")
+}
@@ -0,0 +1,61 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package syslog
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/pipeline/mock"
+)
+
+func (l *TCPListener) tailerCount() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.tailers)
+}
+
+func TestTCPListenerRemovesTailersOfClosedConnections(t *testing.T) {
+	pp := mock.NewMockProvider()
+	msgChan := pp.NextPipelineChan()
+	listener := NewTCPListener(pp, config.NewLogSource("", &config.LogsConfig{Port: 0}), 256)
+	listener.Start()
+	defer listener.Stop()
+
+	conn, err := net.Dial("tcp", listener.listener.Addr().String())
+	require.NoError(t, err)
+	fmt.Fprintf(conn, "<11>1 2003-10-11T22:14:15.003Z host app 42 - - hello\n")
+	<-msgChan
+	assert.Equal(t, 1, listener.tailerCount())
+
+	conn.Close()
+	assert.Eventually(t, func() bool { return listener.tailerCount() == 0 }, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestTCPListenerClosesIdleConnections(t *testing.T) {
+	pp := mock.NewMockProvider()
+	listener := NewTCPListener(pp, config.NewLogSource("", &config.LogsConfig{Port: 0}), 256)
+	listener.idleTimeout = 200 * time.Millisecond
+	listener.Start()
+	defer listener.Stop()
+
+	conn, err := net.Dial("tcp", listener.listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	assert.Eventually(t, func() bool { return listener.tailerCount() == 1 }, 5*time.Second, 10*time.Millisecond)
+	assert.Eventually(t, func() bool { return listener.tailerCount() == 0 }, 5*time.Second, 10*time.Millisecond)
+
+	// the connection was closed by the listener
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second)) //nolint:errcheck
+	_, err = conn.Read(make([]byte, 1))
+	assert.Error(t, err)
+}
@@ -0,0 +1,237 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package syslog
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// nilValue represents an empty field in RFC 5424 messages.
+const nilValue = "-"
+
+// utf8BOM may prefix the MSG part of RFC 5424 messages.
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+var (
+	errMissingPriority = errors.New("missing priority")
+	errInvalidPriority = errors.New("invalid priority")
+	errInvalidHeader   = errors.New("invalid header")
+)
+
+// severityStatusMapping maps syslog severities (the three lowest bits of the priority) to statuses.
+var severityStatusMapping = []string{
+	message.StatusEmergency,
+	message.StatusAlert,
+	message.StatusCritical,
+	message.StatusError,
+	message.StatusWarning,
+	message.StatusNotice,
+	message.StatusInfo,
+	message.StatusDebug,
+}
+
+// syslogMessage holds the fields of a parsed syslog message, either in the RFC 5424
+// or the RFC 3164 (BSD) format. Absent fields are left empty.
+type syslogMessage struct {
+	Facility       int                          `json:"facility"`
+	Severity       int                          `json:"severity"`
+	Version        int                          `json:"version,omitempty"`
+	Timestamp      string                       `json:"timestamp,omitempty"`
+	Hostname       string                       `json:"hostname,omitempty"`
+	AppName        string                       `json:"appname,omitempty"`
+	ProcID         string                       `json:"procid,omitempty"`
+	MsgID          string                       `json:"msgid,omitempty"`
+	StructuredData map[string]map[string]string `json:"structured_data,omitempty"`
+	Message        []byte                       `json:"-"`
+}
+
+// status returns the status matching the severity of the message.
+func (m *syslogMessage) status() string {
+	return severityStatusMapping[m.Severity]
+}
+
+// parse parses a syslog frame, detecting whether it is formatted according to RFC 5424
+// or RFC 3164. RFC 3164 parsing is lenient: any part of the header which can not be
+// recognized is considered to be part of the message.
+func parse(frame []byte) (*syslogMessage, error) {
+	pri, rest, err := parsePriority(frame)
+	if err != nil {
+		return nil, err
+	}
+	msg := &syslogMessage{
+		Facility: pri / 8,
+		Severity: pri % 8,
+	}
+	if len(rest) > 1 && rest[0] >= '1' && rest[0] <= '9' {
+		if i := bytes.IndexByte(rest, ' '); i > 0 && i <= 3 {
+			if version, err := strconv.Atoi(string(rest[:i])); err == nil {
+				msg.Version = version
+				return msg, parseRFC5424(msg, rest[i+1:])
+			}
+		}
+	}
+	parseRFC3164(msg, rest)
+	return msg, nil
+}
+
+// parsePriority parses the "<PRI>" part of a message, returning the priority
+// and the rest of the frame.
+func parsePriority(frame []byte) (int, []byte, error) {
+	if len(frame) == 0 || frame[0] != '<' {
+		return 0, nil, errMissingPriority
+	}
+	end := bytes.IndexByte(frame, '>')
+	if end < 2 || end > 4 {
+		return 0, nil, errInvalidPriority
+	}
+	pri, err := strconv.Atoi(string(frame[1:end]))
+	if err != nil || pri < 0 || pri > 191 {
+		return 0, nil, errInvalidPriority
+	}
+	return pri, frame[end+1:], nil
+}
+
+// parseRFC5424 parses the part of a RFC 5424 message following the version:
+// TIMESTAMP SP HOSTNAME SP APP-NAME SP PROCID SP MSGID SP STRUCTURED-DATA [SP MSG]
+func parseRFC5424(msg *syslogMessage, rest []byte) error {
+	var fields [5]string
+	for i := range fields {
+		end := bytes.IndexByte(rest, ' ')
+		if end < 0 {
+			return errInvalidHeader
+		}
+		if field := string(rest[:end]); field != nilValue {
+			fields[i] = field
+		}
+		rest = rest[end+1:]
+	}
+	msg.Timestamp, msg.Hostname, msg.AppName, msg.ProcID, msg.MsgID = fields[0], fields[1], fields[2], fields[3], fields[4]
+
+	sd, rest, err := parseStructuredData(rest)
+	if err != nil {
+		return err
+	}
+	msg.StructuredData = sd
+	if len(rest) > 0 && rest[0] == ' ' {
+		rest = rest[1:]
+	}
+	msg.Message = bytes.TrimPrefix(rest, utf8BOM)
+	return nil
+}
+
+// parseStructuredData parses the STRUCTURED-DATA part of a RFC 5424 message, returning
+// the elements keyed by SD-ID and the rest of the frame.
+func parseStructuredData(b []byte) (map[string]map[string]string, []byte, error) {
+	if len(b) == 0 {
+		return nil, b, errInvalidHeader
+	}
+	if b[0] == '-' {
+		return nil, b[1:], nil
+	}
+	sd := make(map[string]map[string]string)
+	for len(b) > 0 && b[0] == '[' {
+		b = b[1:]
+		end := bytes.IndexAny(b, " ]")
+		if end <= 0 {
+			return nil, nil, errInvalidHeader
+		}
+		id := string(b[:end])
+		params := make(map[string]string)
+		b = b[end:]
+		for len(b) > 0 && b[0] == ' ' {
+			b = b[1:]
+			eq := bytes.IndexByte(b, '=')
+			if eq <= 0 || len(b) < eq+2 || b[eq+1] != '"' {
+				return nil, nil, errInvalidHeader
+			}
+			name := string(b[:eq])
+			value, n, ok := parseParamValue(b[eq+2:])
+			if !ok {
+				return nil, nil, errInvalidHeader
+			}
+			params[name] = value
+			b = b[eq+2+n:]
+		}
+		if len(b) == 0 || b[0] != ']' {
+			return nil, nil, errInvalidHeader
+		}
+		b = b[1:]
+		sd[id] = params
+	}
+	return sd, b, nil
+}
+
+// parseParamValue parses a quoted PARAM-VALUE, starting right after the opening quote.
+// It returns the unescaped value and the number of bytes consumed, including the
+// closing quote.
+func parseParamValue(b []byte) (string, int, bool) {
+	var value []byte
+	for i := 0; i < len(b); i++ {
+		switch b[i] {
+		case '\\':
+			if i+1 < len(b) && (b[i+1] == '"' || b[i+1] == '\\' || b[i+1] == ']') {
+				i++
+			}
+			value = append(value, b[i])
+		case '"':
+			return string(value), i + 1, true
+		default:
+			value = append(value, b[i])
+		}
+	}
+	return "", 0, false
+}
+
+// rfc3164TimestampLayout is the layout of timestamps in RFC 3164 messages.
+const rfc3164TimestampLayout = time.Stamp
+
+// parseRFC3164 parses the part of a RFC 3164 message following the priority:
+// TIMESTAMP SP HOSTNAME SP TAG MSG
+// where TAG is usually formatted as "app[pid]:". Some senders use a RFC 3339 timestamp
+// instead, which is supported as well.
+func parseRFC3164(msg *syslogMessage, rest []byte) {
+	if len(rest) >= len(rfc3164TimestampLayout) {
+		if _, err := time.Parse(rfc3164TimestampLayout, string(rest[:len(rfc3164TimestampLayout)])); err == nil {
+			msg.Timestamp = string(rest[:len(rfc3164TimestampLayout)])
+			rest = bytes.TrimLeft(rest[len(rfc3164TimestampLayout):], " ")
+		}
+	}
+	if msg.Timestamp == "" {
+		if end := bytes.IndexByte(rest, ' '); end > 0 {
+			if _, err := time.Parse(time.RFC3339Nano, string(rest[:end])); err == nil {
+				msg.Timestamp = string(rest[:end])
+				rest = rest[end+1:]
+			}
+		}
+	}
+	if msg.Timestamp != "" {
+		// the hostname is only expected after a timestamp
+		if end := bytes.IndexByte(rest, ' '); end > 0 && !isTag(rest[:end]) {
+			msg.Hostname = string(rest[:end])
+			rest = rest[end+1:]
+		}
+	}
+	if end := bytes.IndexByte(rest, ' '); end > 0 && isTag(rest[:end]) {
+		tag := rest[:end-1]
+		if start := bytes.IndexByte(tag, '['); start > 0 && tag[len(tag)-1] == ']' {
+			msg.ProcID = string(tag[start+1 : len(tag)-1])
+			tag = tag[:start]
+		}
+		msg.AppName = string(tag)
+		rest = rest[end+1:]
+	}
+	msg.Message = rest
+}
+
+// isTag reports whether the given word looks like a RFC 3164 TAG, e.g. "sshd[123]:" or "cron:".
+func isTag(word []byte) bool {
+	return len(word) > 1 && word[len(word)-1] == ':'
+}
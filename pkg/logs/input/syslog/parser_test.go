@@ -0,0 +1,107 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package syslog
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+func TestParseRFC5424(t *testing.T) {
+	msg, err := parse([]byte(`<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog - ID47 [exampleSDID@32473 iut="3" eventSource="Application"][examplePriority@32473 class="high"] ` + "\xEF\xBB\xBF" + `An application event log entry...`))
+	require.NoError(t, err)
+	assert.Equal(t, 20, msg.Facility)
+	assert.Equal(t, 5, msg.Severity)
+	assert.Equal(t, message.StatusNotice, msg.status())
+	assert.Equal(t, 1, msg.Version)
+	assert.Equal(t, "2003-10-11T22:14:15.003Z", msg.Timestamp)
+	assert.Equal(t, "mymachine.example.com", msg.Hostname)
+	assert.Equal(t, "evntslog", msg.AppName)
+	assert.Equal(t, "", msg.ProcID)
+	assert.Equal(t, "ID47", msg.MsgID)
+	assert.Equal(t, map[string]map[string]string{
+		"exampleSDID@32473":     {"iut": "3", "eventSource": "Application"},
+		"examplePriority@32473": {"class": "high"},
+	}, msg.StructuredData)
+	assert.Equal(t, "An application event log entry...", string(msg.Message))
+}
+
+func TestParseRFC5424WithoutStructuredDataNorMessage(t *testing.T) {
+	msg, err := parse([]byte(`<34>1 2003-10-11T22:14:15.003Z - su 123 - -`))
+	require.NoError(t, err)
+	assert.Equal(t, message.StatusCritical, msg.status())
+	assert.Equal(t, "", msg.Hostname)
+	assert.Equal(t, "su", msg.AppName)
+	assert.Equal(t, "123", msg.ProcID)
+	assert.Nil(t, msg.StructuredData)
+	assert.Equal(t, "", string(msg.Message))
+}
+
+func TestParseRFC5424WithEscapedParamValues(t *testing.T) {
+	msg, err := parse([]byte(`<14>1 - host app - - [id a="x\"y" b="\]\\"] hello`))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"a": `x"y`, "b": `]\`}, msg.StructuredData["id"])
+	assert.Equal(t, "hello", string(msg.Message))
+}
+
+func TestParseRFC3164(t *testing.T) {
+	msg, err := parse([]byte(`<34>Oct 11 22:14:15 mymachine su[123]: 'su root' failed for lonvick on /dev/pts/8`))
+	require.NoError(t, err)
+	assert.Equal(t, 4, msg.Facility)
+	assert.Equal(t, 2, msg.Severity)
+	assert.Equal(t, 0, msg.Version)
+	assert.Equal(t, "Oct 11 22:14:15", msg.Timestamp)
+	assert.Equal(t, "mymachine", msg.Hostname)
+	assert.Equal(t, "su", msg.AppName)
+	assert.Equal(t, "123", msg.ProcID)
+	assert.Equal(t, "'su root' failed for lonvick on /dev/pts/8", string(msg.Message))
+}
+
+func TestParseRFC3164WithoutHostname(t *testing.T) {
+	msg, err := parse([]byte(`<13>Feb  5 17:32:18 cron: job done`))
+	require.NoError(t, err)
+	assert.Equal(t, "Feb  5 17:32:18", msg.Timestamp)
+	assert.Equal(t, "", msg.Hostname)
+	assert.Equal(t, "cron", msg.AppName)
+	assert.Equal(t, "job done", string(msg.Message))
+}
+
+func TestParseRFC3164WithRFC3339Timestamp(t *testing.T) {
+	msg, err := parse([]byte(`<13>2020-02-05T17:32:18.123+01:00 host app: hello`))
+	require.NoError(t, err)
+	assert.Equal(t, "2020-02-05T17:32:18.123+01:00", msg.Timestamp)
+	assert.Equal(t, "host", msg.Hostname)
+	assert.Equal(t, "app", msg.AppName)
+	assert.Equal(t, "hello", string(msg.Message))
+}
+
+func TestParseRFC3164WithoutHeader(t *testing.T) {
+	msg, err := parse([]byte(`<13>just a message`))
+	require.NoError(t, err)
+	assert.Equal(t, "", msg.Timestamp)
+	assert.Equal(t, "", msg.AppName)
+	assert.Equal(t, "just a message", string(msg.Message))
+}
+
+func TestParseShouldFailWithInvalidFrames(t *testing.T) {
+	for _, frame := range []string{
+		"",
+		"no priority",
+		"<>1 - - - - - -",
+		"<192>1 - - - - - -",
+		"<abc>1 - - - - - -",
+		"<14>1 - - -",
+		"<14>1 - - - - - [id a=b]",
+		`<14>1 - - - - - [id a="b]`,
+	} {
+		_, err := parse([]byte(frame))
+		assert.Error(t, err, frame)
+	}
+}
@@ -0,0 +1,122 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package syslog
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// Tailer reads syslog frames from a connection, parses them and forwards them to the pipeline.
+type Tailer struct {
+	source     *config.LogSource
+	conn       net.Conn
+	reader     frameReader
+	outputChan chan *message.Message
+	stop       chan struct{}
+	done       chan struct{}
+}
+
+// NewTailer returns a new Tailer
+func NewTailer(source *config.LogSource, conn net.Conn, reader frameReader, outputChan chan *message.Message) *Tailer {
+	return &Tailer{
+		source:     source,
+		conn:       conn,
+		reader:     reader,
+		outputChan: outputChan,
+		stop:       make(chan struct{}, 1),
+		done:       make(chan struct{}, 1),
+	}
+}
+
+// Start starts reading frames from the connection.
+func (t *Tailer) Start() {
+	go t.readForever()
+}
+
+// Stop stops the tailer and waits for the pending message to be forwarded.
+func (t *Tailer) Stop() {
+	t.stop <- struct{}{}
+	t.conn.Close()
+	<-t.done
+}
+
+// readForever reads frames from the connection until it is closed.
+func (t *Tailer) readForever() {
+	defer func() {
+		t.conn.Close()
+		t.done <- struct{}{}
+	}()
+	for {
+		frame, err := t.reader.next()
+		select {
+		case <-t.stop:
+			// stop reading data from the connection
+			return
+		default:
+		}
+		if err == io.EOF {
+			// connection has been closed client-side, stop from reading new data
+			return
+		}
+		if err != nil {
+			// an error occurred, stop from reading new data
+			log.Warnf("Couldn't read syslog message from connection: %v", err)
+			return
+		}
+		if len(frame) == 0 {
+			continue
+		}
+		t.source.BytesRead.Add(int64(len(frame)))
+		t.outputChan <- t.toMessage(frame)
+	}
+}
+
+// toMessage parses the frame and turns it into a message, if the frame can not be parsed
+// its raw content is forwarded as is.
+func (t *Tailer) toMessage(frame []byte) *message.Message {
+	frame = bytes.TrimRight(frame, "\r\n")
+	msg, err := parse(frame)
+	if err != nil {
+		log.Debugf("Couldn't parse syslog message: %v", err)
+		return message.NewMessageWithSource(frame, message.StatusInfo, t.source, time.Now().UnixNano())
+	}
+	return message.NewMessage(t.getContent(msg), t.getOrigin(msg), msg.status(), time.Now().UnixNano())
+}
+
+// getContent returns the content of the message, the syslog header is forwarded as attributes.
+func (t *Tailer) getContent(msg *syslogMessage) []byte {
+	payload := map[string]interface{}{
+		"message": string(msg.Message),
+		"syslog":  msg,
+	}
+	content, err := json.Marshal(payload)
+	if err != nil {
+		// ensure the message has some content if the json encoding failed
+		content = msg.Message
+	}
+	return content
+}
+
+// getOrigin returns the message origin computed from the syslog header.
+func (t *Tailer) getOrigin(msg *syslogMessage) *message.Origin {
+	origin := message.NewOrigin(t.source)
+	// set the service and the source attributes of the message,
+	// those values are still overridden by the integration config when defined
+	if msg.AppName != "" {
+		origin.SetSource(msg.AppName)
+		origin.SetService(msg.AppName)
+	}
+	return origin
+}
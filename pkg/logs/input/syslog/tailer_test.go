@@ -0,0 +1,63 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package syslog
+
+import (
+	"encoding/json"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+func TestTailerShouldForwardParsedMessages(t *testing.T) {
+	msgChan := make(chan *message.Message)
+	r, w := net.Pipe()
+	source := config.NewLogSource("", &config.LogsConfig{})
+	tailer := NewTailer(source, r, newStreamFrameReader(r, 256), msgChan)
+	tailer.Start()
+
+	go w.Write([]byte("<11>1 2003-10-11T22:14:15.003Z host app 42 - - hello\n")) //nolint:errcheck
+	msg := <-msgChan
+	assert.Equal(t, message.StatusError, msg.GetStatus())
+	assert.Equal(t, "app", msg.Origin.Source())
+	assert.Equal(t, "app", msg.Origin.Service())
+
+	var content map[string]interface{}
+	require.NoError(t, json.Unmarshal(msg.Content, &content))
+	assert.Equal(t, "hello", content["message"])
+	assert.Equal(t, map[string]interface{}{
+		"facility":  1.0,
+		"severity":  3.0,
+		"version":   1.0,
+		"timestamp": "2003-10-11T22:14:15.003Z",
+		"hostname":  "host",
+		"appname":   "app",
+		"procid":    "42",
+	}, content["syslog"])
+
+	tailer.Stop()
+}
+
+func TestTailerShouldForwardInvalidMessagesAsIs(t *testing.T) {
+	msgChan := make(chan *message.Message)
+	r, w := net.Pipe()
+	source := config.NewLogSource("", &config.LogsConfig{Service: "foo"})
+	tailer := NewTailer(source, r, newStreamFrameReader(r, 256), msgChan)
+	tailer.Start()
+
+	go w.Write([]byte("not a syslog message\n")) //nolint:errcheck
+	msg := <-msgChan
+	assert.Equal(t, message.StatusInfo, msg.GetStatus())
+	assert.Equal(t, "not a syslog message", string(msg.Content))
+	assert.Equal(t, "foo", msg.Origin.Service())
+
+	tailer.Stop()
+}
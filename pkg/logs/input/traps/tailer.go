@@ -19,15 +19,17 @@ import (
 type Tailer struct {
 	source     *config.LogSource
 	inputChan  traps.PacketsChannel
+	resolver   traps.OIDResolver
 	outputChan chan *message.Message
 	done       chan interface{}
 }
 
 // NewTailer returns a new Tailer
-func NewTailer(source *config.LogSource, inputChan traps.PacketsChannel, outputChan chan *message.Message) *Tailer {
+func NewTailer(source *config.LogSource, inputChan traps.PacketsChannel, resolver traps.OIDResolver, outputChan chan *message.Message) *Tailer {
 	return &Tailer{
 		source:     source,
 		inputChan:  inputChan,
+		resolver:   resolver,
 		outputChan: outputChan,
 		done:       make(chan interface{}, 1),
 	}
@@ -50,7 +52,7 @@ func (t *Tailer) run() {
 
 	// Loop terminates when the channel is closed.
 	for packet := range t.inputChan {
-		data, err := traps.FormatPacketToJSON(packet)
+		data, err := traps.FormatPacketToJSON(packet, t.resolver)
 		if err != nil {
 			log.Errorf("failed to format packet: %s", err)
 			continue
@@ -16,9 +16,13 @@ type Origin struct {
 	Identifier string
 	LogSource  *config.LogSource
 	Offset     string
-	service    string
-	source     string
-	tags       []string
+	// Fingerprint identifies the content of the file the message comes from, 0 when unknown
+	Fingerprint uint64
+	// Completed is set on the last message of a file which has been entirely read
+	Completed bool
+	service   string
+	source    string
+	tags      []string
 }
 
 // NewOrigin returns a new Origin
@@ -5,6 +5,8 @@
 
 package message
 
+import "strings"
+
 // Status values
 const (
 	StatusEmergency = "emergency"
@@ -41,6 +43,33 @@ var statusSeverityMapping = map[string][]byte{
 	StatusDebug:     SevDebug,
 }
 
+// statusAliases maps the common log levels to their status.
+var statusAliases = map[string]string{
+	"emerg":       StatusEmergency,
+	"emergency":   StatusEmergency,
+	"panic":       StatusEmergency,
+	"alert":       StatusAlert,
+	"crit":        StatusCritical,
+	"critical":    StatusCritical,
+	"fatal":       StatusCritical,
+	"err":         StatusError,
+	"error":       StatusError,
+	"severe":      StatusError,
+	"warn":        StatusWarning,
+	"warning":     StatusWarning,
+	"notice":      StatusNotice,
+	"info":        StatusInfo,
+	"information": StatusInfo,
+	"debug":       StatusDebug,
+	"trace":       StatusDebug,
+}
+
+// NormalizeStatus returns the status matching a log level, case insensitively,
+// or an empty string if the level is unknown.
+func NormalizeStatus(level string) string {
+	return statusAliases[strings.ToLower(strings.TrimSpace(level))]
+}
+
 // StatusToSeverity transforms a severity into a status.
 func StatusToSeverity(status string) []byte {
 	if sev, exists := statusSeverityMapping[status]; exists {
@@ -33,3 +33,12 @@ func TestStatusToSeverity(t *testing.T) {
 	// default value should be "info"
 	assert.Equal(t, 0, bytes.Compare(SevInfo, StatusToSeverity("foo")))
 }
+
+func TestNormalizeStatus(t *testing.T) {
+	assert.Equal(t, StatusWarning, NormalizeStatus("WARNING"))
+	assert.Equal(t, StatusWarning, NormalizeStatus("warn"))
+	assert.Equal(t, StatusError, NormalizeStatus("Error"))
+	assert.Equal(t, StatusCritical, NormalizeStatus("FATAL"))
+	assert.Equal(t, StatusDebug, NormalizeStatus(" trace "))
+	assert.Equal(t, "", NormalizeStatus("foo"))
+}
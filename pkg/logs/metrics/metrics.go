@@ -25,6 +25,17 @@ var (
 	TlmLogsProcessed = telemetry.NewCounter("logs", "processed",
 		nil, "Total number of processed logs")
 
+	// LogsDroppedBySampling is the total number of logs dropped by the sampling of the sources.
+	LogsDroppedBySampling = expvar.Int{}
+	// TlmLogsDroppedBySampling is the total number of logs dropped by the sampling of the sources.
+	TlmLogsDroppedBySampling = telemetry.NewCounter("logs", "dropped_by_sampling",
+		nil, "Total number of logs dropped by the sampling of the sources")
+	// LogsDroppedByRateLimit is the total number of logs dropped by the rate limits of the sources.
+	LogsDroppedByRateLimit = expvar.Int{}
+	// TlmLogsDroppedByRateLimit is the total number of logs dropped by the rate limits of the sources.
+	TlmLogsDroppedByRateLimit = telemetry.NewCounter("logs", "dropped_by_rate_limit",
+		nil, "Total number of logs dropped by the rate limits of the sources")
+
 	// LogsSent is the total number of sent logs.
 	LogsSent = expvar.Int{}
 	// TlmLogsSent is the total number of sent logs.
@@ -46,11 +57,32 @@ var (
 	TlmBytesSent = telemetry.NewCounter("logs", "bytes_sent",
 		nil, "Total number of bytes send before encoding if any")
 
+	// PayloadsSpooled is the total number of payloads written to the disk buffer
+	PayloadsSpooled = expvar.Int{}
+	// TlmPayloadsSpooled is the total number of payloads written to the disk buffer
+	TlmPayloadsSpooled = telemetry.NewCounter("logs", "payloads_spooled",
+		nil, "Total number of payloads written to the disk buffer")
+	// PayloadsReplayed is the total number of payloads of the disk buffer sent
+	PayloadsReplayed = expvar.Int{}
+	// TlmPayloadsReplayed is the total number of payloads of the disk buffer sent
+	TlmPayloadsReplayed = telemetry.NewCounter("logs", "payloads_replayed",
+		nil, "Total number of payloads of the disk buffer sent")
+
 	// EncodedBytesSent is the total number of sent bytes after encoding if any
 	EncodedBytesSent = expvar.Int{}
 	// TlmEncodedBytesSent is the total number of sent bytes after encoding if any
 	TlmEncodedBytesSent = telemetry.NewCounter("logs", "encoded_bytes_sent",
 		nil, "Total number of sent bytes after encoding if any")
+
+	// TlmEncodingTime is the total time in seconds spent encoding the payloads per compression kind
+	TlmEncodingTime = telemetry.NewCounter("logs", "encoding_time",
+		[]string{"compression_kind"}, "Total time in seconds spent encoding the payloads per compression kind")
+	// TlmBytesEncoded is the total number of bytes before encoding per compression kind
+	TlmBytesEncoded = telemetry.NewCounter("logs", "bytes_encoded",
+		[]string{"compression_kind"}, "Total number of bytes before encoding per compression kind")
+	// TlmEncodedBytes is the total number of bytes after encoding per compression kind
+	TlmEncodedBytes = telemetry.NewCounter("logs", "encoded_bytes",
+		[]string{"compression_kind"}, "Total number of bytes after encoding per compression kind")
 	// TODO: Add LogsCollected for the total number of collected logs.
 
 )
@@ -59,9 +91,13 @@ func init() {
 	LogsExpvars = expvar.NewMap("logs-agent")
 	LogsExpvars.Set("LogsDecoded", &LogsDecoded)
 	LogsExpvars.Set("LogsProcessed", &LogsProcessed)
+	LogsExpvars.Set("LogsDroppedBySampling", &LogsDroppedBySampling)
+	LogsExpvars.Set("LogsDroppedByRateLimit", &LogsDroppedByRateLimit)
 	LogsExpvars.Set("LogsSent", &LogsSent)
 	LogsExpvars.Set("DestinationErrors", &DestinationErrors)
 	LogsExpvars.Set("DestinationLogsDropped", &DestinationLogsDropped)
 	LogsExpvars.Set("BytesSent", &BytesSent)
 	LogsExpvars.Set("EncodedBytesSent", &EncodedBytesSent)
+	LogsExpvars.Set("PayloadsSpooled", &PayloadsSpooled)
+	LogsExpvars.Set("PayloadsReplayed", &PayloadsReplayed)
 }
@@ -6,6 +6,9 @@
 package pipeline
 
 import (
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
 	"github.com/DataDog/datadog-agent/pkg/logs/client"
 	"github.com/DataDog/datadog-agent/pkg/logs/client/http"
 	"github.com/DataDog/datadog-agent/pkg/logs/client/tcp"
@@ -24,20 +27,35 @@ type Pipeline struct {
 }
 
 // NewPipeline returns a new Pipeline
-func NewPipeline(outputChan chan *message.Message, processingRules []*config.ProcessingRule, endpoints *config.Endpoints, destinationsContext *client.DestinationsContext, diagnosticMessageReceiver diagnostic.MessageReceiver, serverless bool) *Pipeline {
+func NewPipeline(outputChan chan *message.Message, processingRules []*config.ProcessingRule, endpoints *config.Endpoints, destinationsContext *client.DestinationsContext, diagnosticMessageReceiver diagnostic.MessageReceiver, serverless bool, diskBuffer *sender.DiskBuffer, metricsSender aggregator.Sender) *Pipeline {
 	var destinations *client.Destinations
+	var routes []*sender.Route
 	if endpoints.UseHTTP {
 		main := http.NewDestination(endpoints.Main, http.JSONContentType, destinationsContext)
 		additionals := []client.Destination{}
 		for _, endpoint := range endpoints.Additionals {
-			additionals = append(additionals, http.NewDestination(endpoint, http.JSONContentType, destinationsContext))
+			destination := http.NewDestination(endpoint, http.JSONContentType, destinationsContext)
+			if endpoint.IsRouted() {
+				routes = append(routes, sender.NewRoute(endpoint, destination, newRouteBatchStrategy(endpoint, endpoints)))
+				continue
+			}
+			additionals = append(additionals, destination)
 		}
 		destinations = client.NewDestinations(main, additionals)
 	} else {
 		main := tcp.NewDestination(endpoints.Main, endpoints.UseProto, destinationsContext)
 		additionals := []client.Destination{}
 		for _, endpoint := range endpoints.Additionals {
-			additionals = append(additionals, tcp.NewDestination(endpoint, endpoints.UseProto, destinationsContext))
+			destination := tcp.NewDestination(endpoint, endpoints.UseProto, destinationsContext)
+			if endpoint.IsRouted() {
+				var strategy sender.Strategy = sender.StreamStrategy
+				if serverless {
+					strategy = newRouteBatchStrategy(endpoint, endpoints)
+				}
+				routes = append(routes, sender.NewRoute(endpoint, destination, strategy))
+				continue
+			}
+			additionals = append(additionals, destination)
 		}
 		destinations = client.NewDestinations(main, additionals)
 	}
@@ -50,7 +68,7 @@ func NewPipeline(outputChan chan *message.Message, processingRules []*config.Pro
 	} else {
 		strategy = sender.StreamStrategy
 	}
-	sender := sender.NewSender(senderChan, outputChan, destinations, strategy)
+	sender := sender.NewSender(senderChan, outputChan, destinations, strategy, diskBuffer, routes)
 
 	var encoder processor.Encoder
 	if serverless {
@@ -64,7 +82,7 @@ func NewPipeline(outputChan chan *message.Message, processingRules []*config.Pro
 	}
 
 	inputChan := make(chan *message.Message, config.ChanSize)
-	processor := processor.New(inputChan, senderChan, processingRules, encoder, diagnosticMessageReceiver)
+	processor := processor.New(inputChan, senderChan, processingRules, encoder, diagnosticMessageReceiver, metricsSender)
 
 	return &Pipeline{
 		InputChan: inputChan,
@@ -73,6 +91,16 @@ func NewPipeline(outputChan chan *message.Message, processingRules []*config.Pro
 	}
 }
 
+// newRouteBatchStrategy returns the batch strategy of a routed endpoint,
+// falling back on the batch wait of the endpoints.
+func newRouteBatchStrategy(endpoint config.Endpoint, endpoints *config.Endpoints) sender.Strategy {
+	batchWait := endpoints.BatchWait
+	if endpoint.BatchWait > 0 {
+		batchWait = time.Duration(endpoint.BatchWait) * time.Second
+	}
+	return sender.NewBatchStrategyWithSize(sender.ArraySerializer, batchWait, endpoint.BatchMaxSize)
+}
+
 // Start launches the pipeline
 func (p *Pipeline) Start() {
 	p.sender.Start()
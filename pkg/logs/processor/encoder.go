@@ -6,6 +6,8 @@
 package processor
 
 import (
+	"encoding/json"
+	"time"
 	"unicode"
 	"unicode/utf8"
 
@@ -33,3 +35,31 @@ func toValidUtf8(msg []byte) string {
 	}
 	return string(str)
 }
+
+// contentWithAttributes returns the content of a message with attributes as a
+// JSON object holding the message and the attributes, for the formats which can't
+// carry the attributes separately, the JSON messages being parsed at intake.
+func contentWithAttributes(msg *message.Message, redactedMsg []byte) []byte {
+	if len(msg.Attributes) == 0 {
+		return redactedMsg
+	}
+	fields := make(map[string]interface{}, len(msg.Attributes)+1)
+	for key, value := range msg.Attributes {
+		fields[key] = value
+	}
+	fields["message"] = toValidUtf8(redactedMsg)
+	content, err := json.Marshal(fields)
+	if err != nil {
+		return redactedMsg
+	}
+	return content
+}
+
+// messageTimestamp returns the timestamp of the message, or the current time
+// if it is not set.
+func messageTimestamp(msg *message.Message) time.Time {
+	if !msg.Timestamp.IsZero() {
+		return msg.Timestamp
+	}
+	return time.Now().UTC()
+}
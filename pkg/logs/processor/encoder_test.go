@@ -227,3 +227,32 @@ func TestEncoderToValidUTF8(t *testing.T) {
 	assert.Equal(t, "a���z", toValidUtf8([]byte("a\xed\xa0\x80z")))
 	assert.Equal(t, "a����z", toValidUtf8([]byte("a\xf0\x8f\xbf\xbfz")))
 }
+
+func TestEncodersWithAttributes(t *testing.T) {
+	source := config.NewLogSource("", &config.LogsConfig{Service: "Service", Source: "Source"})
+	msg := newMessage([]byte("message"), source, message.StatusInfo)
+	msg.Timestamp = time.Date(2021, 1, 31, 15, 4, 5, 0, time.UTC)
+	msg.Attributes = map[string]interface{}{"user": "bob", "service": "other"}
+
+	jsonMessage, err := JSONEncoder.Encode(msg, []byte("redacted"))
+	assert.Nil(t, err)
+	var fields map[string]interface{}
+	assert.Nil(t, json.Unmarshal(jsonMessage, &fields))
+	assert.Equal(t, "redacted", fields["message"])
+	assert.Equal(t, "bob", fields["user"])
+	// the reserved fields take precedence over the attributes
+	assert.Equal(t, "Service", fields["service"])
+	assert.Equal(t, float64(msg.Timestamp.UnixNano()/nanoToMillis), fields["timestamp"])
+
+	proto, err := ProtoEncoder.Encode(msg, []byte("redacted"))
+	assert.Nil(t, err)
+	log := &pb.Log{}
+	assert.Nil(t, log.Unmarshal(proto))
+	assert.Equal(t, msg.Timestamp.UnixNano(), log.Timestamp)
+	assert.JSONEq(t, `{"message":"redacted","user":"bob","service":"other"}`, log.Message)
+
+	raw, err := RawEncoder.Encode(msg, []byte("redacted"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(raw), "2021-01-31T15:04:05")
+	assert.Contains(t, string(raw), `{"message":"redacted","service":"other","user":"bob"}`)
+}
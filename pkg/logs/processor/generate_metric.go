@@ -0,0 +1,131 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package processor
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// metricsCommitInterval is the interval at which the metrics generated from the logs
+// are committed to the aggregator, in line with its flush interval.
+const metricsCommitInterval = 15 * time.Second
+
+// metricsSenderID is the ID of the sender of the metrics generated from the logs, it is
+// distinct from the default sender so that their commits don't interfere with the checks ones.
+const metricsSenderID check.ID = "logs_generate_metric"
+
+// NewMetricsSender returns the sender of the metrics generated from the logs, shared by all
+// the processors, or nil if the aggregator is not initialized.
+func NewMetricsSender() aggregator.Sender {
+	sender, err := aggregator.GetSender(metricsSenderID)
+	if err != nil {
+		log.Debugf("Can't generate metrics from the logs: %v", err)
+		return nil
+	}
+	return sender
+}
+
+// applyMetricRule generates the metric of a generate_metric rule when the message matches it,
+// that is when the content matches its pattern and the metric field has been extracted.
+// It returns true if a metric has been generated, never when there is no sender.
+func (p *Processor) applyMetricRule(rule *config.ProcessingRule, msg *message.Message, content []byte) bool {
+	if p.sender == nil {
+		return false
+	}
+	var captures map[string]interface{}
+	if rule.Regex != nil {
+		if captures = parseGrokAttributes(content, rule); captures == nil {
+			return false
+		}
+	}
+	// the named captures of the pattern take precedence over the attributes of the message
+	getField := func(path string) (interface{}, bool) {
+		if parent, key, found := lookupAttribute(captures, path); found {
+			return parent[key], true
+		}
+		if parent, key, found := lookupAttribute(msg.Attributes, path); found {
+			return parent[key], true
+		}
+		return nil, false
+	}
+
+	value := 1.0
+	if rule.MetricField != "" {
+		field, found := getField(rule.MetricField)
+		if !found {
+			return false
+		}
+		if rule.MetricType == config.DistributionMetric {
+			var ok bool
+			if value, ok = toFloat(field); !ok {
+				log.Debugf("Can't generate metric %s, the value of %s is not a number: %v", rule.MetricName, rule.MetricField, field)
+				return false
+			}
+		}
+	}
+
+	tags := append([]string{}, msg.Origin.Tags()...)
+	if service := msg.Origin.Service(); service != "" {
+		tags = append(tags, "service:"+service)
+	}
+	if source := msg.Origin.Source(); source != "" {
+		tags = append(tags, "source:"+source)
+	}
+	for _, tagField := range rule.MetricTagFields {
+		if field, found := getField(tagField); found {
+			tags = append(tags, tagField+":"+fieldToString(field))
+		}
+	}
+
+	switch rule.MetricType {
+	case config.DistributionMetric:
+		p.sender.Distribution(rule.MetricName, value, "", tags)
+	default:
+		p.sender.Count(rule.MetricName, value, "", tags)
+	}
+	p.hasPendingMetrics = true
+	return true
+}
+
+// commitMetrics commits the metrics generated since the last commit.
+func (p *Processor) commitMetrics() {
+	if !p.hasPendingMetrics {
+		return
+	}
+	p.sender.Commit()
+	p.hasPendingMetrics = false
+}
+
+// toFloat returns the numeric value of a field.
+func toFloat(field interface{}) (float64, bool) {
+	switch v := field.(type) {
+	case float64:
+		return v, true
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// fieldToString returns the value of a field to use in a tag.
+func fieldToString(field interface{}) string {
+	switch v := field.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return fmt.Sprint(field)
+}
@@ -7,7 +7,6 @@ package processor
 
 import (
 	"encoding/json"
-	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
 )
@@ -33,17 +32,31 @@ type jsonPayload struct {
 
 // Encode encodes a message into a JSON byte array.
 func (j *jsonEncoder) Encode(msg *message.Message, redactedMsg []byte) ([]byte, error) {
-	ts := time.Now().UTC()
-	if !msg.Timestamp.IsZero() {
-		ts = msg.Timestamp
-	}
-	return json.Marshal(jsonPayload{
+	payload := jsonPayload{
 		Message:   toValidUtf8(redactedMsg),
 		Status:    msg.GetStatus(),
-		Timestamp: ts.UnixNano() / nanoToMillis,
+		Timestamp: messageTimestamp(msg).UnixNano() / nanoToMillis,
 		Hostname:  getHostname(),
 		Service:   msg.Origin.Service(),
 		Source:    msg.Origin.Source(),
 		Tags:      msg.Origin.TagsToString(),
-	})
+	}
+	if len(msg.Attributes) == 0 {
+		return json.Marshal(payload)
+	}
+
+	// the attributes are sent as top-level fields,
+	// the fields of the payload taking precedence
+	fields := make(map[string]interface{}, len(msg.Attributes)+7)
+	for key, value := range msg.Attributes {
+		fields[key] = value
+	}
+	fields["message"] = payload.Message
+	fields["status"] = payload.Status
+	fields["timestamp"] = payload.Timestamp
+	fields["hostname"] = payload.Hostname
+	fields["service"] = payload.Service
+	fields["ddsource"] = payload.Source
+	fields["ddtags"] = payload.Tags
+	return json.Marshal(fields)
 }
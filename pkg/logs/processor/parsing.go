@@ -0,0 +1,378 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package processor
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// messageAttribute is the attribute which, when extracted, becomes the content of the log.
+const messageAttribute = "message"
+
+// defaultKVSeparator separates the keys from the values when not set in the parse_kv rules.
+const defaultKVSeparator = "="
+
+// timestampLayouts are the layouts tried to parse the timestamps promoted from the attributes.
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999",
+}
+
+// applyParsingRule extracts the attributes of the content with a parsing rule and
+// adds them to the message, it returns the content to send. The content is replaced
+// by the message attribute if one was extracted and is emptied when the whole
+// content has been parsed as JSON, so that removed attributes don't leave the host.
+// Otherwise the excluded and masked fields are redacted from the content as well.
+func applyParsingRule(rule *config.ProcessingRule, msg *message.Message, content []byte) []byte {
+	var attributes map[string]interface{}
+	switch rule.Type {
+	case config.JSONParser:
+		attributes = parseJSONAttributes(content)
+	case config.KVParser:
+		attributes = parseKVAttributes(content, rule.KVSeparator)
+	case config.GrokParser:
+		attributes = parseGrokAttributes(content, rule)
+	}
+	if len(attributes) == 0 {
+		return content
+	}
+
+	promoteAttributes(rule, msg, attributes)
+	for _, field := range rule.ExcludeFields {
+		if parent, key, found := lookupAttribute(attributes, field); found {
+			delete(parent, key)
+		}
+	}
+	for _, field := range rule.MaskFields {
+		if parent, key, found := lookupAttribute(attributes, field); found {
+			parent[key] = rule.ReplacePlaceholder
+		}
+	}
+
+	if msg.Attributes == nil {
+		msg.Attributes = make(map[string]interface{}, len(attributes))
+	}
+	for key, value := range attributes {
+		msg.Attributes[key] = value
+	}
+
+	if value, ok := msg.Attributes[messageAttribute].(string); ok {
+		delete(msg.Attributes, messageAttribute)
+		return []byte(value)
+	}
+	if rule.Type == config.JSONParser {
+		return []byte{}
+	}
+	return redactFields(rule, content)
+}
+
+// contentEdit replaces the bytes of the content between start and end.
+type contentEdit struct {
+	start, end  int
+	replacement string
+}
+
+// redactFields removes the excluded fields of the rule from a KV or grok parsed content,
+// and replaces the values of the masked fields by the placeholder of the rule.
+func redactFields(rule *config.ProcessingRule, content []byte) []byte {
+	if len(rule.ExcludeFields) == 0 && len(rule.MaskFields) == 0 {
+		return content
+	}
+	var edits []contentEdit
+	switch rule.Type {
+	case config.KVParser:
+		edits = kvFieldEdits(rule, content)
+	case config.GrokParser:
+		edits = grokFieldEdits(rule, content)
+	}
+	return applyContentEdits(content, edits)
+}
+
+// kvFieldEdits returns the edits redacting every occurrence of the excluded and masked keys.
+func kvFieldEdits(rule *config.ProcessingRule, content []byte) []contentEdit {
+	separator := rule.KVSeparator
+	if separator == "" {
+		separator = defaultKVSeparator
+	}
+	var edits []contentEdit
+	for _, span := range splitQuotedFields(string(content)) {
+		field := string(content[span[0]:span[1]])
+		i := strings.Index(field, separator)
+		if i <= 0 {
+			continue
+		}
+		key, valueStart := field[:i], span[0]+i+len(separator)
+		switch {
+		case containsString(rule.ExcludeFields, key):
+			start, end := span[0], span[1]
+			for end < len(content) && isFieldSpace(content[end]) {
+				end++
+			}
+			if end == len(content) {
+				// last field, remove the whitespaces preceding it instead
+				end = span[1]
+				for start > 0 && isFieldSpace(content[start-1]) {
+					start--
+				}
+			}
+			edits = append(edits, contentEdit{start: start, end: end})
+		case containsString(rule.MaskFields, key):
+			replacement := rule.ReplacePlaceholder
+			if valueStart < span[1] && content[valueStart] == '"' {
+				replacement = strconv.Quote(replacement)
+			}
+			edits = append(edits, contentEdit{start: valueStart, end: span[1], replacement: replacement})
+		}
+	}
+	return edits
+}
+
+// grokFieldEdits returns the edits redacting the captures of the excluded and masked fields.
+func grokFieldEdits(rule *config.ProcessingRule, content []byte) []contentEdit {
+	matches := rule.Regex.FindSubmatchIndex(content)
+	if matches == nil {
+		return nil
+	}
+	var edits []contentEdit
+	for i, name := range rule.Regex.SubexpNames() {
+		if name == "" || matches[2*i] < 0 {
+			continue
+		}
+		switch {
+		case containsString(rule.ExcludeFields, name):
+			edits = append(edits, contentEdit{start: matches[2*i], end: matches[2*i+1]})
+		case containsString(rule.MaskFields, name):
+			edits = append(edits, contentEdit{start: matches[2*i], end: matches[2*i+1], replacement: rule.ReplacePlaceholder})
+		}
+	}
+	return edits
+}
+
+// applyContentEdits returns a copy of the content with the edits applied. When edits
+// overlap, the one starting first, or the longest, is applied as it covers the others.
+func applyContentEdits(content []byte, edits []contentEdit) []byte {
+	if len(edits) == 0 {
+		return content
+	}
+	sort.Slice(edits, func(i, j int) bool {
+		if edits[i].start != edits[j].start {
+			return edits[i].start < edits[j].start
+		}
+		return edits[i].end > edits[j].end
+	})
+	redacted := make([]byte, 0, len(content))
+	last := 0
+	for _, e := range edits {
+		if e.start < last {
+			continue
+		}
+		redacted = append(redacted, content[last:e.start]...)
+		redacted = append(redacted, e.replacement...)
+		last = e.end
+	}
+	return append(redacted, content[last:]...)
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// parseJSONAttributes returns the attributes of a JSON object, nil if the content is not one.
+func parseJSONAttributes(content []byte) map[string]interface{} {
+	content = bytes.TrimSpace(content)
+	if len(content) == 0 || content[0] != '{' {
+		return nil
+	}
+	var attributes map[string]interface{}
+	if err := json.Unmarshal(content, &attributes); err != nil {
+		return nil
+	}
+	return attributes
+}
+
+// parseKVAttributes returns the key/value pairs separated by whitespaces found in
+// the content, the values can be double-quoted to contain whitespaces.
+func parseKVAttributes(content []byte, separator string) map[string]interface{} {
+	if separator == "" {
+		separator = defaultKVSeparator
+	}
+	attributes := make(map[string]interface{})
+	for _, span := range splitQuotedFields(string(content)) {
+		field := string(content[span[0]:span[1]])
+		i := strings.Index(field, separator)
+		if i <= 0 {
+			continue
+		}
+		key, value := field[:i], field[i+len(separator):]
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		}
+		attributes[key] = value
+	}
+	return attributes
+}
+
+// splitQuotedFields splits a string around whitespaces which are not in a
+// double-quoted section, it returns the start and end offsets of the fields.
+func splitQuotedFields(s string) [][2]int {
+	var fields [][2]int
+	inQuotes, escaped := false, false
+	start := -1
+	for i, r := range s {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\' && inQuotes:
+			escaped = true
+		case r == '"':
+			inQuotes = !inQuotes
+		case (r == ' ' || r == '\t') && !inQuotes:
+			if start >= 0 {
+				fields = append(fields, [2]int{start, i})
+				start = -1
+			}
+			continue
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		fields = append(fields, [2]int{start, len(s)})
+	}
+	return fields
+}
+
+func isFieldSpace(c byte) bool {
+	return c == ' ' || c == '\t'
+}
+
+// parseGrokAttributes returns the named captures of the rule matching the content.
+func parseGrokAttributes(content []byte, rule *config.ProcessingRule) map[string]interface{} {
+	matches := rule.Regex.FindSubmatchIndex(content)
+	if matches == nil {
+		return nil
+	}
+	attributes := make(map[string]interface{})
+	for i, name := range rule.Regex.SubexpNames() {
+		if name == "" || matches[2*i] < 0 {
+			continue
+		}
+		attributes[name] = string(content[matches[2*i]:matches[2*i+1]])
+	}
+	return attributes
+}
+
+// promoteAttributes sets the status, service and timestamp of the message from
+// the attributes configured in the rule, values that can't be used are ignored.
+func promoteAttributes(rule *config.ProcessingRule, msg *message.Message, attributes map[string]interface{}) {
+	if value, ok := getStringAttribute(attributes, rule.StatusField); ok {
+		if status := message.NormalizeStatus(value); status != "" {
+			msg.SetStatus(status)
+		}
+	}
+	if value, ok := getStringAttribute(attributes, rule.ServiceField); ok && value != "" {
+		msg.Origin.SetService(value)
+	}
+	if rule.TimestampField != "" {
+		if parent, key, found := lookupAttribute(attributes, rule.TimestampField); found {
+			if ts, ok := parseTimestamp(parent[key]); ok {
+				msg.Timestamp = ts
+			}
+		}
+	}
+}
+
+// lookupAttribute returns the map holding the attribute designated by a path,
+// whose components are separated by dots for the nested attributes, and its key.
+func lookupAttribute(attributes map[string]interface{}, path string) (map[string]interface{}, string, bool) {
+	if path == "" {
+		return nil, "", false
+	}
+	if _, found := attributes[path]; found {
+		return attributes, path, true
+	}
+	parts := strings.SplitN(path, ".", 2)
+	if len(parts) == 2 {
+		if nested, ok := attributes[parts[0]].(map[string]interface{}); ok {
+			return lookupAttribute(nested, parts[1])
+		}
+	}
+	return nil, "", false
+}
+
+func getStringAttribute(attributes map[string]interface{}, path string) (string, bool) {
+	parent, key, found := lookupAttribute(attributes, path)
+	if !found {
+		return "", false
+	}
+	value, ok := parent[key].(string)
+	return value, ok
+}
+
+// parseTimestamp parses a date or a unix timestamp, in seconds or milliseconds.
+func parseTimestamp(value interface{}) (time.Time, bool) {
+	switch v := value.(type) {
+	case float64:
+		return unixToTime(v), true
+	case string:
+		v = strings.Replace(strings.TrimSpace(v), ",", ".", 1)
+		for _, layout := range timestampLayouts {
+			if ts, err := time.Parse(layout, v); err == nil {
+				return ts.UTC(), true
+			}
+		}
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return unixToTime(f), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// unixToTime converts a unix timestamp to a time, timestamps too large to be
+// in seconds being considered in milliseconds.
+func unixToTime(ts float64) time.Time {
+	if ts > 1e11 {
+		return time.Unix(0, int64(ts*float64(time.Millisecond))).UTC()
+	}
+	return time.Unix(0, int64(ts*float64(time.Second))).UTC()
+}
+
+// maskAttributes replaces the sequences matching the rule in all the string attributes.
+func maskAttributes(attributes map[string]interface{}, rule *config.ProcessingRule) {
+	for key, value := range attributes {
+		attributes[key] = maskAttribute(value, rule)
+	}
+}
+
+func maskAttribute(value interface{}, rule *config.ProcessingRule) interface{} {
+	switch v := value.(type) {
+	case string:
+		return rule.Regex.ReplaceAllString(v, string(rule.Placeholder))
+	case map[string]interface{}:
+		maskAttributes(v, rule)
+	case []interface{}:
+		for i := range v {
+			v[i] = maskAttribute(v[i], rule)
+		}
+	}
+	return value
+}
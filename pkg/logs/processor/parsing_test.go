@@ -0,0 +1,63 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package processor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseKVAttributes(t *testing.T) {
+	assert.Equal(t, map[string]interface{}{"a": "1", "b": "two words", "c": `say "hi"`}, parseKVAttributes([]byte(`a=1 b="two words"  c="say \"hi\"" ignored =empty`), ""))
+	assert.Equal(t, map[string]interface{}{"a": "1", "b": "2"}, parseKVAttributes([]byte("a:1\tb:2"), ":"))
+	assert.Empty(t, parseKVAttributes([]byte("no pairs here"), ""))
+}
+
+func TestLookupAttribute(t *testing.T) {
+	attributes := map[string]interface{}{
+		"a":   map[string]interface{}{"b": "nested"},
+		"a.c": "dotted",
+	}
+
+	value, ok := getStringAttribute(attributes, "a.b")
+	assert.True(t, ok)
+	assert.Equal(t, "nested", value)
+
+	value, ok = getStringAttribute(attributes, "a.c")
+	assert.True(t, ok)
+	assert.Equal(t, "dotted", value)
+
+	_, ok = getStringAttribute(attributes, "a.d")
+	assert.False(t, ok)
+	_, ok = getStringAttribute(attributes, "a")
+	assert.False(t, ok)
+	_, ok = getStringAttribute(attributes, "")
+	assert.False(t, ok)
+}
+
+func TestParseTimestamp(t *testing.T) {
+	expected := time.Date(2021, 1, 31, 15, 4, 5, 0, time.UTC)
+	for _, value := range []interface{}{
+		"2021-01-31T15:04:05Z",
+		"2021-01-31T16:04:05+01:00",
+		"2021-01-31 15:04:05",
+		"2021-01-31 15:04:05,000",
+		"1612105445",
+		float64(1612105445),
+		float64(1612105445000),
+	} {
+		ts, ok := parseTimestamp(value)
+		assert.True(t, ok, value)
+		assert.True(t, expected.Equal(ts), value)
+	}
+
+	_, ok := parseTimestamp("yesterday")
+	assert.False(t, ok)
+	_, ok = parseTimestamp(true)
+	assert.False(t, ok)
+}
@@ -7,7 +7,9 @@ package processor
 
 import (
 	"sync"
+	"time"
 
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 
 	"github.com/DataDog/datadog-agent/pkg/logs/config"
@@ -26,10 +28,13 @@ type Processor struct {
 	done                      chan struct{}
 	diagnosticMessageReceiver diagnostic.MessageReceiver
 	mu                        sync.Mutex
+	// sender of the metrics generated from the logs, nil when they can't be sent
+	sender            aggregator.Sender
+	hasPendingMetrics bool
 }
 
 // New returns an initialized Processor.
-func New(inputChan, outputChan chan *message.Message, processingRules []*config.ProcessingRule, encoder Encoder, diagnosticMessageReceiver diagnostic.MessageReceiver) *Processor {
+func New(inputChan, outputChan chan *message.Message, processingRules []*config.ProcessingRule, encoder Encoder, diagnosticMessageReceiver diagnostic.MessageReceiver, metricsSender aggregator.Sender) *Processor {
 	return &Processor{
 		inputChan:                 inputChan,
 		outputChan:                outputChan,
@@ -37,6 +42,7 @@ func New(inputChan, outputChan chan *message.Message, processingRules []*config.
 		encoder:                   encoder,
 		done:                      make(chan struct{}),
 		diagnosticMessageReceiver: diagnosticMessageReceiver,
+		sender:                    metricsSender,
 	}
 }
 
@@ -66,25 +72,44 @@ func (p *Processor) Flush() {
 }
 
 // run starts the processing of the inputChan
+// and regularly commits the metrics generated from the logs.
 func (p *Processor) run() {
+	commitTicker := time.NewTicker(metricsCommitInterval)
 	defer func() {
+		commitTicker.Stop()
+		p.commitMetrics()
 		p.done <- struct{}{}
 	}()
-	for msg := range p.inputChan {
-		p.processMessage(msg)
-		p.mu.Lock() // block here if we're trying to flush synchronously
-		p.mu.Unlock()
+	for {
+		select {
+		case msg, isOpen := <-p.inputChan:
+			if !isOpen {
+				return
+			}
+			p.processMessage(msg)
+			p.mu.Lock() // block here if we're trying to flush synchronously
+			p.mu.Unlock()
+		case <-commitTicker.C:
+			p.mu.Lock()
+			p.commitMetrics()
+			p.mu.Unlock()
+		}
 	}
 }
 
 func (p *Processor) processMessage(msg *message.Message) {
 	metrics.LogsDecoded.Add(1)
 	metrics.TlmLogsDecoded.Inc()
-	if shouldProcess, redactedMsg := p.applyRedactingRules(msg); shouldProcess {
+	if p.diagnosticMessageReceiver.IsStageEnabled(diagnostic.StageTailer) {
+		p.diagnosticMessageReceiver.HandleMessage(*msg, msg.Content, diagnostic.StageTailer)
+	}
+	if shouldProcess, redactedMsg := p.applyRedactingRules(msg); shouldProcess && p.applyThrottling(msg, redactedMsg) {
 		metrics.LogsProcessed.Add(1)
 		metrics.TlmLogsProcessed.Inc()
 
-		p.diagnosticMessageReceiver.HandleMessage(*msg)
+		if p.diagnosticMessageReceiver.IsStageEnabled(diagnostic.StageProcessed) {
+			p.diagnosticMessageReceiver.HandleMessage(*msg, redactedMsg, diagnostic.StageProcessed)
+		}
 
 		// Encode the message to its final format
 		content, err := p.encoder.Encode(msg, redactedMsg)
@@ -93,12 +118,39 @@ func (p *Processor) processMessage(msg *message.Message) {
 			return
 		}
 		msg.Content = content
+		if p.diagnosticMessageReceiver.IsStageEnabled(diagnostic.StageEncoded) {
+			p.diagnosticMessageReceiver.HandleMessage(*msg, content, diagnostic.StageEncoded)
+		}
 		p.outputChan <- msg
 	}
 }
 
+// applyThrottling returns true if the message is kept by the sampling
+// and the rate limits of its source, the dropped messages being counted.
+func (p *Processor) applyThrottling(msg *message.Message, redactedMsg []byte) bool {
+	source := msg.Origin.LogSource
+	if source.Throttler == nil {
+		return true
+	}
+	switch source.Throttler.Throttle(redactedMsg) {
+	case config.DroppedBySampling:
+		source.LogsDroppedBySampling.Add(1)
+		metrics.LogsDroppedBySampling.Add(1)
+		metrics.TlmLogsDroppedBySampling.Inc()
+		return false
+	case config.DroppedByRateLimit:
+		source.LogsDroppedByRateLimit.Add(1)
+		metrics.LogsDroppedByRateLimit.Add(1)
+		metrics.TlmLogsDroppedByRateLimit.Inc()
+		return false
+	}
+	return true
+}
+
 // applyRedactingRules returns given a message if we should process it or not,
-// and a copy of the message with some fields redacted, depending on config
+// and a copy of the message with some fields redacted, depending on config.
+// The parsing rules add the attributes they extract to the message and the
+// generate_metric rules send their metrics to the aggregator.
 func (p *Processor) applyRedactingRules(msg *message.Message) (bool, []byte) {
 	content := msg.Content
 	rules := append(p.processingRules, msg.Origin.LogSource.Config.ProcessingRules...)
@@ -114,6 +166,15 @@ func (p *Processor) applyRedactingRules(msg *message.Message) (bool, []byte) {
 			}
 		case config.MaskSequences:
 			content = rule.Regex.ReplaceAll(content, rule.Placeholder)
+			if msg.Attributes != nil {
+				maskAttributes(msg.Attributes, rule)
+			}
+		case config.JSONParser, config.KVParser, config.GrokParser:
+			content = applyParsingRule(rule, msg, content)
+		case config.GenerateMetric:
+			if p.applyMetricRule(rule, msg, content) && rule.DropLine {
+				return false, nil
+			}
 		}
 	}
 	return true, content
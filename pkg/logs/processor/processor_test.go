@@ -8,8 +8,11 @@ package processor
 import (
 	"regexp"
 	"testing"
+	"time"
 
+	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
 	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/diagnostic"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
 	"github.com/stretchr/testify/assert"
 )
@@ -156,3 +159,202 @@ func newSource(ruleType, replacePlaceholder, pattern string) config.LogSource {
 func newMessage(content []byte, source *config.LogSource, status string) *message.Message {
 	return message.NewMessageWithSource(content, status, source, 0)
 }
+
+func TestParseJSON(t *testing.T) {
+	p := &Processor{}
+
+	rule := &config.ProcessingRule{Type: config.JSONParser, Name: "test", StatusField: "level", ServiceField: "app", TimestampField: "time", ExcludeFields: []string{"debug"}}
+	source := config.LogSource{Config: &config.LogsConfig{ProcessingRules: []*config.ProcessingRule{rule}}}
+
+	msg := newMessage([]byte(`{"message":"hello","level":"warning","app":"web","time":"2021-01-31T15:04:05.123Z","debug":true,"http":{"status":200}}`), &source, "")
+	shouldProcess, redactedMessage := p.applyRedactingRules(msg)
+	assert.Equal(t, true, shouldProcess)
+	assert.Equal(t, []byte("hello"), redactedMessage)
+	assert.Equal(t, message.StatusWarning, msg.GetStatus())
+	assert.Equal(t, "web", msg.Origin.Service())
+	assert.Equal(t, time.Date(2021, 1, 31, 15, 4, 5, 123000000, time.UTC), msg.Timestamp)
+	assert.Equal(t, map[string]interface{}{"level": "warning", "app": "web", "time": "2021-01-31T15:04:05.123Z", "http": map[string]interface{}{"status": float64(200)}}, msg.Attributes)
+
+	// the content is left untouched when it is not a JSON object
+	msg = newMessage([]byte("hello world"), &source, "")
+	shouldProcess, redactedMessage = p.applyRedactingRules(msg)
+	assert.Equal(t, true, shouldProcess)
+	assert.Equal(t, []byte("hello world"), redactedMessage)
+	assert.Nil(t, msg.Attributes)
+}
+
+func TestParseKV(t *testing.T) {
+	p := &Processor{}
+
+	rule := &config.ProcessingRule{Type: config.KVParser, Name: "test", StatusField: "lvl", ExcludeFields: []string{"token"}, MaskFields: []string{"password"}, ReplacePlaceholder: "[masked]"}
+	source := config.LogSource{Config: &config.LogsConfig{ProcessingRules: []*config.ProcessingRule{rule}}}
+
+	msg := newMessage([]byte(`lvl=error user=bob password=secret token=abc123 msg="login failed"`), &source, "")
+	shouldProcess, redactedMessage := p.applyRedactingRules(msg)
+	assert.Equal(t, true, shouldProcess)
+	// the excluded and masked fields don't leave the host in the content
+	assert.Equal(t, []byte(`lvl=error user=bob password=[masked] msg="login failed"`), redactedMessage)
+	assert.NotContains(t, string(redactedMessage), "secret")
+	assert.NotContains(t, string(redactedMessage), "abc123")
+	assert.Equal(t, message.StatusError, msg.GetStatus())
+	assert.Equal(t, map[string]interface{}{"lvl": "error", "user": "bob", "password": "[masked]", "msg": "login failed"}, msg.Attributes)
+
+	msg = newMessage([]byte(`password="my secret" user=bob token=abc123`), &source, "")
+	_, redactedMessage = p.applyRedactingRules(msg)
+	assert.Equal(t, []byte(`password="[masked]" user=bob`), redactedMessage)
+}
+
+func TestParseGrok(t *testing.T) {
+	p := &Processor{}
+
+	rule := &config.ProcessingRule{Type: config.GrokParser, Name: "test", Pattern: `%{IP:client} %{WORD:method} %{URIPATHPARAM:path} %{GREEDYDATA:message}`}
+	assert.Nil(t, config.ValidateProcessingRules([]*config.ProcessingRule{rule}))
+	assert.Nil(t, config.CompileProcessingRules([]*config.ProcessingRule{rule}))
+	source := config.LogSource{Config: &config.LogsConfig{ProcessingRules: []*config.ProcessingRule{rule, newProcessingRule(config.MaskSequences, "[ip]", `\d+\.\d+\.\d+\.\d+`)}}}
+
+	msg := newMessage([]byte("10.0.0.1 GET /index.html?q=1 took 12ms"), &source, "")
+	shouldProcess, redactedMessage := p.applyRedactingRules(msg)
+	assert.Equal(t, true, shouldProcess)
+	assert.Equal(t, []byte("took 12ms"), redactedMessage)
+	// the masking rules apply to the attributes as well
+	assert.Equal(t, map[string]interface{}{"client": "[ip]", "method": "GET", "path": "/index.html?q=1"}, msg.Attributes)
+
+	msg = newMessage([]byte("not an access log"), &source, "")
+	shouldProcess, redactedMessage = p.applyRedactingRules(msg)
+	assert.Equal(t, true, shouldProcess)
+	assert.Equal(t, []byte("not an access log"), redactedMessage)
+	assert.Nil(t, msg.Attributes)
+}
+
+func TestParseGrokRedactsContent(t *testing.T) {
+	p := &Processor{}
+
+	rule := &config.ProcessingRule{Type: config.GrokParser, Name: "test", Pattern: `%{WORD:user} %{WORD:password} %{IP:client} %{GREEDYDATA:msg}`, ExcludeFields: []string{"client"}, MaskFields: []string{"password"}, ReplacePlaceholder: "[masked]"}
+	assert.Nil(t, config.ValidateProcessingRules([]*config.ProcessingRule{rule}))
+	assert.Nil(t, config.CompileProcessingRules([]*config.ProcessingRule{rule}))
+	source := config.LogSource{Config: &config.LogsConfig{ProcessingRules: []*config.ProcessingRule{rule}}}
+
+	msg := newMessage([]byte("bob secret 10.0.0.1 logged in"), &source, "")
+	shouldProcess, redactedMessage := p.applyRedactingRules(msg)
+	assert.Equal(t, true, shouldProcess)
+	assert.Equal(t, []byte("bob [masked]  logged in"), redactedMessage)
+	assert.Equal(t, map[string]interface{}{"user": "bob", "password": "[masked]", "msg": "logged in"}, msg.Attributes)
+}
+
+func TestGenerateMetric(t *testing.T) {
+	sender := mocksender.NewMockSender("")
+	sender.SetupAcceptAll()
+	p := &Processor{sender: sender}
+
+	countRule := &config.ProcessingRule{Type: config.GenerateMetric, Name: "errors", MetricName: "app.errors", Pattern: "ERROR"}
+	kvRule := &config.ProcessingRule{Type: config.KVParser, Name: "kv"}
+	statusRule := &config.ProcessingRule{Type: config.GenerateMetric, Name: "status", MetricName: "app.requests", MetricField: "status", MetricTagFields: []string{"status"}}
+	durationRule := &config.ProcessingRule{Type: config.GenerateMetric, Name: "duration", MetricName: "app.duration", MetricType: config.DistributionMetric, MetricField: "duration", Pattern: `took (?P<duration>\d+)ms`, DropLine: true}
+	rules := []*config.ProcessingRule{countRule, kvRule, statusRule, durationRule}
+	assert.Nil(t, config.CompileProcessingRules(rules))
+	source := config.LogSource{Config: &config.LogsConfig{Service: "web", Source: "nginx", Tags: []string{"env:prod"}, ProcessingRules: rules}}
+
+	shouldProcess, redactedMessage := p.applyRedactingRules(newMessage([]byte("ERROR something failed"), &source, ""))
+	assert.Equal(t, true, shouldProcess)
+	assert.Equal(t, []byte("ERROR something failed"), redactedMessage)
+	sender.AssertMetric(t, "Count", "app.errors", 1, "", []string{"env:prod", "service:web", "source:nginx"})
+	sender.AssertNumberOfCalls(t, "Count", 1)
+
+	shouldProcess, _ = p.applyRedactingRules(newMessage([]byte("status=404 path=/"), &source, ""))
+	assert.Equal(t, true, shouldProcess)
+	sender.AssertMetric(t, "Count", "app.requests", 1, "", []string{"env:prod", "service:web", "source:nginx", "status:404"})
+	sender.AssertNumberOfCalls(t, "Count", 2)
+
+	// the line is dropped once the metric has been generated
+	shouldProcess, redactedMessage = p.applyRedactingRules(newMessage([]byte("request took 12ms"), &source, ""))
+	assert.Equal(t, false, shouldProcess)
+	assert.Nil(t, redactedMessage)
+	sender.AssertMetric(t, "Distribution", "app.duration", 12, "", []string{"env:prod", "service:web", "source:nginx"})
+	sender.AssertNumberOfCalls(t, "Count", 2)
+
+	p.commitMetrics()
+	sender.AssertNumberOfCalls(t, "Commit", 1)
+	// nothing to commit
+	p.commitMetrics()
+	sender.AssertNumberOfCalls(t, "Commit", 1)
+}
+
+func TestGenerateMetricWithoutSender(t *testing.T) {
+	p := &Processor{}
+
+	rule := &config.ProcessingRule{Type: config.GenerateMetric, Name: "errors", MetricName: "app.errors", Pattern: "ERROR", DropLine: true}
+	assert.Nil(t, config.CompileProcessingRules([]*config.ProcessingRule{rule}))
+	source := config.LogSource{Config: &config.LogsConfig{ProcessingRules: []*config.ProcessingRule{rule}}}
+
+	// the line is kept as no metric can be generated
+	shouldProcess, redactedMessage := p.applyRedactingRules(newMessage([]byte("ERROR something failed"), &source, ""))
+	assert.Equal(t, true, shouldProcess)
+	assert.Equal(t, []byte("ERROR something failed"), redactedMessage)
+	p.commitMetrics()
+}
+
+func TestThrottling(t *testing.T) {
+	p := &Processor{}
+
+	source := config.NewLogSource("", &config.LogsConfig{SampleOneIn: 2})
+	assert.True(t, p.applyThrottling(newMessage([]byte("hello"), source, ""), []byte("hello")))
+	assert.False(t, p.applyThrottling(newMessage([]byte("hello"), source, ""), []byte("hello")))
+	assert.Equal(t, int64(1), source.LogsDroppedBySampling.Value())
+
+	source = config.NewLogSource("", &config.LogsConfig{RateLimitLines: 1})
+	assert.True(t, p.applyThrottling(newMessage([]byte("hello"), source, ""), []byte("hello")))
+	assert.False(t, p.applyThrottling(newMessage([]byte("hello"), source, ""), []byte("hello")))
+	assert.Equal(t, int64(1), source.LogsDroppedByRateLimit.Value())
+
+	source = config.NewLogSource("", &config.LogsConfig{})
+	assert.True(t, p.applyThrottling(newMessage([]byte("hello"), source, ""), []byte("hello")))
+}
+
+func TestDiagnosticStages(t *testing.T) {
+	receiver := diagnostic.NewBufferedMessageReceiver()
+	receiver.SetEnabled(true)
+	p := &Processor{
+		outputChan:                make(chan *message.Message, 1),
+		encoder:                   RawEncoder,
+		diagnosticMessageReceiver: receiver,
+	}
+	filters := &diagnostic.Filters{Stages: []string{diagnostic.StageTailer, diagnostic.StageProcessed, diagnostic.StageEncoded}}
+
+	// only the processed messages are buffered when no stages are requested
+	source := newSource("mask_sequences", "[redacted]", "password=\\w+")
+	p.processMessage(newMessage([]byte("password=foo"), &source, ""))
+	line, ok := receiver.Next(filters)
+	assert.True(t, ok)
+	assert.Contains(t, line, "Stage: processed")
+	_, ok = receiver.Next(filters)
+	assert.False(t, ok)
+	<-p.outputChan
+
+	receiver.SetStages(filters.Stages)
+	p.processMessage(newMessage([]byte("password=foo"), &source, ""))
+
+	line, ok = receiver.Next(filters)
+	assert.True(t, ok)
+	assert.Contains(t, line, "Stage: tailer")
+	assert.Contains(t, line, "Message: password=foo")
+	line, ok = receiver.Next(filters)
+	assert.True(t, ok)
+	assert.Contains(t, line, "Stage: processed")
+	assert.Contains(t, line, "Message: [redacted]")
+	line, ok = receiver.Next(filters)
+	assert.True(t, ok)
+	assert.Contains(t, line, "Stage: encoded")
+	assert.Contains(t, line, "[redacted]")
+	_, ok = receiver.Next(filters)
+	assert.False(t, ok)
+	<-p.outputChan
+
+	// the excluded messages are only seen from the tailer
+	source = newSource("exclude_at_match", "", "world")
+	p.processMessage(newMessage([]byte("hello world"), &source, ""))
+	line, ok = receiver.Next(filters)
+	assert.True(t, ok)
+	assert.Contains(t, line, "Stage: tailer")
+	_, ok = receiver.Next(filters)
+	assert.False(t, ok)
+}
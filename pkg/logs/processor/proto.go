@@ -6,8 +6,6 @@
 package processor
 
 import (
-	"time"
-
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
 	"github.com/DataDog/datadog-agent/pkg/logs/pb"
 )
@@ -21,9 +19,9 @@ type protoEncoder struct{}
 // Encode encodes a message into a protobuf byte array.
 func (p *protoEncoder) Encode(msg *message.Message, redactedMsg []byte) ([]byte, error) {
 	return (&pb.Log{
-		Message:   toValidUtf8(redactedMsg),
+		Message:   toValidUtf8(contentWithAttributes(msg, redactedMsg)),
 		Status:    msg.GetStatus(),
-		Timestamp: time.Now().UTC().UnixNano(),
+		Timestamp: messageTimestamp(msg).UnixNano(),
 		Hostname:  getHostname(),
 		Service:   msg.Origin.Service(),
 		Source:    msg.Origin.Source(),
@@ -7,7 +7,6 @@ package processor
 
 import (
 	"regexp"
-	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/logs/config"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
@@ -35,7 +34,7 @@ func (r *rawEncoder) Encode(msg *message.Message, redactedMsg []byte) ([]byte, e
 		extraContent = append(extraContent, ' ')
 
 		// Timestamp
-		extraContent = time.Now().UTC().AppendFormat(extraContent, config.DateFormat)
+		extraContent = messageTimestamp(msg).AppendFormat(extraContent, config.DateFormat)
 		extraContent = append(extraContent, ' ')
 
 		extraContent = append(extraContent, []byte(getHostname())...)
@@ -61,7 +60,7 @@ func (r *rawEncoder) Encode(msg *message.Message, redactedMsg []byte) ([]byte, e
 		}
 		extraContent = append(extraContent, ' ')
 
-		return append(extraContent, redactedMsg...), nil
+		return append(extraContent, contentWithAttributes(msg, redactedMsg)...), nil
 
 	}
 
@@ -29,8 +29,16 @@ type batchStrategy struct {
 
 // NewBatchStrategy returns a new batchStrategy.
 func NewBatchStrategy(serializer Serializer, batchWait time.Duration) Strategy {
+	return NewBatchStrategyWithSize(serializer, batchWait, maxBatchSize)
+}
+
+// NewBatchStrategyWithSize returns a new batchStrategy sending at most batchSize messages per payload.
+func NewBatchStrategyWithSize(serializer Serializer, batchWait time.Duration, batchSize int) Strategy {
+	if batchSize <= 0 || batchSize > maxBatchSize {
+		batchSize = maxBatchSize
+	}
 	return &batchStrategy{
-		buffer:     NewMessageBuffer(maxBatchSize, maxContentSize),
+		buffer:     NewMessageBuffer(batchSize, maxContentSize),
 		serializer: serializer,
 		batchWait:  batchWait,
 	}
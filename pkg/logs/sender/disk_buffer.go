@@ -0,0 +1,300 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package sender
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/client"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+)
+
+const (
+	diskBufferFileExtension = ".payload"
+	diskBufferTempExtension = ".tmp"
+	// diskBufferMinRetryDelay and diskBufferMaxRetryDelay bound the delay before replaying
+	// the payloads again after the destination was unreachable, doubled on each failure.
+	diskBufferMinRetryDelay = 1 * time.Second
+	diskBufferMaxRetryDelay = 30 * time.Second
+	// diskBufferStopTimeout is the maximum time to wait for the payload being replayed when stopping.
+	diskBufferStopTimeout = 5 * time.Second
+)
+
+var errDiskBufferFull = errors.New("the disk buffer is full")
+
+// spooledPayload is a payload written in the disk buffer.
+type spooledPayload struct {
+	filename string
+	size     int64
+}
+
+// DiskBuffer spools on disk, up to a maximum size, the payloads which could not be
+// sent because the main destination of a pipeline was unreachable. They are replayed
+// in order to the destination, in the background as soon as they are written, and
+// survive a restart of the agent.
+type DiskBuffer struct {
+	path               string
+	maxSizeInBytes     int64
+	destination        client.Destination
+	mu                 sync.Mutex
+	payloads           []spooledPayload
+	currentSizeInBytes int64
+	sequence           int64
+	// replayMu ensures that a payload is replayed only once when the sender
+	// replays the oldest payload itself to make room in the buffer
+	replayMu    sync.Mutex
+	pushed      chan struct{}
+	stopTimeout time.Duration
+	stop        chan struct{}
+	done        chan struct{}
+}
+
+// NewDiskBuffer returns a new DiskBuffer writing the payloads in path and replaying them
+// to destination, the payloads left by a previous run are replayed first.
+func NewDiskBuffer(path string, maxSizeInBytes int64, destination client.Destination) (*DiskBuffer, error) {
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return nil, err
+	}
+	b := &DiskBuffer{
+		path:           path,
+		maxSizeInBytes: maxSizeInBytes,
+		destination:    destination,
+		pushed:         make(chan struct{}, 1),
+		stopTimeout:    diskBufferStopTimeout,
+	}
+	if err := b.reloadExistingPayloads(); err != nil {
+		return nil, err
+	}
+	if len(b.payloads) > 0 {
+		b.notifyPushed()
+	}
+	return b, nil
+}
+
+// Start starts replaying the payloads.
+func (b *DiskBuffer) Start() {
+	b.stop = make(chan struct{})
+	b.done = make(chan struct{})
+	go b.run()
+}
+
+// Stop stops replaying the payloads, the remaining ones stay on disk. It does not wait
+// more than stopTimeout for the payload being replayed, which is replayed again on the
+// next start if it was not sent.
+func (b *DiskBuffer) Stop() {
+	close(b.stop)
+	select {
+	case <-b.done:
+	case <-time.After(b.stopTimeout):
+		log.Warnf("Timed out waiting for the payload being replayed from the disk buffer %s", b.path)
+	}
+}
+
+// Push durably writes a payload at the end of the buffer,
+// returns an error if the buffer is full or the payload could not be written.
+func (b *DiskBuffer) Push(payload []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	size := int64(len(payload))
+	if b.currentSizeInBytes+size > b.maxSizeInBytes {
+		return errDiskBufferFull
+	}
+
+	// the names of the files keep the order of the payloads across restarts
+	b.sequence++
+	filename := filepath.Join(b.path, fmt.Sprintf("%019d_%010d%s", time.Now().UnixNano(), b.sequence, diskBufferFileExtension))
+	if err := writeFileSync(filename, payload); err != nil {
+		return err
+	}
+
+	b.payloads = append(b.payloads, spooledPayload{filename: filename, size: size})
+	b.currentSizeInBytes += size
+	metrics.PayloadsSpooled.Add(1)
+	metrics.TlmPayloadsSpooled.Inc()
+	b.notifyPushed()
+	return nil
+}
+
+// IsEmpty returns true if all the payloads of the buffer have been sent.
+func (b *DiskBuffer) IsEmpty() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.payloads) == 0
+}
+
+// notifyPushed wakes up the replay of the payloads.
+func (b *DiskBuffer) notifyPushed() {
+	select {
+	case b.pushed <- struct{}{}:
+	default:
+	}
+}
+
+// run replays the payloads as soon as they are pushed until stop, waiting
+// longer and longer between two attempts while the destination is unreachable.
+func (b *DiskBuffer) run() {
+	defer close(b.done)
+	var retryDelay time.Duration
+	for {
+		if retryDelay == 0 {
+			select {
+			case <-b.pushed:
+			case <-b.stop:
+				return
+			}
+		} else {
+			timer := time.NewTimer(retryDelay)
+			select {
+			case <-timer.C:
+			case <-b.stop:
+				timer.Stop()
+				return
+			}
+		}
+
+		if b.replay() {
+			retryDelay = 0
+			continue
+		}
+		retryDelay *= 2
+		if retryDelay < diskBufferMinRetryDelay {
+			retryDelay = diskBufferMinRetryDelay
+		} else if retryDelay > diskBufferMaxRetryDelay {
+			retryDelay = diskBufferMaxRetryDelay
+		}
+	}
+}
+
+// replay sends the payloads in order until the buffer is empty, the destination is
+// unreachable or the buffer is stopped, returns false when the destination is unreachable.
+func (b *DiskBuffer) replay() bool {
+	for {
+		select {
+		case <-b.stop:
+			return true
+		default:
+		}
+		sent, err := b.replayOldest()
+		if err != nil {
+			return false
+		}
+		if !sent {
+			return true
+		}
+	}
+}
+
+// replayOldest sends the oldest payload of the buffer, returns false if the buffer is empty
+// and an error if the destination is unreachable, the payload being kept in this case.
+func (b *DiskBuffer) replayOldest() (bool, error) {
+	b.replayMu.Lock()
+	defer b.replayMu.Unlock()
+
+	b.mu.Lock()
+	if len(b.payloads) == 0 {
+		b.mu.Unlock()
+		return false, nil
+	}
+	oldest := b.payloads[0]
+	b.mu.Unlock()
+
+	payload, err := ioutil.ReadFile(oldest.filename)
+	if err != nil {
+		log.Warnf("Could not read %s from the disk buffer, dropping it: %v", oldest.filename, err)
+		b.remove(oldest)
+		return true, nil
+	}
+
+	err = b.destination.Send(payload)
+	if err != nil {
+		metrics.DestinationErrors.Add(1)
+		metrics.TlmDestinationErrors.Inc()
+		if _, ok := err.(*client.RetryableError); ok || shouldStopSending(err) {
+			// the destination is still unreachable, retry later
+			return false, err
+		}
+		log.Warnf("Could not send payload from the disk buffer: %v", err)
+	} else {
+		metrics.PayloadsReplayed.Add(1)
+		metrics.TlmPayloadsReplayed.Inc()
+	}
+	b.remove(oldest)
+	return true, nil
+}
+
+// remove deletes the oldest payload of the buffer.
+func (b *DiskBuffer) remove(payload spooledPayload) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := os.Remove(payload.filename); err != nil && !os.IsNotExist(err) {
+		log.Warnf("Could not remove %s from the disk buffer: %v", payload.filename, err)
+	}
+	b.payloads = b.payloads[1:]
+	b.currentSizeInBytes -= payload.size
+}
+
+// reloadExistingPayloads adds to the buffer the payloads written by a previous run,
+// the files which were being written are removed.
+func (b *DiskBuffer) reloadExistingPayloads() error {
+	files, err := ioutil.ReadDir(b.path)
+	if err != nil {
+		return err
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Name() < files[j].Name()
+	})
+	for _, file := range files {
+		filename := filepath.Join(b.path, file.Name())
+		switch {
+		case file.IsDir():
+			continue
+		case strings.HasSuffix(file.Name(), diskBufferTempExtension):
+			os.Remove(filename) //nolint:errcheck
+		case strings.HasSuffix(file.Name(), diskBufferFileExtension):
+			b.payloads = append(b.payloads, spooledPayload{filename: filename, size: file.Size()})
+			b.currentSizeInBytes += file.Size()
+		}
+	}
+	if len(b.payloads) > 0 {
+		log.Infof("Found %d payloads to replay in the disk buffer %s", len(b.payloads), b.path)
+	}
+	return nil
+}
+
+// writeFileSync writes a file and flushes it on disk, the file is created with
+// its final name only once complete.
+func writeFileSync(filename string, content []byte) error {
+	tempFilename := filename + diskBufferTempExtension
+	f, err := os.OpenFile(tempFilename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(content)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tempFilename, filename)
+	}
+	if err != nil {
+		os.Remove(tempFilename) //nolint:errcheck
+	}
+	return err
+}
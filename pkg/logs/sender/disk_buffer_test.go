@@ -0,0 +1,192 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package sender
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/client"
+)
+
+// fakeDestination records the payloads it sends and fails while err is set.
+type fakeDestination struct {
+	mu       sync.Mutex
+	err      error
+	payloads []string
+}
+
+func (d *fakeDestination) Send(payload []byte) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.err != nil {
+		return d.err
+	}
+	d.payloads = append(d.payloads, string(payload))
+	return nil
+}
+
+func (d *fakeDestination) SendAsync(payload []byte) {
+	d.Send(payload) //nolint:errcheck
+}
+
+func (d *fakeDestination) setError(err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.err = err
+}
+
+func (d *fakeDestination) getPayloads() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]string{}, d.payloads...)
+}
+
+func TestDiskBufferPushAndReplay(t *testing.T) {
+	path, err := ioutil.TempDir("", "logs-disk-buffer-test-")
+	require.Nil(t, err)
+	defer os.RemoveAll(path)
+
+	destination := &fakeDestination{err: client.NewRetryableError(errors.New("unreachable"))}
+	b, err := NewDiskBuffer(path, 10, destination)
+	require.Nil(t, err)
+	assert.True(t, b.IsEmpty())
+
+	assert.Nil(t, b.Push([]byte("abcd")))
+	assert.Nil(t, b.Push([]byte("efgh")))
+	assert.False(t, b.IsEmpty())
+
+	// the buffer is full
+	assert.Equal(t, errDiskBufferFull, b.Push([]byte("ijk")))
+
+	files, err := ioutil.ReadDir(path)
+	require.Nil(t, err)
+	assert.Len(t, files, 2)
+
+	// the destination is unreachable, the payloads are kept
+	b.replay()
+	assert.False(t, b.IsEmpty())
+	assert.Empty(t, destination.getPayloads())
+
+	// the payloads are sent in order once the destination is reachable
+	destination.setError(nil)
+	b.replay()
+	assert.True(t, b.IsEmpty())
+	assert.Equal(t, []string{"abcd", "efgh"}, destination.getPayloads())
+
+	files, err = ioutil.ReadDir(path)
+	require.Nil(t, err)
+	assert.Len(t, files, 0)
+
+	// there is room again
+	assert.Nil(t, b.Push([]byte("ijk")))
+}
+
+func TestDiskBufferDropsPayloadsOnNonRetryableErrors(t *testing.T) {
+	path, err := ioutil.TempDir("", "logs-disk-buffer-test-")
+	require.Nil(t, err)
+	defer os.RemoveAll(path)
+
+	destination := &fakeDestination{err: errors.New("client error")}
+	b, err := NewDiskBuffer(path, 10, destination)
+	require.Nil(t, err)
+
+	assert.Nil(t, b.Push([]byte("abcd")))
+	b.replay()
+	assert.True(t, b.IsEmpty())
+}
+
+func TestDiskBufferReloadsExistingPayloads(t *testing.T) {
+	path, err := ioutil.TempDir("", "logs-disk-buffer-test-")
+	require.Nil(t, err)
+	defer os.RemoveAll(path)
+
+	destination := &fakeDestination{err: client.NewRetryableError(errors.New("unreachable"))}
+	b, err := NewDiskBuffer(path, 100, destination)
+	require.Nil(t, err)
+	for _, payload := range []string{"a", "b", "c"} {
+		assert.Nil(t, b.Push([]byte(payload)))
+	}
+	// a payload which was being written when the agent stopped
+	require.Nil(t, ioutil.WriteFile(filepath.Join(path, "0_0"+diskBufferFileExtension+diskBufferTempExtension), []byte("d"), 0600))
+
+	destination = &fakeDestination{}
+	b, err = NewDiskBuffer(path, 100, destination)
+	require.Nil(t, err)
+	assert.Equal(t, int64(3), b.currentSizeInBytes)
+	b.Start()
+	defer b.Stop()
+
+	// the payloads are replayed right away
+	assert.Eventually(t, b.IsEmpty, diskBufferMinRetryDelay/2, 10*time.Millisecond)
+	assert.Equal(t, []string{"a", "b", "c"}, destination.getPayloads())
+
+	files, err := ioutil.ReadDir(path)
+	require.Nil(t, err)
+	assert.Len(t, files, 0)
+}
+
+func TestDiskBufferReplaysPushedPayloads(t *testing.T) {
+	path, err := ioutil.TempDir("", "logs-disk-buffer-test-")
+	require.Nil(t, err)
+	defer os.RemoveAll(path)
+
+	destination := &fakeDestination{}
+	b, err := NewDiskBuffer(path, 100, destination)
+	require.Nil(t, err)
+	b.Start()
+	defer b.Stop()
+
+	assert.Nil(t, b.Push([]byte("a")))
+	assert.Eventually(t, b.IsEmpty, diskBufferMinRetryDelay/2, 10*time.Millisecond)
+	assert.Nil(t, b.Push([]byte("b")))
+	assert.Eventually(t, b.IsEmpty, diskBufferMinRetryDelay/2, 10*time.Millisecond)
+	assert.Equal(t, []string{"a", "b"}, destination.getPayloads())
+}
+
+// blockingDestination blocks sending until unblock is closed.
+type blockingDestination struct {
+	fakeDestination
+	unblock chan struct{}
+}
+
+func (d *blockingDestination) Send(payload []byte) error {
+	<-d.unblock
+	return d.fakeDestination.Send(payload)
+}
+
+func TestDiskBufferStopDoesNotWaitForTheDestination(t *testing.T) {
+	path, err := ioutil.TempDir("", "logs-disk-buffer-test-")
+	require.Nil(t, err)
+	defer os.RemoveAll(path)
+
+	destination := &blockingDestination{unblock: make(chan struct{})}
+	defer close(destination.unblock)
+	b, err := NewDiskBuffer(path, 100, destination)
+	require.Nil(t, err)
+	b.stopTimeout = 10 * time.Millisecond
+	assert.Nil(t, b.Push([]byte("a")))
+	b.Start()
+
+	stopped := make(chan struct{})
+	go func() {
+		b.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		assert.Fail(t, "the disk buffer should be stopped")
+	}
+}
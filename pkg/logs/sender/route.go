@@ -0,0 +1,175 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package sender
+
+import (
+	"expvar"
+	"strings"
+	"sync"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/client"
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+)
+
+// warningPeriod is the number of dropped logs between two warnings.
+const warningPeriod = 1000
+
+// Route sends the logs matching a filter to an additional destination with its own strategy,
+// independently of the main destination.
+// The logs of an exclusive route are not sent to the main destination, they are forwarded
+// to the next stage of the pipeline once sent. The logs are buffered up to config.ChanSize
+// per route. The logs a non-exclusive route can't keep up with are dropped so that a slow or
+// failing destination never blocks the dispatch of the logs to the other destinations, while
+// an exclusive route, being the only destination of its logs, applies backpressure instead.
+type Route struct {
+	name        string
+	filter      *config.EndpointFilter
+	exclusive   bool
+	destination client.Destination
+	strategy    Strategy
+	inputChan   chan *message.Message
+	outputChan  chan *message.Message
+	done        chan struct{}
+	mu          sync.Mutex
+}
+
+// NewRoute returns a new route sending the logs matching the filter of the endpoint to destination.
+func NewRoute(endpoint config.Endpoint, destination client.Destination, strategy Strategy) *Route {
+	// the counter is shared by the routes of all the pipelines, it is only created by the first one
+	metrics.DestinationLogsDropped.Add(endpoint.Host, 0)
+	return &Route{
+		name:        endpoint.Host,
+		filter:      endpoint.Filter,
+		exclusive:   endpoint.Exclusive,
+		destination: destination,
+		strategy:    strategy,
+		inputChan:   make(chan *message.Message, config.ChanSize),
+		done:        make(chan struct{}),
+	}
+}
+
+// start starts sending the logs of the route, the logs of an exclusive route
+// are forwarded to outputChan once sent.
+func (r *Route) start(outputChan chan *message.Message) {
+	if r.exclusive {
+		r.outputChan = outputChan
+	} else {
+		r.outputChan = make(chan *message.Message, config.ChanSize)
+		go func() {
+			for range r.outputChan {
+				// the offsets are committed by the main destination
+			}
+		}()
+	}
+	go func() {
+		r.strategy.Send(r.inputChan, r.outputChan, r.send, &r.mu)
+		if !r.exclusive {
+			close(r.outputChan)
+		}
+		r.done <- struct{}{}
+	}()
+}
+
+// stop stops the route once all its logs have been sent.
+func (r *Route) stop() {
+	close(r.inputChan)
+	<-r.done
+}
+
+// flush sends synchronously the logs of the route.
+func (r *Route) flush() {
+	r.strategy.Flush(r.inputChan, r.outputChan, r.send, &r.mu)
+}
+
+// add adds a log to the route. It blocks while an exclusive route is full, otherwise the log
+// is dropped if the route is full.
+func (r *Route) add(msg *message.Message) {
+	if r.exclusive {
+		r.inputChan <- msg
+		return
+	}
+	select {
+	case r.inputChan <- msg:
+	default:
+		if metrics.DestinationLogsDropped.Get(r.name).(*expvar.Int).Value()%warningPeriod == 0 {
+			log.Warnf("Some logs sent to additional destination %v were dropped", r.name)
+		}
+		metrics.DestinationLogsDropped.Add(r.name, 1)
+		metrics.TlmLogsDropped.Inc(r.name)
+	}
+}
+
+// send sends a payload to the destination of the route,
+// it will forever retry unless the error is not retryable.
+func (r *Route) send(payload []byte) error {
+	for {
+		err := r.destination.Send(payload)
+		if err == nil {
+			return nil
+		}
+		metrics.DestinationErrors.Add(1)
+		metrics.TlmDestinationErrors.Inc()
+		if _, ok := err.(*client.RetryableError); !ok {
+			return err
+		}
+	}
+}
+
+// matches returns true if the log must be sent to the route.
+func (r *Route) matches(msg *message.Message) bool {
+	return matchesFilter(r.filter, msg)
+}
+
+// matchesFilter returns true if the log matches one of the values of each
+// non-empty criterion of the filter.
+func matchesFilter(filter *config.EndpointFilter, msg *message.Message) bool {
+	if filter == nil {
+		return true
+	}
+	if len(filter.Statuses) > 0 && !containsFold(filter.Statuses, msg.GetStatus()) {
+		return false
+	}
+	if len(filter.Sources) == 0 && len(filter.Services) == 0 && len(filter.Tags) == 0 {
+		return true
+	}
+	if msg.Origin == nil {
+		return false
+	}
+	if len(filter.Sources) > 0 && !containsFold(filter.Sources, msg.Origin.Source()) {
+		return false
+	}
+	if len(filter.Services) > 0 && !containsFold(filter.Services, msg.Origin.Service()) {
+		return false
+	}
+	if len(filter.Tags) > 0 && !containsAny(filter.Tags, msg.Origin.Tags()) {
+		return false
+	}
+	return true
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAny(values []string, tags []string) bool {
+	for _, tag := range tags {
+		for _, v := range values {
+			if v == tag {
+				return true
+			}
+		}
+	}
+	return false
+}
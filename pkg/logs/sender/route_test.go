@@ -0,0 +1,132 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package sender
+
+import (
+	"expvar"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/client"
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+)
+
+func TestMatchesFilter(t *testing.T) {
+	source := config.NewLogSource("", &config.LogsConfig{Source: "audit", Service: "auth", Tags: []string{"env:prod", "team:security"}})
+	msg := newMessage([]byte("a"), source, message.StatusWarning)
+
+	assert.True(t, matchesFilter(nil, msg))
+	assert.True(t, matchesFilter(&config.EndpointFilter{}, msg))
+	assert.True(t, matchesFilter(&config.EndpointFilter{Sources: []string{"nginx", "audit"}}, msg))
+	assert.False(t, matchesFilter(&config.EndpointFilter{Sources: []string{"nginx"}}, msg))
+	assert.True(t, matchesFilter(&config.EndpointFilter{Services: []string{"Auth"}}, msg))
+	assert.False(t, matchesFilter(&config.EndpointFilter{Services: []string{"web"}}, msg))
+	assert.True(t, matchesFilter(&config.EndpointFilter{Tags: []string{"team:security"}}, msg))
+	assert.False(t, matchesFilter(&config.EndpointFilter{Tags: []string{"env:staging"}}, msg))
+	assert.True(t, matchesFilter(&config.EndpointFilter{Statuses: []string{"error", "warn"}}, msg))
+	assert.False(t, matchesFilter(&config.EndpointFilter{Statuses: []string{"error"}}, msg))
+
+	// all the criteria must match
+	assert.True(t, matchesFilter(&config.EndpointFilter{Sources: []string{"audit"}, Statuses: []string{"warn"}}, msg))
+	assert.False(t, matchesFilter(&config.EndpointFilter{Sources: []string{"audit"}, Statuses: []string{"error"}}, msg))
+
+	// messages without origin only match statuses
+	msg = message.NewMessage([]byte("a"), nil, message.StatusError, 0)
+	assert.True(t, matchesFilter(&config.EndpointFilter{Statuses: []string{"error"}}, msg))
+	assert.False(t, matchesFilter(&config.EndpointFilter{Sources: []string{"audit"}}, msg))
+}
+
+func TestSenderRoutesMessages(t *testing.T) {
+	auditSource := config.NewLogSource("", &config.LogsConfig{Source: "audit"})
+	appSource := config.NewLogSource("", &config.LogsConfig{Source: "app"})
+
+	input := make(chan *message.Message, 10)
+	output := make(chan *message.Message, 10)
+
+	mainDestination := &fakeDestination{}
+	auditDestination := &fakeDestination{}
+	errorsDestination := &fakeDestination{}
+	routes := []*Route{
+		NewRoute(config.Endpoint{Host: "audit", Filter: &config.EndpointFilter{Sources: []string{"audit"}}, Exclusive: true}, auditDestination, StreamStrategy),
+		NewRoute(config.Endpoint{Host: "errors", Filter: &config.EndpointFilter{Statuses: []string{"error"}}}, errorsDestination, StreamStrategy),
+	}
+
+	sender := NewSender(input, output, client.NewDestinations(mainDestination, nil), StreamStrategy, nil, routes)
+	sender.Start()
+
+	input <- newMessage([]byte("audit"), auditSource, message.StatusInfo)
+	input <- newMessage([]byte("audit error"), auditSource, message.StatusError)
+	input <- newMessage([]byte("app"), appSource, message.StatusInfo)
+	input <- newMessage([]byte("app error"), appSource, message.StatusError)
+	sender.Stop()
+
+	assert.Equal(t, []string{"app", "app error"}, mainDestination.getPayloads())
+	assert.Equal(t, []string{"audit", "audit error"}, auditDestination.getPayloads())
+	assert.Equal(t, []string{"audit error", "app error"}, errorsDestination.getPayloads())
+
+	// the messages are forwarded once sent by the main destination or an exclusive route
+	close(output)
+	var forwarded []string
+	for msg := range output {
+		forwarded = append(forwarded, string(msg.Content))
+	}
+	assert.ElementsMatch(t, []string{"audit", "audit error", "app", "app error"}, forwarded)
+}
+
+func TestRouteDropsMessagesWhenFull(t *testing.T) {
+	route := NewRoute(config.Endpoint{Host: "slow.destination", Filter: &config.EndpointFilter{}}, &fakeDestination{}, StreamStrategy)
+
+	source := config.NewLogSource("", &config.LogsConfig{})
+	// the route is not started, the messages which can't be buffered are dropped
+	for i := 0; i < config.ChanSize+2; i++ {
+		route.add(newMessage([]byte("a"), source, ""))
+	}
+	assert.Equal(t, int64(2), metrics.DestinationLogsDropped.Get("slow.destination").(*expvar.Int).Value())
+}
+
+func TestExclusiveRouteBlocksWhenFull(t *testing.T) {
+	route := NewRoute(config.Endpoint{Host: "audit.destination", Filter: &config.EndpointFilter{Sources: []string{"audit"}}, Exclusive: true}, &fakeDestination{}, StreamStrategy)
+
+	source := config.NewLogSource("", &config.LogsConfig{Source: "audit"})
+	for i := 0; i < config.ChanSize; i++ {
+		route.add(newMessage([]byte("a"), source, ""))
+	}
+	added := make(chan struct{})
+	go func() {
+		route.add(newMessage([]byte("a"), source, ""))
+		close(added)
+	}()
+	select {
+	case <-added:
+		assert.Fail(t, "the message should not be added to a full exclusive route")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// the messages are never dropped, they are all sent once the route is started
+	output := make(chan *message.Message, config.ChanSize+1)
+	route.start(output)
+	<-added
+	route.stop()
+	assert.Len(t, output, config.ChanSize+1)
+	assert.Equal(t, int64(0), metrics.DestinationLogsDropped.Get("audit.destination").(*expvar.Int).Value())
+}
+
+func TestRouteDropCounterIsSharedByThePipelines(t *testing.T) {
+	endpoint := config.Endpoint{Host: "shared.destination", Filter: &config.EndpointFilter{}}
+	route := NewRoute(endpoint, &fakeDestination{}, StreamStrategy)
+
+	source := config.NewLogSource("", &config.LogsConfig{})
+	for i := 0; i < config.ChanSize+1; i++ {
+		route.add(newMessage([]byte("a"), source, ""))
+	}
+	// the route of another pipeline does not reset the counter
+	NewRoute(endpoint, &fakeDestination{}, StreamStrategy)
+	assert.Equal(t, int64(1), metrics.DestinationLogsDropped.Get("shared.destination").(*expvar.Int).Value())
+}
@@ -9,7 +9,10 @@ import (
 	"context"
 	"sync"
 
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+
 	"github.com/DataDog/datadog-agent/pkg/logs/client"
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
 	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
 )
@@ -27,23 +30,44 @@ type Sender struct {
 	outputChan   chan *message.Message
 	destinations *client.Destinations
 	strategy     Strategy
+	diskBuffer   *DiskBuffer
+	routes       []*Route
+	mainChan     chan *message.Message
 	done         chan struct{}
+	routesDone   chan struct{}
 	mu           sync.Mutex
+	routeMu      sync.Mutex
 }
 
-// NewSender returns a new sender.
-func NewSender(inputChan chan *message.Message, outputChan chan *message.Message, destinations *client.Destinations, strategy Strategy) *Sender {
+// NewSender returns a new sender, the payloads which can't be sent to the main destination
+// being written to the disk buffer when not nil. The messages matching a route are also
+// sent to its destination, and only to it when the route is exclusive.
+func NewSender(inputChan chan *message.Message, outputChan chan *message.Message, destinations *client.Destinations, strategy Strategy, diskBuffer *DiskBuffer, routes []*Route) *Sender {
+	mainChan := inputChan
+	if len(routes) > 0 {
+		mainChan = make(chan *message.Message, config.ChanSize)
+	}
 	return &Sender{
 		inputChan:    inputChan,
 		outputChan:   outputChan,
 		destinations: destinations,
 		strategy:     strategy,
+		diskBuffer:   diskBuffer,
+		routes:       routes,
+		mainChan:     mainChan,
 		done:         make(chan struct{}),
+		routesDone:   make(chan struct{}),
 	}
 }
 
 // Start starts the sender.
 func (s *Sender) Start() {
+	if len(s.routes) > 0 {
+		for _, route := range s.routes {
+			route.start(s.outputChan)
+		}
+		go s.dispatchForever()
+	}
 	go s.run()
 }
 
@@ -52,37 +76,70 @@ func (s *Sender) Start() {
 func (s *Sender) Stop() {
 	close(s.inputChan)
 	<-s.done
+	if len(s.routes) > 0 {
+		<-s.routesDone
+	}
 }
 
 // Flush sends synchronously the messages that this sender has to send.
 func (s *Sender) Flush() {
-	s.strategy.Flush(s.inputChan, s.outputChan, s.send, &s.mu)
+	if len(s.routes) > 0 {
+		s.routeMu.Lock()
+		for len(s.inputChan) > 0 {
+			s.dispatch(<-s.inputChan)
+		}
+		s.routeMu.Unlock()
+		for _, route := range s.routes {
+			route.flush()
+		}
+	}
+	s.strategy.Flush(s.mainChan, s.outputChan, s.send, &s.mu)
 }
 
 func (s *Sender) run() {
 	defer func() {
 		s.done <- struct{}{}
 	}()
-	s.strategy.Send(s.inputChan, s.outputChan, s.send, &s.mu)
+	s.strategy.Send(s.mainChan, s.outputChan, s.send, &s.mu)
+}
+
+// dispatchForever dispatches the messages to the main destination and the routes
+// until inputChan is closed, then stops the routes once the main destination is flushed,
+// so that a failing route does not delay it.
+func (s *Sender) dispatchForever() {
+	for msg := range s.inputChan {
+		s.routeMu.Lock()
+		s.dispatch(msg)
+		s.routeMu.Unlock()
+	}
+	close(s.mainChan)
+	for _, route := range s.routes {
+		route.stop()
+	}
+	close(s.routesDone)
+}
+
+// dispatch sends a message to the routes it matches,
+// and to the main destination unless one of them is exclusive.
+func (s *Sender) dispatch(msg *message.Message) {
+	exclusive := false
+	for _, route := range s.routes {
+		if route.matches(msg) {
+			route.add(msg)
+			exclusive = exclusive || route.exclusive
+		}
+	}
+	if !exclusive {
+		s.mainChan <- msg
+	}
 }
 
 // send sends a payload to multiple destinations,
 // it will forever retry for the main destination unless the error is not retryable
-// and only try once for additionnal destinations.
+// or the payload could be written to the disk buffer, and only try once for additionnal destinations.
 func (s *Sender) send(payload []byte) error {
-	for {
-		err := s.destinations.Main.Send(payload)
-		if err != nil {
-			metrics.DestinationErrors.Add(1)
-			metrics.TlmDestinationErrors.Inc()
-			if _, ok := err.(*client.RetryableError); ok {
-				// could not send the payload because of a client issue,
-				// let's retry
-				continue
-			}
-			return err
-		}
-		break
+	if err := s.sendToMain(payload); err != nil {
+		return err
 	}
 
 	for _, destination := range s.destinations.Additionals {
@@ -94,6 +151,50 @@ func (s *Sender) send(payload []byte) error {
 	return nil
 }
 
+// sendToMain sends a payload to the main destination. The payloads are written to the
+// disk buffer while it is not empty, so that they are sent in order, and when the main
+// destination is unreachable.
+func (s *Sender) sendToMain(payload []byte) error {
+	for {
+		if s.diskBuffer != nil && !s.diskBuffer.IsEmpty() {
+			if s.spool(payload) {
+				// the payload will be sent after the ones written before
+				return nil
+			}
+			// the buffer is full, make room by sending its oldest payload
+			if _, err := s.diskBuffer.replayOldest(); shouldStopSending(err) {
+				return err
+			}
+			continue
+		}
+		err := s.destinations.Main.Send(payload)
+		if err == nil {
+			return nil
+		}
+		metrics.DestinationErrors.Add(1)
+		metrics.TlmDestinationErrors.Inc()
+		if _, ok := err.(*client.RetryableError); !ok {
+			return err
+		}
+		if s.diskBuffer != nil && s.spool(payload) {
+			// the payload will be sent once the main destination is reachable again
+			return nil
+		}
+		// could not send the payload because of a client issue,
+		// let's retry
+	}
+}
+
+// spool writes a payload to the disk buffer, returns false if it could not be written,
+// like when the buffer is full.
+func (s *Sender) spool(payload []byte) bool {
+	if err := s.diskBuffer.Push(payload); err != nil {
+		log.Debugf("Could not write payload to the disk buffer: %v", err)
+		return false
+	}
+	return true
+}
+
 // shouldStopSending returns true if a component should stop sending logs.
 func shouldStopSending(err error) bool {
 	return err == context.Canceled
@@ -6,6 +6,9 @@
 package sender
 
 import (
+	"errors"
+	"io/ioutil"
+	"os"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -36,7 +39,7 @@ func TestSender(t *testing.T) {
 	destination := tcp.AddrToDestination(l.Addr(), destinationsCtx)
 	destinations := client.NewDestinations(destination, nil)
 
-	sender := NewSender(input, output, destinations, StreamStrategy)
+	sender := NewSender(input, output, destinations, StreamStrategy, nil, nil)
 	sender.Start()
 
 	expectedMessage := newMessage([]byte("fake line"), source, "")
@@ -69,7 +72,7 @@ func TestSenderNotBlockedByAdditional(t *testing.T) {
 	additionalDestination := tcp.NewDestination(config.Endpoint{Host: "dont.exist.local", Port: 0}, true, destinationsCtx)
 	destinations := client.NewDestinations(mainDestination, []client.Destination{additionalDestination})
 
-	sender := NewSender(input, output, destinations, StreamStrategy)
+	sender := NewSender(input, output, destinations, StreamStrategy, nil, nil)
 	sender.Start()
 
 	expectedMessage1 := newMessage([]byte("fake line"), source, "")
@@ -87,3 +90,78 @@ func TestSenderNotBlockedByAdditional(t *testing.T) {
 	sender.Stop()
 	destinationsCtx.Stop()
 }
+
+func TestSenderWritesToDiskBufferWhenUnreachable(t *testing.T) {
+	path, err := ioutil.TempDir("", "logs-disk-buffer-test-")
+	assert.Nil(t, err)
+	defer os.RemoveAll(path)
+
+	source := config.NewLogSource("", &config.LogsConfig{})
+
+	input := make(chan *message.Message, 1)
+	output := make(chan *message.Message, 1)
+
+	mainDestination := &fakeDestination{err: client.NewRetryableError(errors.New("unreachable"))}
+	destinations := client.NewDestinations(mainDestination, nil)
+	diskBuffer, err := NewDiskBuffer(path, 100, &fakeDestination{})
+	assert.Nil(t, err)
+
+	sender := NewSender(input, output, destinations, StreamStrategy, diskBuffer, nil)
+	sender.Start()
+
+	// the message is forwarded to the auditor once written to the disk buffer
+	expectedMessage1 := newMessage([]byte("fake line"), source, "")
+	input <- expectedMessage1
+	message, ok := <-output
+	assert.True(t, ok)
+	assert.Equal(t, expectedMessage1, message)
+	assert.False(t, diskBuffer.IsEmpty())
+
+	// the next messages go to the disk buffer until it is empty to keep their order
+	mainDestination.setError(nil)
+	expectedMessage2 := newMessage([]byte("fake line 2"), source, "")
+	input <- expectedMessage2
+	message, ok = <-output
+	assert.True(t, ok)
+	assert.Equal(t, expectedMessage2, message)
+	assert.Empty(t, mainDestination.getPayloads())
+
+	diskBuffer.replay()
+	assert.True(t, diskBuffer.IsEmpty())
+	expectedMessage3 := newMessage([]byte("fake line 3"), source, "")
+	input <- expectedMessage3
+	<-output
+	assert.Equal(t, []string{"fake line 3"}, mainDestination.getPayloads())
+
+	sender.Stop()
+}
+
+func TestSenderReplaysTheOldestPayloadWhenTheDiskBufferIsFull(t *testing.T) {
+	path, err := ioutil.TempDir("", "logs-disk-buffer-test-")
+	assert.Nil(t, err)
+	defer os.RemoveAll(path)
+
+	source := config.NewLogSource("", &config.LogsConfig{})
+
+	input := make(chan *message.Message, 1)
+	output := make(chan *message.Message, 1)
+
+	mainDestination := &fakeDestination{}
+	destinations := client.NewDestinations(mainDestination, nil)
+	replayDestination := &fakeDestination{}
+	diskBuffer, err := NewDiskBuffer(path, 10, replayDestination)
+	assert.Nil(t, err)
+	assert.Nil(t, diskBuffer.Push([]byte("0123456789")))
+
+	sender := NewSender(input, output, destinations, StreamStrategy, diskBuffer, nil)
+	sender.Start()
+
+	// the payload is not sent before the ones of the disk buffer
+	input <- newMessage([]byte("fake line"), source, "")
+	<-output
+	assert.Equal(t, []string{"0123456789"}, replayDestination.getPayloads())
+	assert.Equal(t, []string{"fake line"}, mainDestination.getPayloads())
+	assert.True(t, diskBuffer.IsEmpty())
+
+	sender.Stop()
+}
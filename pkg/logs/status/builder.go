@@ -124,17 +124,19 @@ func (b *Builder) getIntegrations() []Integration {
 		var sources []Source
 		for _, source := range logSources {
 			sources = append(sources, Source{
-				BytesRead:          source.BytesRead.Value(),
-				AllTimeAvgLatency:  source.LatencyStats.AllTimeAvg() / int64(time.Millisecond),
-				AllTimePeakLatency: source.LatencyStats.AllTimePeak() / int64(time.Millisecond),
-				RecentAvgLatency:   source.LatencyStats.MovingAvg() / int64(time.Millisecond),
-				RecentPeakLatency:  source.LatencyStats.MovingPeak() / int64(time.Millisecond),
-				Type:               source.Config.Type,
-				Configuration:      b.toDictionary(source.Config),
-				Status:             b.toString(source.Status),
-				Inputs:             source.GetInputs(),
-				Messages:           source.Messages.GetMessages(),
-				Info:               source.GetInfo(),
+				BytesRead:              source.BytesRead.Value(),
+				LogsDroppedBySampling:  source.LogsDroppedBySampling.Value(),
+				LogsDroppedByRateLimit: source.LogsDroppedByRateLimit.Value(),
+				AllTimeAvgLatency:      source.LatencyStats.AllTimeAvg() / int64(time.Millisecond),
+				AllTimePeakLatency:     source.LatencyStats.AllTimePeak() / int64(time.Millisecond),
+				RecentAvgLatency:       source.LatencyStats.MovingAvg() / int64(time.Millisecond),
+				RecentPeakLatency:      source.LatencyStats.MovingPeak() / int64(time.Millisecond),
+				Type:                   source.Config.Type,
+				Configuration:          b.toDictionary(source.Config),
+				Status:                 b.toString(source.Status),
+				Inputs:                 source.GetInputs(),
+				Messages:               source.Messages.GetMessages(),
+				Info:                   source.GetInfo(),
 			})
 		}
 		integrations = append(integrations, Integration{
@@ -177,6 +179,9 @@ func (b *Builder) toDictionary(c *config.LogsConfig) map[string]interface{} {
 	switch c.Type {
 	case config.TCPType, config.UDPType:
 		dictionary["Port"] = c.Port
+	case config.SyslogType:
+		dictionary["Port"] = c.Port
+		dictionary["Protocol"] = c.Protocol
 	case config.FileType:
 		dictionary["Path"] = c.Path
 		dictionary["TailingMode"] = c.TailingMode
@@ -205,6 +210,8 @@ func (b *Builder) getMetricsStatus() map[string]int64 {
 	var metrics = make(map[string]int64, 2)
 	metrics["LogsProcessed"] = b.logsExpVars.Get("LogsProcessed").(*expvar.Int).Value()
 	metrics["LogsSent"] = b.logsExpVars.Get("LogsSent").(*expvar.Int).Value()
+	metrics["LogsDroppedBySampling"] = b.logsExpVars.Get("LogsDroppedBySampling").(*expvar.Int).Value()
+	metrics["LogsDroppedByRateLimit"] = b.logsExpVars.Get("LogsDroppedByRateLimit").(*expvar.Int).Value()
 	metrics["BytesSent"] = b.logsExpVars.Get("BytesSent").(*expvar.Int).Value()
 	metrics["EncodedBytesSent"] = b.logsExpVars.Get("EncodedBytesSent").(*expvar.Int).Value()
 	return metrics
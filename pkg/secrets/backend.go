@@ -0,0 +1,171 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+// +build secrets
+
+package secrets
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// nativeBackend resolves secrets handles without calling the secret_backend_command
+// executable. Handles are routed to a native backend when they are prefixed with
+// its name, e.g. "ENC[vault:secret/data/db#password]".
+type nativeBackend interface {
+	// fetchSecrets returns the value of every reference, a reference being the
+	// handle stripped from the backend prefix. An error is returned if any
+	// reference can not be resolved.
+	fetchSecrets(refs []string) (map[string]string, error)
+	// String returns a description of the backend configuration
+	String() string
+}
+
+// backendFactory builds a native backend from its configuration
+type backendFactory func(config map[string]interface{}) (nativeBackend, error)
+
+// backendFactories lists the available native backends by name
+var backendFactories = map[string]backendFactory{
+	"file":                newFileBackend,
+	"vault":               newVaultBackend,
+	"aws_secrets_manager": newAWSSecretsManagerBackend,
+	"k8s_secret":          newK8sSecretBackend,
+}
+
+var (
+	// configured native backends by name
+	nativeBackends map[string]nativeBackend
+	// configuration errors of the native backends by name
+	nativeBackendErrors map[string]error
+)
+
+// initNativeBackends builds the native backends listed in the `secret_backends`
+// configuration, where every key is the name of a backend and its value the
+// backend configuration.
+func initNativeBackends(configs map[string]interface{}) {
+	nativeBackends = make(map[string]nativeBackend)
+	nativeBackendErrors = make(map[string]error)
+	for name, rawConfig := range configs {
+		factory, ok := backendFactories[name]
+		if !ok {
+			log.Errorf("Unknown secret backend '%s', available backends are: %s", name, strings.Join(availableBackends(), ", "))
+			continue
+		}
+		backend, err := factory(toStringMap(rawConfig))
+		if err != nil {
+			log.Errorf("Could not configure secret backend '%s': %s", name, err)
+			nativeBackendErrors[name] = err
+			continue
+		}
+		log.Infof("Secret backend '%s' configured: %s", name, backend)
+		nativeBackends[name] = backend
+	}
+}
+
+// availableBackends returns the sorted names of the native backends
+func availableBackends() []string {
+	names := make([]string, 0, len(backendFactories))
+	for name := range backendFactories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// nativeBackendsEnabled returns true if at least one native backend is configured
+func nativeBackendsEnabled() bool {
+	return len(nativeBackends) > 0 || len(nativeBackendErrors) > 0
+}
+
+// splitHandle returns the name of the native backend to use for the handle and the
+// reference to resolve. An empty name is returned if the handle must be resolved
+// by the secret_backend_command executable.
+func splitHandle(handle string) (string, string) {
+	parts := strings.SplitN(handle, ":", 2)
+	if len(parts) != 2 {
+		return "", handle
+	}
+	if _, ok := nativeBackends[parts[0]]; ok {
+		return parts[0], parts[1]
+	}
+	if _, ok := nativeBackendErrors[parts[0]]; ok {
+		return parts[0], parts[1]
+	}
+	return "", handle
+}
+
+// fetchNativeSecrets resolves handles with their native backends, the handles are
+// expected to be prefixed with a configured backend name.
+func fetchNativeSecrets(handles []string) (map[string]string, error) {
+	refsByBackend := make(map[string][]string)
+	for _, handle := range handles {
+		name, ref := splitHandle(handle)
+		refsByBackend[name] = append(refsByBackend[name], ref)
+	}
+
+	res := make(map[string]string)
+	for name, refs := range refsByBackend {
+		backend, ok := nativeBackends[name]
+		if !ok {
+			return nil, fmt.Errorf("secret backend '%s' is not configured properly: %s", name, nativeBackendErrors[name])
+		}
+		secrets, err := backend.fetchSecrets(refs)
+		if err != nil {
+			return nil, fmt.Errorf("secret backend '%s': %s", name, err)
+		}
+		for _, ref := range refs {
+			value, ok := secrets[ref]
+			if !ok {
+				return nil, fmt.Errorf("secret handle '%s:%s' was not resolved by the secret backend", name, ref)
+			}
+			if value == "" {
+				return nil, fmt.Errorf("decrypted secret for '%s:%s' is empty", name, ref)
+			}
+			res[name+":"+ref] = value
+		}
+	}
+	return res, nil
+}
+
+// splitReference splits a "<path>#<key>" reference, the key being optional
+func splitReference(ref string) (string, string) {
+	if idx := strings.LastIndex(ref, "#"); idx >= 0 {
+		return ref[:idx], ref[idx+1:]
+	}
+	return ref, ""
+}
+
+// toStringMap converts a configuration map decoded from YAML to a map[string]interface{}
+func toStringMap(raw interface{}) map[string]interface{} {
+	res := make(map[string]interface{})
+	switch m := raw.(type) {
+	case map[string]interface{}:
+		return m
+	case map[interface{}]interface{}:
+		for k, v := range m {
+			res[fmt.Sprintf("%v", k)] = v
+		}
+	}
+	return res
+}
+
+// getConfigString returns the string value of a backend configuration option, falling back
+// to the value of the environment variable envVar, and then to the default value.
+func getConfigString(config map[string]interface{}, key, envVar, defaultValue string) string {
+	if v, ok := config[key]; ok && v != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	if envVar != "" {
+		if v := os.Getenv(envVar); v != "" {
+			return v
+		}
+	}
+	return defaultValue
+}
@@ -0,0 +1,103 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+// +build secrets
+
+package secrets
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+)
+
+// secretsManagerClient is the subset of the AWS Secrets Manager API used by the backend
+type secretsManagerClient interface {
+	GetSecretValue(*secretsmanager.GetSecretValueInput) (*secretsmanager.GetSecretValueOutput, error)
+}
+
+// awsSecretsManagerBackend reads secrets from AWS Secrets Manager. Handles are formatted as
+// "aws_secrets_manager:<secret id or ARN>", or "aws_secrets_manager:<secret id or ARN>#<key>"
+// to extract a single key of a secret storing a JSON object. The credentials are looked up
+// with the default AWS credentials chain (environment, shared configuration, instance role...).
+//
+// Options:
+//   region: AWS region of the secrets, defaults to the region of the AWS configuration
+type awsSecretsManagerBackend struct {
+	region string
+	client secretsManagerClient
+}
+
+func newAWSSecretsManagerBackend(config map[string]interface{}) (nativeBackend, error) {
+	region := getConfigString(config, "region", "", "")
+	awsConfig := &aws.Config{}
+	if region != "" {
+		awsConfig.Region = aws.String(region)
+	}
+	sess, err := session.NewSession(awsConfig)
+	if err != nil {
+		return nil, fmt.Errorf("could not create AWS session: %s", err)
+	}
+	return &awsSecretsManagerBackend{
+		region: region,
+		client: secretsmanager.New(sess),
+	}, nil
+}
+
+func (b *awsSecretsManagerBackend) fetchSecrets(refs []string) (map[string]string, error) {
+	// read every secret once, even if several keys are referenced
+	values := make(map[string]string)
+	res := make(map[string]string)
+	for _, ref := range refs {
+		secretID, key := splitReference(ref)
+		value, ok := values[secretID]
+		if !ok {
+			var err error
+			value, err = b.getSecretValue(secretID)
+			if err != nil {
+				return nil, fmt.Errorf("could not read '%s': %s", secretID, err)
+			}
+			values[secretID] = value
+		}
+		if key == "" {
+			res[ref] = value
+			continue
+		}
+		var fields map[string]interface{}
+		if err := json.Unmarshal([]byte(value), &fields); err != nil {
+			return nil, fmt.Errorf("secret '%s' is not a JSON object: %s", secretID, err)
+		}
+		field, ok := fields[key]
+		if !ok {
+			return nil, fmt.Errorf("key '%s' not found in '%s'", key, secretID)
+		}
+		res[ref] = stringValue(field)
+	}
+	return res, nil
+}
+
+func (b *awsSecretsManagerBackend) getSecretValue(secretID string) (string, error) {
+	output, err := b.client.GetSecretValue(&secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(secretID),
+	})
+	if err != nil {
+		return "", err
+	}
+	if output.SecretString != nil {
+		return *output.SecretString, nil
+	}
+	// binary secrets are already base64 decoded by the SDK
+	return string(output.SecretBinary), nil
+}
+
+func (b *awsSecretsManagerBackend) String() string {
+	if b.region == "" {
+		return "AWS Secrets Manager"
+	}
+	return fmt.Sprintf("AWS Secrets Manager in %s", b.region)
+}
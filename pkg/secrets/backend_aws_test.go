@@ -0,0 +1,67 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+// +build secrets
+
+package secrets
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockSecretsManagerClient struct {
+	secrets  map[string]*secretsmanager.GetSecretValueOutput
+	requests map[string]int
+}
+
+func (c *mockSecretsManagerClient) GetSecretValue(input *secretsmanager.GetSecretValueInput) (*secretsmanager.GetSecretValueOutput, error) {
+	c.requests[*input.SecretId]++
+	output, ok := c.secrets[*input.SecretId]
+	if !ok {
+		return nil, fmt.Errorf("ResourceNotFoundException: secret not found")
+	}
+	return output, nil
+}
+
+func TestAWSSecretsManagerBackend(t *testing.T) {
+	client := &mockSecretsManagerClient{
+		secrets: map[string]*secretsmanager.GetSecretValueOutput{
+			"api_key": {SecretString: aws.String("abcdef")},
+			"db":      {SecretString: aws.String(`{"user":"admin","port":5432}`)},
+			"binary":  {SecretBinary: []byte("p1")},
+		},
+		requests: map[string]int{},
+	}
+	backend := &awsSecretsManagerBackend{region: "us-east-1", client: client}
+	assert.Equal(t, "AWS Secrets Manager in us-east-1", backend.String())
+
+	res, err := backend.fetchSecrets([]string{"api_key", "db#user", "db#port", "binary"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"api_key": "abcdef",
+		"db#user": "admin",
+		"db#port": "5432",
+		"binary":  "p1",
+	}, res)
+	// every secret is read once
+	assert.Equal(t, map[string]int{"api_key": 1, "db": 1, "binary": 1}, client.requests)
+
+	_, err = backend.fetchSecrets([]string{"db#missing"})
+	require.Error(t, err)
+	assert.Equal(t, "key 'missing' not found in 'db'", err.Error())
+
+	_, err = backend.fetchSecrets([]string{"api_key#user"})
+	assert.Error(t, err)
+
+	_, err = backend.fetchSecrets([]string{"unknown"})
+	require.Error(t, err)
+	assert.Equal(t, "could not read 'unknown': ResourceNotFoundException: secret not found", err.Error())
+}
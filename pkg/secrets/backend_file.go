@@ -0,0 +1,96 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+// +build secrets
+
+package secrets
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// fileBackend reads secrets from files, e.g. Docker or Kubernetes secrets mounted in the
+// agent container. Handles are formatted as "file:<absolute path>". The trailing new line
+// of the file, if any, is removed.
+//
+// Options:
+//   root: if set, only the files located under this directory can be read
+type fileBackend struct {
+	root string
+}
+
+func newFileBackend(config map[string]interface{}) (nativeBackend, error) {
+	root := getConfigString(config, "root", "", "")
+	if root != "" {
+		if !filepath.IsAbs(root) {
+			return nil, fmt.Errorf("root must be an absolute path, got '%s'", root)
+		}
+		root = filepath.Clean(root)
+	}
+	return &fileBackend{root: root}, nil
+}
+
+func (b *fileBackend) fetchSecrets(refs []string) (map[string]string, error) {
+	res := make(map[string]string)
+	for _, ref := range refs {
+		value, err := b.readSecretFile(ref)
+		if err != nil {
+			return nil, fmt.Errorf("could not read '%s': %s", ref, err)
+		}
+		res[ref] = value
+	}
+	return res, nil
+}
+
+func (b *fileBackend) readSecretFile(path string) (string, error) {
+	if !filepath.IsAbs(path) {
+		return "", fmt.Errorf("path must be absolute")
+	}
+	if b.root != "" {
+		// resolve symlinks so that they can not be used to escape from the root, the resolved
+		// path is the one read so that a link swapped after this check is not followed
+		resolved, err := filepath.EvalSymlinks(path)
+		if err != nil {
+			return "", err
+		}
+		root, err := filepath.EvalSymlinks(b.root)
+		if err != nil {
+			return "", err
+		}
+		if !strings.HasPrefix(resolved, root+string(filepath.Separator)) {
+			return "", fmt.Errorf("file is not located under '%s'", b.root)
+		}
+		path = resolved
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	if fi.Size() > int64(SecretBackendOutputMaxSize) {
+		return "", fmt.Errorf("file exceeds max allowed size of %d bytes", SecretBackendOutputMaxSize)
+	}
+	content, err := ioutil.ReadAll(io.LimitReader(f, int64(SecretBackendOutputMaxSize)))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
+
+func (b *fileBackend) String() string {
+	if b.root == "" {
+		return "files"
+	}
+	return fmt.Sprintf("files under %s", b.root)
+}
@@ -0,0 +1,70 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+// +build secrets
+
+package secrets
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileBackend(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secrets")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	root := filepath.Join(dir, "root")
+	require.NoError(t, os.Mkdir(root, 0700))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(root, "password"), []byte("p1\n"), 0600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "outside"), []byte("p2"), 0600))
+
+	backend, err := newFileBackend(map[string]interface{}{})
+	require.NoError(t, err)
+	res, err := backend.fetchSecrets([]string{filepath.Join(root, "password"), filepath.Join(dir, "outside")})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		filepath.Join(root, "password"): "p1",
+		filepath.Join(dir, "outside"):   "p2",
+	}, res)
+
+	_, err = backend.fetchSecrets([]string{"relative/path"})
+	assert.Error(t, err)
+	_, err = backend.fetchSecrets([]string{filepath.Join(root, "missing")})
+	assert.Error(t, err)
+
+	backend, err = newFileBackend(map[string]interface{}{"root": root})
+	require.NoError(t, err)
+	_, err = backend.fetchSecrets([]string{filepath.Join(root, "password")})
+	assert.NoError(t, err)
+	_, err = backend.fetchSecrets([]string{filepath.Join(root, "..", "outside")})
+	assert.Error(t, err)
+
+	_, err = newFileBackend(map[string]interface{}{"root": "relative"})
+	assert.Error(t, err)
+}
+
+func TestFileBackendMaxSize(t *testing.T) {
+	defer func() { SecretBackendOutputMaxSize = 1024 * 1024 }()
+
+	f, err := ioutil.TempFile("", "secret")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString("some long password")
+	require.NoError(t, err)
+	f.Close()
+
+	SecretBackendOutputMaxSize = 10
+	backend, err := newFileBackend(map[string]interface{}{})
+	require.NoError(t, err)
+	_, err = backend.fetchSecrets([]string{f.Name()})
+	assert.Error(t, err)
+}
@@ -0,0 +1,147 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+// +build secrets
+
+package secrets
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	defaultK8sTokenFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	defaultK8sCAFile    = "/var/run/secrets/kubernetes.io/serviceaccount/ca.crt"
+)
+
+// k8sSecretBackend reads secrets from the Kubernetes API server. Handles are formatted as
+// "k8s_secret:<namespace>/<name>/<key>". The service account of the agent must be allowed
+// to get the referenced secrets.
+//
+// Options:
+//   api_server: URL of the API server, defaults to the in-cluster configuration
+//   token_file: file containing the bearer token, read before every request, defaults to
+//               the service account token
+//   ca_file:    CA certificate used to verify the API server, defaults to the service account CA
+type k8sSecretBackend struct {
+	apiServer string
+	tokenFile string
+	client    *http.Client
+}
+
+func newK8sSecretBackend(config map[string]interface{}) (nativeBackend, error) {
+	apiServer := getConfigString(config, "api_server", "", "")
+	if apiServer == "" {
+		host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+		if host == "" || port == "" {
+			return nil, fmt.Errorf("no api_server set and not running in a Kubernetes cluster")
+		}
+		apiServer = "https://" + net.JoinHostPort(host, port)
+	}
+
+	tlsConfig := &tls.Config{}
+	caFile := getConfigString(config, "ca_file", "", defaultK8sCAFile)
+	if ca, err := ioutil.ReadFile(caFile); err == nil {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("could not load the CA certificate from %s", caFile)
+		}
+		tlsConfig.RootCAs = pool
+	} else if caFile != defaultK8sCAFile {
+		return nil, fmt.Errorf("could not read the CA certificate: %s", err)
+	}
+
+	return &k8sSecretBackend{
+		apiServer: strings.TrimRight(apiServer, "/"),
+		tokenFile: getConfigString(config, "token_file", "", defaultK8sTokenFile),
+		client: &http.Client{
+			Timeout:   time.Duration(secretBackendTimeout) * time.Second,
+			Transport: &http.Transport{TLSClientConfig: tlsConfig},
+		},
+	}, nil
+}
+
+func (b *k8sSecretBackend) fetchSecrets(refs []string) (map[string]string, error) {
+	// read every secret once, even if several keys are referenced
+	secrets := make(map[string]map[string]string)
+	res := make(map[string]string)
+	for _, ref := range refs {
+		parts := strings.Split(ref, "/")
+		if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+			return nil, fmt.Errorf("invalid reference '%s', expected '<namespace>/<name>/<key>'", ref)
+		}
+		secretPath := parts[0] + "/" + parts[1]
+		data, ok := secrets[secretPath]
+		if !ok {
+			var err error
+			data, err = b.getSecret(parts[0], parts[1])
+			if err != nil {
+				return nil, fmt.Errorf("could not read secret '%s': %s", secretPath, err)
+			}
+			secrets[secretPath] = data
+		}
+		value, ok := data[parts[2]]
+		if !ok {
+			return nil, fmt.Errorf("key '%s' not found in secret '%s'", parts[2], secretPath)
+		}
+		res[ref] = value
+	}
+	return res, nil
+}
+
+// getSecret returns the decoded data of a secret
+func (b *k8sSecretBackend) getSecret(namespace, name string) (map[string]string, error) {
+	token, err := ioutil.ReadFile(b.tokenFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not read token file: %s", err)
+	}
+	endpoint := fmt.Sprintf("%s/api/v1/namespaces/%s/secrets/%s", b.apiServer, url.PathEscape(namespace), url.PathEscape(name))
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	req.Header.Set("Accept", "application/json")
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	var secret struct {
+		Data map[string]string `json:"data"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, int64(SecretBackendOutputMaxSize))).Decode(&secret); err != nil {
+		return nil, fmt.Errorf("could not decode response: %s", err)
+	}
+	data := make(map[string]string, len(secret.Data))
+	for key, encoded := range secret.Data {
+		value, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("could not decode key '%s': %s", key, err)
+		}
+		data[key] = string(value)
+	}
+	return data, nil
+}
+
+func (b *k8sSecretBackend) String() string {
+	return fmt.Sprintf("Kubernetes API server %s", b.apiServer)
+}
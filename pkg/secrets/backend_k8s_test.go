@@ -0,0 +1,92 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+// +build secrets
+
+package secrets
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestK8sSecretBackend(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("Authorization") != "Bearer my-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/api/v1/namespaces/default/secrets/db":
+			// "admin" and "p1" base64 encoded
+			w.Write([]byte(`{"kind":"Secret","data":{"user":"YWRtaW4=","password":"cDE="}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	f, err := ioutil.TempFile("", "k8s-token")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	require.NoError(t, ioutil.WriteFile(f.Name(), []byte("my-token\n"), 0600))
+
+	backend, err := newK8sSecretBackend(map[string]interface{}{
+		"api_server": server.URL,
+		"token_file": f.Name(),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Kubernetes API server "+server.URL, backend.String())
+
+	res, err := backend.fetchSecrets([]string{"default/db/user", "default/db/password"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"default/db/user": "admin", "default/db/password": "p1"}, res)
+	// every secret is read once
+	assert.Equal(t, 1, requests)
+
+	_, err = backend.fetchSecrets([]string{"default/db/missing"})
+	require.Error(t, err)
+	assert.Equal(t, "key 'missing' not found in secret 'default/db'", err.Error())
+
+	_, err = backend.fetchSecrets([]string{"default/unknown/user"})
+	require.Error(t, err)
+	assert.Equal(t, "could not read secret 'default/unknown': unexpected status code 404", err.Error())
+
+	_, err = backend.fetchSecrets([]string{"default/db"})
+	require.Error(t, err)
+	assert.Equal(t, "invalid reference 'default/db', expected '<namespace>/<name>/<key>'", err.Error())
+
+	// the token file is read before every request
+	require.NoError(t, ioutil.WriteFile(f.Name(), []byte("other-token"), 0600))
+	_, err = backend.fetchSecrets([]string{"default/db/user"})
+	require.Error(t, err)
+	assert.Equal(t, "could not read secret 'default/db': unexpected status code 401", err.Error())
+}
+
+func TestK8sSecretBackendConfig(t *testing.T) {
+	os.Unsetenv("KUBERNETES_SERVICE_HOST")
+	os.Unsetenv("KUBERNETES_SERVICE_PORT")
+	_, err := newK8sSecretBackend(map[string]interface{}{})
+	assert.Error(t, err)
+
+	os.Setenv("KUBERNETES_SERVICE_HOST", "10.0.0.1")
+	os.Setenv("KUBERNETES_SERVICE_PORT", "443")
+	defer os.Unsetenv("KUBERNETES_SERVICE_HOST")
+	defer os.Unsetenv("KUBERNETES_SERVICE_PORT")
+	backend, err := newK8sSecretBackend(map[string]interface{}{})
+	require.NoError(t, err)
+	assert.Equal(t, "Kubernetes API server https://10.0.0.1:443", backend.String())
+
+	_, err = newK8sSecretBackend(map[string]interface{}{"ca_file": "/does/not/exist"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,216 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+// +build secrets
+
+package secrets
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/DataDog/datadog-agent/pkg/util/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type dummyBackend struct {
+	secrets map[string]string
+	err     error
+	calls   [][]string
+}
+
+func (b *dummyBackend) fetchSecrets(refs []string) (map[string]string, error) {
+	b.calls = append(b.calls, refs)
+	if b.err != nil {
+		return nil, b.err
+	}
+	res := map[string]string{}
+	for _, ref := range refs {
+		if v, ok := b.secrets[ref]; ok {
+			res[ref] = v
+		}
+	}
+	return res, nil
+}
+
+func (b *dummyBackend) String() string {
+	return "dummy"
+}
+
+func resetNativeBackends() {
+	secretsLock.Lock()
+	defer secretsLock.Unlock()
+	nativeBackends = nil
+	nativeBackendErrors = nil
+	secretBackendCommand = ""
+	secretCache = map[string]string{}
+	secretOrigin = map[string]common.StringSet{}
+	secretErrors = map[string]string{}
+	runCommand = execCommand
+}
+
+func TestInitNativeBackends(t *testing.T) {
+	defer resetNativeBackends()
+
+	initNativeBackends(map[string]interface{}{
+		"file":    map[interface{}]interface{}{"root": "/run/secrets"},
+		"vault":   map[string]interface{}{},
+		"unknown": map[string]interface{}{},
+	})
+
+	require.Len(t, nativeBackends, 1)
+	assert.Equal(t, "files under /run/secrets", nativeBackends["file"].String())
+	require.Len(t, nativeBackendErrors, 1)
+	assert.Contains(t, nativeBackendErrors, "vault")
+	assert.True(t, nativeBackendsEnabled())
+
+	initNativeBackends(nil)
+	assert.False(t, nativeBackendsEnabled())
+}
+
+func TestSplitHandle(t *testing.T) {
+	defer resetNativeBackends()
+	nativeBackends = map[string]nativeBackend{"vault": &dummyBackend{}}
+	nativeBackendErrors = map[string]error{"file": fmt.Errorf("some error")}
+
+	tests := []struct {
+		handle string
+		name   string
+		ref    string
+	}{
+		{"vault:secret/data/db#password", "vault", "secret/data/db#password"},
+		{"file:/run/secrets/password", "file", "/run/secrets/password"},
+		{"k8s_secret:ns/name/key", "", "k8s_secret:ns/name/key"},
+		{"some:handle", "", "some:handle"},
+		{"password", "", "password"},
+	}
+	for _, test := range tests {
+		name, ref := splitHandle(test.handle)
+		assert.Equal(t, test.name, name, test.handle)
+		assert.Equal(t, test.ref, ref, test.handle)
+	}
+}
+
+func TestSplitReference(t *testing.T) {
+	path, key := splitReference("secret/data/db#password")
+	assert.Equal(t, "secret/data/db", path)
+	assert.Equal(t, "password", key)
+
+	path, key = splitReference("my-secret")
+	assert.Equal(t, "my-secret", path)
+	assert.Equal(t, "", key)
+}
+
+func TestFetchNativeSecrets(t *testing.T) {
+	defer resetNativeBackends()
+	backend := &dummyBackend{secrets: map[string]string{
+		"db#user":     "admin",
+		"db#password": "p1",
+		"empty":       "",
+	}}
+	nativeBackends = map[string]nativeBackend{"dummy": backend}
+	nativeBackendErrors = map[string]error{"broken": fmt.Errorf("some error")}
+
+	res, err := fetchNativeSecrets([]string{"dummy:db#user", "dummy:db#password"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"dummy:db#user": "admin", "dummy:db#password": "p1"}, res)
+	// all the references of a backend are fetched at once
+	assert.Equal(t, [][]string{{"db#user", "db#password"}}, backend.calls)
+
+	_, err = fetchNativeSecrets([]string{"dummy:unknown"})
+	require.Error(t, err)
+	assert.Equal(t, "secret handle 'dummy:unknown' was not resolved by the secret backend", err.Error())
+
+	_, err = fetchNativeSecrets([]string{"dummy:empty"})
+	require.Error(t, err)
+	assert.Equal(t, "decrypted secret for 'dummy:empty' is empty", err.Error())
+
+	_, err = fetchNativeSecrets([]string{"broken:something"})
+	require.Error(t, err)
+	assert.Equal(t, "secret backend 'broken' is not configured properly: some error", err.Error())
+
+	backend.err = fmt.Errorf("backend error")
+	_, err = fetchNativeSecrets([]string{"dummy:db#user"})
+	require.Error(t, err)
+	assert.Equal(t, "secret backend 'dummy': backend error", err.Error())
+}
+
+func TestDecryptNativeAndCommand(t *testing.T) {
+	defer resetNativeBackends()
+	nativeBackends = map[string]nativeBackend{"dummy": &dummyBackend{secrets: map[string]string{"db#password": "native_password"}}}
+	secretBackendCommand = "some_command"
+
+	var payload string
+	runCommand = func(in string) ([]byte, error) {
+		payload = in
+		return []byte("{\"pass1\":{\"value\":\"command_password\"}}"), nil
+	}
+
+	conf := []byte(`---
+instances:
+- password: ENC[dummy:db#password]
+- password: ENC[pass1]
+`)
+	newConf, err := Decrypt(conf, "test")
+	require.NoError(t, err)
+	assert.Equal(t, `instances:
+- password: native_password
+- password: command_password
+`, string(newConf))
+
+	// only the handles without a native backend prefix are sent to the command
+	assert.Contains(t, payload, `"secrets":["pass1"]`)
+	assert.Equal(t, map[string]string{"dummy:db#password": "native_password", "pass1": "command_password"}, secretCache)
+	assert.Equal(t, common.NewStringSet("test"), secretOrigin["dummy:db#password"])
+}
+
+func TestDecryptNativeOnly(t *testing.T) {
+	defer resetNativeBackends()
+	nativeBackends = map[string]nativeBackend{"dummy": &dummyBackend{secrets: map[string]string{"password": "p1"}}}
+
+	newConf, err := Decrypt([]byte("password: ENC[dummy:password]\n"), "test")
+	require.NoError(t, err)
+	assert.Equal(t, "password: p1\n", string(newConf))
+
+	_, err = Decrypt([]byte("password: ENC[other]\n"), "test")
+	require.Error(t, err)
+	assert.Equal(t, "secret handle 'other' does not match any configured secret backend and no secret_backend_command is set", err.Error())
+}
+
+func TestGetDebugInfoNativeBackends(t *testing.T) {
+	defer resetNativeBackends()
+	nativeBackends = map[string]nativeBackend{"dummy": &dummyBackend{err: fmt.Errorf("permission denied")}}
+	nativeBackendErrors = map[string]error{"vault": fmt.Errorf("no Vault address set")}
+
+	_, err := Decrypt([]byte("password: ENC[dummy:password]\n"), "test")
+	require.Error(t, err)
+
+	info, err := GetDebugInfo()
+	require.NoError(t, err)
+	assert.Equal(t, "", info.ExecutablePath)
+	assert.Equal(t, map[string]string{
+		"dummy": "dummy",
+		"vault": "error: no Vault address set",
+	}, info.Backends)
+	assert.Equal(t, map[string]string{
+		"dummy:password": "secret backend 'dummy': permission denied",
+	}, info.SecretsErrors)
+
+	var b strings.Builder
+	info.Print(&b)
+	assert.NotContains(t, b.String(), "Checking executable rights")
+	assert.Contains(t, b.String(), "- vault: error: no Vault address set\n")
+	assert.Contains(t, b.String(), "- dummy:password: secret backend 'dummy': permission denied\n")
+
+	// errors are cleared once the handle is fetched
+	nativeBackends["dummy"] = &dummyBackend{secrets: map[string]string{"password": "p1"}}
+	_, err = Decrypt([]byte("password: ENC[dummy:password]\n"), "test")
+	require.NoError(t, err)
+	info, err = GetDebugInfo()
+	require.NoError(t, err)
+	assert.Empty(t, info.SecretsErrors)
+}
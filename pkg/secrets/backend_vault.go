@@ -0,0 +1,153 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+// +build secrets
+
+package secrets
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// vaultBackend reads secrets from HashiCorp Vault through its HTTP API. Handles are formatted
+// as "vault:<path>#<key>", e.g. "vault:secret/data/db#password". Both versions of the KV
+// secrets engine are supported, the path of KV version 2 secrets must contain "/data/".
+//
+// Options:
+//   address:    address of the Vault server, defaults to $VAULT_ADDR
+//   token:      token used to authenticate, defaults to $VAULT_TOKEN
+//   token_file: file containing the token, read before every request, e.g. written by the Vault agent
+//   namespace:  Vault Enterprise namespace, defaults to $VAULT_NAMESPACE
+type vaultBackend struct {
+	address   string
+	token     string
+	tokenFile string
+	namespace string
+	client    *http.Client
+}
+
+func newVaultBackend(config map[string]interface{}) (nativeBackend, error) {
+	b := &vaultBackend{
+		address:   strings.TrimRight(getConfigString(config, "address", "VAULT_ADDR", ""), "/"),
+		token:     getConfigString(config, "token", "VAULT_TOKEN", ""),
+		tokenFile: getConfigString(config, "token_file", "", ""),
+		namespace: getConfigString(config, "namespace", "VAULT_NAMESPACE", ""),
+		client:    &http.Client{Timeout: time.Duration(secretBackendTimeout) * time.Second},
+	}
+	if b.address == "" {
+		return nil, fmt.Errorf("no Vault address set")
+	}
+	if b.token == "" && b.tokenFile == "" {
+		return nil, fmt.Errorf("no Vault token or token_file set")
+	}
+	return b, nil
+}
+
+func (b *vaultBackend) fetchSecrets(refs []string) (map[string]string, error) {
+	// read every path once, even if several keys are referenced
+	keysByPath := make(map[string][]string)
+	for _, ref := range refs {
+		path, key := splitReference(ref)
+		if path == "" || key == "" {
+			return nil, fmt.Errorf("invalid reference '%s', expected '<path>#<key>'", ref)
+		}
+		keysByPath[path] = append(keysByPath[path], key)
+	}
+
+	res := make(map[string]string)
+	for path, keys := range keysByPath {
+		data, err := b.read(path)
+		if err != nil {
+			return nil, fmt.Errorf("could not read '%s': %s", path, err)
+		}
+		for _, key := range keys {
+			value, ok := data[key]
+			if !ok {
+				return nil, fmt.Errorf("key '%s' not found in '%s'", key, path)
+			}
+			res[path+"#"+key] = stringValue(value)
+		}
+	}
+	return res, nil
+}
+
+// vaultResponse is the response of Vault to read requests
+type vaultResponse struct {
+	Data   map[string]interface{} `json:"data"`
+	Errors []string               `json:"errors"`
+}
+
+// read returns the data stored at path, unwrapping the KV version 2 envelope
+func (b *vaultBackend) read(path string) (map[string]interface{}, error) {
+	token, err := b.getToken()
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodGet, b.address+"/v1/"+strings.TrimLeft(path, "/"), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", token)
+	if b.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", b.namespace)
+	}
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var body vaultResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, int64(SecretBackendOutputMaxSize))).Decode(&body); err != nil && resp.StatusCode == http.StatusOK {
+		return nil, fmt.Errorf("could not decode response: %s", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		if len(body.Errors) > 0 {
+			return nil, fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, strings.Join(body.Errors, ", "))
+		}
+		return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	// KV version 2 secrets are wrapped along with their metadata
+	if inner, ok := body.Data["data"].(map[string]interface{}); ok {
+		if _, ok := body.Data["metadata"]; ok {
+			return inner, nil
+		}
+	}
+	return body.Data, nil
+}
+
+func (b *vaultBackend) getToken() (string, error) {
+	if b.tokenFile == "" {
+		return b.token, nil
+	}
+	token, err := ioutil.ReadFile(b.tokenFile)
+	if err != nil {
+		return "", fmt.Errorf("could not read token file: %s", err)
+	}
+	return strings.TrimSpace(string(token)), nil
+}
+
+func (b *vaultBackend) String() string {
+	return fmt.Sprintf("Vault server %s", b.address)
+}
+
+// stringValue returns the value as is if it is a string, JSON encoded otherwise
+func stringValue(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(encoded)
+}
@@ -0,0 +1,125 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+// +build secrets
+
+package secrets
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newVaultServer returns a server mimicking the Vault HTTP API for a KV version 1
+// secret at "kv/db" and a KV version 2 secret at "secret/data/db"
+func newVaultServer(t *testing.T, token string) (*httptest.Server, map[string]int) {
+	requests := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests[r.URL.Path]++
+		assert.Equal(t, "my-namespace", r.Header.Get("X-Vault-Namespace"))
+		if r.Header.Get("X-Vault-Token") != token {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/kv/db":
+			w.Write([]byte(`{"data":{"user":"admin","port":5432}}`))
+		case "/v1/secret/data/db":
+			w.Write([]byte(`{"data":{"data":{"user":"admin2","password":"p2"},"metadata":{"version":3}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"errors":[]}`))
+		}
+	}))
+	return server, requests
+}
+
+func TestVaultBackend(t *testing.T) {
+	server, requests := newVaultServer(t, "my-token")
+	defer server.Close()
+
+	backend, err := newVaultBackend(map[string]interface{}{
+		"address":   server.URL + "/",
+		"token":     "my-token",
+		"namespace": "my-namespace",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Vault server "+server.URL, backend.String())
+
+	res, err := backend.fetchSecrets([]string{"kv/db#user", "kv/db#port", "secret/data/db#user", "secret/data/db#password"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"kv/db#user":              "admin",
+		"kv/db#port":              "5432",
+		"secret/data/db#user":     "admin2",
+		"secret/data/db#password": "p2",
+	}, res)
+	// every path is read once
+	assert.Equal(t, map[string]int{"/v1/kv/db": 1, "/v1/secret/data/db": 1}, requests)
+
+	_, err = backend.fetchSecrets([]string{"kv/db#missing"})
+	require.Error(t, err)
+	assert.Equal(t, "key 'missing' not found in 'kv/db'", err.Error())
+
+	_, err = backend.fetchSecrets([]string{"kv/unknown#user"})
+	require.Error(t, err)
+	assert.Equal(t, "could not read 'kv/unknown': unexpected status code 404", err.Error())
+
+	_, err = backend.fetchSecrets([]string{"kv/db"})
+	require.Error(t, err)
+	assert.Equal(t, "invalid reference 'kv/db', expected '<path>#<key>'", err.Error())
+}
+
+func TestVaultBackendTokenFile(t *testing.T) {
+	server, _ := newVaultServer(t, "my-token")
+	defer server.Close()
+
+	f, err := ioutil.TempFile("", "vault-token")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	require.NoError(t, ioutil.WriteFile(f.Name(), []byte("wrong-token\n"), 0600))
+
+	backend, err := newVaultBackend(map[string]interface{}{
+		"address":    server.URL,
+		"token_file": f.Name(),
+		"namespace":  "my-namespace",
+	})
+	require.NoError(t, err)
+
+	_, err = backend.fetchSecrets([]string{"kv/db#user"})
+	require.Error(t, err)
+	assert.Equal(t, "could not read 'kv/db': unexpected status code 403: permission denied", err.Error())
+
+	// the token file is read before every request
+	require.NoError(t, ioutil.WriteFile(f.Name(), []byte("my-token\n"), 0600))
+	res, err := backend.fetchSecrets([]string{"kv/db#user"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"kv/db#user": "admin"}, res)
+}
+
+func TestVaultBackendConfig(t *testing.T) {
+	os.Unsetenv("VAULT_ADDR")
+	os.Unsetenv("VAULT_TOKEN")
+
+	_, err := newVaultBackend(map[string]interface{}{"token": "my-token"})
+	assert.Error(t, err)
+	_, err = newVaultBackend(map[string]interface{}{"address": "http://127.0.0.1:8200"})
+	assert.Error(t, err)
+
+	os.Setenv("VAULT_ADDR", "http://127.0.0.1:8200")
+	os.Setenv("VAULT_TOKEN", "my-token")
+	defer os.Unsetenv("VAULT_ADDR")
+	defer os.Unsetenv("VAULT_TOKEN")
+	backend, err := newVaultBackend(map[string]interface{}{})
+	require.NoError(t, err)
+	assert.Equal(t, "Vault server http://127.0.0.1:8200", backend.String())
+}
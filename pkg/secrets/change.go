@@ -0,0 +1,19 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package secrets
+
+// SecretChange describes a secret whose value changed when the secrets were refreshed
+type SecretChange struct {
+	Handle string
+	// Origins are the names of the configurations where the handle was found
+	Origins  []string
+	OldValue string
+	NewValue string
+}
+
+// RefreshCallback is called with the secrets whose value changed when the
+// secrets are refreshed
+type RefreshCallback func(changes []SecretChange)
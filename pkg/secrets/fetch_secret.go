@@ -100,10 +100,78 @@ type Secret struct {
 // for testing purpose
 var runCommand = execCommand
 
-// fetchSecret receives a list of secrets name to fetch, exec a custom
-// executable to fetch the actual secrets and returns them. Origin should be
-// the name of the configuration where the secret was referenced.
+// fetchSecret receives a list of secrets name to fetch, resolves them with
+// the native backends they are prefixed with or by executing the
+// secret_backend_command, and returns them. Origin should be the name of the
+// configuration where the secret was referenced.
 func fetchSecret(secretsHandle []string, origin string) (map[string]string, error) {
+	res, failedHandles, err := resolveSecrets(secretsHandle)
+	if err != nil {
+		recordSecretErrors(failedHandles, err)
+		return nil, err
+	}
+
+	for handle, value := range res {
+		// add it to the cache
+		secretCache[handle] = value
+		// keep track of place where a handle was found
+		secretOrigin[handle] = common.NewStringSet(origin)
+		delete(secretErrors, handle)
+	}
+	return res, nil
+}
+
+// resolveSecrets returns the value of every handle. It doesn't access the cache nor the
+// errors of the secrets so that it can be called without holding secretsLock. On error,
+// it also returns the handles which could not be resolved.
+func resolveSecrets(secretsHandle []string) (map[string]string, []string, error) {
+	nativeHandles := []string{}
+	commandHandles := []string{}
+	for _, handle := range secretsHandle {
+		if name, _ := splitHandle(handle); name != "" {
+			nativeHandles = append(nativeHandles, handle)
+		} else {
+			commandHandles = append(commandHandles, handle)
+		}
+	}
+
+	res := map[string]string{}
+	if len(nativeHandles) != 0 {
+		secrets, err := fetchNativeSecrets(nativeHandles)
+		if err != nil {
+			return nil, nativeHandles, err
+		}
+		for handle, value := range secrets {
+			res[handle] = value
+		}
+	}
+	if len(commandHandles) != 0 {
+		if secretBackendCommand == "" && nativeBackendsEnabled() {
+			err := fmt.Errorf("secret handle '%s' does not match any configured secret backend and no secret_backend_command is set", commandHandles[0])
+			return nil, commandHandles, err
+		}
+		secrets, err := fetchSecretFromCommand(commandHandles)
+		if err != nil {
+			return nil, commandHandles, err
+		}
+		for handle, value := range secrets {
+			res[handle] = value
+		}
+	}
+	return res, nil, nil
+}
+
+// recordSecretErrors keeps track of the last error met for every handle so
+// that it can be reported by the 'secret' command.
+func recordSecretErrors(handles []string, err error) {
+	for _, handle := range handles {
+		secretErrors[handle] = err.Error()
+	}
+}
+
+// fetchSecretFromCommand exec the secret_backend_command to fetch the
+// actual secrets and returns them.
+func fetchSecretFromCommand(secretsHandle []string) (map[string]string, error) {
 	payload := map[string]interface{}{
 		"version": PayloadVersion,
 		"secrets": secretsHandle,
@@ -138,10 +206,6 @@ func fetchSecret(secretsHandle []string, origin string) (map[string]string, erro
 			return nil, fmt.Errorf("decrypted secret for '%s' is empty", sec)
 		}
 
-		// add it to the cache
-		secretCache[sec] = v.Value
-		// keep track of place where a handle was found
-		secretOrigin[sec] = common.NewStringSet(origin)
 		res[sec] = v.Value
 	}
 	return res, nil
@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"io"
 	"runtime"
+	"sort"
 	"strings"
 )
 
@@ -20,27 +21,58 @@ type SecretInfo struct {
 	UnixOwner      string
 	UnixGroup      string
 	SecretsHandles map[string][]string
+	// Backends describes the native backends by name
+	Backends map[string]string
+	// SecretsErrors holds the last error met while fetching a handle
+	SecretsErrors map[string]string
 }
 
 // Print output a SecretInfo to a io.Writer
 func (si *SecretInfo) Print(w io.Writer) {
-	fmt.Fprintf(w, "=== Checking executable rights ===\n")
-	fmt.Fprintf(w, "Executable path: %s\n", si.ExecutablePath)
+	if si.ExecutablePath != "" {
+		fmt.Fprintf(w, "=== Checking executable rights ===\n")
+		fmt.Fprintf(w, "Executable path: %s\n", si.ExecutablePath)
 
-	fmt.Fprintf(w, "Check Rights: %s\n", si.Rights)
+		fmt.Fprintf(w, "Check Rights: %s\n", si.Rights)
 
-	fmt.Fprintf(w, "\nRights Detail:\n")
-	fmt.Fprintf(w, "%s\n", si.RightDetails)
+		fmt.Fprintf(w, "\nRights Detail:\n")
+		fmt.Fprintf(w, "%s\n", si.RightDetails)
 
-	if runtime.GOOS != "windows" {
-		fmt.Fprintf(w, "Owner username: %s\n", si.UnixOwner)
-		fmt.Fprintf(w, "Group name: %s\n", si.UnixGroup)
+		if runtime.GOOS != "windows" {
+			fmt.Fprintf(w, "Owner username: %s\n", si.UnixOwner)
+			fmt.Fprintf(w, "Group name: %s\n", si.UnixGroup)
+		}
+		fmt.Fprintf(w, "\n")
 	}
 
-	fmt.Fprintf(w, "\n=== Secrets stats ===\n")
+	if len(si.Backends) != 0 {
+		fmt.Fprintf(w, "=== Secret backends ===\n")
+		for _, name := range sortedKeys(si.Backends) {
+			fmt.Fprintf(w, "- %s: %s\n", name, si.Backends[name])
+		}
+		fmt.Fprintf(w, "\n")
+	}
+
+	fmt.Fprintf(w, "=== Secrets stats ===\n")
 	fmt.Fprintf(w, "Number of secrets decrypted: %d\n", len(si.SecretsHandles))
 	fmt.Fprintf(w, "Secrets handle decrypted:\n")
 	for handle, origins := range si.SecretsHandles {
 		fmt.Fprintf(w, "- %s: from %s\n", handle, strings.Join(origins, ", "))
 	}
+
+	if len(si.SecretsErrors) != 0 {
+		fmt.Fprintf(w, "\nSecrets handle in error:\n")
+		for _, handle := range sortedKeys(si.SecretsErrors) {
+			fmt.Fprintf(w, "- %s: %s\n", handle, si.SecretsErrors[handle])
+		}
+	}
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
 }
@@ -11,13 +11,14 @@ package secrets
 
 import (
 	"fmt"
+	"time"
 )
 
 // SecretBackendOutputMaxSize defines max size of the JSON output from a secrets reader backend
 var SecretBackendOutputMaxSize = 1024 * 1024
 
 // Init placeholder when compiled without the 'secrets' build tag
-func Init(command string, arguments []string, timeout int, maxSize int, groupExecPerm bool) {}
+func Init(command string, arguments []string, timeout int, maxSize int, groupExecPerm bool, backendsConfig map[string]interface{}) {}
 
 // Decrypt encrypted secrets are not available on windows
 func Decrypt(data []byte, origin string) ([]byte, error) {
@@ -28,3 +29,12 @@ func Decrypt(data []byte, origin string) ([]byte, error) {
 func GetDebugInfo() (*SecretInfo, error) {
 	return nil, fmt.Errorf("Secret feature is not available in this version of the agent")
 }
+
+// RegisterRefreshCallback placeholder when compiled without the 'secrets' build tag
+func RegisterRefreshCallback(callback RefreshCallback) {}
+
+// StartRefresh placeholder when compiled without the 'secrets' build tag
+func StartRefresh(interval time.Duration) {}
+
+// StopRefresh placeholder when compiled without the 'secrets' build tag
+func StopRefresh() {}
@@ -0,0 +1,134 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+// +build secrets
+
+package secrets
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+var (
+	refreshCallbacks []RefreshCallback
+	refreshStop      chan struct{}
+	refreshLock      sync.Mutex
+)
+
+// RegisterRefreshCallback registers a callback to be notified of the secrets whose
+// value changed when the secrets are refreshed.
+func RegisterRefreshCallback(callback RefreshCallback) {
+	refreshLock.Lock()
+	defer refreshLock.Unlock()
+	refreshCallbacks = append(refreshCallbacks, callback)
+}
+
+// StartRefresh starts resolving again every decrypted secret at the given interval,
+// the registered callbacks being notified of the secrets whose value changed.
+func StartRefresh(interval time.Duration) {
+	refreshLock.Lock()
+	defer refreshLock.Unlock()
+
+	if interval <= 0 || refreshStop != nil {
+		return
+	}
+	log.Infof("Refreshing secrets every %s", interval)
+	refreshStop = make(chan struct{})
+	go refreshLoop(interval, refreshStop)
+}
+
+// StopRefresh stops the refresh of the secrets
+func StopRefresh() {
+	refreshLock.Lock()
+	defer refreshLock.Unlock()
+
+	if refreshStop != nil {
+		close(refreshStop)
+		refreshStop = nil
+	}
+}
+
+func refreshLoop(interval time.Duration, stop chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			changes := refreshSecrets()
+			if len(changes) != 0 {
+				notifyRefreshCallbacks(changes)
+			}
+		}
+	}
+}
+
+// refreshSecrets resolves again every secret in the cache, updates the cache and
+// returns the secrets whose value changed. The previous values are kept if the
+// secrets can not be resolved. The secrets are resolved without holding secretsLock,
+// so that the configurations can still be decrypted while the backends are queried.
+func refreshSecrets() []SecretChange {
+	secretsLock.Lock()
+	handles := make([]string, 0, len(secretCache))
+	for handle := range secretCache {
+		handles = append(handles, handle)
+	}
+	secretsLock.Unlock()
+	if len(handles) == 0 {
+		return nil
+	}
+	sort.Strings(handles)
+
+	secrets, failedHandles, err := resolveSecrets(handles)
+
+	secretsLock.Lock()
+	defer secretsLock.Unlock()
+	if err != nil {
+		recordSecretErrors(failedHandles, err)
+		log.Errorf("Could not refresh secrets, keeping the previous values: %s", err)
+		return nil
+	}
+
+	changes := []SecretChange{}
+	for _, handle := range handles {
+		delete(secretErrors, handle)
+		oldValue, newValue := secretCache[handle], secrets[handle]
+		if oldValue == newValue {
+			continue
+		}
+		log.Infof("Secret '%s' changed", handle)
+		secretCache[handle] = newValue
+
+		var origins []string
+		if originNames, ok := secretOrigin[handle]; ok {
+			origins = originNames.GetAll()
+			sort.Strings(origins)
+		}
+		changes = append(changes, SecretChange{
+			Handle:   handle,
+			Origins:  origins,
+			OldValue: oldValue,
+			NewValue: newValue,
+		})
+	}
+	return changes
+}
+
+func notifyRefreshCallbacks(changes []SecretChange) {
+	refreshLock.Lock()
+	callbacks := make([]RefreshCallback, len(refreshCallbacks))
+	copy(callbacks, refreshCallbacks)
+	refreshLock.Unlock()
+
+	for _, callback := range callbacks {
+		callback(changes)
+	}
+}
@@ -0,0 +1,123 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+// +build secrets
+
+package secrets
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefreshSecrets(t *testing.T) {
+	defer resetNativeBackends()
+	backend := &dummyBackend{secrets: map[string]string{"api_key": "key1", "password": "p1"}}
+	nativeBackends = map[string]nativeBackend{"dummy": backend}
+
+	_, err := Decrypt([]byte("api_key: ENC[dummy:api_key]\n"), "datadog.yaml")
+	require.NoError(t, err)
+	_, err = Decrypt([]byte("password: ENC[dummy:password]\n"), "postgres")
+	require.NoError(t, err)
+	_, err = Decrypt([]byte("password: ENC[dummy:password]\n"), "mysql")
+	require.NoError(t, err)
+
+	// nothing changed
+	assert.Empty(t, refreshSecrets())
+
+	backend.secrets["password"] = "p2"
+	changes := refreshSecrets()
+	assert.Equal(t, []SecretChange{{
+		Handle:   "dummy:password",
+		Origins:  []string{"mysql", "postgres"},
+		OldValue: "p1",
+		NewValue: "p2",
+	}}, changes)
+
+	// the cache is updated with the new values
+	conf, err := Decrypt([]byte("password: ENC[dummy:password]\n"), "postgres")
+	require.NoError(t, err)
+	assert.Equal(t, "password: p2\n", string(conf))
+	assert.Empty(t, refreshSecrets())
+
+	// previous values are kept on errors
+	backend.err = fmt.Errorf("some error")
+	assert.Empty(t, refreshSecrets())
+	assert.Equal(t, map[string]string{"dummy:api_key": "key1", "dummy:password": "p2"}, secretCache)
+}
+
+func TestRefreshRoutine(t *testing.T) {
+	defer resetNativeBackends()
+	defer func() { refreshCallbacks = nil }()
+	backend := &dummyBackend{secrets: map[string]string{"api_key": "key1"}}
+	nativeBackends = map[string]nativeBackend{"dummy": backend}
+
+	_, err := Decrypt([]byte("api_key: ENC[dummy:api_key]\n"), "datadog.yaml")
+	require.NoError(t, err)
+
+	notified := make(chan []SecretChange, 10)
+	RegisterRefreshCallback(func(changes []SecretChange) { notified <- changes })
+
+	backend.secrets["api_key"] = "key2"
+
+	StartRefresh(10 * time.Millisecond)
+	defer StopRefresh()
+
+	select {
+	case changes := <-notified:
+		require.Len(t, changes, 1)
+		assert.Equal(t, "key1", changes[0].OldValue)
+		assert.Equal(t, "key2", changes[0].NewValue)
+		assert.Equal(t, []string{"datadog.yaml"}, changes[0].Origins)
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "secrets were not refreshed")
+	}
+}
+
+// blockingBackend blocks in fetchSecrets until it is released.
+type blockingBackend struct {
+	dummyBackend
+	fetching chan struct{}
+	release  chan struct{}
+}
+
+func (b *blockingBackend) fetchSecrets(refs []string) (map[string]string, error) {
+	b.fetching <- struct{}{}
+	<-b.release
+	return b.dummyBackend.fetchSecrets(refs)
+}
+
+func TestRefreshSecretsDoesNotBlockDecrypt(t *testing.T) {
+	defer resetNativeBackends()
+	dummy := &dummyBackend{secrets: map[string]string{"password": "p1"}}
+	nativeBackends = map[string]nativeBackend{"dummy": dummy}
+	_, err := Decrypt([]byte("password: ENC[dummy:password]\n"), "postgres")
+	require.NoError(t, err)
+
+	backend := &blockingBackend{
+		dummyBackend: dummyBackend{secrets: map[string]string{"password": "p2"}},
+		fetching:     make(chan struct{}),
+		release:      make(chan struct{}),
+	}
+	nativeBackends = map[string]nativeBackend{"dummy": backend}
+
+	done := make(chan []SecretChange)
+	go func() { done <- refreshSecrets() }()
+	<-backend.fetching
+
+	// the cached secrets can be decrypted while the backend is queried
+	conf, err := Decrypt([]byte("password: ENC[dummy:password]\n"), "postgres")
+	require.NoError(t, err)
+	assert.Equal(t, "password: p1\n", string(conf))
+
+	close(backend.release)
+	changes := <-done
+	require.Len(t, changes, 1)
+	assert.Equal(t, "p2", changes[0].NewValue)
+}
@@ -10,6 +10,7 @@ package secrets
 import (
 	"fmt"
 	"strings"
+	"sync"
 
 	yaml "gopkg.in/yaml.v2"
 
@@ -18,9 +19,15 @@ import (
 )
 
 var (
+	// secretsLock protects the cache, the origins and the errors of the secrets
+	// which are accessed by Decrypt and by the refresh routine
+	secretsLock sync.Mutex
+
 	secretCache map[string]string
 	// list of handles and where they were found
 	secretOrigin map[string]common.StringSet
+	// last error met while fetching a handle
+	secretErrors map[string]string
 
 	secretBackendCommand               string
 	secretBackendArguments             []string
@@ -34,12 +41,14 @@ var (
 func init() {
 	secretCache = make(map[string]string)
 	secretOrigin = make(map[string]common.StringSet)
+	secretErrors = make(map[string]string)
 }
 
 // Init initializes the command and other options of the secrets package. Since
 // this package is used by the 'config' package to decrypt itself we can't
-// directly use it.
-func Init(command string, arguments []string, timeout int, maxSize int, groupExecPerm bool) {
+// directly use it. backendsConfig holds the configuration of the native
+// backends by name, as found in the 'secret_backends' setting.
+func Init(command string, arguments []string, timeout int, maxSize int, groupExecPerm bool, backendsConfig map[string]interface{}) {
 	secretBackendCommand = command
 	secretBackendArguments = arguments
 	secretBackendTimeout = timeout
@@ -48,6 +57,7 @@ func Init(command string, arguments []string, timeout int, maxSize int, groupExe
 	if secretBackendCommandAllowGroupExec {
 		log.Warnf("Agent configuration relax permissions constraint on the secret backend cmd, Group can read and exec")
 	}
+	initNativeBackends(backendsConfig)
 }
 
 type walkerCallback func(string) (string, error)
@@ -126,10 +136,11 @@ func isEnc(str string) (bool, string) {
 // testing purpose
 var secretFetcher = fetchSecret
 
-// Decrypt replaces all encrypted secrets in data by executing
-// "secret_backend_command" once if all secrets aren't present in the cache.
+// Decrypt replaces all encrypted secrets in data by calling the native backends
+// or executing "secret_backend_command" once if all secrets aren't present in
+// the cache.
 func Decrypt(data []byte, origin string) ([]byte, error) {
-	if data == nil || secretBackendCommand == "" {
+	if data == nil || (secretBackendCommand == "" && !nativeBackendsEnabled()) {
 		return data, nil
 	}
 
@@ -139,6 +150,9 @@ func Decrypt(data []byte, origin string) ([]byte, error) {
 		return nil, fmt.Errorf("could not Unmarshal config: %s", err)
 	}
 
+	secretsLock.Lock()
+	defer secretsLock.Unlock()
+
 	// First we collect all new handles in the config
 	newHandles := []string{}
 	haveSecret := false
@@ -176,7 +190,7 @@ func Decrypt(data []byte, origin string) ([]byte, error) {
 		err = walk(&config, func(str string) (string, error) {
 			if ok, handle := isEnc(str); ok {
 				if secret, ok := secrets[handle]; ok {
-					log.Debugf("Secret '%s' was retrieved from executable", handle)
+					log.Debugf("Secret '%s' was retrieved from backend", handle)
 					return secret, nil
 				}
 				// This should never happen since fetchSecret will return an error
@@ -199,11 +213,29 @@ func Decrypt(data []byte, origin string) ([]byte, error) {
 
 // GetDebugInfo exposes debug informations about secrets to be included in a flare
 func GetDebugInfo() (*SecretInfo, error) {
-	if secretBackendCommand == "" {
-		return nil, fmt.Errorf("No secret_backend_command set: secrets feature is not enabled")
+	if secretBackendCommand == "" && !nativeBackendsEnabled() {
+		return nil, fmt.Errorf("No secret_backend_command or secret_backends set: secrets feature is not enabled")
 	}
+	secretsLock.Lock()
+	defer secretsLock.Unlock()
+
 	info := &SecretInfo{ExecutablePath: secretBackendCommand}
-	info.populateRights()
+	if secretBackendCommand != "" {
+		info.populateRights()
+	}
+
+	info.Backends = map[string]string{}
+	for name, backend := range nativeBackends {
+		info.Backends[name] = backend.String()
+	}
+	for name, err := range nativeBackendErrors {
+		info.Backends[name] = fmt.Sprintf("error: %s", err)
+	}
+
+	info.SecretsErrors = map[string]string{}
+	for handle, err := range secretErrors {
+		info.SecretsErrors[handle] = err
+	}
 
 	info.SecretsHandles = map[string][]string{}
 	for handle, originNames := range secretOrigin {
@@ -13,10 +13,17 @@ import (
 )
 
 func validateCredentials(p *gosnmp.SnmpPacket, c *Config) error {
-	if p.Version != gosnmp.Version2c {
+	switch p.Version {
+	case gosnmp.Version1, gosnmp.Version2c:
+		return validateCommunityString(p, c)
+	case gosnmp.Version3:
+		return validateUser(p, c)
+	default:
 		return fmt.Errorf("Unsupported version: %s", p.Version)
 	}
+}
 
+func validateCommunityString(p *gosnmp.SnmpPacket, c *Config) error {
 	// At least one of the known community strings must match.
 	for _, community := range c.CommunityStrings {
 		if community == p.Community {
@@ -26,3 +33,33 @@ func validateCredentials(p *gosnmp.SnmpPacket, c *Config) error {
 
 	return errors.New("Unknown community string")
 }
+
+func validateUser(p *gosnmp.SnmpPacket, c *Config) error {
+	/*
+		GoSNMP has already authenticated and decrypted the packet using the keys of the first configured user
+		with this name whose security level is met by the packet, but it lets through packets whose security
+		level is lower than the one of the user (eg noAuthNoPriv packets when the user requires authPriv).
+		Several users may share the same name, with different engine IDs or protocols, so all of them are
+		checked.
+	*/
+	securityParams, ok := p.SecurityParameters.(*gosnmp.UsmSecurityParameters)
+	if !ok {
+		return errors.New("Unsupported security model")
+	}
+
+	found := false
+	for _, user := range c.Users {
+		if user.Username != securityParams.UserName {
+			continue
+		}
+		if p.MsgFlags&gosnmp.AuthPriv >= user.securityLevel() {
+			return nil
+		}
+		found = true
+	}
+	if found {
+		return fmt.Errorf("Security level too low for user %s", securityParams.UserName)
+	}
+
+	return errors.New("Unknown user")
+}
@@ -6,8 +6,12 @@
 package traps
 
 import (
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"path/filepath"
+	"strings"
+
 	"github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/soniah/gosnmp"
 )
@@ -17,13 +21,25 @@ func IsEnabled() bool {
 	return config.Datadog.GetBool("snmp_traps_enabled")
 }
 
+// UserV3 contains the definition of an SNMPv3 USM user allowed to send traps.
+type UserV3 struct {
+	Username     string `mapstructure:"user" yaml:"user"`
+	EngineID     string `mapstructure:"engine_id" yaml:"engine_id"`
+	AuthProtocol string `mapstructure:"auth_protocol" yaml:"auth_protocol"`
+	AuthKey      string `mapstructure:"auth_key" yaml:"auth_key"`
+	PrivProtocol string `mapstructure:"priv_protocol" yaml:"priv_protocol"`
+	PrivKey      string `mapstructure:"priv_key" yaml:"priv_key"`
+}
+
 // Config contains configuration for SNMP trap listeners.
 // YAML field tags provided for test marshalling purposes.
 type Config struct {
 	Port             uint16   `mapstructure:"port" yaml:"port"`
 	CommunityStrings []string `mapstructure:"community_strings" yaml:"community_strings"`
+	Users            []UserV3 `mapstructure:"users" yaml:"users"`
 	BindHost         string   `mapstructure:"bind_host" yaml:"bind_host"`
 	StopTimeout      int      `mapstructure:"stop_timeout" yaml:"stop_timeout"`
+	TrapsDBFolder    string   `mapstructure:"traps_db_folder" yaml:"traps_db_folder"`
 }
 
 // ReadConfig builds and returns configuration from Agent configuration.
@@ -35,8 +51,13 @@ func ReadConfig() (*Config, error) {
 	}
 
 	// Validate required fields.
-	if c.CommunityStrings == nil || len(c.CommunityStrings) == 0 {
-		return nil, errors.New("`community_strings` is required and must be non-empty")
+	if len(c.CommunityStrings) == 0 && len(c.Users) == 0 {
+		return nil, errors.New("`community_strings` or `users` is required and must be non-empty")
+	}
+	for _, user := range c.Users {
+		if _, err := user.buildSecurityParams(); err != nil {
+			return nil, fmt.Errorf("invalid SNMPv3 user %q: %s", user.Username, err)
+		}
 	}
 
 	// Set defaults.
@@ -50,6 +71,9 @@ func ReadConfig() (*Config, error) {
 	if c.StopTimeout == 0 {
 		c.StopTimeout = defaultStopTimeout
 	}
+	if c.TrapsDBFolder == "" {
+		c.TrapsDBFolder = filepath.Join(config.Datadog.GetString("confd_path"), "snmp.d", "traps_db")
+	}
 
 	return &c, nil
 }
@@ -68,3 +92,106 @@ func (c *Config) BuildV2Params() *gosnmp.GoSNMP {
 		Logger:    &trapLogger{},
 	}
 }
+
+// buildSecurityParams returns the GoSNMP USM security parameters of the user.
+func (u *UserV3) buildSecurityParams() (*gosnmp.UsmSecurityParameters, error) {
+	if u.Username == "" {
+		return nil, errors.New("`user` is required")
+	}
+
+	authProtocol, err := parseAuthProtocol(u.AuthProtocol)
+	if err != nil {
+		return nil, err
+	}
+	privProtocol, err := parsePrivProtocol(u.PrivProtocol)
+	if err != nil {
+		return nil, err
+	}
+	engineID, err := parseEngineID(u.EngineID)
+	if err != nil {
+		return nil, err
+	}
+
+	if authProtocol != gosnmp.NoAuth {
+		if u.AuthKey == "" {
+			return nil, errors.New("`auth_key` is required when `auth_protocol` is set")
+		}
+	}
+	if privProtocol != gosnmp.NoPriv {
+		if authProtocol == gosnmp.NoAuth {
+			return nil, errors.New("`auth_protocol` is required when `priv_protocol` is set")
+		}
+		if u.PrivKey == "" {
+			return nil, errors.New("`priv_key` is required when `priv_protocol` is set")
+		}
+	}
+
+	return &gosnmp.UsmSecurityParameters{
+		UserName:                 u.Username,
+		AuthoritativeEngineID:    engineID,
+		AuthenticationProtocol:   authProtocol,
+		AuthenticationPassphrase: u.AuthKey,
+		PrivacyProtocol:          privProtocol,
+		PrivacyPassphrase:        u.PrivKey,
+	}, nil
+}
+
+// securityLevel returns the minimum security level of the traps sent by the user.
+func (u *UserV3) securityLevel() gosnmp.SnmpV3MsgFlags {
+	switch {
+	case u.PrivProtocol != "":
+		return gosnmp.AuthPriv
+	case u.AuthProtocol != "":
+		return gosnmp.AuthNoPriv
+	default:
+		return gosnmp.NoAuthNoPriv
+	}
+}
+
+func parseAuthProtocol(authProtocol string) (gosnmp.SnmpV3AuthProtocol, error) {
+	switch strings.ToLower(authProtocol) {
+	case "":
+		return gosnmp.NoAuth, nil
+	case "md5":
+		return gosnmp.MD5, nil
+	case "sha":
+		return gosnmp.SHA, nil
+	case "sha224":
+		return gosnmp.SHA224, nil
+	case "sha256":
+		return gosnmp.SHA256, nil
+	case "sha384":
+		return gosnmp.SHA384, nil
+	case "sha512":
+		return gosnmp.SHA512, nil
+	default:
+		return gosnmp.NoAuth, fmt.Errorf("unsupported authentication protocol: %s", authProtocol)
+	}
+}
+
+func parsePrivProtocol(privProtocol string) (gosnmp.SnmpV3PrivProtocol, error) {
+	switch strings.ToLower(privProtocol) {
+	case "":
+		return gosnmp.NoPriv, nil
+	case "des":
+		return gosnmp.DES, nil
+	case "aes":
+		return gosnmp.AES, nil
+	case "aes192":
+		return gosnmp.AES192, nil
+	case "aes256":
+		return gosnmp.AES256, nil
+	default:
+		return gosnmp.NoPriv, fmt.Errorf("unsupported privacy protocol: %s", privProtocol)
+	}
+}
+
+// parseEngineID decodes an optional engine ID given as an hexadecimal string, eg "0x8000000001020304".
+func parseEngineID(engineID string) (string, error) {
+	engineID = strings.TrimPrefix(strings.ToLower(engineID), "0x")
+	decoded, err := hex.DecodeString(engineID)
+	if err != nil {
+		return "", fmt.Errorf("invalid `engine_id`: %s", err)
+	}
+	return string(decoded), nil
+}
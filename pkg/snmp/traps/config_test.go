@@ -6,9 +6,12 @@
 package traps
 
 import (
+	"path/filepath"
+	"testing"
+
+	coreconfig "github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/soniah/gosnmp"
 	"github.com/stretchr/testify/assert"
-	"testing"
 )
 
 func TestConfig(t *testing.T) {
@@ -71,3 +74,93 @@ func TestStopTimeout(t *testing.T) {
 
 	assert.Equal(t, 11, config.StopTimeout)
 }
+
+func TestUsersWithoutCommunityStrings(t *testing.T) {
+	Configure(t, Config{
+		Users: []UserV3{{Username: "user"}},
+	})
+	_, err := ReadConfig()
+	assert.NoError(t, err)
+}
+
+func TestMultipleUsers(t *testing.T) {
+	Configure(t, Config{
+		Users: []UserV3{
+			{Username: "user", AuthProtocol: "sha", AuthKey: "auth-password"},
+			{Username: "other-user", EngineID: "0x8000000001020304", AuthProtocol: "md5", AuthKey: "auth-password"},
+		},
+	})
+	config, err := ReadConfig()
+	assert.NoError(t, err)
+	assert.Len(t, config.Users, 2)
+}
+
+func TestSecurityParamsTable(t *testing.T) {
+	table, err := newSecurityParamsTable([]UserV3{
+		{Username: "user", AuthProtocol: "sha256", AuthKey: "auth-password", PrivProtocol: "aes", PrivKey: "priv-password"},
+		{Username: "user", EngineID: "0x8000000001020304", AuthProtocol: "md5", AuthKey: "other-password"},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, table.users, 1)
+	assert.Len(t, table.users["user"], 2)
+
+	// The keys are localized with the engine ID of the packet.
+	params := table.users["user"][0].paramsFor("engine-1")
+	assert.Equal(t, gosnmp.Version3, params.Version)
+	assert.Equal(t, gosnmp.UserSecurityModel, params.SecurityModel)
+	assert.Equal(t, gosnmp.AuthPriv, params.MsgFlags)
+	securityParams := params.SecurityParameters.(*gosnmp.UsmSecurityParameters)
+	assert.Equal(t, "user", securityParams.UserName)
+	assert.Equal(t, "engine-1", securityParams.AuthoritativeEngineID)
+	assert.Equal(t, gosnmp.SHA256, securityParams.AuthenticationProtocol)
+	assert.Equal(t, "auth-password", securityParams.AuthenticationPassphrase)
+	assert.Equal(t, gosnmp.AES, securityParams.PrivacyProtocol)
+	assert.Equal(t, "priv-password", securityParams.PrivacyPassphrase)
+	assert.Same(t, params, table.users["user"][0].paramsFor("engine-1"))
+	assert.NotSame(t, params, table.users["user"][0].paramsFor("engine-2"))
+
+	_, err = table.unmarshalTrap(nil, "engine-1", "unknown")
+	assert.Error(t, err)
+}
+
+func TestInvalidUsers(t *testing.T) {
+	validUser := UserV3{
+		Username:     "user",
+		EngineID:     "8000000001020304",
+		AuthProtocol: "md5",
+		AuthKey:      "auth-password",
+		PrivProtocol: "des",
+		PrivKey:      "priv-password",
+	}
+
+	for name, update := range map[string]func(u *UserV3){
+		"missing user":          func(u *UserV3) { u.Username = "" },
+		"unknown auth protocol": func(u *UserV3) { u.AuthProtocol = "sha1024" },
+		"unknown priv protocol": func(u *UserV3) { u.PrivProtocol = "3des" },
+		"missing auth key":      func(u *UserV3) { u.AuthKey = "" },
+		"missing priv key":      func(u *UserV3) { u.PrivKey = "" },
+		"invalid engine ID":     func(u *UserV3) { u.EngineID = "0xzz" },
+		"privacy without auth":  func(u *UserV3) { u.AuthProtocol = "" },
+	} {
+		t.Run(name, func(t *testing.T) {
+			user := validUser
+			update(&user)
+			Configure(t, Config{
+				CommunityStrings: []string{"public"},
+				Users:            []UserV3{validUser, user},
+			})
+			_, err := ReadConfig()
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestDefaultTrapsDBFolder(t *testing.T) {
+	Configure(t, Config{
+		CommunityStrings: []string{"public"},
+	})
+	config, err := ReadConfig()
+	assert.NoError(t, err)
+
+	assert.Equal(t, filepath.Join(coreconfig.Datadog.GetString("confd_path"), "snmp.d", "traps_db"), config.TrapsDBFolder)
+}
@@ -15,11 +15,30 @@ import (
 const (
 	sysUpTimeInstanceOID = "1.3.6.1.2.1.1.3.0"
 	snmpTrapOID          = "1.3.6.1.6.3.1.1.4.1.0"
+	snmpTrapsOID         = "1.3.6.1.6.3.1.1.5"
+
+	enterpriseSpecificGenericTrap = 6
 )
 
 // FormatPacketToJSON converts an SNMP trap packet to a JSON-serializable object.
-func FormatPacketToJSON(packet *SnmpPacket) (map[string]interface{}, error) {
-	return formatTrapPDUs(packet.Content.Variables)
+// The trap and variable OIDs known by the resolver are completed with their symbolic names.
+func FormatPacketToJSON(packet *SnmpPacket, resolver OIDResolver) (map[string]interface{}, error) {
+	var data map[string]interface{}
+	var err error
+
+	if packet.Content.Version == gosnmp.Version1 {
+		data, err = formatV1Trap(packet.Content)
+	} else {
+		data, err = formatTrapPDUs(packet.Content.Variables)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	data["snmp_version"] = formatVersion(packet)
+	resolveOIDs(data, resolver)
+
+	return data, nil
 }
 
 // GetTags returns a list of tags associated to an SNMP trap packet.
@@ -32,8 +51,12 @@ func GetTags(packet *SnmpPacket) []string {
 
 func formatVersion(packet *SnmpPacket) string {
 	switch packet.Content.Version {
+	case gosnmp.Version1:
+		return "1"
 	case gosnmp.Version2c:
 		return "2"
+	case gosnmp.Version3:
+		return "3"
 	default:
 		return "unknown"
 	}
@@ -41,7 +64,7 @@ func formatVersion(packet *SnmpPacket) string {
 
 func formatTrapPDUs(variables []gosnmp.SnmpPDU) (map[string]interface{}, error) {
 	/*
-		An SNMPv2 trap packet consists in the following variables (PDUs):
+		An SNMPv2 or SNMPv3 trap packet consists in the following variables (PDUs):
 		{sysUpTime.0, snmpTrapOID.0, additionalDataVariables...}
 		See: https://tools.ietf.org/html/rfc3416#section-4.2.6
 	*/
@@ -68,6 +91,62 @@ func formatTrapPDUs(variables []gosnmp.SnmpPDU) (map[string]interface{}, error)
 	return data, nil
 }
 
+func formatV1Trap(content *gosnmp.SnmpPacket) (map[string]interface{}, error) {
+	/*
+		An SNMPv1 trap packet holds the enterprise OID, the generic and specific trap types and the uptime
+		in its header, followed by the additional data variables.
+		The snmpTrapOID is derived from the header as described in https://tools.ietf.org/html/rfc3584#section-3.1
+	*/
+	if content.GenericTrap < 0 || content.GenericTrap > enterpriseSpecificGenericTrap {
+		return nil, fmt.Errorf("invalid generic trap type %d", content.GenericTrap)
+	}
+
+	data := make(map[string]interface{})
+	data["uptime"] = uint32(content.Timestamp)
+
+	enterpriseOID := normalizeOID(content.Enterprise)
+	if content.GenericTrap == enterpriseSpecificGenericTrap {
+		if enterpriseOID == "" {
+			return nil, fmt.Errorf("expected an enterprise OID for an enterprise specific trap")
+		}
+		data["oid"] = fmt.Sprintf("%s.0.%d", enterpriseOID, content.SpecificTrap)
+	} else {
+		data["oid"] = fmt.Sprintf("%s.%d", snmpTrapsOID, content.GenericTrap+1)
+	}
+	data["enterprise_oid"] = enterpriseOID
+	data["generic_trap"] = content.GenericTrap
+	data["specific_trap"] = content.SpecificTrap
+
+	data["variables"] = parseVariables(content.Variables)
+
+	return data, nil
+}
+
+func resolveOIDs(data map[string]interface{}, resolver OIDResolver) {
+	if trapOID, ok := data["oid"].(string); ok {
+		if trap, found := resolver.GetTrapMetadata(trapOID); found {
+			data["name"] = trap.Name
+			data["mib"] = trap.MIBName
+		}
+	}
+
+	variables, _ := data["variables"].([]map[string]interface{})
+	for _, variable := range variables {
+		oid, _ := variable["oid"].(string)
+		metadata, found := resolver.GetVariableMetadata(oid)
+		if !found {
+			continue
+		}
+		variable["name"] = metadata.Name
+		if value, ok := variable["value"].(int); ok && len(metadata.Enumeration) > 0 {
+			if label, ok := metadata.Enumeration[value]; ok {
+				variable["value"] = label
+				variable["raw_value"] = value
+			}
+		}
+	}
+}
+
 func normalizeOID(value string) string {
 	// OIDs can be formatted as ".1.2.3..." ("absolute form") or "1.2.3..." ("relative form").
 	// Convert everything to relative form, like we do in the Python check.
@@ -28,11 +28,12 @@ func createTestPacket() *SnmpPacket {
 func TestFormatPacketToJSON(t *testing.T) {
 	packet := createTestPacket()
 
-	data, err := FormatPacketToJSON(packet)
+	data, err := FormatPacketToJSON(packet, &MultiFilesOIDResolver{})
 	require.NoError(t, err)
 
 	assert.Equal(t, "1.3.6.1.4.1.8072.2.3.0.1", data["oid"])
 	assert.NotNil(t, data["uptime"])
+	assert.Equal(t, "2", data["snmp_version"])
 
 	variables, ok := data["variables"].([]map[string]interface{})
 	assert.True(t, ok)
@@ -49,13 +50,133 @@ func TestFormatPacketToJSON(t *testing.T) {
 	assert.Equal(t, heartBeatName["value"], "test")
 }
 
+func TestFormatPacketToJSONResolvesOIDs(t *testing.T) {
+	resolver, err := NewMultiFilesOIDResolver("testdata/traps_db")
+	require.NoError(t, err)
+
+	packet := createTestPacket()
+	data, err := FormatPacketToJSON(packet, resolver)
+	require.NoError(t, err)
+
+	assert.Equal(t, "1.3.6.1.4.1.8072.2.3.0.1", data["oid"])
+	assert.Equal(t, "netSnmpExampleHeartbeatNotification", data["name"])
+	assert.Equal(t, "NET-SNMP-EXAMPLES-MIB", data["mib"])
+
+	variables, ok := data["variables"].([]map[string]interface{})
+	require.True(t, ok)
+	assert.Equal(t, "netSnmpExampleHeartbeatRate", variables[0]["name"])
+	assert.Equal(t, 1024, variables[0]["value"])
+	assert.Equal(t, "heartbeatName", variables[1]["name"])
+
+	// enum values are replaced by their label, unknown OIDs are kept numeric
+	packet.Content.Variables = []gosnmp.SnmpPDU{
+		{Name: "1.3.6.1.2.1.1.3.0", Type: gosnmp.TimeTicks, Value: uint32(1000)},
+		{Name: "1.3.6.1.6.3.1.1.4.1.0", Type: gosnmp.OctetString, Value: "1.3.6.1.6.3.1.1.5.4"},
+		// ifIndex
+		{Name: "1.3.6.1.2.1.2.2.1.1.3", Type: gosnmp.Integer, Value: 3},
+		// ifAdminStatus
+		{Name: "1.3.6.1.2.1.2.2.1.7.3", Type: gosnmp.Integer, Value: 2},
+		// ifOperStatus
+		{Name: "1.3.6.1.2.1.2.2.1.8.3", Type: gosnmp.Integer, Value: 2},
+	}
+	data, err = FormatPacketToJSON(packet, resolver)
+	require.NoError(t, err)
+
+	assert.Equal(t, "1.3.6.1.6.3.1.1.5.4", data["oid"])
+	assert.NotContains(t, data, "name")
+	assert.NotContains(t, data, "mib")
+
+	variables, ok = data["variables"].([]map[string]interface{})
+	require.True(t, ok)
+	assert.Equal(t, map[string]interface{}{
+		"oid":   "1.3.6.1.2.1.2.2.1.1.3",
+		"type":  "integer",
+		"value": 3,
+		"name":  "ifIndex",
+	}, variables[0])
+	assert.Equal(t, map[string]interface{}{
+		"oid":       "1.3.6.1.2.1.2.2.1.7.3",
+		"type":      "integer",
+		"value":     "down",
+		"raw_value": 2,
+		"name":      "ifAdminStatus",
+	}, variables[1])
+	assert.Equal(t, map[string]interface{}{
+		"oid":   "1.3.6.1.2.1.2.2.1.8.3",
+		"type":  "integer",
+		"value": 2,
+	}, variables[2])
+}
+
+func TestFormatV1PacketToJSON(t *testing.T) {
+	packet := createTestPacket()
+	packet.Content.Version = gosnmp.Version1
+	packet.Content.SnmpTrap = NetSNMPExampleHeartbeatNotificationV1Trap
+	packet.Content.Enterprise = ".1.3.6.1.4.1.8072.2.3"
+	packet.Content.Variables = NetSNMPExampleHeartbeatNotificationV1Trap.Variables
+
+	data, err := FormatPacketToJSON(packet, &MultiFilesOIDResolver{})
+	require.NoError(t, err)
+
+	assert.Equal(t, "1", data["snmp_version"])
+	assert.Equal(t, "1.3.6.1.4.1.8072.2.3.0.1", data["oid"])
+	assert.Equal(t, "1.3.6.1.4.1.8072.2.3", data["enterprise_oid"])
+	assert.Equal(t, 6, data["generic_trap"])
+	assert.Equal(t, 1, data["specific_trap"])
+	assert.Equal(t, uint32(1000), data["uptime"])
+
+	variables, ok := data["variables"].([]map[string]interface{})
+	assert.True(t, ok)
+	assert.Equal(t, len(variables), 2)
+	assert.Equal(t, variables[0]["oid"], "1.3.6.1.4.1.8072.2.3.2.1")
+	assert.Equal(t, variables[1]["oid"], "1.3.6.1.4.1.8072.2.3.2.2")
+}
+
+func TestFormatV1GenericTrapToJSON(t *testing.T) {
+	packet := createTestPacket()
+	packet.Content.Version = gosnmp.Version1
+	packet.Content.SnmpTrap = gosnmp.SnmpTrap{
+		Enterprise:   ".1.3.6.1.4.1.8072.3.2.10",
+		AgentAddress: "127.0.0.1",
+		GenericTrap:  2, // linkDown
+		Timestamp:    42,
+	}
+	packet.Content.Variables = []gosnmp.SnmpPDU{
+		// ifIndex
+		{Name: "1.3.6.1.2.1.2.2.1.1.3", Type: gosnmp.Integer, Value: 3},
+	}
+
+	data, err := FormatPacketToJSON(packet, &MultiFilesOIDResolver{})
+	require.NoError(t, err)
+
+	assert.Equal(t, "1.3.6.1.6.3.1.1.5.3", data["oid"])
+	assert.Equal(t, 2, data["generic_trap"])
+	assert.Equal(t, uint32(42), data["uptime"])
+
+	packet.Content.GenericTrap = 7
+	_, err = FormatPacketToJSON(packet, &MultiFilesOIDResolver{})
+	require.Error(t, err)
+}
+
+func TestFormatV3PacketToJSON(t *testing.T) {
+	packet := createTestPacket()
+	packet.Content.Version = gosnmp.Version3
+	packet.Content.Community = ""
+
+	data, err := FormatPacketToJSON(packet, &MultiFilesOIDResolver{})
+	require.NoError(t, err)
+
+	assert.Equal(t, "3", data["snmp_version"])
+	assert.Equal(t, "1.3.6.1.4.1.8072.2.3.0.1", data["oid"])
+}
+
 func TestFormatPacketToJSONShouldFailIfNotEnoughVariables(t *testing.T) {
 	packet := createTestPacket()
 
 	packet.Content.Variables = []gosnmp.SnmpPDU{
 		// No variables at all.
 	}
-	_, err := FormatPacketToJSON(packet)
+	_, err := FormatPacketToJSON(packet, &MultiFilesOIDResolver{})
 	require.Error(t, err)
 
 	packet.Content.Variables = []gosnmp.SnmpPDU{
@@ -64,7 +185,7 @@ func TestFormatPacketToJSONShouldFailIfNotEnoughVariables(t *testing.T) {
 		{Name: "1.3.6.1.4.1.8072.2.3.2.1", Type: gosnmp.Integer, Value: 1024},
 		{Name: "1.3.6.1.4.1.8072.2.3.2.2", Type: gosnmp.OctetString, Value: "test"},
 	}
-	_, err = FormatPacketToJSON(packet)
+	_, err = FormatPacketToJSON(packet, &MultiFilesOIDResolver{})
 	require.Error(t, err)
 
 	packet.Content.Variables = []gosnmp.SnmpPDU{
@@ -73,7 +194,7 @@ func TestFormatPacketToJSONShouldFailIfNotEnoughVariables(t *testing.T) {
 		{Name: "1.3.6.1.4.1.8072.2.3.2.1", Type: gosnmp.Integer, Value: 1024},
 		{Name: "1.3.6.1.4.1.8072.2.3.2.2", Type: gosnmp.OctetString, Value: "test"},
 	}
-	_, err = FormatPacketToJSON(packet)
+	_, err = FormatPacketToJSON(packet, &MultiFilesOIDResolver{})
 	require.Error(t, err)
 }
 
@@ -86,11 +207,32 @@ func TestGetTags(t *testing.T) {
 	})
 }
 
-func TestGetTagsForUnsupportedVersionShouldStillSucceed(t *testing.T) {
+func TestGetTagsV1(t *testing.T) {
+	packet := createTestPacket()
+	packet.Content.Version = gosnmp.Version1
+	tags := GetTags(packet)
+	assert.Equal(t, tags, []string{
+		"snmp_version:1",
+		"snmp_device:127.0.0.1",
+	})
+}
+
+func TestGetTagsV3(t *testing.T) {
 	packet := createTestPacket()
 	packet.Content.Version = gosnmp.Version3
 	packet.Content.Community = ""
 	tags := GetTags(packet)
+	assert.Equal(t, tags, []string{
+		"snmp_version:3",
+		"snmp_device:127.0.0.1",
+	})
+}
+
+func TestGetTagsForUnsupportedVersionShouldStillSucceed(t *testing.T) {
+	packet := createTestPacket()
+	packet.Content.Version = gosnmp.SnmpVersion(0x2)
+	packet.Content.Community = ""
+	tags := GetTags(packet)
 	assert.Equal(t, tags, []string{
 		"snmp_version:unknown",
 		"snmp_device:127.0.0.1",
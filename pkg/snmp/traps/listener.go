@@ -0,0 +1,99 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2020 Datadog, Inc.
+
+package traps
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+	"github.com/soniah/gosnmp"
+)
+
+// Same as the buffer size of gosnmp.TrapListener.
+const maxPacketSize = 4096
+
+// trapListener receives trap packets on an UDP socket.
+// Unlike gosnmp.TrapListener, which decodes all SNMPv3 traps with the security parameters of
+// a single user, it picks the security parameters from the user name of each packet.
+type trapListener struct {
+	conn     *net.UDPConn
+	v2Params *gosnmp.GoSNMP
+	usmTable *securityParamsTable
+	onTrap   func(*gosnmp.SnmpPacket, *net.UDPAddr)
+	done     chan struct{}
+}
+
+func startTrapListener(c *Config, onTrap func(*gosnmp.SnmpPacket, *net.UDPAddr)) (*trapListener, error) {
+	usmTable, err := newSecurityParamsTable(c.Users)
+	if err != nil {
+		return nil, err
+	}
+
+	addr, err := net.ResolveUDPAddr("udp", c.Addr())
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenUDP("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	listener := &trapListener{
+		conn:     conn,
+		v2Params: c.BuildV2Params(),
+		usmTable: usmTable,
+		onTrap:   onTrap,
+		done:     make(chan struct{}),
+	}
+	go listener.run()
+	return listener, nil
+}
+
+func (l *trapListener) run() {
+	defer close(l.done)
+	buf := make([]byte, maxPacketSize)
+	for {
+		n, remote, err := l.conn.ReadFromUDP(buf)
+		if err != nil {
+			if strings.Contains(err.Error(), "use of closed network connection") {
+				return
+			}
+			log.Warnf("Error reading trap packet: %v", err)
+			continue
+		}
+		packet, err := l.unmarshalTrap(buf[:n])
+		if err != nil {
+			log.Debugf("Dropping packet from %s: %v", remote.String(), err)
+			trapsPacketsAuthErrors.Add(1)
+			continue
+		}
+		l.onTrap(packet, remote)
+	}
+}
+
+// unmarshalTrap decodes a trap packet, the packet is not modified.
+func (l *trapListener) unmarshalTrap(packet []byte) (*gosnmp.SnmpPacket, error) {
+	version, engineID, userName, err := parsePacketHeader(packet)
+	if err != nil {
+		return nil, fmt.Errorf("invalid packet: %s", err)
+	}
+	if version == gosnmp.Version3 {
+		return l.usmTable.unmarshalTrap(packet, engineID, userName)
+	}
+	if p := l.v2Params.UnmarshalTrap(append([]byte(nil), packet...)); p != nil {
+		return p, nil
+	}
+	return nil, errors.New("cannot decode the packet")
+}
+
+// Close stops listening and waits for the packet being processed, if any.
+func (l *trapListener) Close() {
+	_ = l.conn.Close()
+	<-l.done
+}
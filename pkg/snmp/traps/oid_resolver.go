@@ -0,0 +1,153 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2020 Datadog, Inc.
+
+package traps
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+	"gopkg.in/yaml.v2"
+)
+
+// TrapMetadata is the metadata of a trap defined in a MIB.
+type TrapMetadata struct {
+	Name        string `json:"name" yaml:"name"`
+	MIBName     string `json:"mib" yaml:"mib"`
+	Description string `json:"descr" yaml:"descr"`
+}
+
+// VariableMetadata is the metadata of a trap variable defined in a MIB. Column is set for the
+// columns of a table, whose instances are suffixed with the index of their row.
+type VariableMetadata struct {
+	Name        string         `json:"name" yaml:"name"`
+	Description string         `json:"descr" yaml:"descr"`
+	Enumeration map[int]string `json:"enum" yaml:"enum"`
+	Column      bool           `json:"column" yaml:"column"`
+}
+
+// trapsDBFileContent is the content of a traps database file, derived from compiled MIBs.
+// OIDs are in relative form, eg "1.3.6.1.6.3.1.1.5.3".
+type trapsDBFileContent struct {
+	Traps     map[string]TrapMetadata     `json:"traps" yaml:"traps"`
+	Variables map[string]VariableMetadata `json:"vars" yaml:"vars"`
+}
+
+// OIDResolver resolves trap and variable OIDs to their MIB definitions.
+type OIDResolver interface {
+	GetTrapMetadata(trapOID string) (TrapMetadata, bool)
+	GetVariableMetadata(variableOID string) (VariableMetadata, bool)
+}
+
+// MultiFilesOIDResolver is an OIDResolver backed by the traps database files found in a folder.
+type MultiFilesOIDResolver struct {
+	traps     map[string]TrapMetadata
+	variables map[string]VariableMetadata
+}
+
+// NewMultiFilesOIDResolver loads the JSON and YAML traps database files from a folder and
+// returns a resolver for the OIDs they define. Files are loaded in lexical order, definitions
+// from a file overriding the ones from previous files. A missing folder yields a resolver that
+// does not resolve anything.
+func NewMultiFilesOIDResolver(folder string) (*MultiFilesOIDResolver, error) {
+	resolver := &MultiFilesOIDResolver{
+		traps:     make(map[string]TrapMetadata),
+		variables: make(map[string]VariableMetadata),
+	}
+
+	files, err := ioutil.ReadDir(folder)
+	if err != nil {
+		if os.IsNotExist(err) {
+			log.Debugf("Traps database folder %s does not exist, OIDs will not be resolved", folder)
+			return resolver, nil
+		}
+		return nil, err
+	}
+
+	var fileNames []string
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		switch strings.ToLower(filepath.Ext(file.Name())) {
+		case ".json", ".yaml", ".yml":
+			fileNames = append(fileNames, file.Name())
+		}
+	}
+	sort.Strings(fileNames)
+
+	for _, fileName := range fileNames {
+		path := filepath.Join(folder, fileName)
+		if err := resolver.loadFile(path); err != nil {
+			log.Warnf("Could not load traps database file %s: %s", path, err)
+		}
+	}
+	log.Debugf("Loaded %d trap and %d variable definitions from %s", len(resolver.traps), len(resolver.variables), folder)
+
+	return resolver, nil
+}
+
+func (r *MultiFilesOIDResolver) loadFile(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var content trapsDBFileContent
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		err = json.Unmarshal(data, &content)
+	} else {
+		err = yaml.Unmarshal(data, &content)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid content: %s", err)
+	}
+
+	for oid, trap := range content.Traps {
+		r.traps[normalizeOID(oid)] = trap
+	}
+	for oid, variable := range content.Variables {
+		r.variables[normalizeOID(oid)] = variable
+	}
+
+	return nil
+}
+
+// GetTrapMetadata returns the metadata of a trap OID.
+func (r *MultiFilesOIDResolver) GetTrapMetadata(trapOID string) (TrapMetadata, bool) {
+	trap, ok := r.traps[normalizeOID(trapOID)]
+	return trap, ok
+}
+
+// GetVariableMetadata returns the metadata of a variable OID. Variables of tables are suffixed
+// with the index of their row, so an OID without a definition of its own resolves to the longest
+// OID prefix defining a table column. Other variables only resolve their exact OID.
+func (r *MultiFilesOIDResolver) GetVariableMetadata(variableOID string) (VariableMetadata, bool) {
+	oid := normalizeOID(variableOID)
+	if variable, ok := r.variables[oid]; ok {
+		return variable, true
+	}
+	for {
+		lastDot := strings.LastIndex(oid, ".")
+		if lastDot == -1 {
+			break
+		}
+		oid = oid[:lastDot]
+		if variable, ok := r.variables[oid]; ok {
+			if !variable.Column {
+				// the OID is not an instance of this variable, only a child of its node
+				break
+			}
+			return variable, true
+		}
+	}
+	return VariableMetadata{}, false
+}
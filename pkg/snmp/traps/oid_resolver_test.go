@@ -0,0 +1,63 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2020 Datadog, Inc.
+
+package traps
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOIDResolver(t *testing.T) {
+	resolver, err := NewMultiFilesOIDResolver("testdata/traps_db")
+	require.NoError(t, err)
+
+	trap, found := resolver.GetTrapMetadata("1.3.6.1.4.1.8072.2.3.0.1")
+	require.True(t, found)
+	assert.Equal(t, "netSnmpExampleHeartbeatNotification", trap.Name)
+	assert.Equal(t, "NET-SNMP-EXAMPLES-MIB", trap.MIBName)
+
+	// OIDs are normalized
+	trap, found = resolver.GetTrapMetadata(".1.3.6.1.6.3.1.1.5.3")
+	require.True(t, found)
+	assert.Equal(t, "linkDown", trap.Name)
+
+	_, found = resolver.GetTrapMetadata("1.3.6.1.6.3.1.1.5.4")
+	assert.False(t, found)
+
+	variable, found := resolver.GetVariableMetadata("1.3.6.1.4.1.8072.2.3.2.1")
+	require.True(t, found)
+	assert.Equal(t, "netSnmpExampleHeartbeatRate", variable.Name)
+
+	// definitions of later files override the ones of previous files
+	variable, found = resolver.GetVariableMetadata("1.3.6.1.4.1.8072.2.3.2.2")
+	require.True(t, found)
+	assert.Equal(t, "heartbeatName", variable.Name)
+
+	// variables of tables are suffixed by the row index
+	variable, found = resolver.GetVariableMetadata("1.3.6.1.2.1.2.2.1.7.3")
+	require.True(t, found)
+	assert.Equal(t, "ifAdminStatus", variable.Name)
+	assert.Equal(t, map[int]string{1: "up", 2: "down", 3: "testing"}, variable.Enumeration)
+
+	_, found = resolver.GetVariableMetadata("1.3.6.1.2.1.2.2.1.8.3")
+	assert.False(t, found)
+
+	// the OIDs under a variable which is not a table column are not instances of this variable
+	_, found = resolver.GetVariableMetadata("1.3.6.1.4.1.8072.2.3.2.1.5")
+	assert.False(t, found)
+	_, found = resolver.GetVariableMetadata("1.3.6.1.4.1.8072.2.3.2.1.5.1")
+	assert.False(t, found)
+}
+
+func TestOIDResolverMissingFolder(t *testing.T) {
+	resolver, err := NewMultiFilesOIDResolver("testdata/does_not_exist")
+	require.NoError(t, err)
+
+	_, found := resolver.GetTrapMetadata("1.3.6.1.4.1.8072.2.3.0.1")
+	assert.False(t, found)
+}
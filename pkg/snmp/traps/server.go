@@ -22,12 +22,13 @@ type SnmpPacket struct {
 // PacketsChannel is the type of channels of trap packets.
 type PacketsChannel = chan *SnmpPacket
 
-// TrapServer manages an SNMPv2 trap listener.
+// TrapServer manages an SNMP trap listener.
 type TrapServer struct {
 	Addr     string
 	config   *Config
-	listener *gosnmp.TrapListener
+	listener *trapListener
 	packets  PacketsChannel
+	resolver OIDResolver
 }
 
 var (
@@ -62,6 +63,11 @@ func GetPacketsChannel() PacketsChannel {
 	return serverInstance.packets
 }
 
+// GetOIDResolver returns the resolver of the OIDs of the received trap packets.
+func GetOIDResolver() OIDResolver {
+	return serverInstance.resolver
+}
+
 // NewTrapServer configures and returns a running SNMP traps server.
 func NewTrapServer() (*TrapServer, error) {
 	config, err := ReadConfig()
@@ -69,9 +75,14 @@ func NewTrapServer() (*TrapServer, error) {
 		return nil, err
 	}
 
+	resolver, err := NewMultiFilesOIDResolver(config.TrapsDBFolder)
+	if err != nil {
+		return nil, err
+	}
+
 	packets := make(PacketsChannel, packetsChanSize)
 
-	listener, err := startSNMPv2Listener(config, packets)
+	listener, err := startSNMPTrapListener(config, packets)
 	if err != nil {
 		return nil, err
 	}
@@ -80,16 +91,15 @@ func NewTrapServer() (*TrapServer, error) {
 		listener: listener,
 		config:   config,
 		packets:  packets,
+		resolver: resolver,
 	}
 
 	return server, nil
 }
 
-func startSNMPv2Listener(c *Config, packets PacketsChannel) (*gosnmp.TrapListener, error) {
-	listener := gosnmp.NewTrapListener()
-	listener.Params = c.BuildV2Params()
-
-	listener.OnNewTrap = func(p *gosnmp.SnmpPacket, u *net.UDPAddr) {
+func startSNMPTrapListener(c *Config, packets PacketsChannel) (*trapListener, error) {
+	log.Infof("Start listening for traps on %s", c.Addr())
+	return startTrapListener(c, func(p *gosnmp.SnmpPacket, u *net.UDPAddr) {
 		if err := validateCredentials(p, c); err != nil {
 			log.Warnf("Invalid credentials from %s on listener %s, dropping packet", u.String(), c.Addr())
 			trapsPacketsAuthErrors.Add(1)
@@ -98,32 +108,7 @@ func startSNMPv2Listener(c *Config, packets PacketsChannel) (*gosnmp.TrapListene
 		log.Debugf("Packet received from %s on listener %s", u.String(), c.Addr())
 		trapsPackets.Add(1)
 		packets <- &SnmpPacket{Content: p, Addr: u}
-	}
-
-	errors := make(chan error, 1)
-
-	// Start actually listening in the background.
-	go func() {
-		log.Infof("Start listening for traps on %s", c.Addr())
-		err := listener.Listen(c.Addr())
-		if err != nil {
-			errors <- err
-		}
-	}()
-
-	select {
-	// Wait for listener to be started and listening to traps.
-	// See: https://godoc.org/github.com/soniah/gosnmp#TrapListener.Listening
-	case <-listener.Listening():
-		break
-	// If the listener failed to start (eg because it couldn't bind to a socket),
-	// we'll get an error here.
-	case err := <-errors:
-		close(errors)
-		return nil, err
-	}
-
-	return listener, nil
+	})
 }
 
 // Stop stops the TrapServer.
@@ -8,6 +8,8 @@ package traps
 import (
 	"testing"
 
+	"github.com/soniah/gosnmp"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -38,6 +40,182 @@ func TestServerV2BadCredentials(t *testing.T) {
 	assertNoPacketReceived(t)
 }
 
+func TestServerV1(t *testing.T) {
+	config := Config{Port: GetPort(t), CommunityStrings: []string{"public"}}
+	Configure(t, config)
+
+	err := StartServer()
+	require.NoError(t, err)
+	defer StopServer()
+
+	sendTestV1Trap(t, config, "public")
+	packet := receivePacket(t)
+	require.NotNil(t, packet)
+	require.Equal(t, gosnmp.Version1, packet.Content.Version)
+	assert.Equal(t, ".1.3.6.1.4.1.8072.2.3", packet.Content.Enterprise)
+	assert.Equal(t, 6, packet.Content.GenericTrap)
+	assert.Equal(t, 1, packet.Content.SpecificTrap)
+	assert.Equal(t, 2, len(packet.Content.Variables))
+}
+
+func TestServerV1BadCredentials(t *testing.T) {
+	config := Config{Port: GetPort(t), CommunityStrings: []string{"public"}}
+	Configure(t, config)
+
+	err := StartServer()
+	require.NoError(t, err)
+	defer StopServer()
+
+	sendTestV1Trap(t, config, "wrong-community")
+	assertNoPacketReceived(t)
+}
+
+func TestServerV3(t *testing.T) {
+	user := UserV3{
+		Username:     "user",
+		EngineID:     "0x8000000001020304",
+		AuthProtocol: "sha256",
+		AuthKey:      "password",
+		PrivProtocol: "aes",
+		PrivKey:      "password",
+	}
+	config := Config{Port: GetPort(t), CommunityStrings: []string{"public"}, Users: []UserV3{user}}
+	Configure(t, config)
+
+	err := StartServer()
+	require.NoError(t, err)
+	defer StopServer()
+
+	sendTestV3Trap(t, config, user)
+	packet := receivePacket(t)
+	require.NotNil(t, packet)
+	require.Equal(t, gosnmp.Version3, packet.Content.Version)
+	assertV2Variables(t, packet)
+
+	// SNMPv2 traps are still accepted
+	sendTestV2Trap(t, config, "public")
+	packet = receivePacket(t)
+	require.NotNil(t, packet)
+	assertIsValidV2Packet(t, packet, config)
+}
+
+func TestServerV3BadCredentials(t *testing.T) {
+	user := UserV3{
+		Username:     "user",
+		EngineID:     "0x8000000001020304",
+		AuthProtocol: "sha",
+		AuthKey:      "password",
+		PrivProtocol: "des",
+		PrivKey:      "password",
+	}
+	config := Config{Port: GetPort(t), Users: []UserV3{user}}
+	Configure(t, config)
+
+	err := StartServer()
+	require.NoError(t, err)
+	defer StopServer()
+
+	wrongKey := user
+	wrongKey.AuthKey = "wrong-password"
+	sendTestV3Trap(t, config, wrongKey)
+	assertNoPacketReceived(t)
+
+	noPriv := user
+	noPriv.PrivProtocol = ""
+	noPriv.PrivKey = ""
+	sendTestV3Trap(t, config, noPriv)
+	assertNoPacketReceived(t)
+
+	noAuth := UserV3{Username: "user", EngineID: user.EngineID}
+	sendTestV3Trap(t, config, noAuth)
+	assertNoPacketReceived(t)
+
+	wrongUser := user
+	wrongUser.Username = "other"
+	sendTestV3Trap(t, config, wrongUser)
+	assertNoPacketReceived(t)
+}
+
+func TestServerV3MultipleUsers(t *testing.T) {
+	sha := UserV3{Username: "sha-user", AuthProtocol: "sha", AuthKey: "password", PrivProtocol: "des", PrivKey: "password"}
+	md5 := UserV3{Username: "md5-user", AuthProtocol: "md5", AuthKey: "other-password"}
+	config := Config{Port: GetPort(t), Users: []UserV3{sha, md5}}
+	Configure(t, config)
+
+	err := StartServer()
+	require.NoError(t, err)
+	defer StopServer()
+
+	// Without a configured engine ID, the traps of any device are accepted.
+	for _, engineID := range []string{"0x8000000001020304", "0x8000000005060708"} {
+		for _, user := range []UserV3{sha, md5} {
+			user.EngineID = engineID
+			sendTestV3Trap(t, config, user)
+			packet := receivePacket(t)
+			require.NotNil(t, packet)
+			require.Equal(t, gosnmp.Version3, packet.Content.Version)
+			assert.Equal(t, user.Username, packet.Content.SecurityParameters.(*gosnmp.UsmSecurityParameters).UserName)
+			assertV2Variables(t, packet)
+		}
+	}
+
+	// The keys of a user are not accepted for another one.
+	wrongUser := sha
+	wrongUser.Username = md5.Username
+	wrongUser.EngineID = "0x8000000001020304"
+	sendTestV3Trap(t, config, wrongUser)
+	assertNoPacketReceived(t)
+}
+
+func TestServerV3UsersWithTheSameName(t *testing.T) {
+	authPriv := UserV3{Username: "user", EngineID: "0x8000000001020304", AuthProtocol: "sha", AuthKey: "password", PrivProtocol: "des", PrivKey: "password"}
+	noAuth := UserV3{Username: "user", EngineID: "0x8000000005060708"}
+	md5 := UserV3{Username: "user", AuthProtocol: "md5", AuthKey: "other-password"}
+	config := Config{Port: GetPort(t), Users: []UserV3{authPriv, noAuth, md5}}
+	Configure(t, config)
+
+	err := StartServer()
+	require.NoError(t, err)
+	defer StopServer()
+
+	// Each trap is validated by the user whose parameters authenticate it.
+	for _, user := range []UserV3{authPriv, noAuth, md5} {
+		if user.EngineID == "" {
+			user.EngineID = "0x8000000009090909"
+		}
+		sendTestV3Trap(t, config, user)
+		packet := receivePacket(t)
+		require.NotNil(t, packet)
+		require.Equal(t, gosnmp.Version3, packet.Content.Version)
+		assertV2Variables(t, packet)
+	}
+
+	// A trap without authentication is only accepted from the engine of the user which doesn't require it.
+	lowerLevel := UserV3{Username: "user", EngineID: authPriv.EngineID}
+	sendTestV3Trap(t, config, lowerLevel)
+	assertNoPacketReceived(t)
+}
+
+func TestServerV3EngineID(t *testing.T) {
+	user := UserV3{Username: "user", EngineID: "0x8000000001020304", AuthProtocol: "sha", AuthKey: "password"}
+	config := Config{Port: GetPort(t), Users: []UserV3{user}}
+	Configure(t, config)
+
+	err := StartServer()
+	require.NoError(t, err)
+	defer StopServer()
+
+	otherEngine := user
+	otherEngine.EngineID = "0x8000000005060708"
+	sendTestV3Trap(t, config, otherEngine)
+	assertNoPacketReceived(t)
+
+	sendTestV3Trap(t, config, user)
+	packet := receivePacket(t)
+	require.NotNil(t, packet)
+	require.Equal(t, gosnmp.Version3, packet.Content.Version)
+}
+
 func TestStartFailure(t *testing.T) {
 	/*
 		Start two servers with the same config to trigger an "address already in use" error.
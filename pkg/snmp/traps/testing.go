@@ -32,6 +32,16 @@ var (
 		// heartBeatName
 		{Name: "1.3.6.1.4.1.8072.2.3.2.2", Type: gosnmp.OctetString, Value: "test"},
 	}
+
+	// The same notification sent as an SNMPv1 trap, where the trap OID and the uptime are part of the trap header.
+	NetSNMPExampleHeartbeatNotificationV1Trap = gosnmp.SnmpTrap{
+		Enterprise:   "1.3.6.1.4.1.8072.2.3",
+		AgentAddress: "127.0.0.1",
+		GenericTrap:  6, // enterpriseSpecific
+		SpecificTrap: 1,
+		Timestamp:    1000,
+		Variables:    NetSNMPExampleHeartbeatNotificationVariables[2:],
+	}
 )
 
 func parsePort(t *testing.T, addr string) uint16 {
@@ -84,6 +94,46 @@ func sendTestV2Trap(t *testing.T, trapConfig Config, community string) *gosnmp.G
 	return params
 }
 
+func sendTestV1Trap(t *testing.T, trapConfig Config, community string) *gosnmp.GoSNMP {
+	params := trapConfig.BuildV2Params()
+	params.Version = gosnmp.Version1
+	params.Community = community
+	params.Timeout = 1 * time.Second // Must be non-zero when sending traps.
+	params.Retries = 1               // Must be non-zero when sending traps.
+
+	err := params.Connect()
+	require.NoError(t, err)
+	defer params.Conn.Close()
+
+	_, err = params.SendTrap(NetSNMPExampleHeartbeatNotificationV1Trap)
+	require.NoError(t, err)
+
+	return params
+}
+
+func sendTestV3Trap(t *testing.T, trapConfig Config, user UserV3) *gosnmp.GoSNMP {
+	securityParams, err := user.buildSecurityParams()
+	require.NoError(t, err)
+
+	params := trapConfig.BuildV2Params()
+	params.Version = gosnmp.Version3
+	params.SecurityModel = gosnmp.UserSecurityModel
+	params.MsgFlags = user.securityLevel()
+	params.SecurityParameters = securityParams
+	params.Timeout = 1 * time.Second // Must be non-zero when sending traps.
+	params.Retries = 1               // Must be non-zero when sending traps.
+
+	err = params.Connect()
+	require.NoError(t, err)
+	defer params.Conn.Close()
+
+	trap := gosnmp.SnmpTrap{Variables: NetSNMPExampleHeartbeatNotificationVariables}
+	_, err = params.SendTrap(trap)
+	require.NoError(t, err)
+
+	return params
+}
+
 // receivePacket waits for a received trap packet and returns it.
 func receivePacket(t *testing.T) *SnmpPacket {
 	select {
@@ -0,0 +1,177 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2020 Datadog, Inc.
+
+package traps
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/soniah/gosnmp"
+)
+
+// Localizing the keys of a user is expensive, so the params are cached per engine ID.
+// The cache of a user is reset when it is full, to not grow with spoofed engine IDs.
+const maxEngineIDsPerUser = 100
+
+var errTruncatedPacket = errors.New("truncated packet")
+
+// securityParamsTable holds the SNMPv3 USM security parameters of the configured users, keyed by user name.
+// The keys of a user are localized with the authoritative engine ID of the device sending the trap, so
+// the GoSNMP params used to decode a trap are built from the user name and engine ID of the packet.
+type securityParamsTable struct {
+	users map[string][]*usmUser
+}
+
+type usmUser struct {
+	securityParams *gosnmp.UsmSecurityParameters
+	securityLevel  gosnmp.SnmpV3MsgFlags
+	params         map[string]*gosnmp.GoSNMP
+}
+
+func newSecurityParamsTable(users []UserV3) (*securityParamsTable, error) {
+	table := &securityParamsTable{users: make(map[string][]*usmUser)}
+	for _, user := range users {
+		securityParams, err := user.buildSecurityParams()
+		if err != nil {
+			return nil, fmt.Errorf("invalid SNMPv3 user %q: %s", user.Username, err)
+		}
+		securityParams.Logger = &trapLogger{}
+		table.users[user.Username] = append(table.users[user.Username], &usmUser{
+			securityParams: securityParams,
+			securityLevel:  user.securityLevel(),
+			params:         make(map[string]*gosnmp.GoSNMP),
+		})
+	}
+	return table, nil
+}
+
+// unmarshalTrap decodes an SNMPv3 trap packet with the security parameters of its user.
+// The packet is not modified.
+func (t *securityParamsTable) unmarshalTrap(packet []byte, engineID string, userName string) (*gosnmp.SnmpPacket, error) {
+	users, ok := t.users[userName]
+	if !ok {
+		return nil, fmt.Errorf("unknown user %q", userName)
+	}
+	for _, user := range users {
+		// A user configured with an engine ID only accepts the traps of this engine.
+		if user.securityParams.AuthoritativeEngineID != "" && user.securityParams.AuthoritativeEngineID != engineID {
+			continue
+		}
+		// GoSNMP decrypts the packet in place. It lets through packets whose security level is lower than
+		// the one of the user, these are left to another user with the same name, if any.
+		p := user.paramsFor(engineID).UnmarshalTrap(append([]byte(nil), packet...))
+		if p != nil && p.MsgFlags&gosnmp.AuthPriv >= user.securityLevel {
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("cannot authenticate or decrypt the trap of user %q", userName)
+}
+
+// paramsFor returns the GoSNMP params of the user for traps sent by the given engine.
+func (u *usmUser) paramsFor(engineID string) *gosnmp.GoSNMP {
+	if params, ok := u.params[engineID]; ok {
+		return params
+	}
+	if len(u.params) >= maxEngineIDsPerUser {
+		u.params = make(map[string]*gosnmp.GoSNMP)
+	}
+
+	securityParams := u.securityParams.Copy().(*gosnmp.UsmSecurityParameters)
+	securityParams.AuthoritativeEngineID = engineID
+	params := &gosnmp.GoSNMP{
+		Transport:          "udp",
+		Version:            gosnmp.Version3,
+		SecurityModel:      gosnmp.UserSecurityModel,
+		MsgFlags:           u.securityLevel,
+		SecurityParameters: securityParams,
+		Logger:             &trapLogger{},
+	}
+	u.params[engineID] = params
+	return params
+}
+
+// parsePacketHeader returns the SNMP version of a packet and, for SNMPv3 packets,
+// the authoritative engine ID and the user name of their USM security parameters.
+func parsePacketHeader(packet []byte) (gosnmp.SnmpVersion, string, string, error) {
+	message, err := readBERValue(packet, byte(gosnmp.Sequence))
+	if err != nil {
+		return 0, "", "", err
+	}
+	rawVersion, message, err := readBERField(message, byte(gosnmp.Integer))
+	if err != nil {
+		return 0, "", "", err
+	}
+	version := 0
+	for _, b := range rawVersion {
+		version = version<<8 | int(b)
+	}
+	if gosnmp.SnmpVersion(version) != gosnmp.Version3 {
+		return gosnmp.SnmpVersion(version), "", "", nil
+	}
+
+	// msgGlobalData
+	_, message, err = readBERField(message, byte(gosnmp.Sequence))
+	if err != nil {
+		return 0, "", "", err
+	}
+	rawSecurityParams, _, err := readBERField(message, byte(gosnmp.OctetString))
+	if err != nil {
+		return 0, "", "", err
+	}
+	securityParams, err := readBERValue(rawSecurityParams, byte(gosnmp.Sequence))
+	if err != nil {
+		return 0, "", "", err
+	}
+	engineID, securityParams, err := readBERField(securityParams, byte(gosnmp.OctetString))
+	if err != nil {
+		return 0, "", "", err
+	}
+	// msgAuthoritativeEngineBoots and msgAuthoritativeEngineTime
+	for i := 0; i < 2; i++ {
+		if _, securityParams, err = readBERField(securityParams, byte(gosnmp.Integer)); err != nil {
+			return 0, "", "", err
+		}
+	}
+	userName, _, err := readBERField(securityParams, byte(gosnmp.OctetString))
+	if err != nil {
+		return 0, "", "", err
+	}
+	return gosnmp.Version3, string(engineID), string(userName), nil
+}
+
+// readBERValue returns the value of the BER encoded field at the start of data.
+func readBERValue(data []byte, tag byte) ([]byte, error) {
+	value, _, err := readBERField(data, tag)
+	return value, err
+}
+
+// readBERField returns the value of the BER encoded field at the start of data,
+// and the data following the field.
+func readBERField(data []byte, tag byte) ([]byte, []byte, error) {
+	if len(data) < 2 {
+		return nil, nil, errTruncatedPacket
+	}
+	if data[0] != tag {
+		return nil, nil, fmt.Errorf("unexpected BER tag 0x%x, expected 0x%x", data[0], tag)
+	}
+	length := int(data[1])
+	data = data[2:]
+	if length&0x80 != 0 {
+		size := length & 0x7f
+		if size == 0 || size > 4 || len(data) < size {
+			return nil, nil, errors.New("invalid BER length")
+		}
+		length = 0
+		for _, b := range data[:size] {
+			length = length<<8 | int(b)
+		}
+		data = data[size:]
+	}
+	if length < 0 || length > len(data) {
+		return nil, nil, errTruncatedPacket
+	}
+	return data[:length], data[length:], nil
+}
@@ -40,10 +40,12 @@ type Agent struct {
 	Concentrator       *stats.Concentrator
 	Blacklister        *filters.Blacklister
 	Replacer           *filters.Replacer
+	RulesSampler       *sampler.RulesSampler
 	ScoreSampler       *Sampler
 	ErrorsScoreSampler *Sampler
 	ExceptionSampler   *sampler.ExceptionSampler
 	PrioritySampler    *Sampler
+	TailSampler        *sampler.TailSampler
 	EventProcessor     *event.Processor
 	TraceWriter        *writer.TraceWriter
 	StatsWriter        *writer.StatsWriter
@@ -73,6 +75,7 @@ func NewAgent(ctx context.Context, conf *config.AgentConfig) *Agent {
 		Concentrator:       stats.NewConcentrator(conf.BucketInterval.Nanoseconds(), statsChan),
 		Blacklister:        filters.NewBlacklister(conf.Ignore["resource"]),
 		Replacer:           filters.NewReplacer(conf.ReplaceTags),
+		RulesSampler:       sampler.NewRulesSampler(conf.SamplingRules),
 		ScoreSampler:       NewScoreSampler(conf),
 		ExceptionSampler:   sampler.NewExceptionSampler(),
 		ErrorsScoreSampler: NewErrorsSampler(conf),
@@ -85,6 +88,9 @@ func NewAgent(ctx context.Context, conf *config.AgentConfig) *Agent {
 		conf:               conf,
 		ctx:                ctx,
 	}
+	if conf.TailSampling != nil && conf.TailSampling.Enabled {
+		agnt.TailSampler = sampler.NewTailSampler(conf, agnt.writeTailSampledTraces)
+	}
 	agnt.Receiver = api.NewHTTPReceiver(conf, dynConf, in, agnt)
 	return agnt
 }
@@ -101,6 +107,9 @@ func (a *Agent) Run() {
 	} {
 		starter.Start()
 	}
+	if a.TailSampler != nil {
+		a.TailSampler.Start()
+	}
 
 	go a.TraceWriter.Run()
 	go a.StatsWriter.Run()
@@ -135,8 +144,13 @@ func (a *Agent) loop() {
 				log.Error(err)
 			}
 			a.Concentrator.Stop()
+			if a.TailSampler != nil {
+				// write the buffered traces before stopping the writer
+				a.TailSampler.Stop()
+			}
 			a.TraceWriter.Stop()
 			a.StatsWriter.Stop()
+			a.RulesSampler.Stop()
 			a.ScoreSampler.Stop()
 			a.ExceptionSampler.Stop()
 			a.ErrorsScoreSampler.Stop()
@@ -187,6 +201,10 @@ func (a *Agent) Process(p *api.Payload, sublayerCalculator *stats.SublayerCalcul
 		// Extra sanitization steps of the trace.
 		for _, span := range t {
 			a.obfuscator.Obfuscate(span)
+			if matched, obfuscated := a.obfuscator.ObfuscateWithRules(span); matched > 0 {
+				atomic.AddInt64(&ts.ObfuscationMatched, matched)
+				atomic.AddInt64(&ts.ObfuscationApplied, obfuscated)
+			}
 			Truncate(span)
 			if p.ClientComputedTopLevel {
 				traceutil.UpdateTracerTopLevel(span)
@@ -226,8 +244,10 @@ func (a *Agent) Process(p *api.Payload, sublayerCalculator *stats.SublayerCalcul
 		}
 
 		events, keep := a.sample(ts, pt)
+		// with tail sampling, the trace may be kept once all its chunks have been received
+		mayKeep := keep || a.TailSampler != nil
 
-		if sublayerCalculator.ShouldCompute(keep) {
+		if sublayerCalculator.ShouldCompute(mayKeep) {
 			pt.Sublayers = make(map[*pb.Span][]stats.SublayerValue)
 			subtraces := stats.ExtractSubtraces(t, root)
 			for _, subtrace := range subtraces {
@@ -235,7 +255,7 @@ func (a *Agent) Process(p *api.Payload, sublayerCalculator *stats.SublayerCalcul
 				if sublayerCalculator.WithStats() {
 					pt.Sublayers[subtrace.Root] = subtraceSublayers
 				}
-				if keep {
+				if mayKeep {
 					stats.SetSublayersOnSpan(subtrace.Root, subtraceSublayers)
 				}
 			}
@@ -246,7 +266,9 @@ func (a *Agent) Process(p *api.Payload, sublayerCalculator *stats.SublayerCalcul
 			Env:           pt.Env,
 			SublayersOnly: p.ClientComputedStats,
 		})
-		if keep {
+		if a.TailSampler != nil {
+			a.TailSampler.Add(pt.Env, t, keep)
+		} else if keep {
 			ss.Traces = append(ss.Traces, traceutil.APITrace(t))
 			ss.Size += t.Msgsize()
 			ss.SpanCount += int64(len(t))
@@ -268,6 +290,23 @@ func (a *Agent) Process(p *api.Payload, sublayerCalculator *stats.SublayerCalcul
 	}
 }
 
+// writeTailSampledTraces writes the traces kept by the tail sampler.
+func (a *Agent) writeTailSampledTraces(traces []pb.Trace) {
+	ss := new(writer.SampledSpans)
+	for _, t := range traces {
+		ss.Traces = append(ss.Traces, traceutil.APITrace(t))
+		ss.Size += t.Msgsize()
+		ss.SpanCount += int64(len(t))
+		if ss.Size > writer.MaxPayloadSize {
+			a.TraceWriter.In <- ss
+			ss = new(writer.SampledSpans)
+		}
+	}
+	if ss.Size > 0 {
+		a.TraceWriter.In <- ss
+	}
+}
+
 var _ api.StatsProcessor = (*Agent)(nil)
 
 // ProcessStats processes incoming client stats in from the given language lang.
@@ -372,7 +411,7 @@ func (a *Agent) sample(ts *info.TagStats, pt ProcessedTrace) (events []*pb.Span,
 		return nil, false
 	}
 
-	sampled := a.runSamplers(pt, hasPriority)
+	sampled := a.runSamplers(pt, priority, hasPriority)
 
 	events, numExtracted := a.EventProcessor.Process(pt.Root, pt.Trace)
 
@@ -383,8 +422,12 @@ func (a *Agent) sample(ts *info.TagStats, pt ProcessedTrace) (events []*pb.Span,
 }
 
 // runSamplers runs all the agent's samplers on pt and returns the sampling decision
-// along with the sampling rate.
-func (a *Agent) runSamplers(pt ProcessedTrace, hasPriority bool) bool {
+// along with the sampling rate. The user-defined sampling rules take precedence over the
+// other samplers, but never drop a trace explicitly kept by the user.
+func (a *Agent) runSamplers(pt ProcessedTrace, priority sampler.SamplingPriority, hasPriority bool) bool {
+	if matched, sampled := a.RulesSampler.Sample(pt.Env, pt.Root); matched {
+		return sampled || priority == sampler.PriorityUserKeep
+	}
 	if hasPriority {
 		return a.samplePriorityTrace(pt)
 	}
@@ -103,6 +103,84 @@ func TestProcess(t *testing.T) {
 		assert.Equal("SELECT name FROM people WHERE age = ? AND extra = ?", span.Meta["sql.query"])
 	})
 
+	t.Run("ObfuscationRules", func(t *testing.T) {
+		cfg := config.New()
+		cfg.Endpoints[0].APIKey = "test"
+		cfg.Obfuscation = &config.ObfuscationConfig{Rules: []*config.ObfuscationRule{{
+			ServiceRe: regexp.MustCompile("^billing$"),
+			TagsRe:    []*regexp.Regexp{regexp.MustCompile(`^card\..*$`)},
+			Action:    config.ObfuscationActionMaskDigits,
+		}}}
+		ctx, cancel := context.WithCancel(context.Background())
+		agnt := NewAgent(ctx, cfg)
+		defer cancel()
+
+		now := time.Now()
+		span := &pb.Span{
+			TraceID:  1,
+			SpanID:   1,
+			Service:  "billing",
+			Resource: "charge",
+			Start:    now.Add(-time.Second).UnixNano(),
+			Duration: (500 * time.Millisecond).Nanoseconds(),
+			Meta:     map[string]string{"card.number": "4111", "card.type": "visa"},
+		}
+		ts := info.NewReceiverStats().GetTagStats(info.Tags{})
+		agnt.Process(&api.Payload{
+			Traces: pb.Traces{{span}},
+			Source: ts,
+		}, stats.NewSublayerCalculator())
+
+		assert := assert.New(t)
+		assert.Equal("****", span.Meta["card.number"])
+		assert.Equal("visa", span.Meta["card.type"])
+		assert.EqualValues(2, ts.ObfuscationMatched)
+		assert.EqualValues(1, ts.ObfuscationApplied)
+	})
+
+	t.Run("ObfuscationRulesSkipInternalMetrics", func(t *testing.T) {
+		cfg := config.New()
+		cfg.Endpoints[0].APIKey = "test"
+		// the "*" metrics pattern
+		cfg.Obfuscation = &config.ObfuscationConfig{Rules: []*config.ObfuscationRule{{
+			MetricsRe: []*regexp.Regexp{regexp.MustCompile(`^.*$`)},
+			Action:    config.ObfuscationActionRedact,
+		}}}
+		ctx, cancel := context.WithCancel(context.Background())
+		agnt := NewAgent(ctx, cfg)
+		defer cancel()
+
+		now := time.Now()
+		span := &pb.Span{
+			TraceID:  1,
+			SpanID:   1,
+			Service:  "billing",
+			Resource: "charge",
+			Start:    now.Add(-time.Second).UnixNano(),
+			Duration: (500 * time.Millisecond).Nanoseconds(),
+			Metrics: map[string]float64{
+				"_sampling_priority_v1":      1,
+				"_sampling_priority_rate_v1": 0.5,
+				"_sample_rate":               0.5,
+				"_dd1.sr.rcusr":              0.5,
+				"balance":                    42,
+			},
+		}
+		ts := info.NewReceiverStats().GetTagStats(info.Tags{})
+		agnt.Process(&api.Payload{
+			Traces: pb.Traces{{span}},
+			Source: ts,
+		}, stats.NewSublayerCalculator())
+
+		assert := assert.New(t)
+		assert.NotContains(span.Metrics, "balance")
+		for _, k := range []string{"_sampling_priority_v1", "_sampling_priority_rate_v1", "_sample_rate", "_dd1.sr.rcusr"} {
+			assert.Contains(span.Metrics, k)
+		}
+		assert.EqualValues(1, ts.ObfuscationMatched)
+		assert.EqualValues(1, ts.ObfuscationApplied)
+	})
+
 	t.Run("Blacklister", func(t *testing.T) {
 		cfg := config.New()
 		cfg.Endpoints[0].APIKey = "test"
@@ -193,6 +271,57 @@ func TestProcess(t *testing.T) {
 		assert.Equal("unnamed_operation", span.Name)
 	})
 
+	t.Run("TailSampler", func(t *testing.T) {
+		cfg := config.New()
+		cfg.Endpoints[0].APIKey = "test"
+		cfg.TailSampling.Enabled = true
+		ctx, cancel := context.WithCancel(context.Background())
+		agnt := NewAgent(ctx, cfg)
+		agnt.TailSampler.Start()
+		defer cancel()
+
+		now := time.Now()
+		root := &pb.Span{
+			TraceID:  1,
+			SpanID:   1,
+			Resource: "GET /",
+			Start:    now.Add(-time.Second).UnixNano(),
+			Duration: (500 * time.Millisecond).Nanoseconds(),
+			Metrics:  map[string]float64{sampler.KeySamplingPriority: 0},
+		}
+		child := &pb.Span{
+			TraceID:  1,
+			SpanID:   2,
+			ParentID: 1,
+			Resource: "SELECT 1",
+			Start:    now.Add(-time.Second).UnixNano(),
+			Duration: (100 * time.Millisecond).Nanoseconds(),
+			Error:    1,
+			Metrics:  map[string]float64{sampler.KeySamplingPriority: 0},
+		}
+		// the chunks of the trace are received in two payloads
+		for _, chunk := range []*pb.Span{root, child} {
+			agnt.Process(&api.Payload{
+				Traces: pb.Traces{{chunk}},
+				Source: info.NewReceiverStats().GetTagStats(info.Tags{}),
+			}, stats.NewSublayerCalculator())
+		}
+		select {
+		case <-agnt.TraceWriter.In:
+			t.Fatal("the trace must be buffered")
+		default:
+		}
+
+		go agnt.TailSampler.Stop()
+		select {
+		case ss := <-agnt.TraceWriter.In:
+			assert.Len(t, ss.Traces, 1)
+			assert.ElementsMatch(t, []*pb.Span{root, child}, ss.Traces[0].Spans)
+		case <-time.After(2 * time.Second):
+			t.Fatal("timeout: Expected the whole trace to be kept.")
+		}
+	})
+
 	t.Run("ContainerTags", func(t *testing.T) {
 		cfg := config.New()
 		cfg.Endpoints[0].APIKey = "test"
@@ -623,10 +752,12 @@ func TestSampling(t *testing.T) {
 	} {
 		t.Run(name, func(t *testing.T) {
 			a := &Agent{
+				RulesSampler:       sampler.NewRulesSampler(nil),
 				ScoreSampler:       newMockSampler(tt.scoreSampled),
 				ErrorsScoreSampler: newMockSampler(tt.scoreErrorSampled),
 				PrioritySampler:    newMockSampler(tt.prioritySampled),
 			}
+			defer a.RulesSampler.Stop()
 			root := &pb.Span{
 				Service:  "serv1",
 				Start:    time.Now().UnixNano(),
@@ -642,12 +773,54 @@ func TestSampling(t *testing.T) {
 				sampler.SetSamplingPriority(pt.Root, 1)
 			}
 
-			sampled := a.runSamplers(pt, tt.hasPriority)
+			sampled := a.runSamplers(pt, 1, tt.hasPriority)
 			assert.EqualValues(t, tt.wantSampled, sampled)
 		})
 	}
 }
 
+func TestSampleWithRules(t *testing.T) {
+	keep, drop := 1.0, 0.0
+	rules := []*config.SamplingRule{
+		{ServiceRe: regexp.MustCompile("^checkout$"), SampleRate: &keep},
+		{ResourceRe: regexp.MustCompile("^GET /health$"), SampleRate: &drop},
+	}
+	for name, tt := range map[string]struct {
+		service     string
+		resource    string
+		priority    sampler.SamplingPriority
+		hasPriority bool
+		wantSampled bool
+	}{
+		"rule-keeps-no-priority":   {service: "checkout", wantSampled: true},
+		"rule-keeps-auto-drop":     {service: "checkout", priority: 0, hasPriority: true, wantSampled: true},
+		"rule-drops-auto-keep":     {service: "web", resource: "GET /health", priority: 1, hasPriority: true, wantSampled: false},
+		"rule-drops-user-keep":     {service: "web", resource: "GET /health", priority: 2, hasPriority: true, wantSampled: true},
+		"no-rule-priority-sampler": {service: "web", resource: "GET /", priority: 1, hasPriority: true, wantSampled: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			a := &Agent{
+				RulesSampler:       sampler.NewRulesSampler(rules),
+				ScoreSampler:       newMockSampler(false),
+				ErrorsScoreSampler: newMockSampler(false),
+				PrioritySampler:    newMockSampler(true),
+			}
+			defer a.RulesSampler.Stop()
+			root := &pb.Span{
+				Service:  tt.service,
+				Resource: tt.resource,
+				TraceID:  1,
+				Metrics:  map[string]float64{},
+			}
+			if tt.hasPriority {
+				sampler.SetSamplingPriority(root, tt.priority)
+			}
+			pt := ProcessedTrace{Trace: pb.Trace{root}, Root: root}
+			assert.Equal(t, tt.wantSampled, a.runSamplers(pt, tt.priority, tt.hasPriority))
+		})
+	}
+}
+
 func TestEventProcessorFromConf(t *testing.T) {
 	if _, ok := os.LookupEnv("INTEGRATION"); !ok {
 		t.Skip("set INTEGRATION environment variable to run")
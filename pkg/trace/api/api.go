@@ -72,6 +72,7 @@ type HTTPReceiver struct {
 	dynConf        *sampler.DynamicConfig
 	server         *http.Server
 	statsProcessor StatsProcessor
+	otlp           *OTLPReceiver // OpenTelemetry receiver; nil when disabled
 
 	debug               bool
 	rateLimiterResponse int // HTTP status code when refusing
@@ -86,7 +87,7 @@ func NewHTTPReceiver(conf *config.AgentConfig, dynConf *sampler.DynamicConfig, o
 	if config.HasFeature("429") {
 		rateLimiterResponse = http.StatusTooManyRequests
 	}
-	return &HTTPReceiver{
+	r := &HTTPReceiver{
 		Stats:       info.NewReceiverStats(),
 		RateLimiter: newRateLimiter(),
 
@@ -100,6 +101,10 @@ func NewHTTPReceiver(conf *config.AgentConfig, dynConf *sampler.DynamicConfig, o
 
 		exit: make(chan struct{}),
 	}
+	if conf.OTLPReceiver != nil {
+		r.otlp = NewOTLPReceiver(r, conf)
+	}
+	return r
 }
 
 func (r *HTTPReceiver) buildMux() *http.ServeMux {
@@ -120,6 +125,15 @@ func (r *HTTPReceiver) buildMux() *http.ServeMux {
 	mux.HandleFunc("/v0.5/traces", r.handleWithVersion(v05, r.handleTraces))
 	mux.HandleFunc("/v0.5/stats", r.handleStats)
 	mux.Handle("/profiling/v1/input", r.profileProxyHandler())
+	mux.HandleFunc("/api/v2/spans", r.handleCompatTraces(zipkinV2, map[string]compatDecoder{
+		"application/json":       decodeZipkinJSON,
+		"application/x-protobuf": decodeZipkinProto,
+		"application/protobuf":   decodeZipkinProto,
+	}))
+	mux.HandleFunc("/api/traces", r.handleCompatTraces(jaegerThrift, map[string]compatDecoder{
+		"application/x-thrift":                 decodeJaegerThrift,
+		"application/vnd.apache.thrift.binary": decodeJaegerThrift,
+	}))
 
 	return mux
 }
@@ -181,6 +195,10 @@ func (r *HTTPReceiver) Start() {
 		log.Infof("Listening for traces on Windowes pipe %q. Security descriptor is %q", pipepath, secdec)
 	}
 
+	if r.otlp != nil {
+		r.otlp.Start()
+	}
+
 	go r.RateLimiter.Run()
 
 	go func() {
@@ -278,6 +296,9 @@ func (r *HTTPReceiver) Stop() error {
 	if err := r.server.Shutdown(ctx); err != nil {
 		return err
 	}
+	if r.otlp != nil {
+		r.otlp.Stop()
+	}
 	r.wg.Wait()
 	close(r.out)
 	return nil
@@ -480,6 +501,11 @@ func (r *HTTPReceiver) handleTraces(v Version, w http.ResponseWriter, req *http.
 		ClientComputedTopLevel: req.Header.Get(headerComputedTopLevel) != "",
 		ClientComputedStats:    req.Header.Get(headerComputedStats) != "",
 	}
+	r.sendPayload(payload)
+}
+
+// sendPayload sends a payload to the output channel without blocking the request.
+func (r *HTTPReceiver) sendPayload(payload *Payload) {
 	select {
 	case r.out <- payload:
 		// ok
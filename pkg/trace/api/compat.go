@@ -0,0 +1,158 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/metrics"
+	"github.com/DataDog/datadog-agent/pkg/trace/metrics/timing"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// compatDecoder decodes the Datadog traces found in a request body encoded in another format.
+type compatDecoder func(b []byte) (pb.Traces, error)
+
+// handleCompatTraces returns a handler for the traces received in the Zipkin or Jaeger formats,
+// decoded with the decoder registered for the media type of the request. The converted traces
+// go through the same pipeline as the Datadog traces.
+func (r *HTTPReceiver) handleCompatTraces(v Version, decoders map[string]compatDecoder) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		defer timing.Since("datadog.trace_agent.receiver.compat_process_ms", time.Now())
+
+		mediaType := getMediaType(req)
+		decode, ok := decoders[mediaType]
+		if !ok {
+			httpFormatError(w, v, fmt.Errorf("unsupported media type: %q", mediaType))
+			return
+		}
+
+		ts := r.tagStats(v, req)
+		tags := []string{"handler:traces", "v:" + string(v)}
+		slurp, n, err := readRequestBody(req, r.conf.MaxRequestBytes)
+		var traces pb.Traces
+		if err == nil {
+			traces, err = decode(slurp)
+		}
+		if err != nil {
+			httpDecodingError(err, tags, w)
+			log.Errorf("Cannot decode %s traces payload: %v", v, err)
+			return
+		}
+		if r.rateLimited(int64(len(traces))) {
+			// this payload can not be accepted
+			w.WriteHeader(r.rateLimiterResponse)
+			atomic.AddInt64(&ts.PayloadRefused, 1)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		metrics.Count("datadog.trace_agent.receiver.compat_spans", int64(countSpans(traces)), tags, 1)
+
+		atomic.AddInt64(&ts.TracesReceived, int64(len(traces)))
+		atomic.AddInt64(&ts.TracesBytes, n)
+		atomic.AddInt64(&ts.PayloadAccepted, 1)
+
+		r.sendPayload(&Payload{
+			Source:        ts,
+			Traces:        traces,
+			ContainerTags: getContainerTags(req.Header.Get(headerContainerID)),
+		})
+	}
+}
+
+// groupByTraceID groups the spans into traces, keeping the order in which the traces were first seen.
+func groupByTraceID(spans []*pb.Span) pb.Traces {
+	var traces pb.Traces
+	index := make(map[uint64]int)
+	for _, s := range spans {
+		i, ok := index[s.TraceID]
+		if !ok {
+			i = len(traces)
+			index[s.TraceID] = i
+			traces = append(traces, nil)
+		}
+		traces[i] = append(traces[i], s)
+	}
+	return traces
+}
+
+func countSpans(traces pb.Traces) int {
+	n := 0
+	for _, t := range traces {
+		n += len(t)
+	}
+	return n
+}
+
+// compatSpanKind returns the span kind matching the given Zipkin or OpenTracing span kind.
+// Spans without kind are internal.
+func compatSpanKind(kind string) otlpSpanKind {
+	switch strings.ToLower(kind) {
+	case "server":
+		return otlpSpanKindServer
+	case "client":
+		return otlpSpanKindClient
+	case "producer":
+		return otlpSpanKindProducer
+	case "consumer":
+		return otlpSpanKindConsumer
+	default:
+		return otlpSpanKindInternal
+	}
+}
+
+// finishCompatSpan sets the name, type, resource, env and error of a span converted from
+// the Zipkin or Jaeger formats, based on its kind and its tags, the same way as for the
+// OpenTelemetry spans. The resource of the span must be set to its operation name.
+func finishCompatSpan(span *pb.Span, kind otlpSpanKind) {
+	span.Name = spanKindName(kind)
+	if _, ok := span.Meta["span.kind"]; !ok {
+		span.Meta["span.kind"] = spanKindName(kind)
+	}
+
+	span.Type = spanType(kind, span.Meta)
+	if db := span.Meta["db.type"]; db != "" && span.Meta["db.system"] == "" {
+		// OpenTracing semantic conventions
+		span.Type = spanType(kind, map[string]string{"db.system": db})
+	}
+	if kind == otlpSpanKindServer {
+		if method, route := span.Meta["http.method"], span.Meta["http.route"]; method != "" && route != "" {
+			span.Resource = method + " " + route
+		}
+	}
+	if span.Type == "sql" {
+		// use the query as resource so that it gets obfuscated
+		for _, k := range []string{"db.statement", "sql.query"} {
+			if stmt := span.Meta[k]; stmt != "" {
+				span.Resource = stmt
+				break
+			}
+		}
+	}
+
+	if env := span.Meta["deployment.environment"]; env != "" && span.Meta["env"] == "" {
+		span.Meta["env"] = env
+	}
+	if version := span.Meta["service.version"]; version != "" && span.Meta["version"] == "" {
+		span.Meta["version"] = version
+	}
+	if v, ok := span.Meta["error"]; ok && v != "false" {
+		// Zipkin sets the error message as value, OpenTracing sets true
+		span.Error = 1
+		if v != "" && v != "true" && span.Meta["error.msg"] == "" {
+			span.Meta["error.msg"] = v
+		}
+	}
+}
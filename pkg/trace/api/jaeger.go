@@ -0,0 +1,568 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package api
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
+)
+
+// jaegerBatch is a batch of spans reported by a Jaeger client, as defined in:
+//
+//	https://github.com/jaegertracing/jaeger-idl/blob/master/thrift/jaeger.thrift
+type jaegerBatch struct {
+	Process jaegerProcess
+	Spans   []jaegerSpan
+}
+
+// jaegerProcess describes the traced process.
+type jaegerProcess struct {
+	ServiceName string
+	Tags        []jaegerTag
+}
+
+// jaegerSpan is a Jaeger span.
+type jaegerSpan struct {
+	TraceIDLow    uint64
+	TraceIDHigh   uint64
+	SpanID        uint64
+	ParentSpanID  uint64
+	OperationName string
+	References    []jaegerSpanRef
+	Flags         int32
+	StartTime     int64 // microseconds
+	Duration      int64 // microseconds
+	Tags          []jaegerTag
+	Logs          []jaegerLog
+}
+
+// jaegerSpanRef is a reference from a span to another span.
+type jaegerSpanRef struct {
+	RefType     int32
+	TraceIDLow  uint64
+	TraceIDHigh uint64
+	SpanID      uint64
+}
+
+// jaegerLog is a timed event with fields.
+type jaegerLog struct {
+	Timestamp int64 // microseconds
+	Fields    []jaegerTag
+}
+
+// jaegerTag is a typed key/value pair.
+type jaegerTag struct {
+	Key     string
+	VType   int32
+	VStr    string
+	VDouble float64
+	VBool   bool
+	VLong   int64
+	VBinary []byte
+}
+
+// Jaeger tag value types.
+const (
+	jaegerTagString = 0
+	jaegerTagDouble = 1
+	jaegerTagBool   = 2
+	jaegerTagLong   = 3
+	jaegerTagBinary = 4
+)
+
+const (
+	// jaegerRefChildOf is the type of the reference from a span to its parent.
+	jaegerRefChildOf = 0
+	// jaegerFlagDebug is set on the spans which must be kept.
+	jaegerFlagDebug = 2
+)
+
+// decodeJaegerThrift decodes a Jaeger batch encoded with the Thrift binary protocol.
+func decodeJaegerThrift(b []byte) (pb.Traces, error) {
+	var batch jaegerBatch
+	r := thriftReader{buf: b}
+	if err := batch.unmarshalThrift(&r); err != nil {
+		return nil, err
+	}
+	spans := make([]*pb.Span, 0, len(batch.Spans))
+	for _, s := range batch.Spans {
+		spans = append(spans, convertJaegerSpan(batch.Process, s))
+	}
+	return groupByTraceID(spans), nil
+}
+
+// convertJaegerSpan converts a Jaeger span reported by the given process into a Datadog span.
+func convertJaegerSpan(process jaegerProcess, in jaegerSpan) *pb.Span {
+	span := &pb.Span{
+		TraceID:  in.TraceIDLow,
+		SpanID:   in.SpanID,
+		ParentID: in.ParentSpanID,
+		Service:  process.ServiceName,
+		Resource: in.OperationName,
+		Start:    in.StartTime * 1000,
+		Duration: in.Duration * 1000,
+		Meta:     make(map[string]string, len(process.Tags)+len(in.Tags)+1),
+		Metrics:  make(map[string]float64),
+	}
+	if span.ParentID == 0 {
+		for _, ref := range in.References {
+			if ref.RefType == jaegerRefChildOf && ref.TraceIDLow == in.TraceIDLow {
+				span.ParentID = ref.SpanID
+				break
+			}
+		}
+	}
+	for _, tag := range process.Tags {
+		setJaegerTag(span, tag)
+	}
+	for _, tag := range in.Tags {
+		setJaegerTag(span, tag)
+	}
+	span.Meta["jaeger.trace_id"] = fmt.Sprintf("%016x%016x", in.TraceIDHigh, in.TraceIDLow)
+	if len(in.Logs) > 0 {
+		setJaegerLogs(span, in.Logs)
+	}
+	if in.Flags&jaegerFlagDebug != 0 {
+		sampler.SetSamplingPriority(span, sampler.PriorityUserKeep)
+	}
+	finishCompatSpan(span, compatSpanKind(span.Meta["span.kind"]))
+	return span
+}
+
+// setJaegerTag sets the numeric tags as metrics and the other ones as meta on the span.
+func setJaegerTag(span *pb.Span, tag jaegerTag) {
+	switch tag.VType {
+	case jaegerTagDouble:
+		span.Metrics[tag.Key] = tag.VDouble
+	case jaegerTagLong:
+		span.Metrics[tag.Key] = float64(tag.VLong)
+	default:
+		span.Meta[tag.Key] = tag.value()
+	}
+}
+
+// setJaegerLogs stores the logs of a span in its meta, and sets the error related tags
+// from the OpenTracing error log fields.
+func setJaegerLogs(span *pb.Span, logs []jaegerLog) {
+	type logJSON struct {
+		Timestamp int64             `json:"timestamp"`
+		Fields    map[string]string `json:"fields"`
+	}
+	out := make([]logJSON, 0, len(logs))
+	for _, l := range logs {
+		fields := make(map[string]string, len(l.Fields))
+		for _, f := range l.Fields {
+			fields[f.Key] = f.value()
+		}
+		out = append(out, logJSON{Timestamp: l.Timestamp, Fields: fields})
+		if fields["event"] != "error" {
+			continue
+		}
+		for k, tag := range map[string]string{
+			"message":      "error.msg",
+			"error.object": "error.msg",
+			"error.kind":   "error.type",
+			"stack":        "error.stack",
+		} {
+			if v := fields[k]; v != "" && span.Meta[tag] == "" {
+				span.Meta[tag] = v
+			}
+		}
+	}
+	if b, err := json.Marshal(out); err == nil {
+		span.Meta["jaeger.logs"] = string(b)
+	}
+}
+
+// value returns the value of the tag as a string.
+func (t jaegerTag) value() string {
+	switch t.VType {
+	case jaegerTagDouble:
+		return strconv.FormatFloat(t.VDouble, 'g', -1, 64)
+	case jaegerTagBool:
+		return strconv.FormatBool(t.VBool)
+	case jaegerTagLong:
+		return strconv.FormatInt(t.VLong, 10)
+	case jaegerTagBinary:
+		return base64.StdEncoding.EncodeToString(t.VBinary)
+	default:
+		return t.VStr
+	}
+}
+
+func (b *jaegerBatch) unmarshalThrift(r *thriftReader) error {
+	return r.readStruct(func(field int16, typ byte) error {
+		switch {
+		case field == 1 && typ == thriftStruct:
+			return b.Process.unmarshalThrift(r)
+		case field == 2 && typ == thriftList:
+			return r.readList(thriftStruct, func() error {
+				var s jaegerSpan
+				if err := s.unmarshalThrift(r); err != nil {
+					return err
+				}
+				b.Spans = append(b.Spans, s)
+				return nil
+			})
+		default:
+			return r.skip(typ)
+		}
+	})
+}
+
+func (p *jaegerProcess) unmarshalThrift(r *thriftReader) error {
+	return r.readStruct(func(field int16, typ byte) error {
+		var err error
+		switch {
+		case field == 1 && typ == thriftString:
+			p.ServiceName, err = r.string()
+		case field == 2 && typ == thriftList:
+			p.Tags, err = readJaegerTags(r)
+		default:
+			err = r.skip(typ)
+		}
+		return err
+	})
+}
+
+func (s *jaegerSpan) unmarshalThrift(r *thriftReader) error {
+	return r.readStruct(func(field int16, typ byte) error {
+		var err error
+		var v int64
+		switch {
+		case field == 1 && typ == thriftI64:
+			v, err = r.i64()
+			s.TraceIDLow = uint64(v)
+		case field == 2 && typ == thriftI64:
+			v, err = r.i64()
+			s.TraceIDHigh = uint64(v)
+		case field == 3 && typ == thriftI64:
+			v, err = r.i64()
+			s.SpanID = uint64(v)
+		case field == 4 && typ == thriftI64:
+			v, err = r.i64()
+			s.ParentSpanID = uint64(v)
+		case field == 5 && typ == thriftString:
+			s.OperationName, err = r.string()
+		case field == 6 && typ == thriftList:
+			err = r.readList(thriftStruct, func() error {
+				var ref jaegerSpanRef
+				if err := ref.unmarshalThrift(r); err != nil {
+					return err
+				}
+				s.References = append(s.References, ref)
+				return nil
+			})
+		case field == 7 && typ == thriftI32:
+			s.Flags, err = r.i32()
+		case field == 8 && typ == thriftI64:
+			s.StartTime, err = r.i64()
+		case field == 9 && typ == thriftI64:
+			s.Duration, err = r.i64()
+		case field == 10 && typ == thriftList:
+			s.Tags, err = readJaegerTags(r)
+		case field == 11 && typ == thriftList:
+			err = r.readList(thriftStruct, func() error {
+				var l jaegerLog
+				if err := l.unmarshalThrift(r); err != nil {
+					return err
+				}
+				s.Logs = append(s.Logs, l)
+				return nil
+			})
+		default:
+			err = r.skip(typ)
+		}
+		return err
+	})
+}
+
+func (ref *jaegerSpanRef) unmarshalThrift(r *thriftReader) error {
+	return r.readStruct(func(field int16, typ byte) error {
+		var err error
+		var v int64
+		switch {
+		case field == 1 && typ == thriftI32:
+			ref.RefType, err = r.i32()
+		case field == 2 && typ == thriftI64:
+			v, err = r.i64()
+			ref.TraceIDLow = uint64(v)
+		case field == 3 && typ == thriftI64:
+			v, err = r.i64()
+			ref.TraceIDHigh = uint64(v)
+		case field == 4 && typ == thriftI64:
+			v, err = r.i64()
+			ref.SpanID = uint64(v)
+		default:
+			err = r.skip(typ)
+		}
+		return err
+	})
+}
+
+func (l *jaegerLog) unmarshalThrift(r *thriftReader) error {
+	return r.readStruct(func(field int16, typ byte) error {
+		var err error
+		switch {
+		case field == 1 && typ == thriftI64:
+			l.Timestamp, err = r.i64()
+		case field == 2 && typ == thriftList:
+			l.Fields, err = readJaegerTags(r)
+		default:
+			err = r.skip(typ)
+		}
+		return err
+	})
+}
+
+func (t *jaegerTag) unmarshalThrift(r *thriftReader) error {
+	return r.readStruct(func(field int16, typ byte) error {
+		var err error
+		switch {
+		case field == 1 && typ == thriftString:
+			t.Key, err = r.string()
+		case field == 2 && typ == thriftI32:
+			t.VType, err = r.i32()
+		case field == 3 && typ == thriftString:
+			t.VStr, err = r.string()
+		case field == 4 && typ == thriftDouble:
+			t.VDouble, err = r.double()
+		case field == 5 && typ == thriftBool:
+			t.VBool, err = r.bool()
+		case field == 6 && typ == thriftI64:
+			t.VLong, err = r.i64()
+		case field == 7 && typ == thriftString:
+			t.VBinary, err = r.bytes()
+		default:
+			err = r.skip(typ)
+		}
+		return err
+	})
+}
+
+func readJaegerTags(r *thriftReader) ([]jaegerTag, error) {
+	var tags []jaegerTag
+	err := r.readList(thriftStruct, func() error {
+		var t jaegerTag
+		if err := t.unmarshalThrift(r); err != nil {
+			return err
+		}
+		tags = append(tags, t)
+		return nil
+	})
+	return tags, err
+}
+
+// Thrift type identifiers.
+const (
+	thriftStop   = 0
+	thriftBool   = 2
+	thriftByte   = 3
+	thriftDouble = 4
+	thriftI16    = 6
+	thriftI32    = 8
+	thriftI64    = 10
+	thriftString = 11
+	thriftStruct = 12
+	thriftMap    = 13
+	thriftSet    = 14
+	thriftList   = 15
+)
+
+// thriftMaxDepth limits the nesting of the skipped values.
+const thriftMaxDepth = 64
+
+// errThriftTruncated is returned when a message ends unexpectedly.
+var errThriftTruncated = errors.New("thrift: unexpected end of message")
+
+// thriftReader reads values encoded with the Thrift binary protocol.
+type thriftReader struct {
+	buf   []byte
+	depth int
+}
+
+func (r *thriftReader) next(n int) ([]byte, error) {
+	if n < 0 || len(r.buf) < n {
+		return nil, errThriftTruncated
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b, nil
+}
+
+func (r *thriftReader) byte() (byte, error) {
+	b, err := r.next(1)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+func (r *thriftReader) bool() (bool, error) {
+	b, err := r.byte()
+	return b != 0, err
+}
+
+func (r *thriftReader) i16() (int16, error) {
+	b, err := r.next(2)
+	if err != nil {
+		return 0, err
+	}
+	return int16(binary.BigEndian.Uint16(b)), nil
+}
+
+func (r *thriftReader) i32() (int32, error) {
+	b, err := r.next(4)
+	if err != nil {
+		return 0, err
+	}
+	return int32(binary.BigEndian.Uint32(b)), nil
+}
+
+func (r *thriftReader) i64() (int64, error) {
+	b, err := r.next(8)
+	if err != nil {
+		return 0, err
+	}
+	return int64(binary.BigEndian.Uint64(b)), nil
+}
+
+func (r *thriftReader) double() (float64, error) {
+	v, err := r.i64()
+	return math.Float64frombits(uint64(v)), err
+}
+
+func (r *thriftReader) bytes() ([]byte, error) {
+	n, err := r.i32()
+	if err != nil {
+		return nil, err
+	}
+	return r.next(int(n))
+}
+
+func (r *thriftReader) string() (string, error) {
+	b, err := r.bytes()
+	return string(b), err
+}
+
+// readStruct calls fn with the identifier and the type of each field of a struct,
+// fn must read or skip the value of the field.
+func (r *thriftReader) readStruct(fn func(field int16, typ byte) error) error {
+	for {
+		typ, err := r.byte()
+		if err != nil {
+			return err
+		}
+		if typ == thriftStop {
+			return nil
+		}
+		field, err := r.i16()
+		if err != nil {
+			return err
+		}
+		if err := fn(field, typ); err != nil {
+			return err
+		}
+	}
+}
+
+// readList reads the header of a list, checks the type of its elements and calls fn
+// to read each of them.
+func (r *thriftReader) readList(elem byte, fn func() error) error {
+	typ, n, err := r.listHeader()
+	if err != nil {
+		return err
+	}
+	if typ != elem && n > 0 {
+		return fmt.Errorf("thrift: list has elements of type %d, expected %d", typ, elem)
+	}
+	for i := 0; i < n; i++ {
+		if err := fn(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *thriftReader) listHeader() (byte, int, error) {
+	typ, err := r.byte()
+	if err != nil {
+		return 0, 0, err
+	}
+	n, err := r.i32()
+	if err != nil {
+		return 0, 0, err
+	}
+	if n < 0 || int(n) > len(r.buf) {
+		// every element takes at least one byte
+		return 0, 0, errThriftTruncated
+	}
+	return typ, int(n), nil
+}
+
+// skip discards a value of the given type.
+func (r *thriftReader) skip(typ byte) error {
+	r.depth++
+	defer func() { r.depth-- }()
+	if r.depth > thriftMaxDepth {
+		return errors.New("thrift: maximum depth exceeded")
+	}
+	var err error
+	switch typ {
+	case thriftBool, thriftByte:
+		_, err = r.next(1)
+	case thriftI16:
+		_, err = r.next(2)
+	case thriftI32:
+		_, err = r.next(4)
+	case thriftDouble, thriftI64:
+		_, err = r.next(8)
+	case thriftString:
+		_, err = r.bytes()
+	case thriftStruct:
+		err = r.readStruct(func(_ int16, typ byte) error { return r.skip(typ) })
+	case thriftMap:
+		var ktyp, vtyp byte
+		var n int32
+		if ktyp, err = r.byte(); err != nil {
+			return err
+		}
+		if vtyp, err = r.byte(); err != nil {
+			return err
+		}
+		if n, err = r.i32(); err != nil {
+			return err
+		}
+		if n < 0 {
+			return errThriftTruncated
+		}
+		for i := int32(0); i < n && err == nil; i++ {
+			if err = r.skip(ktyp); err == nil {
+				err = r.skip(vtyp)
+			}
+		}
+	case thriftSet, thriftList:
+		var etyp byte
+		var n int
+		if etyp, n, err = r.listHeader(); err != nil {
+			return err
+		}
+		for i := 0; i < n && err == nil; i++ {
+			err = r.skip(etyp)
+		}
+	default:
+		err = fmt.Errorf("thrift: unsupported type %d", typ)
+	}
+	return err
+}
@@ -0,0 +1,229 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package api
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
+)
+
+// thriftStructWriter helps build Thrift binary encoded structs in tests.
+type thriftStructWriter struct{ bytes.Buffer }
+
+func newThriftStruct() *thriftStructWriter { return &thriftStructWriter{} }
+
+func (w *thriftStructWriter) field(id int16, typ byte) {
+	w.WriteByte(typ)
+	binary.Write(w, binary.BigEndian, id) //nolint:errcheck
+}
+
+func (w *thriftStructWriter) i32(id int16, v int32) *thriftStructWriter {
+	w.field(id, thriftI32)
+	binary.Write(w, binary.BigEndian, v) //nolint:errcheck
+	return w
+}
+
+func (w *thriftStructWriter) i64(id int16, v int64) *thriftStructWriter {
+	w.field(id, thriftI64)
+	binary.Write(w, binary.BigEndian, v) //nolint:errcheck
+	return w
+}
+
+func (w *thriftStructWriter) double(id int16, v float64) *thriftStructWriter {
+	w.field(id, thriftDouble)
+	binary.Write(w, binary.BigEndian, math.Float64bits(v)) //nolint:errcheck
+	return w
+}
+
+func (w *thriftStructWriter) bool(id int16, v bool) *thriftStructWriter {
+	w.field(id, thriftBool)
+	if v {
+		w.WriteByte(1)
+	} else {
+		w.WriteByte(0)
+	}
+	return w
+}
+
+func (w *thriftStructWriter) string(id int16, s string) *thriftStructWriter {
+	w.field(id, thriftString)
+	binary.Write(w, binary.BigEndian, int32(len(s))) //nolint:errcheck
+	w.WriteString(s)
+	return w
+}
+
+func (w *thriftStructWriter) message(id int16, sub *thriftStructWriter) *thriftStructWriter {
+	w.field(id, thriftStruct)
+	w.Write(sub.end())
+	return w
+}
+
+func (w *thriftStructWriter) list(id int16, elems ...*thriftStructWriter) *thriftStructWriter {
+	w.field(id, thriftList)
+	w.WriteByte(thriftStruct)
+	binary.Write(w, binary.BigEndian, int32(len(elems))) //nolint:errcheck
+	for _, e := range elems {
+		w.Write(e.end())
+	}
+	return w
+}
+
+// end terminates the struct and returns its encoding.
+func (w *thriftStructWriter) end() []byte {
+	return append(w.Bytes(), thriftStop)
+}
+
+func jaegerStringTag(k, v string) *thriftStructWriter {
+	return newThriftStruct().string(1, k).i32(2, jaegerTagString).string(3, v)
+}
+
+// testJaegerBatch returns a Thrift encoded batch with a server span, its client child and
+// a span of another trace.
+func testJaegerBatch() []byte {
+	process := newThriftStruct().
+		string(1, "frontend").
+		list(2, jaegerStringTag("deployment.environment", "staging"), jaegerStringTag("hostname", "web-1"))
+	server := newThriftStruct().
+		i64(1, 0x240ee60221050802).
+		i64(2, 0x72df520af2bde7a5).
+		i64(3, 0x240ee60221050802).
+		i64(4, 0).
+		string(5, "HTTP GET").
+		i32(7, 3).
+		i64(8, 1600000000000000).
+		i64(9, 1500).
+		list(10,
+			jaegerStringTag("span.kind", "server"),
+			jaegerStringTag("http.method", "GET"),
+			jaegerStringTag("http.route", "/users/{id}"),
+			newThriftStruct().string(1, "http.status_code").i32(2, jaegerTagLong).i64(6, 500),
+			newThriftStruct().string(1, "error").i32(2, jaegerTagBool).bool(5, true),
+		).
+		list(11, newThriftStruct().
+			i64(1, 1600000000000100).
+			list(2,
+				jaegerStringTag("event", "error"),
+				jaegerStringTag("error.kind", "TimeoutError"),
+				jaegerStringTag("message", "upstream timed out"),
+			),
+		)
+	client := newThriftStruct().
+		i64(1, 0x240ee60221050802).
+		i64(2, 0x72df520af2bde7a5).
+		i64(3, 7).
+		i64(4, 0).
+		string(5, "query").
+		list(6, newThriftStruct().
+			i32(1, jaegerRefChildOf).
+			i64(2, 0x240ee60221050802).
+			i64(3, 0x72df520af2bde7a5).
+			i64(4, 0x240ee60221050802),
+		).
+		i32(7, 1).
+		i64(8, 1600000000000200).
+		i64(9, 800).
+		list(10,
+			jaegerStringTag("span.kind", "client"),
+			jaegerStringTag("db.type", "sql"),
+			jaegerStringTag("db.statement", "SELECT * FROM users WHERE id = 42"),
+			newThriftStruct().string(1, "rows").i32(2, jaegerTagDouble).double(4, 1.5),
+			newThriftStruct().string(1, "payload").i32(2, jaegerTagBinary).string(7, "\x01\x02"),
+		)
+	other := newThriftStruct().
+		i64(1, 2).
+		i64(3, 3).
+		string(5, "background").
+		i64(8, 1600000000000000).
+		i64(9, 10).
+		i32(12, 42) // unknown field
+	return newThriftStruct().message(1, process).list(2, server, client, other).end()
+}
+
+func TestDecodeJaegerThrift(t *testing.T) {
+	assert := assert.New(t)
+	traces, err := decodeJaegerThrift(testJaegerBatch())
+	require.NoError(t, err)
+	require.Len(t, traces, 2)
+	require.Len(t, traces[0], 2)
+	require.Len(t, traces[1], 1)
+
+	server := traces[0][0]
+	assert.EqualValues(0x240ee60221050802, server.TraceID)
+	assert.EqualValues(0x240ee60221050802, server.SpanID)
+	assert.Equal("frontend", server.Service)
+	assert.Equal("server", server.Name)
+	assert.Equal("GET /users/{id}", server.Resource)
+	assert.Equal("web", server.Type)
+	assert.EqualValues(1600000000000000000, server.Start)
+	assert.EqualValues(1500000, server.Duration)
+	assert.Equal("staging", server.Meta["env"])
+	assert.Equal("web-1", server.Meta["hostname"])
+	assert.Equal(500.0, server.Metrics["http.status_code"])
+	assert.Equal("72df520af2bde7a5240ee60221050802", server.Meta["jaeger.trace_id"])
+	assert.EqualValues(1, server.Error)
+	assert.Equal("upstream timed out", server.Meta["error.msg"])
+	assert.Equal("TimeoutError", server.Meta["error.type"])
+	assert.Contains(server.Meta["jaeger.logs"], `"error.kind":"TimeoutError"`)
+	priority, ok := sampler.GetSamplingPriority(server)
+	assert.True(ok)
+	assert.Equal(sampler.PriorityUserKeep, priority)
+
+	client := traces[0][1]
+	assert.EqualValues(7, client.SpanID)
+	assert.EqualValues(0x240ee60221050802, client.ParentID)
+	assert.Equal("client", client.Name)
+	assert.Equal("sql", client.Type)
+	assert.Equal("SELECT * FROM users WHERE id = 42", client.Resource)
+	assert.Equal(1.5, client.Metrics["rows"])
+	assert.Equal("AQI=", client.Meta["payload"])
+	assert.EqualValues(0, client.Error)
+	_, ok = sampler.GetSamplingPriority(client)
+	assert.False(ok)
+
+	other := traces[1][0]
+	assert.EqualValues(2, other.TraceID)
+	assert.Equal("internal", other.Name)
+	assert.Equal("background", other.Resource)
+}
+
+func TestDecodeJaegerThriftErrors(t *testing.T) {
+	b := testJaegerBatch()
+	for name, body := range map[string][]byte{
+		"empty":     {},
+		"truncated": b[:len(b)/2],
+		"bad-list":  newThriftStruct().list(2, newThriftStruct()).end()[:8],
+		"bad-type":  {99, 0, 1},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := decodeJaegerThrift(body)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestJaegerEndpoint(t *testing.T) {
+	r := newTestReceiverFromConfig(newTestReceiverConfig())
+	req := httptest.NewRequest("POST", "/api/traces", bytes.NewReader(testJaegerBatch()))
+	req.Header.Set("Content-Type", "application/x-thrift")
+	rec := httptest.NewRecorder()
+	r.buildMux().ServeHTTP(rec, req)
+	require.Equal(t, http.StatusAccepted, rec.Code)
+
+	p := <-r.out
+	assert.Equal(t, string(jaegerThrift), p.Source.EndpointVersion)
+	assert.EqualValues(t, 2, p.Source.TracesReceived)
+	require.Len(t, p.Traces, 2)
+	assert.Len(t, p.Traces[0], 2)
+}
@@ -6,8 +6,11 @@
 package api
 
 import (
+	"compress/gzip"
 	"errors"
 	"io"
+	"io/ioutil"
+	"net/http"
 )
 
 // ErrLimitedReaderLimitReached indicates that the read limit has been
@@ -60,3 +63,22 @@ func (r *LimitedReader) Read(buf []byte) (n int, err error) {
 func (r *LimitedReader) Close() error {
 	return r.r.Close()
 }
+
+// readRequestBody reads the body of req, decompressing it when its Content-Encoding is gzip.
+// Both the body and its decompressed form are limited to limit bytes, so that a small
+// compressed payload can not expand without bounds. It also returns the number of bytes
+// read from the request.
+func readRequestBody(req *http.Request, limit int64) ([]byte, int64, error) {
+	rd := NewLimitedReader(req.Body, limit)
+	var body io.Reader = rd
+	if req.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(rd)
+		if err != nil {
+			return nil, rd.Count, err
+		}
+		defer gz.Close()
+		body = NewLimitedReader(gz, limit)
+	}
+	slurp, err := ioutil.ReadAll(body)
+	return slurp, rd.Count, err
+}
@@ -0,0 +1,513 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package api
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/info"
+	"github.com/DataDog/datadog-agent/pkg/trace/metrics"
+	"github.com/DataDog/datadog-agent/pkg/trace/metrics/timing"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/trace/watchdog"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	// otlpHTTPVersion is the endpoint version reported in stats for OTLP/HTTP payloads.
+	otlpHTTPVersion = "opentelemetry_http_v1"
+
+	// otlpGRPCVersion is the endpoint version reported in stats for OTLP/gRPC payloads.
+	otlpGRPCVersion = "opentelemetry_grpc_v1"
+)
+
+// OTLPReceiver implements an OpenTelemetry protocol receiver which accepts traces
+// over plain HTTP (protobuf and JSON) and gRPC, each on its own port. Received
+// spans are converted to Datadog spans and sent through the same pipeline as
+// traces received by the HTTPReceiver.
+type OTLPReceiver struct {
+	wg      sync.WaitGroup // waits for a graceful shutdown
+	httpsrv *http.Server   // the running HTTP server on a started receiver, if enabled
+	grpcsrv *grpc.Server   // the running gRPC server on a started receiver, if enabled
+	recv    *HTTPReceiver  // the receiver whose stats, rate limiter and output channel are shared
+	conf    *config.OTLP   // receiver config
+	maxSize int64          // maximum allowed request size
+}
+
+// NewOTLPReceiver returns a new OTLPReceiver which sends any incoming traces down the output
+// channel of recv.
+func NewOTLPReceiver(recv *HTTPReceiver, cfg *config.AgentConfig) *OTLPReceiver {
+	return &OTLPReceiver{
+		recv:    recv,
+		conf:    cfg.OTLPReceiver,
+		maxSize: cfg.MaxRequestBytes,
+	}
+}
+
+// Start starts the OTLPReceiver, if any of the servers were configured as active.
+func (o *OTLPReceiver) Start() {
+	if o.conf.HTTPPort != 0 {
+		addr := net.JoinHostPort(o.conf.BindHost, strconv.Itoa(o.conf.HTTPPort))
+		ln, err := net.Listen("tcp", addr)
+		if err != nil {
+			log.Errorf("Error starting OpenTelemetry HTTP server: %v", err)
+		} else {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/v1/traces", o.handleHTTP)
+			o.httpsrv = &http.Server{
+				Handler:      mux,
+				ReadTimeout:  5 * time.Second,
+				WriteTimeout: 5 * time.Second,
+			}
+			o.wg.Add(1)
+			go func() {
+				defer o.wg.Done()
+				defer watchdog.LogOnPanic()
+				if err := o.httpsrv.Serve(ln); err != nil && err != http.ErrServerClosed {
+					log.Errorf("OpenTelemetry HTTP server error: %v", err)
+				}
+			}()
+			log.Infof("Listening for OpenTelemetry HTTP traces at http://%s/v1/traces", addr)
+		}
+	}
+	if o.conf.GRPCPort != 0 {
+		addr := net.JoinHostPort(o.conf.BindHost, strconv.Itoa(o.conf.GRPCPort))
+		ln, err := net.Listen("tcp", addr)
+		if err != nil {
+			log.Errorf("Error starting OpenTelemetry gRPC server: %v", err)
+		} else {
+			o.grpcsrv = grpc.NewServer(
+				grpc.CustomCodec(otlpCodec{}), //nolint:staticcheck
+				grpc.MaxRecvMsgSize(int(o.maxSize)),
+			)
+			o.grpcsrv.RegisterService(&otlpTraceServiceDesc, o)
+			o.wg.Add(1)
+			go func() {
+				defer o.wg.Done()
+				defer watchdog.LogOnPanic()
+				if err := o.grpcsrv.Serve(ln); err != nil {
+					log.Errorf("OpenTelemetry gRPC server error: %v", err)
+				}
+			}()
+			log.Infof("Listening for OpenTelemetry gRPC traces on %s", addr)
+		}
+	}
+}
+
+// Stop stops any running server, waiting for in-flight requests to complete.
+func (o *OTLPReceiver) Stop() {
+	if o.httpsrv != nil {
+		timeout, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if err := o.httpsrv.Shutdown(timeout); err != nil {
+			log.Errorf("Error shutting down OpenTelemetry HTTP server: %v", err)
+		}
+		cancel()
+	}
+	if o.grpcsrv != nil {
+		o.grpcsrv.GracefulStop()
+	}
+	o.wg.Wait()
+}
+
+// handleHTTP handles OTLP/HTTP trace requests, encoded as protobuf or JSON.
+func (o *OTLPReceiver) handleHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	defer timing.Since("datadog.trace_agent.otlp.process_ms", time.Now())
+
+	tags := []string{"endpoint:" + otlpHTTPVersion}
+	slurp, n, err := readRequestBody(req, o.maxSize)
+	if err != nil {
+		httpDecodingError(err, tags, w)
+		return
+	}
+	metrics.Count("datadog.trace_agent.otlp.bytes", n, tags, 1)
+
+	var in otlpTraceRequest
+	mediaType := getMediaType(req)
+	switch mediaType {
+	case "application/x-protobuf", "application/protobuf":
+		err = in.UnmarshalProto(slurp)
+	case "application/json":
+		err = json.Unmarshal(slurp, &in)
+	default:
+		httpFormatError(w, otlpHTTPVersion, fmt.Errorf("unsupported media type: %q", mediaType))
+		return
+	}
+	if err != nil {
+		httpDecodingError(err, tags, w)
+		log.Errorf("Cannot decode OpenTelemetry traces payload: %v", err)
+		return
+	}
+	if !o.processRequest(otlpHTTPVersion, req.Header.Get(headerContainerID), &in, n) {
+		w.WriteHeader(o.recv.rateLimiterResponse)
+		return
+	}
+
+	// the response is an empty ExportTraceServiceResponse in the same encoding as the request
+	if mediaType == "application/json" {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, "{}")
+		return
+	}
+	w.Header().Set("Content-Type", mediaType)
+	w.WriteHeader(http.StatusOK)
+}
+
+// Export implements otlpTraceServer.
+func (o *OTLPReceiver) Export(ctx context.Context, in *otlpTraceRequest) (*otlpTraceResponse, error) {
+	defer timing.Since("datadog.trace_agent.otlp.process_ms", time.Now())
+
+	var containerID string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(headerContainerID); len(v) > 0 {
+			containerID = v[0]
+		}
+	}
+	if !o.processRequest(otlpGRPCVersion, containerID, in, in.size) && o.recv.rateLimiterResponse == http.StatusTooManyRequests {
+		return nil, status.Error(codes.ResourceExhausted, "too many traces")
+	}
+	return &otlpTraceResponse{}, nil
+}
+
+// processRequest converts the spans found in the given request into Datadog traces and
+// sends them to the receiver's output channel, one payload per resource. The request is
+// accounted as a single payload of size bytes, under the tags of its first resource. It
+// returns false when the request was refused by the rate limiter.
+func (o *OTLPReceiver) processRequest(protocol string, containerID string, in *otlpTraceRequest, size int64) bool {
+	var containerTags string
+	if containerID != "" {
+		containerTags = getContainerTags(containerID)
+	}
+	ts := o.recv.Stats.GetTagStats(info.Tags{EndpointVersion: protocol})
+	payloads := make([]*Payload, 0, len(in.ResourceSpans))
+	var tracen int64
+	for i, rs := range in.ResourceSpans {
+		rattr := make(map[string]string, len(rs.Resource))
+		for _, kv := range rs.Resource {
+			rattr[kv.Key] = otlpValueString(kv.Value)
+		}
+		tagstats := o.recv.Stats.GetTagStats(info.Tags{
+			Lang:            rattr["telemetry.sdk.language"],
+			TracerVersion:   "otlp-" + rattr["telemetry.sdk.version"],
+			EndpointVersion: protocol,
+		})
+		if i == 0 {
+			ts = tagstats
+		}
+		byID := make(map[uint64]pb.Trace)
+		for _, libspans := range rs.LibrarySpans {
+			for _, span := range libspans.Spans {
+				traceID := otlpID(span.TraceID)
+				byID[traceID] = append(byID[traceID], convertSpan(rattr, libspans, span))
+			}
+		}
+		if len(byID) == 0 {
+			// no spans for this resource, nothing to send
+			continue
+		}
+		traces := make(pb.Traces, 0, len(byID))
+		for _, trace := range byID {
+			traces = append(traces, trace)
+		}
+		tracen += int64(len(traces))
+		payloads = append(payloads, &Payload{
+			Source:        tagstats,
+			Traces:        traces,
+			ContainerTags: containerTags,
+		})
+	}
+	if o.recv.rateLimited(tracen) {
+		atomic.AddInt64(&ts.PayloadRefused, 1)
+		return false
+	}
+	atomic.AddInt64(&ts.TracesBytes, size)
+	atomic.AddInt64(&ts.PayloadAccepted, 1)
+	for _, p := range payloads {
+		atomic.AddInt64(&p.Source.TracesReceived, int64(len(p.Traces)))
+		o.recv.sendPayload(p)
+	}
+	return true
+}
+
+// convertSpan converts the span in into a Datadog span. The resource attributes found
+// in rattr are added to the span's meta.
+func convertSpan(rattr map[string]string, lib otlpLibrarySpans, in otlpSpan) *pb.Span {
+	span := &pb.Span{
+		TraceID:  otlpID(in.TraceID),
+		SpanID:   otlpID(in.SpanID),
+		ParentID: otlpID(in.ParentSpanID),
+		Service:  rattr["service.name"],
+		Name:     spanKindName(in.Kind),
+		Resource: in.Name,
+		Start:    int64(in.StartTime),
+		Duration: int64(in.EndTime) - int64(in.StartTime),
+		Meta:     make(map[string]string, len(rattr)+len(in.Attributes)+2),
+		Metrics:  make(map[string]float64),
+	}
+	if lib.LibraryName != "" {
+		span.Name = lib.LibraryName + "." + span.Name
+	}
+	for k, v := range rattr {
+		span.Meta[k] = v
+	}
+	for _, kv := range in.Attributes {
+		switch v := kv.Value.(type) {
+		case int64:
+			span.Metrics[kv.Key] = float64(v)
+		case float64:
+			span.Metrics[kv.Key] = v
+		default:
+			span.Meta[kv.Key] = otlpValueString(v)
+		}
+	}
+	span.Meta["otel.trace_id"] = hex.EncodeToString(in.TraceID)
+	if _, ok := span.Meta["span.kind"]; !ok {
+		span.Meta["span.kind"] = spanKindName(in.Kind)
+	}
+	if lib.LibraryName != "" {
+		span.Meta["otel.library.name"] = lib.LibraryName
+	}
+	if lib.LibraryVersion != "" {
+		span.Meta["otel.library.version"] = lib.LibraryVersion
+	}
+	if env := span.Meta["deployment.environment"]; env != "" {
+		span.Meta["env"] = env
+	}
+	if version := span.Meta["service.version"]; version != "" {
+		span.Meta["version"] = version
+	}
+	span.Type = spanType(in.Kind, span.Meta)
+	if in.Kind == otlpSpanKindServer {
+		if method, route := span.Meta["http.method"], span.Meta["http.route"]; method != "" && route != "" {
+			span.Resource = method + " " + route
+		}
+	}
+	if stmt := span.Meta["db.statement"]; stmt != "" && span.Type == "sql" {
+		// use the query as resource so that it gets obfuscated
+		span.Resource = stmt
+	}
+	setStatus(span, in)
+	return span
+}
+
+// setStatus sets the error and error related tags on span, based on the status and
+// the exception events found in the OTLP span in.
+func setStatus(span *pb.Span, in otlpSpan) {
+	switch in.Status.Code {
+	case otlpStatusCodeOk:
+		span.Meta["otel.status_code"] = "Ok"
+	case otlpStatusCodeError:
+		span.Meta["otel.status_code"] = "Error"
+		span.Error = 1
+	}
+	if msg := in.Status.Message; msg != "" {
+		span.Meta["otel.status_description"] = msg
+		if span.Error == 1 {
+			span.Meta["error.msg"] = msg
+		}
+	}
+	if span.Error == 0 {
+		return
+	}
+	for _, e := range in.Events {
+		if e.Name != "exception" {
+			continue
+		}
+		for _, kv := range e.Attributes {
+			switch kv.Key {
+			case "exception.message":
+				span.Meta["error.msg"] = otlpValueString(kv.Value)
+			case "exception.type":
+				span.Meta["error.type"] = otlpValueString(kv.Value)
+			case "exception.stacktrace":
+				span.Meta["error.stack"] = otlpValueString(kv.Value)
+			}
+		}
+	}
+}
+
+// spanKindName returns the lowercase name of the given span kind.
+func spanKindName(k otlpSpanKind) string {
+	switch k {
+	case otlpSpanKindInternal:
+		return "internal"
+	case otlpSpanKindServer:
+		return "server"
+	case otlpSpanKindClient:
+		return "client"
+	case otlpSpanKindProducer:
+		return "producer"
+	case otlpSpanKindConsumer:
+		return "consumer"
+	default:
+		return "unspecified"
+	}
+}
+
+// spanType returns the Datadog span type for a span of the given kind, having
+// the given meta.
+func spanType(kind otlpSpanKind, meta map[string]string) string {
+	switch kind {
+	case otlpSpanKindServer:
+		return "web"
+	case otlpSpanKindClient:
+		switch db := meta["db.system"]; db {
+		case "":
+			return "http"
+		case "redis", "memcached", "mongodb", "elasticsearch", "cassandra":
+			return db
+		default:
+			return "sql"
+		}
+	default:
+		return "custom"
+	}
+}
+
+// otlpID converts the given OTLP trace or span ID into a Datadog ID. 128-bit trace IDs
+// are truncated to their lower 64 bits.
+func otlpID(b []byte) uint64 {
+	if len(b) < 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(b[len(b)-8:])
+}
+
+// otlpValueString returns the string representation of the given attribute value.
+func otlpValueString(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case []byte:
+		return base64.StdEncoding.EncodeToString(v)
+	default:
+		b, err := json.Marshal(otlpValueJSON(v))
+		if err != nil {
+			return ""
+		}
+		return string(b)
+	}
+}
+
+// otlpValueJSON converts the array and key-value list attribute values into types
+// which can be encoded to JSON.
+func otlpValueJSON(v interface{}) interface{} {
+	switch v := v.(type) {
+	case []interface{}:
+		arr := make([]interface{}, len(v))
+		for i, el := range v {
+			arr[i] = otlpValueJSON(el)
+		}
+		return arr
+	case []otlpKeyValue:
+		m := make(map[string]interface{}, len(v))
+		for _, kv := range v {
+			m[kv.Key] = otlpValueJSON(kv.Value)
+		}
+		return m
+	default:
+		return v
+	}
+}
+
+// otlpTraceResponse is an ExportTraceServiceResponse. It has no fields.
+type otlpTraceResponse struct{}
+
+// otlpTraceServer is the server API for the OTLP TraceService.
+type otlpTraceServer interface {
+	Export(context.Context, *otlpTraceRequest) (*otlpTraceResponse, error)
+}
+
+// otlpTraceServiceDesc describes the OTLP TraceService, as found in
+// opentelemetry/proto/collector/trace/v1/trace_service.proto.
+var otlpTraceServiceDesc = grpc.ServiceDesc{
+	ServiceName: "opentelemetry.proto.collector.trace.v1.TraceService",
+	HandlerType: (*otlpTraceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Export",
+			Handler:    otlpExportHandler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "opentelemetry/proto/collector/trace/v1/trace_service.proto",
+}
+
+func otlpExportHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(otlpTraceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(otlpTraceServer).Export(ctx, in)
+	}
+	sinfo := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/opentelemetry.proto.collector.trace.v1.TraceService/Export",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(otlpTraceServer).Export(ctx, req.(*otlpTraceRequest))
+	}
+	return interceptor(ctx, in, sinfo, handler)
+}
+
+// otlpCodec is the gRPC codec used by the OTLP gRPC server. It decodes requests using
+// our own minimal OTLP protobuf decoder.
+type otlpCodec struct{}
+
+// Marshal implements grpc.Codec.
+func (otlpCodec) Marshal(v interface{}) ([]byte, error) {
+	if _, ok := v.(*otlpTraceResponse); !ok {
+		return nil, fmt.Errorf("otlp: can not marshal %T", v)
+	}
+	// an empty message encodes to zero bytes
+	return []byte{}, nil
+}
+
+// Unmarshal implements grpc.Codec.
+func (otlpCodec) Unmarshal(data []byte, v interface{}) error {
+	req, ok := v.(*otlpTraceRequest)
+	if !ok {
+		return fmt.Errorf("otlp: can not unmarshal into %T", v)
+	}
+	if err := req.UnmarshalProto(data); err != nil {
+		return err
+	}
+	req.size = int64(len(data))
+	return nil
+}
+
+// String implements grpc.Codec.
+func (otlpCodec) String() string { return "proto" }
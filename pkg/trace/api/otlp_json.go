@@ -0,0 +1,241 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package api
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// This file contains the decoder for the JSON encoding of OTLP trace requests. As per
+// the OTLP specification, the JSON mapping follows the standard protobuf JSON mapping,
+// with the exception of trace and span IDs, which are hex-encoded instead of base64.
+
+type jsonTraceRequest struct {
+	ResourceSpans []struct {
+		Resource struct {
+			Attributes []jsonKeyValue `json:"attributes"`
+		} `json:"resource"`
+		InstrumentationLibrarySpans []jsonLibrarySpans `json:"instrumentationLibrarySpans"`
+		ScopeSpans                  []jsonLibrarySpans `json:"scopeSpans"`
+	} `json:"resourceSpans"`
+}
+
+type jsonLibrary struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type jsonLibrarySpans struct {
+	InstrumentationLibrary *jsonLibrary `json:"instrumentationLibrary"`
+	Scope                  *jsonLibrary `json:"scope"`
+	Spans                  []struct {
+		TraceID           jsonID         `json:"traceId"`
+		SpanID            jsonID         `json:"spanId"`
+		ParentSpanID      jsonID         `json:"parentSpanId"`
+		Name              string         `json:"name"`
+		Kind              jsonEnum       `json:"kind"`
+		StartTimeUnixNano jsonUint64     `json:"startTimeUnixNano"`
+		EndTimeUnixNano   jsonUint64     `json:"endTimeUnixNano"`
+		Attributes        []jsonKeyValue `json:"attributes"`
+		Events            []struct {
+			TimeUnixNano jsonUint64     `json:"timeUnixNano"`
+			Name         string         `json:"name"`
+			Attributes   []jsonKeyValue `json:"attributes"`
+		} `json:"events"`
+		Status struct {
+			Code    jsonEnum `json:"code"`
+			Message string   `json:"message"`
+		} `json:"status"`
+	} `json:"spans"`
+}
+
+type jsonKeyValue struct {
+	Key   string        `json:"key"`
+	Value *jsonAnyValue `json:"value"`
+}
+
+type jsonAnyValue struct {
+	StringValue *string     `json:"stringValue"`
+	BoolValue   *bool       `json:"boolValue"`
+	IntValue    *jsonUint64 `json:"intValue"`
+	DoubleValue *float64    `json:"doubleValue"`
+	BytesValue  *string     `json:"bytesValue"`
+	ArrayValue  *struct {
+		Values []*jsonAnyValue `json:"values"`
+	} `json:"arrayValue"`
+	KvlistValue *struct {
+		Values []jsonKeyValue `json:"values"`
+	} `json:"kvlistValue"`
+}
+
+// jsonUint64 decodes 64-bit integers which may be encoded either as numbers or strings.
+type jsonUint64 uint64
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (n *jsonUint64) UnmarshalJSON(b []byte) error {
+	s := string(bytes.Trim(b, `"`))
+	if s == "" || s == "null" {
+		return nil
+	}
+	if v, err := strconv.ParseUint(s, 10, 64); err == nil {
+		*n = jsonUint64(v)
+		return nil
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid integer %s", b)
+	}
+	*n = jsonUint64(v)
+	return nil
+}
+
+// jsonID decodes hex-encoded trace and span IDs.
+type jsonID []byte
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (id *jsonID) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	v, err := hex.DecodeString(s)
+	if err != nil {
+		return fmt.Errorf("invalid ID %q: %v", s, err)
+	}
+	*id = v
+	return nil
+}
+
+// jsonEnum decodes enum values, which may be encoded either as numbers or as their names.
+type jsonEnum int32
+
+// otlpEnumValues maps enum names to their values.
+var otlpEnumValues = map[string]int32{
+	"SPAN_KIND_UNSPECIFIED": int32(otlpSpanKindUnspecified),
+	"SPAN_KIND_INTERNAL":    int32(otlpSpanKindInternal),
+	"SPAN_KIND_SERVER":      int32(otlpSpanKindServer),
+	"SPAN_KIND_CLIENT":      int32(otlpSpanKindClient),
+	"SPAN_KIND_PRODUCER":    int32(otlpSpanKindProducer),
+	"SPAN_KIND_CONSUMER":    int32(otlpSpanKindConsumer),
+	"STATUS_CODE_UNSET":     int32(otlpStatusCodeUnset),
+	"STATUS_CODE_OK":        int32(otlpStatusCodeOk),
+	"STATUS_CODE_ERROR":     int32(otlpStatusCodeError),
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (e *jsonEnum) UnmarshalJSON(b []byte) error {
+	if len(b) > 0 && b[0] == '"' {
+		var s string
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+		v, ok := otlpEnumValues[s]
+		if !ok {
+			return fmt.Errorf("unknown enum value %q", s)
+		}
+		*e = jsonEnum(v)
+		return nil
+	}
+	var v int32
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	*e = jsonEnum(v)
+	return nil
+}
+
+// UnmarshalJSON decodes the JSON encoded ExportTraceServiceRequest found in b.
+func (req *otlpTraceRequest) UnmarshalJSON(b []byte) error {
+	var in jsonTraceRequest
+	if err := json.Unmarshal(b, &in); err != nil {
+		return err
+	}
+	for _, jrs := range in.ResourceSpans {
+		rs := otlpResourceSpans{Resource: convertJSONKeyValues(jrs.Resource.Attributes)}
+		for _, jls := range append(jrs.InstrumentationLibrarySpans, jrs.ScopeSpans...) {
+			var ls otlpLibrarySpans
+			if lib := jls.InstrumentationLibrary; lib != nil {
+				ls.LibraryName, ls.LibraryVersion = lib.Name, lib.Version
+			}
+			if lib := jls.Scope; lib != nil {
+				ls.LibraryName, ls.LibraryVersion = lib.Name, lib.Version
+			}
+			for _, js := range jls.Spans {
+				span := otlpSpan{
+					TraceID:      js.TraceID,
+					SpanID:       js.SpanID,
+					ParentSpanID: js.ParentSpanID,
+					Name:         js.Name,
+					Kind:         otlpSpanKind(js.Kind),
+					StartTime:    uint64(js.StartTimeUnixNano),
+					EndTime:      uint64(js.EndTimeUnixNano),
+					Attributes:   convertJSONKeyValues(js.Attributes),
+					Status: otlpStatus{
+						Code:    otlpStatusCode(js.Status.Code),
+						Message: js.Status.Message,
+					},
+				}
+				for _, je := range js.Events {
+					span.Events = append(span.Events, otlpEvent{
+						Time:       uint64(je.TimeUnixNano),
+						Name:       je.Name,
+						Attributes: convertJSONKeyValues(je.Attributes),
+					})
+				}
+				ls.Spans = append(ls.Spans, span)
+			}
+			rs.LibrarySpans = append(rs.LibrarySpans, ls)
+		}
+		req.ResourceSpans = append(req.ResourceSpans, rs)
+	}
+	return nil
+}
+
+func convertJSONKeyValues(in []jsonKeyValue) []otlpKeyValue {
+	if len(in) == 0 {
+		return nil
+	}
+	out := make([]otlpKeyValue, 0, len(in))
+	for _, kv := range in {
+		out = append(out, otlpKeyValue{Key: kv.Key, Value: convertJSONAnyValue(kv.Value)})
+	}
+	return out
+}
+
+func convertJSONAnyValue(v *jsonAnyValue) interface{} {
+	switch {
+	case v == nil:
+		return nil
+	case v.StringValue != nil:
+		return *v.StringValue
+	case v.BoolValue != nil:
+		return *v.BoolValue
+	case v.IntValue != nil:
+		return int64(*v.IntValue)
+	case v.DoubleValue != nil:
+		return *v.DoubleValue
+	case v.BytesValue != nil:
+		b, err := base64.StdEncoding.DecodeString(*v.BytesValue)
+		if err != nil {
+			return *v.BytesValue
+		}
+		return b
+	case v.ArrayValue != nil:
+		arr := make([]interface{}, 0, len(v.ArrayValue.Values))
+		for _, el := range v.ArrayValue.Values {
+			arr = append(arr, convertJSONAnyValue(el))
+		}
+		return arr
+	case v.KvlistValue != nil:
+		return convertJSONKeyValues(v.KvlistValue.Values)
+	}
+	return nil
+}
@@ -0,0 +1,472 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package api
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// This file contains a minimal decoder for the OpenTelemetry protocol (OTLP) trace
+// messages, as defined in the opentelemetry-proto repository under:
+//
+//	opentelemetry/proto/collector/trace/v1/trace_service.proto
+//	opentelemetry/proto/trace/v1/trace.proto
+//	opentelemetry/proto/common/v1/common.proto
+//	opentelemetry/proto/resource/v1/resource.proto
+//
+// Only the fields which are used by the trace-agent are decoded; all others are
+// skipped. Field numbers are stable across OTLP versions, which allows us to support
+// both the "instrumentation_library_spans" and the newer "scope_spans" payloads.
+
+// otlpTraceRequest is the decoded form of an ExportTraceServiceRequest.
+type otlpTraceRequest struct {
+	ResourceSpans []otlpResourceSpans
+
+	// size is the size of the encoded request, set when it is received over gRPC.
+	size int64
+}
+
+// otlpResourceSpans is a collection of spans sharing the same resource.
+type otlpResourceSpans struct {
+	Resource     []otlpKeyValue
+	LibrarySpans []otlpLibrarySpans
+}
+
+// otlpLibrarySpans is a collection of spans produced by the same instrumentation library (scope).
+type otlpLibrarySpans struct {
+	LibraryName    string
+	LibraryVersion string
+	Spans          []otlpSpan
+}
+
+// otlpKeyValue is an attribute. Value holds one of: string, bool, int64, float64, []byte,
+// []interface{} (array value) or []otlpKeyValue (key-value list).
+type otlpKeyValue struct {
+	Key   string
+	Value interface{}
+}
+
+// otlpSpan holds the decoded form of an OTLP span.
+type otlpSpan struct {
+	TraceID      []byte
+	SpanID       []byte
+	ParentSpanID []byte
+	Name         string
+	Kind         otlpSpanKind
+	StartTime    uint64
+	EndTime      uint64
+	Attributes   []otlpKeyValue
+	Events       []otlpEvent
+	Status       otlpStatus
+}
+
+// otlpEvent is a time-stamped annotation of a span.
+type otlpEvent struct {
+	Time       uint64
+	Name       string
+	Attributes []otlpKeyValue
+}
+
+// otlpStatus is the status of a span.
+type otlpStatus struct {
+	Code    otlpStatusCode
+	Message string
+}
+
+// otlpSpanKind specifies the OTLP span kind.
+type otlpSpanKind int32
+
+const (
+	otlpSpanKindUnspecified otlpSpanKind = iota
+	otlpSpanKindInternal
+	otlpSpanKindServer
+	otlpSpanKindClient
+	otlpSpanKindProducer
+	otlpSpanKindConsumer
+)
+
+// otlpStatusCode specifies the OTLP status code.
+type otlpStatusCode int32
+
+const (
+	otlpStatusCodeUnset otlpStatusCode = iota
+	otlpStatusCodeOk
+	otlpStatusCodeError
+)
+
+// protobuf wire types
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// errProtoTruncated is returned when a message ends unexpectedly.
+var errProtoTruncated = errors.New("protobuf: unexpected end of message")
+
+// protoReader reads fields from a protobuf encoded message.
+type protoReader struct{ buf []byte }
+
+// done reports whether the whole message was read.
+func (r *protoReader) done() bool { return len(r.buf) == 0 }
+
+// next reads the next field tag, returning its number and wire type.
+func (r *protoReader) next() (field uint64, wire int, err error) {
+	key, err := r.varint()
+	if err != nil {
+		return 0, 0, err
+	}
+	return key >> 3, int(key & 7), nil
+}
+
+func (r *protoReader) varint() (uint64, error) {
+	v, n := binary.Uvarint(r.buf)
+	if n <= 0 {
+		return 0, errProtoTruncated
+	}
+	r.buf = r.buf[n:]
+	return v, nil
+}
+
+func (r *protoReader) fixed64() (uint64, error) {
+	if len(r.buf) < 8 {
+		return 0, errProtoTruncated
+	}
+	v := binary.LittleEndian.Uint64(r.buf)
+	r.buf = r.buf[8:]
+	return v, nil
+}
+
+func (r *protoReader) bytes() ([]byte, error) {
+	n, err := r.varint()
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(r.buf)) < n {
+		return nil, errProtoTruncated
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b, nil
+}
+
+func (r *protoReader) string() (string, error) {
+	b, err := r.bytes()
+	return string(b), err
+}
+
+// skip discards the value of a field having the given wire type.
+func (r *protoReader) skip(wire int) error {
+	switch wire {
+	case wireVarint:
+		_, err := r.varint()
+		return err
+	case wireFixed64:
+		_, err := r.fixed64()
+		return err
+	case wireBytes:
+		_, err := r.bytes()
+		return err
+	case wireFixed32:
+		if len(r.buf) < 4 {
+			return errProtoTruncated
+		}
+		r.buf = r.buf[4:]
+		return nil
+	default:
+		return fmt.Errorf("protobuf: unsupported wire type %d", wire)
+	}
+}
+
+// expect returns an error if the wire type of the given field is not want.
+func expect(field uint64, wire, want int) error {
+	if wire != want {
+		return fmt.Errorf("protobuf: field %d has wire type %d, expected %d", field, wire, want)
+	}
+	return nil
+}
+
+// decodeMessage calls fn for each field found in the message b, stopping at the
+// first error.
+func decodeMessage(b []byte, fn func(r *protoReader, field uint64, wire int) error) error {
+	r := protoReader{buf: b}
+	for !r.done() {
+		field, wire, err := r.next()
+		if err != nil {
+			return err
+		}
+		if err := fn(&r, field, wire); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// subMessage reads a length-delimited field and decodes it using fn.
+func subMessage(r *protoReader, field uint64, wire int, fn func(b []byte) error) error {
+	if err := expect(field, wire, wireBytes); err != nil {
+		return err
+	}
+	b, err := r.bytes()
+	if err != nil {
+		return err
+	}
+	return fn(b)
+}
+
+// UnmarshalProto decodes the protobuf encoded ExportTraceServiceRequest found in b.
+func (req *otlpTraceRequest) UnmarshalProto(b []byte) error {
+	return decodeMessage(b, func(r *protoReader, field uint64, wire int) error {
+		if field != 1 { // resource_spans
+			return r.skip(wire)
+		}
+		return subMessage(r, field, wire, func(b []byte) error {
+			var rs otlpResourceSpans
+			if err := rs.unmarshalProto(b); err != nil {
+				return err
+			}
+			req.ResourceSpans = append(req.ResourceSpans, rs)
+			return nil
+		})
+	})
+}
+
+func (rs *otlpResourceSpans) unmarshalProto(b []byte) error {
+	return decodeMessage(b, func(r *protoReader, field uint64, wire int) error {
+		switch field {
+		case 1: // resource
+			return subMessage(r, field, wire, func(b []byte) error {
+				return decodeMessage(b, func(r *protoReader, field uint64, wire int) error {
+					if field != 1 { // attributes
+						return r.skip(wire)
+					}
+					return subMessage(r, field, wire, func(b []byte) error {
+						kv, err := unmarshalKeyValue(b)
+						rs.Resource = append(rs.Resource, kv)
+						return err
+					})
+				})
+			})
+		case 2: // instrumentation_library_spans or scope_spans
+			return subMessage(r, field, wire, func(b []byte) error {
+				var ls otlpLibrarySpans
+				if err := ls.unmarshalProto(b); err != nil {
+					return err
+				}
+				rs.LibrarySpans = append(rs.LibrarySpans, ls)
+				return nil
+			})
+		default:
+			return r.skip(wire)
+		}
+	})
+}
+
+func (ls *otlpLibrarySpans) unmarshalProto(b []byte) error {
+	return decodeMessage(b, func(r *protoReader, field uint64, wire int) error {
+		switch field {
+		case 1: // instrumentation_library or scope
+			return subMessage(r, field, wire, func(b []byte) error {
+				return decodeMessage(b, func(r *protoReader, field uint64, wire int) error {
+					var err error
+					switch field {
+					case 1:
+						ls.LibraryName, err = r.string()
+					case 2:
+						ls.LibraryVersion, err = r.string()
+					default:
+						err = r.skip(wire)
+					}
+					return err
+				})
+			})
+		case 2: // spans
+			return subMessage(r, field, wire, func(b []byte) error {
+				var s otlpSpan
+				if err := s.unmarshalProto(b); err != nil {
+					return err
+				}
+				ls.Spans = append(ls.Spans, s)
+				return nil
+			})
+		default:
+			return r.skip(wire)
+		}
+	})
+}
+
+func (s *otlpSpan) unmarshalProto(b []byte) error {
+	return decodeMessage(b, func(r *protoReader, field uint64, wire int) error {
+		var err error
+		switch field {
+		case 1:
+			s.TraceID, err = r.bytes()
+		case 2:
+			s.SpanID, err = r.bytes()
+		case 4:
+			s.ParentSpanID, err = r.bytes()
+		case 5:
+			s.Name, err = r.string()
+		case 6:
+			var v uint64
+			v, err = r.varint()
+			s.Kind = otlpSpanKind(v)
+		case 7:
+			if err = expect(field, wire, wireFixed64); err == nil {
+				s.StartTime, err = r.fixed64()
+			}
+		case 8:
+			if err = expect(field, wire, wireFixed64); err == nil {
+				s.EndTime, err = r.fixed64()
+			}
+		case 9:
+			err = subMessage(r, field, wire, func(b []byte) error {
+				kv, err := unmarshalKeyValue(b)
+				s.Attributes = append(s.Attributes, kv)
+				return err
+			})
+		case 11:
+			err = subMessage(r, field, wire, func(b []byte) error {
+				var e otlpEvent
+				if err := e.unmarshalProto(b); err != nil {
+					return err
+				}
+				s.Events = append(s.Events, e)
+				return nil
+			})
+		case 15:
+			err = subMessage(r, field, wire, s.Status.unmarshalProto)
+		default:
+			err = r.skip(wire)
+		}
+		return err
+	})
+}
+
+func (e *otlpEvent) unmarshalProto(b []byte) error {
+	return decodeMessage(b, func(r *protoReader, field uint64, wire int) error {
+		var err error
+		switch field {
+		case 1:
+			if err = expect(field, wire, wireFixed64); err == nil {
+				e.Time, err = r.fixed64()
+			}
+		case 2:
+			e.Name, err = r.string()
+		case 3:
+			err = subMessage(r, field, wire, func(b []byte) error {
+				kv, err := unmarshalKeyValue(b)
+				e.Attributes = append(e.Attributes, kv)
+				return err
+			})
+		default:
+			err = r.skip(wire)
+		}
+		return err
+	})
+}
+
+func (st *otlpStatus) unmarshalProto(b []byte) error {
+	return decodeMessage(b, func(r *protoReader, field uint64, wire int) error {
+		var err error
+		switch field {
+		case 2:
+			st.Message, err = r.string()
+		case 3:
+			var v uint64
+			v, err = r.varint()
+			st.Code = otlpStatusCode(v)
+		default:
+			err = r.skip(wire)
+		}
+		return err
+	})
+}
+
+func unmarshalKeyValue(b []byte) (otlpKeyValue, error) {
+	var kv otlpKeyValue
+	err := decodeMessage(b, func(r *protoReader, field uint64, wire int) error {
+		var err error
+		switch field {
+		case 1:
+			kv.Key, err = r.string()
+		case 2:
+			err = subMessage(r, field, wire, func(b []byte) error {
+				kv.Value, err = unmarshalAnyValue(b)
+				return err
+			})
+		default:
+			err = r.skip(wire)
+		}
+		return err
+	})
+	return kv, err
+}
+
+func unmarshalAnyValue(b []byte) (interface{}, error) {
+	var v interface{}
+	err := decodeMessage(b, func(r *protoReader, field uint64, wire int) error {
+		var err error
+		switch field {
+		case 1: // string_value
+			v, err = r.string()
+		case 2: // bool_value
+			var n uint64
+			n, err = r.varint()
+			v = n != 0
+		case 3: // int_value
+			var n uint64
+			n, err = r.varint()
+			v = int64(n)
+		case 4: // double_value
+			var n uint64
+			if err = expect(field, wire, wireFixed64); err == nil {
+				n, err = r.fixed64()
+				v = math.Float64frombits(n)
+			}
+		case 5: // array_value
+			var arr []interface{}
+			err = subMessage(r, field, wire, func(b []byte) error {
+				return decodeMessage(b, func(r *protoReader, field uint64, wire int) error {
+					if field != 1 {
+						return r.skip(wire)
+					}
+					return subMessage(r, field, wire, func(b []byte) error {
+						el, err := unmarshalAnyValue(b)
+						arr = append(arr, el)
+						return err
+					})
+				})
+			})
+			v = arr
+		case 6: // kvlist_value
+			var kvs []otlpKeyValue
+			err = subMessage(r, field, wire, func(b []byte) error {
+				return decodeMessage(b, func(r *protoReader, field uint64, wire int) error {
+					if field != 1 {
+						return r.skip(wire)
+					}
+					return subMessage(r, field, wire, func(b []byte) error {
+						kv, err := unmarshalKeyValue(b)
+						kvs = append(kvs, kv)
+						return err
+					})
+				})
+			})
+			v = kvs
+		case 7: // bytes_value
+			v, err = r.bytes()
+		default:
+			err = r.skip(wire)
+		}
+		return err
+	})
+	return v, err
+}
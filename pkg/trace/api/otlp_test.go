@@ -0,0 +1,485 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/info"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
+)
+
+// protoMessage helps build protobuf encoded messages in tests.
+type protoMessage struct{ *proto.Buffer }
+
+func newProtoMessage() protoMessage { return protoMessage{proto.NewBuffer(nil)} }
+
+func (m protoMessage) bytes(field uint64, b []byte) protoMessage {
+	m.EncodeVarint(field<<3 | wireBytes)
+	m.EncodeRawBytes(b)
+	return m
+}
+
+func (m protoMessage) string(field uint64, s string) protoMessage {
+	return m.bytes(field, []byte(s))
+}
+
+func (m protoMessage) message(field uint64, sub protoMessage) protoMessage {
+	return m.bytes(field, sub.Bytes())
+}
+
+func (m protoMessage) varint(field uint64, v uint64) protoMessage {
+	m.EncodeVarint(field<<3 | wireVarint)
+	m.EncodeVarint(v)
+	return m
+}
+
+func (m protoMessage) fixed64(field uint64, v uint64) protoMessage {
+	m.EncodeVarint(field<<3 | wireFixed64)
+	m.EncodeFixed64(v)
+	return m
+}
+
+func protoKeyValue(key string, value protoMessage) protoMessage {
+	return newProtoMessage().string(1, key).message(2, value)
+}
+
+var (
+	testTraceID = []byte{0x72, 0xdf, 0x52, 0x0a, 0xf2, 0xbd, 0xe7, 0xa5, 0x24, 0x0e, 0xe6, 0x02, 0x21, 0x05, 0x08, 0x02}
+	testSpanID  = []byte{0x24, 0x0e, 0xe6, 0x02, 0x21, 0x05, 0x08, 0x02}
+	testParent  = []byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x07}
+)
+
+// testOTLPProtoRequest returns a protobuf encoded ExportTraceServiceRequest.
+func testOTLPProtoRequest() []byte {
+	return newProtoMessage().message(1, testOTLPResourceSpans()).Bytes()
+}
+
+// testOTLPResourceSpans returns the protobuf encoded ResourceSpans of the test request.
+func testOTLPResourceSpans() protoMessage {
+	resource := newProtoMessage().
+		message(1, protoKeyValue("service.name", newProtoMessage().string(1, "pylons"))).
+		message(1, protoKeyValue("deployment.environment", newProtoMessage().string(1, "staging"))).
+		message(1, protoKeyValue("service.version", newProtoMessage().string(1, "1.2.3"))).
+		message(1, protoKeyValue("telemetry.sdk.language", newProtoMessage().string(1, "python")))
+	span := newProtoMessage().
+		bytes(1, testTraceID).
+		bytes(2, testSpanID).
+		string(3, "state").
+		bytes(4, testParent).
+		string(5, "/path").
+		varint(6, uint64(otlpSpanKindServer)).
+		fixed64(7, 1000).
+		fixed64(8, 3000).
+		message(9, protoKeyValue("http.method", newProtoMessage().string(1, "GET"))).
+		message(9, protoKeyValue("http.route", newProtoMessage().string(1, "/users/:id"))).
+		message(9, protoKeyValue("http.status_code", newProtoMessage().varint(3, 500))).
+		message(9, protoKeyValue("ratio", newProtoMessage().fixed64(4, math.Float64bits(0.5)))).
+		message(9, protoKeyValue("cached", newProtoMessage().varint(2, 1))).
+		message(11, newProtoMessage().
+				fixed64(1, 2000).
+				string(2, "exception").
+				message(3, protoKeyValue("exception.message", newProtoMessage().string(1, "boom"))).
+				message(3, protoKeyValue("exception.type", newProtoMessage().string(1, "ValueError")))).
+		varint(14, 3). // dropped_links_count, unknown to us
+		message(15, newProtoMessage().string(2, "internal error").varint(3, uint64(otlpStatusCodeError)))
+	libspans := newProtoMessage().
+		message(1, newProtoMessage().string(1, "flask").string(2, "0.1")).
+		message(2, span)
+	return newProtoMessage().
+		message(1, resource).
+		message(2, libspans)
+}
+
+const testOTLPJSONRequest = `{
+  "resourceSpans": [{
+    "resource": {"attributes": [
+      {"key": "service.name", "value": {"stringValue": "pylons"}},
+      {"key": "deployment.environment", "value": {"stringValue": "staging"}},
+      {"key": "service.version", "value": {"stringValue": "1.2.3"}},
+      {"key": "telemetry.sdk.language", "value": {"stringValue": "python"}}
+    ]},
+    "scopeSpans": [{
+      "scope": {"name": "flask", "version": "0.1"},
+      "spans": [{
+        "traceId": "72df520af2bde7a5240ee60221050802",
+        "spanId": "240ee60221050802",
+        "parentSpanId": "0000000000000007",
+        "name": "/path",
+        "kind": "SPAN_KIND_SERVER",
+        "startTimeUnixNano": "1000",
+        "endTimeUnixNano": 3000,
+        "attributes": [
+          {"key": "http.method", "value": {"stringValue": "GET"}},
+          {"key": "http.route", "value": {"stringValue": "/users/:id"}},
+          {"key": "http.status_code", "value": {"intValue": "500"}},
+          {"key": "ratio", "value": {"doubleValue": 0.5}},
+          {"key": "cached", "value": {"boolValue": true}}
+        ],
+        "events": [{
+          "timeUnixNano": "2000",
+          "name": "exception",
+          "attributes": [
+            {"key": "exception.message", "value": {"stringValue": "boom"}},
+            {"key": "exception.type", "value": {"stringValue": "ValueError"}}
+          ]
+        }],
+        "status": {"code": 2, "message": "internal error"}
+      }]
+    }]
+  }]
+}`
+
+// assertTestSpan asserts that span was converted from the span in the test requests.
+func assertTestSpan(t *testing.T, span *pb.Span) {
+	assert := assert.New(t)
+	assert.Equal(uint64(0x240ee60221050802), span.TraceID)
+	assert.Equal(uint64(0x240ee60221050802), span.SpanID)
+	assert.Equal(uint64(7), span.ParentID)
+	assert.Equal("pylons", span.Service)
+	assert.Equal("flask.server", span.Name)
+	assert.Equal("GET /users/:id", span.Resource)
+	assert.Equal("web", span.Type)
+	assert.Equal(int64(1000), span.Start)
+	assert.Equal(int64(2000), span.Duration)
+	assert.Equal(int32(1), span.Error)
+	assert.Equal("staging", span.Meta["env"])
+	assert.Equal("1.2.3", span.Meta["version"])
+	assert.Equal("GET", span.Meta["http.method"])
+	assert.Equal("true", span.Meta["cached"])
+	assert.Equal("boom", span.Meta["error.msg"])
+	assert.Equal("ValueError", span.Meta["error.type"])
+	assert.Equal("internal error", span.Meta["otel.status_description"])
+	assert.Equal("72df520af2bde7a5240ee60221050802", span.Meta["otel.trace_id"])
+	assert.Equal("server", span.Meta["span.kind"])
+	assert.Equal("0.1", span.Meta["otel.library.version"])
+	assert.Equal(500., span.Metrics["http.status_code"])
+	assert.Equal(0.5, span.Metrics["ratio"])
+}
+
+func TestOTLPDecode(t *testing.T) {
+	t.Run("proto", func(t *testing.T) {
+		var req otlpTraceRequest
+		require.NoError(t, req.UnmarshalProto(testOTLPProtoRequest()))
+		require.Len(t, req.ResourceSpans, 1)
+		rs := req.ResourceSpans[0]
+		require.Len(t, rs.LibrarySpans, 1)
+		require.Len(t, rs.LibrarySpans[0].Spans, 1)
+		assert.Len(t, rs.Resource, 4)
+		assert.Equal(t, "flask", rs.LibrarySpans[0].LibraryName)
+		span := rs.LibrarySpans[0].Spans[0]
+		assert.Equal(t, testTraceID, span.TraceID)
+		assert.Equal(t, otlpSpanKindServer, span.Kind)
+		assert.Equal(t, otlpStatus{Code: otlpStatusCodeError, Message: "internal error"}, span.Status)
+		assert.Equal(t, []otlpKeyValue{
+			{Key: "http.method", Value: "GET"},
+			{Key: "http.route", Value: "/users/:id"},
+			{Key: "http.status_code", Value: int64(500)},
+			{Key: "ratio", Value: 0.5},
+			{Key: "cached", Value: true},
+		}, span.Attributes)
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var protoReq, jsonReq otlpTraceRequest
+		require.NoError(t, protoReq.UnmarshalProto(testOTLPProtoRequest()))
+		require.NoError(t, jsonReq.UnmarshalJSON([]byte(testOTLPJSONRequest)))
+		assert.Equal(t, protoReq, jsonReq)
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		var req otlpTraceRequest
+		b := testOTLPProtoRequest()
+		assert.Error(t, req.UnmarshalProto(b[:len(b)-5]))
+	})
+}
+
+func TestOTLPValueString(t *testing.T) {
+	for _, tt := range []struct {
+		in  interface{}
+		out string
+	}{
+		{nil, ""},
+		{"a", "a"},
+		{true, "true"},
+		{int64(-3), "-3"},
+		{1.25, "1.25"},
+		{[]byte("abc"), "YWJj"},
+		{[]interface{}{"a", int64(1)}, `["a",1]`},
+		{[]otlpKeyValue{{Key: "k", Value: []interface{}{false}}}, `{"k":[false]}`},
+	} {
+		assert.Equal(t, tt.out, otlpValueString(tt.in))
+	}
+}
+
+func TestOTLPConvertSpan(t *testing.T) {
+	rattr := map[string]string{"service.name": "mysvc"}
+	lib := otlpLibrarySpans{}
+
+	t.Run("db", func(t *testing.T) {
+		span := convertSpan(rattr, lib, otlpSpan{
+			TraceID: testTraceID,
+			SpanID:  testSpanID,
+			Name:    "SELECT",
+			Kind:    otlpSpanKindClient,
+			Attributes: []otlpKeyValue{
+				{Key: "db.system", Value: "postgresql"},
+				{Key: "db.statement", Value: "SELECT * FROM users WHERE id = 42"},
+			},
+		})
+		assert.Equal(t, "sql", span.Type)
+		assert.Equal(t, "client", span.Name)
+		assert.Equal(t, "SELECT * FROM users WHERE id = 42", span.Resource)
+		assert.Equal(t, int32(0), span.Error)
+	})
+
+	t.Run("types", func(t *testing.T) {
+		for kind, typ := range map[otlpSpanKind]string{
+			otlpSpanKindServer:   "web",
+			otlpSpanKindClient:   "http",
+			otlpSpanKindInternal: "custom",
+			otlpSpanKindProducer: "custom",
+		} {
+			span := convertSpan(rattr, lib, otlpSpan{Kind: kind})
+			assert.Equal(t, typ, span.Type)
+		}
+		span := convertSpan(rattr, lib, otlpSpan{
+			Kind:       otlpSpanKindClient,
+			Attributes: []otlpKeyValue{{Key: "db.system", Value: "redis"}},
+		})
+		assert.Equal(t, "redis", span.Type)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		span := convertSpan(rattr, lib, otlpSpan{
+			Status: otlpStatus{Code: otlpStatusCodeOk, Message: "fine"},
+			Events: []otlpEvent{{Name: "exception", Attributes: []otlpKeyValue{{Key: "exception.message", Value: "ignored"}}}},
+		})
+		assert.Equal(t, int32(0), span.Error)
+		assert.Equal(t, "Ok", span.Meta["otel.status_code"])
+		assert.NotContains(t, span.Meta, "error.msg")
+	})
+}
+
+func newTestOTLPReceiver(out chan *Payload, cfg *config.OTLP) *OTLPReceiver {
+	conf := config.New()
+	conf.OTLPReceiver = cfg
+	return NewHTTPReceiver(conf, sampler.NewDynamicConfig("none"), out, noopStatsProcessor{}).otlp
+}
+
+func TestOTLPReceiverHTTP(t *testing.T) {
+	for name, tt := range map[string]struct {
+		contentType string
+		body        []byte
+	}{
+		"proto": {"application/x-protobuf", testOTLPProtoRequest()},
+		"json":  {"application/json", []byte(testOTLPJSONRequest)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			out := make(chan *Payload, 1)
+			o := newTestOTLPReceiver(out, &config.OTLP{})
+			req := httptest.NewRequest("POST", "/v1/traces", bytes.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			rec := httptest.NewRecorder()
+			o.handleHTTP(rec, req)
+			require.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, tt.contentType, rec.Header().Get("Content-Type"))
+
+			p := <-out
+			assert.Equal(t, "python", p.Source.Lang)
+			assert.Equal(t, otlpHTTPVersion, p.Source.EndpointVersion)
+			assert.EqualValues(t, 1, p.Source.TracesReceived)
+			assert.EqualValues(t, 1, p.Source.PayloadAccepted)
+			assert.EqualValues(t, len(tt.body), p.Source.TracesBytes)
+			require.Len(t, p.Traces, 1)
+			require.Len(t, p.Traces[0], 1)
+			assertTestSpan(t, p.Traces[0][0])
+		})
+	}
+
+	t.Run("bad-media-type", func(t *testing.T) {
+		o := newTestOTLPReceiver(make(chan *Payload, 1), &config.OTLP{})
+		req := httptest.NewRequest("POST", "/v1/traces", strings.NewReader("abc"))
+		req.Header.Set("Content-Type", "text/plain")
+		rec := httptest.NewRecorder()
+		o.handleHTTP(rec, req)
+		assert.Equal(t, http.StatusUnsupportedMediaType, rec.Code)
+	})
+
+	t.Run("decoding-error", func(t *testing.T) {
+		o := newTestOTLPReceiver(make(chan *Payload, 1), &config.OTLP{})
+		req := httptest.NewRequest("POST", "/v1/traces", strings.NewReader("{"))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		o.handleHTTP(rec, req)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("gzip", func(t *testing.T) {
+		out := make(chan *Payload, 1)
+		o := newTestOTLPReceiver(out, &config.OTLP{})
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		gz.Write([]byte(testOTLPJSONRequest))
+		gz.Close()
+		req := httptest.NewRequest("POST", "/v1/traces", &buf)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Content-Encoding", "gzip")
+		rec := httptest.NewRecorder()
+		o.handleHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Len(t, (<-out).Traces, 1)
+	})
+
+	t.Run("gzip-too-large", func(t *testing.T) {
+		out := make(chan *Payload, 1)
+		o := newTestOTLPReceiver(out, &config.OTLP{})
+		o.maxSize = 1024
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		gz.Write(bytes.Repeat([]byte(" "), 100*1024))
+		gz.Write([]byte(testOTLPJSONRequest))
+		gz.Close()
+		require.True(t, int64(buf.Len()) < o.maxSize)
+		req := httptest.NewRequest("POST", "/v1/traces", &buf)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Content-Encoding", "gzip")
+		rec := httptest.NewRecorder()
+		o.handleHTTP(rec, req)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+		assert.Len(t, out, 0)
+	})
+
+	t.Run("resources", func(t *testing.T) {
+		out := make(chan *Payload, 2)
+		o := newTestOTLPReceiver(out, &config.OTLP{})
+		rspans := newProtoMessage().message(1, testOTLPResourceSpans())
+		body := rspans.message(1, testOTLPResourceSpans()).Bytes()
+		req := httptest.NewRequest("POST", "/v1/traces", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/x-protobuf")
+		rec := httptest.NewRecorder()
+		o.handleHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Len(t, out, 2)
+
+		// the request is accounted once, no matter how many resources it holds
+		ts := (<-out).Source
+		assert.EqualValues(t, 2, ts.TracesReceived)
+		assert.EqualValues(t, 1, ts.PayloadAccepted)
+		assert.EqualValues(t, len(body), ts.TracesBytes)
+	})
+
+	t.Run("rate-limited", func(t *testing.T) {
+		out := make(chan *Payload, 1)
+		o := newTestOTLPReceiver(out, &config.OTLP{})
+		o.recv.RateLimiter.SetTargetRate(0)
+		o.recv.RateLimiter.Permits(1)
+		req := httptest.NewRequest("POST", "/v1/traces", bytes.NewReader(testOTLPProtoRequest()))
+		req.Header.Set("Content-Type", "application/x-protobuf")
+		rec := httptest.NewRecorder()
+		o.handleHTTP(rec, req)
+		assert.Equal(t, o.recv.rateLimiterResponse, rec.Code)
+		assert.Len(t, out, 0)
+		ts := o.recv.Stats.GetTagStats(info.Tags{Lang: "python", TracerVersion: "otlp-", EndpointVersion: otlpHTTPVersion})
+		assert.EqualValues(t, 1, ts.PayloadRefused)
+		assert.EqualValues(t, 0, ts.PayloadAccepted)
+	})
+
+	t.Run("blocked", func(t *testing.T) {
+		out := make(chan *Payload)
+		o := newTestOTLPReceiver(out, &config.OTLP{})
+		req := httptest.NewRequest("POST", "/v1/traces", bytes.NewReader(testOTLPProtoRequest()))
+		req.Header.Set("Content-Type", "application/x-protobuf")
+		rec := httptest.NewRecorder()
+		done := make(chan struct{})
+		go func() {
+			// the request is answered even though nobody reads the output channel
+			o.handleHTTP(rec, req)
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("the request was blocked by the output channel")
+		}
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Len(t, (<-out).Traces, 1)
+	})
+
+	t.Run("no-spans", func(t *testing.T) {
+		out := make(chan *Payload, 1)
+		o := newTestOTLPReceiver(out, &config.OTLP{})
+		body := `{"resourceSpans":[{"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"svc"}}]}}]}`
+		req := httptest.NewRequest("POST", "/v1/traces", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		o.handleHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Len(t, out, 0)
+	})
+}
+
+// rawCodec is a gRPC codec which sends and receives raw bytes.
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v interface{}) ([]byte, error) { return v.([]byte), nil }
+
+func (rawCodec) Unmarshal(data []byte, v interface{}) error {
+	*(v.(*[]byte)) = data
+	return nil
+}
+
+func (rawCodec) String() string { return "proto" }
+
+func TestOTLPReceiverGRPC(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+
+	out := make(chan *Payload, 1)
+	o := newTestOTLPReceiver(out, &config.OTLP{BindHost: "127.0.0.1", GRPCPort: port})
+	o.Start()
+	defer o.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := grpc.DialContext(ctx, fmt.Sprintf("127.0.0.1:%d", port),
+		grpc.WithInsecure(),
+		grpc.WithBlock(),
+		grpc.WithCodec(rawCodec{}), //nolint:staticcheck
+	)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	var resp []byte
+	err = conn.Invoke(ctx, "/opentelemetry.proto.collector.trace.v1.TraceService/Export", testOTLPProtoRequest(), &resp)
+	require.NoError(t, err)
+	assert.Empty(t, resp)
+
+	p := <-out
+	assert.Equal(t, otlpGRPCVersion, p.Source.EndpointVersion)
+	require.Len(t, p.Traces, 1)
+	require.Len(t, p.Traces[0], 1)
+	assertTestSpan(t, p.Traces[0][0])
+}
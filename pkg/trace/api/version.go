@@ -68,4 +68,19 @@ const (
 	// 		The dictionary in this case would be []string{""}, having only the empty string at index 0.
 	//
 	v05 Version = "v0.5"
+
+	// zipkinV2
+	//
+	// Endpoint: /api/v2/spans
+	// Content-Type: application/json or application/x-protobuf
+	// Payload: A list of Zipkin v2 spans, see https://zipkin.io/zipkin-api/#/default/post_spans
+	zipkinV2 Version = "zipkin_v2"
+
+	// jaegerThrift
+	//
+	// Endpoint: /api/traces
+	// Content-Type: application/x-thrift or application/vnd.apache.thrift.binary
+	// Payload: A Jaeger batch encoded using the Thrift binary protocol, as sent by the Jaeger
+	// clients to the collector.
+	jaegerThrift Version = "jaeger_thrift"
 )
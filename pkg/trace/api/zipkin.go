@@ -0,0 +1,298 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package api
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
+)
+
+// zipkinSpan is a Zipkin v2 span, as defined in:
+//
+//	https://github.com/openzipkin/zipkin-api/blob/master/zipkin2-api.yaml
+//	https://github.com/openzipkin/zipkin-api/blob/master/zipkin.proto
+//
+// The IDs are hex encoded in JSON, they are decoded from their binary form in protobuf.
+type zipkinSpan struct {
+	TraceID        string             `json:"traceId"`
+	ID             string             `json:"id"`
+	ParentID       string             `json:"parentId"`
+	Name           string             `json:"name"`
+	Kind           string             `json:"kind"`
+	Timestamp      uint64             `json:"timestamp"` // microseconds
+	Duration       uint64             `json:"duration"`  // microseconds
+	LocalEndpoint  *zipkinEndpoint    `json:"localEndpoint"`
+	RemoteEndpoint *zipkinEndpoint    `json:"remoteEndpoint"`
+	Annotations    []zipkinAnnotation `json:"annotations"`
+	Tags           map[string]string  `json:"tags"`
+	Debug          bool               `json:"debug"`
+	Shared         bool               `json:"shared"`
+}
+
+// zipkinEndpoint is the network context of a node in the service graph.
+type zipkinEndpoint struct {
+	ServiceName string `json:"serviceName"`
+	IPv4        string `json:"ipv4"`
+	IPv6        string `json:"ipv6"`
+	Port        int    `json:"port"`
+}
+
+// zipkinAnnotation is an event explaining latency with a timestamp.
+type zipkinAnnotation struct {
+	Timestamp uint64 `json:"timestamp"` // microseconds
+	Value     string `json:"value"`
+}
+
+// zipkinKinds are the Zipkin span kinds, by their value in protobuf.
+var zipkinKinds = []string{"", "CLIENT", "SERVER", "PRODUCER", "CONSUMER"}
+
+// decodeZipkinJSON decodes a list of Zipkin v2 spans encoded in JSON.
+func decodeZipkinJSON(b []byte) (pb.Traces, error) {
+	var in []zipkinSpan
+	if err := json.Unmarshal(b, &in); err != nil {
+		return nil, err
+	}
+	return convertZipkinSpans(in)
+}
+
+// decodeZipkinProto decodes a list of Zipkin v2 spans encoded in protobuf.
+func decodeZipkinProto(b []byte) (pb.Traces, error) {
+	var in []zipkinSpan
+	err := decodeMessage(b, func(r *protoReader, field uint64, wire int) error {
+		if field != 1 { // spans
+			return r.skip(wire)
+		}
+		return subMessage(r, field, wire, func(b []byte) error {
+			var s zipkinSpan
+			if err := s.unmarshalProto(b); err != nil {
+				return err
+			}
+			in = append(in, s)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return convertZipkinSpans(in)
+}
+
+func convertZipkinSpans(in []zipkinSpan) (pb.Traces, error) {
+	spans := make([]*pb.Span, 0, len(in))
+	for _, s := range in {
+		span, err := convertZipkinSpan(s)
+		if err != nil {
+			return nil, err
+		}
+		spans = append(spans, span)
+	}
+	return groupByTraceID(spans), nil
+}
+
+// convertZipkinSpan converts a Zipkin span into a Datadog span.
+func convertZipkinSpan(in zipkinSpan) (*pb.Span, error) {
+	traceID, err := zipkinID(in.TraceID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trace ID %q: %v", in.TraceID, err)
+	}
+	spanID, err := zipkinID(in.ID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid span ID %q: %v", in.ID, err)
+	}
+	parentID, err := zipkinID(in.ParentID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid parent ID %q: %v", in.ParentID, err)
+	}
+	span := &pb.Span{
+		TraceID:  traceID,
+		SpanID:   spanID,
+		ParentID: parentID,
+		Resource: in.Name,
+		Start:    int64(in.Timestamp) * 1000,
+		Duration: int64(in.Duration) * 1000,
+		Meta:     make(map[string]string, len(in.Tags)+4),
+		Metrics:  make(map[string]float64),
+	}
+	if in.LocalEndpoint != nil {
+		span.Service = in.LocalEndpoint.ServiceName
+	}
+	for k, v := range in.Tags {
+		span.Meta[k] = v
+	}
+	span.Meta["zipkin.trace_id"] = in.TraceID
+	if ep := in.RemoteEndpoint; ep != nil {
+		if ep.ServiceName != "" {
+			span.Meta["peer.service"] = ep.ServiceName
+		}
+		if ep.IPv4 != "" {
+			span.Meta["peer.ipv4"] = ep.IPv4
+		}
+		if ep.IPv6 != "" {
+			span.Meta["peer.ipv6"] = ep.IPv6
+		}
+		if ep.Port != 0 {
+			span.Meta["peer.port"] = strconv.Itoa(ep.Port)
+		}
+	}
+	if len(in.Annotations) > 0 {
+		if b, err := json.Marshal(in.Annotations); err == nil {
+			span.Meta["zipkin.annotations"] = string(b)
+		}
+	}
+	if in.Shared {
+		span.Meta["zipkin.shared"] = "true"
+	}
+	if in.Debug {
+		sampler.SetSamplingPriority(span, sampler.PriorityUserKeep)
+	}
+	finishCompatSpan(span, compatSpanKind(in.Kind))
+	return span, nil
+}
+
+// zipkinID converts a hex encoded Zipkin ID into a Datadog ID. 128-bit trace IDs
+// are truncated to their lower 64 bits.
+func zipkinID(id string) (uint64, error) {
+	if id == "" {
+		return 0, nil
+	}
+	if len(id) > 16 {
+		id = id[len(id)-16:]
+	}
+	return strconv.ParseUint(id, 16, 64)
+}
+
+func (s *zipkinSpan) unmarshalProto(b []byte) error {
+	return decodeMessage(b, func(r *protoReader, field uint64, wire int) error {
+		var err error
+		switch field {
+		case 1:
+			var id []byte
+			id, err = r.bytes()
+			s.TraceID = hex.EncodeToString(id)
+		case 2:
+			var id []byte
+			id, err = r.bytes()
+			s.ParentID = hex.EncodeToString(id)
+		case 3:
+			var id []byte
+			id, err = r.bytes()
+			s.ID = hex.EncodeToString(id)
+		case 4:
+			var v uint64
+			v, err = r.varint()
+			if v < uint64(len(zipkinKinds)) {
+				s.Kind = zipkinKinds[v]
+			}
+		case 5:
+			s.Name, err = r.string()
+		case 6:
+			if err = expect(field, wire, wireFixed64); err == nil {
+				s.Timestamp, err = r.fixed64()
+			}
+		case 7:
+			s.Duration, err = r.varint()
+		case 8:
+			s.LocalEndpoint = new(zipkinEndpoint)
+			err = subMessage(r, field, wire, s.LocalEndpoint.unmarshalProto)
+		case 9:
+			s.RemoteEndpoint = new(zipkinEndpoint)
+			err = subMessage(r, field, wire, s.RemoteEndpoint.unmarshalProto)
+		case 10:
+			err = subMessage(r, field, wire, func(b []byte) error {
+				var a zipkinAnnotation
+				if err := a.unmarshalProto(b); err != nil {
+					return err
+				}
+				s.Annotations = append(s.Annotations, a)
+				return nil
+			})
+		case 11:
+			err = subMessage(r, field, wire, func(b []byte) error {
+				var k, v string
+				err := decodeMessage(b, func(r *protoReader, field uint64, wire int) error {
+					var err error
+					switch field {
+					case 1:
+						k, err = r.string()
+					case 2:
+						v, err = r.string()
+					default:
+						err = r.skip(wire)
+					}
+					return err
+				})
+				if s.Tags == nil {
+					s.Tags = make(map[string]string)
+				}
+				s.Tags[k] = v
+				return err
+			})
+		case 12:
+			var v uint64
+			v, err = r.varint()
+			s.Debug = v != 0
+		case 13:
+			var v uint64
+			v, err = r.varint()
+			s.Shared = v != 0
+		default:
+			err = r.skip(wire)
+		}
+		return err
+	})
+}
+
+func (e *zipkinEndpoint) unmarshalProto(b []byte) error {
+	return decodeMessage(b, func(r *protoReader, field uint64, wire int) error {
+		var err error
+		switch field {
+		case 1:
+			e.ServiceName, err = r.string()
+		case 2:
+			var ip []byte
+			ip, err = r.bytes()
+			if len(ip) == net.IPv4len {
+				e.IPv4 = net.IP(ip).String()
+			}
+		case 3:
+			var ip []byte
+			ip, err = r.bytes()
+			if len(ip) == net.IPv6len {
+				e.IPv6 = net.IP(ip).String()
+			}
+		case 4:
+			var v uint64
+			v, err = r.varint()
+			e.Port = int(v)
+		default:
+			err = r.skip(wire)
+		}
+		return err
+	})
+}
+
+func (a *zipkinAnnotation) unmarshalProto(b []byte) error {
+	return decodeMessage(b, func(r *protoReader, field uint64, wire int) error {
+		var err error
+		switch field {
+		case 1:
+			if err = expect(field, wire, wireFixed64); err == nil {
+				a.Timestamp, err = r.fixed64()
+			}
+		case 2:
+			a.Value, err = r.string()
+		default:
+			err = r.skip(wire)
+		}
+		return err
+	})
+}
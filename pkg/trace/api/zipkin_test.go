@@ -0,0 +1,260 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
+)
+
+const testZipkinJSON = `[
+  {
+    "traceId": "72df520af2bde7a5240ee60221050802",
+    "id": "240ee60221050802",
+    "name": "get /users/{id}",
+    "kind": "SERVER",
+    "timestamp": 1600000000000000,
+    "duration": 1500,
+    "localEndpoint": {"serviceName": "frontend", "ipv4": "10.0.0.1"},
+    "remoteEndpoint": {"serviceName": "lb", "ipv4": "10.0.0.2", "port": 8080},
+    "annotations": [{"timestamp": 1600000000000100, "value": "wr"}],
+    "tags": {
+      "http.method": "GET",
+      "http.route": "/users/{id}",
+      "http.status_code": "500",
+      "error": "Internal Server Error"
+    },
+    "debug": true
+  },
+  {
+    "traceId": "72df520af2bde7a5240ee60221050802",
+    "parentId": "240ee60221050802",
+    "id": "0000000000000007",
+    "name": "query",
+    "kind": "CLIENT",
+    "timestamp": 1600000000000200,
+    "duration": 800,
+    "localEndpoint": {"serviceName": "frontend"},
+    "tags": {"db.system": "postgresql", "db.statement": "SELECT * FROM users WHERE id = 42"}
+  },
+  {
+    "traceId": "0000000000000002",
+    "id": "0000000000000003",
+    "name": "background",
+    "timestamp": 1600000000000000,
+    "duration": 10,
+    "localEndpoint": {"serviceName": "worker"}
+  }
+]`
+
+// assertZipkinTestTraces checks the traces decoded from the spans of testZipkinJSON.
+func assertZipkinTestTraces(t *testing.T, traces pb.Traces) {
+	require.Len(t, traces, 2)
+	require.Len(t, traces[0], 2)
+	require.Len(t, traces[1], 1)
+
+	server := traces[0][0]
+	assert.EqualValues(t, 0x240ee60221050802, server.TraceID)
+	assert.EqualValues(t, 0x240ee60221050802, server.SpanID)
+	assert.EqualValues(t, 0, server.ParentID)
+	assert.Equal(t, "frontend", server.Service)
+	assert.Equal(t, "server", server.Name)
+	assert.Equal(t, "GET /users/{id}", server.Resource)
+	assert.Equal(t, "web", server.Type)
+	assert.EqualValues(t, 1600000000000000000, server.Start)
+	assert.EqualValues(t, 1500000, server.Duration)
+	assert.EqualValues(t, 1, server.Error)
+	assert.Equal(t, "Internal Server Error", server.Meta["error.msg"])
+	assert.Equal(t, "72df520af2bde7a5240ee60221050802", server.Meta["zipkin.trace_id"])
+	assert.Equal(t, "lb", server.Meta["peer.service"])
+	assert.Equal(t, "10.0.0.2", server.Meta["peer.ipv4"])
+	assert.Equal(t, "8080", server.Meta["peer.port"])
+	assert.Equal(t, `[{"timestamp":1600000000000100,"value":"wr"}]`, server.Meta["zipkin.annotations"])
+	priority, ok := sampler.GetSamplingPriority(server)
+	assert.True(t, ok)
+	assert.Equal(t, sampler.PriorityUserKeep, priority)
+
+	client := traces[0][1]
+	assert.EqualValues(t, 7, client.SpanID)
+	assert.EqualValues(t, 0x240ee60221050802, client.ParentID)
+	assert.Equal(t, "client", client.Name)
+	assert.Equal(t, "sql", client.Type)
+	assert.Equal(t, "SELECT * FROM users WHERE id = 42", client.Resource)
+	assert.EqualValues(t, 0, client.Error)
+
+	internal := traces[1][0]
+	assert.EqualValues(t, 2, internal.TraceID)
+	assert.Equal(t, "worker", internal.Service)
+	assert.Equal(t, "internal", internal.Name)
+	assert.Equal(t, "background", internal.Resource)
+	_, ok = sampler.GetSamplingPriority(internal)
+	assert.False(t, ok)
+}
+
+// testZipkinProto returns the spans of testZipkinJSON encoded in protobuf.
+func testZipkinProto() []byte {
+	traceID := []byte{0x72, 0xdf, 0x52, 0x0a, 0xf2, 0xbd, 0xe7, 0xa5, 0x24, 0x0e, 0xe6, 0x02, 0x21, 0x05, 0x08, 0x02}
+	spanID := []byte{0x24, 0x0e, 0xe6, 0x02, 0x21, 0x05, 0x08, 0x02}
+	tag := func(k, v string) protoMessage { return newProtoMessage().string(1, k).string(2, v) }
+	server := newProtoMessage().
+		bytes(1, traceID).
+		bytes(3, spanID).
+		varint(4, 2).
+		string(5, "get /users/{id}").
+		fixed64(6, 1600000000000000).
+		varint(7, 1500).
+		message(8, newProtoMessage().string(1, "frontend").bytes(2, []byte{10, 0, 0, 1})).
+		message(9, newProtoMessage().string(1, "lb").bytes(2, []byte{10, 0, 0, 2}).varint(4, 8080)).
+		message(10, newProtoMessage().fixed64(1, 1600000000000100).string(2, "wr")).
+		message(11, tag("http.method", "GET")).
+		message(11, tag("http.route", "/users/{id}")).
+		message(11, tag("http.status_code", "500")).
+		message(11, tag("error", "Internal Server Error")).
+		varint(12, 1)
+	client := newProtoMessage().
+		bytes(1, traceID).
+		bytes(2, spanID).
+		bytes(3, []byte{0, 0, 0, 0, 0, 0, 0, 7}).
+		varint(4, 1).
+		string(5, "query").
+		fixed64(6, 1600000000000200).
+		varint(7, 800).
+		message(8, newProtoMessage().string(1, "frontend")).
+		message(11, tag("db.system", "postgresql")).
+		message(11, tag("db.statement", "SELECT * FROM users WHERE id = 42"))
+	internal := newProtoMessage().
+		bytes(1, []byte{0, 0, 0, 0, 0, 0, 0, 2}).
+		bytes(3, []byte{0, 0, 0, 0, 0, 0, 0, 3}).
+		string(5, "background").
+		fixed64(6, 1600000000000000).
+		varint(7, 10).
+		message(8, newProtoMessage().string(1, "worker"))
+	return newProtoMessage().message(1, server).message(1, client).message(1, internal).Bytes()
+}
+
+func TestDecodeZipkin(t *testing.T) {
+	t.Run("json", func(t *testing.T) {
+		traces, err := decodeZipkinJSON([]byte(testZipkinJSON))
+		require.NoError(t, err)
+		assertZipkinTestTraces(t, traces)
+	})
+
+	t.Run("proto", func(t *testing.T) {
+		traces, err := decodeZipkinProto(testZipkinProto())
+		require.NoError(t, err)
+		assertZipkinTestTraces(t, traces)
+	})
+
+	t.Run("invalid-id", func(t *testing.T) {
+		_, err := decodeZipkinJSON([]byte(`[{"traceId": "xyz", "id": "1"}]`))
+		assert.Error(t, err)
+	})
+
+	t.Run("truncated-proto", func(t *testing.T) {
+		b := testZipkinProto()
+		_, err := decodeZipkinProto(b[:len(b)-3])
+		assert.Error(t, err)
+	})
+}
+
+func TestZipkinEndpoint(t *testing.T) {
+	for name, tt := range map[string]struct {
+		contentType string
+		body        []byte
+	}{
+		"json":  {"application/json", []byte(testZipkinJSON)},
+		"proto": {"application/x-protobuf", testZipkinProto()},
+	} {
+		t.Run(name, func(t *testing.T) {
+			r := newTestReceiverFromConfig(newTestReceiverConfig())
+			req := httptest.NewRequest("POST", "/api/v2/spans", bytes.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			rec := httptest.NewRecorder()
+			r.buildMux().ServeHTTP(rec, req)
+			require.Equal(t, http.StatusAccepted, rec.Code)
+
+			p := <-r.out
+			assert.Equal(t, string(zipkinV2), p.Source.EndpointVersion)
+			assert.EqualValues(t, 2, p.Source.TracesReceived)
+			assertZipkinTestTraces(t, p.Traces)
+		})
+	}
+
+	t.Run("gzip", func(t *testing.T) {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		_, err := gz.Write([]byte(testZipkinJSON))
+		require.NoError(t, err)
+		require.NoError(t, gz.Close())
+
+		r := newTestReceiverFromConfig(newTestReceiverConfig())
+		req := httptest.NewRequest("POST", "/api/v2/spans", &buf)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Content-Encoding", "gzip")
+		rec := httptest.NewRecorder()
+		r.buildMux().ServeHTTP(rec, req)
+		require.Equal(t, http.StatusAccepted, rec.Code)
+		assertZipkinTestTraces(t, (<-r.out).Traces)
+	})
+
+	t.Run("gzip-too-large", func(t *testing.T) {
+		conf := newTestReceiverConfig()
+		conf.MaxRequestBytes = 1024
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		_, err := gz.Write(bytes.Repeat([]byte(" "), 100*1024))
+		require.NoError(t, err)
+		_, err = gz.Write([]byte(testZipkinJSON))
+		require.NoError(t, err)
+		require.NoError(t, gz.Close())
+		require.True(t, int64(buf.Len()) < conf.MaxRequestBytes)
+
+		r := newTestReceiverFromConfig(conf)
+		req := httptest.NewRequest("POST", "/api/v2/spans", &buf)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Content-Encoding", "gzip")
+		rec := httptest.NewRecorder()
+		r.buildMux().ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+		assert.Len(t, r.out, 0)
+	})
+
+	t.Run("bad-media-type", func(t *testing.T) {
+		r := newTestReceiverFromConfig(newTestReceiverConfig())
+		req := httptest.NewRequest("POST", "/api/v2/spans", strings.NewReader(testZipkinJSON))
+		req.Header.Set("Content-Type", "text/plain")
+		rec := httptest.NewRecorder()
+		r.buildMux().ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusUnsupportedMediaType, rec.Code)
+	})
+
+	t.Run("decoding-error", func(t *testing.T) {
+		r := newTestReceiverFromConfig(newTestReceiverConfig())
+		req := httptest.NewRequest("POST", "/api/v2/spans", strings.NewReader("{"))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		r.buildMux().ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("method-not-allowed", func(t *testing.T) {
+		r := newTestReceiverFromConfig(newTestReceiverConfig())
+		req := httptest.NewRequest("GET", "/api/v2/spans", nil)
+		rec := httptest.NewRecorder()
+		r.buildMux().ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	})
+}
@@ -23,6 +23,10 @@ import (
 // apiEndpointPrefix is the URL prefix prepended to the default site value from YamlAgentConfig.
 const apiEndpointPrefix = "https://trace.agent."
 
+// defaultTailSamplingDecisionWait is the default time, in seconds, during which the chunks
+// of a trace are buffered by the tail-based sampling.
+const defaultTailSamplingDecisionWait = 5
+
 // ObfuscationConfig holds the configuration for obfuscating sensitive data
 // for various span types.
 type ObfuscationConfig struct {
@@ -42,6 +46,9 @@ type ObfuscationConfig struct {
 	// HTTP holds the obfuscation settings for HTTP URLs.
 	HTTP HTTPObfuscationConfig `mapstructure:"http"`
 
+	// SQL holds the obfuscation settings for SQL queries.
+	SQL SQLObfuscationConfig `mapstructure:"sql"`
+
 	// RemoveStackTraces specifies whether stack traces should be removed.
 	// More specifically "error.stack" tag values will be cleared.
 	RemoveStackTraces bool `mapstructure:"remove_stack_traces"`
@@ -53,6 +60,64 @@ type ObfuscationConfig struct {
 	// Memcached holds the configuration for obfuscating the "memcached.command" tag
 	// for spans of type "memcached".
 	Memcached Enablable `mapstructure:"memcached"`
+
+	// Rules holds the user-defined obfuscation rules, applied in order after the obfuscation
+	// based on the span type. They are read from "apm_config.obfuscation.rules".
+	Rules []*ObfuscationRule `mapstructure:"-"`
+}
+
+// Actions of the obfuscation rules.
+const (
+	// ObfuscationActionRedact replaces the values with "?" and removes the metrics.
+	ObfuscationActionRedact = "redact"
+	// ObfuscationActionHash replaces the values with their salted SHA-256 hash.
+	ObfuscationActionHash = "hash"
+	// ObfuscationActionTruncate truncates the values to a maximum length.
+	ObfuscationActionTruncate = "truncate"
+	// ObfuscationActionMaskDigits replaces the digits of the values with "*", except the last ones.
+	ObfuscationActionMaskDigits = "mask_digits"
+	// ObfuscationActionKeepJSONKeys obfuscates the JSON values, except the ones of an allowlist of keys.
+	ObfuscationActionKeepJSONKeys = "keep_json_keys"
+)
+
+// ObfuscationRule specifies a user-defined obfuscation rule. It applies an action to the values
+// of the targeted tags, metrics and resource of the spans of the matching services. The service,
+// tags and metrics patterns support the "*" and "?" wildcards. The env and the internal tags
+// and metrics, the ones starting with "_", are never targeted.
+type ObfuscationRule struct {
+	// Service is the pattern of the services whose spans are obfuscated, all services match if empty.
+	Service string `mapstructure:"service"`
+
+	// Tags holds the patterns of the keys of the targeted meta tags.
+	Tags []string `mapstructure:"tags"`
+
+	// Metrics holds the patterns of the keys of the targeted metrics. Metrics only support
+	// the "redact" action.
+	Metrics []string `mapstructure:"metrics"`
+
+	// Resource specifies whether the resource is targeted.
+	Resource bool `mapstructure:"resource"`
+
+	// Action is the action applied to the targeted values, one of the ObfuscationAction* constants.
+	Action string `mapstructure:"action"`
+
+	// Salt is prepended to the values hashed by the "hash" action.
+	Salt string `mapstructure:"salt"`
+
+	// MaxLength is the maximum length in bytes of the values truncated by the "truncate" action.
+	MaxLength int `mapstructure:"max_length"`
+
+	// KeepDigits is the number of trailing digits left unmasked by the "mask_digits" action.
+	KeepDigits int `mapstructure:"keep_digits"`
+
+	// KeepKeys holds the keys whose values are not obfuscated by the "keep_json_keys" action.
+	KeepKeys []string `mapstructure:"keep_keys"`
+
+	// ServiceRe, TagsRe and MetricsRe hold the compiled patterns and are only used internally.
+	// A nil ServiceRe matches any service.
+	ServiceRe *regexp.Regexp   `mapstructure:"-"`
+	TagsRe    []*regexp.Regexp `mapstructure:"-"`
+	MetricsRe []*regexp.Regexp `mapstructure:"-"`
 }
 
 // HTTPObfuscationConfig holds the configuration settings for HTTP obfuscation.
@@ -64,6 +129,13 @@ type HTTPObfuscationConfig struct {
 	RemovePathDigits bool `mapstructure:"remove_paths_with_digits"`
 }
 
+// SQLObfuscationConfig holds the configuration settings for SQL obfuscation.
+type SQLObfuscationConfig struct {
+	// CollectMetadata specifies whether the tables, commands and sqlcommenter comment keys of
+	// the queries should be reported in the "sql.tables", "sql.commands" and "sql.comments" tags.
+	CollectMetadata bool `mapstructure:"collect_metadata"`
+}
+
 // Enablable can represent any option that has an "enabled" boolean sub-field.
 type Enablable struct {
 	Enabled bool `mapstructure:"enabled"`
@@ -102,6 +174,59 @@ type ReplaceRule struct {
 	Repl string `mapstructure:"repl"`
 }
 
+// SamplingRule specifies a user-defined sampling rule. A trace matches the rule when its
+// root span matches all the non-empty criteria, which support the "*" and "?" wildcards.
+type SamplingRule struct {
+	// Service, OperationName, Resource and Env match the corresponding attributes of the root span.
+	Service       string `mapstructure:"service"`
+	OperationName string `mapstructure:"name"`
+	Resource      string `mapstructure:"resource"`
+	Env           string `mapstructure:"env"`
+
+	// Tags maps tag keys to the patterns their values must match on the root span.
+	Tags map[string]string `mapstructure:"tags"`
+
+	// SampleRate specifies the rate at which the matching traces are kept.
+	SampleRate *float64 `mapstructure:"sample_rate"`
+
+	// MaxTPS specifies the maximum number of matching traces kept per second.
+	MaxTPS float64 `mapstructure:"max_tps"`
+
+	// ServiceRe, OperationNameRe, ResourceRe, EnvRe and TagsRe hold the compiled patterns
+	// and are only used internally. A nil pattern matches any value.
+	ServiceRe       *regexp.Regexp            `mapstructure:"-"`
+	OperationNameRe *regexp.Regexp            `mapstructure:"-"`
+	ResourceRe      *regexp.Regexp            `mapstructure:"-"`
+	EnvRe           *regexp.Regexp            `mapstructure:"-"`
+	TagsRe          map[string]*regexp.Regexp `mapstructure:"-"`
+}
+
+// TailSamplingConfig holds the configuration of the tail-based sampling, which buffers
+// the chunks of the traces to decide on complete traces.
+type TailSamplingConfig struct {
+	// Enabled specifies whether the traces are buffered to be sampled once complete.
+	Enabled bool `mapstructure:"enabled"`
+
+	// DecisionWaitSeconds specifies how long the chunks of a trace are buffered after
+	// its first chunk is received, in seconds. Fractions are permitted.
+	DecisionWaitSeconds float64 `mapstructure:"decision_wait_seconds"`
+
+	// MaxMemoryRatio specifies the share of the agent's maximum memory that the
+	// buffered traces can use.
+	MaxMemoryRatio float64 `mapstructure:"max_memory_ratio"`
+
+	// Errors specifies whether the traces containing an error are kept.
+	Errors bool `mapstructure:"errors"`
+
+	// LatencyPercentile specifies the percentile of the duration of the root spans of a
+	// service above which its traces are kept. 0 disables it.
+	LatencyPercentile float64 `mapstructure:"latency_percentile"`
+
+	// Tags specifies the tags for which the traces having a span with the tag are kept.
+	// An empty value matches any value.
+	Tags map[string]string `mapstructure:"tags"`
+}
+
 // WriterConfig specifies configuration for an API writer.
 type WriterConfig struct {
 	// ConnectionLimit specifies the maximum number of concurrent outgoing
@@ -238,6 +363,18 @@ func (c *AgentConfig) applyDatadogConfig() error {
 		}
 	}
 
+	if k := "apm_config.sampling_rules"; config.Datadog.IsSet(k) {
+		rules := make([]*SamplingRule, 0)
+		if err := config.Datadog.UnmarshalKey(k, &rules); err != nil {
+			log.Errorf("Bad format for %q it should be of the form '[{\"service\": \"service_pattern\",\"sample_rate\": 0.5}]', error: %v", "apm_config.sampling_rules", err)
+		} else {
+			if err := compileSamplingRules(rules); err != nil {
+				osutil.Exitf("sampling_rules: %s", err)
+			}
+			c.SamplingRules = rules
+		}
+	}
+
 	if config.Datadog.IsSet("bind_host") || config.Datadog.IsSet("apm_config.apm_non_local_traffic") {
 		if config.Datadog.IsSet("bind_host") {
 			host := config.Datadog.GetString("bind_host")
@@ -254,6 +391,14 @@ func (c *AgentConfig) applyDatadogConfig() error {
 		c.ReceiverHost = "0.0.0.0"
 	}
 
+	if config.Datadog.IsSet("apm_config.otlp.http_port") || config.Datadog.IsSet("apm_config.otlp.grpc_port") {
+		c.OTLPReceiver = &OTLP{
+			BindHost: c.ReceiverHost,
+			HTTPPort: config.Datadog.GetInt("apm_config.otlp.http_port"),
+			GRPCPort: config.Datadog.GetInt("apm_config.otlp.grpc_port"),
+		}
+	}
+
 	if config.Datadog.IsSet("apm_config.obfuscation") {
 		var o ObfuscationConfig
 		err := config.Datadog.UnmarshalKey("apm_config.obfuscation", &o)
@@ -265,6 +410,35 @@ func (c *AgentConfig) applyDatadogConfig() error {
 		}
 	}
 
+	if k := "apm_config.obfuscation.rules"; config.Datadog.IsSet(k) {
+		rules := make([]*ObfuscationRule, 0)
+		if err := config.Datadog.UnmarshalKey(k, &rules); err != nil {
+			log.Errorf("Bad format for %q it should be of the form '[{\"service\": \"service_pattern\",\"tags\": [\"tag_pattern\"],\"action\": \"redact\"}]', error: %v", k, err)
+		} else {
+			if err := compileObfuscationRules(rules); err != nil {
+				osutil.Exitf("obfuscation rules: %s", err)
+			}
+			if c.Obfuscation == nil {
+				c.Obfuscation = new(ObfuscationConfig)
+			}
+			c.Obfuscation.Rules = rules
+		}
+	}
+
+	if k := "apm_config.tail_sampling"; config.Datadog.IsSet(k) {
+		if err := config.Datadog.UnmarshalKey(k, c.TailSampling); err != nil {
+			log.Errorf("Error reading tail sampling config %q: %v", k, err)
+		}
+		if p := c.TailSampling.LatencyPercentile; p < 0 || p >= 1 {
+			log.Errorf("Invalid value for %s.latency_percentile, it must be between 0 and 1: %v", k, p)
+			c.TailSampling.LatencyPercentile = 0
+		}
+		if c.TailSampling.DecisionWaitSeconds <= 0 {
+			log.Errorf("Invalid value for %s.decision_wait_seconds, it must be positive: %v", k, c.TailSampling.DecisionWaitSeconds)
+			c.TailSampling.DecisionWaitSeconds = defaultTailSamplingDecisionWait
+		}
+	}
+
 	// undocumented
 	if config.Datadog.IsSet("apm_config.max_cpu_percent") {
 		c.MaxCPU = config.Datadog.GetFloat64("apm_config.max_cpu_percent") / 100
@@ -396,6 +570,85 @@ func compileReplaceRules(rules []*ReplaceRule) error {
 	return nil
 }
 
+// compileSamplingRules validates the sampling rules and compiles their patterns.
+func compileSamplingRules(rules []*SamplingRule) error {
+	for i, r := range rules {
+		switch {
+		case r.SampleRate != nil && r.MaxTPS != 0:
+			return fmt.Errorf("rule %d: \"sample_rate\" and \"max_tps\" can't be both set", i)
+		case r.SampleRate != nil && (*r.SampleRate < 0 || *r.SampleRate > 1):
+			return fmt.Errorf("rule %d: \"sample_rate\" must be between 0 and 1", i)
+		case r.SampleRate == nil && r.MaxTPS <= 0:
+			return fmt.Errorf("rule %d: either a \"sample_rate\" or a positive \"max_tps\" must be set", i)
+		}
+		r.ServiceRe = compileGlob(r.Service)
+		r.OperationNameRe = compileGlob(r.OperationName)
+		r.ResourceRe = compileGlob(r.Resource)
+		r.EnvRe = compileGlob(r.Env)
+		if len(r.Tags) > 0 {
+			r.TagsRe = make(map[string]*regexp.Regexp, len(r.Tags))
+			for k, v := range r.Tags {
+				if v == "" {
+					// any value matches as long as the tag is set
+					v = "*"
+				}
+				r.TagsRe[k] = compileGlob(v)
+			}
+		}
+	}
+	return nil
+}
+
+// compileObfuscationRules validates the obfuscation rules and compiles their patterns.
+func compileObfuscationRules(rules []*ObfuscationRule) error {
+	for i, r := range rules {
+		switch r.Action {
+		case ObfuscationActionRedact, ObfuscationActionHash, ObfuscationActionMaskDigits, ObfuscationActionKeepJSONKeys:
+		case ObfuscationActionTruncate:
+			if r.MaxLength <= 0 {
+				return fmt.Errorf("rule %d: \"max_length\" must be positive", i)
+			}
+		default:
+			return fmt.Errorf("rule %d: unknown action %q", i, r.Action)
+		}
+		if len(r.Metrics) > 0 && r.Action != ObfuscationActionRedact {
+			return fmt.Errorf("rule %d: metrics only support the %q action", i, ObfuscationActionRedact)
+		}
+		if len(r.Tags) == 0 && len(r.Metrics) == 0 && !r.Resource {
+			return fmt.Errorf("rule %d: at least one of \"tags\", \"metrics\" or \"resource\" must be set", i)
+		}
+		if r.KeepDigits < 0 {
+			return fmt.Errorf("rule %d: \"keep_digits\" can't be negative", i)
+		}
+		r.ServiceRe = compileGlob(r.Service)
+		r.TagsRe = make([]*regexp.Regexp, 0, len(r.Tags))
+		for _, p := range r.Tags {
+			if p != "" {
+				r.TagsRe = append(r.TagsRe, compileGlob(p))
+			}
+		}
+		r.MetricsRe = make([]*regexp.Regexp, 0, len(r.Metrics))
+		for _, p := range r.Metrics {
+			if p != "" {
+				r.MetricsRe = append(r.MetricsRe, compileGlob(p))
+			}
+		}
+	}
+	return nil
+}
+
+// compileGlob returns a regular expression matching the whole value against a pattern
+// supporting the "*" and "?" wildcards, or nil if the pattern is empty.
+func compileGlob(pattern string) *regexp.Regexp {
+	if pattern == "" {
+		return nil
+	}
+	quoted := regexp.QuoteMeta(pattern)
+	quoted = strings.Replace(quoted, `\*`, ".*", -1)
+	quoted = strings.Replace(quoted, `\?`, ".", -1)
+	return regexp.MustCompile("^" + quoted + "$")
+}
+
 // getDuration returns the duration of the provided value in seconds
 func getDuration(seconds int) time.Duration {
 	return time.Duration(seconds) * time.Second
@@ -27,3 +27,80 @@ func TestParseRepaceRules(t *testing.T) {
 		assert.Equal(r.Pattern, r.Re.String())
 	}
 }
+
+func TestCompileSamplingRules(t *testing.T) {
+	rate := func(r float64) *float64 { return &r }
+
+	t.Run("patterns", func(t *testing.T) {
+		assert := assert.New(t)
+		rules := []*SamplingRule{
+			{Service: "web-*", OperationName: "http.request", Resource: "GET /user/?", Tags: map[string]string{"region": "us-*", "canary": ""}, SampleRate: rate(0.5)},
+			{MaxTPS: 10},
+		}
+		assert.NoError(compileSamplingRules(rules))
+
+		r := rules[0]
+		assert.True(r.ServiceRe.MatchString("web-store"))
+		assert.False(r.ServiceRe.MatchString("my-web-store"))
+		assert.True(r.OperationNameRe.MatchString("http.request"))
+		assert.False(r.OperationNameRe.MatchString("httpxrequest"))
+		assert.True(r.ResourceRe.MatchString("GET /user/1"))
+		assert.False(r.ResourceRe.MatchString("GET /user/12"))
+		assert.Nil(r.EnvRe)
+		assert.True(r.TagsRe["region"].MatchString("us-east-1"))
+		assert.True(r.TagsRe["canary"].MatchString("true"))
+
+		r = rules[1]
+		assert.Nil(r.ServiceRe)
+		assert.Nil(r.TagsRe)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, r := range []*SamplingRule{
+			{Service: "web"},
+			{Service: "web", MaxTPS: -1},
+			{Service: "web", SampleRate: rate(1.5)},
+			{Service: "web", SampleRate: rate(-0.1)},
+			{Service: "web", SampleRate: rate(0.5), MaxTPS: 10},
+		} {
+			assert.Error(t, compileSamplingRules([]*SamplingRule{r}))
+		}
+	})
+}
+
+func TestCompileObfuscationRules(t *testing.T) {
+	t.Run("patterns", func(t *testing.T) {
+		assert := assert.New(t)
+		rules := []*ObfuscationRule{
+			{Service: "billing-*", Tags: []string{"user.*", "card"}, Metrics: []string{"balance"}, Action: ObfuscationActionRedact},
+			{Resource: true, Action: ObfuscationActionTruncate, MaxLength: 10},
+		}
+		assert.NoError(compileObfuscationRules(rules))
+
+		r := rules[0]
+		assert.True(r.ServiceRe.MatchString("billing-api"))
+		assert.False(r.ServiceRe.MatchString("web"))
+		assert.Len(r.TagsRe, 2)
+		assert.True(r.TagsRe[0].MatchString("user.email"))
+		assert.False(r.TagsRe[1].MatchString("card.type"))
+		assert.Len(r.MetricsRe, 1)
+		assert.True(r.MetricsRe[0].MatchString("balance"))
+
+		r = rules[1]
+		assert.Nil(r.ServiceRe)
+		assert.Empty(r.TagsRe)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, r := range []*ObfuscationRule{
+			{Tags: []string{"card"}},
+			{Tags: []string{"card"}, Action: "encrypt"},
+			{Tags: []string{"card"}, Action: ObfuscationActionTruncate},
+			{Tags: []string{"card"}, Action: ObfuscationActionMaskDigits, KeepDigits: -1},
+			{Metrics: []string{"balance"}, Action: ObfuscationActionHash},
+			{Service: "web", Action: ObfuscationActionRedact},
+		} {
+			assert.Error(t, compileObfuscationRules([]*ObfuscationRule{r}))
+		}
+	})
+}
@@ -41,6 +41,20 @@ type Endpoint struct {
 	NoProxy bool
 }
 
+// OTLP holds the configuration for the OpenTelemetry (OTLP) trace receiver.
+type OTLP struct {
+	// BindHost specifies the host to bind the receiver to.
+	BindHost string `mapstructure:"-"`
+
+	// HTTPPort specifies the port to use for the plain HTTP (protobuf and JSON) receiver.
+	// If unset (or 0), the receiver will be off.
+	HTTPPort int `mapstructure:"http_port"`
+
+	// GRPCPort specifies the port to use for the gRPC receiver.
+	// If unset (or 0), the receiver will be off.
+	GRPCPort int `mapstructure:"grpc_port"`
+}
+
 // AgentConfig handles the interpretation of the configuration (with default
 // behaviors) in one place. It is also a simple structure to share across all
 // the Agent components, with 100% safe and reliable values.
@@ -77,6 +91,10 @@ type AgentConfig struct {
 	ReceiverTimeout int
 	MaxRequestBytes int64 // specifies the maximum allowed request size for incoming trace payloads
 
+	// OTLPReceiver holds the configuration for the OpenTelemetry receiver. It is nil
+	// when the receiver is disabled.
+	OTLPReceiver *OTLP
+
 	// Writers
 	StatsWriter             *WriterConfig
 	TraceWriter             *WriterConfig
@@ -107,6 +125,13 @@ type AgentConfig struct {
 	// It maps tag keys to a set of replacements. Only supported in A6.
 	ReplaceTags []*ReplaceRule
 
+	// SamplingRules are the user-defined sampling rules, evaluated in order on the
+	// root span of the traces before the sampling engines.
+	SamplingRules []*SamplingRule
+
+	// TailSampling holds the configuration of the tail-based sampling.
+	TailSampling *TailSamplingConfig
+
 	// transaction analytics
 	AnalyzedRateByServiceLegacy map[string]float64
 	AnalyzedSpansByService      map[string]map[string]float64
@@ -131,6 +156,12 @@ func New() *AgentConfig {
 		TargetTPS:       10,
 		MaxEPS:          200,
 
+		TailSampling: &TailSamplingConfig{
+			DecisionWaitSeconds: defaultTailSamplingDecisionWait,
+			MaxMemoryRatio:      0.25,
+			Errors:              true,
+		},
+
 		ReceiverHost:    "localhost",
 		ReceiverPort:    8126,
 		MaxRequestBytes: 50 * 1024 * 1024, // 50MB
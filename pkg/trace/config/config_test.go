@@ -202,6 +202,24 @@ func TestFullYamlConfig(t *testing.T) {
 		},
 	}, c.ReplaceTags)
 
+	assert.Equal(&TailSamplingConfig{
+		Enabled:             true,
+		DecisionWaitSeconds: 2.5,
+		MaxMemoryRatio:      0.25,
+		Errors:              true,
+		LatencyPercentile:   0.99,
+		Tags:                map[string]string{"customer.tier": "gold"},
+	}, c.TailSampling)
+
+	assert.Len(c.SamplingRules, 2)
+	assert.Equal("checkout", c.SamplingRules[0].Service)
+	assert.Equal(1.0, *c.SamplingRules[0].SampleRate)
+	assert.Equal("http.request", c.SamplingRules[1].OperationName)
+	assert.Equal("GET /health*", c.SamplingRules[1].Resource)
+	assert.Equal(map[string]string{"env": "prod"}, c.SamplingRules[1].Tags)
+	assert.Nil(c.SamplingRules[1].SampleRate)
+	assert.Equal(5.0, c.SamplingRules[1].MaxTPS)
+
 	assert.EqualValues([]string{"/health", "/500"}, c.Ignore["resource"])
 
 	o := c.Obfuscation
@@ -212,9 +230,17 @@ func TestFullYamlConfig(t *testing.T) {
 	assert.EqualValues([]string{"uid", "cat_id"}, o.Mongo.KeepValues)
 	assert.True(o.HTTP.RemoveQueryString)
 	assert.True(o.HTTP.RemovePathDigits)
+	assert.True(o.SQL.CollectMetadata)
 	assert.True(o.RemoveStackTraces)
 	assert.True(c.Obfuscation.Redis.Enabled)
 	assert.True(c.Obfuscation.Memcached.Enabled)
+	assert.Len(o.Rules, 2)
+	assert.Equal(ObfuscationActionMaskDigits, o.Rules[0].Action)
+	assert.Equal(4, o.Rules[0].KeepDigits)
+	assert.True(o.Rules[0].ServiceRe.MatchString("billing-api"))
+	assert.True(o.Rules[0].TagsRe[0].MatchString("card.number"))
+	assert.True(o.Rules[1].Resource)
+	assert.Equal(100, o.Rules[1].MaxLength)
 }
 
 func TestUndocumentedYamlConfig(t *testing.T) {
@@ -253,6 +253,22 @@ func TestLoadEnv(t *testing.T) {
 		assert.Contains(cfg.ReplaceTags, rule2)
 	})
 
+	env = "DD_APM_SAMPLING_RULES"
+	t.Run(env, func(t *testing.T) {
+		defer cleanConfig()()
+		assert := assert.New(t)
+		err := os.Setenv(env, `[{"service":"checkout", "sample_rate":1}, {"resource":"GET /health*","max_tps":0.5}]`)
+		assert.NoError(err)
+		defer os.Unsetenv(env)
+		cfg, err := Load("./testdata/full.yaml")
+		assert.NoError(err)
+		assert.Len(cfg.SamplingRules, 2)
+		assert.Equal("checkout", cfg.SamplingRules[0].Service)
+		assert.Equal(1.0, *cfg.SamplingRules[0].SampleRate)
+		assert.Equal("GET /health*", cfg.SamplingRules[1].Resource)
+		assert.Equal(0.5, cfg.SamplingRules[1].MaxTPS)
+	})
+
 	for _, envKey := range []string{
 		"DD_CONNECTION_LIMIT", // deprecated
 		"DD_APM_CONNECTION_LIMIT",
@@ -69,6 +69,7 @@ const (
   From {{if $ts.Tags.Lang}}{{ $ts.Tags.Lang }} {{ $ts.Tags.LangVersion }} ({{ $ts.Tags.Interpreter }}), client {{ $ts.Tags.TracerVersion }}{{else}}unknown clients{{end}}
     Traces received: {{ $ts.Stats.TracesReceived }} ({{ $ts.Stats.TracesBytes }} bytes)
     Spans received: {{ $ts.Stats.SpansReceived }}
+    {{if gt $ts.Stats.ObfuscationMatched 0}}Obfuscation rules: {{ $ts.Stats.ObfuscationMatched }} values matched, {{ $ts.Stats.ObfuscationApplied }} obfuscated{{end}}
     {{ with $ts.WarnString }}
     WARNING: {{ . }}
     {{end}}
@@ -144,6 +144,8 @@ func (ts *TagStats) publish() {
 	spansFiltered := atomic.LoadInt64(&ts.SpansFiltered)
 	eventsExtracted := atomic.LoadInt64(&ts.EventsExtracted)
 	eventsSampled := atomic.LoadInt64(&ts.EventsSampled)
+	obfuscationMatched := atomic.LoadInt64(&ts.ObfuscationMatched)
+	obfuscationApplied := atomic.LoadInt64(&ts.ObfuscationApplied)
 	requestsMade := atomic.LoadInt64(&ts.PayloadAccepted)
 	requestsRejected := atomic.LoadInt64(&ts.PayloadRefused)
 
@@ -164,6 +166,8 @@ func (ts *TagStats) publish() {
 	metrics.Count("datadog.trace_agent.receiver.spans_filtered", spansFiltered, tags, 1)
 	metrics.Count("datadog.trace_agent.receiver.events_extracted", eventsExtracted, tags, 1)
 	metrics.Count("datadog.trace_agent.receiver.events_sampled", eventsSampled, tags, 1)
+	metrics.Count("datadog.trace_agent.obfuscation.rules.matched", obfuscationMatched, tags, 1)
+	metrics.Count("datadog.trace_agent.obfuscation.rules.obfuscated", obfuscationApplied, tags, 1)
 	metrics.Count("datadog.trace_agent.receiver.payload_accepted", requestsMade, tags, 1)
 	metrics.Count("datadog.trace_agent.receiver.payload_refused", requestsRejected, tags, 1)
 
@@ -317,6 +321,11 @@ type Stats struct {
 	EventsExtracted int64
 	// EventsSampled is the total number of APM events sampled.
 	EventsSampled int64
+	// ObfuscationMatched is the number of span values (tags, metrics and resources) matched by
+	// the user-defined obfuscation rules.
+	ObfuscationMatched int64
+	// ObfuscationApplied is the number of span values changed by the user-defined obfuscation rules.
+	ObfuscationApplied int64
 	// PayloadAccepted counts the number of payloads that have been accepted by the HTTP handler.
 	PayloadAccepted int64
 	// PayloadRefused counts the number of payloads that have been rejected by the rate limiter.
@@ -356,6 +365,8 @@ func (s *Stats) update(recent *Stats) {
 	atomic.AddInt64(&s.SpansFiltered, atomic.LoadInt64(&recent.SpansFiltered))
 	atomic.AddInt64(&s.EventsExtracted, atomic.LoadInt64(&recent.EventsExtracted))
 	atomic.AddInt64(&s.EventsSampled, atomic.LoadInt64(&recent.EventsSampled))
+	atomic.AddInt64(&s.ObfuscationMatched, atomic.LoadInt64(&recent.ObfuscationMatched))
+	atomic.AddInt64(&s.ObfuscationApplied, atomic.LoadInt64(&recent.ObfuscationApplied))
 	atomic.AddInt64(&s.PayloadAccepted, atomic.LoadInt64(&recent.PayloadAccepted))
 	atomic.AddInt64(&s.PayloadRefused, atomic.LoadInt64(&recent.PayloadRefused))
 }
@@ -394,6 +405,8 @@ func (s *Stats) reset() {
 	atomic.StoreInt64(&s.SpansFiltered, 0)
 	atomic.StoreInt64(&s.EventsExtracted, 0)
 	atomic.StoreInt64(&s.EventsSampled, 0)
+	atomic.StoreInt64(&s.ObfuscationMatched, 0)
+	atomic.StoreInt64(&s.ObfuscationApplied, 0)
 	atomic.StoreInt64(&s.PayloadAccepted, 0)
 	atomic.StoreInt64(&s.PayloadRefused, 0)
 }
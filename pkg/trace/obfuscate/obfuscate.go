@@ -31,6 +31,8 @@ type Obfuscator struct {
 	sqlLiteralEscapes int32
 	// queryCache keeps a cache of already obfuscated queries.
 	queryCache *measuredCache
+	// rules holds the user-defined obfuscation rules.
+	rules []*obfuscationRule
 }
 
 // SetSQLLiteralEscapes sets whether or not escape characters should be treated literally by the SQL obfuscator.
@@ -55,6 +57,7 @@ func NewObfuscator(cfg *config.ObfuscationConfig) *Obfuscator {
 	o := Obfuscator{
 		opts:       cfg,
 		queryCache: newMeasuredCache(),
+		rules:      newObfuscationRules(cfg.Rules),
 	}
 	if cfg.ES.Enabled {
 		o.es = newJSONObfuscator(&cfg.ES, &o)
@@ -112,6 +115,7 @@ func (o *Obfuscator) ObfuscateStatsGroup(b *pb.ClientGroupedStats) {
 	case "redis":
 		b.Resource = o.QuantizeRedisString(b.Resource)
 	}
+	o.obfuscateStatsGroupWithRules(b)
 }
 
 // compactWhitespaces compacts all whitespaces in t.
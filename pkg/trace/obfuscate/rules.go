@@ -0,0 +1,154 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package obfuscate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
+)
+
+// hashLen is the number of bytes of the SHA-256 hash kept by the "hash" action.
+const hashLen = 16
+
+// Keys of the tags and metrics used by the agent and the backend, which the rules never target.
+const (
+	reservedKeyPrefix = "_"
+	envKey            = "env"
+)
+
+// obfuscationRule applies a user-defined obfuscation rule.
+type obfuscationRule struct {
+	*config.ObfuscationRule
+	keepKeys map[string]bool // keys kept by the "keep_json_keys" action
+}
+
+func newObfuscationRules(rules []*config.ObfuscationRule) []*obfuscationRule {
+	out := make([]*obfuscationRule, 0, len(rules))
+	for _, r := range rules {
+		keepKeys := make(map[string]bool, len(r.KeepKeys))
+		for _, k := range r.KeepKeys {
+			keepKeys[k] = true
+		}
+		out = append(out, &obfuscationRule{ObfuscationRule: r, keepKeys: keepKeys})
+	}
+	return out
+}
+
+// ObfuscateWithRules applies the user-defined obfuscation rules to the span. It returns the
+// number of values matched by the rules and the number of values which were changed.
+func (o *Obfuscator) ObfuscateWithRules(span *pb.Span) (matched, obfuscated int64) {
+	for _, r := range o.rules {
+		if r.ServiceRe != nil && !r.ServiceRe.MatchString(span.Service) {
+			continue
+		}
+		if r.Resource {
+			matched++
+			if v := r.apply(span.Resource); v != span.Resource {
+				span.Resource = v
+				obfuscated++
+			}
+		}
+		for k, v := range span.Meta {
+			if isReservedKey(k) || !matchAny(r.TagsRe, k) {
+				continue
+			}
+			matched++
+			if nv := r.apply(v); nv != v {
+				span.Meta[k] = nv
+				obfuscated++
+			}
+		}
+		for k := range span.Metrics {
+			if isReservedKey(k) || !matchAny(r.MetricsRe, k) {
+				continue
+			}
+			// metrics only support the "redact" action
+			matched++
+			obfuscated++
+			delete(span.Metrics, k)
+		}
+	}
+	return matched, obfuscated
+}
+
+// obfuscateStatsGroupWithRules applies the rules targeting the resource to the stats bucket group.
+func (o *Obfuscator) obfuscateStatsGroupWithRules(b *pb.ClientGroupedStats) {
+	for _, r := range o.rules {
+		if !r.Resource || (r.ServiceRe != nil && !r.ServiceRe.MatchString(b.Service)) {
+			continue
+		}
+		b.Resource = r.apply(b.Resource)
+	}
+}
+
+// apply returns the value obfuscated with the action of the rule.
+func (r *obfuscationRule) apply(v string) string {
+	switch r.Action {
+	case config.ObfuscationActionRedact:
+		return "?"
+	case config.ObfuscationActionHash:
+		sum := sha256.Sum256([]byte(r.Salt + v))
+		return hex.EncodeToString(sum[:hashLen])
+	case config.ObfuscationActionTruncate:
+		return traceutil.TruncateUTF8(v, r.MaxLength)
+	case config.ObfuscationActionMaskDigits:
+		return maskDigits(v, r.KeepDigits)
+	case config.ObfuscationActionKeepJSONKeys:
+		// the JSON obfuscator is stateful, a new one is used for each value
+		obf := &jsonObfuscator{closures: []bool{}, keepKeys: r.keepKeys, scan: &scanner{}}
+		out, err := obf.obfuscate([]byte(v))
+		if err != nil {
+			// the value is not valid JSON, it can't be partially kept
+			return "?"
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// maskDigits replaces the digits of s with '*', except the keep last ones,
+// example: maskDigits("4111-1111-1111-1234", 4) --> "****-****-****-1234"
+func maskDigits(s string, keep int) string {
+	n := 0
+	for i := 0; i < len(s); i++ {
+		if isDigit(rune(s[i])) {
+			n++
+		}
+	}
+	if n <= keep {
+		return s
+	}
+	b := []byte(s)
+	for i := 0; i < len(b) && n > keep; i++ {
+		if isDigit(rune(b[i])) {
+			b[i] = '*'
+			n--
+		}
+	}
+	return string(b)
+}
+
+// isReservedKey returns true if the tag or metric key is used by the agent or the backend,
+// like the sampling priority and rates, the top-level and measured flags, the origin or the env.
+func isReservedKey(k string) bool {
+	return strings.HasPrefix(k, reservedKeyPrefix) || k == envKey
+}
+
+func matchAny(patterns []*regexp.Regexp, s string) bool {
+	for _, re := range patterns {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
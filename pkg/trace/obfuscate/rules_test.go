@@ -0,0 +1,184 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package obfuscate
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newRulesObfuscator(rules ...*config.ObfuscationRule) *Obfuscator {
+	return NewObfuscator(&config.ObfuscationConfig{Rules: rules})
+}
+
+func TestObfuscateWithRules(t *testing.T) {
+	assert := assert.New(t)
+	o := newRulesObfuscator(
+		&config.ObfuscationRule{
+			Action:    config.ObfuscationActionRedact,
+			TagsRe:    []*regexp.Regexp{regexp.MustCompile(`^user\..*$`)},
+			MetricsRe: []*regexp.Regexp{regexp.MustCompile(`^account_balance$`)},
+		},
+		&config.ObfuscationRule{
+			ServiceRe: regexp.MustCompile(`^billing.*$`),
+			Action:    config.ObfuscationActionMaskDigits,
+			Resource:  true,
+			TagsRe:    []*regexp.Regexp{regexp.MustCompile(`^card$`)},
+		},
+	)
+	defer o.Stop()
+
+	span := &pb.Span{
+		Service:  "billing-api",
+		Resource: "GET /invoices/1234",
+		Meta: map[string]string{
+			"user.email": "jane@example.com",
+			"user.id":    "?",
+			"card":       "4111-1111",
+			"http.url":   "/invoices/1234",
+		},
+		Metrics: map[string]float64{"account_balance": 42, "_sampling_priority_v1": 1},
+	}
+	matched, obfuscated := o.ObfuscateWithRules(span)
+	assert.EqualValues(5, matched)
+	assert.EqualValues(4, obfuscated)
+	assert.Equal("GET /invoices/****", span.Resource)
+	assert.Equal(map[string]string{
+		"user.email": "?",
+		"user.id":    "?",
+		"card":       "****-****",
+		"http.url":   "/invoices/1234",
+	}, span.Meta)
+	assert.Equal(map[string]float64{"_sampling_priority_v1": 1}, span.Metrics)
+
+	// the second rule is scoped to the billing services
+	span = &pb.Span{Service: "web", Resource: "GET /invoices/1234", Meta: map[string]string{"card": "4111"}}
+	matched, obfuscated = o.ObfuscateWithRules(span)
+	assert.EqualValues(0, matched)
+	assert.EqualValues(0, obfuscated)
+	assert.Equal("GET /invoices/1234", span.Resource)
+	assert.Equal("4111", span.Meta["card"])
+}
+
+func TestObfuscateWithRulesSkipsReservedKeys(t *testing.T) {
+	assert := assert.New(t)
+	o := newRulesObfuscator(&config.ObfuscationRule{
+		Action:    config.ObfuscationActionRedact,
+		TagsRe:    []*regexp.Regexp{regexp.MustCompile(`^.*$`)},
+		MetricsRe: []*regexp.Regexp{regexp.MustCompile(`^.*$`)},
+	})
+	defer o.Stop()
+
+	span := &pb.Span{
+		Meta: map[string]string{
+			"env":        "prod",
+			"_dd.origin": "synthetics",
+			"_dd.p.dm":   "-1",
+			"user.email": "jane@example.com",
+		},
+		Metrics: map[string]float64{
+			"_sampling_priority_v1":      2,
+			"_sampling_priority_rate_v1": 0.5,
+			"_sample_rate":               0.5,
+			"_dd1.sr.eausr":              1,
+			"_top_level":                 1,
+			"_dd.measured":               1,
+			"account_balance":            42,
+		},
+	}
+	matched, obfuscated := o.ObfuscateWithRules(span)
+	assert.EqualValues(2, matched)
+	assert.EqualValues(2, obfuscated)
+	assert.Equal(map[string]string{
+		"env":        "prod",
+		"_dd.origin": "synthetics",
+		"_dd.p.dm":   "-1",
+		"user.email": "?",
+	}, span.Meta)
+	assert.Equal(map[string]float64{
+		"_sampling_priority_v1":      2,
+		"_sampling_priority_rate_v1": 0.5,
+		"_sample_rate":               0.5,
+		"_dd1.sr.eausr":              1,
+		"_top_level":                 1,
+		"_dd.measured":               1,
+	}, span.Metrics)
+}
+
+func TestObfuscationRuleActions(t *testing.T) {
+	for _, tt := range []struct {
+		rule     config.ObfuscationRule
+		in, want string
+	}{
+		{
+			rule: config.ObfuscationRule{Action: config.ObfuscationActionRedact},
+			in:   "secret",
+			want: "?",
+		},
+		{
+			rule: config.ObfuscationRule{Action: config.ObfuscationActionHash, Salt: "pepper"},
+			in:   "jane@example.com",
+			want: "2daebb07b6fe2686ef59cd504c8b776a",
+		},
+		{
+			rule: config.ObfuscationRule{Action: config.ObfuscationActionTruncate, MaxLength: 5},
+			in:   "SELECT * FROM users",
+			want: "SELEC",
+		},
+		{
+			rule: config.ObfuscationRule{Action: config.ObfuscationActionTruncate, MaxLength: 50},
+			in:   "short",
+			want: "short",
+		},
+		{
+			rule: config.ObfuscationRule{Action: config.ObfuscationActionMaskDigits, KeepDigits: 4},
+			in:   "4111 1111 1111 1234",
+			want: "**** **** **** 1234",
+		},
+		{
+			rule: config.ObfuscationRule{Action: config.ObfuscationActionMaskDigits, KeepDigits: 4},
+			in:   "12",
+			want: "12",
+		},
+		{
+			rule: config.ObfuscationRule{Action: config.ObfuscationActionKeepJSONKeys, KeepKeys: []string{"id"}},
+			in:   `{"id": 12, "user": {"name": "jane", "id": 3}}`,
+			want: `{"id":12,"user":{"name":"?","id":3}}`,
+		},
+		{
+			rule: config.ObfuscationRule{Action: config.ObfuscationActionKeepJSONKeys, KeepKeys: []string{"id"}},
+			in:   `not json`,
+			want: "?",
+		},
+	} {
+		t.Run(tt.rule.Action, func(t *testing.T) {
+			r := newObfuscationRules([]*config.ObfuscationRule{&tt.rule})[0]
+			assert.Equal(t, tt.want, r.apply(tt.in))
+		})
+	}
+}
+
+func TestObfuscateStatsGroupWithRules(t *testing.T) {
+	o := newRulesObfuscator(&config.ObfuscationRule{
+		ServiceRe: regexp.MustCompile(`^billing$`),
+		Action:    config.ObfuscationActionRedact,
+		Resource:  true,
+	})
+	defer o.Stop()
+
+	b := &pb.ClientGroupedStats{Service: "billing", Resource: "GET /invoices/1234"}
+	o.ObfuscateStatsGroup(b)
+	assert.Equal(t, "?", b.Resource)
+
+	b = &pb.ClientGroupedStats{Service: "web", Resource: "GET /invoices/1234"}
+	o.ObfuscateStatsGroup(b)
+	assert.Equal(t, "GET /invoices/1234", b.Resource)
+}
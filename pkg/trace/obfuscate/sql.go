@@ -22,6 +22,12 @@ import (
 const sqlQueryTag = "sql.query"
 const nonParsableResource = "Non-parsable SQL query"
 
+const (
+	sqlTablesTag   = "sql.tables"
+	sqlCommandsTag = "sql.commands"
+	sqlCommentsTag = "sql.comments"
+)
+
 // tokenFilter is a generic interface that a sqlObfuscator expects. It defines
 // the Filter() function used to filter or replace given tokens.
 // A filter can be stateful and keep an internal state to apply the filter later;
@@ -175,26 +181,42 @@ func (f *groupingFilter) Reset() {
 // some elements such as comments and aliases and obfuscation attempts to hide sensitive information
 // in strings and numbers by redacting them.
 func (o *Obfuscator) ObfuscateSQLString(in string) (*ObfuscatedQuery, error) {
-	if v, ok := o.queryCache.Get(in); ok {
+	return o.ObfuscateSQLStringForDialect(in, SQLDialectGeneric)
+}
+
+// ObfuscateSQLStringForDialect quantizes and obfuscates the given input SQL query string, written
+// in the given SQL dialect.
+func (o *Obfuscator) ObfuscateSQLStringForDialect(in string, dialect SQLDialect) (*ObfuscatedQuery, error) {
+	key := in
+	if dialect != SQLDialectGeneric {
+		// the same query may be tokenized differently depending on the dialect
+		key = dialect.String() + "\x00" + in
+	}
+	if v, ok := o.queryCache.Get(key); ok {
 		return v.(*ObfuscatedQuery), nil
 	}
-	oq, err := o.obfuscateSQLString(in)
+	oq, err := o.obfuscateSQLStringForDialect(in, dialect)
 	if err != nil {
 		return oq, err
 	}
-	o.queryCache.Set(in, oq, oq.Cost())
+	o.queryCache.Set(key, oq, oq.Cost())
 	return oq, nil
 }
 
 func (o *Obfuscator) obfuscateSQLString(in string) (*ObfuscatedQuery, error) {
+	return o.obfuscateSQLStringForDialect(in, SQLDialectGeneric)
+}
+
+func (o *Obfuscator) obfuscateSQLStringForDialect(in string, dialect SQLDialect) (*ObfuscatedQuery, error) {
 	lesc := o.SQLLiteralEscapes()
-	tok := NewSQLTokenizer(in, lesc)
-	out, err := attemptObfuscation(tok)
+	collectMetadata := o.opts.SQL.CollectMetadata
+	tok := newSQLTokenizer(in, lesc, dialect)
+	out, err := attemptObfuscation(tok, collectMetadata)
 	if err != nil && tok.SeenEscape() {
 		// If the tokenizer failed, but saw an escape character in the process,
 		// try again treating escapes differently
-		tok = NewSQLTokenizer(in, !lesc)
-		if out, err2 := attemptObfuscation(tok); err2 == nil {
+		tok = newSQLTokenizer(in, !lesc, dialect)
+		if out, err2 := attemptObfuscation(tok, collectMetadata); err2 == nil {
 			// If the second attempt succeeded, change the default behavior so that
 			// on the next run we get it right in the first run.
 			o.SetSQLLiteralEscapes(!lesc)
@@ -208,6 +230,8 @@ func (o *Obfuscator) obfuscateSQLString(in string) (*ObfuscatedQuery, error) {
 // token in a query.
 type tableFinderFilter struct {
 	storeTableNames bool
+	// dialect is the SQL dialect of the query, which defines how table names can start.
+	dialect SQLDialect
 	// seen keeps track of unique table names encountered by the filter.
 	seen map[string]struct{}
 	// csv specifies a comma-separated list of tables
@@ -221,7 +245,7 @@ func (f *tableFinderFilter) Filter(token, lastToken TokenKind, buffer []byte) (T
 		// SELECT ... FROM [tableName]
 		// DELETE FROM [tableName]
 		// ... JOIN [tableName]
-		if r, _ := utf8.DecodeRune(buffer); !unicode.IsLetter(r) {
+		if r, _ := utf8.DecodeRune(buffer); !f.isTableNameStart(r) {
 			// first character in buffer is not a letter; we might have a nested
 			// query like SELECT * FROM (SELECT ...)
 			break
@@ -238,6 +262,19 @@ func (f *tableFinderFilter) Filter(token, lastToken TokenKind, buffer []byte) (T
 	return token, buffer, nil
 }
 
+// isTableNameStart returns true if a table name can start with r, bracketed and temporary
+// tables being specific to SQL Server and backquoted ones to MySQL.
+func (f *tableFinderFilter) isTableNameStart(r rune) bool {
+	switch f.dialect {
+	case SQLDialectMSSQL:
+		return unicode.IsLetter(r) || r == '[' || r == '#'
+	case SQLDialectMySQL:
+		return unicode.IsLetter(r) || r == '`'
+	default:
+		return unicode.IsLetter(r)
+	}
+}
+
 // storeName marks the given table name as seen in the internal storage.
 func (f *tableFinderFilter) storeName(name string) {
 	if _, ok := f.seen[name]; ok {
@@ -264,24 +301,179 @@ func (f *tableFinderFilter) Reset() {
 	f.csv.Reset()
 }
 
+// sqlCommands holds the keywords reported as commands by the metadataFinderFilter.
+var sqlCommands = map[string]bool{
+	"SELECT":   true,
+	"INSERT":   true,
+	"UPDATE":   true,
+	"DELETE":   true,
+	"MERGE":    true,
+	"UPSERT":   true,
+	"REPLACE":  true,
+	"CREATE":   true,
+	"ALTER":    true,
+	"DROP":     true,
+	"TRUNCATE": true,
+	"GRANT":    true,
+	"REVOKE":   true,
+	"CALL":     true,
+	"EXEC":     true,
+	"EXECUTE":  true,
+	"BEGIN":    true,
+	"COMMIT":   true,
+	"ROLLBACK": true,
+}
+
+// metadataFinderFilter is a filter which collects the commands of a query and the keys of its
+// sqlcommenter comments, the other comments and the values are not collected as they may contain
+// sensitive data. It runs before any other filter, on the tokens as they are found by the tokenizer.
+type metadataFinderFilter struct {
+	// midStatement is true when the last token did not start a new statement.
+	midStatement bool
+	// lastParen is true when the last token was an opening parenthesis.
+	lastParen bool
+	// seen keeps track of unique commands encountered by the filter.
+	seen map[string]struct{}
+	// csv specifies a comma-separated list of commands
+	csv strings.Builder
+	// seenCommentKeys keeps track of unique sqlcommenter keys encountered by the filter.
+	seenCommentKeys map[string]struct{}
+	// commentKeysCSV specifies a comma-separated list of sqlcommenter keys
+	commentKeysCSV strings.Builder
+}
+
+// Filter implements tokenFilter.
+func (f *metadataFinderFilter) Filter(token, lastToken TokenKind, buffer []byte) (TokenKind, []byte, error) {
+	switch token {
+	case Comment:
+		for _, key := range sqlCommenterKeys(string(bytes.TrimSpace(buffer))) {
+			f.storeCommentKey(key)
+		}
+		return token, buffer, nil
+	case ID, Update, Insert:
+		if !f.midStatement || f.lastParen {
+			// commands start statements, or sub-queries such as "(SELECT ...)"
+			var space [16]byte
+			if cmd := toUpper(buffer, space[:0]); sqlCommands[string(cmd)] && (!f.lastParen || string(cmd) == "SELECT") {
+				f.storeCommand(string(cmd))
+			}
+		}
+	}
+	f.midStatement = token != ';'
+	f.lastParen = token == '('
+	return token, buffer, nil
+}
+
+// storeCommand marks the given command as seen in the internal storage.
+func (f *metadataFinderFilter) storeCommand(cmd string) {
+	if _, ok := f.seen[cmd]; ok {
+		return
+	}
+	if f.seen == nil {
+		f.seen = make(map[string]struct{}, 1)
+	}
+	f.seen[cmd] = struct{}{}
+	if f.csv.Len() > 0 {
+		f.csv.WriteByte(',')
+	}
+	f.csv.WriteString(cmd)
+}
+
+// storeCommentKey marks the given sqlcommenter key as seen in the internal storage.
+func (f *metadataFinderFilter) storeCommentKey(key string) {
+	if _, ok := f.seenCommentKeys[key]; ok {
+		return
+	}
+	if f.seenCommentKeys == nil {
+		f.seenCommentKeys = make(map[string]struct{}, 1)
+	}
+	f.seenCommentKeys[key] = struct{}{}
+	if f.commentKeysCSV.Len() > 0 {
+		f.commentKeysCSV.WriteByte(',')
+	}
+	f.commentKeysCSV.WriteString(key)
+}
+
+// CSV returns a comma-separated list of the commands seen by the filter.
+func (f *metadataFinderFilter) CSV() string { return f.csv.String() }
+
+// CommentKeysCSV returns a comma-separated list of the sqlcommenter keys seen by the filter.
+func (f *metadataFinderFilter) CommentKeysCSV() string { return f.commentKeysCSV.String() }
+
+// Reset implements tokenFilter.
+func (f *metadataFinderFilter) Reset() {
+	f.midStatement = false
+	f.lastParen = false
+	for k := range f.seen {
+		delete(f.seen, k)
+	}
+	f.csv.Reset()
+	for k := range f.seenCommentKeys {
+		delete(f.seenCommentKeys, k)
+	}
+	f.commentKeysCSV.Reset()
+}
+
+// sqlCommenterKeys returns the keys of a sqlcommenter comment, such as
+// /*controller='users',action='show'*/, or nil if the comment is not one.
+func sqlCommenterKeys(comment string) []string {
+	if !strings.HasPrefix(comment, "/*") || !strings.HasSuffix(comment, "*/") || len(comment) < 4 {
+		return nil
+	}
+	body := strings.TrimSpace(comment[2 : len(comment)-2])
+	if body == "" {
+		return nil
+	}
+	var keys []string
+	// the keys and values are URL encoded, they don't contain commas
+	for _, pair := range strings.Split(body, ",") {
+		i := strings.IndexByte(pair, '=')
+		if i <= 0 {
+			return nil
+		}
+		key, value := strings.TrimSpace(pair[:i]), strings.TrimSpace(pair[i+1:])
+		if !isSQLCommenterKey(key) || len(value) < 2 || value[0] != '\'' || value[len(value)-1] != '\'' {
+			return nil
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// isSQLCommenterKey returns true if s is a valid URL encoded sqlcommenter key.
+func isSQLCommenterKey(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !isDigit(r) && r != '_' && r != '-' && r != '.' && r != '%' {
+			return false
+		}
+	}
+	return true
+}
+
 // ObfuscatedQuery specifies information about an obfuscated SQL query.
 type ObfuscatedQuery struct {
-	Query     string // the obfuscated SQL query
-	TablesCSV string // comma-separated list of tables that the query addresses
+	Query          string // the obfuscated SQL query
+	TablesCSV      string // comma-separated list of tables that the query addresses
+	CommandsCSV    string // comma-separated list of commands found in the query, such as SELECT
+	CommentKeysCSV string // comma-separated list of the keys of the sqlcommenter comments of the query
 }
 
 // Cost returns the number of bytes needed to store all the fields
 // of this ObfuscatedQuery.
 func (oq *ObfuscatedQuery) Cost() int64 {
-	return int64(len(oq.Query) + len(oq.TablesCSV))
+	return int64(len(oq.Query) + len(oq.TablesCSV) + len(oq.CommandsCSV) + len(oq.CommentKeysCSV))
 }
 
 // attemptObfuscation attempts to obfuscate the SQL query loaded into the tokenizer, using the
-// given set of filters.
-func attemptObfuscation(tokenizer *SQLTokenizer) (*ObfuscatedQuery, error) {
+// given set of filters. If collectMetadata is true, the tables, commands and sqlcommenter keys of
+// the query are reported in the result.
+func attemptObfuscation(tokenizer *SQLTokenizer, collectMetadata bool) (*ObfuscatedQuery, error) {
 
 	var (
-		storeTableNames    = config.HasFeature("table_names")
+		storeTableNames    = collectMetadata || config.HasFeature("table_names")
 		quantizeTableNames = config.HasFeature("quantize_sql_tables")
 		out                = bytes.NewBuffer(make([]byte, 0, len(tokenizer.buf)))
 		err                error
@@ -289,7 +481,8 @@ func attemptObfuscation(tokenizer *SQLTokenizer) (*ObfuscatedQuery, error) {
 		discard            discardFilter
 		replace            = replaceFilter{quantizeTableNames: quantizeTableNames}
 		grouping           groupingFilter
-		tableFinder        = tableFinderFilter{storeTableNames: storeTableNames}
+		tableFinder        = tableFinderFilter{storeTableNames: storeTableNames, dialect: tokenizer.dialect}
+		metadataFinder     metadataFinderFilter
 	)
 	// call Scan() function until tokens are available or if a LEX_ERROR is raised. After
 	// retrieving a token, send it to the tokenFilter chains so that the token is discarded
@@ -303,6 +496,11 @@ func attemptObfuscation(tokenizer *SQLTokenizer) (*ObfuscatedQuery, error) {
 			return nil, fmt.Errorf("%v", tokenizer.Err())
 		}
 
+		if collectMetadata {
+			if token, buff, err = metadataFinder.Filter(token, lastToken, buff); err != nil {
+				return nil, err
+			}
+		}
 		if token, buff, err = discard.Filter(token, lastToken, buff); err != nil {
 			return nil, err
 		}
@@ -340,16 +538,37 @@ func attemptObfuscation(tokenizer *SQLTokenizer) (*ObfuscatedQuery, error) {
 		return nil, errors.New("result is empty")
 	}
 	return &ObfuscatedQuery{
-		Query:     out.String(),
-		TablesCSV: tableFinder.CSV(),
+		Query:          out.String(),
+		TablesCSV:      tableFinder.CSV(),
+		CommandsCSV:    metadataFinder.CSV(),
+		CommentKeysCSV: metadataFinder.CommentKeysCSV(),
 	}, nil
 }
 
+// sqlDialect returns the SQL dialect of the query of the given span, based on its "db.type"
+// or "db.system" tag.
+func sqlDialect(span *pb.Span) SQLDialect {
+	dbType := span.Meta["db.type"]
+	if dbType == "" {
+		dbType = span.Meta["db.system"]
+	}
+	switch strings.ToLower(dbType) {
+	case "postgresql", "postgres":
+		return SQLDialectPostgreSQL
+	case "mysql", "mariadb":
+		return SQLDialectMySQL
+	case "mssql", "sqlserver", "sql server":
+		return SQLDialectMSSQL
+	default:
+		return SQLDialectGeneric
+	}
+}
+
 func (o *Obfuscator) obfuscateSQL(span *pb.Span) {
 	if span.Resource == "" {
 		return
 	}
-	oq, err := o.ObfuscateSQLString(span.Resource)
+	oq, err := o.ObfuscateSQLStringForDialect(span.Resource, sqlDialect(span))
 	if err != nil {
 		// we have an error, discard the SQL to avoid polluting user resources.
 		log.Debugf("Error parsing SQL query: %v. Resource: %q", err, span.Resource)
@@ -366,7 +585,13 @@ func (o *Obfuscator) obfuscateSQL(span *pb.Span) {
 	span.Resource = oq.Query
 
 	if len(oq.TablesCSV) > 0 {
-		traceutil.SetMeta(span, "sql.tables", oq.TablesCSV)
+		traceutil.SetMeta(span, sqlTablesTag, oq.TablesCSV)
+	}
+	if len(oq.CommandsCSV) > 0 {
+		traceutil.SetMeta(span, sqlCommandsTag, oq.CommandsCSV)
+	}
+	if len(oq.CommentKeysCSV) > 0 {
+		traceutil.SetMeta(span, sqlCommentsTag, oq.CommentKeysCSV)
 	}
 	if span.Meta != nil && span.Meta[sqlQueryTag] != "" {
 		// "sql.query" tag already set by user, do not change it.
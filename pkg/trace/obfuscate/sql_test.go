@@ -14,6 +14,7 @@ import (
 	"sync/atomic"
 	"testing"
 
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
 	"github.com/DataDog/datadog-agent/pkg/trace/pb"
 	"github.com/stretchr/testify/assert"
 )
@@ -173,6 +174,168 @@ func TestSQLTableNames(t *testing.T) {
 	})
 }
 
+func TestSQLDialects(t *testing.T) {
+	for _, tt := range []struct {
+		dialect SQLDialect
+		in, out string
+	}{
+		{
+			SQLDialectPostgreSQL,
+			"CREATE FUNCTION add(a integer) RETURNS integer AS $$ SELECT a + 42 $$ LANGUAGE SQL",
+			"CREATE FUNCTION add ( a integer ) RETURNS integer LANGUAGE SQL",
+		},
+		{
+			SQLDialectPostgreSQL,
+			"DO $body$ BEGIN RAISE NOTICE 'it''s $$ here'; END $body$",
+			"DO ?",
+		},
+		{
+			SQLDialectPostgreSQL,
+			"SELECT * FROM users WHERE id = $1 AND name = $2",
+			"SELECT * FROM users WHERE id = ? AND name = ?",
+		},
+		{
+			SQLDialectPostgreSQL,
+			"SELECT data->'user'->>'email' FROM events WHERE flags # 4 = 0 AND data#>'{a,b}' IS NOT NULL",
+			"SELECT data -> ? ->> ? FROM events WHERE flags # ? = ? AND data #> ? IS NOT ?",
+		},
+		{
+			SQLDialectMySQL,
+			"SELECT * FROM `my table` WHERE `id` = 1 # lookup by id\nAND name = \"jane\"",
+			"SELECT * FROM `my table` WHERE id = ? AND name = ?",
+		},
+		{
+			SQLDialectMySQL,
+			"SELECT `a``b`, `c` FROM `db`.`t$1`",
+			"SELECT `a``b`, c FROM db . `t$1`",
+		},
+		{
+			SQLDialectMSSQL,
+			"SELECT [u].[Full Name] AS [Name] FROM [dbo].[Users] AS [u] WHERE [u].[Id] = 5",
+			"SELECT [u].[Full Name] FROM [dbo].[Users] WHERE [u].[Id] = ?",
+		},
+		{
+			SQLDialectMSSQL,
+			"INSERT INTO #temp_users ([a]]b]) VALUES (N'jane')",
+			"INSERT INTO #temp_users ( [a]]b] ) VALUES ( ? )",
+		},
+	} {
+		t.Run(tt.dialect.String(), func(t *testing.T) {
+			oq, err := NewObfuscator(nil).ObfuscateSQLStringForDialect(tt.in, tt.dialect)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.out, oq.Query)
+		})
+	}
+
+	t.Run("errors", func(t *testing.T) {
+		for _, tt := range []struct {
+			dialect SQLDialect
+			in      string
+		}{
+			{SQLDialectPostgreSQL, "SELECT $$ unterminated"},
+			{SQLDialectMySQL, "SELECT `unterminated"},
+			{SQLDialectMSSQL, "SELECT [unterminated"},
+		} {
+			_, err := NewObfuscator(nil).ObfuscateSQLStringForDialect(tt.in, tt.dialect)
+			assert.Error(t, err, tt.in)
+		}
+	})
+
+	t.Run("db.type", func(t *testing.T) {
+		span := &pb.Span{
+			Resource: "SELECT $$ secret $$",
+			Type:     "sql",
+			Meta:     map[string]string{"db.type": "postgresql"},
+		}
+		NewObfuscator(nil).Obfuscate(span)
+		assert.Equal(t, "SELECT ?", span.Resource)
+
+		// the generic tokenizer can't parse the dollar-quoted string
+		span = &pb.Span{Resource: "SELECT $$ secret $$", Type: "sql"}
+		NewObfuscator(nil).Obfuscate(span)
+		assert.Equal(t, nonParsableResource, span.Resource)
+	})
+}
+
+func TestSQLMetadata(t *testing.T) {
+	o := NewObfuscator(&config.ObfuscationConfig{SQL: config.SQLObfuscationConfig{CollectMetadata: true}})
+	defer o.Stop()
+
+	for _, tt := range []struct {
+		dbType   string
+		query    string
+		tables   string
+		commands string
+		comments string
+	}{
+		{
+			query:    "SELECT * FROM users WHERE id = 42",
+			tables:   "users",
+			commands: "SELECT",
+		},
+		{
+			query:    "/* controller='users' */ UPDATE users SET name = 'jane' WHERE id IN (SELECT id FROM admins) -- by id",
+			tables:   "users,admins",
+			commands: "UPDATE,SELECT",
+			comments: "controller",
+		},
+		{
+			query:    "BEGIN; INSERT INTO orders (id) VALUES (1); DELETE FROM carts WHERE id = 2; COMMIT",
+			tables:   "orders,carts",
+			commands: "BEGIN,INSERT,DELETE,COMMIT",
+		},
+		{
+			query:    "SELECT REPLACE(name, 'a', 'b') FROM t WHERE (REPLACE(name, 'a', 'b') = 'c')",
+			tables:   "t",
+			commands: "SELECT",
+		},
+		{
+			dbType:   "mysql",
+			query:    "SELECT * FROM `user accounts` # all users",
+			tables:   "`user accounts`",
+			commands: "SELECT",
+		},
+		{
+			// only the keys of the sqlcommenter comments are reported
+			query:    "SELECT * FROM users /*controller='users',action='show%20user',db.driver='pg'*/ /* user jane@example.com */",
+			tables:   "users",
+			commands: "SELECT",
+			comments: "controller,action,db.driver",
+		},
+		{
+			// brackets only quote table names in SQL Server
+			query:    "SELECT * FROM [dbo].[Order Details]",
+			commands: "SELECT",
+		},
+		{
+			dbType:   "sqlserver",
+			query:    "EXEC sp_who; SELECT * FROM [dbo].[Users]",
+			tables:   "[dbo].[Users]",
+			commands: "EXEC,SELECT",
+		},
+	} {
+		t.Run("", func(t *testing.T) {
+			span := &pb.Span{
+				Resource: tt.query,
+				Type:     "sql",
+				Meta:     map[string]string{"db.type": tt.dbType},
+			}
+			o.Obfuscate(span)
+			assert.Equal(t, tt.tables, span.Meta["sql.tables"])
+			assert.Equal(t, tt.commands, span.Meta["sql.commands"])
+			assert.Equal(t, tt.comments, span.Meta["sql.comments"])
+		})
+	}
+
+	t.Run("off", func(t *testing.T) {
+		span := &pb.Span{Resource: "SELECT * FROM users -- comment", Type: "sql"}
+		NewObfuscator(nil).Obfuscate(span)
+		assert.NotContains(t, span.Meta, "sql.tables")
+		assert.NotContains(t, span.Meta, "sql.commands")
+		assert.NotContains(t, span.Meta, "sql.comments")
+	})
+}
+
 func TestSQLQuantizeTableNames(t *testing.T) {
 	t.Run("on", func(t *testing.T) {
 		os.Setenv("DD_APM_FEATURES", "quantize_sql_tables")
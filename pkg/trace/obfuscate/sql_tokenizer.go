@@ -78,6 +78,37 @@ const (
 
 const escapeCharacter = '\\'
 
+// SQLDialect specifies the SQL dialect of a query. The tokenizer handles the syntax specific to
+// the dialect, such as quoted identifiers, comments and string literals.
+type SQLDialect int
+
+const (
+	// SQLDialectGeneric tokenizes the syntax common to most SQL engines.
+	SQLDialectGeneric SQLDialect = iota
+	// SQLDialectPostgreSQL adds support for dollar-quoted strings, such as function bodies in
+	// $$...$$, and for the "#" operators.
+	SQLDialectPostgreSQL
+	// SQLDialectMySQL adds support for backtick-quoted identifiers containing any character
+	// and treats double-quoted strings as string literals.
+	SQLDialectMySQL
+	// SQLDialectMSSQL adds support for [bracketed] identifiers, #temporary tables and N'unicode' strings.
+	SQLDialectMSSQL
+)
+
+// String implements fmt.Stringer.
+func (d SQLDialect) String() string {
+	switch d {
+	case SQLDialectPostgreSQL:
+		return "postgresql"
+	case SQLDialectMySQL:
+		return "mysql"
+	case SQLDialectMSSQL:
+		return "mssql"
+	default:
+		return "generic"
+	}
+}
+
 // SQLTokenizer is the struct used to generate SQL
 // tokens for the parser.
 type SQLTokenizer struct {
@@ -91,14 +122,22 @@ type SQLTokenizer struct {
 
 	literalEscapes bool // indicates we should not treat backslashes as escape characters
 	seenEscape     bool // indicates whether this tokenizer has seen an escape character within a string
+
+	dialect SQLDialect // the SQL dialect of the query
 }
 
 // NewSQLTokenizer creates a new SQLTokenizer for the given SQL string. The literalEscapes argument specifies
 // whether escape characters should be treated literally or as such.
 func NewSQLTokenizer(sql string, literalEscapes bool) *SQLTokenizer {
+	return newSQLTokenizer(sql, literalEscapes, SQLDialectGeneric)
+}
+
+// newSQLTokenizer creates a new SQLTokenizer for the given SQL string written in the given dialect.
+func newSQLTokenizer(sql string, literalEscapes bool, dialect SQLDialect) *SQLTokenizer {
 	return &SQLTokenizer{
 		buf:            []byte(sql),
 		literalEscapes: literalEscapes,
+		dialect:        dialect,
 	}
 }
 
@@ -148,6 +187,14 @@ func (tkn *SQLTokenizer) Scan() (TokenKind, []byte) {
 	tkn.skipBlank()
 
 	switch ch := tkn.lastChar; {
+	case tkn.dialect == SQLDialectMSSQL && (ch == 'N' || ch == 'n') && tkn.peek() == '\'':
+		// N'unicode string'
+		tkn.advance()
+		tkn.advance()
+		return tkn.scanString('\'', String)
+	case tkn.dialect == SQLDialectMSSQL && (ch == '#' || ch == '['):
+		// #temporary_table or [bracketed identifier]
+		return tkn.scanIdentifier()
 	case isLeadingLetter(ch):
 		return tkn.scanIdentifier()
 	case isDigit(ch):
@@ -196,8 +243,16 @@ func (tkn *SQLTokenizer) Scan() (TokenKind, []byte) {
 				tkn.advance()
 				return tkn.scanCommentType1("--")
 			}
+			if tkn.dialect == SQLDialectPostgreSQL && tkn.lastChar == '>' {
+				// JSON operators -> and ->>
+				return tkn.scanOperator(ch)
+			}
 			return TokenKind(ch), tkn.bytes()
 		case '#':
+			if tkn.dialect == SQLDialectPostgreSQL {
+				// bitwise XOR and JSON operators #> and #>>
+				return tkn.scanOperator(ch)
+			}
 			tkn.advance()
 			return tkn.scanCommentType1("#")
 		case '<':
@@ -233,8 +288,15 @@ func (tkn *SQLTokenizer) Scan() (TokenKind, []byte) {
 		case '\'':
 			return tkn.scanString(ch, String)
 		case '"':
+			if tkn.dialect == SQLDialectMySQL {
+				// double quotes delimit strings, unless ANSI_QUOTES is enabled
+				return tkn.scanString(ch, String)
+			}
 			return tkn.scanString(ch, DoubleQuotedString)
 		case '`':
+			if tkn.dialect == SQLDialectMySQL {
+				return tkn.scanQuotedIdentifier('`')
+			}
 			return tkn.scanLiteralIdentifier('`')
 		case '%':
 			if tkn.lastChar == '(' {
@@ -247,6 +309,9 @@ func (tkn *SQLTokenizer) Scan() (TokenKind, []byte) {
 			// modulo operator (e.g. 'id % 8')
 			return TokenKind(ch), tkn.bytes()
 		case '$':
+			if tkn.dialect == SQLDialectPostgreSQL && !isDigit(tkn.lastChar) {
+				return tkn.scanDollarQuotedString()
+			}
 			return tkn.scanPreparedStatement('$')
 		case '{':
 			if tkn.pos == 1 || tkn.curlys > 0 {
@@ -321,8 +386,11 @@ func toUpper(src, dst []byte) []byte {
 }
 
 func (tkn *SQLTokenizer) scanIdentifier() (TokenKind, []byte) {
+	if tkn.dialect == SQLDialectMSSQL {
+		return tkn.scanMSSQLIdentifier()
+	}
 	tkn.advance()
-	for isLetter(tkn.lastChar) || isDigit(tkn.lastChar) || tkn.lastChar == '.' || tkn.lastChar == '*' {
+	for tkn.isIdentifierChar(tkn.lastChar) {
 		tkn.advance()
 	}
 
@@ -338,6 +406,125 @@ func (tkn *SQLTokenizer) scanIdentifier() (TokenKind, []byte) {
 	return ID, t
 }
 
+// isIdentifierChar reports whether ch may be part of an unquoted identifier in the dialect of the query.
+func (tkn *SQLTokenizer) isIdentifierChar(ch rune) bool {
+	switch tkn.dialect {
+	case SQLDialectPostgreSQL, SQLDialectMySQL:
+		// "#" is an operator in PostgreSQL and starts a comment in MySQL
+		return isLeadingLetter(ch) || isDigit(ch) || ch == '$' || ch == '.' || ch == '*'
+	default:
+		return isLetter(ch) || isDigit(ch) || ch == '.' || ch == '*'
+	}
+}
+
+// scanMSSQLIdentifier scans a T-SQL identifier, made of unquoted and [bracketed] parts
+// such as [dbo].[Order Details] or #temp_table.
+func (tkn *SQLTokenizer) scanMSSQLIdentifier() (TokenKind, []byte) {
+	for {
+		switch ch := tkn.lastChar; {
+		case ch == '[':
+			// brackets are escaped by doubling them: [a]]b]
+			for tkn.advance(); tkn.lastChar != ']' || tkn.peek() == ']'; tkn.advance() {
+				if tkn.lastChar == EndChar {
+					tkn.setErr("unexpected EOF in bracketed identifier")
+					return LexError, tkn.bytes()
+				}
+				if tkn.lastChar == ']' {
+					tkn.advance()
+				}
+			}
+			tkn.advance()
+		case isLetter(ch) || isDigit(ch) || ch == '$' || ch == '.' || ch == '*':
+			tkn.advance()
+		default:
+			t := tkn.bytes()
+			var space [256]byte
+			if keywordID, found := keywords[string(toUpper(t, space[:0]))]; found {
+				return keywordID, t
+			}
+			return ID, t
+		}
+	}
+}
+
+// scanQuotedIdentifier scans a MySQL identifier quoted with backticks, which may contain
+// any character. Quotes are escaped by doubling them. Like with scanLiteralIdentifier, the
+// quotes are removed unless they are needed to keep the query valid.
+func (tkn *SQLTokenizer) scanQuotedIdentifier(quote rune) (TokenKind, []byte) {
+	tkn.bytes() // throw away initial quote
+	plain := true
+	for ; tkn.lastChar != quote || tkn.peek() == byte(quote); tkn.advance() {
+		if tkn.lastChar == EndChar {
+			tkn.setErr(`quoted identifiers must end in "%c"`, quote)
+			return LexError, tkn.bytes()
+		}
+		if tkn.lastChar == quote {
+			tkn.advance()
+		}
+		plain = plain && skipNonLiteralIdentifier(tkn.lastChar)
+	}
+	t := tkn.bytes()
+	tkn.advance()
+	if len(t) == 0 {
+		tkn.setErr("empty quoted identifier")
+		return LexError, t
+	}
+	if plain {
+		return ID, t
+	}
+	q := runeBytes(quote)
+	return ID, append(append(append([]byte{}, q...), t...), q...)
+}
+
+// scanDollarQuotedString scans a PostgreSQL dollar-quoted string such as $$...$$ or
+// $tag$...$tag$, the opening "$" having been read.
+func (tkn *SQLTokenizer) scanDollarQuotedString() (TokenKind, []byte) {
+	for tkn.lastChar != '$' {
+		if !isLeadingLetter(tkn.lastChar) && !isDigit(tkn.lastChar) {
+			tkn.setErr(`invalid character in dollar quote tag: "%c" (%d)`, tkn.lastChar, tkn.lastChar)
+			return LexError, tkn.bytes()
+		}
+		tkn.advance()
+	}
+	tkn.advance()
+	if tkn.lastChar == EndChar {
+		tkn.setErr("unexpected EOF in dollar-quoted string")
+		return LexError, tkn.bytes()
+	}
+	delim := tkn.buf[:tkn.off-utf8.RuneLen(tkn.lastChar)] // the opening $tag$
+	for {
+		if tkn.lastChar == EndChar {
+			tkn.setErr("unexpected EOF in dollar-quoted string")
+			return LexError, tkn.bytes()
+		}
+		if tkn.lastChar == '$' && bytes.HasPrefix(tkn.buf[tkn.off-1:], delim) {
+			for i := 0; i < len(delim); i++ {
+				tkn.advance()
+			}
+			break
+		}
+		tkn.advance()
+	}
+	return String, tkn.bytes()
+}
+
+// scanOperator scans an operator made of the given character followed by ">" characters,
+// such as the PostgreSQL JSON operators "->>" and "#>".
+func (tkn *SQLTokenizer) scanOperator(ch rune) (TokenKind, []byte) {
+	for tkn.lastChar == '>' {
+		tkn.advance()
+	}
+	return TokenKind(ch), tkn.bytes()
+}
+
+// peek returns the byte following the last read rune, or 0 at the end of the query.
+func (tkn *SQLTokenizer) peek() byte {
+	if tkn.off < len(tkn.buf) {
+		return tkn.buf[tkn.off]
+	}
+	return 0
+}
+
 func (tkn *SQLTokenizer) scanLiteralIdentifier(quote rune) (TokenKind, []byte) {
 	tkn.bytes() // throw away initial quote
 	if !isLetter(tkn.lastChar) && !isDigit(tkn.lastChar) {
@@ -0,0 +1,154 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package sampler
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/metrics"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"golang.org/x/time/rate"
+)
+
+// rateEstimationPeriod is the period over which the rate applied by a TPS rule is estimated.
+const rateEstimationPeriod = 1 * time.Second
+
+// RulesSampler samples the traces matching the user-defined sampling rules. The rules are
+// evaluated in order on the root span, and the first matching one decides whether the trace
+// is kept, either with a fixed rate or within a TPS budget.
+type RulesSampler struct {
+	rules     []*samplingRule
+	tickStats *time.Ticker
+}
+
+// samplingRule is the runtime state of a user-defined sampling rule.
+type samplingRule struct {
+	// Variables access through the 'atomic' package must be 64bits aligned.
+	kept    int64
+	dropped int64
+
+	*config.SamplingRule
+	tag string
+
+	// limiter, estimatedRate and the window fields are only used by TPS rules.
+	limiter       *rate.Limiter
+	mu            sync.Mutex
+	windowStart   time.Time
+	windowSeen    float64
+	estimatedRate float64
+}
+
+// NewRulesSampler returns a RulesSampler evaluating the given compiled rules.
+func NewRulesSampler(rules []*config.SamplingRule) *RulesSampler {
+	s := &RulesSampler{
+		rules:     make([]*samplingRule, 0, len(rules)),
+		tickStats: time.NewTicker(10 * time.Second),
+	}
+	for i, r := range rules {
+		rule := &samplingRule{
+			SamplingRule:  r,
+			tag:           "rule:" + strconv.Itoa(i),
+			estimatedRate: 1,
+		}
+		if r.SampleRate == nil {
+			// allow a burst of one second of traces
+			rule.limiter = rate.NewLimiter(rate.Limit(r.MaxTPS), int(math.Ceil(r.MaxTPS)))
+		}
+		s.rules = append(s.rules, rule)
+	}
+	go func() {
+		for range s.tickStats.C {
+			s.report()
+		}
+	}()
+	return s
+}
+
+// Sample applies the first rule matching the root span of the trace. It returns whether a rule
+// matched and, if so, whether the trace should be kept. The rate of the rule is recorded on
+// the root span of the kept traces.
+func (s *RulesSampler) Sample(env string, root *pb.Span) (matched bool, sampled bool) {
+	return s.sample(time.Now(), env, root)
+}
+
+func (s *RulesSampler) sample(now time.Time, env string, root *pb.Span) (matched bool, sampled bool) {
+	for _, r := range s.rules {
+		if !r.matches(env, root) {
+			continue
+		}
+		var rate float64
+		if r.limiter != nil {
+			sampled, rate = r.allow(now)
+		} else {
+			rate = *r.SampleRate
+			sampled = SampleByRate(root.TraceID, rate)
+		}
+		if sampled {
+			SetRuleSampleRate(root, rate)
+			atomic.AddInt64(&r.kept, 1)
+		} else {
+			atomic.AddInt64(&r.dropped, 1)
+		}
+		return true, sampled
+	}
+	return false, false
+}
+
+// Stop stops reporting stats
+func (s *RulesSampler) Stop() {
+	s.tickStats.Stop()
+}
+
+func (s *RulesSampler) report() {
+	for _, r := range s.rules {
+		tags := []string{r.tag}
+		metrics.Count("datadog.trace_agent.sampler.rules.kept", atomic.SwapInt64(&r.kept, 0), tags, 1)
+		metrics.Count("datadog.trace_agent.sampler.rules.dropped", atomic.SwapInt64(&r.dropped, 0), tags, 1)
+	}
+}
+
+// matches returns true if the root span matches all the criteria of the rule.
+func (r *samplingRule) matches(env string, root *pb.Span) bool {
+	if !matchesPattern(r.ServiceRe, root.Service) ||
+		!matchesPattern(r.OperationNameRe, root.Name) ||
+		!matchesPattern(r.ResourceRe, root.Resource) ||
+		!matchesPattern(r.EnvRe, env) {
+		return false
+	}
+	for k, re := range r.TagsRe {
+		v, ok := root.Meta[k]
+		if !ok || !re.MatchString(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// allow returns whether a trace fits in the TPS budget of the rule, along with
+// the rate applied by the rule, estimated over the previous period.
+func (r *samplingRule) allow(now time.Time) (bool, float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if elapsed := now.Sub(r.windowStart); elapsed >= rateEstimationPeriod {
+		if !r.windowStart.IsZero() && r.windowSeen > 0 {
+			r.estimatedRate = math.Min(1, r.MaxTPS*elapsed.Seconds()/r.windowSeen)
+		}
+		r.windowStart = now
+		r.windowSeen = 0
+	}
+	r.windowSeen++
+	return r.limiter.AllowN(now, 1), r.estimatedRate
+}
+
+func matchesPattern(re *regexp.Regexp, value string) bool {
+	return re == nil || re.MatchString(value)
+}
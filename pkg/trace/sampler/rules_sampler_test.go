@@ -0,0 +1,96 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package sampler
+
+import (
+	"math/rand"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRulesSamplerMatching(t *testing.T) {
+	one, zero := 1.0, 0.0
+	s := NewRulesSampler([]*config.SamplingRule{
+		{ServiceRe: regexp.MustCompile("^checkout$"), SampleRate: &one},
+		{ResourceRe: regexp.MustCompile("^GET /health.*$"), SampleRate: &zero},
+		{OperationNameRe: regexp.MustCompile("^db\\..*$"), EnvRe: regexp.MustCompile("^prod$"), SampleRate: &zero},
+		{TagsRe: map[string]*regexp.Regexp{"customer.tier": regexp.MustCompile("^gold$")}, SampleRate: &one},
+	})
+	s.Stop()
+
+	for _, tt := range []struct {
+		name    string
+		env     string
+		span    *pb.Span
+		matched bool
+		sampled bool
+	}{
+		{"service", "prod", &pb.Span{Service: "checkout", Resource: "GET /health"}, true, true},
+		{"resource", "prod", &pb.Span{Service: "web", Resource: "GET /healthz"}, true, false},
+		{"name-and-env", "prod", &pb.Span{Service: "web", Name: "db.query"}, true, false},
+		{"name-other-env", "staging", &pb.Span{Service: "web", Name: "db.query"}, false, false},
+		{"tags", "prod", &pb.Span{Service: "web", Meta: map[string]string{"customer.tier": "gold"}}, true, true},
+		{"other-tags", "prod", &pb.Span{Service: "web", Meta: map[string]string{"customer.tier": "silver"}}, false, false},
+		{"no-match", "prod", &pb.Span{Service: "web", Resource: "GET /"}, false, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.span.TraceID = rand.Uint64()
+			matched, sampled := s.Sample(tt.env, tt.span)
+			assert.Equal(t, tt.matched, matched)
+			assert.Equal(t, tt.sampled, sampled)
+		})
+	}
+}
+
+func TestRulesSamplerSampleRate(t *testing.T) {
+	assert := assert.New(t)
+	sampleRate := 0.25
+	s := NewRulesSampler([]*config.SamplingRule{{SampleRate: &sampleRate}})
+	s.Stop()
+
+	kept := 0
+	for i := 0; i < 10000; i++ {
+		root := &pb.Span{TraceID: rand.Uint64()}
+		matched, sampled := s.Sample("", root)
+		assert.True(matched)
+		if sampled {
+			kept++
+			assert.Equal(sampleRate, GetRuleSampleRate(root))
+		}
+	}
+	assert.InDelta(2500, kept, 250)
+}
+
+func TestRulesSamplerMaxTPS(t *testing.T) {
+	assert := assert.New(t)
+	s := NewRulesSampler([]*config.SamplingRule{{MaxTPS: 10}})
+	s.Stop()
+
+	// 100 traces per second during 3 seconds
+	now := time.Unix(13829192398, 0)
+	kept := 0
+	var root *pb.Span
+	for i := 0; i < 300; i++ {
+		root = &pb.Span{TraceID: rand.Uint64()}
+		if _, sampled := s.sample(now, "", root); sampled {
+			kept++
+		}
+		now = now.Add(10 * time.Millisecond)
+	}
+	// the initial burst and 10 traces per second
+	assert.InDelta(40, kept, 2)
+
+	root = &pb.Span{TraceID: rand.Uint64()}
+	for sampled := false; !sampled; now = now.Add(100 * time.Millisecond) {
+		_, sampled = s.sample(now, "", root)
+	}
+	assert.InDelta(0.1, GetRuleSampleRate(root), 0.01)
+}
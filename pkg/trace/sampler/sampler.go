@@ -34,6 +34,9 @@ const (
 	// KeySamplingRatePreSampler is a metric key holding the API rate limiter's rate for APM events.
 	KeySamplingRatePreSampler = "_dd1.sr.rapre"
 
+	// KeySamplingRateRule is a metric key holding the rate of the user-defined sampling rule matching the trace.
+	KeySamplingRateRule = "_dd1.sr.rule"
+
 	// KeySamplingRateEventExtraction is the key of the metric storing the event extraction rate on an APM event.
 	KeySamplingRateEventExtraction = "_dd1.sr.eausr"
 
@@ -119,6 +122,22 @@ func SetPreSampleRate(s *pb.Span, rate float64) {
 	}
 }
 
+// GetRuleSampleRate returns the rate at which the trace this span belongs to was sampled by a user-defined rule.
+// NOTE: This defaults to 1 if no rate is stored.
+func GetRuleSampleRate(s *pb.Span) float64 {
+	return getMetricDefault(s, KeySamplingRateRule, 1.0)
+}
+
+// SetRuleSampleRate sets the rate at which the trace this span belongs to was sampled by a user-defined rule.
+func SetRuleSampleRate(s *pb.Span, rate float64) {
+	if rate < 1 {
+		setMetric(s, KeySamplingRateRule, rate)
+	} else {
+		// We assume missing value is 1 to save bandwidth (check getter).
+		delete(s.Metrics, KeySamplingRateRule)
+	}
+}
+
 // GetEventExtractionRate gets the rate at which the trace from which we extracted this event was sampled at the tracer.
 // This defaults to 1 if no rate is stored.
 func GetEventExtractionRate(s *pb.Span) float64 {
@@ -0,0 +1,414 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package sampler
+
+import (
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/metrics"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/trace/stats/quantile"
+	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
+	"github.com/DataDog/datadog-agent/pkg/trace/watchdog"
+)
+
+const (
+	// tailSamplerTickPeriod is the period at which the tail sampler decides on the buffered traces.
+	tailSamplerTickPeriod = 500 * time.Millisecond
+	// latencyWindow is the period over which the percentiles of the duration of the root spans are computed.
+	latencyWindow = time.Minute
+	// minLatencySamples is the number of root spans of a service needed to use its duration percentile.
+	minLatencySamples = 100
+	// maxLatencyServices limits the number of services for which duration percentiles are computed.
+	maxLatencyServices = 1000
+	// decisionCacheSize is the number of recent decisions applied to the chunks received late.
+	decisionCacheSize = 10000
+	// defaultTailSamplerMaxSize limits the buffered traces when the agent's maximum memory is not limited.
+	defaultTailSamplerMaxSize = 100 * 1024 * 1024
+)
+
+// Reasons for which the tail sampler keeps a trace.
+const (
+	tailReasonSampled = "sampled"
+	tailReasonError   = "error"
+	tailReasonTags    = "tags"
+	tailReasonLatency = "latency"
+)
+
+// TailSampler buffers the chunks of the traces, keyed by trace ID, for a short window to
+// decide on complete traces. A trace is kept as a whole if one of its chunks was kept by the
+// other samplers, or if it contains an error, a span with one of the configured tags or a root
+// span slower than the configured percentile of its service. The buffer is limited to a share
+// of the agent's maximum memory, the oldest traces are decided early when it is full.
+type TailSampler struct {
+	conf             *config.TailSamplingConfig
+	decisionWait     time.Duration
+	maxSize          int
+	maxMemory        float64
+	watchdogInterval time.Duration
+	write            func([]pb.Trace)
+
+	mu     sync.Mutex
+	traces map[uint64]*bufferedTrace
+	queue  []*bufferedTrace // ordered by deadline
+	size   int
+	// pending holds the chunks of kept traces received after the decision.
+	pending []pb.Trace
+
+	decisions     map[uint64]bool
+	decisionsRing []uint64
+	decisionsNext int
+
+	latencies          map[ServiceSignature]*latencyStats
+	latencyWindowStart time.Time
+
+	kept    map[string]int64
+	dropped int64
+	evicted int64
+	late    int64
+
+	exit chan struct{}
+	done chan struct{}
+}
+
+// bufferedTrace holds the chunks of a trace waiting for a decision.
+type bufferedTrace struct {
+	id          uint64
+	env         string
+	chunks      []pb.Trace
+	root        *pb.Span
+	sampled     bool
+	userDropped bool
+	hasError    bool
+	size        int
+	deadline    time.Time
+}
+
+// latencyStats holds the durations of the root spans of a service and env over
+// the current and the previous windows.
+type latencyStats struct {
+	current  *quantile.SliceSummary
+	previous *quantile.SliceSummary
+}
+
+// NewTailSampler returns a TailSampler which calls write with the kept traces,
+// each of them made of all the chunks received for the trace.
+func NewTailSampler(conf *config.AgentConfig, write func([]pb.Trace)) *TailSampler {
+	return &TailSampler{
+		conf:             conf.TailSampling,
+		decisionWait:     time.Duration(conf.TailSampling.DecisionWaitSeconds * float64(time.Second)),
+		maxSize:          tailSamplerMaxSize(conf),
+		maxMemory:        conf.MaxMemory,
+		watchdogInterval: conf.WatchdogInterval,
+		write:            write,
+		traces:           make(map[uint64]*bufferedTrace),
+		decisions:        make(map[uint64]bool),
+		decisionsRing:    make([]uint64, 0, decisionCacheSize),
+		latencies:        make(map[ServiceSignature]*latencyStats),
+		kept:             make(map[string]int64),
+		exit:             make(chan struct{}),
+		done:             make(chan struct{}),
+	}
+}
+
+// tailSamplerMaxSize returns the maximum size of the buffered traces, a share of the agent's
+// maximum memory or defaultTailSamplerMaxSize when it is not limited.
+func tailSamplerMaxSize(conf *config.AgentConfig) int {
+	if maxSize := int(conf.TailSampling.MaxMemoryRatio * conf.MaxMemory); maxSize > 0 {
+		return maxSize
+	}
+	return defaultTailSamplerMaxSize
+}
+
+// Start starts deciding on the buffered traces.
+func (s *TailSampler) Start() {
+	go func() {
+		defer watchdog.LogOnPanic()
+		s.run()
+	}()
+}
+
+// Stop stops the sampler and decides on all the buffered traces.
+func (s *TailSampler) Stop() {
+	close(s.exit)
+	<-s.done
+	s.mu.Lock()
+	out := s.decideOldest(time.Now(), len(s.queue))
+	out = append(out, s.takePending()...)
+	s.mu.Unlock()
+	s.flush(out)
+	s.report()
+}
+
+func (s *TailSampler) run() {
+	defer close(s.done)
+	tick := time.NewTicker(tailSamplerTickPeriod)
+	defer tick.Stop()
+	tickStats := time.NewTicker(10 * time.Second)
+	defer tickStats.Stop()
+	var tickWatchdog <-chan time.Time
+	if s.maxMemory > 0 && s.watchdogInterval > 0 {
+		t := time.NewTicker(s.watchdogInterval)
+		defer t.Stop()
+		tickWatchdog = t.C
+	}
+	for {
+		select {
+		case now := <-tick.C:
+			s.decideExpired(now)
+		case now := <-tickWatchdog:
+			if float64(watchdog.Mem().Alloc) > s.maxMemory {
+				// the agent uses too much memory, release half of the buffer
+				s.mu.Lock()
+				n := (len(s.queue) + 1) / 2
+				s.evicted += int64(n)
+				out := s.decideOldest(now, n)
+				s.mu.Unlock()
+				s.flush(out)
+			}
+		case <-tickStats.C:
+			s.report()
+		case <-s.exit:
+			return
+		}
+	}
+}
+
+// Add buffers a chunk of a trace along with the decision of the other samplers on it.
+func (s *TailSampler) Add(env string, chunk pb.Trace, sampled bool) {
+	s.add(time.Now(), env, chunk, sampled)
+}
+
+func (s *TailSampler) add(now time.Time, env string, chunk pb.Trace, sampled bool) {
+	if len(chunk) == 0 {
+		return
+	}
+	id := chunk[0].TraceID
+	size := chunk.Msgsize()
+
+	s.mu.Lock()
+	if keep, ok := s.decisions[id]; ok {
+		// the trace was already decided, the chunk follows the decision
+		s.late++
+		if keep {
+			s.pending = append(s.pending, chunk)
+		}
+		s.mu.Unlock()
+		return
+	}
+	t, ok := s.traces[id]
+	if !ok {
+		t = &bufferedTrace{id: id, env: env, deadline: now.Add(s.decisionWait)}
+		s.traces[id] = t
+		s.queue = append(s.queue, t)
+	}
+	t.add(chunk, sampled, size)
+	s.size += size
+
+	var out []pb.Trace
+	if s.size > s.maxSize {
+		// the buffer is full, the oldest traces are decided early
+		n := 0
+		for freed := 0; n < len(s.queue) && s.size-freed > s.maxSize; n++ {
+			freed += s.queue[n].size
+		}
+		s.evicted += int64(n)
+		out = s.decideOldest(now, n)
+	}
+	s.mu.Unlock()
+	s.flush(out)
+}
+
+// decideExpired decides on the traces buffered for the decision wait, and writes the kept ones.
+func (s *TailSampler) decideExpired(now time.Time) {
+	s.mu.Lock()
+	n := 0
+	for n < len(s.queue) && !now.Before(s.queue[n].deadline) {
+		n++
+	}
+	out := s.decideOldest(now, n)
+	out = append(out, s.takePending()...)
+	s.mu.Unlock()
+	s.flush(out)
+}
+
+// decideOldest decides on the n oldest buffered traces and returns the kept ones.
+// It must be called with the lock held.
+func (s *TailSampler) decideOldest(now time.Time, n int) []pb.Trace {
+	var out []pb.Trace
+	for i, t := range s.queue[:n] {
+		if s.decide(now, t) {
+			out = append(out, t.merge())
+		}
+		s.queue[i] = nil
+	}
+	s.queue = s.queue[n:]
+	return out
+}
+
+// decide returns true if the trace should be kept and forgets it.
+// It must be called with the lock held.
+func (s *TailSampler) decide(now time.Time, t *bufferedTrace) bool {
+	var reason string
+	switch {
+	case t.userDropped:
+	case t.sampled:
+		reason = tailReasonSampled
+	case s.conf.Errors && t.hasError:
+		reason = tailReasonError
+	case s.hasTags(t):
+		reason = tailReasonTags
+	case s.isSlow(t):
+		reason = tailReasonLatency
+	}
+	if t.root != nil {
+		s.recordLatency(now, ServiceSignature{t.root.Service, t.env}, t.root.Duration)
+	}
+
+	delete(s.traces, t.id)
+	s.size -= t.size
+	s.addDecision(t.id, reason != "")
+
+	if reason == "" {
+		s.dropped++
+		return false
+	}
+	s.kept[reason]++
+	return true
+}
+
+// hasTags returns true if a span of the trace has one of the configured tags.
+func (s *TailSampler) hasTags(t *bufferedTrace) bool {
+	if len(s.conf.Tags) == 0 {
+		return false
+	}
+	for _, chunk := range t.chunks {
+		for _, span := range chunk {
+			for k, v := range s.conf.Tags {
+				if actual, ok := span.Meta[k]; ok && (v == "" || v == actual) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// isSlow returns true if the root span of the trace is slower than the configured
+// percentile of the durations of the root spans of its service and env.
+func (s *TailSampler) isSlow(t *bufferedTrace) bool {
+	if s.conf.LatencyPercentile <= 0 || t.root == nil {
+		return false
+	}
+	l, ok := s.latencies[ServiceSignature{t.root.Service, t.env}]
+	if !ok || l.previous == nil || l.previous.N < minLatencySamples {
+		return false
+	}
+	return float64(t.root.Duration) > l.previous.Quantile(s.conf.LatencyPercentile)
+}
+
+// recordLatency adds the duration of a root span to the percentiles of its service.
+func (s *TailSampler) recordLatency(now time.Time, sig ServiceSignature, duration int64) {
+	if s.conf.LatencyPercentile <= 0 {
+		return
+	}
+	if now.Sub(s.latencyWindowStart) >= latencyWindow {
+		for sig, l := range s.latencies {
+			if l.current.N == 0 {
+				delete(s.latencies, sig)
+				continue
+			}
+			l.previous, l.current = l.current, quantile.NewSliceSummary()
+		}
+		s.latencyWindowStart = now
+	}
+	l, ok := s.latencies[sig]
+	if !ok {
+		if len(s.latencies) >= maxLatencyServices {
+			return
+		}
+		l = &latencyStats{current: quantile.NewSliceSummary()}
+		s.latencies[sig] = l
+	}
+	l.current.Insert(float64(duration))
+}
+
+// addDecision remembers the decision on a trace to apply it to its late chunks.
+// It must be called with the lock held.
+func (s *TailSampler) addDecision(id uint64, keep bool) {
+	if len(s.decisionsRing) < decisionCacheSize {
+		s.decisionsRing = append(s.decisionsRing, id)
+	} else {
+		delete(s.decisions, s.decisionsRing[s.decisionsNext])
+		s.decisionsRing[s.decisionsNext] = id
+		s.decisionsNext = (s.decisionsNext + 1) % decisionCacheSize
+	}
+	s.decisions[id] = keep
+}
+
+// takePending returns the late chunks of the kept traces. It must be called with the lock held.
+func (s *TailSampler) takePending() []pb.Trace {
+	pending := s.pending
+	s.pending = nil
+	return pending
+}
+
+func (s *TailSampler) flush(traces []pb.Trace) {
+	if len(traces) > 0 {
+		s.write(traces)
+	}
+}
+
+func (s *TailSampler) report() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for reason, n := range s.kept {
+		metrics.Count("datadog.trace_agent.sampler.tail.kept", n, []string{"reason:" + reason}, 1)
+		delete(s.kept, reason)
+	}
+	metrics.Count("datadog.trace_agent.sampler.tail.dropped", s.dropped, nil, 1)
+	metrics.Count("datadog.trace_agent.sampler.tail.evicted", s.evicted, nil, 1)
+	metrics.Count("datadog.trace_agent.sampler.tail.late_chunks", s.late, nil, 1)
+	metrics.Gauge("datadog.trace_agent.sampler.tail.buffered_traces", float64(len(s.traces)), nil, 1)
+	metrics.Gauge("datadog.trace_agent.sampler.tail.buffered_bytes", float64(s.size), nil, 1)
+	s.dropped, s.evicted, s.late = 0, 0, 0
+}
+
+// add adds a chunk to the trace.
+func (t *bufferedTrace) add(chunk pb.Trace, sampled bool, size int) {
+	t.chunks = append(t.chunks, chunk)
+	t.size += size
+	t.sampled = t.sampled || sampled
+	if priority, ok := GetSamplingPriority(traceutil.GetRoot(chunk)); ok && priority < 0 {
+		t.userDropped = true
+	}
+	for _, span := range chunk {
+		if span.ParentID == 0 {
+			t.root = span
+		}
+		if span.Error != 0 {
+			t.hasError = true
+		}
+	}
+}
+
+// merge returns the spans of all the chunks of the trace.
+func (t *bufferedTrace) merge() pb.Trace {
+	if len(t.chunks) == 1 {
+		return t.chunks[0]
+	}
+	n := 0
+	for _, chunk := range t.chunks {
+		n += len(chunk)
+	}
+	merged := make(pb.Trace, 0, n)
+	for _, chunk := range t.chunks {
+		merged = append(merged, chunk...)
+	}
+	return merged
+}
@@ -0,0 +1,186 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package sampler
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/stretchr/testify/assert"
+)
+
+// tailSamplerWriter records the traces written by a TailSampler.
+type tailSamplerWriter struct {
+	mu     sync.Mutex
+	traces []pb.Trace
+}
+
+func (w *tailSamplerWriter) write(traces []pb.Trace) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.traces = append(w.traces, traces...)
+}
+
+func (w *tailSamplerWriter) ids() []uint64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var ids []uint64
+	for _, t := range w.traces {
+		ids = append(ids, t[0].TraceID)
+	}
+	return ids
+}
+
+func newTestTailSampler(tsc *config.TailSamplingConfig) (*TailSampler, *tailSamplerWriter) {
+	conf := config.New()
+	conf.TailSampling = tsc
+	w := &tailSamplerWriter{}
+	return NewTailSampler(conf, w.write), w
+}
+
+func TestTailSamplerKeepsWholeTraces(t *testing.T) {
+	assert := assert.New(t)
+	s, w := newTestTailSampler(&config.TailSamplingConfig{DecisionWaitSeconds: 5, Errors: true})
+	now := time.Unix(13829192398, 0)
+
+	// trace 1 is kept by the other samplers on one of its chunks only
+	s.add(now, "prod", pb.Trace{{TraceID: 1, SpanID: 2, ParentID: 1}}, false)
+	s.add(now, "prod", pb.Trace{{TraceID: 1, SpanID: 1}}, true)
+	// trace 2 has an error in a chunk
+	s.add(now, "prod", pb.Trace{{TraceID: 2, SpanID: 1}}, false)
+	s.add(now.Add(time.Second), "prod", pb.Trace{{TraceID: 2, SpanID: 2, ParentID: 1, Error: 1}}, false)
+	// trace 3 is not interesting
+	s.add(now, "prod", pb.Trace{{TraceID: 3, SpanID: 1}}, false)
+	// trace 4 was dropped by the user
+	s.add(now, "prod", pb.Trace{{TraceID: 4, SpanID: 1, Error: 1, Metrics: map[string]float64{KeySamplingPriority: -1}}}, false)
+
+	s.decideExpired(now.Add(4 * time.Second))
+	assert.Empty(w.ids())
+	assert.Len(s.traces, 4)
+
+	s.decideExpired(now.Add(5 * time.Second))
+	assert.Equal([]uint64{1, 2}, w.ids())
+	assert.Len(w.traces[0], 2)
+	assert.Len(w.traces[1], 2)
+	assert.Empty(s.traces)
+	assert.Equal(0, s.size)
+	assert.Equal(int64(1), s.kept[tailReasonSampled])
+	assert.Equal(int64(1), s.kept[tailReasonError])
+	assert.Equal(int64(2), s.dropped)
+
+	// the late chunks follow the decision on their trace
+	s.add(now.Add(6*time.Second), "prod", pb.Trace{{TraceID: 1, SpanID: 3, ParentID: 1}}, false)
+	s.add(now.Add(6*time.Second), "prod", pb.Trace{{TraceID: 3, SpanID: 2, ParentID: 1}}, true)
+	assert.Empty(s.traces)
+	s.decideExpired(now.Add(6 * time.Second))
+	assert.Equal([]uint64{1, 2, 1}, w.ids())
+	assert.Equal(int64(2), s.late)
+}
+
+func TestTailSamplerTags(t *testing.T) {
+	assert := assert.New(t)
+	s, w := newTestTailSampler(&config.TailSamplingConfig{
+		DecisionWaitSeconds: 1,
+		Tags:                map[string]string{"customer.tier": "gold", "canary": ""},
+	})
+	now := time.Unix(13829192398, 0)
+
+	s.add(now, "", pb.Trace{{TraceID: 1, Meta: map[string]string{"customer.tier": "gold"}}}, false)
+	s.add(now, "", pb.Trace{{TraceID: 2, Meta: map[string]string{"customer.tier": "silver"}}}, false)
+	s.add(now, "", pb.Trace{{TraceID: 3, Meta: map[string]string{"canary": "true"}}}, false)
+	s.add(now, "", pb.Trace{{TraceID: 4, Error: 1}}, false)
+	s.decideExpired(now.Add(time.Second))
+	assert.Equal([]uint64{1, 3}, w.ids())
+}
+
+func TestTailSamplerLatency(t *testing.T) {
+	assert := assert.New(t)
+	s, w := newTestTailSampler(&config.TailSamplingConfig{DecisionWaitSeconds: 1, LatencyPercentile: 0.9})
+	now := time.Unix(13829192398, 0)
+
+	id := uint64(1)
+	addTraces := func(service string, durations ...int64) {
+		for _, d := range durations {
+			s.add(now, "prod", pb.Trace{{TraceID: id, Service: service, Duration: d}}, false)
+			id++
+		}
+		now = now.Add(time.Second)
+		s.decideExpired(now)
+	}
+	durations := make([]int64, minLatencySamples)
+	for i := range durations {
+		durations[i] = int64(i)
+	}
+
+	// no percentile is known during the first window
+	addTraces("web", durations...)
+	addTraces("web", 1000)
+	assert.Empty(w.ids())
+
+	// the percentile of the previous window is used
+	now = now.Add(latencyWindow)
+	addTraces("web", 50)
+	addTraces("web", 1000, 10)
+	addTraces("db", 1000)
+	assert.Equal([]uint64{uint64(minLatencySamples + 3)}, w.ids())
+}
+
+func TestTailSamplerMaxSize(t *testing.T) {
+	assert := assert.New(t)
+	conf := config.New()
+	conf.TailSampling = &config.TailSamplingConfig{DecisionWaitSeconds: 5, MaxMemoryRatio: 1}
+	chunkSize := pb.Trace{{TraceID: 1}}.Msgsize()
+	conf.MaxMemory = float64(3 * chunkSize)
+	w := &tailSamplerWriter{}
+	s := NewTailSampler(conf, w.write)
+	now := time.Unix(13829192398, 0)
+
+	for id := uint64(1); id <= 5; id++ {
+		s.add(now, "", pb.Trace{{TraceID: id}}, true)
+	}
+	// the oldest traces were decided early to stay within the limit
+	assert.Equal([]uint64{1, 2}, w.ids())
+	assert.Len(s.traces, 3)
+	assert.Equal(3*chunkSize, s.size)
+	assert.Equal(int64(2), s.evicted)
+}
+
+func TestTailSamplerDefaultMaxSize(t *testing.T) {
+	conf := config.New()
+	conf.TailSampling = &config.TailSamplingConfig{DecisionWaitSeconds: 5, MaxMemoryRatio: 0.5}
+	conf.MaxMemory = 1000
+	assert.Equal(t, 500, tailSamplerMaxSize(conf))
+
+	// the buffer is still limited when the agent's memory is not
+	conf.MaxMemory = 0
+	assert.Equal(t, defaultTailSamplerMaxSize, tailSamplerMaxSize(conf))
+	conf.MaxMemory = 1000
+	conf.TailSampling.MaxMemoryRatio = 0
+	assert.Equal(t, defaultTailSamplerMaxSize, tailSamplerMaxSize(conf))
+}
+
+func TestTailSamplerStop(t *testing.T) {
+	s, w := newTestTailSampler(&config.TailSamplingConfig{DecisionWaitSeconds: 60})
+	s.Start()
+	s.Add("", pb.Trace{{TraceID: 1}}, true)
+	s.Add("", pb.Trace{{TraceID: 2}}, false)
+	s.Stop()
+	assert.Equal(t, []uint64{1}, w.ids())
+}
+
+func TestTailSamplerDecisionCache(t *testing.T) {
+	assert := assert.New(t)
+	s, _ := newTestTailSampler(&config.TailSamplingConfig{DecisionWaitSeconds: 1})
+	for id := uint64(0); id < decisionCacheSize+10; id++ {
+		s.addDecision(id, true)
+	}
+	assert.Len(s.decisions, decisionCacheSize)
+	assert.NotContains(s.decisions, uint64(9))
+	assert.Contains(s.decisions, uint64(10))
+}
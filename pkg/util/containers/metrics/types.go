@@ -83,6 +83,9 @@ type ContainerMemStats struct {
 
 	// docker.mem.commit_peak_bytes
 	CommitPeakBytes uint64
+
+	// Memory pressure, only available with cgroup v2
+	PSI *PSIStats
 }
 
 // ContainerCPUStats stores CPU times for a cgroup.
@@ -110,6 +113,9 @@ type ContainerCPUStats struct {
 
 	// docker.thread.count
 	ThreadCount uint64
+
+	// CPU pressure, only available with cgroup v2
+	PSI *PSIStats
 }
 
 // ContainerIOStats store I/O statistics about a cgroup.
@@ -133,6 +139,26 @@ type ContainerIOStats struct {
 
 	// docker.container.open_fds
 	OpenFiles uint64
+
+	// I/O pressure, only available with cgroup v2
+	PSI *PSIStats
+}
+
+// PSIStats stores the pressure stall information of a resource.
+// "Some" tracks the share of time during which at least one task was stalled,
+// "Full" the share of time during which all tasks were stalled.
+type PSIStats struct {
+	Some PSIValues
+	Full PSIValues
+}
+
+// PSIValues stores the averages (in percents) over 10s, 60s and 300s windows,
+// and the total stall time in microseconds.
+type PSIValues struct {
+	Avg10  float64
+	Avg60  float64
+	Avg300 float64
+	Total  uint64
 }
 
 // ContainerMetrics wraps all container metrics
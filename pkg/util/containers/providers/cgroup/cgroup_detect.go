@@ -23,6 +23,10 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
 
+// unifiedTarget is the target used to store the mount point and path of
+// the unified hierarchy (cgroup v2), which holds all the controllers
+const unifiedTarget = "unified"
+
 var (
 	// cloudfoundry garden container have IDs in the form aaaaaaaa-bbbb-cccc-dddd-eeee
 	containerRe = regexp.MustCompile("[0-9a-f]{64}|[0-9a-f]{8}(-[0-9a-f]{4}){4}")
@@ -47,8 +51,19 @@ func (c ContainerCgroup) ContainerStartTime() (int64, error) {
 	return stat.ModTime().Unix(), nil
 }
 
+// isUnified returns true if the cgroup is only attached to the unified hierarchy (cgroup v2).
+// On hybrid setups, the cgroup v1 controllers are used.
+func (c ContainerCgroup) isUnified() bool {
+	_, ok := c.Paths[unifiedTarget]
+	return ok && len(c.Paths) == 1
+}
+
 // cgroupFilePath constructs file path to get targeted stats file.
+// With the unified hierarchy (cgroup v2), all the files are located in the same folder.
 func (c ContainerCgroup) cgroupFilePath(target, file string) string {
+	if c.isUnified() {
+		target = unifiedTarget
+	}
 	mount, ok := c.Mounts[target]
 	if !ok {
 		log.Debugf("Missing target %s from mounts", target)
@@ -96,7 +111,11 @@ func (c ContainerCgroup) cgroupParentFilePath(target, file string) string {
 //	 cgroup /sys/fs/cgroup/perf_event cgroup rw,relatime,perf_event 0 0
 //	 cgroup /sys/fs/cgroup/hugetlb cgroup rw,relatime,hugetlb 0 0
 //
-// Returns a map for every target (cpuset, cpu, cpuacct) => path
+// On hosts using the unified hierarchy (cgroup v2), a single entry is found:
+//	 cgroup2 /sys/fs/cgroup cgroup2 rw,nosuid,nodev,noexec,relatime,nsdelegate 0 0
+//
+// Returns a map for every target (cpuset, cpu, cpuacct) => path, the unified
+// hierarchy is stored under the unifiedTarget key
 func cgroupMountPoints() (map[string]string, error) {
 	mountsFile := "/proc/mounts"
 	if !pathExists(mountsFile) {
@@ -117,6 +136,15 @@ func parseCgroupMountPoints(r io.Reader) map[string]string {
 	for scanner.Scan() {
 		mount := scanner.Text()
 		tokens := strings.Split(mount, " ")
+		// Check if the filesystem type is 'cgroup2'
+		if len(tokens) >= 3 && tokens[2] == "cgroup2" {
+			cgroupPath := tokens[1]
+			// The unified hierarchy is usually mounted on the cgroup root itself
+			if strings.HasPrefix(cgroupPath+"/", cgroupRoot) {
+				mountPoints[unifiedTarget] = cgroupPath
+			}
+			continue
+		}
 		// Check if the filesystem type is 'cgroup'
 		if len(tokens) >= 3 && tokens[2] == "cgroup" {
 			cgroupPath := tokens[1]
@@ -214,6 +242,10 @@ func readCgroupsForPath(pidCgroupPath, prefix string) (string, map[string]string
 // 8:memory:/kubepods/besteffort/pod2baa3444-4d37-11e7-bd2f-080027d2bf10/47fc31db38b4fa0f4db44b99d0cad10e3cd4d5f142135a7721c1c95c1aadfb2e
 // 7:blkio:/kubepods/besteffort/pod2baa3444-4d37-11e7-bd2f-080027d2bf10/47fc31db38b4fa0f4db44b99d0cad10e3cd4d5f142135a7721c1c95c1aadfb2e
 //
+// With the unified hierarchy (cgroup v2), there is a single entry with an empty list of controllers:
+//
+// 0::/system.slice/docker-47fc31db38b4fa0f4db44b99d0cad10e3cd4d5f142135a7721c1c95c1aadfb2e.scope
+//
 // Returns the common containerID and a mapping of target => path
 // If any line doesn't have a valid container ID we will return an empty string and an empty slice of paths
 func parseCgroupPaths(r io.Reader, prefix string) (string, map[string]string, error) {
@@ -236,6 +268,9 @@ func parseCgroupPaths(r io.Reader, prefix string) (string, map[string]string, er
 		}
 		// Target can be comma-separate values like cpu,cpuacct
 		tsp := strings.Split(sp[1], ",")
+		if sp[1] == "" {
+			tsp = []string{unifiedTarget}
+		}
 		for _, target := range tsp {
 			if len(sp[2]) > 1 && sp[2] != "/docker" { // if the path is only one character it's the root cgroup
 				paths[target] = sp[2]
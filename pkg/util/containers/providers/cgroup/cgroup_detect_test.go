@@ -112,6 +112,29 @@ func TestParseCgroupMountPoints(t *testing.T) {
 				"systemd":    "/sys/fs/cgroup/systemd",
 			},
 		},
+		{
+			contents: []string{
+				"sysfs /sys sysfs rw,nosuid,nodev,noexec,relatime 0 0",
+				"cgroup2 /sys/fs/cgroup cgroup2 rw,nosuid,nodev,noexec,relatime,nsdelegate 0 0",
+			},
+			expected: map[string]string{
+				"unified": "/sys/fs/cgroup",
+			},
+		},
+		{
+			contents: []string{
+				"tmpfs /sys/fs/cgroup tmpfs ro,nosuid,nodev,noexec,mode=755 0 0",
+				"cgroup2 /sys/fs/cgroup/unified cgroup2 rw,nosuid,nodev,noexec,relatime,nsdelegate 0 0",
+				"cgroup /sys/fs/cgroup/memory cgroup rw,nosuid,nodev,noexec,relatime,memory 0 0",
+				"cgroup /sys/fs/cgroup/cpu,cpuacct cgroup rw,nosuid,nodev,noexec,relatime,cpu,cpuacct 0 0",
+			},
+			expected: map[string]string{
+				"unified": "/sys/fs/cgroup/unified",
+				"memory":  "/sys/fs/cgroup/memory",
+				"cpu":     "/sys/fs/cgroup/cpu,cpuacct",
+				"cpuacct": "/sys/fs/cgroup/cpu,cpuacct",
+			},
+		},
 		{
 			contents: []string{
 				"",
@@ -306,6 +329,30 @@ func TestParseCgroupPaths(t *testing.T) {
 				"name=systemd": "/system.slice/ecs-agent.service/1236529c30c0bf2faf2c5c63c0af2afd134118b91348f321c996734e15b7a8f9",
 			},
 		},
+		// test parsing of the unified hierarchy (cgroup v2)
+		{
+			contents: []string{
+				"0::/system.slice/docker-47fc31db38b4fa0f4db44b99d0cad10e3cd4d5f142135a7721c1c95c1aadfb2e.scope",
+			},
+			expectedContainer: "47fc31db38b4fa0f4db44b99d0cad10e3cd4d5f142135a7721c1c95c1aadfb2e",
+			expectedPaths: map[string]string{
+				"unified": "/system.slice/docker-47fc31db38b4fa0f4db44b99d0cad10e3cd4d5f142135a7721c1c95c1aadfb2e.scope",
+			},
+		},
+		// test parsing of hybrid setups, with both v1 and v2 hierarchies
+		{
+			contents: []string{
+				"4:memory:/docker/47fc31db38b4fa0f4db44b99d0cad10e3cd4d5f142135a7721c1c95c1aadfb2e",
+				"1:name=systemd:/docker/47fc31db38b4fa0f4db44b99d0cad10e3cd4d5f142135a7721c1c95c1aadfb2e",
+				"0::/docker/47fc31db38b4fa0f4db44b99d0cad10e3cd4d5f142135a7721c1c95c1aadfb2e",
+			},
+			expectedContainer: "47fc31db38b4fa0f4db44b99d0cad10e3cd4d5f142135a7721c1c95c1aadfb2e",
+			expectedPaths: map[string]string{
+				"memory":       "/docker/47fc31db38b4fa0f4db44b99d0cad10e3cd4d5f142135a7721c1c95c1aadfb2e",
+				"name=systemd": "/docker/47fc31db38b4fa0f4db44b99d0cad10e3cd4d5f142135a7721c1c95c1aadfb2e",
+				"unified":      "/docker/47fc31db38b4fa0f4db44b99d0cad10e3cd4d5f142135a7721c1c95c1aadfb2e",
+			},
+		},
 	} {
 		contents := strings.NewReader(strings.Join(tc.contents, "\n"))
 		c, p, err := parseCgroupPaths(contents, "")
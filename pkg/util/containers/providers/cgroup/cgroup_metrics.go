@@ -33,6 +33,9 @@ var (
 // Mem returns the memory statistics for a Cgroup. If the cgroup file is not
 // available then we return an empty stats file.
 func (c ContainerCgroup) Mem() (*metrics.ContainerMemStats, error) {
+	if c.isUnified() {
+		return c.memV2()
+	}
 	ret := &metrics.ContainerMemStats{}
 	statfile := c.cgroupFilePath("memory", "memory.stat")
 
@@ -122,6 +125,9 @@ func (c ContainerCgroup) Mem() (*metrics.ContainerMemStats, error) {
 // MemLimit returns the memory limit of the cgroup, if it exists. If the file does not
 // exist or there is no limit then this will default to 0.
 func (c ContainerCgroup) MemLimit() (uint64, error) {
+	if c.isUnified() {
+		return c.memLimitV2()
+	}
 	v, err := c.ParseSingleStat("memory", "memory.limit_in_bytes")
 	if os.IsNotExist(err) {
 		log.Debugf("Missing cgroup file: %s",
@@ -141,6 +147,9 @@ func (c ContainerCgroup) MemLimit() (uint64, error) {
 // FailedMemoryCount returns the number of times this cgroup reached its memory limit, if it exists.
 // If the file does not exist or there is no limit, then this will default to 0
 func (c ContainerCgroup) FailedMemoryCount() (uint64, error) {
+	if c.isUnified() {
+		return c.failedMemoryCountV2()
+	}
 	v, err := c.ParseSingleStat("memory", "memory.failcnt")
 	if os.IsNotExist(err) {
 		log.Debugf("Missing cgroup file: %s",
@@ -155,6 +164,9 @@ func (c ContainerCgroup) FailedMemoryCount() (uint64, error) {
 // KernelMemoryUsage returns the number of bytes of kernel memory used by this cgroup, if it exists.
 // If the file does not exist or there is an error, then this will default to 0
 func (c ContainerCgroup) KernelMemoryUsage() (uint64, error) {
+	if c.isUnified() {
+		return c.kernelMemoryUsageV2()
+	}
 	v, err := c.ParseSingleStat("memory", "memory.kmem.usage_in_bytes")
 	if os.IsNotExist(err) {
 		log.Debugf("Missing cgroup file: %s",
@@ -169,6 +181,9 @@ func (c ContainerCgroup) KernelMemoryUsage() (uint64, error) {
 // SoftMemLimit returns the soft memory limit of the cgroup, if it exists. If the file does not
 // exist or there is no limit then this will default to 0.
 func (c ContainerCgroup) SoftMemLimit() (uint64, error) {
+	if c.isUnified() {
+		return c.softMemLimitV2()
+	}
 	v, err := c.ParseSingleStat("memory", "memory.soft_limit_in_bytes")
 	if os.IsNotExist(err) {
 		log.Debugf("Missing cgroup file: %s",
@@ -188,6 +203,9 @@ func (c ContainerCgroup) SoftMemLimit() (uint64, error) {
 // CPU returns the CPU status for this cgroup instance
 // If the cgroup file does not exist then we just log debug return nothing.
 func (c ContainerCgroup) CPU() (*metrics.ContainerCPUStats, error) {
+	if c.isUnified() {
+		return c.cpuV2()
+	}
 	ret := &metrics.ContainerCPUStats{}
 	statfile := c.cgroupFilePath("cpuacct", "cpuacct.stat")
 	f, err := os.Open(statfile)
@@ -240,6 +258,9 @@ func (c ContainerCgroup) CPU() (*metrics.ContainerCPUStats, error) {
 // throttle/limited because of CPU quota / limit
 // If the cgroup file does not exist then we just log debug and return 0.
 func (c ContainerCgroup) CPUPeriods() (throttledNr uint64, throttledTime float64, err error) {
+	if c.isUnified() {
+		return c.cpuPeriodsV2()
+	}
 	statfile := c.cgroupFilePath("cpu", "cpu.stat")
 	f, err := os.Open(statfile)
 	if os.IsNotExist(err) {
@@ -285,6 +306,9 @@ func (c ContainerCgroup) CPUPeriods() (throttledNr uint64, throttledTime float64
 // If the limits files aren't available (on older version) then
 // we'll return the default value of numCPU * 100.
 func (c ContainerCgroup) CPULimit() (float64, error) {
+	if c.isUnified() {
+		return c.cpuLimitV2()
+	}
 	limit := numCPU * 100.0
 
 	periodFile := c.cgroupFilePath("cpu", "cpu.cfs_period_us")
@@ -359,13 +383,6 @@ func (c ContainerCgroup) CPULimit() (float64, error) {
 // 252:0 Total 58945536
 //
 func (c ContainerCgroup) IO() (*metrics.ContainerIOStats, error) {
-	ret := &metrics.ContainerIOStats{
-		DeviceReadBytes:       make(map[string]uint64),
-		DeviceWriteBytes:      make(map[string]uint64),
-		DeviceReadOperations:  make(map[string]uint64),
-		DeviceWriteOperations: make(map[string]uint64),
-	}
-
 	// Get device id->name mapping
 	var devices map[string]string
 	mapping, err := getDiskDeviceMapping()
@@ -376,7 +393,40 @@ func (c ContainerCgroup) IO() (*metrics.ContainerIOStats, error) {
 		devices = mapping.idToName
 	}
 
-	err = c.scanStatFile("blkio", "blkio.throttle.io_service_bytes", func(line string) error {
+	var ret *metrics.ContainerIOStats
+	if c.isUnified() {
+		ret, err = c.ioV2(devices)
+	} else {
+		ret, err = c.ioV1(devices)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var fileDescCount uint64
+	for _, pid := range c.Pids {
+		fdCount, err := GetFileDescriptorLen(int(pid))
+		if err != nil {
+			log.Debugf("Failed to get file desc length for pid %d, container %s: %s", pid, c.ContainerID[:12], err)
+			continue
+		}
+		fileDescCount += uint64(fdCount)
+	}
+	ret.OpenFiles = fileDescCount
+
+	return ret, nil
+}
+
+// ioV1 returns the disk read and write stats from the blkio controller
+func (c ContainerCgroup) ioV1(devices map[string]string) (*metrics.ContainerIOStats, error) {
+	ret := &metrics.ContainerIOStats{
+		DeviceReadBytes:       make(map[string]uint64),
+		DeviceWriteBytes:      make(map[string]uint64),
+		DeviceReadOperations:  make(map[string]uint64),
+		DeviceWriteOperations: make(map[string]uint64),
+	}
+
+	err := c.scanStatFile("blkio", "blkio.throttle.io_service_bytes", func(line string) error {
 		fields := strings.Split(line, " ")
 		if len(fields) < 3 {
 			return nil
@@ -434,17 +484,6 @@ func (c ContainerCgroup) IO() (*metrics.ContainerIOStats, error) {
 		return nil, err
 	}
 
-	var fileDescCount uint64
-	for _, pid := range c.Pids {
-		fdCount, err := GetFileDescriptorLen(int(pid))
-		if err != nil {
-			log.Debugf("Failed to get file desc length for pid %d, container %s: %s", pid, c.ContainerID[:12], err)
-			continue
-		}
-		fileDescCount += uint64(fdCount)
-	}
-	ret.OpenFiles = fileDescCount
-
 	return ret, nil
 }
 
@@ -0,0 +1,354 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+// +build linux
+
+package cgroup
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/util/containers/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// This file contains the readers for the unified hierarchy (cgroup v2) interface files.
+// ref: https://www.kernel.org/doc/Documentation/admin-guide/cgroup-v2.rst
+// The values are converted to the same units as their cgroup v1 counterpart, so
+// that they can be used interchangeably.
+
+// MicroToUserHZDivisor holds the divisor to convert cgroup v2 times, in microseconds,
+// to the same unit as the cgroup v1 cpuacct.stat values (USER_HZ = 1/100)
+const MicroToUserHZDivisor float64 = 1e6 / 100
+
+// cgroupV2MaxValue is the value written in cgroup v2 files when there is no limit
+const cgroupV2MaxValue = "max"
+
+// memV2 returns the memory statistics from memory.stat, memory.current and memory.swap.current
+func (c ContainerCgroup) memV2() (*metrics.ContainerMemStats, error) {
+	ret := &metrics.ContainerMemStats{}
+	stats, err := c.parseFlatKeyedFile("memory.stat")
+	if err != nil {
+		return nil, err
+	}
+	ret.Cache = stats["file"]
+	ret.RSS = stats["anon"]
+	ret.RSSHuge = stats["anon_thp"]
+	ret.MappedFile = stats["file_mapped"]
+	ret.Pgfault = stats["pgfault"]
+	ret.Pgmajfault = stats["pgmajfault"]
+	ret.InactiveAnon = stats["inactive_anon"]
+	ret.ActiveAnon = stats["active_anon"]
+	ret.InactiveFile = stats["inactive_file"]
+	ret.ActiveFile = stats["active_file"]
+	ret.Unevictable = stats["unevictable"]
+
+	usage, err := c.ParseSingleStat(unifiedTarget, "memory.current")
+	if err == nil {
+		ret.MemUsageInBytes = usage
+	} else {
+		log.Debugf("Missing memory usage stat for %s: %s", c.ContainerID, err.Error())
+	}
+
+	swap, err := c.ParseSingleStat(unifiedTarget, "memory.swap.current")
+	if err == nil {
+		ret.Swap = swap
+		ret.SwapPresent = true
+	} else {
+		log.Debugf("Missing memory swap stat for %s: %s", c.ContainerID, err.Error())
+	}
+
+	ret.PSI, err = c.parsePSIFile("memory.pressure")
+	if err != nil {
+		log.Debugf("Missing memory pressure stat for %s: %s", c.ContainerID, err.Error())
+	}
+
+	return ret, nil
+}
+
+// memLimitV2 returns the memory limit from memory.max
+func (c ContainerCgroup) memLimitV2() (uint64, error) {
+	return c.parseLimitFile("memory.max")
+}
+
+// softMemLimitV2 returns the memory.low value, which is set by container
+// runtimes when a memory reservation (soft limit) is requested
+func (c ContainerCgroup) softMemLimitV2() (uint64, error) {
+	return c.parseLimitFile("memory.low")
+}
+
+// failedMemoryCountV2 returns the number of times the memory usage of the cgroup
+// was about to go over its limit, from memory.events
+func (c ContainerCgroup) failedMemoryCountV2() (uint64, error) {
+	events, err := c.parseFlatKeyedFile("memory.events")
+	if err != nil {
+		return 0, err
+	}
+	return events["max"], nil
+}
+
+// kernelMemoryUsageV2 returns the memory used by kernel data structures, from memory.stat
+func (c ContainerCgroup) kernelMemoryUsageV2() (uint64, error) {
+	stats, err := c.parseFlatKeyedFile("memory.stat")
+	if err != nil {
+		return 0, err
+	}
+	return stats["kernel_stack"] + stats["pagetables"] + stats["slab"], nil
+}
+
+// cpuV2 returns the CPU statistics from cpu.stat and cpu.weight
+func (c ContainerCgroup) cpuV2() (*metrics.ContainerCPUStats, error) {
+	ret := &metrics.ContainerCPUStats{}
+	stats, err := c.parseFlatKeyedFile("cpu.stat")
+	if err != nil {
+		return nil, err
+	}
+	ret.Timestsamp = time.Now()
+	ret.User = uint64(float64(stats["user_usec"]) / MicroToUserHZDivisor)
+	ret.System = uint64(float64(stats["system_usec"]) / MicroToUserHZDivisor)
+	ret.UsageTotal = float64(stats["usage_usec"]) / MicroToUserHZDivisor
+
+	weight, err := c.ParseSingleStat(unifiedTarget, "cpu.weight")
+	if err == nil {
+		ret.Shares = weightToShares(weight)
+	} else {
+		log.Debugf("Missing cpu weight stat for %s: %s", c.ContainerID, err.Error())
+	}
+
+	ret.PSI, err = c.parsePSIFile("cpu.pressure")
+	if err != nil {
+		log.Debugf("Missing cpu pressure stat for %s: %s", c.ContainerID, err.Error())
+	}
+
+	return ret, nil
+}
+
+// cpuPeriodsV2 returns the throttling statistics from cpu.stat
+func (c ContainerCgroup) cpuPeriodsV2() (uint64, float64, error) {
+	stats, err := c.parseFlatKeyedFile("cpu.stat")
+	if err != nil {
+		return 0, 0, err
+	}
+	return stats["nr_throttled"], float64(stats["throttled_usec"]) / MicroToUserHZDivisor, nil
+}
+
+// cpuLimitV2 returns the CPU limit from cpu.max, formatted as "$MAX $PERIOD".
+// See CPULimit for details.
+func (c ContainerCgroup) cpuLimitV2() (float64, error) {
+	limit := numCPU * 100.0
+
+	quota, period, err := parseCPUMax(c.cgroupFilePath(unifiedTarget, "cpu.max"))
+	if os.IsNotExist(err) {
+		log.Debugf("Missing cgroup file: %s", c.cgroupFilePath(unifiedTarget, "cpu.max"))
+		return limit, nil
+	} else if err != nil {
+		return 0, err
+	}
+
+	// If we don't have limit check on current cgroup, check parent
+	// We ignore failures as we already have current cgroup values
+	if quota == -1 {
+		parentQuota, parentPeriod, err := parseCPUMax(c.cgroupParentFilePath(unifiedTarget, "cpu.max"))
+		if err == nil {
+			quota, period = parentQuota, parentPeriod
+		}
+	}
+
+	if (period > 0) && (quota > 0) {
+		limit = quota / period * 100.0
+	}
+	return limit, nil
+}
+
+// parseCPUMax parses a cpu.max file, the quota is -1 if there is no limit
+func parseCPUMax(file string) (float64, float64, error) {
+	lines, err := readLines(file)
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(lines) == 0 {
+		return 0, 0, fmt.Errorf("wrong file format: %s", file)
+	}
+	fields := strings.Fields(lines[0])
+	if len(fields) != 2 {
+		return 0, 0, fmt.Errorf("wrong file format: %s", file)
+	}
+	period, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	if fields[0] == cgroupV2MaxValue {
+		return -1, period, nil
+	}
+	quota, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	return quota, period, nil
+}
+
+// ioV2 returns the disk read and write stats from io.stat.
+// Format:
+//
+// 8:16 rbytes=1130496 wbytes=0 rios=15 wios=0 dbytes=0 dios=0
+// 8:0 rbytes=37858816 wbytes=671846400 rios=2 wios=84 dbytes=0 dios=0
+//
+func (c ContainerCgroup) ioV2(devices map[string]string) (*metrics.ContainerIOStats, error) {
+	ret := &metrics.ContainerIOStats{
+		DeviceReadBytes:       make(map[string]uint64),
+		DeviceWriteBytes:      make(map[string]uint64),
+		DeviceReadOperations:  make(map[string]uint64),
+		DeviceWriteOperations: make(map[string]uint64),
+	}
+
+	err := c.scanStatFile(unifiedTarget, "io.stat", func(line string) error {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return nil
+		}
+		deviceName := devices[fields[0]]
+		for _, field := range fields[1:] {
+			kv := strings.SplitN(field, "=", 2)
+			if len(kv) != 2 {
+				continue
+			}
+			value, err := strconv.ParseUint(kv[1], 10, 64)
+			if err != nil {
+				continue
+			}
+			switch kv[0] {
+			case "rbytes":
+				ret.ReadBytes += value
+				if deviceName != "" {
+					ret.DeviceReadBytes[deviceName] = value
+				}
+			case "wbytes":
+				ret.WriteBytes += value
+				if deviceName != "" {
+					ret.DeviceWriteBytes[deviceName] = value
+				}
+			case "rios":
+				ret.ReadOperations += value
+				if deviceName != "" {
+					ret.DeviceReadOperations[deviceName] = value
+				}
+			case "wios":
+				ret.WriteOperations += value
+				if deviceName != "" {
+					ret.DeviceWriteOperations[deviceName] = value
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ret.PSI, err = c.parsePSIFile("io.pressure")
+	if err != nil {
+		log.Debugf("Missing io pressure stat for %s: %s", c.ContainerID, err.Error())
+	}
+
+	return ret, nil
+}
+
+// parseFlatKeyedFile reads a cgroup v2 file made of "$KEY $VALUE" lines.
+// If the file does not exist, an empty map is returned.
+func (c ContainerCgroup) parseFlatKeyedFile(file string) (map[string]uint64, error) {
+	values := make(map[string]uint64)
+	err := c.scanStatFile(unifiedTarget, file, func(line string) error {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil
+		}
+		v, err := strconv.ParseUint(fields[1], 10, 64)
+		if err == nil {
+			values[fields[0]] = v
+		}
+		return nil
+	})
+	return values, err
+}
+
+// parseLimitFile reads a cgroup v2 single value limit file, "max" meaning no limit.
+// If the file does not exist or there is no limit then this will default to 0.
+func (c ContainerCgroup) parseLimitFile(file string) (uint64, error) {
+	statFile := c.cgroupFilePath(unifiedTarget, file)
+	lines, err := readLines(statFile)
+	if os.IsNotExist(err) {
+		log.Debugf("Missing cgroup file: %s", statFile)
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	if len(lines) != 1 {
+		return 0, fmt.Errorf("wrong file format: %s", statFile)
+	}
+	if lines[0] == cgroupV2MaxValue {
+		return 0, nil
+	}
+	return strconv.ParseUint(lines[0], 10, 64)
+}
+
+// parsePSIFile reads a pressure stall information file.
+// Format:
+//
+// some avg10=0.00 avg60=0.00 avg300=0.00 total=0
+// full avg10=0.00 avg60=0.00 avg300=0.00 total=0
+//
+func (c ContainerCgroup) parsePSIFile(file string) (*metrics.PSIStats, error) {
+	statFile := c.cgroupFilePath(unifiedTarget, file)
+	lines, err := readLines(statFile)
+	if err != nil {
+		return nil, err
+	}
+	ret := &metrics.PSIStats{}
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		var values *metrics.PSIValues
+		switch fields[0] {
+		case "some":
+			values = &ret.Some
+		case "full":
+			values = &ret.Full
+		default:
+			continue
+		}
+		for _, field := range fields[1:] {
+			kv := strings.SplitN(field, "=", 2)
+			if len(kv) != 2 {
+				continue
+			}
+			switch kv[0] {
+			case "avg10":
+				values.Avg10, _ = strconv.ParseFloat(kv[1], 64)
+			case "avg60":
+				values.Avg60, _ = strconv.ParseFloat(kv[1], 64)
+			case "avg300":
+				values.Avg300, _ = strconv.ParseFloat(kv[1], 64)
+			case "total":
+				values.Total, _ = strconv.ParseUint(kv[1], 10, 64)
+			}
+		}
+	}
+	return ret, nil
+}
+
+// weightToShares converts a cpu.weight value (1 to 10000) to cpu.shares (2 to 262144),
+// reversing the conversion done by container runtimes when shares are requested.
+func weightToShares(weight uint64) uint64 {
+	if weight == 0 {
+		return 0
+	}
+	return 2 + ((weight-1)*262142)/9999
+}
@@ -0,0 +1,218 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+// +build linux
+
+package cgroup
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/util/containers/metrics"
+)
+
+func TestIsUnified(t *testing.T) {
+	assert.True(t, newDummyContainerCgroup("/sys/fs/cgroup", unifiedTarget).isUnified())
+	assert.False(t, newDummyContainerCgroup("/sys/fs/cgroup", unifiedTarget, "memory").isUnified())
+	assert.False(t, newDummyContainerCgroup("/sys/fs/cgroup", "memory").isUnified())
+}
+
+func TestMemV2(t *testing.T) {
+	tempFolder, err := newTempFolder("mem-v2")
+	assert.Nil(t, err)
+	defer tempFolder.removeAll()
+
+	memStats := dummyCgroupStat{
+		"anon":          1000,
+		"file":          2000,
+		"anon_thp":      100,
+		"file_mapped":   200,
+		"kernel_stack":  10,
+		"pagetables":    20,
+		"slab":          30,
+		"pgfault":       5,
+		"pgmajfault":    1,
+		"inactive_anon": 300,
+		"active_anon":   700,
+		"inactive_file": 1500,
+		"active_file":   500,
+		"unevictable":   0,
+	}
+	tempFolder.add("unified/memory.stat", memStats.String())
+	tempFolder.add("unified/memory.current", "3500")
+	tempFolder.add("unified/memory.swap.current", "0")
+	tempFolder.add("unified/memory.pressure", detab(`
+		some avg10=1.50 avg60=0.75 avg300=0.10 total=12345
+		full avg10=0.50 avg60=0.25 avg300=0.00 total=2345
+	`))
+
+	cgroup := newDummyContainerCgroup(tempFolder.RootPath, unifiedTarget)
+
+	mem, err := cgroup.Mem()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1000), mem.RSS)
+	assert.Equal(t, uint64(2000), mem.Cache)
+	assert.Equal(t, uint64(100), mem.RSSHuge)
+	assert.Equal(t, uint64(200), mem.MappedFile)
+	assert.Equal(t, uint64(5), mem.Pgfault)
+	assert.Equal(t, uint64(1), mem.Pgmajfault)
+	assert.Equal(t, uint64(300), mem.InactiveAnon)
+	assert.Equal(t, uint64(700), mem.ActiveAnon)
+	assert.Equal(t, uint64(1500), mem.InactiveFile)
+	assert.Equal(t, uint64(500), mem.ActiveFile)
+	assert.Equal(t, uint64(3500), mem.MemUsageInBytes)
+	assert.Equal(t, uint64(0), mem.Swap)
+	assert.True(t, mem.SwapPresent)
+	assert.Equal(t, &metrics.PSIStats{
+		Some: metrics.PSIValues{Avg10: 1.5, Avg60: 0.75, Avg300: 0.1, Total: 12345},
+		Full: metrics.PSIValues{Avg10: 0.5, Avg60: 0.25, Avg300: 0, Total: 2345},
+	}, mem.PSI)
+
+	kmem, err := cgroup.KernelMemoryUsage()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(60), kmem)
+}
+
+func TestMemLimitsV2(t *testing.T) {
+	tempFolder, err := newTempFolder("mem-limits-v2")
+	assert.Nil(t, err)
+	defer tempFolder.removeAll()
+
+	cgroup := newDummyContainerCgroup(tempFolder.RootPath, unifiedTarget)
+
+	// No file
+	value, err := cgroup.MemLimit()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(0), value)
+	value, err = cgroup.SoftMemLimit()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(0), value)
+
+	// No limit
+	tempFolder.add("unified/memory.max", "max")
+	tempFolder.add("unified/memory.low", "0")
+	value, err = cgroup.MemLimit()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(0), value)
+	value, err = cgroup.SoftMemLimit()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(0), value)
+
+	// Limits
+	tempFolder.add("unified/memory.max", "536870912")
+	tempFolder.add("unified/memory.low", "268435456")
+	value, err = cgroup.MemLimit()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(536870912), value)
+	value, err = cgroup.SoftMemLimit()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(268435456), value)
+
+	// Failed count
+	tempFolder.add("unified/memory.events", detab(`
+		low 0
+		high 0
+		max 12
+		oom 1
+		oom_kill 1
+	`))
+	value, err = cgroup.FailedMemoryCount()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(12), value)
+}
+
+func TestCPUV2(t *testing.T) {
+	tempFolder, err := newTempFolder("cpu-v2")
+	assert.Nil(t, err)
+	defer tempFolder.removeAll()
+
+	cpuStats := dummyCgroupStat{
+		"usage_usec":     915266418,
+		"user_usec":      641400000,
+		"system_usec":    183270000,
+		"nr_periods":     20,
+		"nr_throttled":   10,
+		"throttled_usec": 18327,
+	}
+	tempFolder.add("unified/cpu.stat", cpuStats.String())
+	tempFolder.add("unified/cpu.weight", "39")
+
+	cgroup := newDummyContainerCgroup(tempFolder.RootPath, unifiedTarget)
+
+	timeStat, err := cgroup.CPU()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(64140), timeStat.User)
+	assert.Equal(t, uint64(18327), timeStat.System)
+	assert.Equal(t, uint64(998), timeStat.Shares)
+	assert.InDelta(t, 91526.6418, timeStat.UsageTotal, 0.0000001)
+	assert.Nil(t, timeStat.PSI)
+
+	throttled, throttledTime, err := cgroup.CPUPeriods()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(10), throttled)
+	assert.Equal(t, float64(18327)/MicroToUserHZDivisor, throttledTime)
+}
+
+func TestCPULimitV2(t *testing.T) {
+	tempFolder, err := newTempFolder("cpu-limit-v2")
+	assert.Nil(t, err)
+	defer tempFolder.removeAll()
+
+	cgroup := newDummyContainerCgroup(tempFolder.RootPath, unifiedTarget)
+
+	// No file
+	limit, err := cgroup.CPULimit()
+	assert.Nil(t, err)
+	assert.Equal(t, numCPU*100, limit)
+
+	// No limit
+	tempFolder.add("unified/cpu.max", "max 100000")
+	limit, err = cgroup.CPULimit()
+	assert.Nil(t, err)
+	assert.Equal(t, numCPU*100, limit)
+
+	// No limit, with parent limit
+	tempFolder.add("cpu.max", "200000 100000")
+	limit, err = cgroup.CPULimit()
+	assert.Nil(t, err)
+	assert.Equal(t, float64(200), limit)
+
+	// Limit
+	tempFolder.add("unified/cpu.max", "50000 100000")
+	limit, err = cgroup.CPULimit()
+	assert.Nil(t, err)
+	assert.Equal(t, float64(50), limit)
+
+	// Invalid file
+	tempFolder.add("unified/cpu.max", "50000")
+	_, err = cgroup.CPULimit()
+	assert.NotNil(t, err)
+
+	// Empty file
+	tempFolder.add("unified/cpu.max", "")
+	_, err = cgroup.CPULimit()
+	assert.NotNil(t, err)
+}
+
+func TestThreadsV2(t *testing.T) {
+	tempFolder, err := newTempFolder("pids-v2")
+	assert.Nil(t, err)
+	defer tempFolder.removeAll()
+
+	tempFolder.add("unified/pids.current", "12")
+	tempFolder.add("unified/pids.max", "max")
+
+	cgroup := newDummyContainerCgroup(tempFolder.RootPath, unifiedTarget)
+
+	value, err := cgroup.ThreadCount()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(12), value)
+
+	value, err = cgroup.ThreadLimit()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(0), value)
+}
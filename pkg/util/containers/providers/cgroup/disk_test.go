@@ -209,6 +209,63 @@ func (s *DiskMappingTestSuite) TestContainerCgroupIOFailedMapping() {
 	assert.EqualValues(s.T(), expectedStats, ioStat)
 }
 
+func (s *DiskMappingTestSuite) TestContainerCgroupIOV2() {
+	s.proc.add("diskstats", detab(`
+        8       0 sda 24398 2788 1317975 40488 25201 46267 1584744 142336 0 22352 182660
+        8      16 sdb 189 0 4063 220 0 0 0 0 0 112 204
+    `))
+
+	tempFolder, err := newTempFolder("io-stats-v2")
+	assert.Nil(s.T(), err)
+	defer tempFolder.removeAll()
+
+	// 8:0  is sda
+	// 8:16 is sdb
+	// 55:0 is unknown, don't report per-device but keep in sum
+	tempFolder.add("unified/io.stat", detab(`
+		8:16 rbytes=1130496 wbytes=0 rios=15 wios=0 dbytes=0 dios=0
+		8:0 rbytes=37858816 wbytes=671846400 rios=26 wios=512625 dbytes=0 dios=0
+		55:0 rbytes=55 wbytes=55 rios=1 wios=1 dbytes=0 dios=0
+	`))
+	tempFolder.add("unified/io.pressure", detab(`
+		some avg10=0.00 avg60=0.00 avg300=0.00 total=42
+		full avg10=0.00 avg60=0.00 avg300=0.00 total=21
+	`))
+
+	cgroup := newDummyContainerCgroup(tempFolder.RootPath, unifiedTarget)
+
+	expectedStats := &metrics.ContainerIOStats{
+		ReadBytes:  uint64(1130496 + 37858816 + 55),
+		WriteBytes: uint64(0 + 671846400 + 55),
+		DeviceReadBytes: map[string]uint64{
+			"sda": 37858816,
+			"sdb": 1130496,
+		},
+		DeviceWriteBytes: map[string]uint64{
+			"sda": 671846400,
+			"sdb": 0,
+		},
+		ReadOperations:  uint64(15 + 26 + 1),
+		WriteOperations: uint64(0 + 512625 + 1),
+		DeviceReadOperations: map[string]uint64{
+			"sda": 26,
+			"sdb": 15,
+		},
+		DeviceWriteOperations: map[string]uint64{
+			"sda": 512625,
+			"sdb": 0,
+		},
+		PSI: &metrics.PSIStats{
+			Some: metrics.PSIValues{Total: 42},
+			Full: metrics.PSIValues{Total: 21},
+		},
+	}
+
+	ioStat, err := cgroup.IO()
+	assert.Nil(s.T(), err)
+	assert.EqualValues(s.T(), expectedStats, ioStat)
+}
+
 func TestDiskMappingTestSuite(t *testing.T) {
 	suite.Run(t, new(DiskMappingTestSuite))
 }